@@ -19,7 +19,9 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
 
 	"github.com/kumose/kmup/modules/private"
 	"github.com/kumose/kmup/modules/setting"
@@ -34,6 +36,7 @@ var (
 		Usage: "Manage Kmup Actions",
 		Commands: []*cli.Command{
 			subcmdActionsGenRunnerToken,
+			subcmdActionsRunnerGroups,
 		},
 	}
 
@@ -51,8 +54,151 @@ var (
 			},
 		},
 	}
+
+	runnerGroupFlags = []cli.Flag{
+		&cli.StringFlag{
+			Name:  "description",
+			Usage: "Description of the runner group",
+		},
+		&cli.StringSliceFlag{
+			Name:  "owners",
+			Usage: "Names of the orgs or users whose repositories can use the runners",
+		},
+		&cli.StringSliceFlag{
+			Name:  "repos",
+			Usage: "Full names {owner}/{repo} of the repositories which can use the runners, all repositories can use them if neither owners nor repos are given",
+		},
+		&cli.StringSliceFlag{
+			Name:  "workflows",
+			Usage: "Workflow files whose jobs can run on the runners, like org/deploy/.kmup/workflows/deploy.yml@main, all workflows if empty",
+		},
+	}
+
+	subcmdActionsRunnerGroups = &cli.Command{
+		Name:  "runner-groups",
+		Usage: "Manage the groups of global runners which can only be used by the allowed repositories and workflows",
+		Commands: []*cli.Command{
+			{
+				Name:   "list",
+				Usage:  "List the runner groups",
+				Action: runListActionsRunnerGroups,
+			},
+			{
+				Name:      "create",
+				Usage:     "Create a runner group",
+				ArgsUsage: "<name>",
+				Action:    runCreateActionsRunnerGroup,
+				Flags:     runnerGroupFlags,
+			},
+			{
+				Name:      "update",
+				Usage:     "Update a runner group, the omitted flags are unchanged and an empty value clears a list",
+				ArgsUsage: "<name>",
+				Action:    runUpdateActionsRunnerGroup,
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
+						Name:  "new-name",
+						Usage: "New name of the runner group",
+					},
+				}, runnerGroupFlags...),
+			},
+			{
+				Name:      "delete",
+				Usage:     "Delete a runner group which has no runners",
+				ArgsUsage: "<name>",
+				Action:    runDeleteActionsRunnerGroup,
+			},
+			{
+				Name:      "set-runner",
+				Usage:     "Move a global runner into a runner group, or out of its group if the group is empty",
+				ArgsUsage: "<runner-id> [<group>]",
+				Action:    runSetActionsRunnerGroup,
+			},
+		},
+	}
 )
 
+func runnerGroupOptionsFromFlags(c *cli.Command) (*private.RunnerGroupOptions, error) {
+	if c.Args().Len() != 1 {
+		return nil, errors.New("the name of the runner group is required")
+	}
+	opts := &private.RunnerGroupOptions{
+		Name:    c.Args().First(),
+		NewName: c.String("new-name"),
+	}
+	if c.IsSet("description") {
+		description := c.String("description")
+		opts.Description = &description
+	}
+	// the set flags are sent as non-nil lists, so an empty value clears the list when updating
+	if c.IsSet("owners") {
+		opts.AllowedOwners = append([]string{}, c.StringSlice("owners")...)
+	}
+	if c.IsSet("repos") {
+		opts.AllowedRepos = append([]string{}, c.StringSlice("repos")...)
+	}
+	if c.IsSet("workflows") {
+		opts.AllowedWorkflows = append([]string{}, c.StringSlice("workflows")...)
+	}
+	return opts, nil
+}
+
+func runListActionsRunnerGroups(ctx context.Context, c *cli.Command) error {
+	setting.MustInstalled()
+
+	respText, extra := private.ListActionsRunnerGroups(ctx)
+	if extra.HasError() {
+		return handleCliResponseExtra(extra)
+	}
+	_, _ = fmt.Print(respText.Text)
+	return nil
+}
+
+func runCreateActionsRunnerGroup(ctx context.Context, c *cli.Command) error {
+	setting.MustInstalled()
+
+	opts, err := runnerGroupOptionsFromFlags(c)
+	if err != nil {
+		return err
+	}
+	return handleCliResponseExtra(private.CreateActionsRunnerGroup(ctx, opts))
+}
+
+func runUpdateActionsRunnerGroup(ctx context.Context, c *cli.Command) error {
+	setting.MustInstalled()
+
+	opts, err := runnerGroupOptionsFromFlags(c)
+	if err != nil {
+		return err
+	}
+	return handleCliResponseExtra(private.UpdateActionsRunnerGroup(ctx, opts))
+}
+
+func runDeleteActionsRunnerGroup(ctx context.Context, c *cli.Command) error {
+	setting.MustInstalled()
+
+	if c.Args().Len() != 1 {
+		return errors.New("the name of the runner group is required")
+	}
+	return handleCliResponseExtra(private.DeleteActionsRunnerGroup(ctx, c.Args().First()))
+}
+
+func runSetActionsRunnerGroup(ctx context.Context, c *cli.Command) error {
+	setting.MustInstalled()
+
+	if c.Args().Len() < 1 || c.Args().Len() > 2 {
+		return errors.New("the runner id and optionally the name of the runner group are required")
+	}
+	runnerID, err := strconv.ParseInt(c.Args().First(), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid runner id %q: %w", c.Args().First(), err)
+	}
+	return handleCliResponseExtra(private.SetActionsRunnerGroup(ctx, &private.SetRunnerGroupOptions{
+		RunnerID: runnerID,
+		Group:    c.Args().Get(1),
+	}))
+}
+
 func runGenerateActionsRunnerToken(ctx context.Context, c *cli.Command) error {
 	setting.MustInstalled()
 
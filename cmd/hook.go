@@ -269,10 +269,11 @@ Kmup or set your environment appropriately.`, "")
 				hookOptions.OldCommitIDs = oldCommitIDs
 				hookOptions.NewCommitIDs = newCommitIDs
 				hookOptions.RefFullNames = refFullNames
-				extra := private.HookPreReceive(ctx, username, reponame, hookOptions)
+				resp, extra := private.HookPreReceive(ctx, username, reponame, hookOptions)
 				if extra.HasError() {
 					return fail(ctx, extra.UserMsg, "HookPreReceive(batch) failed: %v", extra.Error)
 				}
+				hookPrintWarnings(resp.Warnings)
 				count = 0
 				lastline = 0
 			}
@@ -292,10 +293,11 @@ Kmup or set your environment appropriately.`, "")
 
 		fmt.Fprintf(out, " Checking %d references\n", count)
 
-		extra := private.HookPreReceive(ctx, username, reponame, hookOptions)
+		resp, extra := private.HookPreReceive(ctx, username, reponame, hookOptions)
 		if extra.HasError() {
 			return fail(ctx, extra.UserMsg, "HookPreReceive(last) failed: %v", extra.Error)
 		}
+		hookPrintWarnings(resp.Warnings)
 	} else if lastline > 0 {
 		fmt.Fprintf(out, "\n")
 	}
@@ -493,6 +495,18 @@ func hookPrintResult(output, isCreate bool, branch, url string) {
 	_ = os.Stderr.Sync()
 }
 
+func hookPrintWarnings(warnings []string) {
+	if len(warnings) == 0 {
+		return
+	}
+	fmt.Fprintln(os.Stderr, "")
+	for _, warning := range warnings {
+		fmt.Fprintln(os.Stderr, warning)
+	}
+	fmt.Fprintln(os.Stderr, "")
+	_ = os.Stderr.Sync()
+}
+
 func pushOptions() map[string]string {
 	opts := make(map[string]string)
 	if pushCount, err := strconv.Atoi(os.Getenv(private.GitPushOptionCount)); err == nil {
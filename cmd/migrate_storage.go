@@ -50,7 +50,7 @@ var CmdMigrateStorage = &cli.Command{
 			Name:    "type",
 			Aliases: []string{"t"},
 			Value:   "",
-			Usage:   "Type of stored files to copy.  Allowed types: 'attachments', 'lfs', 'avatars', 'repo-avatars', 'repo-archivers', 'packages', 'actions-log', 'actions-artifacts",
+			Usage:   "Type of stored files to copy.  Allowed types: 'attachments', 'lfs', 'avatars', 'repo-avatars', 'repo-archivers', 'packages', 'actions-log', 'actions-artifacts', 'actions-cache'",
 		},
 		&cli.StringFlag{
 			Name:    "storage",
@@ -227,6 +227,25 @@ func migrateActionsArtifacts(ctx context.Context, dstStorage storage.ObjectStora
 	})
 }
 
+func migrateActionsCache(ctx context.Context, dstStorage storage.ObjectStorage) error {
+	return db.Iterate(ctx, nil, func(ctx context.Context, c *actions_model.ActionCache) error {
+		if !c.Complete {
+			return nil
+		}
+
+		_, err := storage.Copy(dstStorage, c.StoragePath(), storage.ActionsCache, c.StoragePath())
+		if err != nil {
+			// ignore files that do not exist
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		return nil
+	})
+}
+
 func runMigrateStorage(ctx context.Context, cmd *cli.Command) error {
 	if err := initDB(ctx); err != nil {
 		return err
@@ -308,6 +327,7 @@ func runMigrateStorage(ctx context.Context, cmd *cli.Command) error {
 		"packages":          migratePackages,
 		"actions-log":       migrateActionsLog,
 		"actions-artifacts": migrateActionsArtifacts,
+		"actions-cache":     migrateActionsCache,
 	}
 
 	tp := strings.ToLower(cmd.String("type"))
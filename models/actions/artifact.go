@@ -70,7 +70,8 @@ func init() {
 // ActionArtifact is a file that is stored in the artifact storage.
 type ActionArtifact struct {
 	ID                 int64 `xorm:"pk autoincr"`
-	RunID              int64 `xorm:"index unique(runid_name_path)"` // The run id of the artifact
+	RunID              int64 `xorm:"index unique(runid_name_path)"`              // The run id of the artifact
+	RunAttempt         int64 `xorm:"unique(runid_name_path) NOT NULL DEFAULT 1"` // The attempt of the run which uploads the artifact
 	RunnerID           int64
 	RepoID             int64 `xorm:"index"`
 	OwnerID            int64
@@ -91,12 +92,14 @@ func CreateArtifact(ctx context.Context, t *ActionTask, artifactName, artifactPa
 	if err := t.LoadJob(ctx); err != nil {
 		return nil, err
 	}
-	artifact, err := getArtifactByNameAndPath(ctx, t.Job.RunID, artifactName, artifactPath)
+	artifact, err := getArtifactByNameAndPath(ctx, t.Job.RunID, t.RunAttempt, artifactName, artifactPath)
 	if errors.Is(err, util.ErrNotExist) {
+		// an artifact uploaded by an earlier attempt of the run is kept, the new one replaces it in the later attempts
 		artifact := &ActionArtifact{
 			ArtifactName: artifactName,
 			ArtifactPath: artifactPath,
 			RunID:        t.Job.RunID,
+			RunAttempt:   t.RunAttempt,
 			RunnerID:     t.RunnerID,
 			RepoID:       t.RepoID,
 			OwnerID:      t.OwnerID,
@@ -121,9 +124,9 @@ func CreateArtifact(ctx context.Context, t *ActionTask, artifactName, artifactPa
 	return artifact, nil
 }
 
-func getArtifactByNameAndPath(ctx context.Context, runID int64, name, fpath string) (*ActionArtifact, error) {
+func getArtifactByNameAndPath(ctx context.Context, runID, runAttempt int64, name, fpath string) (*ActionArtifact, error) {
 	var art ActionArtifact
-	has, err := db.GetEngine(ctx).Where("run_id = ? AND artifact_name = ? AND artifact_path = ?", runID, name, fpath).Get(&art)
+	has, err := db.GetEngine(ctx).Where("run_id = ? AND run_attempt = ? AND artifact_name = ? AND artifact_path = ?", runID, runAttempt, name, fpath).Get(&art)
 	if err != nil {
 		return nil, err
 	} else if !has {
@@ -143,6 +146,7 @@ type FindArtifactsOptions struct {
 	db.ListOptions
 	RepoID               int64
 	RunID                int64
+	RunAttempt           int64 // only the artifacts visible in the attempt of the run, 0 means the artifacts of all attempts
 	ArtifactName         string
 	Status               int
 	FinalizedArtifactsV4 bool
@@ -162,6 +166,9 @@ func (opts FindArtifactsOptions) ToConds() builder.Cond {
 	if opts.RunID > 0 {
 		cond = cond.And(builder.Eq{"run_id": opts.RunID})
 	}
+	if opts.RunAttempt > 0 {
+		cond = cond.And(runAttemptCond(opts.RunAttempt))
+	}
 	if opts.ArtifactName != "" {
 		cond = cond.And(builder.Eq{"artifact_name": opts.ArtifactName})
 	}
@@ -176,6 +183,16 @@ func (opts FindArtifactsOptions) ToConds() builder.Cond {
 	return cond
 }
 
+// runAttemptCond matches the artifacts visible in the given attempt of their runs.
+// The artifacts uploaded by an earlier attempt are visible until a later attempt uploads an artifact with the same name.
+func runAttemptCond(runAttempt int64) builder.Cond {
+	return builder.Lte{"run_attempt": runAttempt}.And(builder.NotExists(
+		builder.Select("id").From("action_artifact", "newer").Where(builder.Expr(
+			"newer.run_id = action_artifact.run_id AND newer.artifact_name = action_artifact.artifact_name AND newer.run_attempt > action_artifact.run_attempt AND newer.run_attempt <= ?", runAttempt,
+		)),
+	))
+}
+
 // ActionArtifactMeta is the meta-data of an artifact
 type ActionArtifactMeta struct {
 	ArtifactName string
@@ -183,11 +200,12 @@ type ActionArtifactMeta struct {
 	Status       ArtifactStatus
 }
 
-// ListUploadedArtifactsMeta returns all uploaded artifacts meta of a run
-func ListUploadedArtifactsMeta(ctx context.Context, runID int64) ([]*ActionArtifactMeta, error) {
+// ListUploadedArtifactsMeta returns all uploaded artifacts meta visible in the attempt of a run
+func ListUploadedArtifactsMeta(ctx context.Context, runID, runAttempt int64) ([]*ActionArtifactMeta, error) {
 	arts := make([]*ActionArtifactMeta, 0, 10)
 	return arts, db.GetEngine(ctx).Table("action_artifact").
 		Where("run_id=? AND (status=? OR status=?)", runID, ArtifactStatusUploadConfirmed, ArtifactStatusExpired).
+		And(runAttemptCond(runAttempt)).
 		GroupBy("artifact_name").
 		Select("artifact_name, sum(file_size) as file_size, max(status) as status").
 		Find(&arts)
@@ -214,9 +232,9 @@ func SetArtifactExpired(ctx context.Context, artifactID int64) error {
 	return err
 }
 
-// SetArtifactNeedDelete sets an artifact to need-delete, cron job will delete it
-func SetArtifactNeedDelete(ctx context.Context, runID int64, name string) error {
-	_, err := db.GetEngine(ctx).Where("run_id=? AND artifact_name=? AND status = ?", runID, name, ArtifactStatusUploadConfirmed).Cols("status").Update(&ActionArtifact{Status: ArtifactStatusPendingDeletion})
+// SetArtifactNeedDelete sets an artifact uploaded by the attempt of a run to need-delete, cron job will delete it
+func SetArtifactNeedDelete(ctx context.Context, runID, runAttempt int64, name string) error {
+	_, err := db.GetEngine(ctx).Where("run_id=? AND run_attempt=? AND artifact_name=? AND status = ?", runID, runAttempt, name, ArtifactStatusUploadConfirmed).Cols("status").Update(&ActionArtifact{Status: ArtifactStatusPendingDeletion})
 	return err
 }
 
@@ -225,3 +243,18 @@ func SetArtifactDeleted(ctx context.Context, artifactID int64) error {
 	_, err := db.GetEngine(ctx).ID(artifactID).Cols("status").Update(&ActionArtifact{Status: ArtifactStatusDeleted})
 	return err
 }
+
+// GetArtifactSizesByOwner gets the stored size of the uploaded artifacts of every owner
+func GetArtifactSizesByOwner(ctx context.Context) (map[int64]int64, error) {
+	return db.FindOwnerSizes(ctx, builder.Select("owner_id", "SUM(file_compressed_size) AS size").
+		From("action_artifact").
+		Where(builder.Eq{"status": ArtifactStatusUploadConfirmed}).
+		GroupBy("owner_id"))
+}
+
+// ExistsArtifactWithStoragePath checks if an artifact which is neither expired nor deleted is stored at the path
+func ExistsArtifactWithStoragePath(ctx context.Context, storagePath string) (bool, error) {
+	return db.GetEngine(ctx).
+		Where(builder.Eq{"storage_path": storagePath}.And(builder.NotIn("status", ArtifactStatusExpired, ArtifactStatusDeleted))).
+		Exist(new(ActionArtifact))
+}
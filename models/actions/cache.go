@@ -0,0 +1,198 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kumose/kmup/models/db"
+	"github.com/kumose/kmup/modules/optional"
+	"github.com/kumose/kmup/modules/timeutil"
+	"github.com/kumose/kmup/modules/util"
+
+	"xorm.io/builder"
+)
+
+// ActionCache is an entry of actions/cache, it is created by a job for a ref of the repository
+type ActionCache struct {
+	ID       int64
+	RepoID   int64  `xorm:"index"`
+	Scope    string `xorm:"index"`        // the full ref name which created the cache, jobs of other refs can only restore it if the ref is in their scopes
+	CacheKey string `xorm:"VARCHAR(512)"` // the key given by the job, a cache is restored by the exact key or a prefix of it
+	Version  string `xorm:"VARCHAR(255)"` // the hash of the paths and compression method, only caches of the same version can be restored
+	Size     int64  // the size in bytes, while the cache is uploaded it is the size declared by the job or 0 if it is unknown
+	// Complete is false while the job is uploading the cache, the cache can not be restored before it has been committed
+	Complete     bool               `xorm:"index"`
+	CreatedUnix  timeutil.TimeStamp `xorm:"created"`
+	UpdatedUnix  timeutil.TimeStamp `xorm:"updated"`
+	LastUsedUnix timeutil.TimeStamp `xorm:"index"` // the last time the cache was committed or restored, it is used to evict the least recently used caches
+}
+
+func init() {
+	db.RegisterModel(new(ActionCache))
+}
+
+// StoragePath returns the path of the committed cache in the storage
+func (c *ActionCache) StoragePath() string {
+	return fmt.Sprintf("%d/%d/%d", c.RepoID%255, c.RepoID, c.ID)
+}
+
+// TempStoragePath returns the directory of the uploaded chunks of the cache before it has been committed
+func (c *ActionCache) TempStoragePath() string {
+	return fmt.Sprintf("tmp/%d", c.ID)
+}
+
+// ReserveCache inserts an incomplete cache, the cache must not exist in the scope
+func ReserveCache(ctx context.Context, c *ActionCache) error {
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		exist, err := db.GetEngine(ctx).Where(builder.Eq{
+			"repo_id":   c.RepoID,
+			"scope":     c.Scope,
+			"cache_key": c.CacheKey,
+			"version":   c.Version,
+		}).Exist(new(ActionCache))
+		if err != nil {
+			return err
+		} else if exist {
+			return util.NewAlreadyExistErrorf("cache %q already exists", c.CacheKey)
+		}
+		c.Complete = false
+		c.LastUsedUnix = timeutil.TimeStampNow()
+		return db.Insert(ctx, c)
+	})
+}
+
+// GetCacheByID returns the cache of the repository by id
+func GetCacheByID(ctx context.Context, repoID, id int64) (*ActionCache, error) {
+	var c ActionCache
+	has, err := db.GetEngine(ctx).Where("id=? AND repo_id=?", id, repoID).Get(&c)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, util.NewNotExistErrorf("cache with id %d does not exist", id)
+	}
+	return &c, nil
+}
+
+// CommitCache marks the cache as complete with its size
+func CommitCache(ctx context.Context, c *ActionCache, size int64) error {
+	c.Size = size
+	c.Complete = true
+	c.LastUsedUnix = timeutil.TimeStampNow()
+	_, err := db.GetEngine(ctx).ID(c.ID).Cols("size", "complete", "last_used_unix").Update(c)
+	return err
+}
+
+// UpdateCacheLastUsed records that the cache has been restored
+func UpdateCacheLastUsed(ctx context.Context, c *ActionCache) error {
+	c.LastUsedUnix = timeutil.TimeStampNow()
+	_, err := db.GetEngine(ctx).ID(c.ID).NoAutoTime().Cols("last_used_unix").Update(c)
+	return err
+}
+
+// FindCacheToRestore returns the cache matching the keys in the first possible scope.
+// In every scope, the first key is tried as an exact match, then all keys are tried as prefixes and the newest matching cache wins,
+// the same as the restore keys of actions/cache in GitHub.
+func FindCacheToRestore(ctx context.Context, repoID int64, scopes, keys []string, version string) (*ActionCache, error) {
+	if len(keys) == 0 {
+		return nil, util.NewInvalidArgumentErrorf("no cache keys")
+	}
+	for _, scope := range scopes {
+		var caches []*ActionCache
+		if err := db.GetEngine(ctx).Where(builder.Eq{
+			"repo_id":  repoID,
+			"scope":    scope,
+			"version":  version,
+			"complete": true,
+		}).Desc("created_unix", "id").Find(&caches); err != nil {
+			return nil, err
+		}
+		for _, c := range caches {
+			if c.CacheKey == keys[0] {
+				return c, nil
+			}
+		}
+		for _, key := range keys {
+			for _, c := range caches {
+				if strings.HasPrefix(c.CacheKey, key) {
+					return c, nil
+				}
+			}
+		}
+	}
+	return nil, util.NewNotExistErrorf("cache %q does not exist", keys[0])
+}
+
+// GetRepoCacheSize returns the total size of the committed caches of the repository
+func GetRepoCacheSize(ctx context.Context, repoID int64) (int64, error) {
+	return db.GetEngine(ctx).Where("repo_id=? AND complete=?", repoID, true).SumInt(new(ActionCache), "size")
+}
+
+type FindCachesOptions struct {
+	db.ListOptions
+	RepoID     int64
+	Scope      string
+	CacheKey   string
+	Version    string
+	Complete   optional.Option[bool]
+	UsedBefore timeutil.TimeStamp
+}
+
+func (opts FindCachesOptions) ToConds() builder.Cond {
+	cond := builder.NewCond()
+	if opts.RepoID > 0 {
+		cond = cond.And(builder.Eq{"repo_id": opts.RepoID})
+	}
+	if opts.Scope != "" {
+		cond = cond.And(builder.Eq{"scope": opts.Scope})
+	}
+	if opts.CacheKey != "" {
+		cond = cond.And(builder.Eq{"cache_key": opts.CacheKey})
+	}
+	if opts.Version != "" {
+		cond = cond.And(builder.Eq{"version": opts.Version})
+	}
+	if opts.Complete.Has() {
+		cond = cond.And(builder.Eq{"complete": opts.Complete.Value()})
+	}
+	if opts.UsedBefore > 0 {
+		cond = cond.And(builder.Lt{"last_used_unix": opts.UsedBefore})
+	}
+	return cond
+}
+
+// ToOrders returns the least recently used caches first
+func (opts FindCachesOptions) ToOrders() string {
+	return "last_used_unix ASC, id ASC"
+}
+
+// GetReposExceedingCacheSize returns the ids of the repositories whose committed caches are larger than the size
+func GetReposExceedingCacheSize(ctx context.Context, size int64) ([]int64, error) {
+	var repoIDs []int64
+	err := db.GetEngine(ctx).Table("action_cache").Where("complete=?", true).
+		GroupBy("repo_id").Having(fmt.Sprintf("SUM(size) > %d", size)).Cols("repo_id").Find(&repoIDs)
+	return repoIDs, err
+}
+
+// DeleteCache deletes the record of the cache, the caller should remove the stored file
+func DeleteCache(ctx context.Context, c *ActionCache) error {
+	_, err := db.DeleteByID[ActionCache](ctx, c.ID)
+	return err
+}
@@ -0,0 +1,84 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"testing"
+
+	"github.com/kumose/kmup/models/unittest"
+	"github.com/kumose/kmup/modules/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindCacheToRestore(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	insert := func(scope, key, version string, complete bool) *ActionCache {
+		c := &ActionCache{RepoID: 4, Scope: scope, CacheKey: key, Version: version}
+		require.NoError(t, ReserveCache(t.Context(), c))
+		if complete {
+			require.NoError(t, CommitCache(t.Context(), c, 10))
+		}
+		return c
+	}
+	mainExact := insert("refs/heads/main", "linux-deps-abc", "v1", true)
+	mainPrefix := insert("refs/heads/main", "linux-deps-def", "v1", true)
+	featurePrefix := insert("refs/heads/feature", "linux-deps-old", "v1", true)
+	insert("refs/heads/feature", "linux-deps-new", "v1", false)
+	insert("refs/heads/feature", "linux-deps-v2", "v2", true)
+
+	err := ReserveCache(t.Context(), &ActionCache{RepoID: 4, Scope: "refs/heads/main", CacheKey: "linux-deps-abc", Version: "v1"})
+	assert.ErrorIs(t, err, util.ErrAlreadyExist)
+
+	cases := []struct {
+		scopes   []string
+		keys     []string
+		version  string
+		expected *ActionCache
+	}{
+		{[]string{"refs/heads/main"}, []string{"linux-deps-abc"}, "v1", mainExact},
+		// the newest cache matching the prefix
+		{[]string{"refs/heads/main"}, []string{"linux-deps-xyz", "linux-deps-"}, "v1", mainPrefix},
+		// the exact key is tried as a prefix too
+		{[]string{"refs/heads/main"}, []string{"linux-deps-a"}, "v1", mainExact},
+		// the scope of the run is searched first, incomplete caches are ignored
+		{[]string{"refs/heads/feature", "refs/heads/main"}, []string{"linux-deps-abc", "linux-"}, "v1", featurePrefix},
+		{[]string{"refs/heads/feature", "refs/heads/main"}, []string{"linux-deps-abc"}, "v1", mainExact},
+		{[]string{"refs/heads/main"}, []string{"linux-deps-v2"}, "v1", nil},
+		{[]string{"refs/heads/other"}, []string{"linux-"}, "v1", nil},
+	}
+	for _, c := range cases {
+		actual, err := FindCacheToRestore(t.Context(), 4, c.scopes, c.keys, c.version)
+		if c.expected == nil {
+			assert.ErrorIs(t, err, util.ErrNotExist, "%v %v", c.scopes, c.keys)
+			continue
+		}
+		if assert.NoError(t, err, "%v %v", c.scopes, c.keys) {
+			assert.Equal(t, c.expected.ID, actual.ID, "%v %v", c.scopes, c.keys)
+		}
+	}
+
+	size, err := GetRepoCacheSize(t.Context(), 4)
+	require.NoError(t, err)
+	assert.EqualValues(t, 40, size)
+	repoIDs, err := GetReposExceedingCacheSize(t.Context(), 30)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{4}, repoIDs)
+}
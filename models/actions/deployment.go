@@ -0,0 +1,214 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kumose/kmup/models/db"
+	user_model "github.com/kumose/kmup/models/user"
+	"github.com/kumose/kmup/modules/container"
+	"github.com/kumose/kmup/modules/git"
+	"github.com/kumose/kmup/modules/timeutil"
+	"github.com/kumose/kmup/modules/util"
+
+	"xorm.io/builder"
+)
+
+// DeploymentStatus represents the status of a deployment
+type DeploymentStatus int
+
+const (
+	DeploymentStatusWaiting   DeploymentStatus = iota + 1 // 1, waiting for the reviewers or the wait timer
+	DeploymentStatusApproved                              // 2, the job has been allowed to run
+	DeploymentStatusRejected                              // 3, rejected by a reviewer or the ref restrictions
+	DeploymentStatusCancelled                             // 4, the job was cancelled before the deployment was approved
+)
+
+var deploymentStatusNames = map[DeploymentStatus]string{
+	DeploymentStatusWaiting:   "waiting",
+	DeploymentStatusApproved:  "approved",
+	DeploymentStatusRejected:  "rejected",
+	DeploymentStatusCancelled: "cancelled",
+}
+
+// String returns the string name of the DeploymentStatus
+func (s DeploymentStatus) String() string {
+	return deploymentStatusNames[s]
+}
+
+func (s DeploymentStatus) IsWaiting() bool {
+	return s == DeploymentStatusWaiting
+}
+
+// ActionDeployment records a job deploying to an environment
+type ActionDeployment struct {
+	ID            int64
+	RepoID        int64              `xorm:"index"`
+	EnvironmentID int64              `xorm:"index"`
+	Environment   *ActionEnvironment `xorm:"-"`
+	RunID         int64              `xorm:"index"`
+	Run           *ActionRun         `xorm:"-"`
+	RunJobID      int64              `xorm:"index"`
+	Job           *ActionRunJob      `xorm:"-"`
+	Ref           string
+	CommitSHA     string
+	CreatorID     int64
+	Creator       *user_model.User `xorm:"-"`
+	Status        DeploymentStatus `xorm:"index"`
+
+	// ReviewerID is the user who approved or rejected the deployment, a waiting deployment with a reviewer is waiting for the wait timer
+	ReviewerID    int64
+	Reviewer      *user_model.User `xorm:"-"`
+	ReviewComment string           `xorm:"TEXT"`
+	Reviewed      timeutil.TimeStamp
+
+	Created timeutil.TimeStamp `xorm:"created"`
+	Updated timeutil.TimeStamp `xorm:"updated"`
+}
+
+func init() {
+	db.RegisterModel(new(ActionDeployment))
+}
+
+// IsApprovedByReviewer returns whether a required reviewer has approved the deployment
+func (d *ActionDeployment) IsApprovedByReviewer() bool {
+	return d.ReviewerID > 0 && d.Status != DeploymentStatusRejected
+}
+
+// PrettyRef returns the short name of the deployed ref
+func (d *ActionDeployment) PrettyRef() string {
+	return git.RefName(d.Ref).ShortName()
+}
+
+// GetDeploymentByID returns the deployment of the repository by id
+func GetDeploymentByID(ctx context.Context, repoID, id int64) (*ActionDeployment, error) {
+	var d ActionDeployment
+	has, err := db.GetEngine(ctx).Where("id=? AND repo_id=?", id, repoID).Get(&d)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, fmt.Errorf("deployment with id %d: %w", id, util.ErrNotExist)
+	}
+	return &d, nil
+}
+
+// GetWaitingDeploymentByJobID returns the waiting deployment of a job which is pending approval
+func GetWaitingDeploymentByJobID(ctx context.Context, jobID int64) (*ActionDeployment, error) {
+	var d ActionDeployment
+	has, err := db.GetEngine(ctx).Where("run_job_id=? AND status=?", jobID, DeploymentStatusWaiting).Desc("id").Get(&d)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, fmt.Errorf("waiting deployment of job %d: %w", jobID, util.ErrNotExist)
+	}
+	return &d, nil
+}
+
+// UpdateDeployment updates the given columns of a deployment, only a waiting deployment can be updated
+func UpdateDeployment(ctx context.Context, d *ActionDeployment, cols ...string) (bool, error) {
+	n, err := db.GetEngine(ctx).ID(d.ID).Where(builder.Eq{"status": DeploymentStatusWaiting}).Cols(cols...).Update(d)
+	return n > 0, err
+}
+
+type FindDeploymentsOptions struct {
+	db.ListOptions
+	RepoID        int64
+	EnvironmentID int64
+	RunID         int64
+	Status        []DeploymentStatus
+}
+
+func (opts FindDeploymentsOptions) ToConds() builder.Cond {
+	cond := builder.NewCond()
+	if opts.RepoID > 0 {
+		cond = cond.And(builder.Eq{"repo_id": opts.RepoID})
+	}
+	if opts.EnvironmentID > 0 {
+		cond = cond.And(builder.Eq{"environment_id": opts.EnvironmentID})
+	}
+	if opts.RunID > 0 {
+		cond = cond.And(builder.Eq{"run_id": opts.RunID})
+	}
+	if len(opts.Status) > 0 {
+		cond = cond.And(builder.In("status", opts.Status))
+	}
+	return cond
+}
+
+func (opts FindDeploymentsOptions) ToOrders() string {
+	return "`id` DESC"
+}
+
+type DeploymentList []*ActionDeployment
+
+// LoadAttributes loads the environments, runs, jobs, creators and reviewers of the deployments
+func (deployments DeploymentList) LoadAttributes(ctx context.Context) error {
+	envIDs := make(container.Set[int64])
+	runIDs := make(container.Set[int64])
+	jobIDs := make(container.Set[int64])
+	userIDs := make(container.Set[int64])
+	for _, d := range deployments {
+		envIDs.Add(d.EnvironmentID)
+		runIDs.Add(d.RunID)
+		jobIDs.Add(d.RunJobID)
+		userIDs.Add(d.CreatorID)
+		if d.ReviewerID > 0 {
+			userIDs.Add(d.ReviewerID)
+		}
+	}
+
+	envs := make(map[int64]*ActionEnvironment, len(envIDs))
+	if err := db.GetEngine(ctx).In("id", envIDs.Values()).Find(&envs); err != nil {
+		return err
+	}
+	runs := make(map[int64]*ActionRun, len(runIDs))
+	if err := db.GetEngine(ctx).In("id", runIDs.Values()).Find(&runs); err != nil {
+		return err
+	}
+	jobs := make(map[int64]*ActionRunJob, len(jobIDs))
+	if err := db.GetEngine(ctx).In("id", jobIDs.Values()).Find(&jobs); err != nil {
+		return err
+	}
+	users, err := user_model.GetPossibleUserByIDs(ctx, userIDs.Values())
+	if err != nil {
+		return err
+	}
+	userMap := make(map[int64]*user_model.User, len(users))
+	for _, u := range users {
+		userMap[u.ID] = u
+	}
+
+	for _, d := range deployments {
+		d.Environment = envs[d.EnvironmentID]
+		d.Run = runs[d.RunID]
+		d.Job = jobs[d.RunJobID]
+		d.Creator = userMap[d.CreatorID]
+		if d.Creator == nil {
+			d.Creator = user_model.NewGhostUser()
+		}
+		if d.ReviewerID > 0 {
+			d.Reviewer = userMap[d.ReviewerID]
+			if d.Reviewer == nil {
+				d.Reviewer = user_model.NewGhostUser()
+			}
+		}
+	}
+	return nil
+}
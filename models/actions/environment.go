@@ -0,0 +1,189 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kumose/kmup/models/db"
+	"github.com/kumose/kmup/modules/git"
+	"github.com/kumose/kmup/modules/glob"
+	"github.com/kumose/kmup/modules/log"
+	"github.com/kumose/kmup/modules/timeutil"
+	"github.com/kumose/kmup/modules/util"
+
+	"xorm.io/builder"
+)
+
+// ActionEnvironment represents a deployment environment of a repository.
+// The jobs with `environment` deploy to it, they can use its secrets and variables
+// only after its protection rules are satisfied.
+type ActionEnvironment struct {
+	ID        int64
+	RepoID    int64  `xorm:"UNIQUE(repo_name) NOT NULL"`
+	Name      string `xorm:"VARCHAR(255) NOT NULL"`
+	LowerName string `xorm:"VARCHAR(255) UNIQUE(repo_name) NOT NULL"`
+
+	// ReviewerIDs are the users who can approve or reject the deployments, no approval is required if it is empty
+	ReviewerIDs []int64 `xorm:"JSON TEXT"`
+	// WaitTimer is the number of minutes to wait before a deployment is allowed to proceed
+	WaitTimer int64 `xorm:"NOT NULL DEFAULT 0"`
+	// BranchPatterns and TagPatterns are glob patterns of the refs allowed to deploy.
+	// All refs are allowed if both of them are empty.
+	BranchPatterns []string `xorm:"JSON TEXT"`
+	TagPatterns    []string `xorm:"JSON TEXT"`
+
+	Created timeutil.TimeStamp `xorm:"created"`
+	Updated timeutil.TimeStamp `xorm:"updated"`
+}
+
+const (
+	EnvironmentNameMaxLength = 255
+	EnvironmentMaxWaitTimer  = 43200 // 30 days in minutes
+)
+
+func init() {
+	db.RegisterModel(new(ActionEnvironment))
+}
+
+// IsProtected returns whether the deployments to the environment have to wait for reviewers or the wait timer
+func (env *ActionEnvironment) IsProtected() bool {
+	return len(env.ReviewerIDs) > 0 || env.WaitTimer > 0
+}
+
+// IsReviewer returns whether the user can review the deployments to the environment
+func (env *ActionEnvironment) IsReviewer(userID int64) bool {
+	return slices.Contains(env.ReviewerIDs, userID)
+}
+
+// WaitTimerDuration returns the wait timer as a duration
+func (env *ActionEnvironment) WaitTimerDuration() time.Duration {
+	return time.Duration(env.WaitTimer) * time.Minute
+}
+
+// HasRefRestrictions returns whether only some branches or tags can deploy to the environment
+func (env *ActionEnvironment) HasRefRestrictions() bool {
+	return len(env.BranchPatterns) > 0 || len(env.TagPatterns) > 0
+}
+
+// CanDeployRef returns whether the ref is allowed to deploy to the environment
+func (env *ActionEnvironment) CanDeployRef(ref git.RefName) bool {
+	if !env.HasRefRestrictions() {
+		return true
+	}
+	switch {
+	case ref.IsBranch():
+		return matchRefPatterns(env.BranchPatterns, ref.BranchName())
+	case ref.IsTag():
+		return matchRefPatterns(env.TagPatterns, ref.TagName())
+	}
+	return false
+}
+
+func matchRefPatterns(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		g, err := glob.Compile(pattern, '/')
+		if err != nil {
+			log.Warn("Invalid environment ref pattern %q: %v", pattern, err)
+			continue
+		}
+		if g.Match(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateRefPatterns checks whether all the patterns are valid glob patterns
+func ValidateRefPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := glob.Compile(pattern, '/'); err != nil {
+			return util.NewInvalidArgumentErrorf("invalid pattern %q: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+// CreateEnvironment inserts a new environment
+func CreateEnvironment(ctx context.Context, env *ActionEnvironment) error {
+	env.LowerName = strings.ToLower(env.Name)
+	return db.Insert(ctx, env)
+}
+
+// UpdateEnvironment updates the given columns of an environment
+func UpdateEnvironment(ctx context.Context, env *ActionEnvironment, cols ...string) error {
+	env.LowerName = strings.ToLower(env.Name)
+	if slices.Contains(cols, "name") {
+		cols = append(cols, "lower_name")
+	}
+	sess := db.GetEngine(ctx).ID(env.ID)
+	if len(cols) > 0 {
+		sess.Cols(cols...)
+	}
+	_, err := sess.Update(env)
+	return err
+}
+
+// GetEnvironmentByRepoIDAndName returns the environment of the repository by name, the name is case-insensitive
+func GetEnvironmentByRepoIDAndName(ctx context.Context, repoID int64, name string) (*ActionEnvironment, error) {
+	var env ActionEnvironment
+	has, err := db.GetEngine(ctx).Where("repo_id=? AND lower_name=?", repoID, strings.ToLower(name)).Get(&env)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, fmt.Errorf("environment with name %q: %w", name, util.ErrNotExist)
+	}
+	return &env, nil
+}
+
+// GetEnvironmentByRepoIDAndID returns the environment of the repository by id
+func GetEnvironmentByRepoIDAndID(ctx context.Context, repoID, id int64) (*ActionEnvironment, error) {
+	var env ActionEnvironment
+	has, err := db.GetEngine(ctx).Where("id=? AND repo_id=?", id, repoID).Get(&env)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, fmt.Errorf("environment with id %d: %w", id, util.ErrNotExist)
+	}
+	return &env, nil
+}
+
+type FindEnvironmentsOptions struct {
+	db.ListOptions
+	RepoID int64
+	IDs    []int64
+}
+
+func (opts FindEnvironmentsOptions) ToConds() builder.Cond {
+	cond := builder.NewCond()
+	if opts.RepoID > 0 {
+		cond = cond.And(builder.Eq{"repo_id": opts.RepoID})
+	}
+	if len(opts.IDs) > 0 {
+		cond = cond.And(builder.In("id", opts.IDs))
+	}
+	return cond
+}
+
+func (opts FindEnvironmentsOptions) ToOrders() string {
+	return "lower_name ASC"
+}
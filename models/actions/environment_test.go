@@ -0,0 +1,80 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"testing"
+
+	"github.com/kumose/kmup/models/db"
+	"github.com/kumose/kmup/models/unittest"
+	"github.com/kumose/kmup/modules/git"
+	"github.com/kumose/kmup/modules/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestActionEnvironmentCanDeployRef(t *testing.T) {
+	env := &ActionEnvironment{}
+	assert.False(t, env.IsProtected())
+	assert.True(t, env.CanDeployRef(git.RefNameFromBranch("feature/a")))
+	assert.True(t, env.CanDeployRef("refs/pull/1/head"))
+
+	env = &ActionEnvironment{
+		BranchPatterns: []string{"main", "release/*"},
+		TagPatterns:    []string{"v*"},
+	}
+	assert.True(t, env.CanDeployRef(git.RefNameFromBranch("main")))
+	assert.True(t, env.CanDeployRef(git.RefNameFromBranch("release/1.0")))
+	assert.False(t, env.CanDeployRef(git.RefNameFromBranch("release/1.0/hotfix")))
+	assert.False(t, env.CanDeployRef(git.RefNameFromBranch("feature/a")))
+	assert.True(t, env.CanDeployRef(git.RefNameFromTag("v1.0.0")))
+	assert.False(t, env.CanDeployRef(git.RefNameFromTag("nightly")))
+	assert.False(t, env.CanDeployRef("refs/pull/1/head"))
+
+	env = &ActionEnvironment{ReviewerIDs: []int64{2}, WaitTimer: 5}
+	assert.True(t, env.IsProtected())
+	assert.True(t, env.IsReviewer(2))
+	assert.False(t, env.IsReviewer(3))
+	assert.Equal(t, "5m0s", env.WaitTimerDuration().String())
+
+	assert.NoError(t, ValidateRefPatterns([]string{"main", "release/**"}))
+	assert.ErrorIs(t, ValidateRefPatterns([]string{"release/["}), util.ErrInvalidArgument)
+}
+
+func TestGetEnvironmentByRepoIDAndName(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	env := &ActionEnvironment{RepoID: 4, Name: "Production"}
+	require.NoError(t, CreateEnvironment(t.Context(), env))
+	assert.Equal(t, "production", env.LowerName)
+
+	got, err := GetEnvironmentByRepoIDAndName(t.Context(), 4, "PRODUCTION")
+	require.NoError(t, err)
+	assert.Equal(t, env.ID, got.ID)
+
+	_, err = GetEnvironmentByRepoIDAndName(t.Context(), 1, "production")
+	assert.ErrorIs(t, err, util.ErrNotExist)
+
+	env.Name = "Staging"
+	require.NoError(t, UpdateEnvironment(t.Context(), env, "name"))
+	envs, err := db.Find[ActionEnvironment](t.Context(), FindEnvironmentsOptions{RepoID: 4})
+	require.NoError(t, err)
+	require.Len(t, envs, 1)
+	assert.Equal(t, "staging", envs[0].LowerName)
+}
@@ -26,9 +26,12 @@ import (
 func TestMain(m *testing.M) {
 	unittest.MainTest(m, &unittest.TestOptions{
 		FixtureFiles: []string{
+			"action_runner.yml",
 			"action_runner_token.yml",
 			"action_run.yml",
+			"action_run_job.yml",
 			"repository.yml",
+			"user.yml",
 		},
 	})
 }
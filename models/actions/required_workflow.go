@@ -0,0 +1,211 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/kumose/kmup/models/db"
+	repo_model "github.com/kumose/kmup/models/repo"
+	"github.com/kumose/kmup/modules/glob"
+	"github.com/kumose/kmup/modules/log"
+	"github.com/kumose/kmup/modules/timeutil"
+	"github.com/kumose/kmup/modules/util"
+
+	"xorm.io/builder"
+)
+
+// ActionRequiredWorkflow represents a workflow file of a central repository which is required to run for a set of repositories.
+//
+// It can be:
+//  1. instance-level, OwnerID is 0, it applies to the repositories of all owners
+//  2. org-level, OwnerID is the org ID, it applies to the repositories of the org, and the central repository must belong to the org
+//
+// The workflow runs alongside the repositories' own workflows and can't be disabled by them.
+type ActionRequiredWorkflow struct {
+	ID           int64
+	OwnerID      int64                  `xorm:"index NOT NULL DEFAULT 0"`
+	RepoID       int64                  `xorm:"index NOT NULL"` // the central repository containing the workflow file
+	Repo         *repo_model.Repository `xorm:"-"`
+	WorkflowPath string                 `xorm:"VARCHAR(255) NOT NULL"` // the path of the workflow file in the central repository
+	Ref          string                 `xorm:"VARCHAR(255)"`          // the branch to load the workflow file from, the default branch if it is empty
+
+	// RepoPatterns are glob patterns matched against the names of the repositories, or their full names "owner/name" for instance-level ones.
+	// Topics match the repositories having any of them.
+	// All repositories match if both of them are empty.
+	RepoPatterns []string `xorm:"JSON TEXT"`
+	Topics       []string `xorm:"JSON TEXT"`
+
+	Created timeutil.TimeStamp `xorm:"created"`
+	Updated timeutil.TimeStamp `xorm:"updated"`
+}
+
+func init() {
+	db.RegisterModel(new(ActionRequiredWorkflow))
+}
+
+// IsGlobal returns whether it's an instance-level required workflow
+func (rw *ActionRequiredWorkflow) IsGlobal() bool {
+	return rw.OwnerID == 0
+}
+
+// EntryName returns the file name of the workflow, it's used as the workflow ID of the runs
+func (rw *ActionRequiredWorkflow) EntryName() string {
+	return rw.WorkflowPath[strings.LastIndex(rw.WorkflowPath, "/")+1:]
+}
+
+func (rw *ActionRequiredWorkflow) LoadRepo(ctx context.Context) error {
+	if rw.Repo != nil {
+		return nil
+	}
+	repo, err := repo_model.GetRepositoryByID(ctx, rw.RepoID)
+	if err != nil {
+		return err
+	}
+	rw.Repo = repo
+	return nil
+}
+
+// AppliesTo returns whether the workflow is required for the repository
+func (rw *ActionRequiredWorkflow) AppliesTo(repo *repo_model.Repository) bool {
+	if repo.ID == rw.RepoID {
+		// the central repository runs its own workflows
+		return false
+	}
+	if !rw.IsGlobal() && repo.OwnerID != rw.OwnerID {
+		return false
+	}
+	if len(rw.RepoPatterns) == 0 && len(rw.Topics) == 0 {
+		return true
+	}
+
+	name := repo.LowerName
+	if rw.IsGlobal() {
+		name = strings.ToLower(repo.OwnerName) + "/" + repo.LowerName
+	}
+	for _, pattern := range rw.RepoPatterns {
+		g, err := glob.Compile(strings.ToLower(pattern), '/')
+		if err != nil {
+			log.Warn("Invalid required workflow repository pattern %q: %v", pattern, err)
+			continue
+		}
+		if g.Match(name) {
+			return true
+		}
+	}
+	for _, topic := range rw.Topics {
+		if slices.Contains(repo.Topics, topic) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateRepoPatterns checks whether all the patterns are valid glob patterns
+func ValidateRepoPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := glob.Compile(pattern, '/'); err != nil {
+			return util.NewInvalidArgumentErrorf("invalid pattern %q: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+type FindRequiredWorkflowsOptions struct {
+	db.ListOptions
+	OwnerIDs []int64 // 0 means instance-level
+	RepoID   int64
+}
+
+func (opts FindRequiredWorkflowsOptions) ToConds() builder.Cond {
+	cond := builder.NewCond()
+	if len(opts.OwnerIDs) > 0 {
+		cond = cond.And(builder.In("owner_id", opts.OwnerIDs))
+	}
+	if opts.RepoID > 0 {
+		cond = cond.And(builder.Eq{"repo_id": opts.RepoID})
+	}
+	return cond
+}
+
+func (opts FindRequiredWorkflowsOptions) ToOrders() string {
+	return "id ASC"
+}
+
+// GetRequiredWorkflowByID returns the required workflow of the owner, 0 means instance-level
+func GetRequiredWorkflowByID(ctx context.Context, ownerID, id int64) (*ActionRequiredWorkflow, error) {
+	var rw ActionRequiredWorkflow
+	has, err := db.GetEngine(ctx).Where("id=? AND owner_id=?", id, ownerID).Get(&rw)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, fmt.Errorf("required workflow with id %d: %w", id, util.ErrNotExist)
+	}
+	return &rw, nil
+}
+
+// GetRequiredWorkflowsForRepo returns the instance-level and org-level required workflows applied to the repository
+func GetRequiredWorkflowsForRepo(ctx context.Context, repo *repo_model.Repository) ([]*ActionRequiredWorkflow, error) {
+	rws, err := db.Find[ActionRequiredWorkflow](ctx, FindRequiredWorkflowsOptions{
+		OwnerIDs: []int64{0, repo.OwnerID},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(rws, func(rw *ActionRequiredWorkflow) bool {
+		return !rw.AppliesTo(repo)
+	}), nil
+}
+
+// CreateRequiredWorkflow inserts a new required workflow
+func CreateRequiredWorkflow(ctx context.Context, rw *ActionRequiredWorkflow) error {
+	return db.Insert(ctx, rw)
+}
+
+// DeleteRequiredWorkflow deletes the required workflow of the owner, 0 means instance-level
+func DeleteRequiredWorkflow(ctx context.Context, ownerID, id int64) error {
+	n, err := db.GetEngine(ctx).Where("id=? AND owner_id=?", id, ownerID).Delete(new(ActionRequiredWorkflow))
+	if err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("required workflow with id %d: %w", id, util.ErrNotExist)
+	}
+	return nil
+}
+
+// GetLatestRequiredWorkflowRuns returns the latest run of each required workflow on the commit of the repository
+func GetLatestRequiredWorkflowRuns(ctx context.Context, repoID int64, commitSHA string) ([]*ActionRun, error) {
+	var runs []*ActionRun
+	if err := db.GetEngine(ctx).
+		Where("repo_id=? AND commit_sha=? AND required_workflow_id>0", repoID, commitSHA).
+		Desc("id").
+		Find(&runs); err != nil {
+		return nil, err
+	}
+	seen := make(map[int64]bool, len(runs))
+	return slices.DeleteFunc(runs, func(run *ActionRun) bool {
+		if seen[run.RequiredWorkflowID] {
+			return true
+		}
+		seen[run.RequiredWorkflowID] = true
+		return false
+	}), nil
+}
@@ -0,0 +1,80 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"testing"
+
+	"github.com/kumose/kmup/models/db"
+	repo_model "github.com/kumose/kmup/models/repo"
+	"github.com/kumose/kmup/models/unittest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequiredWorkflowAppliesTo(t *testing.T) {
+	repo := &repo_model.Repository{ID: 10, OwnerID: 3, OwnerName: "Org3", LowerName: "service-api", Topics: []string{"go", "backend"}}
+
+	cases := []struct {
+		name    string
+		rw      ActionRequiredWorkflow
+		applies bool
+	}{
+		{"org all repos", ActionRequiredWorkflow{OwnerID: 3, RepoID: 1}, true},
+		{"other org", ActionRequiredWorkflow{OwnerID: 4, RepoID: 1}, false},
+		{"central repo", ActionRequiredWorkflow{OwnerID: 3, RepoID: 10}, false},
+		{"org pattern", ActionRequiredWorkflow{OwnerID: 3, RepoID: 1, RepoPatterns: []string{"service-*"}}, true},
+		{"org pattern not matched", ActionRequiredWorkflow{OwnerID: 3, RepoID: 1, RepoPatterns: []string{"web-*"}}, false},
+		{"org topic", ActionRequiredWorkflow{OwnerID: 3, RepoID: 1, RepoPatterns: []string{"web-*"}, Topics: []string{"backend"}}, true},
+		{"org topic not matched", ActionRequiredWorkflow{OwnerID: 3, RepoID: 1, Topics: []string{"frontend"}}, false},
+		{"global all repos", ActionRequiredWorkflow{RepoID: 1}, true},
+		{"global full name pattern", ActionRequiredWorkflow{RepoID: 1, RepoPatterns: []string{"org3/*"}}, true},
+		{"global name only pattern", ActionRequiredWorkflow{RepoID: 1, RepoPatterns: []string{"service-*"}}, false},
+		{"invalid pattern", ActionRequiredWorkflow{RepoID: 1, RepoPatterns: []string{"["}}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.applies, c.rw.AppliesTo(repo))
+		})
+	}
+
+	assert.NoError(t, ValidateRepoPatterns([]string{"org3/*", "service-?"}))
+	assert.Error(t, ValidateRepoPatterns([]string{"["}))
+	assert.Equal(t, "ci.yml", (&ActionRequiredWorkflow{WorkflowPath: ".kmup/workflows/ci.yml"}).EntryName())
+}
+
+func TestGetLatestRequiredWorkflowRuns(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	const sha = "c2d72f548424103f01ee1dc02889c1e2bff816b0"
+	for _, run := range []*ActionRun{
+		{RepoID: 4, Index: 1001, RequiredWorkflowID: 1, CommitSHA: sha, Status: StatusFailure},
+		{RepoID: 4, Index: 1002, RequiredWorkflowID: 1, CommitSHA: sha, Status: StatusSuccess},
+		{RepoID: 4, Index: 1003, RequiredWorkflowID: 2, CommitSHA: sha, Status: StatusRunning},
+		{RepoID: 4, Index: 1004, RequiredWorkflowID: 2, CommitSHA: "other", Status: StatusSuccess},
+	} {
+		require.NoError(t, db.Insert(t.Context(), run))
+	}
+
+	runs, err := GetLatestRequiredWorkflowRuns(t.Context(), 4, sha)
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.EqualValues(t, 1003, runs[0].Index)
+	assert.EqualValues(t, 1002, runs[1].Index)
+}
@@ -42,30 +42,33 @@ import (
 
 // ActionRun represents a run of a workflow file
 type ActionRun struct {
-	ID                int64
-	Title             string
-	RepoID            int64                  `xorm:"unique(repo_index) index(repo_concurrency)"`
-	Repo              *repo_model.Repository `xorm:"-"`
-	OwnerID           int64                  `xorm:"index"`
-	WorkflowID        string                 `xorm:"index"`                    // the name of workflow file
-	Index             int64                  `xorm:"index unique(repo_index)"` // a unique number for each run of a repository
-	TriggerUserID     int64                  `xorm:"index"`
-	TriggerUser       *user_model.User       `xorm:"-"`
-	ScheduleID        int64
-	Ref               string `xorm:"index"` // the commit/tag/… that caused the run
-	IsRefDeleted      bool   `xorm:"-"`
-	CommitSHA         string
-	IsForkPullRequest bool                         // If this is triggered by a PR from a forked repository or an untrusted user, we need to check if it is approved and limit permissions when running the workflow.
-	NeedApproval      bool                         // may need approval if it's a fork pull request
-	ApprovedBy        int64                        `xorm:"index"` // who approved
-	Event             webhook_module.HookEventType // the webhook event that causes the workflow to run
-	EventPayload      string                       `xorm:"LONGTEXT"`
-	TriggerEvent      string                       // the trigger event defined in the `on` configuration of the triggered workflow
-	Status            Status                       `xorm:"index"`
-	Version           int                          `xorm:"version default 0"` // Status could be updated concomitantly, so an optimistic lock is needed
-	RawConcurrency    string                       // raw concurrency
-	ConcurrencyGroup  string                       `xorm:"index(repo_concurrency) NOT NULL DEFAULT ''"`
-	ConcurrencyCancel bool                         `xorm:"NOT NULL DEFAULT FALSE"`
+	ID                 int64
+	Title              string
+	RepoID             int64                  `xorm:"unique(repo_index) index(repo_concurrency)"`
+	Repo               *repo_model.Repository `xorm:"-"`
+	OwnerID            int64                  `xorm:"index"`
+	WorkflowID         string                 `xorm:"index"`                    // the name of workflow file
+	Index              int64                  `xorm:"index unique(repo_index)"` // a unique number for each run of a repository
+	TriggerUserID      int64                  `xorm:"index"`
+	TriggerUser        *user_model.User       `xorm:"-"`
+	ScheduleID         int64
+	RequiredWorkflowID int64  `xorm:"index NOT NULL DEFAULT 0"` // the ID of the org- or instance-level required workflow which the run is created for
+	Ref                string `xorm:"index"`                    // the commit/tag/… that caused the run
+	IsRefDeleted       bool   `xorm:"-"`
+	CommitSHA          string
+	IsForkPullRequest  bool                         // If this is triggered by a PR from a forked repository or an untrusted user, we need to check if it is approved and limit permissions when running the workflow.
+	NeedApproval       bool                         // may need approval if it's a fork pull request
+	ApprovedBy         int64                        `xorm:"index"` // who approved
+	Event              webhook_module.HookEventType // the webhook event that causes the workflow to run
+	EventPayload       string                       `xorm:"LONGTEXT"`
+	TriggerEvent       string                       // the trigger event defined in the `on` configuration of the triggered workflow
+	Status             Status                       `xorm:"index"`
+	Version            int                          `xorm:"version default 0"` // Status could be updated concomitantly, so an optimistic lock is needed
+	RawConcurrency     string                       // raw concurrency
+	ConcurrencyGroup   string                       `xorm:"index(repo_concurrency) NOT NULL DEFAULT ''"`
+	ConcurrencyCancel  bool                         `xorm:"NOT NULL DEFAULT FALSE"`
+	// Attempt starts at 1 and is increased every time the run is rerun
+	Attempt int64 `xorm:"NOT NULL DEFAULT 1"`
 	// Started and Stopped is used for recording last run time, if rerun happened, they will be reset to 0
 	Started timeutil.TimeStamp
 	Stopped timeutil.TimeStamp
@@ -243,7 +246,7 @@ func CancelPreviousJobs(ctx context.Context, repoID int64, ref, workflowID strin
 		Ref:          ref,
 		WorkflowID:   workflowID,
 		TriggerEvent: event,
-		Status:       []Status{StatusRunning, StatusWaiting, StatusBlocked},
+		Status:       []Status{StatusRunning, StatusWaiting, StatusBlocked, StatusPendingApproval},
 	})
 	if err != nil {
 		return nil, err
@@ -445,7 +448,7 @@ func CancelPreviousJobsByRunConcurrency(ctx context.Context, actionRun *ActionRu
 
 	var jobsToCancel []*ActionRunJob
 
-	statusFindOption := []Status{StatusWaiting, StatusBlocked}
+	statusFindOption := []Status{StatusWaiting, StatusBlocked, StatusPendingApproval}
 	if actionRun.ConcurrencyCancel {
 		statusFindOption = append(statusFindOption, StatusRunning)
 	}
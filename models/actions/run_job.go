@@ -44,6 +44,8 @@ type ActionRunJob struct {
 	IsForkPullRequest bool
 	Name              string `xorm:"VARCHAR(255)"`
 	Attempt           int64
+	// DebugLogging enables the debug logs of the runner and the steps, it is set when the job is rerun with debug logging
+	DebugLogging bool `xorm:"NOT NULL DEFAULT false"`
 
 	// WorkflowPayload is act/jobparser.SingleWorkflow for act/jobparser.Parse
 	// it should contain exactly one job with global workflow fields for this model
@@ -55,6 +57,20 @@ type ActionRunJob struct {
 	TaskID int64    // the latest task of the job
 	Status Status   `xorm:"index"`
 
+	// ParentJobID is the ID of the job which calls the reusable workflow this job belongs to.
+	// It is 0 for the jobs of the triggered workflow.
+	ParentJobID int64 `xorm:"index NOT NULL DEFAULT 0"`
+	// UsesWorkflow is the `uses` of a job calling a reusable workflow.
+	// Such a job is never picked by runners, it is expanded into child jobs by the job emitter instead.
+	UsesWorkflow string `xorm:"TEXT"`
+	// WorkflowCallInputs are the evaluated inputs passed to the called workflow, only valid when UsesWorkflow is not empty
+	WorkflowCallInputs map[string]any `xorm:"JSON TEXT"`
+
+	// Environment is the name of the deployment environment of the job, it may contain expressions evaluated when the job runs
+	Environment string `xorm:"TEXT"`
+	// EnvironmentID is the ID of the environment the job deploys to, it is set after the protection rules of the environment are checked
+	EnvironmentID int64 `xorm:"NOT NULL DEFAULT 0"`
+
 	RawConcurrency string // raw concurrency from job YAML's "concurrency" section
 
 	// IsConcurrencyEvaluated is only valid/needed when this job's RawConcurrency is not empty.
@@ -79,6 +95,17 @@ func (job *ActionRunJob) Duration() time.Duration {
 	return calculateDuration(job.Started, job.Stopped, job.Status)
 }
 
+// IsReusableWorkflowCaller returns true if the job calls a reusable workflow instead of running steps
+func (job *ActionRunJob) IsReusableWorkflowCaller() bool {
+	return job.UsesWorkflow != ""
+}
+
+// RequiresJobEmitter returns true if the job can't be set to waiting directly, the job emitter has to check it:
+// a job calling a reusable workflow is expanded and a job with an environment is checked against the protection rules.
+func (job *ActionRunJob) RequiresJobEmitter() bool {
+	return job.IsReusableWorkflowCaller() || job.Environment != ""
+}
+
 func (job *ActionRunJob) LoadRun(ctx context.Context) error {
 	if job.Run == nil {
 		run, err := GetRunByRepoAndID(ctx, job.RepoID, job.RunID)
@@ -216,7 +243,7 @@ func UpdateRunJob(ctx context.Context, job *ActionRunJob, cond builder.Cond, col
 func AggregateJobStatus(jobs []*ActionRunJob) Status {
 	allSuccessOrSkipped := len(jobs) != 0
 	allSkipped := len(jobs) != 0
-	var hasFailure, hasCancelled, hasWaiting, hasRunning, hasBlocked bool
+	var hasFailure, hasCancelled, hasWaiting, hasRunning, hasBlocked, hasPendingApproval bool
 	for _, job := range jobs {
 		allSuccessOrSkipped = allSuccessOrSkipped && (job.Status == StatusSuccess || job.Status == StatusSkipped)
 		allSkipped = allSkipped && job.Status == StatusSkipped
@@ -225,6 +252,7 @@ func AggregateJobStatus(jobs []*ActionRunJob) Status {
 		hasWaiting = hasWaiting || job.Status == StatusWaiting
 		hasRunning = hasRunning || job.Status == StatusRunning
 		hasBlocked = hasBlocked || job.Status == StatusBlocked
+		hasPendingApproval = hasPendingApproval || job.Status == StatusPendingApproval
 	}
 	switch {
 	case allSkipped:
@@ -237,6 +265,8 @@ func AggregateJobStatus(jobs []*ActionRunJob) Status {
 		return StatusRunning
 	case hasWaiting:
 		return StatusWaiting
+	case hasPendingApproval:
+		return StatusPendingApproval
 	case hasFailure:
 		return StatusFailure
 	case hasBlocked:
@@ -257,7 +287,7 @@ func CancelPreviousJobsByJobConcurrency(ctx context.Context, job *ActionRunJob)
 		return nil, nil
 	}
 
-	statusFindOption := []Status{StatusWaiting, StatusBlocked}
+	statusFindOption := []Status{StatusWaiting, StatusBlocked, StatusPendingApproval}
 	if job.ConcurrencyCancel {
 		statusFindOption = append(statusFindOption, StatusRunning)
 	}
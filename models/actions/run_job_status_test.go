@@ -91,6 +91,15 @@ func TestAggregateJobStatus(t *testing.T) {
 		{[]Status{StatusSkipped, StatusWaiting}, StatusWaiting},
 		{[]Status{StatusSkipped, StatusRunning}, StatusRunning},
 		{[]Status{StatusSkipped, StatusBlocked}, StatusBlocked},
+
+		// pending approval waits for the reviewers of an environment, running or waiting jobs win over it
+		{[]Status{StatusPendingApproval}, StatusPendingApproval},
+		{[]Status{StatusPendingApproval, StatusSuccess}, StatusPendingApproval},
+		{[]Status{StatusPendingApproval, StatusFailure}, StatusPendingApproval},
+		{[]Status{StatusPendingApproval, StatusCancelled}, StatusCancelled},
+		{[]Status{StatusPendingApproval, StatusWaiting}, StatusWaiting},
+		{[]Status{StatusPendingApproval, StatusRunning}, StatusRunning},
+		{[]Status{StatusPendingApproval, StatusBlocked}, StatusPendingApproval},
 	}
 
 	for _, c := range cases {
@@ -63,6 +63,8 @@ type ActionRunner struct {
 	Description string                 `xorm:"TEXT"`
 	Base        int                    // 0 native 1 docker 2 virtual machine
 	RepoRange   string                 // glob match which repositories could use this runner
+	GroupID     int64                  `xorm:"index NOT NULL DEFAULT 0"` // the runner group of a global runner, 0 means the runner isn't in a group
+	Group       *ActionRunnerGroup     `xorm:"-"`
 
 	Token     string `xorm:"-"`
 	TokenHash string `xorm:"UNIQUE"` // sha256 of token
@@ -183,6 +185,19 @@ func (r *ActionRunner) LoadAttributes(ctx context.Context) error {
 	return nil
 }
 
+// LoadGroup loads the runner group of the runner, it returns an error if the group doesn't exist, so the runner isn't used without the restrictions of its group
+func (r *ActionRunner) LoadGroup(ctx context.Context) error {
+	if r.GroupID == 0 || r.Group != nil {
+		return nil
+	}
+	group, err := GetRunnerGroupByID(ctx, r.GroupID)
+	if err != nil {
+		return err
+	}
+	r.Group = group
+	return nil
+}
+
 func (r *ActionRunner) GenerateToken() (err error) {
 	r.Token, r.TokenSalt, r.TokenHash, _, err = generateSaltedToken()
 	return err
@@ -210,6 +225,7 @@ type FindRunnerOptions struct {
 	IDs           []int64
 	RepoID        int64
 	OwnerID       int64 // it will be ignored if RepoID is set
+	GroupID       int64
 	Sort          string
 	Filter        string
 	IsOnline      optional.Option[bool]
@@ -242,6 +258,10 @@ func (opts FindRunnerOptions) ToConds() builder.Cond {
 		cond = cond.And(c)
 	}
 
+	if opts.GroupID > 0 {
+		cond = cond.And(builder.Eq{"group_id": opts.GroupID})
+	}
+
 	if opts.Filter != "" {
 		cond = cond.And(builder.Like{"name", opts.Filter})
 	}
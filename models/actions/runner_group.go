@@ -0,0 +1,317 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/kumose/kmup/models/db"
+	repo_model "github.com/kumose/kmup/models/repo"
+	user_model "github.com/kumose/kmup/models/user"
+	"github.com/kumose/kmup/modules/git"
+	"github.com/kumose/kmup/modules/timeutil"
+	"github.com/kumose/kmup/modules/util"
+
+	"xorm.io/builder"
+)
+
+// ActionRunnerGroup represents a named set of global runners which can only run the jobs of the allowed owners, repositories and workflows.
+//
+// The repositories of the allowed owners and the allowed repositories can use the runners of the group,
+// all repositories can use them if both lists are empty.
+// The allowed workflows further limit the jobs to the ones of the workflow files, they are qualified by the repositories
+// and optionally the refs, like "org/deploy/.kmup/workflows/deploy.yml@main", so the jobs of the reusable workflows
+// are matched by the called workflows rather than by the callers.
+// The global runners without a group could be used by all repositories.
+type ActionRunnerGroup struct {
+	ID               int64
+	Name             string   `xorm:"VARCHAR(255) UNIQUE NOT NULL"`
+	Description      string   `xorm:"TEXT"`
+	AllowedOwnerIDs  []int64  `xorm:"JSON TEXT"`
+	AllowedRepoIDs   []int64  `xorm:"JSON TEXT"`
+	AllowedWorkflows []string `xorm:"JSON TEXT"`
+
+	Created timeutil.TimeStamp `xorm:"created"`
+	Updated timeutil.TimeStamp `xorm:"updated"`
+}
+
+func init() {
+	db.RegisterModel(new(ActionRunnerGroup))
+}
+
+// AllowsAllRepos returns whether all repositories can use the runners of the group
+func (g *ActionRunnerGroup) AllowsAllRepos() bool {
+	return len(g.AllowedOwnerIDs) == 0 && len(g.AllowedRepoIDs) == 0
+}
+
+// GetAllowedNames returns the names of the allowed owners and the full names of the allowed repositories, the deleted ones are omitted
+func (g *ActionRunnerGroup) GetAllowedNames(ctx context.Context) (owners, repos []string, err error) {
+	owners = make([]string, 0, len(g.AllowedOwnerIDs))
+	repos = make([]string, 0, len(g.AllowedRepoIDs))
+	if len(g.AllowedOwnerIDs) > 0 {
+		users, err := user_model.GetUsersMapByIDs(ctx, g.AllowedOwnerIDs)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, id := range g.AllowedOwnerIDs {
+			if u, ok := users[id]; ok {
+				owners = append(owners, u.Name)
+			}
+		}
+	}
+	if len(g.AllowedRepoIDs) > 0 {
+		repoMap, err := repo_model.GetRepositoriesMapByIDs(ctx, g.AllowedRepoIDs)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, id := range g.AllowedRepoIDs {
+			if repo, ok := repoMap[id]; ok {
+				repos = append(repos, repo.FullName())
+			}
+		}
+	}
+	return owners, repos, nil
+}
+
+// jobCond returns the condition of the jobs of the allowed owners and repositories, the allowed workflows are checked by AllowsJob
+func (g *ActionRunnerGroup) jobCond() builder.Cond {
+	cond := builder.NewCond()
+	if !g.AllowsAllRepos() {
+		cond = cond.And(builder.In("owner_id", g.AllowedOwnerIDs).Or(builder.In("repo_id", g.AllowedRepoIDs)))
+	}
+	return cond
+}
+
+// QualifiedWorkflow is a workflow file qualified by its repository and optionally its ref
+type QualifiedWorkflow struct {
+	OwnerName string
+	RepoName  string
+	Path      string
+	Ref       string // any ref if it is empty
+}
+
+// ParseQualifiedWorkflow parses a workflow in the form of "owner/repo/path" or "owner/repo/path@ref",
+// the path isn't checked to be in a workflows directory.
+func ParseQualifiedWorkflow(workflow string) (*QualifiedWorkflow, bool) {
+	target, ref, _ := strings.Cut(workflow, "@")
+	parts := strings.SplitN(target, "/", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return nil, false
+	}
+	return &QualifiedWorkflow{OwnerName: parts[0], RepoName: parts[1], Path: parts[2], Ref: ref}, true
+}
+
+// AllowsJob returns whether the runners of the group can run the job according to the allowed workflows
+func (g *ActionRunnerGroup) AllowsJob(ctx context.Context, job *ActionRunJob) (bool, error) {
+	if len(g.AllowedWorkflows) == 0 {
+		return true, nil
+	}
+	source, err := getJobWorkflowSource(ctx, job)
+	if err != nil {
+		return false, err
+	}
+	for _, workflow := range g.AllowedWorkflows {
+		allowed, ok := ParseQualifiedWorkflow(workflow)
+		if !ok {
+			continue
+		}
+		if !strings.EqualFold(allowed.OwnerName, source.OwnerName) || !strings.EqualFold(allowed.RepoName, source.RepoName) {
+			continue
+		}
+		// the runs only know the file names of their workflows, which can be in either workflows directory
+		if strings.Contains(source.Path, "/") {
+			if allowed.Path != source.Path {
+				continue
+			}
+		} else if path.Base(allowed.Path) != source.Path {
+			continue
+		}
+		if allowed.Ref == "" || git.RefName(allowed.Ref).ShortName() == git.RefName(source.Ref).ShortName() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// getJobWorkflowSource returns the repository, the path and the ref of the workflow file which the job is defined in.
+// The path is only the file name for the jobs of the workflows of the runs.
+func getJobWorkflowSource(ctx context.Context, job *ActionRunJob) (*QualifiedWorkflow, error) {
+	if err := job.LoadRun(ctx); err != nil {
+		return nil, err
+	}
+	run := job.Run
+	if err := run.LoadRepo(ctx); err != nil {
+		return nil, err
+	}
+
+	if job.ParentJobID > 0 {
+		caller, err := GetRunJobByID(ctx, job.ParentJobID)
+		if err != nil {
+			return nil, err
+		}
+		// a called workflow in the same repository is loaded from the commit of the run
+		if localPath, ok := strings.CutPrefix(caller.UsesWorkflow, "./"); ok {
+			return &QualifiedWorkflow{OwnerName: run.Repo.OwnerName, RepoName: run.Repo.Name, Path: localPath, Ref: run.Ref}, nil
+		}
+		source, ok := ParseQualifiedWorkflow(caller.UsesWorkflow)
+		if !ok {
+			return nil, fmt.Errorf("invalid reusable workflow %q of job %d", caller.UsesWorkflow, caller.ID)
+		}
+		return source, nil
+	}
+
+	if run.RequiredWorkflowID > 0 {
+		rw, has, err := db.GetByID[ActionRequiredWorkflow](ctx, run.RequiredWorkflowID)
+		if err != nil {
+			return nil, err
+		} else if !has {
+			return nil, fmt.Errorf("required workflow with id %d: %w", run.RequiredWorkflowID, util.ErrNotExist)
+		}
+		if err := rw.LoadRepo(ctx); err != nil {
+			return nil, err
+		}
+		return &QualifiedWorkflow{
+			OwnerName: rw.Repo.OwnerName,
+			RepoName:  rw.Repo.Name,
+			Path:      rw.WorkflowPath,
+			Ref:       util.IfZero(rw.Ref, rw.Repo.DefaultBranch),
+		}, nil
+	}
+
+	return &QualifiedWorkflow{
+		OwnerName: run.Repo.OwnerName,
+		RepoName:  run.Repo.Name,
+		Path:      run.WorkflowID,
+		Ref:       run.Ref,
+	}, nil
+}
+
+// GetRunnerGroupByID returns a runner group by its ID
+func GetRunnerGroupByID(ctx context.Context, id int64) (*ActionRunnerGroup, error) {
+	group, has, err := db.GetByID[ActionRunnerGroup](ctx, id)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, fmt.Errorf("runner group with id %d: %w", id, util.ErrNotExist)
+	}
+	return group, nil
+}
+
+// GetRunnerGroupByName returns a runner group by its name
+func GetRunnerGroupByName(ctx context.Context, name string) (*ActionRunnerGroup, error) {
+	var group ActionRunnerGroup
+	has, err := db.GetEngine(ctx).Where("name=?", name).Get(&group)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, fmt.Errorf("runner group with name %s: %w", name, util.ErrNotExist)
+	}
+	return &group, nil
+}
+
+// FindRunnerGroups returns all runner groups ordered by name
+func FindRunnerGroups(ctx context.Context) ([]*ActionRunnerGroup, error) {
+	groups := make([]*ActionRunnerGroup, 0, 10)
+	return groups, db.GetEngine(ctx).Asc("name").Find(&groups)
+}
+
+// CreateRunnerGroup creates a runner group, the name must be unique
+func CreateRunnerGroup(ctx context.Context, group *ActionRunnerGroup) error {
+	group.Name = util.EllipsisDisplayString(group.Name, 255)
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		if has, err := db.GetEngine(ctx).Where("name=?", group.Name).Exist(new(ActionRunnerGroup)); err != nil {
+			return err
+		} else if has {
+			return util.NewAlreadyExistErrorf("runner group %s already exists", group.Name)
+		}
+		return db.Insert(ctx, group)
+	})
+}
+
+// UpdateRunnerGroup updates a runner group, the name must be unique
+func UpdateRunnerGroup(ctx context.Context, group *ActionRunnerGroup) error {
+	group.Name = util.EllipsisDisplayString(group.Name, 255)
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		if has, err := db.GetEngine(ctx).Where("name=? AND id<>?", group.Name, group.ID).Exist(new(ActionRunnerGroup)); err != nil {
+			return err
+		} else if has {
+			return util.NewAlreadyExistErrorf("runner group %s already exists", group.Name)
+		}
+		_, err := db.GetEngine(ctx).ID(group.ID).
+			Cols("name", "description", "allowed_owner_ids", "allowed_repo_ids", "allowed_workflows").
+			Update(group)
+		return err
+	})
+}
+
+// DeleteRunnerGroup deletes a runner group which has no runners.
+// The runners must be removed from the group first, so they aren't made available to all repositories by accident.
+func DeleteRunnerGroup(ctx context.Context, id int64) error {
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		if _, err := GetRunnerGroupByID(ctx, id); err != nil {
+			return err
+		}
+		count, err := db.GetEngine(ctx).Where("group_id=?", id).Count(new(ActionRunner))
+		if err != nil {
+			return err
+		} else if count > 0 {
+			return util.NewInvalidArgumentErrorf("runner group still has %d runners", count)
+		}
+		_, err = db.DeleteByID[ActionRunnerGroup](ctx, id)
+		return err
+	})
+}
+
+// SetRunnerGroup moves a global runner into a runner group, or out of its group if groupID is 0
+func SetRunnerGroup(ctx context.Context, runner *ActionRunner, groupID int64) error {
+	if runner.OwnerID != 0 || runner.RepoID != 0 {
+		return util.NewInvalidArgumentErrorf("only global runners can be added to runner groups")
+	}
+	if groupID != 0 {
+		if _, err := GetRunnerGroupByID(ctx, groupID); err != nil {
+			return err
+		}
+	}
+	runner.GroupID = groupID
+	runner.Group = nil
+	return UpdateRunner(ctx, runner, "group_id")
+}
+
+// CountRunnersOfGroups returns the number of runners of each runner group
+func CountRunnersOfGroups(ctx context.Context) (map[int64]int64, error) {
+	var rows []struct {
+		GroupID int64
+		Count   int64
+	}
+	if err := db.GetEngine(ctx).Table("action_runner").
+		Where(builder.Neq{"group_id": 0}).
+		And(builder.Or(builder.IsNull{"deleted"}, builder.Eq{"deleted": 0})).
+		GroupBy("group_id").
+		Select("group_id, COUNT(*) AS count").
+		Find(&rows); err != nil {
+		return nil, err
+	}
+	counts := make(map[int64]int64, len(rows))
+	for _, row := range rows {
+		counts[row.GroupID] = row.Count
+	}
+	return counts, nil
+}
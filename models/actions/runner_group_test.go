@@ -0,0 +1,135 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"testing"
+
+	"github.com/kumose/kmup/models/db"
+	"github.com/kumose/kmup/models/unittest"
+	"github.com/kumose/kmup/modules/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunnerGroups(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	group := &ActionRunnerGroup{Name: "deploy", AllowedRepoIDs: []int64{1}}
+	require.NoError(t, CreateRunnerGroup(t.Context(), group))
+	assert.ErrorIs(t, CreateRunnerGroup(t.Context(), &ActionRunnerGroup{Name: "deploy"}), util.ErrAlreadyExist)
+
+	other := &ActionRunnerGroup{Name: "other"}
+	require.NoError(t, CreateRunnerGroup(t.Context(), other))
+	other.Name = "deploy"
+	assert.ErrorIs(t, UpdateRunnerGroup(t.Context(), other), util.ErrAlreadyExist)
+
+	// only global runners can be added to groups
+	orgRunner := unittest.AssertExistsAndLoadBean(t, &ActionRunner{ID: 34347})
+	assert.ErrorIs(t, SetRunnerGroup(t.Context(), orgRunner, group.ID), util.ErrInvalidArgument)
+
+	runner := unittest.AssertExistsAndLoadBean(t, &ActionRunner{ID: 34349})
+	assert.ErrorIs(t, SetRunnerGroup(t.Context(), runner, 12345), util.ErrNotExist)
+	require.NoError(t, SetRunnerGroup(t.Context(), runner, group.ID))
+
+	counts, err := CountRunnersOfGroups(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, map[int64]int64{group.ID: 1}, counts)
+
+	runners, err := db.Find[ActionRunner](t.Context(), FindRunnerOptions{GroupID: group.ID})
+	require.NoError(t, err)
+	if assert.Len(t, runners, 1) {
+		assert.EqualValues(t, 34349, runners[0].ID)
+	}
+
+	// a group with runners can't be deleted
+	assert.ErrorIs(t, DeleteRunnerGroup(t.Context(), group.ID), util.ErrInvalidArgument)
+	require.NoError(t, SetRunnerGroup(t.Context(), runner, 0))
+	require.NoError(t, DeleteRunnerGroup(t.Context(), group.ID))
+	_, err = GetRunnerGroupByID(t.Context(), group.ID)
+	assert.ErrorIs(t, err, util.ErrNotExist)
+}
+
+func TestCreateTaskForRunnerInGroup(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	// run 791 belongs to repo 4 of user 1, and its workflow is artifact.yaml
+	job := &ActionRunJob{
+		RunID:   791,
+		RepoID:  4,
+		OwnerID: 1,
+		Name:    "deploy",
+		JobID:   "deploy",
+		RunsOn:  []string{"runner-group-test"},
+		Status:  StatusWaiting,
+		WorkflowPayload: []byte(`
+name: test
+on: push
+jobs:
+  deploy:
+    runs-on: runner-group-test
+    steps:
+      - run: echo deploy
+`),
+	}
+	require.NoError(t, db.Insert(t.Context(), job))
+
+	runner := unittest.AssertExistsAndLoadBean(t, &ActionRunner{ID: 34349})
+	runner.AgentLabels = []string{"runner-group-test"}
+
+	pick := func(group *ActionRunnerGroup) bool {
+		require.NoError(t, CreateRunnerGroup(t.Context(), group))
+		require.NoError(t, SetRunnerGroup(t.Context(), runner, group.ID))
+		task, ok, err := CreateTaskForRunner(t.Context(), runner)
+		require.NoError(t, err)
+		return ok && task.JobID == job.ID
+	}
+
+	assert.False(t, pick(&ActionRunnerGroup{Name: "other-repo", AllowedRepoIDs: []int64{1}}))
+	assert.False(t, pick(&ActionRunnerGroup{Name: "other-workflow", AllowedOwnerIDs: []int64{1}, AllowedWorkflows: []string{"user5/repo4/.kmup/workflows/deploy.yml"}}))
+	assert.False(t, pick(&ActionRunnerGroup{Name: "other-workflow-repo", AllowedWorkflows: []string{"user2/repo1/.kmup/workflows/artifact.yaml"}}))
+	assert.False(t, pick(&ActionRunnerGroup{Name: "other-ref", AllowedWorkflows: []string{"user5/repo4/.kmup/workflows/artifact.yaml@release"}}))
+	assert.True(t, pick(&ActionRunnerGroup{Name: "allowed", AllowedOwnerIDs: []int64{1}, AllowedWorkflows: []string{"user5/repo4/.kmup/workflows/artifact.yaml@master"}}))
+
+	// the jobs of a called workflow are matched by the called workflow rather than the caller
+	caller := &ActionRunJob{
+		RunID:        791,
+		RepoID:       4,
+		OwnerID:      1,
+		Name:         "call",
+		JobID:        "call",
+		Status:       StatusRunning,
+		UsesWorkflow: "org3/repo3/.kmup/workflows/deploy.yml@main",
+	}
+	require.NoError(t, db.Insert(t.Context(), caller))
+	job = &ActionRunJob{
+		RunID:           791,
+		RepoID:          4,
+		OwnerID:         1,
+		Name:            "deploy",
+		JobID:           "deploy",
+		ParentJobID:     caller.ID,
+		RunsOn:          []string{"runner-group-test"},
+		Status:          StatusWaiting,
+		WorkflowPayload: job.WorkflowPayload,
+	}
+	require.NoError(t, db.Insert(t.Context(), job))
+	assert.False(t, pick(&ActionRunnerGroup{Name: "caller", AllowedWorkflows: []string{"user5/repo4/.kmup/workflows/artifact.yaml"}}))
+	assert.True(t, pick(&ActionRunnerGroup{Name: "called", AllowedWorkflows: []string{"org3/repo3/.kmup/workflows/deploy.yml@main"}}))
+}
@@ -50,6 +50,9 @@ type ActionTask struct {
 	Started  timeutil.TimeStamp `xorm:"index"`
 	Stopped  timeutil.TimeStamp `xorm:"index(stopped_log_expired)"`
 
+	// RunAttempt is the attempt of the run which the task belongs to
+	RunAttempt int64 `xorm:"NOT NULL DEFAULT 1"`
+
 	RepoID            int64  `xorm:"index"`
 	OwnerID           int64  `xorm:"index"`
 	CommitSHA         string `xorm:"index"`
@@ -176,6 +179,19 @@ func GetTaskByID(ctx context.Context, id int64) (*ActionTask, error) {
 	return &task, nil
 }
 
+// GetTaskOfJobInRunAttempt returns the latest task of the job which had been created when the run was in the given attempt
+func GetTaskOfJobInRunAttempt(ctx context.Context, jobID, runAttempt int64) (*ActionTask, error) {
+	var task ActionTask
+	has, err := db.GetEngine(ctx).Where("job_id=? AND run_attempt<=?", jobID, runAttempt).Desc("id").Get(&task)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, fmt.Errorf("task of job %d in run attempt %d: %w", jobID, runAttempt, util.ErrNotExist)
+	}
+
+	return &task, nil
+}
+
 func GetRunningTaskByToken(ctx context.Context, token string) (*ActionTask, error) {
 	errNotExist := fmt.Errorf("task with token %q: %w", token, util.ErrNotExist)
 	if token == "" {
@@ -248,6 +264,13 @@ func CreateTaskForRunner(ctx context.Context, runner *ActionRunner) (*ActionTask
 	if jobCond.IsValid() {
 		jobCond = builder.In("run_id", builder.Select("id").From("action_run").Where(jobCond))
 	}
+	if err := runner.LoadGroup(ctx); err != nil {
+		return nil, false, fmt.Errorf("load group of runner %d: %w", runner.ID, err)
+	}
+	if runner.Group != nil {
+		// the runners of a group can only run the jobs of the allowed owners, repositories and workflows
+		jobCond = builder.And(jobCond, runner.Group.jobCond())
+	}
 
 	var jobs []*ActionRunJob
 	if err := e.Where("task_id=? AND status=?", 0, StatusWaiting).And(jobCond).Asc("updated", "id").Find(&jobs); err != nil {
@@ -258,10 +281,21 @@ func CreateTaskForRunner(ctx context.Context, runner *ActionRunner) (*ActionTask
 	var job *ActionRunJob
 	log.Trace("runner labels: %v", runner.AgentLabels)
 	for _, v := range jobs {
-		if runner.CanMatchLabels(v.RunsOn) {
-			job = v
-			break
+		if !runner.CanMatchLabels(v.RunsOn) {
+			continue
 		}
+		if runner.Group != nil {
+			allowed, err := runner.Group.AllowsJob(ctx, v)
+			if err != nil {
+				// don't let a broken job block the other jobs of the group
+				log.Error("Unable to check job %d against runner group %d: %v", v.ID, runner.Group.ID, err)
+				continue
+			} else if !allowed {
+				continue
+			}
+		}
+		job = v
+		break
 	}
 	if job == nil {
 		return nil, false, nil
@@ -278,6 +312,7 @@ func CreateTaskForRunner(ctx context.Context, runner *ActionRunner) (*ActionTask
 	task := &ActionTask{
 		JobID:             job.ID,
 		Attempt:           job.Attempt,
+		RunAttempt:        job.Run.Attempt,
 		RunnerID:          runner.ID,
 		Started:           now,
 		Status:            StatusRunning,
@@ -488,6 +523,17 @@ func FindOldTasksToExpire(ctx context.Context, olderThan timeutil.TimeStamp, lim
 		Find(&tasks)
 }
 
+// FindTaskIDsWithArchivedLogs returns ids of tasks whose logs have been archived and not expired, in ascending order and greater than afterID
+func FindTaskIDsWithArchivedLogs(ctx context.Context, afterID int64, limit int) ([]int64, error) {
+	ids := make([]int64, 0, limit)
+	return ids, db.GetEngine(ctx).Table("action_task").
+		Where("id > ? AND log_in_storage = ? AND log_expired = ?", afterID, true, false).
+		OrderBy("id").
+		Limit(limit).
+		Cols("id").
+		Find(&ids)
+}
+
 func convertTimestamp(timestamp *timestamppb.Timestamp) timeutil.TimeStamp {
 	if timestamp.GetSeconds() == 0 && timestamp.GetNanos() == 0 {
 		return timeutil.TimeStamp(0)
@@ -0,0 +1,107 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"context"
+
+	"github.com/kumose/kmup/models/db"
+	"github.com/kumose/kmup/modules/timeutil"
+)
+
+// AnnotationLevel is the level of an ActionTaskAnnotation, named like the annotation levels of GitHub check runs.
+type AnnotationLevel string
+
+const (
+	AnnotationLevelNotice  AnnotationLevel = "notice"
+	AnnotationLevelWarning AnnotationLevel = "warning"
+	AnnotationLevelFailure AnnotationLevel = "failure"
+)
+
+// MaxAnnotationsPerTask is the max number of annotations stored for a task, the others are dropped like GitHub does.
+const MaxAnnotationsPerTask = 50
+
+// ActionTaskAnnotation represents an annotation created by the "error", "warning" or "notice" workflow commands of a task.
+type ActionTaskAnnotation struct {
+	ID          int64
+	TaskID      int64           `xorm:"index"`
+	JobID       int64           `xorm:"index"`
+	RunID       int64           `xorm:"index"`
+	RepoID      int64           `xorm:"index(repo_commit)"`
+	CommitSHA   string          `xorm:"VARCHAR(64) index(repo_commit)"`
+	LogIndex    int64           // the index of the log line, to find the step of the annotation
+	Level       AnnotationLevel `xorm:"VARCHAR(16)"`
+	Title       string          `xorm:"VARCHAR(255)"`
+	Message     string          `xorm:"TEXT"`
+	Path        string          `xorm:"VARCHAR(500)"` // empty if the annotation isn't bound to a file
+	StartLine   int
+	EndLine     int
+	StartColumn int
+	EndColumn   int
+	Created     timeutil.TimeStamp `xorm:"created"`
+}
+
+func init() {
+	db.RegisterModel(new(ActionTaskAnnotation))
+}
+
+// Line returns the line to show the annotation at, it is the end line of a multi-line annotation.
+func (a *ActionTaskAnnotation) Line() int {
+	if a.EndLine > 0 {
+		return a.EndLine
+	}
+	return a.StartLine
+}
+
+// InsertTaskAnnotations inserts the annotations of a task, the ones exceeding MaxAnnotationsPerTask are dropped.
+func InsertTaskAnnotations(ctx context.Context, taskID int64, annotations []*ActionTaskAnnotation) error {
+	if len(annotations) == 0 {
+		return nil
+	}
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		count, err := db.GetEngine(ctx).Where("task_id=?", taskID).Count(new(ActionTaskAnnotation))
+		if err != nil {
+			return err
+		}
+		left := MaxAnnotationsPerTask - int(count)
+		if left <= 0 {
+			return nil
+		}
+		if len(annotations) > left {
+			annotations = annotations[:left]
+		}
+		return db.Insert(ctx, annotations)
+	})
+}
+
+// FindTaskAnnotations returns the annotations of a task.
+func FindTaskAnnotations(ctx context.Context, taskID int64) ([]*ActionTaskAnnotation, error) {
+	var annotations []*ActionTaskAnnotation
+	return annotations, db.GetEngine(ctx).Where("task_id=?", taskID).OrderBy("id ASC").Find(&annotations)
+}
+
+// FindCommitAnnotations returns the file annotations of the jobs which ran for a commit.
+// Only the annotations of the latest attempts of the jobs are returned, so a rerun replaces the annotations of the previous attempt.
+func FindCommitAnnotations(ctx context.Context, repoID int64, commitSHA string) ([]*ActionTaskAnnotation, error) {
+	var annotations []*ActionTaskAnnotation
+	return annotations, db.GetEngine(ctx).
+		Join("INNER", "action_run_job", "action_run_job.task_id = action_task_annotation.task_id").
+		Where("action_task_annotation.repo_id=? AND action_task_annotation.commit_sha=? AND action_task_annotation.path<>''", repoID, commitSHA).
+		OrderBy("action_task_annotation.id ASC").
+		Find(&annotations)
+}
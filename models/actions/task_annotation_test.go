@@ -0,0 +1,60 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"testing"
+
+	"github.com/kumose/kmup/models/unittest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskAnnotations(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	const commitSHA = "c2d72f548424103f01ee1dc02889c1e2bff816b0"
+	newAnnotation := func(taskID int64, path string) *ActionTaskAnnotation {
+		return &ActionTaskAnnotation{TaskID: taskID, RepoID: 4, CommitSHA: commitSHA, Level: AnnotationLevelFailure, Path: path, StartLine: 1}
+	}
+
+	// task 47 is the latest task of its job, task 46 is an older attempt
+	require.NoError(t, InsertTaskAnnotations(t.Context(), 47, []*ActionTaskAnnotation{newAnnotation(47, "main.go"), newAnnotation(47, "")}))
+	require.NoError(t, InsertTaskAnnotations(t.Context(), 46, []*ActionTaskAnnotation{newAnnotation(46, "main.go")}))
+
+	annotations, err := FindTaskAnnotations(t.Context(), 47)
+	require.NoError(t, err)
+	assert.Len(t, annotations, 2)
+
+	annotations, err = FindCommitAnnotations(t.Context(), 4, commitSHA)
+	require.NoError(t, err)
+	if assert.Len(t, annotations, 1) {
+		assert.EqualValues(t, 47, annotations[0].TaskID)
+		assert.Equal(t, "main.go", annotations[0].Path)
+	}
+
+	many := make([]*ActionTaskAnnotation, MaxAnnotationsPerTask)
+	for i := range many {
+		many[i] = newAnnotation(47, "main.go")
+	}
+	require.NoError(t, InsertTaskAnnotations(t.Context(), 47, many))
+	annotations, err = FindTaskAnnotations(t.Context(), 47)
+	require.NoError(t, err)
+	assert.Len(t, annotations, MaxAnnotationsPerTask)
+}
@@ -0,0 +1,252 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"time"
+
+	"github.com/kumose/kmup/models/db"
+	"github.com/kumose/kmup/modules/container"
+	"github.com/kumose/kmup/modules/timeutil"
+	"github.com/kumose/kmup/modules/util"
+
+	"xorm.io/builder"
+)
+
+// TestStatus is the result of a test case
+type TestStatus string
+
+const (
+	TestStatusPassed  TestStatus = "passed"
+	TestStatusFailed  TestStatus = "failed"
+	TestStatusSkipped TestStatus = "skipped"
+)
+
+const (
+	// MaxTestCasesPerReport is the max number of test cases stored for a report, the counts of the report include all of them
+	MaxTestCasesPerReport = 10000
+	// maxTestCaseMessageRunes is the max length of the stored message of a test case, to fit in a TEXT column
+	maxTestCaseMessageRunes = 16 * 1024
+	// flakyTestCaseWindow is how far the history of a test case is looked back to find out whether it's flaky
+	flakyTestCaseWindow = 30 * 24 * time.Hour
+)
+
+// ActionTestReport represents a test report, like a JUnit XML file, which was uploaded by a task
+type ActionTestReport struct {
+	ID        int64
+	RepoID    int64  `xorm:"index(repo_commit)"`
+	CommitSHA string `xorm:"VARCHAR(64) index(repo_commit)"`
+	RunID     int64  `xorm:"index"`
+	JobID     int64  `xorm:"index"`
+	TaskID    int64  `xorm:"index"`
+	Name      string `xorm:"VARCHAR(255)"` // the file name of the report
+	Total     int64
+	Passed    int64
+	Failed    int64
+	Skipped   int64
+	Duration  time.Duration
+	Created   timeutil.TimeStamp `xorm:"created"`
+}
+
+// ActionTestCase represents a test case of an ActionTestReport.
+// The test cases with the same CaseKey in a repository are the history of a test, which is used to find flaky tests.
+type ActionTestCase struct {
+	ID        int64
+	ReportID  int64  `xorm:"index"`
+	RepoID    int64  `xorm:"index(repo_case)"`
+	CaseKey   string `xorm:"VARCHAR(40) index(repo_case)"` // the sha1 of the suite, class name and name
+	RunID     int64  `xorm:"index"`
+	JobID     int64
+	TaskID    int64      `xorm:"index"`
+	Suite     string     `xorm:"VARCHAR(255)"`
+	ClassName string     `xorm:"VARCHAR(255)"`
+	Name      string     `xorm:"VARCHAR(500)"`
+	Status    TestStatus `xorm:"VARCHAR(16)"`
+	Duration  time.Duration
+	Message   string             `xorm:"TEXT"`
+	Created   timeutil.TimeStamp `xorm:"created index"`
+
+	Flaky bool `xorm:"-"`
+}
+
+func init() {
+	db.RegisterModel(new(ActionTestReport))
+	db.RegisterModel(new(ActionTestCase))
+}
+
+// TestCaseKey returns the key identifying a test case across runs
+func TestCaseKey(suite, className, name string) string {
+	h := sha1.Sum([]byte(suite + "\x00" + className + "\x00" + name))
+	return hex.EncodeToString(h[:])
+}
+
+// FullName returns the name of the test case prefixed by its class name
+func (c *ActionTestCase) FullName() string {
+	if c.ClassName == "" {
+		return c.Name
+	}
+	return c.ClassName + "." + c.Name
+}
+
+// InsertTestReport inserts a test report with its test cases, the counts of the report are calculated from the test cases
+func InsertTestReport(ctx context.Context, report *ActionTestReport, cases []*ActionTestCase) error {
+	for _, c := range cases {
+		report.Total++
+		switch c.Status {
+		case TestStatusPassed:
+			report.Passed++
+		case TestStatusFailed:
+			report.Failed++
+		case TestStatusSkipped:
+			report.Skipped++
+		}
+		report.Duration += c.Duration
+	}
+	if len(cases) > MaxTestCasesPerReport {
+		cases = cases[:MaxTestCasesPerReport]
+	}
+
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		if err := db.Insert(ctx, report); err != nil {
+			return err
+		}
+		for _, c := range cases {
+			c.ReportID = report.ID
+			c.RepoID = report.RepoID
+			c.RunID = report.RunID
+			c.JobID = report.JobID
+			c.TaskID = report.TaskID
+			c.CaseKey = TestCaseKey(c.Suite, c.ClassName, c.Name)
+			c.Suite = util.EllipsisDisplayString(c.Suite, 255)
+			c.ClassName = util.EllipsisDisplayString(c.ClassName, 255)
+			c.Name = util.EllipsisDisplayString(c.Name, 500)
+			c.Message = util.TruncateRunes(c.Message, maxTestCaseMessageRunes)
+		}
+		// insert in batches to avoid exceeding the max number of parameters of a statement
+		for i := 0; i < len(cases); i += 100 {
+			if err := db.Insert(ctx, cases[i:min(i+100, len(cases))]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// TestSummary is the sum of the counts of test reports
+type TestSummary struct {
+	Total   int64
+	Passed  int64
+	Failed  int64
+	Skipped int64
+}
+
+// latestAttemptCond matches the records of the latest tasks of the jobs, so the results of a rerun replace the results of the previous attempt
+func latestAttemptCond(jobCond builder.Cond) builder.Cond {
+	return builder.In("task_id", builder.Select("task_id").From("action_run_job").Where(jobCond))
+}
+
+func sumTestReports(ctx context.Context, cond builder.Cond) (*TestSummary, error) {
+	var reports []*ActionTestReport
+	if err := db.GetEngine(ctx).Where(cond).Cols("total", "passed", "failed", "skipped").Find(&reports); err != nil {
+		return nil, err
+	}
+	summary := &TestSummary{}
+	for _, r := range reports {
+		summary.Total += r.Total
+		summary.Passed += r.Passed
+		summary.Failed += r.Failed
+		summary.Skipped += r.Skipped
+	}
+	return summary, nil
+}
+
+// GetTaskTestSummary returns the test summary of a task
+func GetTaskTestSummary(ctx context.Context, taskID int64) (*TestSummary, error) {
+	return sumTestReports(ctx, builder.Eq{"task_id": taskID})
+}
+
+// GetRunTestSummary returns the test summary of the latest attempts of the jobs of a run
+func GetRunTestSummary(ctx context.Context, runID int64) (*TestSummary, error) {
+	return sumTestReports(ctx, latestAttemptCond(builder.Eq{"run_id": runID}))
+}
+
+// GetCommitTestSummary returns the test summary of the latest attempts of the jobs which ran for a commit
+func GetCommitTestSummary(ctx context.Context, repoID int64, commitSHA string) (*TestSummary, error) {
+	return sumTestReports(ctx, builder.Eq{"repo_id": repoID, "commit_sha": commitSHA}.
+		And(latestAttemptCond(builder.Eq{"repo_id": repoID, "commit_sha": commitSHA})))
+}
+
+// FindTestCasesOptions finds the test cases of the latest attempts of the jobs of a run
+type FindTestCasesOptions struct {
+	db.ListOptions
+	RunID  int64 // required
+	TaskID int64
+	Status TestStatus
+}
+
+func (opts FindTestCasesOptions) ToConds() builder.Cond {
+	cond := builder.Eq{"run_id": opts.RunID}.And(latestAttemptCond(builder.Eq{"run_id": opts.RunID}))
+	if opts.TaskID > 0 {
+		cond = cond.And(builder.Eq{"task_id": opts.TaskID})
+	}
+	if opts.Status != "" {
+		cond = cond.And(builder.Eq{"status": opts.Status})
+	}
+	return cond
+}
+
+func (opts FindTestCasesOptions) ToOrders() string {
+	return "id ASC"
+}
+
+// LoadFlaky marks the test cases which both passed and failed in the repository recently, the test cases must belong to the same repository
+func LoadFlaky(ctx context.Context, repoID int64, cases []*ActionTestCase) error {
+	keys := make(container.Set[string])
+	for _, c := range cases {
+		keys.Add(c.CaseKey)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	var rows []struct {
+		CaseKey string
+		Status  TestStatus
+	}
+	if err := db.GetEngine(ctx).Table("action_test_case").
+		Where(builder.Eq{"repo_id": repoID}).
+		And(builder.In("case_key", keys.Values())).
+		And(builder.In("status", TestStatusPassed, TestStatusFailed)).
+		And(builder.Gte{"created": timeutil.TimeStamp(time.Now().Add(-flakyTestCaseWindow).Unix())}).
+		GroupBy("case_key, status").
+		Select("case_key, status").
+		Find(&rows); err != nil {
+		return err
+	}
+	statusCount := make(map[string]int, len(keys))
+	for _, row := range rows {
+		statusCount[row.CaseKey]++
+	}
+	for _, c := range cases {
+		c.Flaky = statusCount[c.CaseKey] > 1
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kumose/kmup/models/db"
+	"github.com/kumose/kmup/models/unittest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTestReports(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	const commitSHA = "c2d72f548424103f01ee1dc02889c1e2bff816b0"
+	newReport := func(taskID int64) *ActionTestReport {
+		return &ActionTestReport{RepoID: 4, CommitSHA: commitSHA, RunID: 791, JobID: 192, TaskID: taskID, Name: "junit.xml"}
+	}
+
+	// task 47 is the latest task of its job, task 46 is an older attempt
+	report := newReport(47)
+	require.NoError(t, InsertTestReport(t.Context(), report, []*ActionTestCase{
+		{ClassName: "pkg", Name: "TestA", Status: TestStatusPassed, Duration: time.Second},
+		{ClassName: "pkg", Name: "TestB", Status: TestStatusFailed, Duration: time.Second, Message: "boom"},
+		{ClassName: "pkg", Name: "TestC", Status: TestStatusSkipped},
+	}))
+	assert.EqualValues(t, 3, report.Total)
+	assert.EqualValues(t, 1, report.Passed)
+	assert.EqualValues(t, 1, report.Failed)
+	assert.EqualValues(t, 1, report.Skipped)
+	assert.Equal(t, 2*time.Second, report.Duration)
+	require.NoError(t, InsertTestReport(t.Context(), newReport(46), []*ActionTestCase{
+		{ClassName: "pkg", Name: "TestA", Status: TestStatusFailed},
+		{ClassName: "pkg", Name: "TestB", Status: TestStatusFailed},
+	}))
+
+	summary, err := GetTaskTestSummary(t.Context(), 46)
+	require.NoError(t, err)
+	assert.Equal(t, &TestSummary{Total: 2, Failed: 2}, summary)
+
+	summary, err = GetRunTestSummary(t.Context(), 791)
+	require.NoError(t, err)
+	assert.Equal(t, &TestSummary{Total: 3, Passed: 1, Failed: 1, Skipped: 1}, summary)
+
+	summary, err = GetCommitTestSummary(t.Context(), 4, commitSHA)
+	require.NoError(t, err)
+	assert.Equal(t, &TestSummary{Total: 3, Passed: 1, Failed: 1, Skipped: 1}, summary)
+
+	cases, err := db.Find[ActionTestCase](t.Context(), FindTestCasesOptions{RunID: 791, Status: TestStatusFailed})
+	require.NoError(t, err)
+	require.Len(t, cases, 1)
+	assert.Equal(t, "pkg.TestB", cases[0].FullName())
+	assert.Equal(t, "boom", cases[0].Message)
+	assert.Equal(t, TestCaseKey("", "pkg", "TestB"), cases[0].CaseKey)
+
+	cases, err = db.Find[ActionTestCase](t.Context(), FindTestCasesOptions{RunID: 791})
+	require.NoError(t, err)
+	require.Len(t, cases, 3)
+	require.NoError(t, LoadFlaky(t.Context(), 4, cases))
+	// TestA passed in task 47 but failed in task 46
+	assert.True(t, cases[0].Flaky)
+	assert.False(t, cases[1].Flaky)
+	assert.False(t, cases[2].Flaky)
+}
@@ -36,6 +36,7 @@ import (
 //  1. global variable, OwnerID is 0 and RepoID is 0
 //  2. org/user level variable, OwnerID is org/user ID and RepoID is 0
 //  3. repo level variable, OwnerID is 0 and RepoID is repo ID
+//  4. environment level variable, OwnerID is 0, RepoID is repo ID and EnvironmentID is the ID of an environment of the repo
 //
 // Please note that it's not acceptable to have both OwnerID and RepoID to be non-zero,
 // or it will be complicated to find variables belonging to a specific owner.
@@ -43,14 +44,15 @@ import (
 // but it's a repo level variable, not an org/user level variable.
 // To avoid this, make it clear with {OwnerID: 0, RepoID: 1} for repo level variables.
 type ActionVariable struct {
-	ID          int64              `xorm:"pk autoincr"`
-	OwnerID     int64              `xorm:"UNIQUE(owner_repo_name)"`
-	RepoID      int64              `xorm:"INDEX UNIQUE(owner_repo_name)"`
-	Name        string             `xorm:"UNIQUE(owner_repo_name) NOT NULL"`
-	Data        string             `xorm:"LONGTEXT NOT NULL"`
-	Description string             `xorm:"TEXT"`
-	CreatedUnix timeutil.TimeStamp `xorm:"created NOT NULL"`
-	UpdatedUnix timeutil.TimeStamp `xorm:"updated"`
+	ID            int64              `xorm:"pk autoincr"`
+	OwnerID       int64              `xorm:"UNIQUE(owner_repo_name)"`
+	RepoID        int64              `xorm:"INDEX UNIQUE(owner_repo_name)"`
+	EnvironmentID int64              `xorm:"INDEX UNIQUE(owner_repo_name) NOT NULL DEFAULT 0"`
+	Name          string             `xorm:"UNIQUE(owner_repo_name) NOT NULL"`
+	Data          string             `xorm:"LONGTEXT NOT NULL"`
+	Description   string             `xorm:"TEXT"`
+	CreatedUnix   timeutil.TimeStamp `xorm:"created NOT NULL"`
+	UpdatedUnix   timeutil.TimeStamp `xorm:"updated"`
 }
 
 const (
@@ -69,28 +71,35 @@ func InsertVariable(ctx context.Context, ownerID, repoID int64, name, data, desc
 		ownerID = 0
 	}
 
-	if utf8.RuneCountInString(data) > VariableDataMaxLength {
-		return nil, util.NewInvalidArgumentErrorf("data too long")
+	return insertVariable(ctx, &ActionVariable{OwnerID: ownerID, RepoID: repoID, Name: name, Data: data, Description: description})
+}
+
+// InsertEnvironmentVariable inserts a variable of an environment of the repository
+func InsertEnvironmentVariable(ctx context.Context, repoID, environmentID int64, name, data, description string) (*ActionVariable, error) {
+	if repoID == 0 || environmentID == 0 {
+		return nil, util.NewInvalidArgumentErrorf("repoID and environmentID cannot be zero")
 	}
 
-	description = util.TruncateRunes(description, VariableDescriptionMaxLength)
+	return insertVariable(ctx, &ActionVariable{RepoID: repoID, EnvironmentID: environmentID, Name: name, Data: data, Description: description})
+}
 
-	variable := &ActionVariable{
-		OwnerID:     ownerID,
-		RepoID:      repoID,
-		Name:        strings.ToUpper(name),
-		Data:        data,
-		Description: description,
+func insertVariable(ctx context.Context, variable *ActionVariable) (*ActionVariable, error) {
+	if utf8.RuneCountInString(variable.Data) > VariableDataMaxLength {
+		return nil, util.NewInvalidArgumentErrorf("data too long")
 	}
+
+	variable.Name = strings.ToUpper(variable.Name)
+	variable.Description = util.TruncateRunes(variable.Description, VariableDescriptionMaxLength)
 	return variable, db.Insert(ctx, variable)
 }
 
 type FindVariablesOpts struct {
 	db.ListOptions
-	IDs     []int64
-	RepoID  int64
-	OwnerID int64 // it will be ignored if RepoID is set
-	Name    string
+	IDs           []int64
+	RepoID        int64
+	OwnerID       int64 // it will be ignored if RepoID is set
+	EnvironmentID int64 // the variables of the environments are only found when it is set
+	Name          string
 }
 
 func (opts FindVariablesOpts) ToConds() builder.Cond {
@@ -113,6 +122,7 @@ func (opts FindVariablesOpts) ToConds() builder.Cond {
 	} else {
 		cond = cond.And(builder.Eq{"owner_id": opts.OwnerID})
 	}
+	cond = cond.And(builder.Eq{"environment_id": opts.EnvironmentID})
 
 	if opts.Name != "" {
 		cond = cond.And(builder.Eq{"name": strings.ToUpper(opts.Name)})
@@ -183,6 +193,31 @@ func GetVariablesOfRun(ctx context.Context, run *ActionRun) (map[string]string,
 	return variables, nil
 }
 
+// GetVariablesOfJob returns the variables of the run with the variables of the environment the job deploys to
+func GetVariablesOfJob(ctx context.Context, job *ActionRunJob) (map[string]string, error) {
+	if err := job.LoadRun(ctx); err != nil {
+		return nil, err
+	}
+	variables, err := GetVariablesOfRun(ctx, job.Run)
+	if err != nil {
+		return nil, err
+	}
+	if job.EnvironmentID == 0 {
+		return variables, nil
+	}
+
+	// Level precedence: Environment > Repo > Org / User > Global
+	environmentVariables, err := db.Find[ActionVariable](ctx, FindVariablesOpts{RepoID: job.RepoID, EnvironmentID: job.EnvironmentID})
+	if err != nil {
+		log.Error("find variables of environment: %d, error: %v", job.EnvironmentID, err)
+		return nil, err
+	}
+	for _, v := range environmentVariables {
+		variables[v.Name] = v.Data
+	}
+	return variables, nil
+}
+
 func CountWrongRepoLevelVariables(ctx context.Context) (int64, error) {
 	var result int64
 	_, err := db.GetEngine(ctx).SQL("SELECT count(`id`) FROM `action_variable` WHERE `repo_id` > 0 AND `owner_id` > 0").Get(&result)
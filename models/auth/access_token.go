@@ -248,3 +248,19 @@ func DeleteAccessTokenByID(ctx context.Context, id, userID int64) error {
 	}
 	return nil
 }
+
+// RevokeAccessTokenBySHA deletes the access token with the given token value, it's used when a token is leaked.
+// The deleted token is returned, so its owner can be told about it.
+func RevokeAccessTokenBySHA(ctx context.Context, token string) (*AccessToken, error) {
+	t, err := GetAccessTokenBySHA(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if err := DeleteAccessTokenByID(ctx, t.ID, t.UID); err != nil {
+		return nil, err
+	}
+	if successfulAccessTokenCache != nil {
+		successfulAccessTokenCache.Remove(token)
+	}
+	return t, nil
+}
@@ -144,3 +144,18 @@ func TestDeleteAccessTokenByID(t *testing.T) {
 	assert.Error(t, err)
 	assert.True(t, auth_model.IsErrAccessTokenNotExist(err))
 }
+
+func TestRevokeAccessTokenBySHA(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	token, err := auth_model.RevokeAccessTokenBySHA(t.Context(), "d2c6c1ba3890b309189a8e618c72a162e4efbf36")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), token.UID)
+	unittest.AssertNotExistsBean(t, &auth_model.AccessToken{ID: token.ID})
+
+	_, err = auth_model.GetAccessTokenBySHA(t.Context(), "d2c6c1ba3890b309189a8e618c72a162e4efbf36")
+	assert.True(t, auth_model.IsErrAccessTokenNotExist(err))
+
+	_, err = auth_model.RevokeAccessTokenBySHA(t.Context(), "d2c6c1ba3890b309189a8e618c72a162e4efbf36")
+	assert.True(t, auth_model.IsErrAccessTokenNotExist(err))
+}
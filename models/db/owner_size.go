@@ -0,0 +1,43 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package db
+
+import (
+	"context"
+
+	"xorm.io/builder"
+)
+
+type ownerSize struct {
+	OwnerID int64
+	Size    int64
+}
+
+// FindOwnerSizes executes a query which selects the columns "owner_id" and "size" and returns the sizes by owner
+func FindOwnerSizes(ctx context.Context, query *builder.Builder) (map[int64]int64, error) {
+	rows := make([]*ownerSize, 0, 10)
+	if err := GetEngine(ctx).SQL(query).Find(&rows); err != nil {
+		return nil, err
+	}
+
+	sizes := make(map[int64]int64, len(rows))
+	for _, row := range rows {
+		sizes[row.OwnerID] += row.Size
+	}
+	return sizes, nil
+}
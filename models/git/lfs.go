@@ -392,3 +392,14 @@ func MarkLFSMetaObject(ctx context.Context, id int64) error {
 	}
 	return err
 }
+
+// GetLFSSizesByOwner gets the size of the distinct LFS objects referenced by the repositories of every owner
+func GetLFSSizesByOwner(ctx context.Context) (map[int64]int64, error) {
+	objects := builder.Select("DISTINCT repository.owner_id", "lfs_meta_object.oid", "lfs_meta_object.size").
+		From("lfs_meta_object").
+		InnerJoin("repository", "repository.id = lfs_meta_object.repository_id")
+
+	return db.FindOwnerSizes(ctx, builder.Select("t.owner_id", "SUM(t.size) AS size").
+		From(objects, "t").
+		GroupBy("t.owner_id"))
+}
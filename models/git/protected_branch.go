@@ -77,9 +77,14 @@ type ProtectedBranch struct {
 	ProtectedFilePatterns         string   `xorm:"TEXT"`
 	UnprotectedFilePatterns       string   `xorm:"TEXT"`
 	BlockAdminMergeOverride       bool     `xorm:"NOT NULL DEFAULT false"`
+	RequireRequiredWorkflows      bool     `xorm:"NOT NULL DEFAULT false"` // the runs of the org- and instance-level required workflows must succeed
+	RequireCodeOwnerApproval      bool     `xorm:"NOT NULL DEFAULT false"` // every code owner of a changed file must approve
+	EnableMergeQueue              bool     `xorm:"NOT NULL DEFAULT false"` // pull requests are merged through a merge queue which tests them together
 
 	CreatedUnix timeutil.TimeStamp `xorm:"created"`
 	UpdatedUnix timeutil.TimeStamp `xorm:"updated"`
+
+	Rulesets []*Ruleset `xorm:"-"` // the org rulesets merged into the rule
 }
 
 func init() {
@@ -132,6 +137,17 @@ func (protectBranch *ProtectedBranch) LoadRepo(ctx context.Context) (err error)
 	return err
 }
 
+// IsDeletionProtected returns whether the branch can't be deleted.
+// The branches protected by a rule of the repository can never be deleted, while the rulesets must block the deletion explicitly.
+func (protectBranch *ProtectedBranch) IsDeletionProtected() bool {
+	return protectBranch.ID != 0 || slices.ContainsFunc(protectBranch.Rulesets, func(rs *Ruleset) bool { return rs.BlockDeletion })
+}
+
+// IsCreationProtected returns whether a ruleset blocks creating the branch
+func (protectBranch *ProtectedBranch) IsCreationProtected() bool {
+	return slices.ContainsFunc(protectBranch.Rulesets, func(rs *Ruleset) bool { return rs.BlockCreation })
+}
+
 // CanUserPush returns if some user could push to this protected branch
 func (protectBranch *ProtectedBranch) CanUserPush(ctx context.Context, user *user_model.User) bool {
 	if !protectBranch.CanPush {
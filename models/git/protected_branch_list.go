@@ -19,11 +19,17 @@ package git
 
 import (
 	"context"
+	"slices"
 	"sort"
 
 	"github.com/kumose/kmup/models/db"
+	repo_model "github.com/kumose/kmup/models/repo"
+	user_model "github.com/kumose/kmup/models/user"
+	"github.com/kumose/kmup/modules/git"
 	"github.com/kumose/kmup/modules/glob"
 	"github.com/kumose/kmup/modules/optional"
+
+	"xorm.io/builder"
 )
 
 type ProtectedBranchRules []*ProtectedBranch
@@ -96,20 +102,85 @@ func FindAllMatchedBranches(ctx context.Context, repoID int64, ruleName string)
 	return results, nil
 }
 
-// GetFirstMatchProtectedBranchRule returns the first matched rules
+// GetFirstMatchProtectedBranchRule returns the first matched rule of the repository merged with the active org rulesets which apply to the branch
 func GetFirstMatchProtectedBranchRule(ctx context.Context, repoID int64, branchName string) (*ProtectedBranch, error) {
+	return GetFirstMatchProtectedBranchRuleForUser(ctx, repoID, branchName, nil)
+}
+
+// GetFirstMatchProtectedBranchRuleForUser returns the first matched rule of the repository merged with the active org rulesets
+// which apply to the branch and which the user can't bypass
+func GetFirstMatchProtectedBranchRuleForUser(ctx context.Context, repoID int64, branchName string, user *user_model.User) (*ProtectedBranch, error) {
+	rules, err := FindEffectiveBranchRules(ctx, repoID)
+	if err != nil {
+		return nil, err
+	}
+	return rules.GetFirstMatchedForUser(ctx, branchName, user), nil
+}
+
+// EffectiveBranchRules holds the protected branch rules of a repository and the active rulesets of its owner,
+// so the effective rules of many branches can be computed without loading them again
+type EffectiveBranchRules struct {
+	repo     *repo_model.Repository
+	rules    ProtectedBranchRules
+	rulesets []*Ruleset
+}
+
+// FindEffectiveBranchRules loads the protected branch rules of the repository and the active rulesets of its owner
+func FindEffectiveBranchRules(ctx context.Context, repoID int64) (*EffectiveBranchRules, error) {
 	rules, err := FindRepoProtectedBranchRules(ctx, repoID)
 	if err != nil {
 		return nil, err
 	}
-	return rules.GetFirstMatched(branchName), nil
+
+	// most of the owners don't have any ruleset, so only load the repository when there are some
+	var rulesets []*Ruleset
+	if err := db.GetEngine(ctx).
+		Where(builder.Eq{"enforcement": RulesetEnforcementActive}).
+		And(builder.In("owner_id", builder.Select("owner_id").From("repository").Where(builder.Eq{"id": repoID}))).
+		Find(&rulesets); err != nil {
+		return nil, err
+	}
+	effective := &EffectiveBranchRules{rules: rules, rulesets: rulesets}
+	if len(rulesets) == 0 {
+		return effective, nil
+	}
+	effective.repo, err = repo_model.GetRepositoryByID(ctx, repoID)
+	if err != nil {
+		return nil, err
+	}
+	effective.rulesets = slices.DeleteFunc(effective.rulesets, func(rs *Ruleset) bool { return !rs.MatchRepo(effective.repo) })
+	return effective, nil
+}
+
+// GetFirstMatchedForUser returns the first matched rule merged with the rulesets which apply to the branch and which the user can't bypass
+func (rules *EffectiveBranchRules) GetFirstMatchedForUser(ctx context.Context, branchName string, user *user_model.User) *ProtectedBranch {
+	rule := rules.rules.GetFirstMatched(branchName)
+	if len(rules.rulesets) == 0 {
+		return rule
+	}
+
+	refName := git.RefNameFromBranch(branchName)
+	var rulesets []*Ruleset
+	for _, rs := range rules.rulesets {
+		if rs.MatchRef(rules.repo, refName) && !rs.CanUserBypass(ctx, user) {
+			rulesets = append(rulesets, rs)
+		}
+	}
+	if rule != nil && len(rulesets) > 0 {
+		// the matched rule may be shared by several branches, so the rulesets are merged into a copy
+		merged := *rule
+		merged.StatusCheckContexts = slices.Clone(rule.StatusCheckContexts)
+		merged.Rulesets = slices.Clone(rule.Rulesets)
+		rule = &merged
+	}
+	return MergeRulesets(rule, rules.repo.ID, branchName, rulesets)
 }
 
-// IsBranchProtected checks if branch is protected
+// IsBranchProtected checks if branch is protected from deletion
 func IsBranchProtected(ctx context.Context, repoID int64, branchName string) (bool, error) {
 	rule, err := GetFirstMatchProtectedBranchRule(ctx, repoID, branchName)
 	if err != nil {
 		return false, err
 	}
-	return rule != nil, nil
+	return rule != nil && rule.IsDeletionProtected(), nil
 }
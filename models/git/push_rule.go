@@ -0,0 +1,216 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/kumose/kmup/models/db"
+	repo_model "github.com/kumose/kmup/models/repo"
+	"github.com/kumose/kmup/modules/glob"
+	"github.com/kumose/kmup/modules/timeutil"
+	"github.com/kumose/kmup/modules/util"
+
+	"xorm.io/builder"
+)
+
+// PushRule represents the rules which the commits pushed to a repository must follow.
+//
+// It can be:
+//  1. instance-level, OwnerID and RepoID are 0, it applies to all repositories
+//  2. org-level, OwnerID is the org ID, it applies to the repositories of the org
+//  3. repo-level, RepoID is the repository ID
+//
+// All the push rules which apply to a repository are enforced, so a repository can only tighten the rules of its owner and the instance.
+type PushRule struct {
+	ID      int64 `xorm:"pk autoincr"`
+	OwnerID int64 `xorm:"UNIQUE(owner_repo) NOT NULL DEFAULT 0"`
+	RepoID  int64 `xorm:"UNIQUE(owner_repo) NOT NULL DEFAULT 0"`
+
+	MaxFileSize          int64    `xorm:"NOT NULL DEFAULT 0"` // the maximum size of a pushed file in bytes, 0 means no limit
+	ForbiddenPaths       []string `xorm:"JSON TEXT"`          // glob patterns matched against the lower-cased paths of the pushed files
+	ForbiddenExtensions  []string `xorm:"JSON TEXT"`          // lower-cased file extensions without the leading dot
+	CommitMessagePattern string   `xorm:"TEXT"`               // a regular expression the messages of the pushed commits must match
+	AuthorEmailDomains   []string `xorm:"JSON TEXT"`          // the domains one of which the author emails of the pushed commits must belong to
+	RejectMergeCommits   bool     `xorm:"NOT NULL DEFAULT false"`
+	RejectSecrets        bool     `xorm:"NOT NULL DEFAULT false"` // reject files containing something which looks like a secret
+
+	CreatedUnix timeutil.TimeStamp `xorm:"created"`
+	UpdatedUnix timeutil.TimeStamp `xorm:"updated"`
+
+	forbiddenPathGlobs   []glob.Glob    `xorm:"-"`
+	commitMessageRegexp  *regexp.Regexp `xorm:"-"`
+	compiledPatternsDone bool           `xorm:"-"`
+}
+
+func init() {
+	db.RegisterModel(new(PushRule))
+}
+
+// IsGlobal returns whether it's an instance-level push rule
+func (pr *PushRule) IsGlobal() bool {
+	return pr.OwnerID == 0 && pr.RepoID == 0
+}
+
+// Validate checks whether the patterns of the push rule are valid and normalizes its lists
+func (pr *PushRule) Validate() error {
+	if pr.MaxFileSize < 0 {
+		return util.NewInvalidArgumentErrorf("max file size must not be negative")
+	}
+	pr.ForbiddenPaths = normalizeList(pr.ForbiddenPaths, func(s string) string { return strings.ToLower(s) })
+	pr.ForbiddenExtensions = normalizeList(pr.ForbiddenExtensions, func(s string) string { return strings.ToLower(strings.TrimPrefix(s, ".")) })
+	pr.AuthorEmailDomains = normalizeList(pr.AuthorEmailDomains, func(s string) string { return strings.ToLower(strings.TrimPrefix(s, "@")) })
+	pr.CommitMessagePattern = strings.TrimSpace(pr.CommitMessagePattern)
+
+	for _, pattern := range pr.ForbiddenPaths {
+		if _, err := glob.Compile(pattern, '/'); err != nil {
+			return util.NewInvalidArgumentErrorf("invalid forbidden path pattern %q: %v", pattern, err)
+		}
+	}
+	if pr.CommitMessagePattern != "" {
+		if _, err := regexp.Compile(pr.CommitMessagePattern); err != nil {
+			return util.NewInvalidArgumentErrorf("invalid commit message pattern %q: %v", pr.CommitMessagePattern, err)
+		}
+	}
+	return nil
+}
+
+func normalizeList(list []string, normalize func(string) string) []string {
+	normalized := make([]string, 0, len(list))
+	for _, s := range list {
+		if s = normalize(strings.TrimSpace(s)); s != "" {
+			normalized = append(normalized, s)
+		}
+	}
+	return normalized
+}
+
+func (pr *PushRule) compilePatterns() {
+	if pr.compiledPatternsDone {
+		return
+	}
+	pr.compiledPatternsDone = true
+	for _, pattern := range pr.ForbiddenPaths {
+		// the patterns were validated when the push rule was saved
+		if g, err := glob.Compile(pattern, '/'); err == nil {
+			pr.forbiddenPathGlobs = append(pr.forbiddenPathGlobs, g)
+		}
+	}
+	if pr.CommitMessagePattern != "" {
+		pr.commitMessageRegexp, _ = regexp.Compile(pr.CommitMessagePattern)
+	}
+}
+
+// IsPathForbidden returns whether the push rule forbids pushing a file with the path
+func (pr *PushRule) IsPathForbidden(path string) bool {
+	pr.compilePatterns()
+	lpath := strings.ToLower(path)
+	if ext := lpath[strings.LastIndex(lpath, "/")+1:]; strings.Contains(ext, ".") {
+		ext = ext[strings.LastIndex(ext, ".")+1:]
+		for _, forbidden := range pr.ForbiddenExtensions {
+			if ext == forbidden {
+				return true
+			}
+		}
+	}
+	for _, g := range pr.forbiddenPathGlobs {
+		if g.Match(lpath) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsCommitMessageAllowed returns whether the commit message matches the commit message pattern of the push rule
+func (pr *PushRule) IsCommitMessageAllowed(message string) bool {
+	pr.compilePatterns()
+	return pr.commitMessageRegexp == nil || pr.commitMessageRegexp.MatchString(message)
+}
+
+// IsAuthorEmailAllowed returns whether the author email belongs to one of the author email domains of the push rule
+func (pr *PushRule) IsAuthorEmailAllowed(email string) bool {
+	if len(pr.AuthorEmailDomains) == 0 {
+		return true
+	}
+	_, domain, ok := strings.Cut(strings.ToLower(email), "@")
+	if !ok {
+		return false
+	}
+	for _, allowed := range pr.AuthorEmailDomains {
+		if domain == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// GetPushRule returns the push rule of the repository, the owner or the instance if both of them are 0
+func GetPushRule(ctx context.Context, ownerID, repoID int64) (*PushRule, error) {
+	var pr PushRule
+	has, err := db.GetEngine(ctx).Where("owner_id=? AND repo_id=?", ownerID, repoID).Get(&pr)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, fmt.Errorf("push rule of owner %d and repo %d: %w", ownerID, repoID, util.ErrNotExist)
+	}
+	return &pr, nil
+}
+
+// GetPushRulesForRepo returns the instance-level, owner-level and repo-level push rules which apply to the repository
+func GetPushRulesForRepo(ctx context.Context, repo *repo_model.Repository) ([]*PushRule, error) {
+	rules := make([]*PushRule, 0, 3)
+	return rules, db.GetEngine(ctx).
+		Where(builder.Eq{"owner_id": 0, "repo_id": 0}.
+			Or(builder.Eq{"owner_id": repo.OwnerID, "repo_id": 0}).
+			Or(builder.Eq{"owner_id": 0, "repo_id": repo.ID})).
+		Asc("id").
+		Find(&rules)
+}
+
+// SetPushRule creates or replaces the push rule of the repository, the owner or the instance
+func SetPushRule(ctx context.Context, rule *PushRule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		existing, err := GetPushRule(ctx, rule.OwnerID, rule.RepoID)
+		if errors.Is(err, util.ErrNotExist) {
+			return db.Insert(ctx, rule)
+		} else if err != nil {
+			return err
+		}
+		rule.ID = existing.ID
+		_, err = db.GetEngine(ctx).ID(rule.ID).AllCols().Update(rule)
+		return err
+	})
+}
+
+// DeletePushRule deletes the push rule of the repository, the owner or the instance
+func DeletePushRule(ctx context.Context, ownerID, repoID int64) error {
+	n, err := db.GetEngine(ctx).Where("owner_id=? AND repo_id=?", ownerID, repoID).Delete(new(PushRule))
+	if err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("push rule of owner %d and repo %d: %w", ownerID, repoID, util.ErrNotExist)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package git_test
+
+import (
+	"testing"
+
+	git_model "github.com/kumose/kmup/models/git"
+	repo_model "github.com/kumose/kmup/models/repo"
+	"github.com/kumose/kmup/models/unittest"
+	"github.com/kumose/kmup/modules/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPushRuleMatch(t *testing.T) {
+	rule := &git_model.PushRule{
+		ForbiddenPaths:       []string{"**/.ENV", "secrets/**"},
+		ForbiddenExtensions:  []string{".EXE", "dll"},
+		CommitMessagePattern: `^[A-Z]+-\d+ `,
+		AuthorEmailDomains:   []string{"@Example.com"},
+	}
+	require.NoError(t, rule.Validate())
+	assert.Equal(t, []string{"exe", "dll"}, rule.ForbiddenExtensions)
+
+	assert.True(t, rule.IsPathForbidden("bin/tool.exe"))
+	assert.True(t, rule.IsPathForbidden("lib/a.DLL"))
+	assert.True(t, rule.IsPathForbidden("app/.env"))
+	assert.True(t, rule.IsPathForbidden("secrets/prod/key.pem"))
+	assert.False(t, rule.IsPathForbidden("exe/readme.md"))
+	assert.False(t, rule.IsPathForbidden("app/.envrc"))
+
+	assert.True(t, rule.IsCommitMessageAllowed("ABC-123 fix the bug"))
+	assert.False(t, rule.IsCommitMessageAllowed("fix the bug"))
+
+	assert.True(t, rule.IsAuthorEmailAllowed("dev@EXAMPLE.com"))
+	assert.False(t, rule.IsAuthorEmailAllowed("dev@example.com.evil"))
+	assert.False(t, rule.IsAuthorEmailAllowed("dev"))
+
+	assert.ErrorIs(t, (&git_model.PushRule{CommitMessagePattern: "("}).Validate(), util.ErrInvalidArgument)
+	assert.ErrorIs(t, (&git_model.PushRule{ForbiddenPaths: []string{"["}}).Validate(), util.ErrInvalidArgument)
+	assert.ErrorIs(t, (&git_model.PushRule{MaxFileSize: -1}).Validate(), util.ErrInvalidArgument)
+}
+
+func TestGetPushRulesForRepo(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 3})
+
+	require.NoError(t, git_model.SetPushRule(t.Context(), &git_model.PushRule{MaxFileSize: 10}))
+	require.NoError(t, git_model.SetPushRule(t.Context(), &git_model.PushRule{OwnerID: repo.OwnerID, RejectSecrets: true}))
+	require.NoError(t, git_model.SetPushRule(t.Context(), &git_model.PushRule{RepoID: repo.ID, RejectMergeCommits: true}))
+	// the push rules of other owners and repositories don't apply
+	require.NoError(t, git_model.SetPushRule(t.Context(), &git_model.PushRule{OwnerID: repo.OwnerID + 1, MaxFileSize: 1}))
+	require.NoError(t, git_model.SetPushRule(t.Context(), &git_model.PushRule{RepoID: repo.ID + 1, MaxFileSize: 1}))
+
+	// setting a push rule again replaces it
+	require.NoError(t, git_model.SetPushRule(t.Context(), &git_model.PushRule{MaxFileSize: 20}))
+
+	rules, err := git_model.GetPushRulesForRepo(t.Context(), repo)
+	require.NoError(t, err)
+	require.Len(t, rules, 3)
+	assert.EqualValues(t, 20, rules[0].MaxFileSize)
+	assert.True(t, rules[1].RejectSecrets)
+	assert.True(t, rules[2].RejectMergeCommits)
+
+	require.NoError(t, git_model.DeletePushRule(t.Context(), 0, repo.ID))
+	assert.ErrorIs(t, git_model.DeletePushRule(t.Context(), 0, repo.ID), util.ErrNotExist)
+	rules, err = git_model.GetPushRulesForRepo(t.Context(), repo)
+	require.NoError(t, err)
+	assert.Len(t, rules, 2)
+}
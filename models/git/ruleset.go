@@ -0,0 +1,448 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package git
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/kumose/kmup/models/db"
+	"github.com/kumose/kmup/models/organization"
+	repo_model "github.com/kumose/kmup/models/repo"
+	user_model "github.com/kumose/kmup/models/user"
+	"github.com/kumose/kmup/modules/container"
+	"github.com/kumose/kmup/modules/git"
+	"github.com/kumose/kmup/modules/glob"
+	"github.com/kumose/kmup/modules/log"
+	"github.com/kumose/kmup/modules/timeutil"
+	"github.com/kumose/kmup/modules/util"
+
+	"xorm.io/builder"
+)
+
+// RulesetEnforcement represents how the rules of a ruleset are enforced
+type RulesetEnforcement string
+
+const (
+	RulesetEnforcementActive   RulesetEnforcement = "active"   // the rules are enforced
+	RulesetEnforcementEvaluate RulesetEnforcement = "evaluate" // the rules are not enforced, the pushes they would have rejected are recorded
+	RulesetEnforcementDisabled RulesetEnforcement = "disabled"
+)
+
+// RulesetEnforcements are all the valid ruleset enforcements
+var RulesetEnforcements = []RulesetEnforcement{RulesetEnforcementActive, RulesetEnforcementEvaluate, RulesetEnforcementDisabled}
+
+// IsValid returns whether the enforcement is a known one
+func (e RulesetEnforcement) IsValid() bool {
+	return slices.Contains(RulesetEnforcements, e)
+}
+
+// RulesetDefaultBranchPattern is the ref pattern which matches the default branch of the repositories
+const RulesetDefaultBranchPattern = "~DEFAULT_BRANCH"
+
+// Ruleset represents the protection rules which an organization applies to the branches and tags of its repositories.
+//
+// A ruleset applies to the repositories of the org whose names match one of RepoNamePatterns and which have one of RepoTopics,
+// an empty list matches all the repositories. It applies to the refs which match one of RefPatterns and none of ExcludeRefPatterns.
+//
+// The active rulesets are merged with the protected branch rule of the repository, so they can only tighten it.
+// The users in the bypass lists are not restricted by the ruleset.
+type Ruleset struct {
+	ID          int64              `xorm:"pk autoincr"`
+	OwnerID     int64              `xorm:"UNIQUE(s) NOT NULL"`
+	Name        string             `xorm:"UNIQUE(s) NOT NULL"`
+	Enforcement RulesetEnforcement `xorm:"VARCHAR(16) NOT NULL DEFAULT 'active'"`
+
+	RepoNamePatterns   []string `xorm:"JSON TEXT"` // glob patterns matched against the lower-cased repository names
+	RepoTopics         []string `xorm:"JSON TEXT"`
+	RefPatterns        []string `xorm:"JSON TEXT"` // glob patterns matched against the full ref names, like refs/heads/main or refs/tags/v*
+	ExcludeRefPatterns []string `xorm:"JSON TEXT"`
+
+	BlockCreation         bool     `xorm:"NOT NULL DEFAULT false"`
+	BlockDeletion         bool     `xorm:"NOT NULL DEFAULT false"`
+	BlockForcePush        bool     `xorm:"NOT NULL DEFAULT false"` // for the tags it blocks moving them
+	RequirePullRequest    bool     `xorm:"NOT NULL DEFAULT false"` // the branches can only be changed by merging pull requests
+	RequiredApprovals     int64    `xorm:"NOT NULL DEFAULT 0"`
+	EnableStatusCheck     bool     `xorm:"NOT NULL DEFAULT false"`
+	StatusCheckContexts   []string `xorm:"JSON TEXT"`
+	RequireSignedCommits  bool     `xorm:"NOT NULL DEFAULT false"`
+	ProtectedFilePatterns string   `xorm:"TEXT"` // semicolon separated glob patterns, like the ones of the protected branches
+
+	BypassUserIDs []int64 `xorm:"JSON TEXT"`
+	BypassTeamIDs []int64 `xorm:"JSON TEXT"`
+
+	CreatedUnix timeutil.TimeStamp `xorm:"created"`
+	UpdatedUnix timeutil.TimeStamp `xorm:"updated"`
+
+	repoNameGlobs   []glob.Glob `xorm:"-"`
+	refGlobs        []glob.Glob `xorm:"-"`
+	excludeRefGlobs []glob.Glob `xorm:"-"`
+	compiledGlobs   bool        `xorm:"-"`
+}
+
+func init() {
+	db.RegisterModel(new(Ruleset))
+	db.RegisterModel(new(RulesetEvaluation))
+}
+
+// Validate checks whether the patterns of the ruleset are valid and normalizes its lists
+func (rs *Ruleset) Validate() error {
+	rs.Name = strings.TrimSpace(rs.Name)
+	if rs.Name == "" {
+		return util.NewInvalidArgumentErrorf("ruleset name must not be empty")
+	}
+	if rs.Enforcement == "" {
+		rs.Enforcement = RulesetEnforcementActive
+	} else if !rs.Enforcement.IsValid() {
+		return util.NewInvalidArgumentErrorf("invalid ruleset enforcement %q", rs.Enforcement)
+	}
+	if rs.RequiredApprovals < 0 {
+		return util.NewInvalidArgumentErrorf("required approvals must not be negative")
+	}
+
+	rs.RepoNamePatterns = normalizeList(rs.RepoNamePatterns, strings.ToLower)
+	rs.RepoTopics = normalizeList(rs.RepoTopics, strings.ToLower)
+	rs.RefPatterns = normalizeList(rs.RefPatterns, func(s string) string { return s })
+	rs.ExcludeRefPatterns = normalizeList(rs.ExcludeRefPatterns, func(s string) string { return s })
+	rs.StatusCheckContexts = normalizeList(rs.StatusCheckContexts, func(s string) string { return s })
+	rs.ProtectedFilePatterns = strings.TrimSpace(rs.ProtectedFilePatterns)
+	if len(rs.RefPatterns) == 0 {
+		return util.NewInvalidArgumentErrorf("ruleset must target at least one ref pattern")
+	}
+
+	for _, pattern := range rs.RepoNamePatterns {
+		if _, err := glob.Compile(pattern); err != nil {
+			return util.NewInvalidArgumentErrorf("invalid repository name pattern %q: %v", pattern, err)
+		}
+	}
+	for _, pattern := range append(slices.Clone(rs.RefPatterns), rs.ExcludeRefPatterns...) {
+		if pattern == RulesetDefaultBranchPattern {
+			continue
+		}
+		if !strings.HasPrefix(pattern, git.BranchPrefix) && !strings.HasPrefix(pattern, git.TagPrefix) {
+			return util.NewInvalidArgumentErrorf("ref pattern %q must start with %s or %s", pattern, git.BranchPrefix, git.TagPrefix)
+		}
+		if _, err := glob.Compile(pattern, '/'); err != nil {
+			return util.NewInvalidArgumentErrorf("invalid ref pattern %q: %v", pattern, err)
+		}
+	}
+	for expr := range strings.SplitSeq(strings.ToLower(rs.ProtectedFilePatterns), ";") {
+		if expr = strings.TrimSpace(expr); expr == "" {
+			continue
+		}
+		if _, err := glob.Compile(expr, '.', '/'); err != nil {
+			return util.NewInvalidArgumentErrorf("invalid protected file pattern %q: %v", expr, err)
+		}
+	}
+	return nil
+}
+
+func (rs *Ruleset) compileGlobs() {
+	if rs.compiledGlobs {
+		return
+	}
+	rs.compiledGlobs = true
+	// the patterns were validated when the ruleset was saved
+	for _, pattern := range rs.RepoNamePatterns {
+		if g, err := glob.Compile(pattern); err == nil {
+			rs.repoNameGlobs = append(rs.repoNameGlobs, g)
+		}
+	}
+	compileRefGlobs := func(patterns []string) (globs []glob.Glob) {
+		for _, pattern := range patterns {
+			if pattern == RulesetDefaultBranchPattern {
+				continue
+			}
+			if g, err := glob.Compile(pattern, '/'); err == nil {
+				globs = append(globs, g)
+			} else {
+				log.Warn("Invalid ref pattern for Ruleset[%d]: %s %v", rs.ID, pattern, err)
+			}
+		}
+		return globs
+	}
+	rs.refGlobs = compileRefGlobs(rs.RefPatterns)
+	rs.excludeRefGlobs = compileRefGlobs(rs.ExcludeRefPatterns)
+}
+
+// MatchRepo returns whether the ruleset applies to the repository
+func (rs *Ruleset) MatchRepo(repo *repo_model.Repository) bool {
+	if repo.OwnerID != rs.OwnerID {
+		return false
+	}
+	rs.compileGlobs()
+	if len(rs.repoNameGlobs) > 0 && !slices.ContainsFunc(rs.repoNameGlobs, func(g glob.Glob) bool { return g.Match(repo.LowerName) }) {
+		return false
+	}
+	if len(rs.RepoTopics) > 0 && !slices.ContainsFunc(rs.RepoTopics, func(topic string) bool { return slices.Contains(repo.Topics, topic) }) {
+		return false
+	}
+	return true
+}
+
+// MatchRef returns whether the ruleset applies to the ref of the repository
+func (rs *Ruleset) MatchRef(repo *repo_model.Repository, refName git.RefName) bool {
+	rs.compileGlobs()
+	match := func(patterns []string, globs []glob.Glob) bool {
+		if slices.Contains(patterns, RulesetDefaultBranchPattern) && refName == git.RefNameFromBranch(repo.DefaultBranch) {
+			return true
+		}
+		return slices.ContainsFunc(globs, func(g glob.Glob) bool { return g.Match(refName.String()) })
+	}
+	return match(rs.RefPatterns, rs.refGlobs) && !match(rs.ExcludeRefPatterns, rs.excludeRefGlobs)
+}
+
+// CanUserBypass returns whether the user is in the bypass lists of the ruleset
+func (rs *Ruleset) CanUserBypass(ctx context.Context, user *user_model.User) bool {
+	if user == nil {
+		return false
+	}
+	if slices.Contains(rs.BypassUserIDs, user.ID) {
+		return true
+	}
+	if len(rs.BypassTeamIDs) == 0 {
+		return false
+	}
+	in, err := organization.IsUserInTeams(ctx, user.ID, rs.BypassTeamIDs)
+	if err != nil {
+		log.Error("IsUserInTeams: %v", err)
+		return false
+	}
+	return in
+}
+
+// ToProtectedBranch returns the protected branch rule of the branch of the repository made of the ruleset alone
+func (rs *Ruleset) ToProtectedBranch(repoID int64, branchName string) *ProtectedBranch {
+	return MergeRulesets(nil, repoID, branchName, []*Ruleset{rs})
+}
+
+// MergeRulesets returns the protected branch rule combining the rule of the repository, which can be nil, and the rulesets.
+// The rulesets only tighten the rule: the unprotected file patterns of the repository are ignored when a ruleset requires pull requests,
+// so they can't be used to push to the branch directly.
+func MergeRulesets(pb *ProtectedBranch, repoID int64, branchName string, rulesets []*Ruleset) *ProtectedBranch {
+	if len(rulesets) == 0 {
+		return pb
+	}
+	if pb == nil {
+		pb = &ProtectedBranch{RepoID: repoID, RuleName: branchName, CanPush: true, CanForcePush: true}
+	}
+	if !pb.EnableStatusCheck {
+		pb.StatusCheckContexts = nil
+	}
+	for _, rs := range rulesets {
+		if rs.RequirePullRequest {
+			pb.CanPush = false
+			pb.UnprotectedFilePatterns = ""
+		}
+		if rs.BlockForcePush {
+			pb.CanForcePush = false
+		}
+		pb.RequiredApprovals = max(pb.RequiredApprovals, rs.RequiredApprovals)
+		if rs.EnableStatusCheck {
+			pb.EnableStatusCheck = true
+			for _, context := range rs.StatusCheckContexts {
+				if !slices.Contains(pb.StatusCheckContexts, context) {
+					pb.StatusCheckContexts = append(pb.StatusCheckContexts, context)
+				}
+			}
+		}
+		pb.RequireSignedCommits = pb.RequireSignedCommits || rs.RequireSignedCommits
+		if rs.ProtectedFilePatterns != "" {
+			if pb.ProtectedFilePatterns != "" {
+				pb.ProtectedFilePatterns += ";"
+			}
+			pb.ProtectedFilePatterns += rs.ProtectedFilePatterns
+		}
+	}
+	pb.Rulesets = append(pb.Rulesets, rulesets...)
+	return pb
+}
+
+// GetRulesetByID returns the ruleset of the owner
+func GetRulesetByID(ctx context.Context, ownerID, id int64) (*Ruleset, error) {
+	var rs Ruleset
+	has, err := db.GetEngine(ctx).Where("owner_id=? AND id=?", ownerID, id).Get(&rs)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, fmt.Errorf("ruleset %d of owner %d: %w", id, ownerID, util.ErrNotExist)
+	}
+	return &rs, nil
+}
+
+// FindRulesetsOptions represents the options to find the rulesets
+type FindRulesetsOptions struct {
+	db.ListOptions
+	OwnerID     int64
+	Enforcement RulesetEnforcement
+}
+
+func (opts FindRulesetsOptions) ToConds() builder.Cond {
+	cond := builder.NewCond()
+	if opts.OwnerID != 0 {
+		cond = cond.And(builder.Eq{"owner_id": opts.OwnerID})
+	}
+	if opts.Enforcement != "" {
+		cond = cond.And(builder.Eq{"enforcement": opts.Enforcement})
+	}
+	return cond
+}
+
+func (opts FindRulesetsOptions) ToOrders() string {
+	return "name ASC"
+}
+
+// FindRulesetsForRepo returns the active and evaluated rulesets which apply to the repository
+func FindRulesetsForRepo(ctx context.Context, repo *repo_model.Repository) ([]*Ruleset, error) {
+	var rulesets []*Ruleset
+	if err := db.GetEngine(ctx).
+		Where("owner_id=? AND enforcement<>?", repo.OwnerID, RulesetEnforcementDisabled).
+		Asc("name").
+		Find(&rulesets); err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(rulesets, func(rs *Ruleset) bool { return !rs.MatchRepo(repo) }), nil
+}
+
+func isRulesetNameUsed(ctx context.Context, rs *Ruleset) (bool, error) {
+	return db.GetEngine(ctx).Where("owner_id=? AND name=? AND id<>?", rs.OwnerID, rs.Name, rs.ID).Exist(new(Ruleset))
+}
+
+// CreateRuleset creates a ruleset
+func CreateRuleset(ctx context.Context, rs *Ruleset) error {
+	if err := rs.Validate(); err != nil {
+		return err
+	}
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		if used, err := isRulesetNameUsed(ctx, rs); err != nil {
+			return err
+		} else if used {
+			return util.NewAlreadyExistErrorf("ruleset %q already exists", rs.Name)
+		}
+		return db.Insert(ctx, rs)
+	})
+}
+
+// UpdateRuleset updates all the columns of a ruleset
+func UpdateRuleset(ctx context.Context, rs *Ruleset) error {
+	if err := rs.Validate(); err != nil {
+		return err
+	}
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		if used, err := isRulesetNameUsed(ctx, rs); err != nil {
+			return err
+		} else if used {
+			return util.NewAlreadyExistErrorf("ruleset %q already exists", rs.Name)
+		}
+		_, err := db.GetEngine(ctx).ID(rs.ID).AllCols().Update(rs)
+		return err
+	})
+}
+
+// DeleteRuleset deletes the ruleset of the owner and its evaluations
+func DeleteRuleset(ctx context.Context, ownerID, id int64) error {
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		n, err := db.GetEngine(ctx).Where("owner_id=? AND id=?", ownerID, id).Delete(new(Ruleset))
+		if err != nil {
+			return err
+		} else if n == 0 {
+			return fmt.Errorf("ruleset %d of owner %d: %w", id, ownerID, util.ErrNotExist)
+		}
+		_, err = db.GetEngine(ctx).Where("ruleset_id=?", id).Delete(new(RulesetEvaluation))
+		return err
+	})
+}
+
+// DeleteRulesetsByOwnerID deletes all the rulesets of the owner and their evaluations
+func DeleteRulesetsByOwnerID(ctx context.Context, ownerID int64) error {
+	if _, err := db.GetEngine(ctx).
+		Where(builder.In("ruleset_id", builder.Select("id").From("ruleset").Where(builder.Eq{"owner_id": ownerID}))).
+		Delete(new(RulesetEvaluation)); err != nil {
+		return err
+	}
+	_, err := db.GetEngine(ctx).Where("owner_id=?", ownerID).Delete(new(Ruleset))
+	return err
+}
+
+// RulesetEvaluation records a push which a ruleset in evaluate mode would have rejected
+type RulesetEvaluation struct {
+	ID          int64                  `xorm:"pk autoincr"`
+	RulesetID   int64                  `xorm:"INDEX NOT NULL"`
+	RepoID      int64                  `xorm:"INDEX NOT NULL"`
+	Repo        *repo_model.Repository `xorm:"-"`
+	RefName     string                 `xorm:"TEXT"`
+	DoerID      int64                  `xorm:"NOT NULL DEFAULT 0"`
+	Doer        *user_model.User       `xorm:"-"`
+	Reason      string                 `xorm:"TEXT"`
+	CreatedUnix timeutil.TimeStamp     `xorm:"created INDEX"`
+}
+
+// InsertRulesetEvaluation records a push which a ruleset would have rejected
+func InsertRulesetEvaluation(ctx context.Context, evaluation *RulesetEvaluation) error {
+	return db.Insert(ctx, evaluation)
+}
+
+// FindRulesetEvaluationsOptions represents the options to find the evaluations of a ruleset
+type FindRulesetEvaluationsOptions struct {
+	db.ListOptions
+	RulesetID int64
+}
+
+func (opts FindRulesetEvaluationsOptions) ToConds() builder.Cond {
+	return builder.Eq{"ruleset_id": opts.RulesetID}
+}
+
+func (opts FindRulesetEvaluationsOptions) ToOrders() string {
+	return "id DESC"
+}
+
+// RulesetEvaluationList is a list of ruleset evaluations
+type RulesetEvaluationList []*RulesetEvaluation
+
+// LoadAttributes loads the repositories and the doers of the evaluations
+func (evaluations RulesetEvaluationList) LoadAttributes(ctx context.Context) error {
+	repoIDs := container.FilterSlice(evaluations, func(evaluation *RulesetEvaluation) (int64, bool) {
+		return evaluation.RepoID, true
+	})
+	repos, err := repo_model.GetRepositoriesMapByIDs(ctx, repoIDs)
+	if err != nil {
+		return err
+	}
+	doerIDs := container.FilterSlice(evaluations, func(evaluation *RulesetEvaluation) (int64, bool) {
+		return evaluation.DoerID, evaluation.DoerID > 0
+	})
+	doers, err := user_model.GetUsersMapByIDs(ctx, doerIDs)
+	if err != nil {
+		return err
+	}
+	for _, evaluation := range evaluations {
+		evaluation.Repo = repos[evaluation.RepoID]
+		if evaluation.Doer = doers[evaluation.DoerID]; evaluation.Doer == nil {
+			evaluation.Doer = user_model.NewGhostUser()
+		}
+	}
+	return nil
+}
+
+// DeleteRulesetEvaluationsByRepoID deletes the evaluations of the pushes to the repository
+func DeleteRulesetEvaluationsByRepoID(ctx context.Context, repoID int64) error {
+	_, err := db.GetEngine(ctx).Where("repo_id=?", repoID).Delete(new(RulesetEvaluation))
+	return err
+}
@@ -0,0 +1,198 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package git_test
+
+import (
+	"testing"
+
+	"github.com/kumose/kmup/models/db"
+	git_model "github.com/kumose/kmup/models/git"
+	repo_model "github.com/kumose/kmup/models/repo"
+	"github.com/kumose/kmup/models/unittest"
+	user_model "github.com/kumose/kmup/models/user"
+	"github.com/kumose/kmup/modules/git"
+	"github.com/kumose/kmup/modules/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRulesetMatch(t *testing.T) {
+	rs := &git_model.Ruleset{
+		OwnerID:            3,
+		Name:               " release ",
+		RepoNamePatterns:   []string{"Repo*"},
+		RepoTopics:         []string{"Go"},
+		RefPatterns:        []string{git_model.RulesetDefaultBranchPattern, "refs/heads/release/*", "refs/tags/v*"},
+		ExcludeRefPatterns: []string{"refs/tags/v*-rc*"},
+	}
+	require.NoError(t, rs.Validate())
+	assert.Equal(t, "release", rs.Name)
+	assert.Equal(t, git_model.RulesetEnforcementActive, rs.Enforcement)
+	assert.Equal(t, []string{"repo*"}, rs.RepoNamePatterns)
+	assert.Equal(t, []string{"go"}, rs.RepoTopics)
+
+	repo := &repo_model.Repository{OwnerID: 3, LowerName: "repo1", DefaultBranch: "main", Topics: []string{"go", "web"}}
+	assert.True(t, rs.MatchRepo(repo))
+	assert.False(t, rs.MatchRepo(&repo_model.Repository{OwnerID: 3, LowerName: "repo1"}))
+	assert.False(t, rs.MatchRepo(&repo_model.Repository{OwnerID: 3, LowerName: "other", Topics: []string{"go"}}))
+	assert.False(t, rs.MatchRepo(&repo_model.Repository{OwnerID: 4, LowerName: "repo1", Topics: []string{"go"}}))
+
+	assert.True(t, rs.MatchRef(repo, git.RefNameFromBranch("main")))
+	assert.True(t, rs.MatchRef(repo, git.RefNameFromBranch("release/v1")))
+	assert.False(t, rs.MatchRef(repo, git.RefNameFromBranch("release/v1/fix")))
+	assert.False(t, rs.MatchRef(repo, git.RefNameFromBranch("feature")))
+	assert.True(t, rs.MatchRef(repo, git.RefNameFromTag("v1.0")))
+	assert.False(t, rs.MatchRef(repo, git.RefNameFromTag("v1.0-rc1")))
+
+	assert.ErrorIs(t, (&git_model.Ruleset{Name: "a"}).Validate(), util.ErrInvalidArgument)
+	assert.ErrorIs(t, (&git_model.Ruleset{Name: "a", RefPatterns: []string{"main"}}).Validate(), util.ErrInvalidArgument)
+	assert.ErrorIs(t, (&git_model.Ruleset{Name: "a", RefPatterns: []string{"refs/heads/["}}).Validate(), util.ErrInvalidArgument)
+	assert.ErrorIs(t, (&git_model.Ruleset{Name: "a", RefPatterns: []string{"refs/heads/*"}, Enforcement: "strict"}).Validate(), util.ErrInvalidArgument)
+}
+
+func TestMergeRulesets(t *testing.T) {
+	assert.Nil(t, git_model.MergeRulesets(nil, 1, "main", nil))
+
+	pb := &git_model.ProtectedBranch{
+		RepoID:                  1,
+		RuleName:                "main",
+		CanPush:                 true,
+		RequiredApprovals:       1,
+		EnableStatusCheck:       true,
+		StatusCheckContexts:     []string{"ci/build"},
+		ProtectedFilePatterns:   "*.md",
+		UnprotectedFilePatterns: "docs/**",
+	}
+	pb = git_model.MergeRulesets(pb, 1, "main", []*git_model.Ruleset{
+		{RequiredApprovals: 2, EnableStatusCheck: true, StatusCheckContexts: []string{"ci/build", "ci/lint"}},
+		{RequirePullRequest: true, BlockForcePush: true, RequireSignedCommits: true, ProtectedFilePatterns: "go.mod"},
+	})
+	assert.False(t, pb.CanPush)
+	assert.False(t, pb.CanForcePush)
+	assert.Empty(t, pb.UnprotectedFilePatterns)
+	assert.EqualValues(t, 2, pb.RequiredApprovals)
+	assert.Equal(t, []string{"ci/build", "ci/lint"}, pb.StatusCheckContexts)
+	assert.True(t, pb.RequireSignedCommits)
+	assert.Equal(t, "*.md;go.mod", pb.ProtectedFilePatterns)
+	assert.Len(t, pb.Rulesets, 2)
+
+	// without a rule of the repository, the ruleset alone protects the branch
+	pb = git_model.MergeRulesets(nil, 1, "main", []*git_model.Ruleset{{BlockDeletion: true}})
+	assert.True(t, pb.CanPush)
+	assert.True(t, pb.CanForcePush)
+	assert.True(t, pb.IsDeletionProtected())
+	assert.False(t, pb.IsCreationProtected())
+}
+
+func TestGetFirstMatchProtectedBranchRuleForUser(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 3})
+	owner := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+	other := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 4})
+
+	require.NoError(t, git_model.CreateRuleset(t.Context(), &git_model.Ruleset{
+		OwnerID:        repo.OwnerID,
+		Name:           "default",
+		RefPatterns:    []string{git_model.RulesetDefaultBranchPattern},
+		BlockForcePush: true,
+		BypassUserIDs:  []int64{owner.ID},
+	}))
+	require.NoError(t, git_model.CreateRuleset(t.Context(), &git_model.Ruleset{
+		OwnerID:       repo.OwnerID,
+		Name:          "evaluated",
+		Enforcement:   git_model.RulesetEnforcementEvaluate,
+		RefPatterns:   []string{"refs/heads/*"},
+		BlockDeletion: true,
+	}))
+	assert.ErrorIs(t, git_model.CreateRuleset(t.Context(), &git_model.Ruleset{
+		OwnerID:     repo.OwnerID,
+		Name:        "default",
+		RefPatterns: []string{"refs/heads/*"},
+	}), util.ErrAlreadyExist)
+
+	pb, err := git_model.GetFirstMatchProtectedBranchRuleForUser(t.Context(), repo.ID, repo.DefaultBranch, other)
+	require.NoError(t, err)
+	require.NotNil(t, pb)
+	assert.False(t, pb.CanForcePush)
+	require.Len(t, pb.Rulesets, 1)
+	assert.Equal(t, "default", pb.Rulesets[0].Name)
+
+	// the rulesets in evaluate mode are not merged and the users in the bypass lists are not restricted
+	pb, err = git_model.GetFirstMatchProtectedBranchRuleForUser(t.Context(), repo.ID, repo.DefaultBranch, owner)
+	require.NoError(t, err)
+	assert.Nil(t, pb)
+	pb, err = git_model.GetFirstMatchProtectedBranchRuleForUser(t.Context(), repo.ID, "feature", other)
+	require.NoError(t, err)
+	assert.Nil(t, pb)
+
+	rulesets, err := git_model.FindRulesetsForRepo(t.Context(), repo)
+	require.NoError(t, err)
+	assert.Len(t, rulesets, 2)
+
+	require.NoError(t, git_model.InsertRulesetEvaluation(t.Context(), &git_model.RulesetEvaluation{
+		RulesetID: rulesets[1].ID,
+		RepoID:    repo.ID,
+		RefName:   "refs/heads/feature",
+		DoerID:    other.ID,
+		Reason:    "deletion is restricted",
+	}))
+	require.NoError(t, git_model.DeleteRuleset(t.Context(), repo.OwnerID, rulesets[1].ID))
+	unittest.AssertNotExistsBean(t, &git_model.RulesetEvaluation{RulesetID: rulesets[1].ID})
+
+	require.NoError(t, git_model.DeleteRulesetsByOwnerID(t.Context(), repo.OwnerID))
+	count, err := db.Count[git_model.Ruleset](t.Context(), git_model.FindRulesetsOptions{OwnerID: repo.OwnerID})
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestFindEffectiveBranchRules(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 3})
+	require.NoError(t, git_model.UpdateProtectBranch(t.Context(), repo, &git_model.ProtectedBranch{
+		RepoID:              repo.ID,
+		RuleName:            "*",
+		EnableStatusCheck:   true,
+		StatusCheckContexts: []string{"build"},
+	}, git_model.WhitelistOptions{}))
+	require.NoError(t, git_model.CreateRuleset(t.Context(), &git_model.Ruleset{
+		OwnerID:             repo.OwnerID,
+		Name:                "default",
+		RefPatterns:         []string{git_model.RulesetDefaultBranchPattern},
+		RequiredApprovals:   2,
+		EnableStatusCheck:   true,
+		StatusCheckContexts: []string{"test"},
+	}))
+
+	rules, err := git_model.FindEffectiveBranchRules(t.Context(), repo.ID)
+	require.NoError(t, err)
+	pb := rules.GetFirstMatchedForUser(t.Context(), repo.DefaultBranch, nil)
+	require.NotNil(t, pb)
+	assert.EqualValues(t, 2, pb.RequiredApprovals)
+	assert.Equal(t, []string{"build", "test"}, pb.StatusCheckContexts)
+	assert.Len(t, pb.Rulesets, 1)
+
+	// the rule shared with the default branch is left untouched
+	pb = rules.GetFirstMatchedForUser(t.Context(), "feature", nil)
+	require.NotNil(t, pb)
+	assert.Zero(t, pb.RequiredApprovals)
+	assert.Equal(t, []string{"build"}, pb.StatusCheckContexts)
+	assert.Empty(t, pb.Rulesets)
+}
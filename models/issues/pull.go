@@ -188,6 +188,12 @@ func DeletePullsByBaseRepoID(ctx context.Context, repoID int64) error {
 		return err
 	}
 
+	// Delete merge queue entries
+	if _, err := db.GetEngine(ctx).In("pull_id", deleteCond).
+		Delete(&pull_model.MergeQueueEntry{}); err != nil {
+		return err
+	}
+
 	_, err := db.DeleteByBean(ctx, &PullRequest{BaseRepoID: repoID})
 	return err
 }
@@ -874,6 +880,11 @@ type CodeOwnerRule struct {
 	Teams    []*org_model.Team
 }
 
+// Match returns true if the owners of the rule own the file
+func (rule *CodeOwnerRule) Match(file string) bool {
+	return rule.Rule.MatchString(file) != rule.Negative
+}
+
 func ParseCodeOwnersLine(ctx context.Context, tokens []string) (*CodeOwnerRule, []string) {
 	var err error
 	rule := &CodeOwnerRule{
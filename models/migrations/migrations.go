@@ -412,6 +412,24 @@ func prepareMigrationTasks() []*migration {
 		// Kmup 1.25.0 ends at migration ID number 322 (database version 323)
 
 		newMigration(323, "Add support for actions concurrency", v1_26.AddActionsConcurrency),
+		newMigration(324, "Add support for reusable workflows", v1_26.AddReusableWorkflowSupport),
+		newMigration(325, "Add environment to action run job", v1_26.AddEnvironmentToActionRunJob),
+		newMigration(326, "Add deployment environments for actions", v1_26.AddActionsDeploymentEnvironments),
+		newMigration(327, "Add actions cache", v1_26.AddActionsCache),
+		newMigration(328, "Add actions required workflows", v1_26.AddActionsRequiredWorkflows),
+		newMigration(329, "Add actions task annotations", v1_26.AddActionsTaskAnnotations),
+		newMigration(330, "Add actions test reports", v1_26.AddActionsTestReports),
+		newMigration(331, "Add actions runner groups", v1_26.AddActionsRunnerGroups),
+		newMigration(332, "Add actions run attempts", v1_26.AddActionsRunAttempts),
+		newMigration(333, "Add package remotes", v1_26.AddPackageRemotes),
+		newMigration(334, "Add package signers", v1_26.AddPackageSigners),
+		newMigration(335, "Add package advisories and vulnerabilities", v1_26.AddPackageAdvisories),
+		newMigration(336, "Add package cleanup run table", v1_26.AddPackageCleanupRunTable),
+		newMigration(337, "Add require code owner approval to protected branch", v1_26.AddRequireCodeOwnerApprovalToProtectedBranch),
+		newMigration(338, "Add merge queue", v1_26.AddMergeQueue),
+		newMigration(339, "Add push rule table", v1_26.AddPushRuleTable),
+		newMigration(340, "Add secret scanning tables", v1_26.AddSecretScanningTables),
+		newMigration(341, "Add org rulesets tables", v1_26.AddRulesetTables),
 	}
 	return preparedMigrations
 }
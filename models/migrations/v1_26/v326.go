@@ -0,0 +1,82 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package v1_26
+
+import (
+	"github.com/kumose/kmup/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func AddActionsDeploymentEnvironments(x *xorm.Engine) error {
+	type ActionEnvironment struct {
+		ID             int64
+		RepoID         int64    `xorm:"UNIQUE(repo_name) NOT NULL"`
+		Name           string   `xorm:"VARCHAR(255) NOT NULL"`
+		LowerName      string   `xorm:"VARCHAR(255) UNIQUE(repo_name) NOT NULL"`
+		ReviewerIDs    []int64  `xorm:"JSON TEXT"`
+		WaitTimer      int64    `xorm:"NOT NULL DEFAULT 0"`
+		BranchPatterns []string `xorm:"JSON TEXT"`
+		TagPatterns    []string `xorm:"JSON TEXT"`
+
+		Created timeutil.TimeStamp `xorm:"created"`
+		Updated timeutil.TimeStamp `xorm:"updated"`
+	}
+
+	type ActionDeployment struct {
+		ID            int64
+		RepoID        int64 `xorm:"index"`
+		EnvironmentID int64 `xorm:"index"`
+		RunID         int64 `xorm:"index"`
+		RunJobID      int64 `xorm:"index"`
+		Ref           string
+		CommitSHA     string
+		CreatorID     int64
+		Status        int `xorm:"index"`
+		ReviewerID    int64
+		ReviewComment string `xorm:"TEXT"`
+		Reviewed      timeutil.TimeStamp
+
+		Created timeutil.TimeStamp `xorm:"created"`
+		Updated timeutil.TimeStamp `xorm:"updated"`
+	}
+
+	type ActionRunJob struct {
+		EnvironmentID int64 `xorm:"NOT NULL DEFAULT 0"`
+	}
+
+	// the environment is a part of the unique keys of secrets and variables
+	type Secret struct {
+		OwnerID       int64  `xorm:"INDEX UNIQUE(owner_repo_name) NOT NULL"`
+		RepoID        int64  `xorm:"INDEX UNIQUE(owner_repo_name) NOT NULL DEFAULT 0"`
+		EnvironmentID int64  `xorm:"INDEX UNIQUE(owner_repo_name) NOT NULL DEFAULT 0"`
+		Name          string `xorm:"UNIQUE(owner_repo_name) NOT NULL"`
+	}
+
+	type ActionVariable struct {
+		OwnerID       int64  `xorm:"UNIQUE(owner_repo_name)"`
+		RepoID        int64  `xorm:"INDEX UNIQUE(owner_repo_name)"`
+		EnvironmentID int64  `xorm:"INDEX UNIQUE(owner_repo_name) NOT NULL DEFAULT 0"`
+		Name          string `xorm:"UNIQUE(owner_repo_name) NOT NULL"`
+	}
+
+	_, err := x.SyncWithOptions(xorm.SyncOptions{
+		IgnoreDropIndices: true,
+	}, new(ActionEnvironment), new(ActionDeployment), new(ActionRunJob), new(Secret), new(ActionVariable))
+	return err
+}
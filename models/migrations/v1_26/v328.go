@@ -0,0 +1,52 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package v1_26
+
+import (
+	"github.com/kumose/kmup/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func AddActionsRequiredWorkflows(x *xorm.Engine) error {
+	type ActionRequiredWorkflow struct {
+		ID           int64
+		OwnerID      int64    `xorm:"index NOT NULL DEFAULT 0"`
+		RepoID       int64    `xorm:"index NOT NULL"`
+		WorkflowPath string   `xorm:"VARCHAR(255) NOT NULL"`
+		Ref          string   `xorm:"VARCHAR(255)"`
+		RepoPatterns []string `xorm:"JSON TEXT"`
+		Topics       []string `xorm:"JSON TEXT"`
+
+		Created timeutil.TimeStamp `xorm:"created"`
+		Updated timeutil.TimeStamp `xorm:"updated"`
+	}
+
+	type ActionRun struct {
+		RequiredWorkflowID int64 `xorm:"index NOT NULL DEFAULT 0"`
+	}
+
+	type ProtectedBranch struct {
+		RequireRequiredWorkflows bool `xorm:"NOT NULL DEFAULT false"`
+	}
+
+	_, err := x.SyncWithOptions(xorm.SyncOptions{
+		IgnoreDropIndices: true,
+	}, new(ActionRequiredWorkflow), new(ActionRun), new(ProtectedBranch))
+	return err
+}
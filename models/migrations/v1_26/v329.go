@@ -0,0 +1,50 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package v1_26
+
+import (
+	"github.com/kumose/kmup/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func AddActionsTaskAnnotations(x *xorm.Engine) error {
+	type ActionTaskAnnotation struct {
+		ID          int64
+		TaskID      int64  `xorm:"index"`
+		JobID       int64  `xorm:"index"`
+		RunID       int64  `xorm:"index"`
+		RepoID      int64  `xorm:"index(repo_commit)"`
+		CommitSHA   string `xorm:"VARCHAR(64) index(repo_commit)"`
+		LogIndex    int64
+		Level       string `xorm:"VARCHAR(16)"`
+		Title       string `xorm:"VARCHAR(255)"`
+		Message     string `xorm:"TEXT"`
+		Path        string `xorm:"VARCHAR(500)"`
+		StartLine   int
+		EndLine     int
+		StartColumn int
+		EndColumn   int
+		Created     timeutil.TimeStamp `xorm:"created"`
+	}
+
+	_, err := x.SyncWithOptions(xorm.SyncOptions{
+		IgnoreDropIndices: true,
+	}, new(ActionTaskAnnotation))
+	return err
+}
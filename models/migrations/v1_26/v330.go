@@ -0,0 +1,66 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package v1_26
+
+import (
+	"time"
+
+	"github.com/kumose/kmup/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func AddActionsTestReports(x *xorm.Engine) error {
+	type ActionTestReport struct {
+		ID        int64
+		RepoID    int64  `xorm:"index(repo_commit)"`
+		CommitSHA string `xorm:"VARCHAR(64) index(repo_commit)"`
+		RunID     int64  `xorm:"index"`
+		JobID     int64  `xorm:"index"`
+		TaskID    int64  `xorm:"index"`
+		Name      string `xorm:"VARCHAR(255)"`
+		Total     int64
+		Passed    int64
+		Failed    int64
+		Skipped   int64
+		Duration  time.Duration
+		Created   timeutil.TimeStamp `xorm:"created"`
+	}
+
+	type ActionTestCase struct {
+		ID        int64
+		ReportID  int64  `xorm:"index"`
+		RepoID    int64  `xorm:"index(repo_case)"`
+		CaseKey   string `xorm:"VARCHAR(40) index(repo_case)"`
+		RunID     int64  `xorm:"index"`
+		JobID     int64
+		TaskID    int64  `xorm:"index"`
+		Suite     string `xorm:"VARCHAR(255)"`
+		ClassName string `xorm:"VARCHAR(255)"`
+		Name      string `xorm:"VARCHAR(500)"`
+		Status    string `xorm:"VARCHAR(16)"`
+		Duration  time.Duration
+		Message   string             `xorm:"TEXT"`
+		Created   timeutil.TimeStamp `xorm:"created index"`
+	}
+
+	_, err := x.SyncWithOptions(xorm.SyncOptions{
+		IgnoreDropIndices: true,
+	}, new(ActionTestReport), new(ActionTestCase))
+	return err
+}
@@ -0,0 +1,47 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package v1_26
+
+import (
+	"github.com/kumose/kmup/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func AddActionsRunnerGroups(x *xorm.Engine) error {
+	type ActionRunnerGroup struct {
+		ID               int64
+		Name             string   `xorm:"VARCHAR(255) UNIQUE NOT NULL"`
+		Description      string   `xorm:"TEXT"`
+		AllowedOwnerIDs  []int64  `xorm:"JSON TEXT"`
+		AllowedRepoIDs   []int64  `xorm:"JSON TEXT"`
+		AllowedWorkflows []string `xorm:"JSON TEXT"`
+
+		Created timeutil.TimeStamp `xorm:"created"`
+		Updated timeutil.TimeStamp `xorm:"updated"`
+	}
+
+	type ActionRunner struct {
+		GroupID int64 `xorm:"index NOT NULL DEFAULT 0"`
+	}
+
+	_, err := x.SyncWithOptions(xorm.SyncOptions{
+		IgnoreDropIndices: true,
+	}, new(ActionRunnerGroup), new(ActionRunner))
+	return err
+}
@@ -0,0 +1,57 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package v1_26
+
+import (
+	"xorm.io/xorm"
+)
+
+func AddActionsRunAttempts(x *xorm.Engine) error {
+	type ActionRun struct {
+		Attempt int64 `xorm:"NOT NULL DEFAULT 1"`
+	}
+
+	type ActionRunJob struct {
+		DebugLogging bool `xorm:"NOT NULL DEFAULT false"`
+	}
+
+	type ActionTask struct {
+		RunAttempt int64 `xorm:"NOT NULL DEFAULT 1"`
+	}
+
+	// the tables above have unique constraints which are not declared here, so they must be kept
+	if _, err := x.SyncWithOptions(xorm.SyncOptions{
+		IgnoreDropIndices: true,
+		IgnoreConstrains:  true,
+	}, new(ActionRun), new(ActionRunJob), new(ActionTask)); err != nil {
+		return err
+	}
+
+	// the artifacts of different attempts of a run can have the same name and path
+	type ActionArtifact struct {
+		RunID        int64  `xorm:"index unique(runid_name_path)"`
+		RunAttempt   int64  `xorm:"unique(runid_name_path) NOT NULL DEFAULT 1"`
+		ArtifactPath string `xorm:"index unique(runid_name_path)"`
+		ArtifactName string `xorm:"index unique(runid_name_path)"`
+	}
+
+	_, err := x.SyncWithOptions(xorm.SyncOptions{
+		IgnoreDropIndices: true,
+	}, new(ActionArtifact))
+	return err
+}
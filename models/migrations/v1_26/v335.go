@@ -0,0 +1,56 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package v1_26
+
+import (
+	"github.com/kumose/kmup/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func AddPackageAdvisories(x *xorm.Engine) error {
+	type PackageAdvisory struct {
+		ID           int64              `xorm:"pk autoincr"`
+		AdvisoryID   string             `xorm:"UNIQUE NOT NULL"`
+		Summary      string             `xorm:"TEXT"`
+		Severity     string             `xorm:"NOT NULL DEFAULT ''"`
+		Content      string             `xorm:"LONGTEXT NOT NULL"`
+		ModifiedUnix timeutil.TimeStamp `xorm:"NOT NULL DEFAULT 0"`
+		CreatedUnix  timeutil.TimeStamp `xorm:"created NOT NULL DEFAULT 0"`
+		UpdatedUnix  timeutil.TimeStamp `xorm:"updated NOT NULL DEFAULT 0"`
+	}
+
+	type PackageAdvisoryAffected struct {
+		ID         int64  `xorm:"pk autoincr"`
+		AdvisoryID int64  `xorm:"INDEX NOT NULL"`
+		Type       string `xorm:"INDEX(s) NOT NULL"`
+		LowerName  string `xorm:"INDEX(s) NOT NULL"`
+	}
+
+	type PackageVulnerability struct {
+		ID          int64              `xorm:"pk autoincr"`
+		VersionID   int64              `xorm:"UNIQUE(s) INDEX NOT NULL"`
+		AdvisoryID  int64              `xorm:"UNIQUE(s) INDEX NOT NULL"`
+		Name        string             `xorm:"UNIQUE(s) NOT NULL"`
+		Version     string             `xorm:"NOT NULL"`
+		Requirement string             `xorm:"NOT NULL DEFAULT ''"`
+		CreatedUnix timeutil.TimeStamp `xorm:"created NOT NULL DEFAULT 0"`
+	}
+
+	return x.Sync(new(PackageAdvisory), new(PackageAdvisoryAffected), new(PackageVulnerability))
+}
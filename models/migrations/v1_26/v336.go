@@ -0,0 +1,40 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package v1_26
+
+import (
+	"github.com/kumose/kmup/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func AddPackageCleanupRunTable(x *xorm.Engine) error {
+	type PackageCleanupRun struct {
+		ID           int64              `xorm:"pk autoincr"`
+		RuleID       int64              `xorm:"INDEX NOT NULL"`
+		OwnerID      int64              `xorm:"INDEX NOT NULL"`
+		Type         string             `xorm:"NOT NULL"`
+		VersionCount int                `xorm:"NOT NULL DEFAULT 0"`
+		FailedCount  int                `xorm:"NOT NULL DEFAULT 0"`
+		Size         int64              `xorm:"NOT NULL DEFAULT 0"`
+		Versions     []string           `xorm:"JSON LONGTEXT"`
+		CreatedUnix  timeutil.TimeStamp `xorm:"created INDEX NOT NULL DEFAULT 0"`
+	}
+
+	return x.Sync(new(PackageCleanupRun))
+}
@@ -0,0 +1,53 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package v1_26
+
+import (
+	"github.com/kumose/kmup/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func AddMergeQueue(x *xorm.Engine) error {
+	type ProtectedBranch struct {
+		EnableMergeQueue bool `xorm:"NOT NULL DEFAULT false"`
+	}
+
+	type MergeQueueEntry struct {
+		ID                     int64  `xorm:"pk autoincr"`
+		RepoID                 int64  `xorm:"INDEX(s) NOT NULL"`
+		BaseBranch             string `xorm:"INDEX(s) NOT NULL"`
+		PullID                 int64  `xorm:"UNIQUE NOT NULL"`
+		DoerID                 int64  `xorm:"INDEX NOT NULL"`
+		MergeStyle             string `xorm:"varchar(30)"`
+		Message                string `xorm:"LONGTEXT"`
+		DeleteBranchAfterMerge bool
+		HeadCommitID           string             `xorm:"VARCHAR(64)"`
+		BaseCommitID           string             `xorm:"VARCHAR(64)"`
+		GroupCommitID          string             `xorm:"VARCHAR(64)"`
+		CreatedUnix            timeutil.TimeStamp `xorm:"created"`
+		UpdatedUnix            timeutil.TimeStamp `xorm:"updated"`
+	}
+
+	if _, err := x.SyncWithOptions(xorm.SyncOptions{
+		IgnoreDropIndices: true,
+	}, new(ProtectedBranch)); err != nil {
+		return err
+	}
+	return x.Sync(new(MergeQueueEntry))
+}
@@ -0,0 +1,45 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package v1_26
+
+import (
+	"github.com/kumose/kmup/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func AddPushRuleTable(x *xorm.Engine) error {
+	type PushRule struct {
+		ID      int64 `xorm:"pk autoincr"`
+		OwnerID int64 `xorm:"UNIQUE(owner_repo) NOT NULL DEFAULT 0"`
+		RepoID  int64 `xorm:"UNIQUE(owner_repo) NOT NULL DEFAULT 0"`
+
+		MaxFileSize          int64    `xorm:"NOT NULL DEFAULT 0"`
+		ForbiddenPaths       []string `xorm:"JSON TEXT"`
+		ForbiddenExtensions  []string `xorm:"JSON TEXT"`
+		CommitMessagePattern string   `xorm:"TEXT"`
+		AuthorEmailDomains   []string `xorm:"JSON TEXT"`
+		RejectMergeCommits   bool     `xorm:"NOT NULL DEFAULT false"`
+		RejectSecrets        bool     `xorm:"NOT NULL DEFAULT false"`
+
+		CreatedUnix timeutil.TimeStamp `xorm:"created"`
+		UpdatedUnix timeutil.TimeStamp `xorm:"updated"`
+	}
+
+	return x.Sync(new(PushRule))
+}
@@ -0,0 +1,64 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package v1_26
+
+import (
+	"github.com/kumose/kmup/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+type secretScanAlert struct {
+	ID             int64  `xorm:"pk autoincr"`
+	RepoID         int64  `xorm:"UNIQUE(s) NOT NULL"`
+	SecretHash     string `xorm:"UNIQUE(s) VARCHAR(64) NOT NULL"`
+	RuleID         string `xorm:"VARCHAR(255) NOT NULL"`
+	Description    string `xorm:"VARCHAR(255)"`
+	RedactedSecret string `xorm:"VARCHAR(255)"`
+	CommitID       string `xorm:"VARCHAR(64)"`
+	Path           string `xorm:"TEXT"`
+	Line           int    `xorm:"NOT NULL DEFAULT 0"`
+	State          string `xorm:"VARCHAR(20) INDEX NOT NULL DEFAULT 'open'"`
+	ResolverID     int64  `xorm:"NOT NULL DEFAULT 0"`
+
+	ResolvedUnix timeutil.TimeStamp
+	CreatedUnix  timeutil.TimeStamp `xorm:"created"`
+	UpdatedUnix  timeutil.TimeStamp `xorm:"updated"`
+}
+
+func (secretScanAlert) TableName() string {
+	return "secret_scan_alert"
+}
+
+type secretScanRepoSetting struct {
+	ID                  int64  `xorm:"pk autoincr"`
+	RepoID              int64  `xorm:"UNIQUE NOT NULL"`
+	PushProtection      string `xorm:"VARCHAR(20) NOT NULL DEFAULT ''"`
+	LastHistoryScanUnix timeutil.TimeStamp
+
+	CreatedUnix timeutil.TimeStamp `xorm:"created"`
+	UpdatedUnix timeutil.TimeStamp `xorm:"updated"`
+}
+
+func (secretScanRepoSetting) TableName() string {
+	return "secret_scan_repo_setting"
+}
+
+func AddSecretScanningTables(x *xorm.Engine) error {
+	return x.Sync(new(secretScanAlert), new(secretScanRepoSetting))
+}
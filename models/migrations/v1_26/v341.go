@@ -0,0 +1,66 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package v1_26
+
+import (
+	"github.com/kumose/kmup/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+type ruleset struct {
+	ID          int64  `xorm:"pk autoincr"`
+	OwnerID     int64  `xorm:"UNIQUE(s) NOT NULL"`
+	Name        string `xorm:"UNIQUE(s) NOT NULL"`
+	Enforcement string `xorm:"VARCHAR(16) NOT NULL DEFAULT 'active'"`
+
+	RepoNamePatterns   []string `xorm:"JSON TEXT"`
+	RepoTopics         []string `xorm:"JSON TEXT"`
+	RefPatterns        []string `xorm:"JSON TEXT"`
+	ExcludeRefPatterns []string `xorm:"JSON TEXT"`
+
+	BlockCreation         bool     `xorm:"NOT NULL DEFAULT false"`
+	BlockDeletion         bool     `xorm:"NOT NULL DEFAULT false"`
+	BlockForcePush        bool     `xorm:"NOT NULL DEFAULT false"`
+	RequirePullRequest    bool     `xorm:"NOT NULL DEFAULT false"`
+	RequiredApprovals     int64    `xorm:"NOT NULL DEFAULT 0"`
+	EnableStatusCheck     bool     `xorm:"NOT NULL DEFAULT false"`
+	StatusCheckContexts   []string `xorm:"JSON TEXT"`
+	RequireSignedCommits  bool     `xorm:"NOT NULL DEFAULT false"`
+	ProtectedFilePatterns string   `xorm:"TEXT"`
+
+	BypassUserIDs []int64 `xorm:"JSON TEXT"`
+	BypassTeamIDs []int64 `xorm:"JSON TEXT"`
+
+	CreatedUnix timeutil.TimeStamp `xorm:"created"`
+	UpdatedUnix timeutil.TimeStamp `xorm:"updated"`
+}
+
+type rulesetEvaluation struct {
+	ID          int64              `xorm:"pk autoincr"`
+	RulesetID   int64              `xorm:"INDEX NOT NULL"`
+	RepoID      int64              `xorm:"INDEX NOT NULL"`
+	RefName     string             `xorm:"TEXT"`
+	DoerID      int64              `xorm:"NOT NULL DEFAULT 0"`
+	Reason      string             `xorm:"TEXT"`
+	CreatedUnix timeutil.TimeStamp `xorm:"created INDEX"`
+}
+
+func AddRulesetTables(x *xorm.Engine) error {
+	return x.Sync(new(ruleset), new(rulesetEvaluation))
+}
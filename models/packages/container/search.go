@@ -262,6 +262,51 @@ func SearchExpiredUploadedBlobs(ctx context.Context, olderThan time.Duration) ([
 		Find(&pfs)
 }
 
+// FindOrphanedReferrers gets all manifests whose subject manifest does not exist anymore
+// If packageID is 0, the referrers of all container packages are searched.
+func FindOrphanedReferrers(ctx context.Context, packageID int64) ([]*packages.PackageVersion, error) {
+	var cond builder.Cond = builder.Eq{
+		"package.type":                packages.TypeContainer,
+		"package_version.is_internal": false,
+	}
+	if packageID != 0 {
+		cond = cond.And(builder.Eq{"package_version.package_id": packageID})
+	}
+
+	cond = cond.And(builder.NotExists(
+		builder.
+			Select("pf.id").
+			From("package_file", "pf").
+			Join("INNER", "package_version pfv", "pfv.id = pf.version_id").
+			Join("INNER", "package_property pfp", "pfp.ref_id = pf.id").
+			Where(builder.Eq{
+				"pf.lower_name": container_module.ManifestFilename,
+				"pfp.ref_type":  packages.PropertyTypeFile,
+				"pfp.name":      container_module.PropertyDigest,
+			}.And(builder.Expr("pfv.package_id = package_version.package_id")).And(builder.Expr("pfp.value = package_property.value"))),
+	))
+
+	pvs := make([]*packages.PackageVersion, 0, 10)
+	return pvs, db.GetEngine(ctx).
+		Join("INNER", "package", "package.id = package_version.package_id").
+		Join("INNER", "package_property", "package_property.ref_id = package_version.id AND package_property.ref_type = ? AND package_property.name = ?", packages.PropertyTypeVersion, container_module.PropertyManifestSubject).
+		Where(cond).
+		Find(&pvs)
+}
+
+// GetIndexVersionsReferencingManifest gets all image index versions of the package which reference the manifest digest
+func GetIndexVersionsReferencingManifest(ctx context.Context, packageID int64, digest string) ([]*packages.PackageVersion, error) {
+	pvs := make([]*packages.PackageVersion, 0, 10)
+	return pvs, db.GetEngine(ctx).
+		Join("INNER", "package_property", "package_property.ref_id = package_version.id AND package_property.ref_type = ? AND package_property.name = ?", packages.PropertyTypeVersion, container_module.PropertyManifestReference).
+		Where(builder.Eq{
+			"package_version.package_id":  packageID,
+			"package_version.is_internal": false,
+			"package_property.value":      digest,
+		}).
+		Find(&pvs)
+}
+
 // GetRepositories gets a sorted list of all repositories
 func GetRepositories(ctx context.Context, actor *user_model.User, n int, last string) ([]string, error) {
 	var cond builder.Cond = builder.Eq{
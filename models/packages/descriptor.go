@@ -38,6 +38,7 @@ import (
 	"github.com/kumose/kmup/modules/packages/cran"
 	"github.com/kumose/kmup/modules/packages/debian"
 	"github.com/kumose/kmup/modules/packages/helm"
+	"github.com/kumose/kmup/modules/packages/hex"
 	"github.com/kumose/kmup/modules/packages/maven"
 	"github.com/kumose/kmup/modules/packages/npm"
 	"github.com/kumose/kmup/modules/packages/nuget"
@@ -46,6 +47,7 @@ import (
 	"github.com/kumose/kmup/modules/packages/rpm"
 	"github.com/kumose/kmup/modules/packages/rubygems"
 	"github.com/kumose/kmup/modules/packages/swift"
+	"github.com/kumose/kmup/modules/packages/terraform"
 	"github.com/kumose/kmup/modules/packages/vagrant"
 	"github.com/kumose/kmup/modules/util"
 
@@ -65,6 +67,16 @@ func (l PackagePropertyList) GetByName(name string) string {
 	return ""
 }
 
+// Has checks if a property with the specific name exists
+func (l PackagePropertyList) Has(name string) bool {
+	for _, pp := range l {
+		if pp.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 // PackageDescriptor describes a package
 type PackageDescriptor struct {
 	Package           *Package
@@ -201,6 +213,8 @@ func GetPackageDescriptorWithCache(ctx context.Context, pv *PackageVersion, c *c
 		// go packages have no metadata
 	case TypeHelm:
 		metadata = &helm.Metadata{}
+	case TypeHex:
+		metadata = &hex.Metadata{}
 	case TypeNuGet:
 		metadata = &nuget.Metadata{}
 	case TypeNpm:
@@ -217,6 +231,12 @@ func GetPackageDescriptorWithCache(ctx context.Context, pv *PackageVersion, c *c
 		metadata = &rubygems.Metadata{}
 	case TypeSwift:
 		metadata = &swift.Metadata{}
+	case TypeTerraformModule:
+		metadata = &terraform.ModuleMetadata{}
+	case TypeTerraformProvider:
+		// terraform provider details are stored as properties
+	case TypeTerraformState:
+		metadata = &terraform.StateMetadata{}
 	case TypeVagrant:
 		metadata = &vagrant.Metadata{}
 	default:
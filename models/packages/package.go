@@ -44,28 +44,32 @@ type Type string
 
 // List of supported packages
 const (
-	TypeAlpine    Type = "alpine"
-	TypeArch      Type = "arch"
-	TypeCargo     Type = "cargo"
-	TypeChef      Type = "chef"
-	TypeComposer  Type = "composer"
-	TypeConan     Type = "conan"
-	TypeConda     Type = "conda"
-	TypeContainer Type = "container"
-	TypeCran      Type = "cran"
-	TypeDebian    Type = "debian"
-	TypeGeneric   Type = "generic"
-	TypeGo        Type = "go"
-	TypeHelm      Type = "helm"
-	TypeMaven     Type = "maven"
-	TypeNpm       Type = "npm"
-	TypeNuGet     Type = "nuget"
-	TypePub       Type = "pub"
-	TypePyPI      Type = "pypi"
-	TypeRpm       Type = "rpm"
-	TypeRubyGems  Type = "rubygems"
-	TypeSwift     Type = "swift"
-	TypeVagrant   Type = "vagrant"
+	TypeAlpine            Type = "alpine"
+	TypeArch              Type = "arch"
+	TypeCargo             Type = "cargo"
+	TypeChef              Type = "chef"
+	TypeComposer          Type = "composer"
+	TypeConan             Type = "conan"
+	TypeConda             Type = "conda"
+	TypeContainer         Type = "container"
+	TypeCran              Type = "cran"
+	TypeDebian            Type = "debian"
+	TypeGeneric           Type = "generic"
+	TypeGo                Type = "go"
+	TypeHelm              Type = "helm"
+	TypeHex               Type = "hex"
+	TypeMaven             Type = "maven"
+	TypeNpm               Type = "npm"
+	TypeNuGet             Type = "nuget"
+	TypePub               Type = "pub"
+	TypePyPI              Type = "pypi"
+	TypeRpm               Type = "rpm"
+	TypeRubyGems          Type = "rubygems"
+	TypeSwift             Type = "swift"
+	TypeTerraformModule   Type = "terraform_module"
+	TypeTerraformProvider Type = "terraform_provider"
+	TypeTerraformState    Type = "terraform_state" // only used for internal packages
+	TypeVagrant           Type = "vagrant"
 )
 
 var TypeList = []Type{
@@ -82,6 +86,7 @@ var TypeList = []Type{
 	TypeGeneric,
 	TypeGo,
 	TypeHelm,
+	TypeHex,
 	TypeMaven,
 	TypeNpm,
 	TypeNuGet,
@@ -90,6 +95,8 @@ var TypeList = []Type{
 	TypeRpm,
 	TypeRubyGems,
 	TypeSwift,
+	TypeTerraformModule,
+	TypeTerraformProvider,
 	TypeVagrant,
 }
 
@@ -122,6 +129,8 @@ func (pt Type) Name() string {
 		return "Go"
 	case TypeHelm:
 		return "Helm"
+	case TypeHex:
+		return "Hex"
 	case TypeMaven:
 		return "Maven"
 	case TypeNpm:
@@ -138,6 +147,12 @@ func (pt Type) Name() string {
 		return "RubyGems"
 	case TypeSwift:
 		return "Swift"
+	case TypeTerraformModule:
+		return "Terraform Module"
+	case TypeTerraformProvider:
+		return "Terraform Provider"
+	case TypeTerraformState:
+		return "Terraform State"
 	case TypeVagrant:
 		return "Vagrant"
 	}
@@ -173,6 +188,8 @@ func (pt Type) SVGName() string {
 		return "kmup-go"
 	case TypeHelm:
 		return "kmup-helm"
+	case TypeHex:
+		return "kmup-hex"
 	case TypeMaven:
 		return "kmup-maven"
 	case TypeNpm:
@@ -189,6 +206,8 @@ func (pt Type) SVGName() string {
 		return "kmup-rubygems"
 	case TypeSwift:
 		return "kmup-swift"
+	case TypeTerraformModule, TypeTerraformProvider, TypeTerraformState:
+		return "kmup-terraform"
 	case TypeVagrant:
 		return "kmup-vagrant"
 	}
@@ -281,11 +300,20 @@ func UpdatePackageNameByID(ctx context.Context, ownerID int64, packageType Type,
 
 // GetPackageByName gets a package by name
 func GetPackageByName(ctx context.Context, ownerID int64, packageType Type, name string) (*Package, error) {
+	return getPackageByName(ctx, ownerID, packageType, name, false)
+}
+
+// GetInternalPackageByName gets an internal package by name
+func GetInternalPackageByName(ctx context.Context, ownerID int64, packageType Type, name string) (*Package, error) {
+	return getPackageByName(ctx, ownerID, packageType, name, true)
+}
+
+func getPackageByName(ctx context.Context, ownerID int64, packageType Type, name string, isInternal bool) (*Package, error) {
 	var cond builder.Cond = builder.Eq{
 		"package.owner_id":    ownerID,
 		"package.type":        packageType,
 		"package.lower_name":  strings.ToLower(name),
-		"package.is_internal": false,
+		"package.is_internal": isInternal,
 	}
 
 	p := &Package{}
@@ -316,6 +344,17 @@ func GetPackagesByType(ctx context.Context, ownerID int64, packageType Type) ([]
 		Find(&ps)
 }
 
+// GetPackagesByRepositoryAndType gets all packages of a specific type linked to a repository, including internal packages
+func GetPackagesByRepositoryAndType(ctx context.Context, repoID int64, packageType Type) ([]*Package, error) {
+	ps := make([]*Package, 0, 10)
+	return ps, db.GetEngine(ctx).
+		Where(builder.Eq{
+			"package.repo_id": repoID,
+			"package.type":    packageType,
+		}).
+		Find(&ps)
+}
+
 // FindUnreferencedPackages gets all packages without associated versions
 func FindUnreferencedPackages(ctx context.Context) ([]*Package, error) {
 	in := builder.
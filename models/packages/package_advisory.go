@@ -0,0 +1,126 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package packages
+
+import (
+	"context"
+
+	"github.com/kumose/kmup/models/db"
+	"github.com/kumose/kmup/modules/timeutil"
+	"github.com/kumose/kmup/modules/util"
+
+	"xorm.io/builder"
+)
+
+var ErrPackageAdvisoryNotExist = util.NewNotExistErrorf("package advisory does not exist")
+
+func init() {
+	db.RegisterModel(new(PackageAdvisory))
+	db.RegisterModel(new(PackageAdvisoryAffected))
+}
+
+// PackageAdvisory represents an imported advisory in the OSV format
+type PackageAdvisory struct {
+	ID           int64              `xorm:"pk autoincr"`
+	AdvisoryID   string             `xorm:"UNIQUE NOT NULL"`
+	Summary      string             `xorm:"TEXT"`
+	Severity     string             `xorm:"NOT NULL DEFAULT ''"`
+	Content      string             `xorm:"LONGTEXT NOT NULL"`
+	ModifiedUnix timeutil.TimeStamp `xorm:"NOT NULL DEFAULT 0"`
+	CreatedUnix  timeutil.TimeStamp `xorm:"created NOT NULL DEFAULT 0"`
+	UpdatedUnix  timeutil.TimeStamp `xorm:"updated NOT NULL DEFAULT 0"`
+}
+
+// PackageAdvisoryAffected indexes the packages affected by an advisory
+type PackageAdvisoryAffected struct {
+	ID         int64  `xorm:"pk autoincr"`
+	AdvisoryID int64  `xorm:"INDEX NOT NULL"`
+	Type       Type   `xorm:"INDEX(s) NOT NULL"`
+	LowerName  string `xorm:"INDEX(s) NOT NULL"`
+}
+
+func GetAdvisoryByAdvisoryID(ctx context.Context, advisoryID string) (*PackageAdvisory, error) {
+	pa := &PackageAdvisory{}
+
+	has, err := db.GetEngine(ctx).Where("advisory_id = ?", advisoryID).Get(pa)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, ErrPackageAdvisoryNotExist
+	}
+	return pa, nil
+}
+
+func GetAdvisoriesByIDs(ctx context.Context, ids []int64) ([]*PackageAdvisory, error) {
+	pas := make([]*PackageAdvisory, 0, len(ids))
+	return pas, db.GetEngine(ctx).In("id", ids).Find(&pas)
+}
+
+// GetAdvisoriesByPackage gets the advisories which affect packages of the type with the (lowercase) name
+func GetAdvisoriesByPackage(ctx context.Context, packageType Type, lowerName string) ([]*PackageAdvisory, error) {
+	pas := make([]*PackageAdvisory, 0, 5)
+	return pas, db.GetEngine(ctx).
+		Where(builder.In("id", builder.Select("advisory_id").From("package_advisory_affected").Where(builder.Eq{"type": packageType, "lower_name": lowerName}))).
+		Find(&pas)
+}
+
+// SaveAdvisory inserts or updates the advisory and replaces the index of the affected packages
+func SaveAdvisory(ctx context.Context, pa *PackageAdvisory, affected []*PackageAdvisoryAffected) error {
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		e := db.GetEngine(ctx)
+
+		if pa.ID == 0 {
+			if _, err := e.Insert(pa); err != nil {
+				return err
+			}
+		} else {
+			if _, err := e.ID(pa.ID).AllCols().Update(pa); err != nil {
+				return err
+			}
+			if _, err := e.Where("advisory_id = ?", pa.ID).Delete(&PackageAdvisoryAffected{}); err != nil {
+				return err
+			}
+		}
+
+		for _, paa := range affected {
+			paa.AdvisoryID = pa.ID
+		}
+		if len(affected) == 0 {
+			return nil
+		}
+		_, err := e.Insert(affected)
+		return err
+	})
+}
+
+// DeleteAdvisory deletes the advisory, its index and the vulnerabilities found with it
+func DeleteAdvisory(ctx context.Context, pa *PackageAdvisory) error {
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		e := db.GetEngine(ctx)
+
+		if _, err := e.Where("advisory_id = ?", pa.ID).Delete(&PackageAdvisoryAffected{}); err != nil {
+			return err
+		}
+		if _, err := e.Where("advisory_id = ?", pa.ID).Delete(&PackageVulnerability{}); err != nil {
+			return err
+		}
+		_, err := e.ID(pa.ID).Delete(&PackageAdvisory{})
+		return err
+	})
+}
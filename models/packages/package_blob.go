@@ -166,3 +166,16 @@ func IsBlobAccessibleForUser(ctx context.Context, blobID int64, user *user_model
 		Where(cond).
 		Exist(&PackageBlob{})
 }
+
+// GetBlobSizesByOwner gets the size of the distinct blobs referenced by the packages of every owner
+func GetBlobSizesByOwner(ctx context.Context) (map[int64]int64, error) {
+	blobs := builder.Select("DISTINCT package.owner_id", "package_file.blob_id").
+		From("package_file").
+		InnerJoin("package_version", "package_version.id = package_file.version_id").
+		InnerJoin("package", "package.id = package_version.package_id")
+
+	return db.FindOwnerSizes(ctx, builder.Select("t.owner_id", "SUM(package_blob.size) AS size").
+		From(blobs, "t").
+		InnerJoin("package_blob", "package_blob.id = t.blob_id").
+		GroupBy("t.owner_id"))
+}
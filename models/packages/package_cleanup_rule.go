@@ -104,8 +104,13 @@ func GetCleanupRulesByOwner(ctx context.Context, ownerID int64) ([]*PackageClean
 }
 
 func DeleteCleanupRuleByID(ctx context.Context, ruleID int64) error {
-	_, err := db.GetEngine(ctx).ID(ruleID).Delete(&PackageCleanupRule{})
-	return err
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		if err := DeleteCleanupRunsByRuleID(ctx, ruleID); err != nil {
+			return err
+		}
+		_, err := db.GetEngine(ctx).ID(ruleID).Delete(&PackageCleanupRule{})
+		return err
+	})
 }
 
 func HasOwnerCleanupRuleForPackageType(ctx context.Context, ownerID int64, packageType Type) (bool, error) {
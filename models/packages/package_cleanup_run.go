@@ -0,0 +1,90 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package packages
+
+import (
+	"context"
+
+	"github.com/kumose/kmup/models/db"
+	"github.com/kumose/kmup/modules/timeutil"
+)
+
+// maxCleanupRunsPerRule is the number of executions kept in the history of a cleanup rule
+const maxCleanupRunsPerRule = 50
+
+func init() {
+	db.RegisterModel(new(PackageCleanupRun))
+}
+
+// PackageCleanupRun represents a single execution of a cleanup rule
+type PackageCleanupRun struct {
+	ID           int64              `xorm:"pk autoincr"`
+	RuleID       int64              `xorm:"INDEX NOT NULL"`
+	OwnerID      int64              `xorm:"INDEX NOT NULL"`
+	Type         Type               `xorm:"NOT NULL"`
+	VersionCount int                `xorm:"NOT NULL DEFAULT 0"`
+	FailedCount  int                `xorm:"NOT NULL DEFAULT 0"`
+	Size         int64              `xorm:"NOT NULL DEFAULT 0"` // bytes of the files of the removed versions
+	Versions     []string           `xorm:"JSON LONGTEXT"`      // "name@version" of the removed versions
+	CreatedUnix  timeutil.TimeStamp `xorm:"created INDEX NOT NULL DEFAULT 0"`
+}
+
+// InsertCleanupRun stores the run and drops the oldest runs of the rule which exceed the history limit
+func InsertCleanupRun(ctx context.Context, run *PackageCleanupRun) error {
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		if err := db.Insert(ctx, run); err != nil {
+			return err
+		}
+
+		ids := make([]int64, 0, maxCleanupRunsPerRule)
+		if err := db.GetEngine(ctx).
+			Table("package_cleanup_run").
+			Cols("id").
+			Where("rule_id = ?", run.RuleID).
+			OrderBy("id DESC").
+			Limit(maxCleanupRunsPerRule).
+			Find(&ids); err != nil {
+			return err
+		}
+		if len(ids) < maxCleanupRunsPerRule {
+			return nil
+		}
+
+		_, err := db.GetEngine(ctx).
+			Where("rule_id = ? AND id < ?", run.RuleID, ids[len(ids)-1]).
+			Delete(&PackageCleanupRun{})
+		return err
+	})
+}
+
+// GetCleanupRunsByRuleID gets the runs of a cleanup rule, most recent first
+func GetCleanupRunsByRuleID(ctx context.Context, ruleID int64, opts db.ListOptions) ([]*PackageCleanupRun, int64, error) {
+	sess := db.GetEngine(ctx).Where("rule_id = ?", ruleID).OrderBy("id DESC")
+	if opts.PageSize > 0 {
+		sess = db.SetSessionPagination(sess, &opts)
+	}
+
+	runs := make([]*PackageCleanupRun, 0, 10)
+	count, err := sess.FindAndCount(&runs)
+	return runs, count, err
+}
+
+func DeleteCleanupRunsByRuleID(ctx context.Context, ruleID int64) error {
+	_, err := db.GetEngine(ctx).Where("rule_id = ?", ruleID).Delete(&PackageCleanupRun{})
+	return err
+}
@@ -0,0 +1,157 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package packages
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/kumose/kmup/models/db"
+	"github.com/kumose/kmup/modules/secret"
+	"github.com/kumose/kmup/modules/setting"
+	"github.com/kumose/kmup/modules/timeutil"
+	"github.com/kumose/kmup/modules/util"
+
+	"xorm.io/builder"
+)
+
+var ErrPackageRemoteNotExist = util.NewNotExistErrorf("package remote does not exist")
+
+// RemoteTypeList contains the package types which can be proxied from an upstream registry
+var RemoteTypeList = []Type{
+	TypeContainer,
+	TypeMaven,
+	TypeNpm,
+	TypePyPI,
+}
+
+// IsRemoteSupported checks if packages of the type can be proxied from an upstream registry
+func (pt Type) IsRemoteSupported() bool {
+	return slices.Contains(RemoteTypeList, pt)
+}
+
+func init() {
+	db.RegisterModel(new(PackageRemote))
+}
+
+// PackageRemote represents an upstream registry from which missing packages of an owner are fetched and cached
+type PackageRemote struct {
+	ID                int64              `xorm:"pk autoincr"`
+	Enabled           bool               `xorm:"INDEX NOT NULL DEFAULT false"`
+	OwnerID           int64              `xorm:"UNIQUE(s) INDEX NOT NULL DEFAULT 0"`
+	Type              Type               `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	URL               string             `xorm:"TEXT NOT NULL"`
+	Username          string             `xorm:"NOT NULL DEFAULT ''"`
+	PasswordEncrypted string             `xorm:"TEXT"`
+	TTLMinutes        int                `xorm:"NOT NULL DEFAULT 0"` // how long upstream metadata and tags are served without revalidation
+	KeepCount         int                `xorm:"NOT NULL DEFAULT 0"` // cleanup: the number of cached versions to keep per package
+	RemoveDays        int                `xorm:"NOT NULL DEFAULT 0"` // cleanup: cached versions older than this are removed
+	CreatedUnix       timeutil.TimeStamp `xorm:"created NOT NULL DEFAULT 0"`
+	UpdatedUnix       timeutil.TimeStamp `xorm:"updated NOT NULL DEFAULT 0"`
+}
+
+// TTL returns the duration for which upstream metadata is considered fresh
+func (pr *PackageRemote) TTL() time.Duration {
+	return time.Duration(pr.TTLMinutes) * time.Minute
+}
+
+// Password returns the decrypted password used to authenticate against the upstream registry
+func (pr *PackageRemote) Password() (string, error) {
+	if pr.PasswordEncrypted == "" {
+		return "", nil
+	}
+	return secret.DecryptSecret(setting.SecretKey, pr.PasswordEncrypted)
+}
+
+// SetPassword encrypts and sets the password used to authenticate against the upstream registry
+func (pr *PackageRemote) SetPassword(password string) error {
+	if password == "" {
+		pr.PasswordEncrypted = ""
+		return nil
+	}
+	encrypted, err := secret.EncryptSecret(setting.SecretKey, password)
+	if err != nil {
+		return err
+	}
+	pr.PasswordEncrypted = encrypted
+	return nil
+}
+
+func InsertRemote(ctx context.Context, pr *PackageRemote) (*PackageRemote, error) {
+	return pr, db.Insert(ctx, pr)
+}
+
+func GetRemoteByID(ctx context.Context, id int64) (*PackageRemote, error) {
+	pr := &PackageRemote{}
+
+	has, err := db.GetEngine(ctx).ID(id).Get(pr)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, ErrPackageRemoteNotExist
+	}
+	return pr, nil
+}
+
+// GetEnabledRemoteByOwnerAndType gets the enabled remote of an owner for the package type
+func GetEnabledRemoteByOwnerAndType(ctx context.Context, ownerID int64, packageType Type) (*PackageRemote, error) {
+	pr := &PackageRemote{}
+
+	has, err := db.GetEngine(ctx).
+		Where(builder.Eq{"owner_id": ownerID, "type": packageType, "enabled": true}).
+		Get(pr)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, ErrPackageRemoteNotExist
+	}
+	return pr, nil
+}
+
+func UpdateRemote(ctx context.Context, pr *PackageRemote) error {
+	_, err := db.GetEngine(ctx).ID(pr.ID).AllCols().Update(pr)
+	return err
+}
+
+func GetRemotesByOwner(ctx context.Context, ownerID int64) ([]*PackageRemote, error) {
+	prs := make([]*PackageRemote, 0, 4)
+	return prs, db.GetEngine(ctx).Where("owner_id = ?", ownerID).Find(&prs)
+}
+
+func DeleteRemoteByID(ctx context.Context, remoteID int64) error {
+	_, err := db.GetEngine(ctx).ID(remoteID).Delete(&PackageRemote{})
+	return err
+}
+
+func HasOwnerRemoteForPackageType(ctx context.Context, ownerID int64, packageType Type) (bool, error) {
+	return db.GetEngine(ctx).
+		Where("owner_id = ? AND type = ?", ownerID, packageType).
+		Exist(&PackageRemote{})
+}
+
+// IterateRemotesWithCleanup iterates the remotes which have cleanup settings for their cached versions
+func IterateRemotesWithCleanup(ctx context.Context, callback func(context.Context, *PackageRemote) error) error {
+	return db.Iterate(
+		ctx,
+		builder.Gt{"keep_count": 0}.Or(builder.Gt{"remove_days": 0}),
+		callback,
+	)
+}
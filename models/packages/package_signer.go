@@ -0,0 +1,82 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package packages
+
+import (
+	"context"
+
+	"github.com/kumose/kmup/models/db"
+	"github.com/kumose/kmup/modules/timeutil"
+	"github.com/kumose/kmup/modules/util"
+)
+
+var ErrPackageSignerNotExist = util.NewNotExistErrorf("package signer does not exist")
+
+func init() {
+	db.RegisterModel(new(PackageSigner))
+}
+
+// PackageSigner represents a signer trusted by an owner to sign its packages.
+// The signer is identified either by a public key or by the identity of a keyless signing certificate.
+type PackageSigner struct {
+	ID          int64              `xorm:"pk autoincr"`
+	OwnerID     int64              `xorm:"INDEX NOT NULL DEFAULT 0"`
+	Name        string             `xorm:"NOT NULL"`
+	PublicKey   string             `xorm:"TEXT"`
+	Identity    string             `xorm:"TEXT"` // glob pattern matched against the certificate identity
+	Issuer      string             `xorm:"TEXT"` // OIDC issuer of the certificate, empty matches all
+	CreatedUnix timeutil.TimeStamp `xorm:"created NOT NULL DEFAULT 0"`
+	UpdatedUnix timeutil.TimeStamp `xorm:"updated NOT NULL DEFAULT 0"`
+}
+
+// IsKeyless returns true if the signer is identified by a certificate identity
+func (ps *PackageSigner) IsKeyless() bool {
+	return ps.PublicKey == ""
+}
+
+func InsertSigner(ctx context.Context, ps *PackageSigner) (*PackageSigner, error) {
+	return ps, db.Insert(ctx, ps)
+}
+
+func GetSignerByID(ctx context.Context, id int64) (*PackageSigner, error) {
+	ps := &PackageSigner{}
+
+	has, err := db.GetEngine(ctx).ID(id).Get(ps)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, ErrPackageSignerNotExist
+	}
+	return ps, nil
+}
+
+func UpdateSigner(ctx context.Context, ps *PackageSigner) error {
+	_, err := db.GetEngine(ctx).ID(ps.ID).AllCols().Update(ps)
+	return err
+}
+
+func GetSignersByOwner(ctx context.Context, ownerID int64) ([]*PackageSigner, error) {
+	pss := make([]*PackageSigner, 0, 4)
+	return pss, db.GetEngine(ctx).Where("owner_id = ?", ownerID).OrderBy("name").Find(&pss)
+}
+
+func DeleteSignerByID(ctx context.Context, signerID int64) error {
+	_, err := db.GetEngine(ctx).ID(signerID).Delete(&PackageSigner{})
+	return err
+}
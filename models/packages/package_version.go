@@ -47,6 +47,7 @@ type PackageVersion struct {
 	LowerVersion  string             `xorm:"UNIQUE(s) INDEX NOT NULL"`
 	CreatedUnix   timeutil.TimeStamp `xorm:"created INDEX NOT NULL"`
 	IsInternal    bool               `xorm:"INDEX NOT NULL DEFAULT false"`
+	IsRemote      bool               `xorm:"INDEX NOT NULL DEFAULT false"` // the version is a cached copy fetched from an upstream registry
 	MetadataJSON  string             `xorm:"metadata_json LONGTEXT"`
 	DownloadCount int64              `xorm:"NOT NULL DEFAULT 0"`
 }
@@ -207,6 +208,7 @@ type PackageSearchOptions struct {
 	Version         SearchValue       // only results with the specific version are found
 	Properties      map[string]string // only results are found which contain all listed version properties with the specific value
 	IsInternal      optional.Option[bool]
+	IsRemote        optional.Option[bool]
 	HasFileWithName string                // only results are found which are associated with a file with the specific name
 	HasFiles        optional.Option[bool] // only results are found which have associated files
 	Sort            VersionSort
@@ -220,6 +222,9 @@ func (opts *PackageSearchOptions) ToConds() builder.Cond {
 			"package_version.is_internal": opts.IsInternal.Value(),
 		}
 	}
+	if opts.IsRemote.Has() {
+		cond = cond.And(builder.Eq{"package_version.is_remote": opts.IsRemote.Value()})
+	}
 
 	if opts.OwnerID != 0 {
 		cond = cond.And(builder.Eq{"package.owner_id": opts.OwnerID})
@@ -0,0 +1,84 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package packages
+
+import (
+	"context"
+
+	"github.com/kumose/kmup/models/db"
+	"github.com/kumose/kmup/modules/timeutil"
+
+	"xorm.io/builder"
+)
+
+func init() {
+	db.RegisterModel(new(PackageVulnerability))
+}
+
+// PackageVulnerability represents an advisory which matches a package version or one of its dependencies
+type PackageVulnerability struct {
+	ID          int64              `xorm:"pk autoincr"`
+	VersionID   int64              `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	AdvisoryID  int64              `xorm:"UNIQUE(s) INDEX NOT NULL"`
+	Name        string             `xorm:"UNIQUE(s) NOT NULL"`  // name of the affected package
+	Version     string             `xorm:"NOT NULL"`            // affected version of the package
+	Requirement string             `xorm:"NOT NULL DEFAULT ''"` // declared requirement if the affected package is a dependency
+	CreatedUnix timeutil.TimeStamp `xorm:"created NOT NULL DEFAULT 0"`
+}
+
+// IsDependency returns true if the vulnerability was found in a dependency of the package version
+func (pv *PackageVulnerability) IsDependency() bool {
+	return pv.Requirement != ""
+}
+
+func GetVulnerabilitiesByVersionID(ctx context.Context, versionID int64) ([]*PackageVulnerability, error) {
+	pvs := make([]*PackageVulnerability, 0, 5)
+	return pvs, db.GetEngine(ctx).Where("version_id = ?", versionID).OrderBy("id").Find(&pvs)
+}
+
+func InsertVulnerabilities(ctx context.Context, pvs []*PackageVulnerability) error {
+	if len(pvs) == 0 {
+		return nil
+	}
+	_, err := db.GetEngine(ctx).Insert(pvs)
+	return err
+}
+
+func DeleteVulnerabilitiesByIDs(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := db.GetEngine(ctx).In("id", ids).Delete(&PackageVulnerability{})
+	return err
+}
+
+func DeleteVulnerabilitiesByVersionID(ctx context.Context, versionID int64) error {
+	_, err := db.GetEngine(ctx).Where("version_id = ?", versionID).Delete(&PackageVulnerability{})
+	return err
+}
+
+// GetVersionIDsByPackageTypes gets the ids of all versions of packages of the types which are not internal
+func GetVersionIDsByPackageTypes(ctx context.Context, packageTypes []Type) ([]int64, error) {
+	ids := make([]int64, 0, 50)
+	return ids, db.GetEngine(ctx).
+		Table("package_version").
+		Join("INNER", "package", "package.id = package_version.package_id").
+		Where(builder.In("package.type", packageTypes).And(builder.Eq{"package_version.is_internal": false})).
+		Cols("package_version.id").
+		Find(&ids)
+}
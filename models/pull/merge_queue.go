@@ -0,0 +1,145 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package pull
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kumose/kmup/models/db"
+	repo_model "github.com/kumose/kmup/models/repo"
+	"github.com/kumose/kmup/modules/timeutil"
+)
+
+// MergeQueueBranchPrefix is the prefix of the branches which hold the merge groups of a merge queue
+const MergeQueueBranchPrefix = "kmup-merge-queue/"
+
+// Reasons why an entry leaves a merge queue
+const (
+	MergeQueueRemovedMerged       = "merged"        // the merge group was merged into the target branch
+	MergeQueueRemovedDequeued     = "dequeued"      // a user removed the pull request from the queue
+	MergeQueueRemovedClosed       = "closed"        // the pull request was closed or merged outside the queue
+	MergeQueueRemovedUpdated      = "updated"       // the head branch of the pull request was updated
+	MergeQueueRemovedConflict     = "conflict"      // the pull request can't be merged on top of the queue
+	MergeQueueRemovedChecksFailed = "checks_failed" // a required status check of the merge group failed
+	MergeQueueRemovedMergeFailed  = "merge_failed"  // the merge group couldn't be pushed to the target branch
+	MergeQueueRemovedDisabled     = "disabled"      // the merge queue was disabled for the target branch
+)
+
+// MergeQueueEntry represents a pull request waiting in the merge queue of its base branch.
+// The entries of a queue are ordered by their ID, every entry has a merge group which stacks
+// the pull request on top of the merge group of the previous entry (or the base branch for the first one).
+type MergeQueueEntry struct {
+	ID                     int64                 `xorm:"pk autoincr"`
+	RepoID                 int64                 `xorm:"INDEX(s) NOT NULL"`
+	BaseBranch             string                `xorm:"INDEX(s) NOT NULL"`
+	PullID                 int64                 `xorm:"UNIQUE NOT NULL"`
+	DoerID                 int64                 `xorm:"INDEX NOT NULL"`
+	MergeStyle             repo_model.MergeStyle `xorm:"varchar(30)"`
+	Message                string                `xorm:"LONGTEXT"`
+	DeleteBranchAfterMerge bool
+	HeadCommitID           string             `xorm:"VARCHAR(64)"` // the head of the pull request when the merge group was built
+	BaseCommitID           string             `xorm:"VARCHAR(64)"` // the parent commit the merge group was built on
+	GroupCommitID          string             `xorm:"VARCHAR(64)"` // the commit of the merge group, empty if it hasn't been built yet
+	CreatedUnix            timeutil.TimeStamp `xorm:"created"`
+	UpdatedUnix            timeutil.TimeStamp `xorm:"updated"`
+}
+
+func init() {
+	db.RegisterModel(new(MergeQueueEntry))
+}
+
+// ErrAlreadyInMergeQueue represents an error if a pull request is already in a merge queue
+type ErrAlreadyInMergeQueue struct {
+	PullID int64
+}
+
+func (err ErrAlreadyInMergeQueue) Error() string {
+	return fmt.Sprintf("pull request is already in the merge queue [pull_id: %d]", err.PullID)
+}
+
+// IsErrAlreadyInMergeQueue checks if an error is a ErrAlreadyInMergeQueue.
+func IsErrAlreadyInMergeQueue(err error) bool {
+	_, ok := err.(ErrAlreadyInMergeQueue)
+	return ok
+}
+
+// GroupBranchName returns the name of the branch which holds the merge group of the entry
+func (e *MergeQueueEntry) GroupBranchName(pullIndex int64) string {
+	return fmt.Sprintf("%s%s/pr-%d", MergeQueueBranchPrefix, e.BaseBranch, pullIndex)
+}
+
+// InsertMergeQueueEntry appends a pull request to the end of the merge queue of its base branch
+func InsertMergeQueueEntry(ctx context.Context, entry *MergeQueueEntry) error {
+	if exists, err := db.GetEngine(ctx).Where("pull_id = ?", entry.PullID).Exist(&MergeQueueEntry{}); err != nil {
+		return err
+	} else if exists {
+		return ErrAlreadyInMergeQueue{PullID: entry.PullID}
+	}
+
+	return db.Insert(ctx, entry)
+}
+
+// GetMergeQueueEntryByPullID gets the merge queue entry of a pull request
+func GetMergeQueueEntryByPullID(ctx context.Context, pullID int64) (*MergeQueueEntry, bool, error) {
+	entry := &MergeQueueEntry{}
+	has, err := db.GetEngine(ctx).Where("pull_id = ?", pullID).Get(entry)
+	if err != nil || !has {
+		return nil, false, err
+	}
+	return entry, true, nil
+}
+
+// GetMergeQueueEntries gets all entries of the merge queue of a branch in queue order
+func GetMergeQueueEntries(ctx context.Context, repoID int64, branch string) ([]*MergeQueueEntry, error) {
+	entries := make([]*MergeQueueEntry, 0, 10)
+	return entries, db.GetEngine(ctx).
+		Where("repo_id = ? AND base_branch = ?", repoID, branch).
+		Asc("id").
+		Find(&entries)
+}
+
+// GetMergeQueueEntriesByGroupCommitID gets the entries of a repository whose merge group is the given commit
+func GetMergeQueueEntriesByGroupCommitID(ctx context.Context, repoID int64, commitID string) ([]*MergeQueueEntry, error) {
+	entries := make([]*MergeQueueEntry, 0, 1)
+	return entries, db.GetEngine(ctx).
+		Where("repo_id = ? AND group_commit_id = ?", repoID, commitID).
+		Find(&entries)
+}
+
+// GetMergeQueueBranches gets the branches of a repository which have a non-empty merge queue
+func GetMergeQueueBranches(ctx context.Context, repoID int64) ([]string, error) {
+	branches := make([]string, 0, 2)
+	return branches, db.GetEngine(ctx).
+		Table("merge_queue_entry").
+		Where("repo_id = ?", repoID).
+		Distinct("base_branch").
+		Find(&branches)
+}
+
+// UpdateMergeQueueEntryGroup stores the merge group which was built for an entry
+func UpdateMergeQueueEntryGroup(ctx context.Context, entry *MergeQueueEntry) error {
+	_, err := db.GetEngine(ctx).ID(entry.ID).Cols("head_commit_id", "base_commit_id", "group_commit_id").Update(entry)
+	return err
+}
+
+// DeleteMergeQueueEntry removes an entry from its merge queue
+func DeleteMergeQueueEntry(ctx context.Context, id int64) error {
+	_, err := db.GetEngine(ctx).ID(id).Delete(&MergeQueueEntry{})
+	return err
+}
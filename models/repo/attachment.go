@@ -31,6 +31,8 @@ import (
 	"github.com/kumose/kmup/modules/storage"
 	"github.com/kumose/kmup/modules/timeutil"
 	"github.com/kumose/kmup/modules/util"
+
+	"xorm.io/builder"
 )
 
 // Attachment represent a attachment of issue/comment/release.
@@ -275,3 +277,27 @@ func DeleteOrphanedAttachments(ctx context.Context) error {
 		Delete(new(Attachment))
 	return err
 }
+
+// GetAttachmentSizesByOwner gets the size of the attachments of every owner.
+// Attachments of a repository belong to the repository owner, all others to the uploader.
+func GetAttachmentSizesByOwner(ctx context.Context) (map[int64]int64, error) {
+	sizes, err := db.FindOwnerSizes(ctx, builder.Select("repository.owner_id", "SUM(attachment.size) AS size").
+		From("attachment").
+		InnerJoin("repository", "repository.id = attachment.repo_id").
+		GroupBy("repository.owner_id"))
+	if err != nil {
+		return nil, err
+	}
+
+	uploaded, err := db.FindOwnerSizes(ctx, builder.Select("uploader_id AS owner_id", "SUM(size) AS size").
+		From("attachment").
+		Where(builder.Eq{"repo_id": 0}).
+		GroupBy("uploader_id"))
+	if err != nil {
+		return nil, err
+	}
+	for ownerID, size := range uploaded {
+		sizes[ownerID] += size
+	}
+	return sizes, nil
+}
@@ -941,6 +941,13 @@ type CountRepositoryOptions struct {
 	Private optional.Option[bool]
 }
 
+// GetGitSizesByOwner gets the size of the git data of the repositories of every owner
+func GetGitSizesByOwner(ctx context.Context) (map[int64]int64, error) {
+	return db.FindOwnerSizes(ctx, builder.Select("owner_id", "SUM(git_size) AS size").
+		From("repository").
+		GroupBy("owner_id"))
+}
+
 // CountRepositories returns number of repositories.
 // Argument private only takes effect when it is false,
 // set it true to count all repositories.
@@ -0,0 +1,189 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package secretscan
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/kumose/kmup/models/db"
+	"github.com/kumose/kmup/modules/timeutil"
+	"github.com/kumose/kmup/modules/util"
+
+	"xorm.io/builder"
+)
+
+// AlertState is the state of a secret scanning alert
+type AlertState string
+
+const (
+	AlertStateOpen          AlertState = "open"           // the secret needs to be handled
+	AlertStateRevoked       AlertState = "revoked"        // the secret has been revoked and can't be used anymore
+	AlertStateFalsePositive AlertState = "false_positive" // the content isn't a secret
+	AlertStateUsedInTests   AlertState = "used_in_tests"  // the secret is a fake one only used by the tests
+)
+
+// AlertStates are all the states of an alert
+var AlertStates = []AlertState{AlertStateOpen, AlertStateRevoked, AlertStateFalsePositive, AlertStateUsedInTests}
+
+// IsValid reports whether the state is a known one
+func (s AlertState) IsValid() bool {
+	for _, state := range AlertStates {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// IsResolved reports whether the alert doesn't need to be handled anymore
+func (s AlertState) IsResolved() bool {
+	return s != AlertStateOpen
+}
+
+// Alert represents a secret found in a repository.
+// A secret is only reported once per repository, the alert keeps the first place where it was found.
+type Alert struct {
+	ID             int64      `xorm:"pk autoincr"`
+	RepoID         int64      `xorm:"UNIQUE(s) NOT NULL"`
+	SecretHash     string     `xorm:"UNIQUE(s) VARCHAR(64) NOT NULL"` // the SHA256 of the secret, the secret itself is never stored
+	RuleID         string     `xorm:"VARCHAR(255) NOT NULL"`
+	Description    string     `xorm:"VARCHAR(255)"`
+	RedactedSecret string     `xorm:"VARCHAR(255)"`
+	CommitID       string     `xorm:"VARCHAR(64)"`
+	Path           string     `xorm:"TEXT"`
+	Line           int        `xorm:"NOT NULL DEFAULT 0"`
+	State          AlertState `xorm:"VARCHAR(20) INDEX NOT NULL DEFAULT 'open'"`
+	ResolverID     int64      `xorm:"NOT NULL DEFAULT 0"` // 0 if the alert was resolved by the system, e.g. a revoked kmup token
+
+	ResolvedUnix timeutil.TimeStamp
+	CreatedUnix  timeutil.TimeStamp `xorm:"created"`
+	UpdatedUnix  timeutil.TimeStamp `xorm:"updated"`
+}
+
+func (a *Alert) TableName() string {
+	return "secret_scan_alert"
+}
+
+func init() {
+	db.RegisterModel(new(Alert))
+}
+
+// ErrAlertNotExist represents a "secret scanning alert not exist" error
+type ErrAlertNotExist struct {
+	ID int64
+}
+
+func (err ErrAlertNotExist) Error() string {
+	return fmt.Sprintf("secret scanning alert does not exist [id: %d]", err.ID)
+}
+
+func (err ErrAlertNotExist) Unwrap() error {
+	return util.ErrNotExist
+}
+
+// HashSecret returns the hash which identifies a secret
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// InsertAlertIfNotExist inserts the alert unless the secret has already been reported for the repository,
+// it returns whether the alert was inserted
+func InsertAlertIfNotExist(ctx context.Context, alert *Alert) (bool, error) {
+	return db.WithTx2(ctx, func(ctx context.Context) (bool, error) {
+		exists, err := db.GetEngine(ctx).Where("repo_id = ? AND secret_hash = ?", alert.RepoID, alert.SecretHash).Exist(&Alert{})
+		if err != nil || exists {
+			return false, err
+		}
+		if alert.State == "" {
+			alert.State = AlertStateOpen
+		}
+		return true, db.Insert(ctx, alert)
+	})
+}
+
+// GetAlertByID returns the alert of the repository with the given ID
+func GetAlertByID(ctx context.Context, repoID, id int64) (*Alert, error) {
+	alert := &Alert{}
+	has, err := db.GetEngine(ctx).Where("id = ? AND repo_id = ?", id, repoID).Get(alert)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, ErrAlertNotExist{ID: id}
+	}
+	return alert, nil
+}
+
+// FindAlertsOptions represents the options to find the alerts of a repository
+type FindAlertsOptions struct {
+	db.ListOptions
+	RepoID int64
+	State  AlertState
+}
+
+func (opts FindAlertsOptions) ToConds() builder.Cond {
+	cond := builder.NewCond()
+	if opts.RepoID > 0 {
+		cond = cond.And(builder.Eq{"repo_id": opts.RepoID})
+	}
+	if opts.State != "" {
+		cond = cond.And(builder.Eq{"state": opts.State})
+	}
+	return cond
+}
+
+func (opts FindAlertsOptions) ToOrders() string {
+	return "id DESC"
+}
+
+// UpdateAlertState changes the state of the alert, resolverID is 0 if the system resolves it
+func UpdateAlertState(ctx context.Context, alert *Alert, state AlertState, resolverID int64) error {
+	alert.State = state
+	if state.IsResolved() {
+		alert.ResolverID = resolverID
+		alert.ResolvedUnix = timeutil.TimeStampNow()
+	} else {
+		alert.ResolverID = 0
+		alert.ResolvedUnix = 0
+	}
+	_, err := db.GetEngine(ctx).ID(alert.ID).Cols("state", "resolver_id", "resolved_unix").Update(alert)
+	return err
+}
+
+// CountAlertsByState returns the number of alerts of a repository in each state
+func CountAlertsByState(ctx context.Context, repoID int64) (map[AlertState]int64, error) {
+	var rows []struct {
+		State AlertState
+		Count int64
+	}
+	if err := db.GetEngine(ctx).Table("secret_scan_alert").
+		Where("repo_id = ?", repoID).
+		GroupBy("state").
+		Select("state, COUNT(*) AS count").
+		Find(&rows); err != nil {
+		return nil, err
+	}
+	counts := make(map[AlertState]int64, len(rows))
+	for _, row := range rows {
+		counts[row.State] = row.Count
+	}
+	return counts, nil
+}
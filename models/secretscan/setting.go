@@ -0,0 +1,112 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package secretscan
+
+import (
+	"context"
+
+	"github.com/kumose/kmup/models/db"
+	"github.com/kumose/kmup/modules/setting"
+	"github.com/kumose/kmup/modules/timeutil"
+)
+
+// PushProtection is how the secrets found in the pushed commits are handled
+type PushProtection string
+
+const (
+	PushProtectionDefault PushProtection = ""      // use the instance default
+	PushProtectionOff     PushProtection = "off"   // the pushes aren't scanned
+	PushProtectionWarn    PushProtection = "warn"  // the pusher is warned and alerts are opened
+	PushProtectionBlock   PushProtection = "block" // the push is rejected
+)
+
+// IsValid reports whether the push protection is a known one
+func (p PushProtection) IsValid() bool {
+	switch p {
+	case PushProtectionDefault, PushProtectionOff, PushProtectionWarn, PushProtectionBlock:
+		return true
+	}
+	return false
+}
+
+// RepoSetting is the secret scanning setting of a repository
+type RepoSetting struct {
+	ID             int64          `xorm:"pk autoincr"`
+	RepoID         int64          `xorm:"UNIQUE NOT NULL"`
+	PushProtection PushProtection `xorm:"VARCHAR(20) NOT NULL DEFAULT ''"`
+	// LastHistoryScanUnix is when the last scan of the whole history of the repository finished
+	LastHistoryScanUnix timeutil.TimeStamp
+
+	CreatedUnix timeutil.TimeStamp `xorm:"created"`
+	UpdatedUnix timeutil.TimeStamp `xorm:"updated"`
+}
+
+func (s *RepoSetting) TableName() string {
+	return "secret_scan_repo_setting"
+}
+
+func init() {
+	db.RegisterModel(new(RepoSetting))
+}
+
+// EffectivePushProtection returns the push protection of the repository, falling back to the instance default
+func (s *RepoSetting) EffectivePushProtection() PushProtection {
+	if !setting.SecretScanning.Enabled {
+		return PushProtectionOff
+	}
+	if s.PushProtection == PushProtectionDefault {
+		return PushProtection(setting.SecretScanning.DefaultPushProtection)
+	}
+	return s.PushProtection
+}
+
+// GetRepoSetting returns the secret scanning setting of a repository, a default one is returned if it has never been set
+func GetRepoSetting(ctx context.Context, repoID int64) (*RepoSetting, error) {
+	s := &RepoSetting{RepoID: repoID}
+	_, err := db.GetEngine(ctx).Where("repo_id = ?", repoID).Get(s)
+	return s, err
+}
+
+func updateRepoSetting(ctx context.Context, repoID int64, update func(s *RepoSetting), cols ...string) error {
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		s, err := GetRepoSetting(ctx, repoID)
+		if err != nil {
+			return err
+		}
+		update(s)
+		if s.ID == 0 {
+			return db.Insert(ctx, s)
+		}
+		_, err = db.GetEngine(ctx).ID(s.ID).Cols(cols...).Update(s)
+		return err
+	})
+}
+
+// SetRepoPushProtection changes the push protection of a repository
+func SetRepoPushProtection(ctx context.Context, repoID int64, pushProtection PushProtection) error {
+	return updateRepoSetting(ctx, repoID, func(s *RepoSetting) {
+		s.PushProtection = pushProtection
+	}, "push_protection")
+}
+
+// UpdateLastHistoryScan records that the whole history of a repository has just been scanned
+func UpdateLastHistoryScan(ctx context.Context, repoID int64) error {
+	return updateRepoSetting(ctx, repoID, func(s *RepoSetting) {
+		s.LastHistoryScanUnix = timeutil.TimeStampNow()
+	}, "last_history_scan_unix")
+}
@@ -0,0 +1,62 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// GetJobEnvironments reads the names of the `environment` of the jobs in a workflow, the names may contain expressions.
+// The jobs without an environment are not included.
+// See https://docs.github.com/en/actions/writing-workflows/workflow-syntax-for-github-actions#jobsjob_idenvironment
+func GetJobEnvironments(content []byte) (map[string]string, error) {
+	var workflow struct {
+		Jobs map[string]struct {
+			Environment yaml.Node `yaml:"environment"`
+		} `yaml:"jobs"`
+	}
+	if err := yaml.Unmarshal(content, &workflow); err != nil {
+		return nil, err
+	}
+
+	environments := make(map[string]string)
+	for id, job := range workflow.Jobs {
+		switch job.Environment.Kind {
+		case 0:
+			continue
+		case yaml.ScalarNode:
+			environments[id] = job.Environment.Value
+		case yaml.MappingNode:
+			var env struct {
+				Name string `yaml:"name"`
+			}
+			if err := job.Environment.Decode(&env); err != nil {
+				return nil, fmt.Errorf("invalid environment of job %q: %w", id, err)
+			}
+			environments[id] = env.Name
+		default:
+			return nil, fmt.Errorf("invalid environment of job %q", id)
+		}
+		if environments[id] == "" {
+			delete(environments, id)
+		}
+	}
+	return environments, nil
+}
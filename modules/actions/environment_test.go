@@ -0,0 +1,61 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetJobEnvironments(t *testing.T) {
+	environments, err := GetJobEnvironments([]byte(`
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo
+  staging:
+    runs-on: ubuntu-latest
+    environment: staging
+    steps:
+      - run: echo
+  production:
+    runs-on: ubuntu-latest
+    environment:
+      name: ${{ matrix.region }}-production
+      url: https://example.com
+    steps:
+      - run: echo
+`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"staging":    "staging",
+		"production": "${{ matrix.region }}-production",
+	}, environments)
+
+	_, err = GetJobEnvironments([]byte(`
+on: push
+jobs:
+  build:
+    environment: [a, b]
+`))
+	assert.Error(t, err)
+}
@@ -37,6 +37,8 @@ const (
 	GithubEventPullRequestComment       = "pull_request_comment"
 	GithubEventGollum                   = "gollum"
 	GithubEventSchedule                 = "schedule"
+	GithubEventWorkflowCall             = "workflow_call"
+	GithubEventMergeGroup               = "merge_group"
 )
 
 // IsDefaultBranchWorkflow returns true if the event only triggers workflows on the default branch
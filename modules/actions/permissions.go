@@ -0,0 +1,44 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"gopkg.in/yaml.v3"
+)
+
+// HasIDTokenWritePermission returns true if the `permissions` allow the job to request an OIDC ID token.
+// The job-level permissions override the workflow-level ones, and there is no such permission by default.
+// See https://docs.github.com/en/actions/writing-workflows/workflow-syntax-for-github-actions#permissions
+func HasIDTokenWritePermission(workflowPermissions, jobPermissions yaml.Node) bool {
+	permissions := jobPermissions
+	if permissions.Kind == 0 {
+		permissions = workflowPermissions
+	}
+
+	switch permissions.Kind {
+	case yaml.ScalarNode:
+		return permissions.Value == "write-all"
+	case yaml.MappingNode:
+		var scopes map[string]string
+		if err := permissions.Decode(&scopes); err != nil {
+			return false
+		}
+		return scopes["id-token"] == "write"
+	}
+	return false
+}
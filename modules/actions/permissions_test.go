@@ -0,0 +1,57 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestHasIDTokenWritePermission(t *testing.T) {
+	parse := func(t *testing.T, s string) yaml.Node {
+		if s == "" {
+			return yaml.Node{}
+		}
+		var node yaml.Node
+		require.NoError(t, yaml.Unmarshal([]byte(s), &node))
+		return *node.Content[0]
+	}
+
+	cases := []struct {
+		workflow string
+		job      string
+		expected bool
+	}{
+		{"", "", false},
+		{"write-all", "", true},
+		{"read-all", "", false},
+		{"id-token: write", "", true},
+		{"id-token: read", "", false},
+		{"contents: write", "", false},
+		{"id-token: write", "contents: read", false},
+		{"read-all", "{id-token: write, contents: read}", true},
+		{"", "write-all", true},
+		{"", "{}", false},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expected, HasIDTokenWritePermission(parse(t, c.workflow), parse(t, c.job)), "workflow: %q, job: %q", c.workflow, c.job)
+	}
+}
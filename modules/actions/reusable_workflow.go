@@ -0,0 +1,232 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/nektos/act/pkg/jobparser"
+	"github.com/nektos/act/pkg/model"
+	"gopkg.in/yaml.v3"
+)
+
+// ReusableWorkflowRef is the reference to a reusable workflow called by a job with `uses`
+// See https://docs.github.com/en/actions/sharing-automations/reusing-workflows#calling-a-reusable-workflow
+type ReusableWorkflowRef struct {
+	OwnerName string // empty for a workflow in the same repository
+	RepoName  string // empty for a workflow in the same repository
+	Path      string // path of the workflow file, e.g. ".github/workflows/build.yml"
+	Ref       string // branch, tag or commit SHA, empty for a workflow in the same repository
+}
+
+// IsLocal returns true if the called workflow is in the same repository and commit as the caller
+func (r *ReusableWorkflowRef) IsLocal() bool {
+	return r.OwnerName == ""
+}
+
+func (r *ReusableWorkflowRef) String() string {
+	if r.IsLocal() {
+		return "./" + r.Path
+	}
+	return fmt.Sprintf("%s/%s/%s@%s", r.OwnerName, r.RepoName, r.Path, r.Ref)
+}
+
+// ParseReusableWorkflowRef parses the `uses` of a job, which can be:
+//   - "./.github/workflows/build.yml" for a workflow in the same repository
+//   - "owner/repo/.github/workflows/build.yml@ref" for a workflow in another repository
+func ParseReusableWorkflowRef(uses string) (*ReusableWorkflowRef, error) {
+	if path, ok := strings.CutPrefix(uses, "./"); ok {
+		if strings.Contains(path, "@") {
+			return nil, fmt.Errorf("reusable workflow %q in the same repository can't have a ref", uses)
+		}
+		if !IsWorkflow(path) {
+			return nil, fmt.Errorf("reusable workflow %q is not in a workflows directory", uses)
+		}
+		return &ReusableWorkflowRef{Path: path}, nil
+	}
+
+	target, ref, ok := strings.Cut(uses, "@")
+	if !ok || ref == "" {
+		return nil, fmt.Errorf("reusable workflow %q must have a ref", uses)
+	}
+	parts := strings.SplitN(target, "/", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("reusable workflow %q must be in the form of owner/repo/path@ref", uses)
+	}
+	if !IsWorkflow(parts[2]) {
+		return nil, fmt.Errorf("reusable workflow %q is not in a workflows directory", uses)
+	}
+	return &ReusableWorkflowRef{
+		OwnerName: parts[0],
+		RepoName:  parts[1],
+		Path:      parts[2],
+		Ref:       ref,
+	}, nil
+}
+
+// IsReusableWorkflowCall returns true if the job calls a reusable workflow instead of running steps
+func IsReusableWorkflowCall(job *jobparser.Job) bool {
+	return job != nil && job.Uses != ""
+}
+
+// GetWorkflowCallConfig returns the `on.workflow_call` config of a workflow,
+// it returns nil if the workflow can't be called by other workflows
+func GetWorkflowCallConfig(content []byte) (*model.WorkflowCall, error) {
+	events, err := GetEventsFromContent(content)
+	if err != nil {
+		return nil, err
+	}
+	for _, evt := range events {
+		if evt.Name == GithubEventWorkflowCall {
+			workflow := &model.Workflow{}
+			if err := yaml.Unmarshal(content, workflow); err != nil {
+				return nil, err
+			}
+			return workflow.WorkflowCallConfig(), nil
+		}
+	}
+	return nil, nil
+}
+
+// ResolveWorkflowCallInputs checks the inputs passed by `with` against the `on.workflow_call.inputs` of the called workflow,
+// and fills the default values of the inputs which are not passed.
+func ResolveWorkflowCallInputs(config *model.WorkflowCall, with map[string]any) (map[string]any, error) {
+	inputs := make(map[string]any, len(config.Inputs))
+	for name := range with {
+		if _, ok := config.Inputs[name]; !ok {
+			return nil, fmt.Errorf("input %q is not defined in the called workflow", name)
+		}
+	}
+	for name, input := range config.Inputs {
+		value, ok := with[name]
+		if !ok {
+			if input.Required {
+				return nil, fmt.Errorf("input %q is required but not provided", name)
+			}
+			value = input.Default
+		}
+		switch input.Type {
+		case "boolean":
+			switch v := value.(type) {
+			case bool:
+				inputs[name] = v
+			case string:
+				inputs[name] = v == "true"
+			default:
+				return nil, fmt.Errorf("input %q should be a boolean", name)
+			}
+		case "number":
+			switch v := value.(type) {
+			case int:
+				inputs[name] = float64(v)
+			case float64:
+				inputs[name] = v
+			case string:
+				if v == "" {
+					inputs[name] = float64(0)
+					break
+				}
+				f, err := strconv.ParseFloat(v, 64)
+				if err != nil {
+					return nil, fmt.Errorf("input %q should be a number", name)
+				}
+				inputs[name] = f
+			default:
+				return nil, fmt.Errorf("input %q should be a number", name)
+			}
+		default:
+			inputs[name] = fmt.Sprint(value)
+		}
+	}
+	return inputs, nil
+}
+
+var (
+	workflowExpressionRegexp     = regexp.MustCompile(`\$\{\{(.*?)\}\}`)
+	workflowInputsPropertyRegexp = regexp.MustCompile(`(^|[^.\w])inputs(?:\.([A-Za-z_][\w-]*)|\[\s*'([^']*)'\s*\])`)
+)
+
+// ReplaceWorkflowCallInputs replaces the references to the `inputs` context in the expressions of a called workflow
+// with the literal values of the inputs, so the runner can evaluate the jobs without knowing the caller.
+// Only the `${{ }}` expressions and the `if` conditions are rewritten, other texts are kept as they are.
+func ReplaceWorkflowCallInputs(content []byte, inputs map[string]any) ([]byte, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(content, &node); err != nil {
+		return nil, err
+	}
+	replaceInputsInNode(&node, inputs, false)
+	return yaml.Marshal(&node)
+}
+
+func replaceInputsInNode(node *yaml.Node, inputs map[string]any, isCondition bool) {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, n := range node.Content {
+			replaceInputsInNode(n, inputs, false)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			replaceInputsInNode(node.Content[i+1], inputs, node.Content[i].Value == "if")
+		}
+	case yaml.ScalarNode:
+		if isCondition && !strings.Contains(node.Value, "${{") {
+			node.Value = replaceInputsInExpression(node.Value, inputs)
+			return
+		}
+		node.Value = workflowExpressionRegexp.ReplaceAllStringFunc(node.Value, func(expr string) string {
+			inner := expr[3 : len(expr)-2]
+			return "${{" + replaceInputsInExpression(inner, inputs) + "}}"
+		})
+	}
+}
+
+func replaceInputsInExpression(expr string, inputs map[string]any) string {
+	return workflowInputsPropertyRegexp.ReplaceAllStringFunc(expr, func(ref string) string {
+		m := workflowInputsPropertyRegexp.FindStringSubmatch(ref)
+		name := m[2]
+		if name == "" {
+			name = m[3]
+		}
+		for k, v := range inputs {
+			if strings.EqualFold(k, name) {
+				return m[1] + toExpressionLiteral(v)
+			}
+		}
+		return m[1] + "null"
+	})
+}
+
+func toExpressionLiteral(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(val)
+	case int:
+		return strconv.Itoa(val)
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	default:
+		return "'" + strings.ReplaceAll(fmt.Sprint(val), "'", "''") + "'"
+	}
+}
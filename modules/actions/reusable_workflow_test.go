@@ -0,0 +1,109 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"testing"
+
+	"github.com/nektos/act/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReusableWorkflowRef(t *testing.T) {
+	ref, err := ParseReusableWorkflowRef("./.github/workflows/build.yml")
+	require.NoError(t, err)
+	assert.True(t, ref.IsLocal())
+	assert.Equal(t, ".github/workflows/build.yml", ref.Path)
+	assert.Equal(t, "./.github/workflows/build.yml", ref.String())
+
+	ref, err = ParseReusableWorkflowRef("org/ci/.kmup/workflows/sub/build.yaml@v1")
+	require.NoError(t, err)
+	assert.False(t, ref.IsLocal())
+	assert.Equal(t, &ReusableWorkflowRef{OwnerName: "org", RepoName: "ci", Path: ".kmup/workflows/sub/build.yaml", Ref: "v1"}, ref)
+	assert.Equal(t, "org/ci/.kmup/workflows/sub/build.yaml@v1", ref.String())
+
+	for _, uses := range []string{
+		"./.github/workflows/build.yml@v1",
+		"./build.yml",
+		"org/ci/.github/workflows/build.yml",
+		"org/ci/.github/workflows/build.yml@",
+		"org/.github/workflows/build.yml@v1",
+		"org/ci/build.yml@v1",
+		"actions/checkout@v4",
+	} {
+		_, err = ParseReusableWorkflowRef(uses)
+		assert.Error(t, err, uses)
+	}
+}
+
+func TestResolveWorkflowCallInputs(t *testing.T) {
+	config := &model.WorkflowCall{
+		Inputs: map[string]model.WorkflowCallInput{
+			"env":     {Type: "string", Required: true},
+			"debug":   {Type: "boolean", Default: "true"},
+			"retries": {Type: "number", Default: "3"},
+		},
+	}
+
+	inputs, err := ResolveWorkflowCallInputs(config, map[string]any{"env": "prod", "debug": false})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"env": "prod", "debug": false, "retries": float64(3)}, inputs)
+
+	_, err = ResolveWorkflowCallInputs(config, map[string]any{"debug": false})
+	assert.ErrorContains(t, err, `"env" is required`)
+
+	_, err = ResolveWorkflowCallInputs(config, map[string]any{"env": "prod", "unknown": "x"})
+	assert.ErrorContains(t, err, `"unknown" is not defined`)
+
+	_, err = ResolveWorkflowCallInputs(config, map[string]any{"env": "prod", "retries": "many"})
+	assert.ErrorContains(t, err, `"retries" should be a number`)
+}
+
+func TestReplaceWorkflowCallInputs(t *testing.T) {
+	content := []byte(`on:
+  workflow_call:
+    inputs:
+      env:
+        type: string
+jobs:
+  deploy:
+    if: inputs.debug && github.event.inputs.debug
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo "${{ inputs.env }} ${{ inputs['retries'] }} inputs.env"
+      - if: ${{ inputs.missing == '' }}
+        run: echo ok
+`)
+	replaced, err := ReplaceWorkflowCallInputs(content, map[string]any{"env": "it's prod", "debug": true, "retries": float64(3)})
+	require.NoError(t, err)
+	assert.Equal(t, `on:
+    workflow_call:
+        inputs:
+            env:
+                type: string
+jobs:
+    deploy:
+        if: true && github.event.inputs.debug
+        runs-on: ubuntu-latest
+        steps:
+            - run: echo "${{ 'it''s prod' }} ${{ 3 }} inputs.env"
+            - if: ${{ null == '' }}
+              run: echo ok
+`, string(replaced))
+}
@@ -0,0 +1,222 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	actions_model "github.com/kumose/kmup/models/actions"
+)
+
+// TestCaseResult is a test case parsed from a test report
+type TestCaseResult struct {
+	Suite     string
+	ClassName string
+	Name      string
+	Status    actions_model.TestStatus
+	Duration  time.Duration
+	Message   string // the failure message and details, or the reason of skipping
+}
+
+// ErrUnknownTestReportFormat is returned when a file is neither a JUnit XML nor a TRX report
+var ErrUnknownTestReportFormat = errors.New("unknown test report format")
+
+// ParseTestReport parses a JUnit XML or a Visual Studio TRX report, the format is detected by the root element.
+func ParseTestReport(r io.Reader) ([]*TestCaseResult, error) {
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, ErrUnknownTestReportFormat
+		} else if err != nil {
+			return nil, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "testsuites":
+			var suites junitTestSuites
+			if err := decoder.DecodeElement(&suites, &start); err != nil {
+				return nil, err
+			}
+			var results []*TestCaseResult
+			for _, suite := range suites.Suites {
+				results = suite.appendResults(results)
+			}
+			return results, nil
+		case "testsuite":
+			var suite junitTestSuite
+			if err := decoder.DecodeElement(&suite, &start); err != nil {
+				return nil, err
+			}
+			return suite.appendResults(nil), nil
+		case "TestRun":
+			var run trxTestRun
+			if err := decoder.DecodeElement(&run, &start); err != nil {
+				return nil, err
+			}
+			return run.results(), nil
+		default:
+			return nil, ErrUnknownTestReportFormat
+		}
+	}
+}
+
+type junitTestSuites struct {
+	Suites []*junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name   string            `xml:"name,attr"`
+	Suites []*junitTestSuite `xml:"testsuite"`
+	Cases  []*junitTestCase  `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string          `xml:"name,attr"`
+	ClassName string          `xml:"classname,attr"`
+	Time      string          `xml:"time,attr"`
+	Failures  []*junitProblem `xml:"failure"`
+	Errors    []*junitProblem `xml:"error"`
+	Skipped   *junitProblem   `xml:"skipped"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func (p *junitProblem) String() string {
+	return strings.TrimSpace(strings.Join(filterEmpty(p.Message, strings.TrimSpace(p.Text)), "\n"))
+}
+
+func (s *junitTestSuite) appendResults(results []*TestCaseResult) []*TestCaseResult {
+	for _, c := range s.Cases {
+		result := &TestCaseResult{
+			Suite:     s.Name,
+			ClassName: c.ClassName,
+			Name:      c.Name,
+			Status:    actions_model.TestStatusPassed,
+		}
+		if seconds, err := strconv.ParseFloat(strings.ReplaceAll(c.Time, ",", ""), 64); err == nil {
+			result.Duration = time.Duration(seconds * float64(time.Second))
+		}
+		var messages []string
+		for _, p := range append(c.Failures, c.Errors...) {
+			result.Status = actions_model.TestStatusFailed
+			messages = append(messages, p.String())
+		}
+		if result.Status != actions_model.TestStatusFailed && c.Skipped != nil {
+			result.Status = actions_model.TestStatusSkipped
+			messages = append(messages, c.Skipped.String())
+		}
+		result.Message = strings.Join(filterEmpty(messages...), "\n\n")
+		results = append(results, result)
+	}
+	for _, suite := range s.Suites {
+		results = suite.appendResults(results)
+	}
+	return results
+}
+
+type trxTestRun struct {
+	Definitions []*trxUnitTest       `xml:"TestDefinitions>UnitTest"`
+	Results     []*trxUnitTestResult `xml:"Results>UnitTestResult"`
+}
+
+type trxUnitTest struct {
+	ID     string `xml:"id,attr"`
+	Name   string `xml:"name,attr"`
+	Method struct {
+		ClassName string `xml:"className,attr"`
+		Name      string `xml:"name,attr"`
+	} `xml:"TestMethod"`
+}
+
+type trxUnitTestResult struct {
+	TestID   string `xml:"testId,attr"`
+	TestName string `xml:"testName,attr"`
+	Outcome  string `xml:"outcome,attr"`
+	Duration string `xml:"duration,attr"`
+	Message  string `xml:"Output>ErrorInfo>Message"`
+	Trace    string `xml:"Output>ErrorInfo>StackTrace"`
+}
+
+func (run *trxTestRun) results() []*TestCaseResult {
+	definitions := make(map[string]*trxUnitTest, len(run.Definitions))
+	for _, d := range run.Definitions {
+		definitions[d.ID] = d
+	}
+	results := make([]*TestCaseResult, 0, len(run.Results))
+	for _, r := range run.Results {
+		result := &TestCaseResult{
+			Name:     r.TestName,
+			Duration: parseTrxDuration(r.Duration),
+			Message:  strings.Join(filterEmpty(strings.TrimSpace(r.Message), strings.TrimSpace(r.Trace)), "\n"),
+		}
+		if d, ok := definitions[r.TestID]; ok {
+			result.ClassName = d.Method.ClassName
+			if result.Name == "" {
+				result.Name = d.Name
+			}
+		}
+		switch strings.ToLower(r.Outcome) {
+		case "passed", "passedbutrunaborted", "warning":
+			result.Status = actions_model.TestStatusPassed
+		case "notexecuted", "inconclusive", "notrunnable", "disconnected", "pending":
+			result.Status = actions_model.TestStatusSkipped
+		default: // failed, error, timeout, aborted
+			result.Status = actions_model.TestStatusFailed
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// parseTrxDuration parses the "hh:mm:ss.fffffff" durations of TRX reports
+func parseTrxDuration(s string) time.Duration {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return 0
+	}
+	hours, err1 := strconv.Atoi(parts[0])
+	minutes, err2 := strconv.Atoi(parts[1])
+	seconds, err3 := strconv.ParseFloat(parts[2], 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return 0
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds*float64(time.Second))
+}
+
+func filterEmpty(values ...string) []string {
+	ret := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != "" {
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
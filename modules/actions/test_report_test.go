@@ -0,0 +1,104 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	actions_model "github.com/kumose/kmup/models/actions"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTestReport_JUnit(t *testing.T) {
+	report := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="pkg">
+    <testcase classname="pkg.Math" name="TestAdd" time="0.5"/>
+    <testcase classname="pkg.Math" name="TestSub" time="1,000.25">
+      <failure message="expected 1" type="assert">math_test.go:12</failure>
+    </testcase>
+    <testcase classname="pkg.Math" name="TestDiv">
+      <error message="panic"/>
+    </testcase>
+    <testcase classname="pkg.Math" name="TestMul">
+      <skipped message="not implemented"/>
+    </testcase>
+    <testsuite name="pkg/sub">
+      <testcase classname="pkg.sub.Str" name="TestTrim"/>
+    </testsuite>
+  </testsuite>
+</testsuites>`
+	results, err := ParseTestReport(strings.NewReader(report))
+	require.NoError(t, err)
+	assert.Equal(t, []*TestCaseResult{
+		{Suite: "pkg", ClassName: "pkg.Math", Name: "TestAdd", Status: actions_model.TestStatusPassed, Duration: 500 * time.Millisecond},
+		{Suite: "pkg", ClassName: "pkg.Math", Name: "TestSub", Status: actions_model.TestStatusFailed, Duration: 1000250 * time.Millisecond, Message: "expected 1\nmath_test.go:12"},
+		{Suite: "pkg", ClassName: "pkg.Math", Name: "TestDiv", Status: actions_model.TestStatusFailed, Message: "panic"},
+		{Suite: "pkg", ClassName: "pkg.Math", Name: "TestMul", Status: actions_model.TestStatusSkipped, Message: "not implemented"},
+		{Suite: "pkg/sub", ClassName: "pkg.sub.Str", Name: "TestTrim", Status: actions_model.TestStatusPassed},
+	}, results)
+
+	// a single testsuite root
+	results, err = ParseTestReport(strings.NewReader(`<testsuite name="s"><testcase name="a"/></testsuite>`))
+	require.NoError(t, err)
+	assert.Equal(t, []*TestCaseResult{{Suite: "s", Name: "a", Status: actions_model.TestStatusPassed}}, results)
+}
+
+func TestParseTestReport_TRX(t *testing.T) {
+	report := `<?xml version="1.0" encoding="UTF-8"?>
+<TestRun id="1" xmlns="http://microsoft.com/schemas/VisualStudio/TeamTest/2010">
+  <Results>
+    <UnitTestResult testId="t1" testName="Adds" outcome="Passed" duration="00:00:01.5000000"/>
+    <UnitTestResult testId="t2" testName="Subtracts" outcome="Failed" duration="00:01:00">
+      <Output>
+        <ErrorInfo>
+          <Message>Assert.Equal() Failure</Message>
+          <StackTrace>at MathTests.Subtracts()</StackTrace>
+        </ErrorInfo>
+      </Output>
+    </UnitTestResult>
+    <UnitTestResult testId="t3" testName="Divides" outcome="NotExecuted"/>
+  </Results>
+  <TestDefinitions>
+    <UnitTest id="t1" name="Adds"><TestMethod className="MathTests" name="Adds"/></UnitTest>
+    <UnitTest id="t2" name="Subtracts"><TestMethod className="MathTests" name="Subtracts"/></UnitTest>
+  </TestDefinitions>
+</TestRun>`
+	results, err := ParseTestReport(strings.NewReader(report))
+	require.NoError(t, err)
+	assert.Equal(t, []*TestCaseResult{
+		{ClassName: "MathTests", Name: "Adds", Status: actions_model.TestStatusPassed, Duration: 1500 * time.Millisecond},
+		{ClassName: "MathTests", Name: "Subtracts", Status: actions_model.TestStatusFailed, Duration: time.Minute, Message: "Assert.Equal() Failure\nat MathTests.Subtracts()"},
+		{Name: "Divides", Status: actions_model.TestStatusSkipped},
+	}, results)
+}
+
+func TestParseTestReport_Unknown(t *testing.T) {
+	_, err := ParseTestReport(strings.NewReader(`<html><body/></html>`))
+	assert.ErrorIs(t, err, ErrUnknownTestReportFormat)
+
+	_, err = ParseTestReport(strings.NewReader(""))
+	assert.ErrorIs(t, err, ErrUnknownTestReportFormat)
+
+	_, err = ParseTestReport(strings.NewReader(`<testsuite><testcase`))
+	assert.Error(t, err)
+}
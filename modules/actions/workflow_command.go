@@ -0,0 +1,92 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"strconv"
+	"strings"
+)
+
+// WorkflowCommand is a workflow command written by a step to its log, like "::error file=app.js,line=1::Missing semicolon".
+// See https://docs.github.com/en/actions/using-workflows/workflow-commands-for-github-actions
+type WorkflowCommand struct {
+	Command    string
+	Properties map[string]string
+	Message    string
+}
+
+const (
+	WorkflowCommandError   = "error"
+	WorkflowCommandWarning = "warning"
+	WorkflowCommandNotice  = "notice"
+)
+
+var (
+	commandDataUnescaper     = strings.NewReplacer("%0D", "\r", "%0A", "\n", "%25", "%")
+	commandPropertyUnescaper = strings.NewReplacer("%0D", "\r", "%0A", "\n", "%3A", ":", "%2C", ",", "%25", "%")
+)
+
+// ParseWorkflowCommand parses a log line as a workflow command, it returns false if the line isn't one.
+func ParseWorkflowCommand(line string) (*WorkflowCommand, bool) {
+	line = strings.TrimLeft(line, " \t")
+	rest, ok := strings.CutPrefix(line, "::")
+	if !ok {
+		return nil, false
+	}
+	head, message, ok := strings.Cut(rest, "::")
+	if !ok {
+		return nil, false
+	}
+	name, props, _ := strings.Cut(head, " ")
+	if name == "" || strings.ContainsAny(name, " \t") {
+		return nil, false
+	}
+
+	cmd := &WorkflowCommand{
+		Command:    name,
+		Properties: map[string]string{},
+		Message:    commandDataUnescaper.Replace(strings.TrimRight(message, "\r\n")),
+	}
+	for prop := range strings.SplitSeq(props, ",") {
+		key, value, ok := strings.Cut(prop, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			continue
+		}
+		cmd.Properties[key] = commandPropertyUnescaper.Replace(value)
+	}
+	return cmd, true
+}
+
+// IsAnnotation returns whether the command creates an annotation.
+func (c *WorkflowCommand) IsAnnotation() bool {
+	switch c.Command {
+	case WorkflowCommandError, WorkflowCommandWarning, WorkflowCommandNotice:
+		return true
+	}
+	return false
+}
+
+// IntProperty returns the property as a non-negative integer, or 0 if it's missing or invalid.
+func (c *WorkflowCommand) IntProperty(key string) int {
+	v, err := strconv.Atoi(c.Properties[key])
+	if err != nil || v < 0 {
+		return 0
+	}
+	return v
+}
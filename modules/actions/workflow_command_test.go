@@ -0,0 +1,72 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseWorkflowCommand(t *testing.T) {
+	kases := []struct {
+		line string
+		want *WorkflowCommand
+	}{
+		{
+			line: "::error file=app.js,line=1,col=5,endColumn=7::Missing semicolon",
+			want: &WorkflowCommand{
+				Command:    "error",
+				Properties: map[string]string{"file": "app.js", "line": "1", "col": "5", "endColumn": "7"},
+				Message:    "Missing semicolon",
+			},
+		},
+		{
+			line: "  ::warning title=a%3Ab%2Cc::first%0Asecond%25",
+			want: &WorkflowCommand{
+				Command:    "warning",
+				Properties: map[string]string{"title": "a:b,c"},
+				Message:    "first\nsecond%",
+			},
+		},
+		{
+			line: "::notice::plain\n",
+			want: &WorkflowCommand{Command: "notice", Properties: map[string]string{}, Message: "plain"},
+		},
+		{
+			line: "::group::Build%0Aand test",
+			want: &WorkflowCommand{Command: "group", Properties: map[string]string{}, Message: "Build\nand test"},
+		},
+		{line: "error::not a command"},
+		{line: "::error no end marker"},
+		{line: ":: ::empty name"},
+	}
+	for _, kase := range kases {
+		t.Run(kase.line, func(t *testing.T) {
+			got, ok := ParseWorkflowCommand(kase.line)
+			assert.Equal(t, kase.want != nil, ok)
+			assert.Equal(t, kase.want, got)
+		})
+	}
+
+	cmd, _ := ParseWorkflowCommand("::error line=3,endLine=x::msg")
+	assert.True(t, cmd.IsAnnotation())
+	assert.Equal(t, 3, cmd.IntProperty("line"))
+	assert.Equal(t, 0, cmd.IntProperty("endLine"))
+	assert.Equal(t, 0, cmd.IntProperty("col"))
+}
@@ -163,6 +163,34 @@ func DetectWorkflows(
 	return workflows, schedules, nil
 }
 
+// DetectWorkflowContent returns the events of a workflow loaded from elsewhere, like a required workflow of a central repository,
+// which match the triggered event on the commit. The schedule events are ignored.
+func DetectWorkflowContent(
+	gitRepo *git.Repository,
+	commit *git.Commit,
+	entryName string,
+	content []byte,
+	triggedEvent webhook_module.HookEventType,
+	payload api.Payloader,
+) ([]*DetectedWorkflow, error) {
+	events, err := GetEventsFromContent(content)
+	if err != nil {
+		return nil, err
+	}
+	var workflows []*DetectedWorkflow
+	for _, evt := range events {
+		log.Trace("detect workflow %q for event %#v matching %q", entryName, evt, triggedEvent)
+		if !evt.IsSchedule() && detectMatched(gitRepo, commit, triggedEvent, payload, evt) {
+			workflows = append(workflows, &DetectedWorkflow{
+				EntryName:    entryName,
+				TriggerEvent: evt,
+				Content:      content,
+			})
+		}
+	}
+	return workflows, nil
+}
+
 func DetectScheduledWorkflows(gitRepo *git.Repository, commit *git.Commit) ([]*DetectedWorkflow, error) {
 	_, entries, err := ListWorkflows(commit)
 	if err != nil {
@@ -264,6 +292,10 @@ func detectMatched(gitRepo *git.Repository, commit *git.Commit, triggedEvent web
 		webhook_module.HookEventWorkflowRun:
 		return matchWorkflowRunEvent(payload.(*api.WorkflowRunPayload), evt)
 
+	case // merge_group
+		webhook_module.HookEventMergeGroup:
+		return matchMergeGroupEvent(payload.(*api.MergeGroupPayload), evt)
+
 	default:
 		log.Warn("unsupported event %q", triggedEvent)
 		return false
@@ -767,3 +799,48 @@ func matchWorkflowRunEvent(payload *api.WorkflowRunPayload, evt *jobparser.Event
 	}
 	return matchTimes == len(evt.Acts())
 }
+
+func matchMergeGroupEvent(payload *api.MergeGroupPayload, evt *jobparser.Event) bool {
+	// with no special filter parameters
+	if len(evt.Acts()) == 0 {
+		return true
+	}
+
+	baseBranch := git.RefName(payload.MergeGroup.BaseRef).BranchName()
+	matchTimes := 0
+	// all acts conditions should be satisfied
+	for cond, vals := range evt.Acts() {
+		switch cond {
+		case "types":
+			// See https://docs.github.com/en/actions/using-workflows/events-that-trigger-workflows#merge_group
+			// Activity types with the same name:
+			// checks_requested
+			action := payload.Action
+			for _, val := range vals {
+				if glob.MustCompile(val, '/').Match(string(action)) {
+					matchTimes++
+					break
+				}
+			}
+		case "branches":
+			patterns, err := workflowpattern.CompilePatterns(vals...)
+			if err != nil {
+				break
+			}
+			if !workflowpattern.Skip(patterns, []string{baseBranch}, &workflowpattern.EmptyTraceWriter{}) {
+				matchTimes++
+			}
+		case "branches-ignore":
+			patterns, err := workflowpattern.CompilePatterns(vals...)
+			if err != nil {
+				break
+			}
+			if !workflowpattern.Filter(patterns, []string{baseBranch}, &workflowpattern.EmptyTraceWriter{}) {
+				matchTimes++
+			}
+		default:
+			log.Warn("merge group event unsupported condition %q", cond)
+		}
+	}
+	return matchTimes == len(evt.Acts())
+}
@@ -139,6 +139,26 @@ func TestDetectMatched(t *testing.T) {
 			yamlOn:       "on: schedule",
 			expected:     true,
 		},
+		{
+			desc:         "HookEventMergeGroup(merge_group) `checks_requested` action matches GithubEventMergeGroup(merge_group) with `checks_requested` activity type",
+			triggedEvent: webhook_module.HookEventMergeGroup,
+			payload: &api.MergeGroupPayload{
+				Action:     api.HookMergeGroupChecksRequested,
+				MergeGroup: &api.MergeGroup{BaseRef: "refs/heads/main"},
+			},
+			yamlOn:   "on:\n  merge_group:\n    types: [checks_requested]",
+			expected: true,
+		},
+		{
+			desc:         "HookEventMergeGroup(merge_group) doesn't match GithubEventMergeGroup(merge_group) with other branches",
+			triggedEvent: webhook_module.HookEventMergeGroup,
+			payload: &api.MergeGroupPayload{
+				Action:     api.HookMergeGroupChecksRequested,
+				MergeGroup: &api.MergeGroup{BaseRef: "refs/heads/main"},
+			},
+			yamlOn:   "on:\n  merge_group:\n    branches: [release/*]",
+			expected: false,
+		},
 		{
 			desc:         "push to tag matches workflow with paths condition (should skip paths check)",
 			triggedEvent: webhook_module.HookEventPush,
@@ -169,6 +189,25 @@ func TestDetectMatched(t *testing.T) {
 	}
 }
 
+func TestDetectWorkflowContent(t *testing.T) {
+	content := []byte("on:\n  issues:\n  create:\n  schedule:\n    - cron: '0 0 * * *'\njobs:\n  test:\n    runs-on: ubuntu-latest\n    steps:\n      - run: echo\n")
+
+	workflows, err := DetectWorkflowContent(nil, nil, "ci.yml", content, webhook_module.HookEventCreate, nil)
+	assert.NoError(t, err)
+	if assert.Len(t, workflows, 1) {
+		assert.Equal(t, "ci.yml", workflows[0].EntryName)
+		assert.Equal(t, "create", workflows[0].TriggerEvent.Name)
+		assert.Equal(t, content, workflows[0].Content)
+	}
+
+	workflows, err = DetectWorkflowContent(nil, nil, "ci.yml", content, webhook_module.HookEventDelete, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, workflows)
+
+	_, err = DetectWorkflowContent(nil, nil, "ci.yml", []byte("on: ["), webhook_module.HookEventCreate, nil)
+	assert.Error(t, err)
+}
+
 func TestMatchIssuesEvent(t *testing.T) {
 	testCases := []struct {
 		desc      string
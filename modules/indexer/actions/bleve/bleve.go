@@ -0,0 +1,231 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package bleve
+
+import (
+	"context"
+
+	"github.com/kumose/kmup/modules/indexer"
+	"github.com/kumose/kmup/modules/indexer/actions/internal"
+	indexer_internal "github.com/kumose/kmup/modules/indexer/internal"
+	inner_bleve "github.com/kumose/kmup/modules/indexer/internal/bleve"
+	"github.com/kumose/kmup/modules/util"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/custom"
+	analyzer_keyword "github.com/blevesearch/bleve/v2/analysis/analyzer/keyword"
+	"github.com/blevesearch/bleve/v2/analysis/token/lowercase"
+	"github.com/blevesearch/bleve/v2/analysis/token/unicodenorm"
+	"github.com/blevesearch/bleve/v2/analysis/tokenizer/unicode"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+const (
+	actionsLogIndexerAnalyzer      = "actionsLogIndexer"
+	actionsLogIndexerDocType       = "actionsLogIndexerDocType"
+	actionsLogIndexerLatestVersion = 1
+)
+
+const unicodeNormalizeName = "unicodeNormalize"
+
+const (
+	maxBatchSize = 16
+	// deleteSearchSize is the number of documents to look up in one round when deleting the documents of tasks
+	deleteSearchSize = 1000
+)
+
+// IndexerData an update to the actions log indexer
+type IndexerData internal.IndexerData
+
+// Type returns the document type, for bleve's mapping.Classifier interface.
+func (i *IndexerData) Type() string {
+	return actionsLogIndexerDocType
+}
+
+// generateActionsLogIndexMapping generates the bleve index mapping for actions logs
+func generateActionsLogIndexMapping() (mapping.IndexMapping, error) {
+	mapping := bleve.NewIndexMapping()
+	docMapping := bleve.NewDocumentMapping()
+
+	numericFieldMapping := bleve.NewNumericFieldMapping()
+	numericFieldMapping.Store = false
+	numericFieldMapping.IncludeInAll = false
+	docMapping.AddFieldMappingsAt("repo_id", numericFieldMapping)
+	docMapping.AddFieldMappingsAt("task_id", numericFieldMapping)
+	docMapping.AddFieldMappingsAt("created_unix", numericFieldMapping)
+
+	textFieldMapping := bleve.NewTextFieldMapping()
+	textFieldMapping.Store = false
+	textFieldMapping.IncludeInAll = false
+	docMapping.AddFieldMappingsAt("content", textFieldMapping)
+
+	termFieldMapping := bleve.NewTextFieldMapping()
+	termFieldMapping.Store = false
+	termFieldMapping.IncludeInAll = false
+	termFieldMapping.Analyzer = analyzer_keyword.Name
+	docMapping.AddFieldMappingsAt("workflow_id", termFieldMapping)
+	docMapping.AddFieldMappingsAt("ref", termFieldMapping)
+
+	if err := mapping.AddCustomTokenFilter(unicodeNormalizeName, map[string]any{
+		"type": unicodenorm.Name,
+		"form": unicodenorm.NFC,
+	}); err != nil {
+		return nil, err
+	} else if err = mapping.AddCustomAnalyzer(actionsLogIndexerAnalyzer, map[string]any{
+		"type":          custom.Name,
+		"char_filters":  []string{},
+		"tokenizer":     unicode.Name,
+		"token_filters": []string{unicodeNormalizeName, lowercase.Name},
+	}); err != nil {
+		return nil, err
+	}
+
+	mapping.DefaultAnalyzer = actionsLogIndexerAnalyzer
+	mapping.AddDocumentMapping(actionsLogIndexerDocType, docMapping)
+	mapping.AddDocumentMapping("_all", bleve.NewDocumentDisabledMapping())
+	mapping.DefaultMapping = bleve.NewDocumentDisabledMapping() // disable default mapping, avoid indexing unexpected structs
+
+	return mapping, nil
+}
+
+var _ internal.Indexer = &Indexer{}
+
+// Indexer implements Indexer interface
+type Indexer struct {
+	inner                    *inner_bleve.Indexer
+	indexer_internal.Indexer // do not composite inner_bleve.Indexer directly to avoid exposing too much
+}
+
+func (b *Indexer) SupportedSearchModes() []indexer.SearchMode {
+	return indexer.SearchModesExactWordsFuzzy()
+}
+
+// NewIndexer creates a new bleve local indexer
+func NewIndexer(indexDir string) *Indexer {
+	inner := inner_bleve.NewIndexer(indexDir, actionsLogIndexerLatestVersion, generateActionsLogIndexMapping)
+	return &Indexer{
+		Indexer: inner,
+		inner:   inner,
+	}
+}
+
+// Index will save the index data
+func (b *Indexer) Index(_ context.Context, data ...*internal.IndexerData) error {
+	batch := inner_bleve.NewFlushingBatch(b.inner.Indexer, maxBatchSize)
+	for _, d := range data {
+		if err := batch.Index(d.ID, (*IndexerData)(d)); err != nil {
+			return err
+		}
+	}
+	return batch.Flush()
+}
+
+// Delete deletes the indexes of the given tasks
+func (b *Indexer) Delete(ctx context.Context, taskIDs ...int64) error {
+	if len(taskIDs) == 0 {
+		return nil
+	}
+	taskQueries := make([]query.Query, 0, len(taskIDs))
+	for _, taskID := range taskIDs {
+		taskQueries = append(taskQueries, inner_bleve.NumericEqualityQuery(taskID, "task_id"))
+	}
+	search := bleve.NewSearchRequestOptions(bleve.NewDisjunctionQuery(taskQueries...), deleteSearchSize, 0, false)
+	for {
+		result, err := b.inner.Indexer.SearchInContext(ctx, search)
+		if err != nil {
+			return err
+		}
+		if len(result.Hits) == 0 {
+			return nil
+		}
+		batch := inner_bleve.NewFlushingBatch(b.inner.Indexer, maxBatchSize)
+		for _, hit := range result.Hits {
+			if err := batch.Delete(hit.ID); err != nil {
+				return err
+			}
+		}
+		if err := batch.Flush(); err != nil {
+			return err
+		}
+	}
+}
+
+// Search searches for log chunks by given conditions.
+func (b *Indexer) Search(ctx context.Context, options *internal.SearchOptions) (*internal.SearchResult, error) {
+	var queries []query.Query
+
+	if options.Keyword != "" {
+		searchMode := util.IfZero(options.SearchMode, b.SupportedSearchModes()[0].ModeValue)
+		if searchMode == indexer.SearchModeWords || searchMode == indexer.SearchModeFuzzy {
+			fuzziness := 0
+			if searchMode == indexer.SearchModeFuzzy {
+				fuzziness = inner_bleve.GuessFuzzinessByKeyword(options.Keyword)
+			}
+			queries = append(queries, inner_bleve.MatchAndQuery(options.Keyword, "content", actionsLogIndexerAnalyzer, fuzziness))
+		} else /* exact */ {
+			queries = append(queries, inner_bleve.MatchPhraseQuery(options.Keyword, "content", actionsLogIndexerAnalyzer, 0))
+		}
+	}
+
+	repoQueries := make([]query.Query, 0, len(options.RepoIDs))
+	for _, repoID := range options.RepoIDs {
+		repoQueries = append(repoQueries, inner_bleve.NumericEqualityQuery(repoID, "repo_id"))
+	}
+	queries = append(queries, bleve.NewDisjunctionQuery(repoQueries...))
+
+	if options.WorkflowID != "" {
+		q := bleve.NewTermQuery(options.WorkflowID)
+		q.SetField("workflow_id")
+		queries = append(queries, q)
+	}
+	if options.Ref != "" {
+		q := bleve.NewTermQuery(options.Ref)
+		q.SetField("ref")
+		queries = append(queries, q)
+	}
+
+	if options.CreatedAfterUnix.Has() || options.CreatedBeforeUnix.Has() {
+		queries = append(queries, inner_bleve.NumericRangeInclusiveQuery(
+			options.CreatedAfterUnix,
+			options.CreatedBeforeUnix,
+			"created_unix"))
+	}
+
+	skip, limit := indexer_internal.ParsePaginator(options.Paginator)
+	search := bleve.NewSearchRequestOptions(bleve.NewConjunctionQuery(queries...), limit, skip, false)
+	search.SortBy([]string{"-created_unix", "-_id"})
+
+	result, err := b.inner.Indexer.SearchInContext(ctx, search)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &internal.SearchResult{
+		Total: int64(result.Total),
+		Hits:  make([]internal.Match, 0, len(result.Hits)),
+	}
+	for _, hit := range result.Hits {
+		m, err := internal.ParseDocID(hit.ID)
+		if err != nil {
+			return nil, err
+		}
+		ret.Hits = append(ret.Hits, m)
+	}
+	return ret, nil
+}
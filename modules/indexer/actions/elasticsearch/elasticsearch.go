@@ -0,0 +1,197 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package elasticsearch
+
+import (
+	"context"
+
+	"github.com/kumose/kmup/modules/graceful"
+	"github.com/kumose/kmup/modules/indexer"
+	"github.com/kumose/kmup/modules/indexer/actions/internal"
+	indexer_internal "github.com/kumose/kmup/modules/indexer/internal"
+	inner_elasticsearch "github.com/kumose/kmup/modules/indexer/internal/elasticsearch"
+	"github.com/kumose/kmup/modules/util"
+
+	"github.com/olivere/elastic/v7"
+)
+
+const (
+	actionsLogIndexerLatestVersion = 1
+	// multi-match-types, currently only 2 types are used
+	// Reference: https://www.elastic.co/guide/en/elasticsearch/reference/7.0/query-dsl-multi-match-query.html#multi-match-types
+	esMultiMatchTypeBestFields   = "best_fields"
+	esMultiMatchTypePhrasePrefix = "phrase_prefix"
+)
+
+var _ internal.Indexer = &Indexer{}
+
+// Indexer implements Indexer interface
+type Indexer struct {
+	inner                    *inner_elasticsearch.Indexer
+	indexer_internal.Indexer // do not composite inner_elasticsearch.Indexer directly to avoid exposing too much
+}
+
+func (b *Indexer) SupportedSearchModes() []indexer.SearchMode {
+	return indexer.SearchModesExactWords()
+}
+
+// NewIndexer creates a new elasticsearch indexer
+func NewIndexer(url, indexerName string) *Indexer {
+	inner := inner_elasticsearch.NewIndexer(url, indexerName, actionsLogIndexerLatestVersion, defaultMapping)
+	indexer := &Indexer{
+		inner:   inner,
+		Indexer: inner,
+	}
+	return indexer
+}
+
+const (
+	defaultMapping = `
+{
+	"mappings": {
+		"properties": {
+			"id": { "type": "keyword", "index": true },
+			"repo_id": { "type": "long", "index": true },
+			"run_id": { "type": "long", "index": true },
+			"job_id": { "type": "long", "index": true },
+			"task_id": { "type": "long", "index": true },
+			"step": { "type": "long", "index": false },
+			"line_start": { "type": "long", "index": false },
+
+			"content": { "type": "text", "index": true },
+
+			"workflow_id": { "type": "keyword", "index": true },
+			"ref": { "type": "keyword", "index": true },
+			"created_unix": { "type": "long", "index": true }
+		}
+	}
+}
+`
+)
+
+// Index will save the index data
+func (b *Indexer) Index(ctx context.Context, data ...*internal.IndexerData) error {
+	if len(data) == 0 {
+		return nil
+	} else if len(data) == 1 {
+		_, err := b.inner.Client.Index().
+			Index(b.inner.VersionedIndexName()).
+			Id(data[0].ID).
+			BodyJson(data[0]).
+			Do(ctx)
+		return err
+	}
+
+	reqs := make([]elastic.BulkableRequest, 0, len(data))
+	for _, d := range data {
+		reqs = append(reqs,
+			elastic.NewBulkIndexRequest().
+				Index(b.inner.VersionedIndexName()).
+				Id(d.ID).
+				Doc(d),
+		)
+	}
+
+	_, err := b.inner.Client.Bulk().
+		Index(b.inner.VersionedIndexName()).
+		Add(reqs...).
+		Do(graceful.GetManager().HammerContext())
+	return err
+}
+
+// Delete deletes the indexes of the given tasks
+func (b *Indexer) Delete(ctx context.Context, taskIDs ...int64) error {
+	if len(taskIDs) == 0 {
+		return nil
+	}
+	_, err := b.inner.Client.DeleteByQuery(b.inner.VersionedIndexName()).
+		Query(elastic.NewTermsQuery("task_id", toAnySlice(taskIDs)...)).
+		Refresh("true").
+		Do(ctx)
+	return err
+}
+
+// Search searches for log chunks by given conditions.
+func (b *Indexer) Search(ctx context.Context, options *internal.SearchOptions) (*internal.SearchResult, error) {
+	query := elastic.NewBoolQuery()
+
+	if options.Keyword != "" {
+		searchMode := util.IfZero(options.SearchMode, b.SupportedSearchModes()[0].ModeValue)
+		if searchMode == indexer.SearchModeExact {
+			query.Must(elastic.NewMultiMatchQuery(options.Keyword, "content").Type(esMultiMatchTypePhrasePrefix))
+		} else /* words */ {
+			query.Must(elastic.NewMultiMatchQuery(options.Keyword, "content").Type(esMultiMatchTypeBestFields).Operator("and"))
+		}
+	}
+
+	query.Must(elastic.NewTermsQuery("repo_id", toAnySlice(options.RepoIDs)...))
+
+	if options.WorkflowID != "" {
+		query.Must(elastic.NewTermQuery("workflow_id", options.WorkflowID))
+	}
+	if options.Ref != "" {
+		query.Must(elastic.NewTermQuery("ref", options.Ref))
+	}
+
+	if options.CreatedAfterUnix.Has() || options.CreatedBeforeUnix.Has() {
+		q := elastic.NewRangeQuery("created_unix")
+		if options.CreatedAfterUnix.Has() {
+			q.Gte(options.CreatedAfterUnix.Value())
+		}
+		if options.CreatedBeforeUnix.Has() {
+			q.Lte(options.CreatedBeforeUnix.Value())
+		}
+		query.Must(q)
+	}
+
+	// See https://stackoverflow.com/questions/35206409/elasticsearch-2-1-result-window-is-too-large-index-max-result-window/35221900
+	const maxPageSize = 10000
+
+	skip, limit := indexer_internal.ParsePaginator(options.Paginator, maxPageSize)
+	searchResult, err := b.inner.Client.Search().
+		Index(b.inner.VersionedIndexName()).
+		Query(query).
+		SortBy(elastic.NewFieldSort("created_unix").Desc(), elastic.NewFieldSort("id").Desc()).
+		From(skip).Size(limit).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]internal.Match, 0, limit)
+	for _, hit := range searchResult.Hits.Hits {
+		m, err := internal.ParseDocID(hit.Id)
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, m)
+	}
+
+	return &internal.SearchResult{
+		Total: searchResult.TotalHits(),
+		Hits:  hits,
+	}, nil
+}
+
+func toAnySlice[T any](s []T) []any {
+	ret := make([]any, 0, len(s))
+	for _, item := range s {
+		ret = append(ret, item)
+	}
+	return ret
+}
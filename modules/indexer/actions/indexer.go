@@ -0,0 +1,286 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"context"
+	"os"
+	"runtime/pprof"
+	"sync/atomic"
+	"time"
+
+	actions_model "github.com/kumose/kmup/models/actions"
+	"github.com/kumose/kmup/modules/graceful"
+	"github.com/kumose/kmup/modules/indexer"
+	"github.com/kumose/kmup/modules/indexer/actions/bleve"
+	"github.com/kumose/kmup/modules/indexer/actions/elasticsearch"
+	"github.com/kumose/kmup/modules/indexer/actions/internal"
+	"github.com/kumose/kmup/modules/indexer/actions/meilisearch"
+	"github.com/kumose/kmup/modules/log"
+	"github.com/kumose/kmup/modules/process"
+	"github.com/kumose/kmup/modules/queue"
+	"github.com/kumose/kmup/modules/setting"
+	"github.com/kumose/kmup/modules/util"
+)
+
+// IndexerMetadata is used to send data to the queue, so it contains only the ids.
+// If the IsDelete flag is true, the TaskIDs specify the tasks whose logs should be removed from the index.
+// If the IsDelete flag is false, the TaskID specifies the task whose logs should be (re)indexed,
+// if the task doesn't exist or its logs have expired, the logs will be removed from the index.
+type IndexerMetadata struct {
+	TaskID int64 `json:"task_id"`
+
+	IsDelete bool    `json:"is_delete"`
+	TaskIDs  []int64 `json:"task_ids"`
+}
+
+var (
+	indexerQueue *queue.WorkerPoolQueue[*IndexerMetadata]
+	// globalIndexer is the global indexer, it cannot be nil.
+	// When the real indexer is not ready, it will be a dummy indexer which will return error to explain it's not ready.
+	// So it's always safe use it as *globalIndexer.Load() and call its methods.
+	globalIndexer atomic.Pointer[internal.Indexer]
+)
+
+func init() {
+	dummyIndexer := internal.NewDummyIndexer()
+	globalIndexer.Store(&dummyIndexer)
+}
+
+// IsEnabled returns whether the actions log indexer is enabled
+func IsEnabled() bool {
+	return setting.Actions.Enabled && setting.Indexer.ActionsLogIndexerEnabled
+}
+
+// Init initializes the actions log indexer
+func Init() {
+	if !IsEnabled() {
+		return
+	}
+
+	ctx, _, finished := process.GetManager().AddTypedContext(context.Background(), "Service: ActionsLogIndexer", process.SystemProcessType, false)
+
+	waitChannel := make(chan time.Duration, 1)
+
+	// Create the Queue
+	indexerQueue = queue.CreateUniqueQueue(ctx, "actions_log_indexer", getIndexerQueueHandler(ctx))
+	if indexerQueue == nil {
+		log.Fatal("Unable to create actions log indexer queue")
+	}
+
+	graceful.GetManager().RunAtTerminate(finished)
+
+	// Create the Indexer
+	go func() {
+		pprof.SetGoroutineLabels(ctx)
+		start := time.Now()
+		log.Info("PID %d: Initializing Actions Log Indexer: %s", os.Getpid(), setting.Indexer.ActionsLogType)
+		var (
+			logIndexer internal.Indexer
+			existed    bool
+			err        error
+		)
+		switch setting.Indexer.ActionsLogType {
+		case "bleve":
+			defer func() {
+				if err := recover(); err != nil {
+					log.Error("PANIC whilst initializing actions log indexer: %v\nStacktrace: %s", err, log.Stack(2))
+					log.Error("The indexer files are likely corrupted and may need to be deleted")
+					log.Error("You can completely remove the %q directory to make Kmup recreate the indexes", setting.Indexer.ActionsLogPath)
+					log.Fatal("PID: %d Unable to initialize the Bleve Actions Log Indexer at path: %s Error: %v", os.Getpid(), setting.Indexer.ActionsLogPath, err)
+				}
+			}()
+			logIndexer = bleve.NewIndexer(setting.Indexer.ActionsLogPath)
+			existed, err = logIndexer.Init(ctx)
+			if err != nil {
+				log.Fatal("Unable to initialize Bleve Actions Log Indexer at path: %s Error: %v", setting.Indexer.ActionsLogPath, err)
+			}
+		case "elasticsearch":
+			logIndexer = elasticsearch.NewIndexer(setting.Indexer.ActionsLogConnStr, setting.Indexer.ActionsLogIndexerName)
+			existed, err = logIndexer.Init(ctx)
+			if err != nil {
+				log.Fatal("Unable to initialize Actions Log Indexer with connection %s Error: %v", util.SanitizeCredentialURLs(setting.Indexer.ActionsLogConnStr), err)
+			}
+		case "meilisearch":
+			logIndexer = meilisearch.NewIndexer(setting.Indexer.ActionsLogConnStr, setting.Indexer.ActionsLogConnAuth, setting.Indexer.ActionsLogIndexerName)
+			existed, err = logIndexer.Init(ctx)
+			if err != nil {
+				log.Fatal("Unable to initialize Actions Log Indexer with connection %s Error: %v", util.SanitizeCredentialURLs(setting.Indexer.ActionsLogConnStr), err)
+			}
+		default:
+			log.Fatal("Unknown actions log indexer type: %s", setting.Indexer.ActionsLogType)
+		}
+		globalIndexer.Store(&logIndexer)
+
+		graceful.GetManager().RunAtTerminate(func() {
+			log.Debug("Closing actions log indexer")
+			(*globalIndexer.Load()).Close()
+			log.Info("PID: %d Actions Log Indexer closed", os.Getpid())
+		})
+
+		// Start processing the queue
+		go graceful.GetManager().RunWithCancel(indexerQueue)
+
+		// Populate the index
+		if !existed {
+			go graceful.GetManager().RunWithShutdownContext(populateIndexer)
+		}
+
+		waitChannel <- time.Since(start)
+		close(waitChannel)
+	}()
+
+	if setting.Indexer.StartupTimeout > 0 {
+		go func() {
+			pprof.SetGoroutineLabels(ctx)
+			timeout := setting.Indexer.StartupTimeout
+			if graceful.GetManager().IsChild() && setting.GracefulHammerTime > 0 {
+				timeout += setting.GracefulHammerTime
+			}
+			select {
+			case duration := <-waitChannel:
+				log.Info("Actions Log Indexer Initialization took %v", duration)
+			case <-graceful.GetManager().IsShutdown():
+				log.Warn("Shutdown occurred before actions log index initialisation was complete")
+			case <-time.After(timeout):
+				indexerQueue.ShutdownWait(5 * time.Second)
+				log.Fatal("Actions Log Indexer Initialization timed-out after: %v", timeout)
+			}
+		}()
+	}
+}
+
+func getIndexerQueueHandler(ctx context.Context) func(items ...*IndexerMetadata) []*IndexerMetadata {
+	return func(items ...*IndexerMetadata) []*IndexerMetadata {
+		var unhandled []*IndexerMetadata
+
+		indexer := *globalIndexer.Load()
+		for _, item := range items {
+			log.Trace("IndexerMetadata Process: %d %v %t", item.TaskID, item.TaskIDs, item.IsDelete)
+			if item.IsDelete {
+				if err := indexer.Delete(ctx, item.TaskIDs...); err != nil {
+					log.Error("Actions log indexer handler: failed to delete tasks %v from index: %v", item.TaskIDs, err)
+					unhandled = append(unhandled, item)
+				}
+				continue
+			}
+			data, err := getIndexerData(ctx, item.TaskID)
+			if err != nil {
+				log.Error("Actions log indexer handler: failed to get log data of task %d: %v", item.TaskID, err)
+				// do not re-queue the item, otherwise a broken log file will block the queue
+				continue
+			}
+			// always remove the old documents first, the number of chunks could be different when the task is indexed again
+			if err := indexer.Delete(ctx, item.TaskID); err != nil {
+				log.Error("Actions log indexer handler: failed to delete task %d from index: %v", item.TaskID, err)
+				unhandled = append(unhandled, item)
+				continue
+			}
+			if err := indexer.Index(ctx, data...); err != nil {
+				log.Error("Actions log indexer handler: failed to index task %d: %v", item.TaskID, err)
+				unhandled = append(unhandled, item)
+				continue
+			}
+		}
+
+		return unhandled
+	}
+}
+
+// populateIndexer populates the indexer with the archived logs of all tasks, it's only run when the index is created
+func populateIndexer(ctx context.Context) {
+	ctx, _, finished := process.GetManager().AddTypedContext(ctx, "Service: PopulateActionsLogIndexer", process.SystemProcessType, true)
+	defer finished()
+
+	log.Info("Populating the actions log indexer with existing logs")
+	var maxTaskID int64
+	for {
+		select {
+		case <-ctx.Done():
+			log.Warn("Actions Log Indexer population shutdown before completion")
+			return
+		default:
+		}
+		ids, err := actions_model.FindTaskIDsWithArchivedLogs(ctx, maxTaskID, 50)
+		if err != nil {
+			log.Error("populateIndexer: %v", err)
+			return
+		} else if len(ids) == 0 {
+			break
+		}
+		for _, id := range ids {
+			if err := indexerQueue.Push(&IndexerMetadata{TaskID: id}); err != nil {
+				log.Error("indexerQueue.Push: %v", err)
+				return
+			}
+		}
+		maxTaskID = ids[len(ids)-1]
+	}
+	log.Info("Done populating the actions log indexer with existing logs")
+}
+
+// UpdateTaskIndexer adds or updates the logs of a task to the indexer, it does nothing if the indexer is disabled
+func UpdateTaskIndexer(taskID int64) {
+	if indexerQueue == nil {
+		return
+	}
+	if err := indexerQueue.Push(&IndexerMetadata{TaskID: taskID}); err != nil {
+		log.Error("Unable to push task %d to actions log indexer: %v", taskID, err)
+	}
+}
+
+// DeleteTasksIndexer removes the logs of tasks from the indexer, it does nothing if the indexer is disabled
+func DeleteTasksIndexer(taskIDs ...int64) {
+	if indexerQueue == nil || len(taskIDs) == 0 {
+		return
+	}
+	if err := indexerQueue.Push(&IndexerMetadata{IsDelete: true, TaskIDs: taskIDs}); err != nil {
+		log.Error("Unable to push deleted tasks %v to actions log indexer: %v", taskIDs, err)
+	}
+}
+
+// IsAvailable checks if the actions log indexer is available
+func IsAvailable(ctx context.Context) bool {
+	return (*globalIndexer.Load()).Ping(ctx) == nil
+}
+
+// SearchOptions indicates the options for searching actions logs
+type SearchOptions = internal.SearchOptions
+
+// Match represents a chunk of a step log matching the search
+type Match = internal.Match
+
+// SearchLogs searches chunks of actions logs by options
+func SearchLogs(ctx context.Context, opts *SearchOptions) ([]Match, int64, error) {
+	if len(opts.RepoIDs) == 0 {
+		return nil, 0, nil
+	}
+	result, err := (*globalIndexer.Load()).Search(ctx, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	return result.Hits, result.Total, nil
+}
+
+func SupportedSearchModes() []indexer.SearchMode {
+	gi := globalIndexer.Load()
+	if gi == nil {
+		return nil
+	}
+	return (*gi).SupportedSearchModes()
+}
@@ -0,0 +1,92 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package internal
+
+import (
+	"fmt"
+
+	"github.com/kumose/kmup/models/db"
+	"github.com/kumose/kmup/modules/indexer"
+	"github.com/kumose/kmup/modules/optional"
+	"github.com/kumose/kmup/modules/timeutil"
+)
+
+// IndexerData is a chunk of consecutive lines of a step log stored in the actions log indexer
+type IndexerData struct {
+	ID     string `json:"id"`
+	RepoID int64  `json:"repo_id"`
+	RunID  int64  `json:"run_id"`
+	JobID  int64  `json:"job_id"`
+	TaskID int64  `json:"task_id"`
+
+	// Step is the index of the step in the full steps of the task, including the "Set up job" and "Complete job" steps.
+	Step int64 `json:"step"`
+	// LineStart is the 0-based index of the first line of the chunk in the step log.
+	LineStart int64 `json:"line_start"`
+
+	// Fields used for keyword searching
+	Content string `json:"content"`
+
+	// Fields used for filtering
+	WorkflowID  string             `json:"workflow_id"`
+	Ref         string             `json:"ref"`
+	CreatedUnix timeutil.TimeStamp `json:"created_unix"`
+}
+
+// DocID returns the document id of a chunk, it only contains digits and underscores to be accepted by all indexers.
+func DocID(taskID, step, lineStart int64) string {
+	return fmt.Sprintf("%d_%d_%d", taskID, step, lineStart)
+}
+
+// ParseDocID parses a document id generated by DocID into a Match
+func ParseDocID(id string) (Match, error) {
+	var m Match
+	if _, err := fmt.Sscanf(id, "%d_%d_%d", &m.TaskID, &m.Step, &m.LineStart); err != nil {
+		return m, fmt.Errorf("invalid document id %q: %w", id, err)
+	}
+	return m, nil
+}
+
+// Match represents a chunk of a step log matching the search
+type Match struct {
+	TaskID    int64
+	Step      int64
+	LineStart int64
+}
+
+// SearchResult represents the result of a search
+type SearchResult struct {
+	Total int64
+	Hits  []Match
+}
+
+// SearchOptions represents the options of a search, the results are always sorted by the run creation time in descending order
+type SearchOptions struct {
+	Keyword string
+
+	RepoIDs    []int64 // only search logs of these repositories, it's required to make sure users can't see logs of inaccessible repositories
+	WorkflowID string  // the file name of the workflow, like "build.yml"
+	Ref        string  // the full ref name, like "refs/heads/main"
+
+	CreatedAfterUnix  optional.Option[int64]
+	CreatedBeforeUnix optional.Option[int64]
+
+	SearchMode indexer.SearchModeType
+
+	Paginator *db.ListOptions
+}
@@ -0,0 +1,36 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocID(t *testing.T) {
+	id := DocID(12, 3, 200)
+	assert.Equal(t, "12_3_200", id)
+
+	m, err := ParseDocID(id)
+	assert.NoError(t, err)
+	assert.Equal(t, Match{TaskID: 12, Step: 3, LineStart: 200}, m)
+
+	_, err = ParseDocID("12-3")
+	assert.Error(t, err)
+}
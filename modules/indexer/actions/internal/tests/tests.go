@@ -0,0 +1,169 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+// This package contains tests for actions log indexer modules.
+// All the code in this package is only used for testing.
+// Do not put any production code in this package to avoid it being included in the final binary.
+
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kumose/kmup/models/db"
+	"github.com/kumose/kmup/modules/indexer"
+	"github.com/kumose/kmup/modules/indexer/actions/internal"
+	"github.com/kumose/kmup/modules/optional"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexer(t *testing.T, indexer internal.Indexer) {
+	_, err := indexer.Init(t.Context())
+	require.NoError(t, err)
+
+	require.NoError(t, indexer.Ping(t.Context()))
+
+	require.NoError(t, indexer.Index(t.Context(), generateDefaultIndexerData()...))
+	waitData(t, indexer, 4)
+
+	for _, c := range cases {
+		t.Run(c.Name, func(t *testing.T) {
+			result, err := indexer.Search(t.Context(), c.SearchOptions)
+			require.NoError(t, err)
+
+			assert.Equal(t, c.Expected, result.Hits)
+			assert.EqualValues(t, len(c.Expected), result.Total)
+
+			// test counting
+			c.SearchOptions.Paginator = &db.ListOptions{PageSize: 0}
+			countResult, err := indexer.Search(t.Context(), c.SearchOptions)
+			require.NoError(t, err)
+			assert.Empty(t, countResult.Hits)
+			assert.Equal(t, result.Total, countResult.Total)
+		})
+	}
+
+	t.Run("delete", func(t *testing.T) {
+		require.NoError(t, indexer.Delete(t.Context(), 1, 3))
+		waitData(t, indexer, 1)
+
+		result, err := indexer.Search(t.Context(), &internal.SearchOptions{RepoIDs: []int64{1, 2}})
+		require.NoError(t, err)
+		assert.Equal(t, []internal.Match{{TaskID: 2, Step: 1, LineStart: 100}}, result.Hits)
+
+		require.NoError(t, indexer.Delete(t.Context(), 2))
+		waitData(t, indexer, 0)
+	})
+}
+
+func generateDefaultIndexerData() []*internal.IndexerData {
+	return []*internal.IndexerData{
+		{
+			ID: internal.DocID(1, 1, 0), RepoID: 1, RunID: 1, JobID: 1, TaskID: 1, Step: 1, LineStart: 0,
+			Content:    "build started\nerror: connection refused\nbuild failed",
+			WorkflowID: "build.yml", Ref: "refs/heads/main", CreatedUnix: 1000,
+		},
+		{
+			ID: internal.DocID(1, 2, 0), RepoID: 1, RunID: 1, JobID: 1, TaskID: 1, Step: 2, LineStart: 0,
+			Content:    "running tests\nall tests passed",
+			WorkflowID: "build.yml", Ref: "refs/heads/main", CreatedUnix: 1000,
+		},
+		{
+			ID: internal.DocID(2, 1, 100), RepoID: 1, RunID: 2, JobID: 2, TaskID: 2, Step: 1, LineStart: 100,
+			Content:    "Error: Connection Refused by remote host",
+			WorkflowID: "test.yml", Ref: "refs/heads/dev", CreatedUnix: 2000,
+		},
+		{
+			ID: internal.DocID(3, 1, 0), RepoID: 2, RunID: 3, JobID: 3, TaskID: 3, Step: 1, LineStart: 0,
+			Content:    "error: connection refused",
+			WorkflowID: "build.yml", Ref: "refs/heads/main", CreatedUnix: 3000,
+		},
+	}
+}
+
+var (
+	match110  = internal.Match{TaskID: 1, Step: 1, LineStart: 0}
+	match120  = internal.Match{TaskID: 1, Step: 2, LineStart: 0}
+	match2100 = internal.Match{TaskID: 2, Step: 1, LineStart: 100}
+	match310  = internal.Match{TaskID: 3, Step: 1, LineStart: 0}
+)
+
+var cases = []*testIndexerCase{
+	{
+		Name:          "no repo",
+		SearchOptions: &internal.SearchOptions{},
+		Expected:      []internal.Match{},
+	},
+	{
+		Name:          "repo",
+		SearchOptions: &internal.SearchOptions{RepoIDs: []int64{1}},
+		Expected:      []internal.Match{match2100, match120, match110},
+	},
+	{
+		Name:          "keyword",
+		SearchOptions: &internal.SearchOptions{RepoIDs: []int64{1}, Keyword: "connection refused", SearchMode: indexer.SearchModeExact},
+		Expected:      []internal.Match{match2100, match110},
+	},
+	{
+		Name:          "keyword words",
+		SearchOptions: &internal.SearchOptions{RepoIDs: []int64{1}, Keyword: "passed tests", SearchMode: indexer.SearchModeWords},
+		Expected:      []internal.Match{match120},
+	},
+	{
+		Name:          "repos",
+		SearchOptions: &internal.SearchOptions{RepoIDs: []int64{1, 2}, Keyword: "connection refused"},
+		Expected:      []internal.Match{match310, match2100, match110},
+	},
+	{
+		Name:          "workflow",
+		SearchOptions: &internal.SearchOptions{RepoIDs: []int64{1, 2}, Keyword: "connection", WorkflowID: "build.yml"},
+		Expected:      []internal.Match{match310, match110},
+	},
+	{
+		Name:          "ref",
+		SearchOptions: &internal.SearchOptions{RepoIDs: []int64{1, 2}, Ref: "refs/heads/dev"},
+		Expected:      []internal.Match{match2100},
+	},
+	{
+		Name: "created",
+		SearchOptions: &internal.SearchOptions{
+			RepoIDs:           []int64{1, 2},
+			CreatedAfterUnix:  optional.Some[int64](1500),
+			CreatedBeforeUnix: optional.Some[int64](2500),
+		},
+		Expected: []internal.Match{match2100},
+	},
+}
+
+type testIndexerCase struct {
+	Name          string
+	SearchOptions *internal.SearchOptions
+	Expected      []internal.Match
+}
+
+// waitData waits for the indexer to index all data.
+// Some engines like Elasticsearch index data asynchronously, so we need to wait for a while.
+func waitData(t *testing.T, indexer internal.Indexer, total int64) {
+	assert.Eventually(t, func() bool {
+		result, err := indexer.Search(t.Context(), &internal.SearchOptions{RepoIDs: []int64{1, 2}, Paginator: &db.ListOptions{}})
+		require.NoError(t, err)
+		return result.Total == total
+	}, 10*time.Second, 100*time.Millisecond, "expected total=%d", total)
+}
@@ -0,0 +1,209 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package meilisearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kumose/kmup/modules/indexer"
+	"github.com/kumose/kmup/modules/indexer/actions/internal"
+	indexer_internal "github.com/kumose/kmup/modules/indexer/internal"
+	inner_meilisearch "github.com/kumose/kmup/modules/indexer/internal/meilisearch"
+	"github.com/kumose/kmup/modules/json"
+
+	"github.com/meilisearch/meilisearch-go"
+)
+
+const (
+	actionsLogIndexerLatestVersion = 1
+
+	// TODO: make this configurable if necessary
+	maxTotalHits = 10000
+)
+
+// ErrMalformedResponse is never expected as we initialize the indexer ourself and so define the types.
+var ErrMalformedResponse = errors.New("meilisearch returned unexpected malformed content")
+
+var _ internal.Indexer = &Indexer{}
+
+// Indexer implements Indexer interface
+type Indexer struct {
+	inner                    *inner_meilisearch.Indexer
+	indexer_internal.Indexer // do not composite inner_meilisearch.Indexer directly to avoid exposing too much
+}
+
+func (b *Indexer) SupportedSearchModes() []indexer.SearchMode {
+	return indexer.SearchModesExactWords()
+}
+
+// NewIndexer creates a new meilisearch indexer
+func NewIndexer(url, apiKey, indexerName string) *Indexer {
+	settings := &meilisearch.Settings{
+		// The default ranking rules of meilisearch are: ["words", "typo", "proximity", "attribute", "sort", "exactness"]
+		// So even if we specify the sort order, it could not be respected because the priority of "sort" is so low.
+		// So we need to specify the ranking rules to make sure the sort order is respected.
+		// See https://www.meilisearch.com/docs/learn/core_concepts/relevancy
+		RankingRules: []string{"sort", // make sure "sort" has the highest priority
+			"words", "typo", "proximity", "attribute", "exactness"},
+
+		SearchableAttributes: []string{
+			"content",
+		},
+		DisplayedAttributes: []string{
+			"id",
+		},
+		FilterableAttributes: []string{
+			"repo_id",
+			"task_id",
+			"workflow_id",
+			"ref",
+			"created_unix",
+		},
+		SortableAttributes: []string{
+			"created_unix",
+			"id",
+		},
+		Pagination: &meilisearch.Pagination{
+			MaxTotalHits: maxTotalHits,
+		},
+	}
+
+	inner := inner_meilisearch.NewIndexer(url, apiKey, indexerName, actionsLogIndexerLatestVersion, settings)
+	indexer := &Indexer{
+		inner:   inner,
+		Indexer: inner,
+	}
+	return indexer
+}
+
+// Index will save the index data
+func (b *Indexer) Index(_ context.Context, data ...*internal.IndexerData) error {
+	if len(data) == 0 {
+		return nil
+	}
+	// use default primary key which should be "id"
+	_, err := b.inner.Client.Index(b.inner.VersionedIndexName()).AddDocuments(data, nil)
+	return err
+}
+
+// Delete deletes the indexes of the given tasks
+func (b *Indexer) Delete(_ context.Context, taskIDs ...int64) error {
+	if len(taskIDs) == 0 {
+		return nil
+	}
+	_, err := b.inner.Client.Index(b.inner.VersionedIndexName()).DeleteDocumentsByFilter(inner_meilisearch.NewFilterIn("task_id", taskIDs...).Statement())
+	return err
+}
+
+// Search searches for log chunks by given conditions.
+func (b *Indexer) Search(ctx context.Context, options *internal.SearchOptions) (*internal.SearchResult, error) {
+	if len(options.RepoIDs) == 0 {
+		// an empty "IN" filter is dropped, so return early to avoid searching in all repositories
+		return &internal.SearchResult{}, nil
+	}
+
+	query := inner_meilisearch.FilterAnd{}
+	query.And(inner_meilisearch.NewFilterIn("repo_id", options.RepoIDs...))
+
+	if options.WorkflowID != "" {
+		query.And(inner_meilisearch.NewFilterEqString("workflow_id", options.WorkflowID))
+	}
+	if options.Ref != "" {
+		query.And(inner_meilisearch.NewFilterEqString("ref", options.Ref))
+	}
+
+	if options.CreatedAfterUnix.Has() {
+		query.And(inner_meilisearch.NewFilterGte("created_unix", options.CreatedAfterUnix.Value()))
+	}
+	if options.CreatedBeforeUnix.Has() {
+		query.And(inner_meilisearch.NewFilterLte("created_unix", options.CreatedBeforeUnix.Value()))
+	}
+
+	skip, limit := indexer_internal.ParsePaginator(options.Paginator, maxTotalHits)
+
+	counting := limit == 0
+	if counting {
+		// If set limit to 0, it will be 20 by default, and -1 is not allowed.
+		// See https://www.meilisearch.com/docs/reference/api/search#limit
+		// So set limit to 1 to make the cost as low as possible, then clear the result before returning.
+		limit = 1
+	}
+
+	keyword := options.Keyword // default to match "words"
+	if options.SearchMode == indexer.SearchModeExact {
+		// https://www.meilisearch.com/docs/reference/api/search#phrase-search
+		keyword = doubleQuoteKeyword(keyword)
+	}
+
+	searchRes, err := b.inner.Client.Index(b.inner.VersionedIndexName()).SearchWithContext(ctx, keyword, &meilisearch.SearchRequest{
+		Filter:           query.Statement(),
+		Limit:            int64(limit),
+		Offset:           int64(skip),
+		Sort:             []string{"created_unix:desc", "id:desc"},
+		MatchingStrategy: "all",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if counting {
+		searchRes.Hits = nil
+	}
+
+	hits, err := convertHits(searchRes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &internal.SearchResult{
+		Total: searchRes.EstimatedTotalHits,
+		Hits:  hits,
+	}, nil
+}
+
+func doubleQuoteKeyword(k string) string {
+	kp := strings.Split(k, " ")
+	parts := 0
+	for i := range kp {
+		part := strings.Trim(kp[i], "\"")
+		if part != "" {
+			kp[parts] = fmt.Sprintf(`"%s"`, part)
+			parts++
+		}
+	}
+	return strings.Join(kp[:parts], " ")
+}
+
+func convertHits(searchRes *meilisearch.SearchResponse) ([]internal.Match, error) {
+	hits := make([]internal.Match, 0, len(searchRes.Hits))
+	for _, hit := range searchRes.Hits {
+		var id string
+		if err := json.Unmarshal(hit["id"], &id); err != nil {
+			return nil, ErrMalformedResponse
+		}
+		m, err := internal.ParseDocID(id)
+		if err != nil {
+			return nil, ErrMalformedResponse
+		}
+		hits = append(hits, m)
+	}
+	return hits, nil
+}
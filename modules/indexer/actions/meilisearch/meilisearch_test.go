@@ -0,0 +1,83 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package meilisearch
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kumose/kmup/modules/indexer/actions/internal"
+	"github.com/kumose/kmup/modules/indexer/actions/internal/tests"
+	"github.com/kumose/kmup/modules/json"
+
+	"github.com/meilisearch/meilisearch-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeilisearchIndexer(t *testing.T) {
+	// The meilisearch instance started by pull-db-tests.yml > test-unit > services > meilisearch
+	url := "http://meilisearch:7700"
+	key := "" // auth has been disabled in test environment
+
+	if os.Getenv("CI") == "" {
+		// Make it possible to run tests against a local meilisearch instance
+		url = os.Getenv("TEST_MEILISEARCH_URL")
+		if url == "" {
+			t.Skip("TEST_MEILISEARCH_URL not set and not running in CI")
+			return
+		}
+		key = os.Getenv("TEST_MEILISEARCH_KEY")
+	}
+
+	require.Eventually(t, func() bool {
+		resp, err := http.Get(url)
+		return err == nil && resp.StatusCode == http.StatusOK
+	}, time.Minute, time.Second, "Expected meilisearch to be up")
+
+	indexer := NewIndexer(url, key, fmt.Sprintf("test_meilisearch_actions_log_indexer_%d", time.Now().Unix()))
+	defer indexer.Close()
+
+	tests.TestIndexer(t, indexer)
+}
+
+func TestConvertHits(t *testing.T) {
+	convert := func(d any) []byte {
+		b, _ := json.Marshal(d)
+		return b
+	}
+
+	_, err := convertHits(&meilisearch.SearchResponse{
+		Hits: []meilisearch.Hit{{"id": convert(1)}},
+	})
+	assert.ErrorIs(t, err, ErrMalformedResponse)
+
+	_, err = convertHits(&meilisearch.SearchResponse{
+		Hits: []meilisearch.Hit{{"id": convert("not-a-doc-id")}},
+	})
+	assert.ErrorIs(t, err, ErrMalformedResponse)
+
+	hits, err := convertHits(&meilisearch.SearchResponse{
+		Hits: []meilisearch.Hit{{"id": convert("11_2_300")}, {"id": convert("12_0_0")}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []internal.Match{{TaskID: 11, Step: 2, LineStart: 300}, {TaskID: 12}}, hits)
+}
@@ -0,0 +1,156 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	actions_model "github.com/kumose/kmup/models/actions"
+	"github.com/kumose/kmup/modules/actions"
+	"github.com/kumose/kmup/modules/indexer/actions/internal"
+	"github.com/kumose/kmup/modules/util"
+)
+
+// LinesPerDocument is the max number of log lines stored in one document of the indexer
+const LinesPerDocument = 100
+
+var ansiEscapeRegexp = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]`)
+
+// getIndexerData returns the documents of the logs of the task.
+// It returns nothing if the task doesn't exist or the logs are not ready or have expired.
+func getIndexerData(ctx context.Context, taskID int64) ([]*internal.IndexerData, error) {
+	task, err := actions_model.GetTaskByID(ctx, taskID)
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !task.Status.IsDone() || !task.LogInStorage || task.LogExpired {
+		return nil, nil
+	}
+	if err := task.LoadAttributes(ctx); err != nil {
+		return nil, err
+	}
+	run := task.Job.Run
+
+	rows, err := actions.ReadLogs(ctx, task.LogInStorage, task.LogFilename, 0, -1)
+	if err != nil {
+		return nil, fmt.Errorf("read logs: %w", err)
+	}
+
+	var data []*internal.IndexerData
+	for stepIndex, step := range actions.FullSteps(task) {
+		for lineStart := int64(0); lineStart < step.LogLength; lineStart += LinesPerDocument {
+			begin := step.LogIndex + lineStart
+			end := min(begin+LinesPerDocument, step.LogIndex+step.LogLength, int64(len(rows)))
+			if begin >= end {
+				break
+			}
+			lines := make([]string, 0, end-begin)
+			for _, row := range rows[begin:end] {
+				lines = append(lines, CleanLogLine(row.Content))
+			}
+			data = append(data, &internal.IndexerData{
+				ID:          internal.DocID(task.ID, int64(stepIndex), lineStart),
+				RepoID:      task.RepoID,
+				RunID:       run.ID,
+				JobID:       task.JobID,
+				TaskID:      task.ID,
+				Step:        int64(stepIndex),
+				LineStart:   lineStart,
+				Content:     strings.Join(lines, "\n"),
+				WorkflowID:  run.WorkflowID,
+				Ref:         run.Ref,
+				CreatedUnix: run.Created,
+			})
+		}
+	}
+	return data, nil
+}
+
+// CleanLogLine removes the ANSI escape sequences from a log line, so only the text will be indexed and displayed
+func CleanLogLine(line string) string {
+	return ansiEscapeRegexp.ReplaceAllString(line, "")
+}
+
+// ReadMatchedLines reads the log lines of a matched chunk, the task must have its steps loaded
+func ReadMatchedLines(ctx context.Context, task *actions_model.ActionTask, m Match) ([]string, error) {
+	steps := actions.FullSteps(task)
+	if m.Step < 0 || m.Step >= int64(len(steps)) {
+		return nil, fmt.Errorf("step %d of task %d does not exist", m.Step, task.ID)
+	}
+	step := steps[m.Step]
+	index := step.LogIndex + m.LineStart
+	if m.LineStart < 0 || m.LineStart >= step.LogLength || index >= int64(len(task.LogIndexes)) {
+		return nil, fmt.Errorf("line %d of step %d of task %d does not exist", m.LineStart, m.Step, task.ID)
+	}
+	rows, err := actions.ReadLogs(ctx, task.LogInStorage, task.LogFilename, task.LogIndexes[index], min(LinesPerDocument, step.LogLength-m.LineStart))
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]string, 0, len(rows))
+	for _, row := range rows {
+		lines = append(lines, CleanLogLine(row.Content))
+	}
+	return lines, nil
+}
+
+// MatchLine returns the index of the line which matches the keyword best:
+// the first line containing the whole keyword, or else the first line containing all words of the keyword,
+// or else the first line containing any word of the keyword. It returns 0 if no line matches.
+func MatchLine(lines []string, keyword string) int {
+	keyword = strings.ToLower(strings.TrimSpace(keyword))
+	if keyword == "" {
+		return 0
+	}
+	lowerLines := make([]string, len(lines))
+	for i, line := range lines {
+		lowerLines[i] = strings.ToLower(line)
+	}
+	for i, line := range lowerLines {
+		if strings.Contains(line, keyword) {
+			return i
+		}
+	}
+	words := strings.Fields(keyword)
+	for i, line := range lowerLines {
+		matchedAll := true
+		for _, word := range words {
+			if !strings.Contains(line, word) {
+				matchedAll = false
+				break
+			}
+		}
+		if matchedAll {
+			return i
+		}
+	}
+	for i, line := range lowerLines {
+		for _, word := range words {
+			if strings.Contains(line, word) {
+				return i
+			}
+		}
+	}
+	return 0
+}
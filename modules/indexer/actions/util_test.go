@@ -0,0 +1,43 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCleanLogLine(t *testing.T) {
+	assert.Equal(t, "error: connection refused", CleanLogLine("\x1b[31;1merror:\x1b[0m connection refused"))
+	assert.Equal(t, "plain line", CleanLogLine("plain line"))
+}
+
+func TestMatchLine(t *testing.T) {
+	lines := []string{
+		"Run make test",
+		"dial tcp: connection timed out",
+		"Error: Connection Refused by remote host",
+		"refused connection",
+	}
+	assert.Equal(t, 2, MatchLine(lines, "connection refused"))
+	assert.Equal(t, 3, MatchLine(lines, " Refused Connection "))
+	assert.Equal(t, 1, MatchLine(lines, "tcp refused"))
+	assert.Equal(t, 0, MatchLine(lines, "not found"))
+	assert.Equal(t, 0, MatchLine(lines, ""))
+}
@@ -94,6 +94,14 @@ func NewFilterEq[T bool | int64](field string, value T) FilterEq {
 	return FilterEq(fmt.Sprintf("%s = %v", field, value))
 }
 
+// NewFilterEqString creates a new FilterEq for a string value.
+// The value is quoted and escaped, so it could contain any characters.
+func NewFilterEqString(field, value string) FilterEq {
+	return FilterEq(fmt.Sprintf(`%s = "%s"`, field, filterStringEscaper.Replace(value)))
+}
+
+var filterStringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
 func (f FilterEq) Statement() string {
 	return string(f)
 }
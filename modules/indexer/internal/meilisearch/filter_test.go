@@ -0,0 +1,33 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package meilisearch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFilterEqString(t *testing.T) {
+	assert.Equal(t, `ref = "refs/heads/main"`, NewFilterEqString("ref", "refs/heads/main").Statement())
+	assert.Equal(t, `ref = "a\"b\\c"`, NewFilterEqString("ref", `a"b\c`).Statement())
+
+	q := &FilterAnd{}
+	q.And(NewFilterIn("repo_id", int64(1), int64(2))).And(NewFilterEqString("workflow_id", "build.yml"))
+	assert.Equal(t, `(repo_id IN [1, 2]) AND (workflow_id = "build.yml")`, q.Statement())
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/kumose/kmup/modules/packages/container/helm"
 	"github.com/kumose/kmup/modules/validation"
 
+	"github.com/opencontainers/go-digest"
 	oci "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
@@ -35,6 +36,7 @@ const (
 	PropertyMediaType         = "container.mediatype"
 	PropertyManifestTagged    = "container.manifest.tagged"
 	PropertyManifestReference = "container.manifest.reference"
+	PropertyManifestSubject   = "container.manifest.subject"
 
 	DefaultPlatform = "linux/amd64"
 
@@ -49,8 +51,9 @@ const (
 type ImageType string
 
 const (
-	TypeOCI  ImageType = "oci"
-	TypeHelm ImageType = "helm"
+	TypeOCI      ImageType = "oci"
+	TypeHelm     ImageType = "helm"
+	TypeArtifact ImageType = "artifact"
 )
 
 // Name gets the name of the image type
@@ -58,6 +61,8 @@ func (it ImageType) Name() string {
 	switch it {
 	case TypeHelm:
 		return "Helm Chart"
+	case TypeArtifact:
+		return "OCI Artifact"
 	default:
 		return "OCI / Docker"
 	}
@@ -77,6 +82,9 @@ type Metadata struct {
 	Labels           map[string]string `json:"labels,omitempty"`
 	ImageLayers      []string          `json:"layer_creation,omitempty"`
 	Manifests        []*Manifest       `json:"manifests,omitempty"`
+	ArtifactType     string            `json:"artifact_type,omitempty"`
+	Subject          string            `json:"subject,omitempty"`
+	Annotations      map[string]string `json:"annotations,omitempty"`
 }
 
 type Manifest struct {
@@ -97,11 +105,50 @@ func IsMediaTypeImageIndex(mt string) bool {
 	return strings.EqualFold(mt, oci.MediaTypeImageIndex) || strings.EqualFold(mt, "application/vnd.docker.distribution.manifest.list.v2+json")
 }
 
+// ReferrersTag returns the tag of the fallback image index which lists the referrers of the subject
+// https://github.com/opencontainers/distribution-spec/blob/main/spec.md#referrers-tag-schema
+func ReferrersTag(subject digest.Digest) string {
+	alg, encoded, _ := strings.Cut(subject.String(), ":")
+	if len(alg) > 32 {
+		alg = alg[:32]
+	}
+	if len(encoded) > 64 {
+		encoded = encoded[:64]
+	}
+	return alg + "-" + encoded
+}
+
+// ParseReferrersTag returns the subject digest if the tag follows the referrers tag schema
+func ParseReferrersTag(tag string) (digest.Digest, bool) {
+	alg, encoded, ok := strings.Cut(tag, "-")
+	if !ok || alg != string(digest.SHA256) {
+		return "", false
+	}
+	d := digest.NewDigestFromEncoded(digest.SHA256, encoded)
+	if d.Validate() != nil {
+		return "", false
+	}
+	return d, true
+}
+
+// ArtifactTypeOf returns the artifact type of a manifest as listed in the referrers response.
+// If the manifest has no explicit artifact type, the media type of its config is used.
+func ArtifactTypeOf(artifactType, configMediaType string) string {
+	if artifactType != "" {
+		return artifactType
+	}
+	return configMediaType
+}
+
 // ParseImageConfig parses the metadata of an image config
 func ParseImageConfig(mediaType string, r io.Reader) (*Metadata, error) {
 	if strings.EqualFold(mediaType, helm.ConfigMediaType) {
 		return parseHelmConfig(r)
 	}
+	if strings.EqualFold(mediaType, oci.MediaTypeEmptyJSON) {
+		// artifacts without a config use the empty descriptor
+		return &Metadata{Type: TypeArtifact}, nil
+	}
 
 	// fallback to OCI Image Config
 	// FIXME: this fallback is not right, we should strictly check the media type in the future
@@ -23,6 +23,7 @@ import (
 
 	"github.com/kumose/kmup/modules/packages/container/helm"
 
+	"github.com/opencontainers/go-digest"
 	oci "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -79,4 +80,27 @@ func TestParseImageConfig(t *testing.T) {
 	metadata, err = ParseImageConfig("anything-unknown", strings.NewReader(""))
 	require.NoError(t, err)
 	assert.Equal(t, &Metadata{Platform: "unknown/unknown"}, metadata)
+
+	metadata, err = ParseImageConfig(oci.MediaTypeEmptyJSON, strings.NewReader("{}"))
+	require.NoError(t, err)
+	assert.Equal(t, &Metadata{Type: TypeArtifact}, metadata)
+}
+
+func TestReferrersTag(t *testing.T) {
+	d := digest.Digest("sha256:a3ed95caeb02ffe68cdd9fd84406680ae93d633cb16422d00e8a7c22955b46d4")
+	assert.Equal(t, "sha256-a3ed95caeb02ffe68cdd9fd84406680ae93d633cb16422d00e8a7c22955b46d4", ReferrersTag(d))
+
+	subject, ok := ParseReferrersTag(ReferrersTag(d))
+	assert.True(t, ok)
+	assert.Equal(t, d, subject)
+
+	for _, tag := range []string{"latest", "sha256-abc", "sha512-a3ed95caeb02ffe68cdd9fd84406680ae93d633cb16422d00e8a7c22955b46d4", "sha256-a3ed95caeb02ffe68cdd9fd84406680ae93d633cb16422d00e8a7c22955b46d4.sig"} {
+		_, ok := ParseReferrersTag(tag)
+		assert.False(t, ok, tag)
+	}
+}
+
+func TestArtifactTypeOf(t *testing.T) {
+	assert.Equal(t, "application/vnd.example.sbom", ArtifactTypeOf("application/vnd.example.sbom", oci.MediaTypeEmptyJSON))
+	assert.Equal(t, oci.MediaTypeImageConfig, ArtifactTypeOf("", oci.MediaTypeImageConfig))
 }
@@ -0,0 +1,351 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package hex
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/kumose/kmup/modules/util"
+	"github.com/kumose/kmup/modules/validation"
+)
+
+var (
+	ErrInvalidTarball       = util.NewInvalidArgumentErrorf("package tarball is invalid")
+	ErrUnsupportedVersion   = util.NewInvalidArgumentErrorf("package tarball version is not supported")
+	ErrChecksumMismatch     = util.NewInvalidArgumentErrorf("package tarball checksum does not match")
+	ErrMetadataFileTooLarge = util.NewInvalidArgumentErrorf("metadata.config file is too large")
+	ErrInvalidMetadata      = util.NewInvalidArgumentErrorf("metadata.config file is invalid")
+	ErrInvalidName          = util.NewInvalidArgumentErrorf("package name is invalid")
+	ErrInvalidVersion       = util.NewInvalidArgumentErrorf("package version is invalid")
+	ErrInvalidRequirement   = util.NewInvalidArgumentErrorf("package requirement is invalid")
+)
+
+const (
+	SettingKeyPrivate = "hex.key.private"
+	SettingKeyPublic  = "hex.key.public"
+
+	// tarballVersion is the only supported version of the package tarball format
+	tarballVersion = "3"
+
+	maxMetadataFileSize = 128 * 1024
+	maxReadmeFileSize   = 1024 * 1024
+)
+
+var (
+	namePattern = regexp.MustCompile(`\A[a-z][a-z0-9_]*\z`)
+	// https://semver.org/#is-there-a-suggested-regular-expression-regex-to-check-a-semver-string
+	versionPattern = regexp.MustCompile(`\A(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?\z`)
+)
+
+// Package represents a Hex package
+type Package struct {
+	Name     string
+	Version  string
+	Metadata *Metadata
+}
+
+// Metadata represents the metadata of a Hex package
+type Metadata struct {
+	App           string            `json:"app,omitempty"`
+	Description   string            `json:"description,omitempty"`
+	Licenses      []string          `json:"licenses,omitempty"`
+	Links         map[string]string `json:"links,omitempty"`
+	BuildTools    []string          `json:"build_tools,omitempty"`
+	Elixir        string            `json:"elixir,omitempty"`
+	Requirements  []*Requirement    `json:"requirements,omitempty"`
+	InnerChecksum string            `json:"inner_checksum"`
+	Readme        string            `json:"readme,omitempty"`
+}
+
+// Requirement represents a dependency of a Hex package
+type Requirement struct {
+	Name        string `json:"name"`
+	App         string `json:"app,omitempty"`
+	Requirement string `json:"requirement"`
+	Optional    bool   `json:"optional,omitempty"`
+	Repository  string `json:"repository,omitempty"`
+}
+
+// IsValidName checks if the package name is valid
+func IsValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// IsValidVersion checks if the package version is a valid SemVer 2.0 version
+func IsValidVersion(version string) bool {
+	return versionPattern.MatchString(version)
+}
+
+// TarballFilename returns the name of the package tarball
+func TarballFilename(name, version string) string {
+	return name + "-" + version + ".tar"
+}
+
+// ParseTarballFilename splits a tarball filename into package name and version
+func ParseTarballFilename(filename string) (string, string, bool) {
+	name, version, ok := strings.Cut(strings.TrimSuffix(filename, ".tar"), "-")
+	if !ok || !strings.HasSuffix(filename, ".tar") || !IsValidName(name) || !IsValidVersion(version) {
+		return "", "", false
+	}
+	return name, version, true
+}
+
+// ParsePackage parses the outer package tarball
+// https://github.com/hexpm/specifications/blob/main/package_tarball.md
+func ParsePackage(r io.Reader) (*Package, error) {
+	var version, checksum, metadata []byte
+	var readme string
+	var hasContents bool
+
+	// the inner checksum is calculated over VERSION, metadata.config and contents.tar.gz
+	inner := sha256.New()
+
+	tr := tar.NewReader(r)
+	for {
+		hd, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, ErrInvalidTarball
+		}
+
+		if hd.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		switch hd.Name {
+		case "VERSION":
+			if version != nil || metadata != nil || hasContents {
+				return nil, ErrInvalidTarball
+			}
+			if version, err = io.ReadAll(io.LimitReader(tr, 16)); err != nil {
+				return nil, err
+			}
+			inner.Write(version)
+		case "CHECKSUM":
+			if checksum, err = io.ReadAll(io.LimitReader(tr, 128)); err != nil {
+				return nil, err
+			}
+		case "metadata.config":
+			if version == nil || metadata != nil || hasContents {
+				return nil, ErrInvalidTarball
+			}
+			if hd.Size > maxMetadataFileSize {
+				return nil, ErrMetadataFileTooLarge
+			}
+			if metadata, err = io.ReadAll(io.LimitReader(tr, maxMetadataFileSize)); err != nil {
+				return nil, err
+			}
+			inner.Write(metadata)
+		case "contents.tar.gz":
+			if metadata == nil || hasContents {
+				return nil, ErrInvalidTarball
+			}
+			hasContents = true
+
+			tee := io.TeeReader(tr, inner)
+			if readme, err = readReadme(tee); err != nil {
+				return nil, err
+			}
+			if _, err := io.Copy(io.Discard, tee); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if version == nil || checksum == nil || metadata == nil || !hasContents {
+		return nil, ErrInvalidTarball
+	}
+	if string(bytes.TrimSpace(version)) != tarballVersion {
+		return nil, ErrUnsupportedVersion
+	}
+
+	innerChecksum := hex.EncodeToString(inner.Sum(nil))
+	if !strings.EqualFold(string(bytes.TrimSpace(checksum)), innerChecksum) {
+		return nil, ErrChecksumMismatch
+	}
+
+	p, err := ParseMetadata(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	p.Metadata.InnerChecksum = innerChecksum
+	p.Metadata.Readme = readme
+
+	return p, nil
+}
+
+// readReadme reads the README file from the contents.tar.gz archive
+func readReadme(r io.Reader) (string, error) {
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return "", ErrInvalidTarball
+	}
+	defer gzr.Close()
+
+	tr := tar.NewReader(gzr)
+	for {
+		hd, err := tr.Next()
+		if err == io.EOF {
+			return "", nil
+		}
+		if err != nil {
+			return "", ErrInvalidTarball
+		}
+
+		if hd.Typeflag != tar.TypeReg || hd.Size > maxReadmeFileSize {
+			continue
+		}
+
+		if name := path.Clean(hd.Name); strings.EqualFold(name, "readme.md") || strings.EqualFold(name, "readme") {
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return "", err
+			}
+			return string(data), nil
+		}
+	}
+}
+
+// ParseMetadata parses the metadata.config file of a package
+// https://github.com/hexpm/specifications/blob/main/package_metadata.md
+func ParseMetadata(data []byte) (*Package, error) {
+	terms, err := ParseTerms(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]any, len(terms))
+	for _, term := range terms {
+		tuple, ok := term.(Tuple)
+		if !ok || len(tuple) != 2 {
+			return nil, ErrInvalidMetadata
+		}
+		key, ok := toString(tuple[0])
+		if !ok {
+			return nil, ErrInvalidMetadata
+		}
+		values[key] = tuple[1]
+	}
+
+	name, _ := toString(values["name"])
+	if !IsValidName(name) {
+		return nil, ErrInvalidName
+	}
+
+	version, _ := toString(values["version"])
+	if !IsValidVersion(version) {
+		return nil, ErrInvalidVersion
+	}
+
+	m := &Metadata{}
+	m.App, _ = toString(values["app"])
+	m.Description, _ = toString(values["description"])
+	m.Elixir, _ = toString(values["elixir"])
+	m.Licenses, _ = toStringList(values["licenses"])
+	m.BuildTools, _ = toStringList(values["build_tools"])
+
+	if links, ok := toStringMap(values["links"]); ok {
+		m.Links = make(map[string]string, len(links))
+		for title, link := range links {
+			if s, ok := toString(link); ok && validation.IsValidURL(s) {
+				m.Links[title] = s
+			}
+		}
+	}
+
+	if v, ok := values["requirements"]; ok {
+		if m.Requirements, err = parseRequirements(v); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Package{
+		Name:     name,
+		Version:  version,
+		Metadata: m,
+	}, nil
+}
+
+// parseRequirements parses the requirements which are either a list of proplists
+// containing the name or a proplist with the name as key
+func parseRequirements(v any) ([]*Requirement, error) {
+	var entries []map[string]any
+
+	if list, ok := v.([]any); ok && len(list) > 0 {
+		if _, isTuple := list[0].(Tuple); !isTuple {
+			for _, item := range list {
+				m, ok := toStringMap(item)
+				if !ok {
+					return nil, ErrInvalidRequirement
+				}
+				entries = append(entries, m)
+			}
+		}
+	}
+	if entries == nil {
+		byName, ok := toStringMap(v)
+		if !ok {
+			return nil, ErrInvalidRequirement
+		}
+		for name, item := range byName {
+			m, ok := toStringMap(item)
+			if !ok {
+				return nil, ErrInvalidRequirement
+			}
+			m["name"] = name
+			entries = append(entries, m)
+		}
+	}
+
+	requirements := make([]*Requirement, 0, len(entries))
+	for _, entry := range entries {
+		r := &Requirement{}
+		r.Name, _ = toString(entry["name"])
+		r.App, _ = toString(entry["app"])
+		r.Requirement, _ = toString(entry["requirement"])
+		r.Repository, _ = toString(entry["repository"])
+		r.Optional, _ = entry["optional"].(bool)
+
+		if !IsValidName(r.Name) {
+			return nil, ErrInvalidRequirement
+		}
+		if r.App == r.Name {
+			r.App = ""
+		}
+
+		requirements = append(requirements, r)
+	}
+
+	sort.Slice(requirements, func(i, j int) bool {
+		return requirements[i].Name < requirements[j].Name
+	})
+
+	return requirements, nil
+}
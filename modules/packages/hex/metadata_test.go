@@ -0,0 +1,218 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package hex
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const metadataContent = `{<<"app">>,<<"kmup_client">>}.
+{<<"build_tools">>,[<<"mix">>]}.
+{<<"description">>,<<"Client for the Kmup API \xE2\x9C\x93"/utf8>>}.
+{<<"elixir">>,<<"~> 1.14">>}.
+{<<"files">>,[<<"lib">>,<<"lib/kmup_client.ex">>,<<"mix.exs">>,<<"README.md">>]}.
+{<<"licenses">>,[<<"MIT">>]}.
+{<<"links">>,[{<<"Source">>,<<"https://kmup.com/kmup/kmup_client">>},{<<"Invalid">>,<<"not a url">>}]}.
+{<<"name">>,<<"kmup_client">>}.
+{<<"requirements">>,
+ [[{<<"app">>,<<"jason">>},
+   {<<"name">>,<<"jason">>},
+   {<<"optional">>,false},
+   {<<"repository">>,<<"hexpm">>},
+   {<<"requirement">>,<<"~> 1.4">>}],
+  [{<<"app">>,<<"finch_app">>},
+   {<<"name">>,<<"finch">>},
+   {<<"optional">>,true},
+   {<<"repository">>,<<"kmup">>},
+   {<<"requirement">>,<<">= 0.16.0">>}]]}.
+{<<"version">>,<<"1.2.3-rc.1">>}.
+`
+
+func createContents(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o600, Size: int64(len(content))}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+type tarFile struct {
+	Name    string
+	Content []byte
+}
+
+func createTarball(t *testing.T, files []tarFile) io.Reader {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, f := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: f.Name, Mode: 0o600, Size: int64(len(f.Content))}))
+		_, err := tw.Write(f.Content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	return &buf
+}
+
+func createPackage(t *testing.T, metadata string, contents []byte) (io.Reader, string) {
+	h := sha256.New()
+	h.Write([]byte("3"))
+	h.Write([]byte(metadata))
+	h.Write(contents)
+	checksum := hex.EncodeToString(h.Sum(nil))
+
+	return createTarball(t, []tarFile{
+		{"VERSION", []byte("3")},
+		{"CHECKSUM", []byte(strings.ToUpper(checksum))},
+		{"metadata.config", []byte(metadata)},
+		{"contents.tar.gz", contents},
+	}), checksum
+}
+
+func TestParsePackage(t *testing.T) {
+	contents := createContents(t, map[string]string{
+		"lib/kmup_client.ex": "defmodule KmupClient do\nend\n",
+		"README.md":          "# KmupClient",
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		r, checksum := createPackage(t, metadataContent, contents)
+
+		p, err := ParsePackage(r)
+		require.NoError(t, err)
+		require.NotNil(t, p)
+
+		assert.Equal(t, "kmup_client", p.Name)
+		assert.Equal(t, "1.2.3-rc.1", p.Version)
+		assert.Equal(t, checksum, p.Metadata.InnerChecksum)
+		assert.Equal(t, "kmup_client", p.Metadata.App)
+		assert.Equal(t, "Client for the Kmup API ✓", p.Metadata.Description)
+		assert.Equal(t, "~> 1.14", p.Metadata.Elixir)
+		assert.Equal(t, []string{"MIT"}, p.Metadata.Licenses)
+		assert.Equal(t, []string{"mix"}, p.Metadata.BuildTools)
+		assert.Equal(t, map[string]string{"Source": "https://kmup.com/kmup/kmup_client"}, p.Metadata.Links)
+		assert.Equal(t, "# KmupClient", p.Metadata.Readme)
+		assert.Equal(t, []*Requirement{
+			{Name: "finch", App: "finch_app", Requirement: ">= 0.16.0", Optional: true, Repository: "kmup"},
+			{Name: "jason", Requirement: "~> 1.4", Repository: "hexpm"},
+		}, p.Metadata.Requirements)
+	})
+
+	t.Run("ChecksumMismatch", func(t *testing.T) {
+		r := createTarball(t, []tarFile{
+			{"VERSION", []byte("3")},
+			{"CHECKSUM", []byte(strings.Repeat("A", 64))},
+			{"metadata.config", []byte(metadataContent)},
+			{"contents.tar.gz", contents},
+		})
+
+		p, err := ParsePackage(r)
+		assert.Nil(t, p)
+		assert.ErrorIs(t, err, ErrChecksumMismatch)
+	})
+
+	t.Run("MissingContents", func(t *testing.T) {
+		r := createTarball(t, []tarFile{
+			{"VERSION", []byte("3")},
+			{"CHECKSUM", []byte(strings.Repeat("A", 64))},
+			{"metadata.config", []byte(metadataContent)},
+		})
+
+		p, err := ParsePackage(r)
+		assert.Nil(t, p)
+		assert.ErrorIs(t, err, ErrInvalidTarball)
+	})
+
+	t.Run("UnsupportedVersion", func(t *testing.T) {
+		r := createTarball(t, []tarFile{
+			{"VERSION", []byte("2")},
+			{"CHECKSUM", []byte(strings.Repeat("A", 64))},
+			{"metadata.config", []byte(metadataContent)},
+			{"contents.tar.gz", contents},
+		})
+
+		p, err := ParsePackage(r)
+		assert.Nil(t, p)
+		assert.ErrorIs(t, err, ErrUnsupportedVersion)
+	})
+}
+
+func TestParseMetadata(t *testing.T) {
+	t.Run("InvalidName", func(t *testing.T) {
+		p, err := ParseMetadata([]byte(`{<<"name">>,<<"Kmup-Client">>}. {<<"version">>,<<"1.0.0">>}.`))
+		assert.Nil(t, p)
+		assert.ErrorIs(t, err, ErrInvalidName)
+	})
+
+	t.Run("InvalidVersion", func(t *testing.T) {
+		p, err := ParseMetadata([]byte(`{<<"name">>,<<"kmup">>}. {<<"version">>,<<"1.0">>}.`))
+		assert.Nil(t, p)
+		assert.ErrorIs(t, err, ErrInvalidVersion)
+	})
+
+	t.Run("InvalidTerm", func(t *testing.T) {
+		p, err := ParseMetadata([]byte(`{<<"name">>,<<"kmup">>`))
+		assert.Nil(t, p)
+		assert.ErrorIs(t, err, ErrInvalidTerm)
+	})
+
+	t.Run("RequirementsByName", func(t *testing.T) {
+		p, err := ParseMetadata([]byte(`
+% rebar3 style requirements
+{<<"name">>,<<"kmup">>}.
+{<<"version">>,<<"1.0.0">>}.
+{<<"requirements">>,[{<<"cowboy">>,[{<<"app">>,<<"cowboy">>},{<<"optional">>,false},{<<"requirement">>,<<"2.10.0">>}]}]}.
+`))
+		require.NoError(t, err)
+		assert.Equal(t, []*Requirement{{Name: "cowboy", Requirement: "2.10.0"}}, p.Metadata.Requirements)
+	})
+
+	t.Run("Map", func(t *testing.T) {
+		p, err := ParseMetadata([]byte(`{<<"name">>,"kmup"}. {<<"version">>,<<"1.0.0">>}. {<<"links">>,#{<<"Docs">> => <<"https://kmup.com">>}}.`))
+		require.NoError(t, err)
+		assert.Equal(t, "kmup", p.Name)
+		assert.Equal(t, map[string]string{"Docs": "https://kmup.com"}, p.Metadata.Links)
+	})
+}
+
+func TestParseTarballFilename(t *testing.T) {
+	name, version, ok := ParseTarballFilename("kmup_client-1.2.3-rc.1.tar")
+	assert.True(t, ok)
+	assert.Equal(t, "kmup_client", name)
+	assert.Equal(t, "1.2.3-rc.1", version)
+
+	for _, filename := range []string{"kmup_client-1.2.3.tar.gz", "kmup_client.tar", "Kmup-1.0.0.tar", "kmup-1.0.tar"} {
+		_, _, ok := ParseTarballFilename(filename)
+		assert.False(t, ok, filename)
+	}
+}
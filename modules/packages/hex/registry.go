@@ -0,0 +1,172 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package hex
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The registry resources are gzipped protobuf messages wrapped in a signed envelope
+// https://github.com/hexpm/specifications/blob/main/registry-v2.md
+
+// NamesEntry represents a package in the /names resource
+type NamesEntry struct {
+	Name      string
+	UpdatedAt time.Time
+}
+
+// VersionsEntry represents a package in the /versions resource
+type VersionsEntry struct {
+	Name     string
+	Versions []string
+}
+
+// Release represents a package version in the /packages/<name> resource
+type Release struct {
+	Version       string
+	InnerChecksum []byte
+	OuterChecksum []byte
+	Dependencies  []*Dependency
+}
+
+// Dependency represents a dependency of a release
+type Dependency struct {
+	Package     string
+	Requirement string
+	Optional    bool
+	App         string
+	Repository  string
+}
+
+// EncodeNames encodes the payload of the /names resource
+func EncodeNames(repository string, entries []*NamesEntry) []byte {
+	var b []byte
+	for _, e := range entries {
+		var pkg []byte
+		pkg = appendString(pkg, 1, e.Name)
+		if !e.UpdatedAt.IsZero() {
+			var ts []byte
+			ts = protowire.AppendTag(ts, 1, protowire.VarintType)
+			ts = protowire.AppendVarint(ts, uint64(e.UpdatedAt.Unix()))
+			ts = protowire.AppendTag(ts, 2, protowire.VarintType)
+			ts = protowire.AppendVarint(ts, uint64(e.UpdatedAt.Nanosecond()))
+			pkg = appendBytes(pkg, 2, ts)
+		}
+		b = appendBytes(b, 1, pkg)
+	}
+	return appendString(b, 2, repository)
+}
+
+// EncodeVersions encodes the payload of the /versions resource
+func EncodeVersions(repository string, entries []*VersionsEntry) []byte {
+	var b []byte
+	for _, e := range entries {
+		var pkg []byte
+		pkg = appendString(pkg, 1, e.Name)
+		for _, v := range e.Versions {
+			pkg = appendString(pkg, 2, v)
+		}
+		b = appendBytes(b, 1, pkg)
+	}
+	return appendString(b, 2, repository)
+}
+
+// EncodePackage encodes the payload of the /packages/<name> resource
+func EncodePackage(repository, name string, releases []*Release) []byte {
+	var b []byte
+	for _, r := range releases {
+		var rel []byte
+		rel = appendString(rel, 1, r.Version)
+		rel = appendBytes(rel, 2, r.InnerChecksum)
+		for _, d := range r.Dependencies {
+			var dep []byte
+			dep = appendString(dep, 1, d.Package)
+			dep = appendString(dep, 2, d.Requirement)
+			if d.Optional {
+				dep = protowire.AppendTag(dep, 3, protowire.VarintType)
+				dep = protowire.AppendVarint(dep, 1)
+			}
+			if d.App != "" {
+				dep = appendString(dep, 4, d.App)
+			}
+			if d.Repository != "" {
+				dep = appendString(dep, 5, d.Repository)
+			}
+			rel = appendBytes(rel, 3, dep)
+		}
+		if len(r.OuterChecksum) > 0 {
+			rel = appendBytes(rel, 5, r.OuterChecksum)
+		}
+		b = appendBytes(b, 1, rel)
+	}
+	b = appendString(b, 2, name)
+	return appendString(b, 3, repository)
+}
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendBytes(b []byte, num protowire.Number, v []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+// SignResource signs the payload with the RSA private key and returns the gzipped signed message
+func SignResource(payload []byte, privateKey string) ([]byte, error) {
+	block, _ := pem.Decode([]byte(privateKey))
+	if block == nil {
+		return nil, errors.New("failed to decode private key")
+	}
+	priv, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	h := sha512.Sum512(payload)
+	signature, err := rsa.SignPKCS1v15(rand.Reader, priv, crypto.SHA512, h[:])
+	if err != nil {
+		return nil, err
+	}
+
+	var signed []byte
+	signed = appendBytes(signed, 1, payload)
+	signed = appendBytes(signed, 2, signature)
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(signed); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
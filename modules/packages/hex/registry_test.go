@@ -0,0 +1,137 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package hex
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/kumose/kmup/modules/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// decodeFields decodes a protobuf message into its length-delimited and varint fields
+func decodeFields(t *testing.T, b []byte) map[protowire.Number][]any {
+	fields := make(map[protowire.Number][]any)
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		require.GreaterOrEqual(t, n, 0)
+		b = b[n:]
+		switch typ {
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			require.GreaterOrEqual(t, n, 0)
+			fields[num] = append(fields[num], v)
+			b = b[n:]
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			require.GreaterOrEqual(t, n, 0)
+			fields[num] = append(fields[num], v)
+			b = b[n:]
+		default:
+			t.Fatalf("unexpected wire type %v", typ)
+		}
+	}
+	return fields
+}
+
+func TestEncodePackage(t *testing.T) {
+	payload := EncodePackage("kmup", "kmup_client", []*Release{
+		{
+			Version:       "1.0.0",
+			InnerChecksum: []byte{1, 2},
+			OuterChecksum: []byte{3, 4},
+			Dependencies: []*Dependency{
+				{Package: "finch", Requirement: "~> 0.16", Optional: true, App: "finch_app", Repository: "hexpm"},
+			},
+		},
+	})
+
+	fields := decodeFields(t, payload)
+	assert.Equal(t, []byte("kmup_client"), fields[2][0])
+	assert.Equal(t, []byte("kmup"), fields[3][0])
+	require.Len(t, fields[1], 1)
+
+	release := decodeFields(t, fields[1][0].([]byte))
+	assert.Equal(t, []byte("1.0.0"), release[1][0])
+	assert.Equal(t, []byte{1, 2}, release[2][0])
+	assert.Equal(t, []byte{3, 4}, release[5][0])
+
+	dependency := decodeFields(t, release[3][0].([]byte))
+	assert.Equal(t, []byte("finch"), dependency[1][0])
+	assert.Equal(t, []byte("~> 0.16"), dependency[2][0])
+	assert.EqualValues(t, 1, dependency[3][0])
+	assert.Equal(t, []byte("finch_app"), dependency[4][0])
+	assert.Equal(t, []byte("hexpm"), dependency[5][0])
+}
+
+func TestEncodeNamesAndVersions(t *testing.T) {
+	updated := time.Date(2024, 5, 6, 7, 8, 9, 10, time.UTC)
+
+	fields := decodeFields(t, EncodeNames("kmup", []*NamesEntry{{Name: "kmup_client", UpdatedAt: updated}}))
+	assert.Equal(t, []byte("kmup"), fields[2][0])
+	pkg := decodeFields(t, fields[1][0].([]byte))
+	assert.Equal(t, []byte("kmup_client"), pkg[1][0])
+	ts := decodeFields(t, pkg[2][0].([]byte))
+	assert.EqualValues(t, updated.Unix(), ts[1][0])
+	assert.EqualValues(t, 10, ts[2][0])
+
+	fields = decodeFields(t, EncodeVersions("kmup", []*VersionsEntry{{Name: "kmup_client", Versions: []string{"1.0.0", "1.1.0"}}}))
+	assert.Equal(t, []byte("kmup"), fields[2][0])
+	pkg = decodeFields(t, fields[1][0].([]byte))
+	assert.Equal(t, []any{[]byte("1.0.0"), []byte("1.1.0")}, pkg[2])
+}
+
+func TestSignResource(t *testing.T) {
+	priv, pub, err := util.GenerateKeyPair(1024)
+	require.NoError(t, err)
+
+	payload := EncodeVersions("kmup", nil)
+
+	data, err := SignResource(payload, priv)
+	require.NoError(t, err)
+
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	signed, err := io.ReadAll(zr)
+	require.NoError(t, err)
+
+	fields := decodeFields(t, signed)
+	assert.Equal(t, payload, fields[1][0])
+
+	block, _ := pem.Decode([]byte(pub))
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	require.NoError(t, err)
+
+	h := sha512.Sum512(payload)
+	assert.NoError(t, rsa.VerifyPKCS1v15(key.(*rsa.PublicKey), crypto.SHA512, h[:], fields[2][0].([]byte)))
+
+	_, err = SignResource(payload, "invalid")
+	assert.Error(t, err)
+}
@@ -0,0 +1,460 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package hex
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/kumose/kmup/modules/util"
+)
+
+// Atom represents an Erlang atom which is not a boolean
+type Atom string
+
+// Tuple represents an Erlang tuple
+type Tuple []any
+
+// ErrInvalidTerm is returned if a file contains invalid or unsupported Erlang terms
+var ErrInvalidTerm = util.NewInvalidArgumentErrorf("invalid erlang term")
+
+// ParseTerms parses a file in the format read by file:consult/1.
+// Binaries and strings are returned as string, lists as []any, tuples as Tuple,
+// maps as map[string]any, true and false as bool and all other atoms as Atom.
+func ParseTerms(data string) ([]any, error) {
+	p := &termParser{data: data}
+
+	var terms []any
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			return terms, nil
+		}
+
+		term, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+
+		p.skipSpace()
+		if !p.consume('.') {
+			return nil, p.error("expected '.'")
+		}
+
+		terms = append(terms, term)
+	}
+}
+
+type termParser struct {
+	data string
+	pos  int
+}
+
+func (p *termParser) error(msg string) error {
+	return fmt.Errorf("%w: %s at offset %d", ErrInvalidTerm, msg, p.pos)
+}
+
+func (p *termParser) skipSpace() {
+	for p.pos < len(p.data) {
+		switch c := p.data[p.pos]; c {
+		case ' ', '\t', '\r', '\n':
+			p.pos++
+		case '%':
+			for p.pos < len(p.data) && p.data[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *termParser) peek() byte {
+	if p.pos >= len(p.data) {
+		return 0
+	}
+	return p.data[p.pos]
+}
+
+func (p *termParser) consume(c byte) bool {
+	if p.peek() == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *termParser) parseTerm() (any, error) {
+	p.skipSpace()
+
+	switch c := p.peek(); {
+	case c == '<':
+		return p.parseBinary()
+	case c == '"':
+		return p.parseString('"')
+	case c == '[':
+		p.pos++
+		return p.parseSequence(']')
+	case c == '{':
+		p.pos++
+		items, err := p.parseSequence('}')
+		if err != nil {
+			return nil, err
+		}
+		return Tuple(items), nil
+	case c == '#':
+		return p.parseMap()
+	case c == '\'':
+		s, err := p.parseString('\'')
+		if err != nil {
+			return nil, err
+		}
+		return Atom(s), nil
+	case c == '-' || (c >= '0' && c <= '9'):
+		return p.parseNumber()
+	case c >= 'a' && c <= 'z':
+		start := p.pos
+		for p.pos < len(p.data) && isAtomChar(p.data[p.pos]) {
+			p.pos++
+		}
+		switch atom := p.data[start:p.pos]; atom {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		default:
+			return Atom(atom), nil
+		}
+	}
+	return nil, p.error("unexpected character")
+}
+
+func isAtomChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '@'
+}
+
+// parseSequence parses the items of a list or tuple up to the closing character
+func (p *termParser) parseSequence(end byte) ([]any, error) {
+	items := make([]any, 0, 4)
+
+	p.skipSpace()
+	if p.consume(end) {
+		return items, nil
+	}
+
+	for {
+		item, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+
+		p.skipSpace()
+		if p.consume(end) {
+			return items, nil
+		}
+		if !p.consume(',') {
+			return nil, p.error("expected ','")
+		}
+	}
+}
+
+// parseMap parses a map with binary or atom keys
+func (p *termParser) parseMap() (any, error) {
+	p.pos++
+	if !p.consume('{') {
+		return nil, p.error("expected '{'")
+	}
+
+	m := make(map[string]any)
+
+	p.skipSpace()
+	if p.consume('}') {
+		return m, nil
+	}
+
+	for {
+		key, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+
+		p.skipSpace()
+		if !strings.HasPrefix(p.data[p.pos:], "=>") {
+			return nil, p.error("expected '=>'")
+		}
+		p.pos += 2
+
+		value, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+
+		switch k := key.(type) {
+		case string:
+			m[k] = value
+		case Atom:
+			m[string(k)] = value
+		default:
+			return nil, p.error("unsupported map key")
+		}
+
+		p.skipSpace()
+		if p.consume('}') {
+			return m, nil
+		}
+		if !p.consume(',') {
+			return nil, p.error("expected ','")
+		}
+	}
+}
+
+// parseBinary parses <<"...">>, <<"..."/utf8>> and <<1,2,3>> binaries
+func (p *termParser) parseBinary() (any, error) {
+	if !strings.HasPrefix(p.data[p.pos:], "<<") {
+		return nil, p.error("expected '<<'")
+	}
+	p.pos += 2
+
+	var sb strings.Builder
+
+	p.skipSpace()
+	if strings.HasPrefix(p.data[p.pos:], ">>") {
+		p.pos += 2
+		return "", nil
+	}
+
+	for {
+		p.skipSpace()
+		if p.peek() == '"' {
+			s, err := p.parseString('"')
+			if err != nil {
+				return nil, err
+			}
+			if strings.HasPrefix(p.data[p.pos:], "/utf8") {
+				p.pos += 5
+			}
+			sb.WriteString(s)
+		} else {
+			n, err := p.parseNumber()
+			if err != nil {
+				return nil, err
+			}
+			i, ok := n.(int64)
+			if !ok || i < 0 || i > 255 {
+				return nil, p.error("invalid byte in binary")
+			}
+			sb.WriteByte(byte(i))
+		}
+
+		p.skipSpace()
+		if strings.HasPrefix(p.data[p.pos:], ">>") {
+			p.pos += 2
+			return sb.String(), nil
+		}
+		if !p.consume(',') {
+			return nil, p.error("expected ','")
+		}
+	}
+}
+
+func (p *termParser) parseString(quote byte) (string, error) {
+	p.pos++
+
+	var sb strings.Builder
+	for {
+		if p.pos >= len(p.data) {
+			return "", p.error("unterminated string")
+		}
+
+		c := p.data[p.pos]
+		if c == quote {
+			p.pos++
+			return sb.String(), nil
+		}
+		if c != '\\' {
+			sb.WriteByte(c)
+			p.pos++
+			continue
+		}
+
+		p.pos++
+		if p.pos >= len(p.data) {
+			return "", p.error("unterminated string")
+		}
+
+		c = p.data[p.pos]
+		p.pos++
+		switch c {
+		case 'n':
+			sb.WriteByte('\n')
+		case 't':
+			sb.WriteByte('\t')
+		case 'r':
+			sb.WriteByte('\r')
+		case 'b':
+			sb.WriteByte('\b')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'v':
+			sb.WriteByte('\v')
+		case 'e':
+			sb.WriteByte(0x1b)
+		case 's':
+			sb.WriteByte(' ')
+		case 'd':
+			sb.WriteByte(0x7f)
+		case 'x':
+			var digits string
+			if p.consume('{') {
+				end := strings.IndexByte(p.data[p.pos:], '}')
+				if end < 0 {
+					return "", p.error("invalid escape sequence")
+				}
+				digits = p.data[p.pos : p.pos+end]
+				p.pos += end + 1
+			} else {
+				if p.pos+2 > len(p.data) {
+					return "", p.error("invalid escape sequence")
+				}
+				digits = p.data[p.pos : p.pos+2]
+				p.pos += 2
+			}
+			r, err := strconv.ParseUint(digits, 16, 32)
+			if err != nil {
+				return "", p.error("invalid escape sequence")
+			}
+			writeCodepoint(&sb, r)
+		default:
+			if c >= '0' && c <= '7' {
+				start := p.pos - 1
+				for p.pos < len(p.data) && p.pos-start < 3 && p.data[p.pos] >= '0' && p.data[p.pos] <= '7' {
+					p.pos++
+				}
+				r, _ := strconv.ParseUint(p.data[start:p.pos], 8, 32)
+				writeCodepoint(&sb, r)
+			} else {
+				sb.WriteByte(c)
+			}
+		}
+	}
+}
+
+// writeCodepoint writes bytes as they are and larger values as UTF-8
+func writeCodepoint(sb *strings.Builder, r uint64) {
+	if r < 256 {
+		sb.WriteByte(byte(r))
+	} else {
+		sb.WriteString(string(rune(r)))
+	}
+}
+
+func (p *termParser) parseNumber() (any, error) {
+	start := p.pos
+	p.consume('-')
+	isFloat := false
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		if c == '.' && p.pos+1 < len(p.data) && p.data[p.pos+1] >= '0' && p.data[p.pos+1] <= '9' {
+			isFloat = true
+		} else if (c < '0' || c > '9') && !(isFloat && (c == 'e' || c == 'E' || c == '-' || c == '+')) {
+			break
+		}
+		p.pos++
+	}
+
+	s := p.data[start:p.pos]
+	if isFloat {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, p.error("invalid number")
+		}
+		return f, nil
+	}
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return nil, p.error("invalid number")
+	}
+	return i, nil
+}
+
+// toStringMap converts a map or a proplist with binary or atom keys into a map
+func toStringMap(v any) (map[string]any, bool) {
+	switch t := v.(type) {
+	case map[string]any:
+		return t, true
+	case []any:
+		m := make(map[string]any, len(t))
+		for _, item := range t {
+			tuple, ok := item.(Tuple)
+			if !ok || len(tuple) != 2 {
+				return nil, false
+			}
+			switch k := tuple[0].(type) {
+			case string:
+				m[k] = tuple[1]
+			case Atom:
+				m[string(k)] = tuple[1]
+			default:
+				return nil, false
+			}
+		}
+		return m, true
+	}
+	return nil, false
+}
+
+// toString returns binaries, charlists and atoms as string
+func toString(v any) (string, bool) {
+	switch t := v.(type) {
+	case string:
+		return t, true
+	case Atom:
+		return string(t), true
+	case []any:
+		var sb strings.Builder
+		for _, item := range t {
+			i, ok := item.(int64)
+			if !ok || i < 0 || i > utf8.MaxRune {
+				return "", false
+			}
+			sb.WriteRune(rune(i))
+		}
+		return sb.String(), true
+	}
+	return "", false
+}
+
+// toStringList returns a list of binaries as []string
+func toStringList(v any) ([]string, bool) {
+	items, ok := v.([]any)
+	if !ok {
+		return nil, false
+	}
+	list := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := toString(item)
+		if !ok {
+			return nil, false
+		}
+		list = append(list, s)
+	}
+	return list, true
+}
@@ -22,6 +22,7 @@ import (
 	"crypto/sha1"
 	"crypto/sha512"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"regexp"
@@ -200,57 +201,15 @@ func ParsePackage(r io.Reader) (*Package, error) {
 	}
 
 	for _, meta := range upload.Versions {
-		if !validateName(meta.Name) {
-			return nil, ErrInvalidPackageName
-		}
-
-		v, err := version.NewSemver(meta.Version)
+		p, err := newPackage(meta)
 		if err != nil {
-			return nil, ErrInvalidPackageVersion
-		}
-
-		scope := ""
-		name := meta.Name
-		nameParts := strings.SplitN(meta.Name, "/", 2)
-		if len(nameParts) == 2 {
-			scope = nameParts[0]
-			name = nameParts[1]
-		}
-
-		if !validation.IsValidURL(meta.Homepage) {
-			meta.Homepage = ""
-		}
-
-		p := &Package{
-			Name:     meta.Name,
-			Version:  v.String(),
-			DistTags: make([]string, 0, 1),
-			Metadata: Metadata{
-				Scope:                   scope,
-				Name:                    name,
-				Description:             meta.Description,
-				Author:                  meta.Author.Name,
-				License:                 meta.License,
-				ProjectURL:              meta.Homepage,
-				Keywords:                meta.Keywords,
-				Dependencies:            meta.Dependencies,
-				BundleDependencies:      meta.BundleDependencies,
-				DevelopmentDependencies: meta.DevDependencies,
-				PeerDependencies:        meta.PeerDependencies,
-				PeerDependenciesMeta:    meta.PeerDependenciesMeta,
-				OptionalDependencies:    meta.OptionalDependencies,
-				Bin:                     meta.Bin,
-				Readme:                  meta.Readme,
-				Repository:              meta.Repository,
-			},
+			return nil, err
 		}
 
 		for tag := range upload.DistTags {
 			p.DistTags = append(p.DistTags, tag)
 		}
 
-		p.Filename = strings.ToLower(fmt.Sprintf("%s-%s.tgz", name, p.Version))
-
 		attachment := func() *PackageAttachment {
 			for _, a := range upload.Attachments {
 				return a
@@ -267,31 +226,109 @@ func ParsePackage(r io.Reader) (*Package, error) {
 		}
 		p.Data = data
 
-		integrity := strings.SplitN(meta.Dist.Integrity, "-", 2)
-		if len(integrity) != 2 {
-			return nil, ErrInvalidIntegrity
+		hashSHA1 := sha1.Sum(data)
+		hashSHA512 := sha512.Sum512(data)
+		if err := verifyIntegrity(meta.Dist.Integrity, hashSHA1[:], hashSHA512[:]); err != nil {
+			return nil, err
 		}
-		integrityHash, err := base64.StdEncoding.DecodeString(integrity[1])
-		if err != nil {
-			return nil, ErrInvalidIntegrity
+
+		return p, nil
+	}
+
+	return nil, ErrInvalidPackage
+}
+
+// NewPackageFromMetadataVersion creates a package without data from a version of the package metadata.
+// It is used for packages which are fetched from an upstream registry.
+func NewPackageFromMetadataVersion(meta *PackageMetadataVersion) (*Package, error) {
+	return newPackage(meta)
+}
+
+func newPackage(meta *PackageMetadataVersion) (*Package, error) {
+	if !validateName(meta.Name) {
+		return nil, ErrInvalidPackageName
+	}
+
+	v, err := version.NewSemver(meta.Version)
+	if err != nil {
+		return nil, ErrInvalidPackageVersion
+	}
+
+	scope := ""
+	name := meta.Name
+	nameParts := strings.SplitN(meta.Name, "/", 2)
+	if len(nameParts) == 2 {
+		scope = nameParts[0]
+		name = nameParts[1]
+	}
+
+	if !validation.IsValidURL(meta.Homepage) {
+		meta.Homepage = ""
+	}
+
+	return &Package{
+		Name:     meta.Name,
+		Version:  v.String(),
+		DistTags: make([]string, 0, 1),
+		Metadata: Metadata{
+			Scope:                   scope,
+			Name:                    name,
+			Description:             meta.Description,
+			Author:                  meta.Author.Name,
+			License:                 meta.License,
+			ProjectURL:              meta.Homepage,
+			Keywords:                meta.Keywords,
+			Dependencies:            meta.Dependencies,
+			BundleDependencies:      meta.BundleDependencies,
+			DevelopmentDependencies: meta.DevDependencies,
+			PeerDependencies:        meta.PeerDependencies,
+			PeerDependenciesMeta:    meta.PeerDependenciesMeta,
+			OptionalDependencies:    meta.OptionalDependencies,
+			Bin:                     meta.Bin,
+			Readme:                  meta.Readme,
+			Repository:              meta.Repository,
+		},
+		Filename: strings.ToLower(fmt.Sprintf("%s-%s.tgz", name, v.String())),
+	}, nil
+}
+
+// Verify checks the hashes of the package data against the distribution information
+func (d *PackageDistribution) Verify(hashSHA1, hashSHA512 []byte) error {
+	if d.Integrity != "" {
+		return verifyIntegrity(d.Integrity, hashSHA1, hashSHA512)
+	}
+	if d.Shasum != "" && strings.EqualFold(d.Shasum, hex.EncodeToString(hashSHA1)) {
+		return nil
+	}
+	return ErrInvalidIntegrity
+}
+
+// verifyIntegrity checks a subresource integrity string. It may list multiple hashes separated by whitespace.
+func verifyIntegrity(integrity string, hashSHA1, hashSHA512 []byte) error {
+	for _, entry := range strings.Fields(integrity) {
+		algorithm, encoded, ok := strings.Cut(entry, "-")
+		if !ok {
+			continue
 		}
 		var hash []byte
-		switch integrity[0] {
+		switch algorithm {
 		case "sha1":
-			tmp := sha1.Sum(data)
-			hash = tmp[:]
+			hash = hashSHA1
 		case "sha512":
-			tmp := sha512.Sum512(data)
-			hash = tmp[:]
+			hash = hashSHA512
+		default:
+			continue
 		}
-		if !bytes.Equal(integrityHash, hash) {
-			return nil, ErrInvalidIntegrity
+		integrityHash, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return ErrInvalidIntegrity
 		}
-
-		return p, nil
+		if bytes.Equal(integrityHash, hash) {
+			return nil
+		}
+		return ErrInvalidIntegrity
 	}
-
-	return nil, ErrInvalidPackage
+	return ErrInvalidIntegrity
 }
 
 func validateName(name string) bool {
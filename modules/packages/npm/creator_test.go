@@ -19,7 +19,10 @@ package npm
 
 import (
 	"bytes"
+	"crypto/sha1"
+	"crypto/sha512"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"strings"
 	"testing"
@@ -313,3 +316,54 @@ func TestParsePackage(t *testing.T) {
 		assert.Equal(t, repository.URL, p.Metadata.Repository.URL)
 	})
 }
+
+func TestPackageDistributionVerify(t *testing.T) {
+	data := []byte("npm package data")
+	hashSHA1 := sha1.Sum(data)
+	hashSHA512 := sha512.Sum512(data)
+	integrity := "sha512-" + base64.StdEncoding.EncodeToString(hashSHA512[:])
+
+	cases := []struct {
+		Distribution PackageDistribution
+		IsValid      bool
+	}{
+		{PackageDistribution{Integrity: integrity}, true},
+		{PackageDistribution{Integrity: "sha1-" + base64.StdEncoding.EncodeToString(hashSHA1[:])}, true},
+		{PackageDistribution{Integrity: "sha256-abc " + integrity}, true},
+		{PackageDistribution{Shasum: hex.EncodeToString(hashSHA1[:])}, true},
+		{PackageDistribution{Integrity: "sha512-" + base64.StdEncoding.EncodeToString(hashSHA1[:])}, false},
+		{PackageDistribution{Integrity: "sha256-abc"}, false},
+		{PackageDistribution{Shasum: "abc"}, false},
+		{PackageDistribution{}, false},
+	}
+
+	for _, c := range cases {
+		err := c.Distribution.Verify(hashSHA1[:], hashSHA512[:])
+		if c.IsValid {
+			assert.NoError(t, err, "%+v", c.Distribution)
+		} else {
+			assert.ErrorIs(t, err, ErrInvalidIntegrity, "%+v", c.Distribution)
+		}
+	}
+}
+
+func TestNewPackageFromMetadataVersion(t *testing.T) {
+	p, err := NewPackageFromMetadataVersion(&PackageMetadataVersion{
+		Name:     "@scope/Test",
+		Version:  "1.0.0",
+		Homepage: "not a url",
+	})
+	assert.Nil(t, p)
+	assert.ErrorIs(t, err, ErrInvalidPackageName)
+
+	p, err = NewPackageFromMetadataVersion(&PackageMetadataVersion{
+		Name:    "@scope/test",
+		Version: "1.0.0-Beta",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "@scope/test", p.Name)
+	assert.Equal(t, "1.0.0-Beta", p.Version)
+	assert.Equal(t, "test-1.0.0-beta.tgz", p.Filename)
+	assert.Equal(t, "@scope", p.Metadata.Scope)
+	assert.Equal(t, "test", p.Metadata.Name)
+}
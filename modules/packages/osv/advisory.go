@@ -0,0 +1,280 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package osv
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kumose/kmup/modules/json"
+	"github.com/kumose/kmup/modules/util"
+)
+
+// Ecosystems as named by the OSV schema
+// https://ossf.github.io/osv-schema/#defined-ecosystems
+const (
+	EcosystemCargo    = "crates.io"
+	EcosystemGo       = "Go"
+	EcosystemMaven    = "Maven"
+	EcosystemNpm      = "npm"
+	EcosystemNuGet    = "NuGet"
+	EcosystemPyPI     = "PyPI"
+	EcosystemRubyGems = "RubyGems"
+)
+
+const (
+	RangeTypeSemVer    = "SEMVER"
+	RangeTypeEcosystem = "ECOSYSTEM"
+	RangeTypeGit       = "GIT"
+)
+
+// Severities derived from the advisory
+const (
+	SeverityCritical = "critical"
+	SeverityHigh     = "high"
+	SeverityModerate = "moderate"
+	SeverityLow      = "low"
+)
+
+// MaxAdvisorySize is the maximum size of an advisory file
+const MaxAdvisorySize = 4 << 20
+
+var ErrInvalidAdvisory = util.NewInvalidArgumentErrorf("advisory is invalid")
+
+// Advisory is an advisory in the OSV format
+// https://ossf.github.io/osv-schema/
+type Advisory struct {
+	ID               string         `json:"id"`
+	Modified         time.Time      `json:"modified"`
+	Published        time.Time      `json:"published,omitzero"`
+	Withdrawn        time.Time      `json:"withdrawn,omitzero"`
+	Aliases          []string       `json:"aliases,omitempty"`
+	Summary          string         `json:"summary,omitempty"`
+	Details          string         `json:"details,omitempty"`
+	Severity         []*Severity    `json:"severity,omitempty"`
+	Affected         []*Affected    `json:"affected,omitempty"`
+	References       []*Reference   `json:"references,omitempty"`
+	DatabaseSpecific map[string]any `json:"database_specific,omitempty"`
+}
+
+type Severity struct {
+	Type  string `json:"type"`
+	Score string `json:"score"`
+}
+
+type Affected struct {
+	Package  *Package `json:"package"`
+	Ranges   []*Range `json:"ranges,omitempty"`
+	Versions []string `json:"versions,omitempty"`
+}
+
+type Package struct {
+	Ecosystem string `json:"ecosystem"`
+	Name      string `json:"name"`
+	Purl      string `json:"purl,omitempty"`
+}
+
+type Range struct {
+	Type   string   `json:"type"`
+	Events []*Event `json:"events"`
+}
+
+type Event struct {
+	Introduced   string `json:"introduced,omitempty"`
+	Fixed        string `json:"fixed,omitempty"`
+	LastAffected string `json:"last_affected,omitempty"`
+	Limit        string `json:"limit,omitempty"`
+}
+
+type Reference struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+// ParseAdvisory parses an advisory in the OSV format
+func ParseAdvisory(r io.Reader) (*Advisory, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxAdvisorySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxAdvisorySize {
+		return nil, ErrInvalidAdvisory
+	}
+
+	var a Advisory
+	if err := json.Unmarshal(data, &a); err != nil || a.ID == "" {
+		return nil, ErrInvalidAdvisory
+	}
+	for _, affected := range a.Affected {
+		if affected.Package == nil {
+			return nil, ErrInvalidAdvisory
+		}
+	}
+	return &a, nil
+}
+
+// IsWithdrawn returns true if the advisory was withdrawn and must not be reported anymore
+func (a *Advisory) IsWithdrawn() bool {
+	return !a.Withdrawn.IsZero()
+}
+
+// SeverityLevel returns the severity of the advisory as reported by the database which published it
+func (a *Advisory) SeverityLevel() string {
+	if s, ok := a.DatabaseSpecific["severity"].(string); ok {
+		switch s := strings.ToLower(s); s {
+		case SeverityCritical, SeverityHigh, SeverityModerate, SeverityLow:
+			return s
+		case "medium":
+			return SeverityModerate
+		}
+	}
+	return ""
+}
+
+// URL returns the link to the advisory page
+func (a *Advisory) URL() string {
+	for _, t := range []string{"ADVISORY", "WEB"} {
+		for _, r := range a.References {
+			if r.Type == t {
+				return r.URL
+			}
+		}
+	}
+	return ""
+}
+
+// Match returns the affected entry of the advisory which contains the version of the package
+func (a *Advisory) Match(ecosystem, name, version string) *Affected {
+	for _, affected := range a.Affected {
+		if BaseEcosystem(affected.Package.Ecosystem) != ecosystem || !strings.EqualFold(NormalizeName(ecosystem, affected.Package.Name), NormalizeName(ecosystem, name)) {
+			continue
+		}
+		if affected.IsAffected(ecosystem, version) {
+			return affected
+		}
+	}
+	return nil
+}
+
+// IsAffected checks if the version is listed or contained in one of the ranges
+func (a *Affected) IsAffected(ecosystem, version string) bool {
+	if version == "" {
+		return false
+	}
+
+	for _, v := range a.Versions {
+		if v == version || CompareVersions(ecosystem, v, version) == 0 {
+			return true
+		}
+	}
+
+	for _, r := range a.Ranges {
+		if r.Type == RangeTypeGit {
+			continue
+		}
+		if r.contains(ecosystem, version) {
+			return true
+		}
+	}
+	return false
+}
+
+// FixedVersions returns the versions which fix the advisory
+func (a *Affected) FixedVersions() []string {
+	var fixed []string
+	for _, r := range a.Ranges {
+		if r.Type == RangeTypeGit {
+			continue
+		}
+		for _, e := range r.Events {
+			if e.Fixed != "" {
+				fixed = append(fixed, e.Fixed)
+			}
+		}
+	}
+	return fixed
+}
+
+// contains evaluates the events of the range as described in
+// https://ossf.github.io/osv-schema/#evaluation
+func (r *Range) contains(ecosystem, version string) bool {
+	compare := func(a, b string) int {
+		if r.Type == RangeTypeSemVer {
+			return CompareSemVer(a, b)
+		}
+		return CompareVersions(ecosystem, a, b)
+	}
+
+	eventVersion := func(e *Event) string {
+		return e.Introduced + e.Fixed + e.LastAffected + e.Limit
+	}
+
+	events := make([]*Event, len(r.Events))
+	copy(events, r.Events)
+	sort.SliceStable(events, func(i, j int) bool {
+		vi, vj := eventVersion(events[i]), eventVersion(events[j])
+		if vi == "0" || vj == "0" {
+			return vi == "0" && vj != "0"
+		}
+		return compare(vi, vj) < 0
+	})
+
+	affected := false
+	for _, e := range events {
+		switch {
+		case e.Limit != "":
+			if e.Limit != "*" && compare(version, e.Limit) >= 0 {
+				return false
+			}
+		case e.Introduced != "":
+			if e.Introduced == "0" || compare(version, e.Introduced) >= 0 {
+				affected = true
+			}
+		case e.Fixed != "":
+			if compare(version, e.Fixed) >= 0 {
+				affected = false
+			}
+		case e.LastAffected != "":
+			if compare(version, e.LastAffected) > 0 {
+				affected = false
+			}
+		}
+	}
+	return affected
+}
+
+// BaseEcosystem strips the release suffix of an ecosystem like "Debian:11"
+func BaseEcosystem(ecosystem string) string {
+	base, _, _ := strings.Cut(ecosystem, ":")
+	return base
+}
+
+var pypiNameNormalizer = strings.NewReplacer(".", "-", "_", "-")
+
+// NormalizeName normalizes a package name for comparison
+func NormalizeName(ecosystem, name string) string {
+	switch ecosystem {
+	case EcosystemPyPI:
+		return strings.ToLower(pypiNameNormalizer.Replace(name))
+	case EcosystemGo, EcosystemMaven:
+		return name
+	}
+	return strings.ToLower(name)
+}
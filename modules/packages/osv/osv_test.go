@@ -0,0 +1,133 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package osv
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const advisoryContent = `{
+  "schema_version": "1.6.0",
+  "id": "GHSA-xxxx-yyyy-zzzz",
+  "modified": "2024-01-02T03:04:05Z",
+  "published": "2024-01-01T00:00:00Z",
+  "aliases": ["CVE-2024-0001"],
+  "summary": "Prototype pollution in test-package",
+  "affected": [
+    {
+      "package": {"ecosystem": "npm", "name": "test-package"},
+      "ranges": [
+        {"type": "SEMVER", "events": [{"introduced": "0"}, {"fixed": "1.2.3"}, {"introduced": "2.0.0"}, {"last_affected": "2.1.0"}]}
+      ]
+    },
+    {
+      "package": {"ecosystem": "PyPI", "name": "Test_Package"},
+      "ranges": [
+        {"type": "ECOSYSTEM", "events": [{"introduced": "1.0"}, {"fixed": "1.5.post1"}]}
+      ],
+      "versions": ["0.9"]
+    }
+  ],
+  "references": [
+    {"type": "WEB", "url": "https://example.com/web"},
+    {"type": "ADVISORY", "url": "https://example.com/advisory"}
+  ],
+  "database_specific": {"severity": "HIGH"}
+}`
+
+func TestParseAdvisory(t *testing.T) {
+	a, err := ParseAdvisory(strings.NewReader(advisoryContent))
+	require.NoError(t, err)
+	assert.Equal(t, "GHSA-xxxx-yyyy-zzzz", a.ID)
+	assert.Equal(t, []string{"CVE-2024-0001"}, a.Aliases)
+	assert.Equal(t, 2024, a.Modified.Year())
+	assert.False(t, a.IsWithdrawn())
+	assert.Equal(t, SeverityHigh, a.SeverityLevel())
+	assert.Equal(t, "https://example.com/advisory", a.URL())
+	assert.Len(t, a.Affected, 2)
+	assert.Equal(t, []string{"1.2.3"}, a.Affected[0].FixedVersions())
+
+	for _, content := range []string{``, `{}`, `{"id":"A","affected":[{}]}`} {
+		_, err := ParseAdvisory(strings.NewReader(content))
+		assert.ErrorIs(t, err, ErrInvalidAdvisory)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	a, err := ParseAdvisory(strings.NewReader(advisoryContent))
+	require.NoError(t, err)
+
+	cases := []struct {
+		Ecosystem string
+		Name      string
+		Version   string
+		Affected  bool
+	}{
+		{EcosystemNpm, "test-package", "0.1.0", true},
+		{EcosystemNpm, "test-package", "1.2.2", true},
+		{EcosystemNpm, "test-package", "1.2.3", false},
+		{EcosystemNpm, "test-package", "1.9.0", false},
+		{EcosystemNpm, "test-package", "2.0.0-rc.1", false},
+		{EcosystemNpm, "test-package", "2.0.0", true},
+		{EcosystemNpm, "test-package", "2.1.0", true},
+		{EcosystemNpm, "test-package", "2.1.1", false},
+		{EcosystemNpm, "other-package", "1.0.0", false},
+		{EcosystemPyPI, "test.package", "0.9", true},
+		{EcosystemPyPI, "test-package", "0.9.1", false},
+		{EcosystemPyPI, "test-package", "1.0rc1", false},
+		{EcosystemPyPI, "test-package", "1.0", true},
+		{EcosystemPyPI, "test-package", "1.5", true},
+		{EcosystemPyPI, "test-package", "1.5.post1", false},
+		{EcosystemPyPI, "test-package", "1.5.1", false},
+		{EcosystemMaven, "test-package", "1.0", false},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.Affected, a.Match(c.Ecosystem, c.Name, c.Version) != nil, "%s %s %s", c.Ecosystem, c.Name, c.Version)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	cases := []struct {
+		Ecosystem string
+		A, B      string
+		Expected  int
+	}{
+		{"", "1.0", "1.0.0", 0},
+		{"", "1.0", "1.0.1", -1},
+		{"", "1.10", "1.9", 1},
+		{EcosystemMaven, "1.0-SNAPSHOT", "1.0", -1},
+		{EcosystemMaven, "1.0-alpha-1", "1.0-beta-1", -1},
+		{EcosystemMaven, "1.0-RC1", "1.0", -1},
+		{EcosystemMaven, "1.0.Final", "1.0", 0},
+		{EcosystemMaven, "1.0-sp1", "1.0", 1},
+		{EcosystemPyPI, "1.0.dev1", "1.0a1", -1},
+		{EcosystemPyPI, "1.0.post1", "1.0.1", -1},
+		{EcosystemRubyGems, "1.0.0.pre", "1.0.0", -1},
+		{EcosystemGo, "v1.2.3", "1.2.3", 0},
+		{EcosystemGo, "v0.0.0-20240101000000-abcdef123456", "0.1.0", -1},
+		{EcosystemNpm, "1.0.0-beta.2", "1.0.0-beta.10", -1},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.Expected, CompareVersions(c.Ecosystem, c.A, c.B), "%s %s %s", c.Ecosystem, c.A, c.B)
+		assert.Equal(t, -c.Expected, CompareVersions(c.Ecosystem, c.B, c.A), "%s %s %s", c.Ecosystem, c.B, c.A)
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package osv
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/hashicorp/go-version"
+)
+
+// CompareSemVer compares two semantic versions.
+// Versions which can not be parsed are compared with the generic version ordering.
+func CompareSemVer(a, b string) int {
+	va, errA := version.NewSemver(strings.TrimPrefix(a, "v"))
+	vb, errB := version.NewSemver(strings.TrimPrefix(b, "v"))
+	if errA != nil || errB != nil {
+		return CompareVersions("", a, b)
+	}
+	return va.Compare(vb)
+}
+
+// CompareVersions compares two versions of an ecosystem.
+// The ordering is an approximation of the ecosystem rules which works offline for all supported ecosystems:
+// the versions are split into numeric and textual parts and pre-release qualifiers sort before the release.
+func CompareVersions(ecosystem string, a, b string) int {
+	if ecosystem == EcosystemGo || ecosystem == EcosystemNpm || ecosystem == EcosystemCargo {
+		va, errA := version.NewSemver(strings.TrimPrefix(a, "v"))
+		vb, errB := version.NewSemver(strings.TrimPrefix(b, "v"))
+		if errA == nil && errB == nil {
+			return va.Compare(vb)
+		}
+	}
+
+	ta, tb := tokenize(a), tokenize(b)
+	for i := 0; i < max(len(ta), len(tb)); i++ {
+		var x, y token
+		if i < len(ta) {
+			x = ta[i]
+		}
+		if i < len(tb) {
+			y = tb[i]
+		}
+		if c := x.compare(y); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// token is a numeric or textual part of a version, the zero value represents a missing part
+type token struct {
+	text    string
+	number  int64
+	numeric bool
+}
+
+// rank orders the tokens: pre-release qualifiers < missing part or release qualifiers < post-release qualifiers < numbers
+func (t token) rank() int {
+	if t.numeric {
+		return 100
+	}
+	switch t.text {
+	case "dev", "snapshot":
+		return -5
+	case "a", "alpha":
+		return -4
+	case "b", "beta":
+		return -3
+	case "m", "milestone":
+		return -2
+	case "c", "rc", "cr", "pre", "preview":
+		return -1
+	case "", "final", "ga", "release":
+		return 0
+	case "sp", "post", "p":
+		return 2
+	}
+	return 1
+}
+
+func (t token) compare(o token) int {
+	if t.numeric && o.numeric {
+		switch {
+		case t.number < o.number:
+			return -1
+		case t.number > o.number:
+			return 1
+		}
+		return 0
+	}
+
+	// a missing part is equal to zero
+	if t.numeric && t.number == 0 && o.text == "" && !o.numeric {
+		return 0
+	}
+	if o.numeric && o.number == 0 && t.text == "" && !t.numeric {
+		return 0
+	}
+
+	rt, ro := t.rank(), o.rank()
+	if rt != ro {
+		if rt < ro {
+			return -1
+		}
+		return 1
+	}
+	if rt == 0 {
+		return 0
+	}
+	return strings.Compare(t.text, o.text)
+}
+
+func tokenize(v string) []token {
+	v = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(v), "v"))
+	// build metadata does not affect the ordering
+	v, _, _ = strings.Cut(v, "+")
+
+	var tokens []token
+	var current strings.Builder
+	numeric := false
+	flush := func() {
+		if current.Len() == 0 {
+			return
+		}
+		s := current.String()
+		current.Reset()
+		if numeric {
+			n, err := strconv.ParseInt(s, 10, 64)
+			if err == nil {
+				tokens = append(tokens, token{number: n, numeric: true})
+				return
+			}
+		}
+		tokens = append(tokens, token{text: s})
+	}
+
+	for _, r := range v {
+		isDigit := unicode.IsDigit(r)
+		switch {
+		case !isDigit && !unicode.IsLetter(r):
+			flush()
+		case current.Len() > 0 && isDigit != numeric:
+			flush()
+			numeric = isDigit
+			current.WriteRune(r)
+		default:
+			numeric = isDigit
+			current.WriteRune(r)
+		}
+	}
+	flush()
+
+	return tokens
+}
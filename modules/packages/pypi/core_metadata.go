@@ -0,0 +1,67 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package pypi
+
+import (
+	"archive/zip"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/kumose/kmup/modules/util"
+)
+
+const maxCoreMetadataSize = 10 * 1024 * 1024
+
+var (
+	ErrMissingCoreMetadata  = util.NewInvalidArgumentErrorf("METADATA file is missing")
+	ErrCoreMetadataTooLarge = util.NewInvalidArgumentErrorf("METADATA file is too large")
+)
+
+// IsWheel checks if the filename is a wheel
+func IsWheel(filename string) bool {
+	return strings.HasSuffix(strings.ToLower(filename), ".whl")
+}
+
+// ExtractCoreMetadata extracts the METADATA file from the .dist-info directory of a wheel
+// https://packaging.python.org/en/latest/specifications/binary-distribution-format/#the-dist-info-directory
+func ExtractCoreMetadata(r io.ReaderAt, size int64) ([]byte, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range archive.File {
+		dir, name := path.Split(file.Name)
+		if name != "METADATA" || strings.Count(dir, "/") != 1 || !strings.HasSuffix(dir, ".dist-info/") {
+			continue
+		}
+		if file.UncompressedSize64 > maxCoreMetadataSize {
+			return nil, ErrCoreMetadataTooLarge
+		}
+
+		f, err := archive.Open(file.Name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		return io.ReadAll(io.LimitReader(f, maxCoreMetadataSize))
+	}
+	return nil, ErrMissingCoreMetadata
+}
@@ -0,0 +1,78 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package pypi
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createArchive(files map[string]string) *bytes.Reader {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, _ := zw.Create(name)
+		w.Write([]byte(content))
+	}
+	zw.Close()
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestIsWheel(t *testing.T) {
+	assert.True(t, IsWheel("pkg-1.0-py3-none-any.whl"))
+	assert.True(t, IsWheel("pkg-1.0-py3-none-any.WHL"))
+	assert.False(t, IsWheel("pkg-1.0.tar.gz"))
+}
+
+func TestExtractCoreMetadata(t *testing.T) {
+	metadata := "Metadata-Version: 2.1\nName: pkg\nVersion: 1.0\n"
+
+	t.Run("Valid", func(t *testing.T) {
+		data := createArchive(map[string]string{
+			"pkg/__init__.py":              "",
+			"pkg/METADATA":                 "invalid",
+			"other/pkg.dist-info/METADATA": "invalid",
+			"pkg-1.0.dist-info/METADATA":   metadata,
+		})
+
+		content, err := ExtractCoreMetadata(data, data.Size())
+		require.NoError(t, err)
+		assert.Equal(t, metadata, string(content))
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		data := createArchive(map[string]string{
+			"pkg/__init__.py": "",
+		})
+
+		content, err := ExtractCoreMetadata(data, data.Size())
+		assert.ErrorIs(t, err, ErrMissingCoreMetadata)
+		assert.Nil(t, content)
+	})
+
+	t.Run("InvalidArchive", func(t *testing.T) {
+		data := bytes.NewReader([]byte("test"))
+
+		_, err := ExtractCoreMetadata(data, data.Size())
+		assert.Error(t, err)
+	})
+}
@@ -17,6 +17,13 @@
 
 package pypi
 
+const (
+	// PropertyCoreMetadata holds the METADATA file of a wheel (PEP 658)
+	PropertyCoreMetadata = "pypi.core_metadata"
+	// PropertyYanked marks a version as yanked, the value is the optional reason (PEP 592)
+	PropertyYanked = "pypi.yanked"
+)
+
 // Metadata represents the metadata of a PyPI package
 type Metadata struct {
 	Author          string `json:"author,omitempty"`
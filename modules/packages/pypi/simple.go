@@ -0,0 +1,193 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package pypi
+
+import (
+	"mime"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// https://peps.python.org/pep-0691/
+const (
+	SimpleAPIVersion      = "1.1"
+	SimpleContentType     = "application/vnd.pypi.simple.v1+json"
+	SimpleHTMLContentType = "application/vnd.pypi.simple.v1+html"
+	HTMLContentType       = "text/html"
+)
+
+var nameSeparators = regexp.MustCompile(`[-_.]+`)
+
+// SimpleMeta contains the version of the JSON Simple Repository API
+type SimpleMeta struct {
+	APIVersion string `json:"api-version"`
+}
+
+// SimpleIndex is the root index of the JSON Simple Repository API
+type SimpleIndex struct {
+	Meta     SimpleMeta            `json:"meta"`
+	Projects []*SimpleIndexProject `json:"projects"`
+}
+
+// SimpleIndexProject is a project listed in the root index
+type SimpleIndexProject struct {
+	Name string `json:"name"`
+}
+
+// SimpleProject is a project page of the JSON Simple Repository API
+type SimpleProject struct {
+	Meta     SimpleMeta    `json:"meta"`
+	Name     string        `json:"name"`
+	Files    []*SimpleFile `json:"files"`
+	Versions []string      `json:"versions,omitempty"`
+}
+
+// SimpleFile is a file of a project page of the JSON Simple Repository API
+type SimpleFile struct {
+	Filename         string            `json:"filename"`
+	URL              string            `json:"url"`
+	Hashes           map[string]string `json:"hashes"`
+	RequiresPython   string            `json:"requires-python,omitempty"`
+	CoreMetadata     any               `json:"core-metadata,omitempty"`      // false or the hashes of the metadata file
+	DistInfoMetadata any               `json:"dist-info-metadata,omitempty"` // deprecated name of core-metadata
+	Yanked           any               `json:"yanked,omitempty"`             // false or true or the reason as string
+	Size             int64             `json:"size,omitempty"`
+	UploadTime       string            `json:"upload-time,omitempty"`
+}
+
+// IsYanked checks if the file is marked as yanked
+func (f *SimpleFile) IsYanked() bool {
+	switch v := f.Yanked.(type) {
+	case bool:
+		return v
+	case string:
+		return true
+	}
+	return false
+}
+
+// YankedReason returns the reason why the file is yanked, if any
+func (f *SimpleFile) YankedReason() string {
+	reason, _ := f.Yanked.(string)
+	return reason
+}
+
+// CoreMetadataHash returns the hash of the core metadata file in the "<algorithm>=<hash>" form of the HTML API.
+// An empty string is returned if the metadata file is not available.
+func (f *SimpleFile) CoreMetadataHash() string {
+	metadata := f.CoreMetadata
+	if metadata == nil {
+		metadata = f.DistInfoMetadata
+	}
+	switch v := metadata.(type) {
+	case map[string]string:
+		if hash, ok := v["sha256"]; ok {
+			return "sha256=" + hash
+		}
+	case map[string]any:
+		if hash, ok := v["sha256"].(string); ok {
+			return "sha256=" + hash
+		}
+	}
+	return ""
+}
+
+// NegotiateContentType selects the content type of a Simple Repository API response from the Accept header.
+// An empty string is returned if none of the accepted content types is supported.
+func NegotiateContentType(accept string) string {
+	if strings.TrimSpace(accept) == "" {
+		return HTMLContentType
+	}
+
+	selected := ""
+	selectedQuality := 0.0
+	for part := range strings.SplitSeq(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		var contentType string
+		switch mediaType {
+		case SimpleContentType, "application/vnd.pypi.simple.latest+json":
+			contentType = SimpleContentType
+		case SimpleHTMLContentType, "application/vnd.pypi.simple.latest+html":
+			contentType = SimpleHTMLContentType
+		case HTMLContentType, "text/*", "*/*":
+			contentType = HTMLContentType
+		default:
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		if quality > selectedQuality {
+			selected, selectedQuality = contentType, quality
+		}
+	}
+	return selected
+}
+
+// NormalizeName normalizes a project name
+// https://packaging.python.org/en/latest/specifications/name-normalization/
+func NormalizeName(name string) string {
+	return strings.ToLower(nameSeparators.ReplaceAllString(name, "-"))
+}
+
+// ParseFilenameVersion extracts the version from the name of a distribution file of the project.
+// Source distributions are named {name}-{version}.tar.gz and
+// binary distributions {name}-{version}(-{build tag})?-{python tag}-{abi tag}-{platform tag}.whl
+func ParseFilenameVersion(projectName, filename string) (string, bool) {
+	base := filename
+	isWheel := false
+	switch lower := strings.ToLower(filename); {
+	case strings.HasSuffix(lower, ".whl"):
+		base, isWheel = filename[:len(filename)-4], true
+	case strings.HasSuffix(lower, ".egg"):
+		base, isWheel = filename[:len(filename)-4], true
+	case strings.HasSuffix(lower, ".tar.gz"):
+		base = filename[:len(filename)-7]
+	case strings.HasSuffix(lower, ".zip"), strings.HasSuffix(lower, ".tgz"):
+		base = filename[:len(filename)-4]
+	case strings.HasSuffix(lower, ".tar.bz2"):
+		base = filename[:len(filename)-8]
+	default:
+		return "", false
+	}
+
+	normalizedName := NormalizeName(projectName)
+	for i := range len(base) {
+		if base[i] != '-' || NormalizeName(base[:i]) != normalizedName {
+			continue
+		}
+		version := base[i+1:]
+		if isWheel {
+			version, _, _ = strings.Cut(version, "-")
+		}
+		if version == "" {
+			return "", false
+		}
+		return version, true
+	}
+	return "", false
+}
@@ -0,0 +1,90 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package pypi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeName(t *testing.T) {
+	assert.Equal(t, "friendly-bard", NormalizeName("Friendly-Bard"))
+	assert.Equal(t, "friendly-bard", NormalizeName("FRIENDLY_BARD"))
+	assert.Equal(t, "friendly-bard", NormalizeName("friendly.bard"))
+	assert.Equal(t, "friendly-bard", NormalizeName("friendly--._bard"))
+}
+
+func TestParseFilenameVersion(t *testing.T) {
+	cases := []struct {
+		Project  string
+		Filename string
+		Version  string
+	}{
+		{"requests", "requests-2.31.0.tar.gz", "2.31.0"},
+		{"requests", "requests-2.31.0-py3-none-any.whl", "2.31.0"},
+		{"python-dateutil", "python-dateutil-2.8.2.tar.gz", "2.8.2"},
+		{"python-dateutil", "python_dateutil-2.8.2-py2.py3-none-any.whl", "2.8.2"},
+		{"Zope.Interface", "zope.interface-6.0-1-cp311-cp311-manylinux_2_17_x86_64.whl", "6.0"},
+		{"pkg", "pkg-1.0rc1.zip", "1.0rc1"},
+		{"pkg", "pkg-1.0.exe", ""},
+		{"pkg", "other-1.0.tar.gz", ""},
+		{"pkg", "pkg-.tar.gz", ""},
+	}
+
+	for _, c := range cases {
+		version, ok := ParseFilenameVersion(c.Project, c.Filename)
+		assert.Equal(t, c.Version != "", ok, c.Filename)
+		assert.Equal(t, c.Version, version, c.Filename)
+	}
+}
+
+func TestNegotiateContentType(t *testing.T) {
+	cases := map[string]string{
+		"":                                    HTMLContentType,
+		"text/html":                           HTMLContentType,
+		"*/*":                                 HTMLContentType,
+		"application/vnd.pypi.simple.v1+json": SimpleContentType,
+		"application/vnd.pypi.simple.latest+json": SimpleContentType,
+		"application/vnd.pypi.simple.v1+html":     SimpleHTMLContentType,
+		"application/vnd.pypi.simple.v1+json, application/vnd.pypi.simple.v1+html;q=0.2, text/html;q=0.01": SimpleContentType,
+		"text/html;q=0.5, application/vnd.pypi.simple.v1+json;q=0.1":                                       HTMLContentType,
+		"application/json":                    "",
+		"application/vnd.pypi.simple.v2+json": "",
+	}
+
+	for accept, expected := range cases {
+		assert.Equal(t, expected, NegotiateContentType(accept), accept)
+	}
+}
+
+func TestSimpleFile(t *testing.T) {
+	f := &SimpleFile{}
+	assert.Empty(t, f.YankedReason())
+	assert.Empty(t, f.CoreMetadataHash())
+
+	f = &SimpleFile{Yanked: "broken", CoreMetadata: map[string]string{"sha256": "abc"}}
+	assert.True(t, f.IsYanked())
+	assert.Equal(t, "broken", f.YankedReason())
+	assert.Equal(t, "sha256=abc", f.CoreMetadataHash())
+
+	f = &SimpleFile{Yanked: true, DistInfoMetadata: map[string]any{"sha256": "def"}}
+	assert.True(t, f.IsYanked())
+	assert.Empty(t, f.YankedReason())
+	assert.Equal(t, "sha256=def", f.CoreMetadataHash())
+}
@@ -0,0 +1,264 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package sigstore
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"github.com/kumose/kmup/modules/json"
+	"github.com/kumose/kmup/modules/util"
+)
+
+const (
+	// PropertyBundle is the name of the package version property which stores a bundle
+	PropertyBundle = "sigstore.bundle"
+	// PropertyBundleUploaded is the name of the package version property which stores the upload time of a bundle as "<bundle property id>:<unix time>"
+	PropertyBundleUploaded = "sigstore.bundle.uploaded"
+
+	// MaxBundleSize is the maximum size of a bundle
+	MaxBundleSize = 1 << 20
+
+	bundleMediaTypePrefix = "application/vnd.dev.sigstore.bundle"
+	inTotoPayloadType     = "application/vnd.in-toto+json"
+)
+
+var (
+	ErrInvalidBundle  = util.NewInvalidArgumentErrorf("bundle is invalid")
+	ErrBundleTooLarge = util.NewInvalidArgumentErrorf("bundle is too large")
+)
+
+// Bundle is a Sigstore bundle or a plain DSSE envelope containing an in-toto attestation
+// https://github.com/sigstore/protobuf-specs/blob/main/protos/sigstore_bundle.proto
+type Bundle struct {
+	MediaType            string                `json:"mediaType"`
+	VerificationMaterial *VerificationMaterial `json:"verificationMaterial,omitempty"`
+	MessageSignature     *MessageSignature     `json:"messageSignature,omitempty"`
+	DSSEEnvelope         *Envelope             `json:"dsseEnvelope,omitempty"`
+}
+
+// VerificationMaterial contains the key hint or the certificates of the signer
+type VerificationMaterial struct {
+	PublicKey            *PublicKeyIdentifier    `json:"publicKey,omitempty"`
+	X509CertificateChain *X509CertificateChain   `json:"x509CertificateChain,omitempty"`
+	Certificate          *X509Certificate        `json:"certificate,omitempty"`
+	TlogEntries          []*TransparencyLogEntry `json:"tlogEntries,omitempty"`
+}
+
+type PublicKeyIdentifier struct {
+	Hint string `json:"hint"`
+}
+
+type X509CertificateChain struct {
+	Certificates []*X509Certificate `json:"certificates"`
+}
+
+type X509Certificate struct {
+	RawBytes []byte `json:"rawBytes"`
+}
+
+// TransparencyLogEntry is an entry of the transparency log.
+// It isn't used for the verification, since neither the signed entry timestamp nor the inclusion proof is verified.
+type TransparencyLogEntry struct {
+	IntegratedTime int64 `json:"integratedTime,string"`
+}
+
+// MessageSignature is a signature over the digest of an artifact
+type MessageSignature struct {
+	MessageDigest *HashOutput `json:"messageDigest"`
+	Signature     []byte      `json:"signature"`
+}
+
+type HashOutput struct {
+	Algorithm string `json:"algorithm"`
+	Digest    []byte `json:"digest"`
+}
+
+// Envelope is a DSSE envelope
+// https://github.com/secure-systems-lab/dsse/blob/master/envelope.md
+type Envelope struct {
+	PayloadType string               `json:"payloadType"`
+	Payload     []byte               `json:"payload"`
+	Signatures  []*EnvelopeSignature `json:"signatures"`
+}
+
+type EnvelopeSignature struct {
+	KeyID string `json:"keyid"`
+	Sig   []byte `json:"sig"`
+}
+
+// Statement is an in-toto attestation statement
+// https://github.com/in-toto/attestation/blob/main/spec/v1/statement.md
+type Statement struct {
+	Type          string              `json:"_type"`
+	Subject       []*StatementSubject `json:"subject"`
+	PredicateType string              `json:"predicateType"`
+}
+
+type StatementSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// Digest is a digest of an artifact covered by a bundle
+type Digest struct {
+	Algorithm string // sha256 or sha512
+	Value     string // hex encoded
+}
+
+// ParseBundle parses a Sigstore bundle or a plain DSSE envelope
+func ParseBundle(data []byte) (*Bundle, error) {
+	if len(data) > MaxBundleSize {
+		return nil, ErrBundleTooLarge
+	}
+
+	var b Bundle
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, ErrInvalidBundle
+	}
+
+	if b.MediaType == "" {
+		// a plain DSSE envelope as written by in-toto tooling
+		var e Envelope
+		if err := json.Unmarshal(data, &e); err != nil || e.PayloadType == "" {
+			return nil, ErrInvalidBundle
+		}
+		b = Bundle{DSSEEnvelope: &e}
+	} else if !strings.HasPrefix(b.MediaType, bundleMediaTypePrefix) {
+		return nil, ErrInvalidBundle
+	}
+
+	switch {
+	case b.MessageSignature != nil && b.DSSEEnvelope == nil:
+		if b.MessageSignature.MessageDigest == nil || len(b.MessageSignature.Signature) == 0 {
+			return nil, ErrInvalidBundle
+		}
+		if digestAlgorithm(b.MessageSignature.MessageDigest.Algorithm) == "" {
+			return nil, ErrInvalidBundle
+		}
+	case b.DSSEEnvelope != nil && b.MessageSignature == nil:
+		if len(b.DSSEEnvelope.Signatures) == 0 {
+			return nil, ErrInvalidBundle
+		}
+		if b.DSSEEnvelope.PayloadType == inTotoPayloadType {
+			if _, err := b.Statement(); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, ErrInvalidBundle
+	}
+
+	return &b, nil
+}
+
+// IsAttestation returns true if the bundle contains an in-toto attestation instead of a plain signature
+func (b *Bundle) IsAttestation() bool {
+	return b.DSSEEnvelope != nil
+}
+
+// Statement returns the in-toto statement of an attestation
+func (b *Bundle) Statement() (*Statement, error) {
+	if b.DSSEEnvelope == nil || b.DSSEEnvelope.PayloadType != inTotoPayloadType {
+		return nil, ErrInvalidBundle
+	}
+
+	var s Statement
+	if err := json.Unmarshal(b.DSSEEnvelope.Payload, &s); err != nil || len(s.Subject) == 0 {
+		return nil, ErrInvalidBundle
+	}
+	return &s, nil
+}
+
+// PredicateType returns the predicate type of an attestation
+func (b *Bundle) PredicateType() string {
+	if s, err := b.Statement(); err == nil {
+		return s.PredicateType
+	}
+	return ""
+}
+
+// Digests returns the digests of the artifacts which are covered by the bundle
+func (b *Bundle) Digests() []Digest {
+	if b.MessageSignature != nil {
+		return []Digest{{
+			Algorithm: digestAlgorithm(b.MessageSignature.MessageDigest.Algorithm),
+			Value:     hex.EncodeToString(b.MessageSignature.MessageDigest.Digest),
+		}}
+	}
+
+	s, err := b.Statement()
+	if err != nil {
+		return nil
+	}
+
+	var digests []Digest
+	for _, subject := range s.Subject {
+		for _, algorithm := range []string{"sha256", "sha512"} {
+			if value, ok := subject.Digest[algorithm]; ok {
+				digests = append(digests, Digest{Algorithm: algorithm, Value: strings.ToLower(value)})
+			}
+		}
+	}
+	return digests
+}
+
+// certificates returns the certificate chain of a keyless bundle, the leaf certificate first
+func (b *Bundle) certificates() [][]byte {
+	vm := b.VerificationMaterial
+	if vm == nil {
+		return nil
+	}
+	if vm.Certificate != nil && len(vm.Certificate.RawBytes) > 0 {
+		return [][]byte{vm.Certificate.RawBytes}
+	}
+	if vm.X509CertificateChain != nil {
+		certs := make([][]byte, 0, len(vm.X509CertificateChain.Certificates))
+		for _, c := range vm.X509CertificateChain.Certificates {
+			certs = append(certs, c.RawBytes)
+		}
+		return certs
+	}
+	return nil
+}
+
+func digestAlgorithm(algorithm string) string {
+	switch algorithm {
+	case "SHA2_256":
+		return "sha256"
+	case "SHA2_512":
+		return "sha512"
+	}
+	return ""
+}
+
+// pae returns the pre-authentication encoding of a DSSE payload which is signed
+func pae(payloadType string, payload []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("DSSEv1 ")
+	buf.WriteString(strconv.Itoa(len(payloadType)))
+	buf.WriteByte(' ')
+	buf.WriteString(payloadType)
+	buf.WriteByte(' ')
+	buf.WriteString(strconv.Itoa(len(payload)))
+	buf.WriteByte(' ')
+	buf.Write(payload)
+	return buf.Bytes()
+}
@@ -0,0 +1,214 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package sigstore
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/kumose/kmup/modules/json"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const artifact = "kmup package content"
+
+func createMessageBundle(t *testing.T, key *ecdsa.PrivateKey, material *VerificationMaterial) []byte {
+	digest := sha256.Sum256([]byte(artifact))
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	require.NoError(t, err)
+
+	data, err := json.Marshal(&Bundle{
+		MediaType:            "application/vnd.dev.sigstore.bundle.v0.3+json",
+		VerificationMaterial: material,
+		MessageSignature: &MessageSignature{
+			MessageDigest: &HashOutput{Algorithm: "SHA2_256", Digest: digest[:]},
+			Signature:     sig,
+		},
+	})
+	require.NoError(t, err)
+	return data
+}
+
+func createEnvelope(t *testing.T, key *ecdsa.PrivateKey) []byte {
+	digest := sha256.Sum256([]byte(artifact))
+	payload, err := json.Marshal(&Statement{
+		Type:          "https://in-toto.io/Statement/v1",
+		Subject:       []*StatementSubject{{Name: "package.tgz", Digest: map[string]string{"sha256": hex.EncodeToString(digest[:])}}},
+		PredicateType: "https://slsa.dev/provenance/v1",
+	})
+	require.NoError(t, err)
+
+	message := sha256.Sum256(pae(inTotoPayloadType, payload))
+	sig, err := ecdsa.SignASN1(rand.Reader, key, message[:])
+	require.NoError(t, err)
+
+	data, err := json.Marshal(&Envelope{
+		PayloadType: inTotoPayloadType,
+		Payload:     payload,
+		Signatures:  []*EnvelopeSignature{{Sig: sig}},
+	})
+	require.NoError(t, err)
+	return data
+}
+
+func createCertificate(t *testing.T, template, parent *x509.Certificate, pub crypto.PublicKey, priv crypto.Signer) *x509.Certificate {
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, pub, priv)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+func TestParseBundle(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	digest := sha256.Sum256([]byte(artifact))
+
+	t.Run("MessageSignature", func(t *testing.T) {
+		b, err := ParseBundle(createMessageBundle(t, key, nil))
+		require.NoError(t, err)
+		assert.False(t, b.IsAttestation())
+		assert.Equal(t, []Digest{{Algorithm: "sha256", Value: hex.EncodeToString(digest[:])}}, b.Digests())
+	})
+
+	t.Run("Envelope", func(t *testing.T) {
+		b, err := ParseBundle(createEnvelope(t, key))
+		require.NoError(t, err)
+		assert.True(t, b.IsAttestation())
+		assert.Equal(t, "https://slsa.dev/provenance/v1", b.PredicateType())
+		assert.Equal(t, []Digest{{Algorithm: "sha256", Value: hex.EncodeToString(digest[:])}}, b.Digests())
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, data := range []string{
+			``,
+			`{}`,
+			`{"mediaType":"application/json"}`,
+			`{"mediaType":"application/vnd.dev.sigstore.bundle.v0.3+json"}`,
+			`{"mediaType":"application/vnd.dev.sigstore.bundle.v0.3+json","messageSignature":{"messageDigest":{"algorithm":"MD5","digest":"AA=="},"signature":"AA=="}}`,
+			`{"payloadType":"application/vnd.in-toto+json","payload":"e30=","signatures":[{"sig":"AA=="}]}`,
+		} {
+			_, err := ParseBundle([]byte(data))
+			assert.ErrorIs(t, err, ErrInvalidBundle, data)
+		}
+	})
+}
+
+func TestVerify(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	pub, err := ParsePublicKey(string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})))
+	require.NoError(t, err)
+
+	t.Run("PublicKey", func(t *testing.T) {
+		for _, data := range [][]byte{createMessageBundle(t, key, nil), createEnvelope(t, key)} {
+			b, err := ParseBundle(data)
+			require.NoError(t, err)
+
+			res := b.Verify(&VerifyOptions{Signers: []*Signer{{Name: "other", PublicKey: &otherKey.PublicKey}, {Name: "release", PublicKey: pub}}}, time.Time{})
+			assert.True(t, res.Verified)
+			assert.Equal(t, "release", res.Signer)
+
+			res = b.Verify(&VerifyOptions{Signers: []*Signer{{Name: "other", PublicKey: &otherKey.PublicKey}}}, time.Time{})
+			assert.False(t, res.Verified)
+			assert.ErrorIs(t, res.Err, ErrNoMatchingSigner)
+		}
+	})
+
+	t.Run("Keyless", func(t *testing.T) {
+		caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		notBefore := time.Now().Add(-time.Hour)
+		ca := &x509.Certificate{
+			SerialNumber:          big.NewInt(1),
+			Subject:               pkix.Name{CommonName: "kmup test ca"},
+			NotBefore:             notBefore,
+			NotAfter:              notBefore.Add(24 * time.Hour),
+			IsCA:                  true,
+			BasicConstraintsValid: true,
+			KeyUsage:              x509.KeyUsageCertSign,
+		}
+		ca = createCertificate(t, ca, ca, &caKey.PublicKey, caKey)
+
+		issuer, err := asn1.MarshalWithParams("https://token.actions.example.com", "utf8")
+		require.NoError(t, err)
+		identity, _ := url.Parse("https://example.com/org/repo/.github/workflows/release.yml@refs/heads/main")
+		leaf := createCertificate(t, &x509.Certificate{
+			SerialNumber:    big.NewInt(2),
+			NotBefore:       notBefore,
+			NotAfter:        notBefore.Add(10 * time.Minute),
+			URIs:            []*url.URL{identity},
+			KeyUsage:        x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+			ExtraExtensions: []pkix.Extension{{Id: oidIssuerV2, Value: issuer}},
+		}, ca, &key.PublicKey, caKey)
+
+		b, err := ParseBundle(createMessageBundle(t, key, &VerificationMaterial{
+			Certificate: &X509Certificate{RawBytes: leaf.Raw},
+			TlogEntries: []*TransparencyLogEntry{{IntegratedTime: notBefore.Add(time.Minute).Unix()}},
+		}))
+		require.NoError(t, err)
+
+		roots := x509.NewCertPool()
+		roots.AddCert(ca)
+
+		uploadedAt := notBefore.Add(time.Minute)
+		res := b.Verify(&VerifyOptions{Signers: []*Signer{{Name: "release", Identity: "https://example.com/org/repo/*", Issuer: "https://token.actions.example.com"}}, Roots: roots}, uploadedAt)
+		assert.True(t, res.Verified)
+		assert.Equal(t, "release", res.Signer)
+		assert.Equal(t, identity.String(), res.Identity)
+		assert.Equal(t, "https://token.actions.example.com", res.Issuer)
+
+		res = b.Verify(&VerifyOptions{Signers: []*Signer{{Name: "release", Identity: "https://example.com/other/*"}}, Roots: roots}, uploadedAt)
+		assert.False(t, res.Verified)
+		assert.ErrorIs(t, res.Err, ErrNoMatchingSigner)
+
+		res = b.Verify(&VerifyOptions{Signers: []*Signer{{Name: "release", Identity: "*", Issuer: "https://accounts.example.com"}}, Roots: roots}, uploadedAt)
+		assert.False(t, res.Verified)
+
+		res = b.Verify(&VerifyOptions{Signers: []*Signer{{Name: "release", Identity: "*"}}}, uploadedAt)
+		assert.ErrorIs(t, res.Err, ErrNoTrustedRoots)
+
+		res = b.Verify(&VerifyOptions{Signers: []*Signer{{Name: "release", Identity: "*"}}, Roots: x509.NewCertPool()}, uploadedAt)
+		assert.ErrorIs(t, res.Err, ErrInvalidCertificate)
+
+		// the integrated time of the log entry isn't verified, so the certificate must have been valid when the bundle was uploaded
+		res = b.Verify(&VerifyOptions{Signers: []*Signer{{Name: "release", Identity: "*"}}, Roots: roots}, time.Now())
+		assert.ErrorIs(t, res.Err, ErrInvalidCertificate)
+	})
+}
@@ -0,0 +1,255 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package sigstore
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"time"
+
+	"github.com/kumose/kmup/modules/glob"
+	"github.com/kumose/kmup/modules/util"
+)
+
+var (
+	ErrInvalidPublicKey      = util.NewInvalidArgumentErrorf("public key is invalid")
+	ErrUnsupportedPublicKey  = util.NewInvalidArgumentErrorf("public key type is not supported")
+	ErrNoMatchingSigner      = errors.New("no trusted signer matches the bundle")
+	ErrNoTrustedRoots        = errors.New("no trusted roots are configured for keyless signatures")
+	ErrInvalidCertificate    = errors.New("signing certificate is invalid")
+	ErrSignatureVerification = errors.New("signature verification failed")
+)
+
+var (
+	// https://github.com/sigstore/fulcio/blob/main/docs/oid-info.md
+	oidIssuer   = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	oidIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// Signer is a trusted signer. Either PublicKey or Identity must be set.
+type Signer struct {
+	Name      string
+	PublicKey crypto.PublicKey
+	// Identity is a glob pattern matched against the URI or email of a keyless signing certificate
+	Identity string
+	// Issuer is the OIDC issuer of a keyless signing certificate, empty matches all
+	Issuer string
+}
+
+// VerifyOptions contains the trust configuration to verify bundles against
+type VerifyOptions struct {
+	Signers []*Signer
+	// Roots are the trusted certificate authorities for keyless signatures
+	Roots *x509.CertPool
+}
+
+// Result is the result of a bundle verification
+type Result struct {
+	Verified bool
+	Signer   string
+	Identity string
+	Issuer   string
+	Err      error
+}
+
+// ParsePublicKey parses a PEM encoded PKIX public key
+func ParsePublicKey(s string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(s))
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, ErrInvalidPublicKey
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, ErrInvalidPublicKey
+	}
+	switch pub.(type) {
+	case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+		return pub, nil
+	}
+	return nil, ErrUnsupportedPublicKey
+}
+
+// Verify verifies the signature of the bundle against the trusted signers.
+// Keyless bundles must contain a certificate issued by one of the trusted roots, which was valid when the bundle was uploaded.
+// The inclusion of the bundle in a transparency log is not verified, so the integrated time of the log entries can't be trusted
+// and the short-lived certificates must be uploaded with the bundle right after signing. A zero uploadedAt means now.
+func (b *Bundle) Verify(opts *VerifyOptions, uploadedAt time.Time) *Result {
+	if certs := b.certificates(); len(certs) > 0 {
+		return b.verifyKeyless(opts, certs, uploadedAt)
+	}
+
+	for _, signer := range opts.Signers {
+		if signer.PublicKey == nil {
+			continue
+		}
+		if b.verifySignature(signer.PublicKey) {
+			return &Result{Verified: true, Signer: signer.Name}
+		}
+	}
+	return &Result{Err: ErrNoMatchingSigner}
+}
+
+func (b *Bundle) verifyKeyless(opts *VerifyOptions, certs [][]byte, uploadedAt time.Time) *Result {
+	if opts.Roots == nil {
+		return &Result{Err: ErrNoTrustedRoots}
+	}
+
+	leaf, err := x509.ParseCertificate(certs[0])
+	if err != nil {
+		return &Result{Err: ErrInvalidCertificate}
+	}
+	intermediates := x509.NewCertPool()
+	for _, raw := range certs[1:] {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return &Result{Err: ErrInvalidCertificate}
+		}
+		intermediates.AddCert(cert)
+	}
+
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         opts.Roots,
+		Intermediates: intermediates,
+		CurrentTime:   uploadedAt,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}); err != nil {
+		return &Result{Err: ErrInvalidCertificate}
+	}
+
+	identities := make([]string, 0, len(leaf.URIs)+len(leaf.EmailAddresses))
+	for _, u := range leaf.URIs {
+		identities = append(identities, u.String())
+	}
+	identities = append(identities, leaf.EmailAddresses...)
+	issuer := certificateIssuer(leaf)
+
+	result := &Result{Issuer: issuer}
+	if len(identities) > 0 {
+		result.Identity = identities[0]
+	}
+
+	if !b.verifySignature(leaf.PublicKey) {
+		result.Err = ErrSignatureVerification
+		return result
+	}
+
+	for _, signer := range opts.Signers {
+		if signer.Identity == "" || (signer.Issuer != "" && signer.Issuer != issuer) {
+			continue
+		}
+		g, err := glob.Compile(signer.Identity)
+		if err != nil {
+			continue
+		}
+		for _, identity := range identities {
+			if g.Match(identity) {
+				result.Verified = true
+				result.Signer = signer.Name
+				result.Identity = identity
+				return result
+			}
+		}
+	}
+
+	result.Err = ErrNoMatchingSigner
+	return result
+}
+
+func (b *Bundle) verifySignature(pub crypto.PublicKey) bool {
+	if ms := b.MessageSignature; ms != nil {
+		var hash crypto.Hash
+		switch digestAlgorithm(ms.MessageDigest.Algorithm) {
+		case "sha256":
+			hash = crypto.SHA256
+		case "sha512":
+			hash = crypto.SHA512
+		default:
+			return false
+		}
+		if len(ms.MessageDigest.Digest) != hash.Size() {
+			return false
+		}
+		return verifyDigest(pub, hash, ms.MessageDigest.Digest, ms.Signature)
+	}
+
+	message := pae(b.DSSEEnvelope.PayloadType, b.DSSEEnvelope.Payload)
+	for _, sig := range b.DSSEEnvelope.Signatures {
+		if verifyMessage(pub, message, sig.Sig) {
+			return true
+		}
+	}
+	return false
+}
+
+func verifyDigest(pub crypto.PublicKey, hash crypto.Hash, digest, sig []byte) bool {
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(k, digest, sig)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, hash, digest, sig) == nil
+	}
+	return false
+}
+
+func verifyMessage(pub crypto.PublicKey, message, sig []byte) bool {
+	switch k := pub.(type) {
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, message, sig)
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P384():
+			digest := sha512.Sum384(message)
+			return ecdsa.VerifyASN1(k, digest[:], sig)
+		case elliptic.P521():
+			digest := sha512.Sum512(message)
+			return ecdsa.VerifyASN1(k, digest[:], sig)
+		}
+		digest := sha256.Sum256(message)
+		return ecdsa.VerifyASN1(k, digest[:], sig)
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(message)
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig) == nil
+	}
+	return false
+}
+
+func certificateIssuer(cert *x509.Certificate) string {
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidIssuerV2) {
+			var issuer string
+			if _, err := asn1.UnmarshalWithParams(ext.Value, &issuer, "utf8"); err == nil {
+				return issuer
+			}
+		}
+	}
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidIssuer) {
+			return string(ext.Value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,141 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package terraform
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/kumose/kmup/modules/util"
+)
+
+var (
+	ErrInvalidName    = util.NewInvalidArgumentErrorf("module name is invalid")
+	ErrInvalidSystem  = util.NewInvalidArgumentErrorf("module system is invalid")
+	ErrInvalidVersion = util.NewInvalidArgumentErrorf("version is invalid")
+	ErrReadmeTooLarge = util.NewInvalidArgumentErrorf("readme file is too large")
+)
+
+// https://developer.hashicorp.com/terraform/internals/module-registry-protocol#module-addresses
+var (
+	moduleNamePattern   = regexp.MustCompile(`\A[0-9A-Za-z](?:[0-9A-Za-z_-]{0,62}[0-9A-Za-z])?\z`)
+	moduleSystemPattern = regexp.MustCompile(`\A[0-9a-z]{1,64}\z`)
+)
+
+const maxReadmeFileSize = 1 * 1024 * 1024
+
+// ModuleMetadata represents the metadata of a Terraform module
+type ModuleMetadata struct {
+	Name       string   `json:"name"`
+	System     string   `json:"system"`
+	Readme     string   `json:"readme,omitempty"`
+	Submodules []string `json:"submodules,omitempty"`
+	Examples   []string `json:"examples,omitempty"`
+}
+
+// IsValidModuleName checks if the name is a valid module name
+func IsValidModuleName(name string) bool {
+	return moduleNamePattern.MatchString(name)
+}
+
+// IsValidModuleSystem checks if the system is a valid module target system
+func IsValidModuleSystem(system string) bool {
+	return moduleSystemPattern.MatchString(system)
+}
+
+// ModulePackageName returns the package name used to store a module.
+// The system can't contain a dash so the name is unambiguous.
+func ModulePackageName(name, system string) string {
+	return name + "-" + system
+}
+
+// ParseModuleArchive parses a module archive (.tar.gz) and extracts the readme,
+// the nested submodules (modules/*) and examples (examples/*)
+func ParseModuleArchive(r io.Reader, name, system string) (*ModuleMetadata, error) {
+	if !IsValidModuleName(name) {
+		return nil, ErrInvalidName
+	}
+	if !IsValidModuleSystem(system) {
+		return nil, ErrInvalidSystem
+	}
+
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, util.NewInvalidArgumentErrorf("module archive is invalid: %v", err)
+	}
+	defer gzr.Close()
+
+	m := &ModuleMetadata{
+		Name:   name,
+		System: system,
+	}
+
+	tr := tar.NewReader(gzr)
+	for {
+		hd, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, util.NewInvalidArgumentErrorf("module archive is invalid: %v", err)
+		}
+
+		if hd.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		filename := path.Clean(strings.TrimPrefix(hd.Name, "./"))
+
+		if strings.EqualFold(filename, "readme.md") {
+			if hd.Size > maxReadmeFileSize {
+				return nil, ErrReadmeTooLarge
+			}
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, err
+			}
+			m.Readme = string(data)
+			continue
+		}
+
+		parts := strings.Split(filename, "/")
+		if len(parts) != 3 || !strings.HasSuffix(parts[2], ".tf") {
+			continue
+		}
+		switch parts[0] {
+		case "modules":
+			if !slices.Contains(m.Submodules, parts[1]) {
+				m.Submodules = append(m.Submodules, parts[1])
+			}
+		case "examples":
+			if !slices.Contains(m.Examples, parts[1]) {
+				m.Examples = append(m.Examples, parts[1])
+			}
+		}
+	}
+
+	slices.Sort(m.Submodules)
+	slices.Sort(m.Examples)
+
+	return m, nil
+}
@@ -0,0 +1,97 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package terraform
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/kumose/kmup/modules/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsValidModuleName(t *testing.T) {
+	assert.True(t, IsValidModuleName("vpc"))
+	assert.True(t, IsValidModuleName("consul-cluster_01"))
+	assert.False(t, IsValidModuleName(""))
+	assert.False(t, IsValidModuleName("-vpc"))
+	assert.False(t, IsValidModuleName("vpc/aws"))
+
+	assert.True(t, IsValidModuleSystem("aws"))
+	assert.False(t, IsValidModuleSystem("AWS"))
+	assert.False(t, IsValidModuleSystem("aws-gov"))
+}
+
+func TestParseModuleArchive(t *testing.T) {
+	createArchive := func(files map[string]string) io.Reader {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(zw)
+		for filename, content := range files {
+			tw.WriteHeader(&tar.Header{
+				Name: filename,
+				Mode: 0o600,
+				Size: int64(len(content)),
+			})
+			tw.Write([]byte(content))
+		}
+		tw.Close()
+		zw.Close()
+		return &buf
+	}
+
+	t.Run("InvalidName", func(t *testing.T) {
+		m, err := ParseModuleArchive(createArchive(nil), "-vpc", "aws")
+		assert.Nil(t, m)
+		assert.ErrorIs(t, err, ErrInvalidName)
+
+		m, err = ParseModuleArchive(createArchive(nil), "vpc", "Aws")
+		assert.Nil(t, m)
+		assert.ErrorIs(t, err, ErrInvalidSystem)
+	})
+
+	t.Run("InvalidArchive", func(t *testing.T) {
+		m, err := ParseModuleArchive(bytes.NewReader([]byte("dummy")), "vpc", "aws")
+		assert.Nil(t, m)
+		assert.ErrorIs(t, err, util.ErrInvalidArgument)
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		m, err := ParseModuleArchive(createArchive(map[string]string{
+			"./README.md":                   "# VPC",
+			"main.tf":                       "",
+			"modules/subnet/main.tf":        "",
+			"modules/subnet/variables.tf":   "",
+			"modules/routes/README.md":      "",
+			"examples/simple/main.tf":       "",
+			"examples/complete/main.tf":     "",
+			"examples/complete/nested/a.tf": "",
+		}), "vpc", "aws")
+		require.NoError(t, err)
+		assert.Equal(t, "vpc", m.Name)
+		assert.Equal(t, "aws", m.System)
+		assert.Equal(t, "# VPC", m.Readme)
+		assert.Equal(t, []string{"subnet"}, m.Submodules)
+		assert.Equal(t, []string{"complete", "simple"}, m.Examples)
+	})
+}
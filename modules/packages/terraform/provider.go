@@ -0,0 +1,178 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package terraform
+
+import (
+	"bufio"
+	"encoding/hex"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/kumose/kmup/modules/json"
+	"github.com/kumose/kmup/modules/util"
+)
+
+var (
+	ErrInvalidProviderType = util.NewInvalidArgumentErrorf("provider type is invalid")
+	ErrInvalidFilename     = util.NewInvalidArgumentErrorf("provider filename is invalid")
+	ErrInvalidManifest     = util.NewInvalidArgumentErrorf("provider manifest is invalid")
+	ErrInvalidSHA256Sums   = util.NewInvalidArgumentErrorf("SHA256SUMS file is invalid")
+)
+
+const (
+	PropertyProtocols    = "terraform.protocols"
+	PropertySigningKeyID = "terraform.signing_key_id"
+	PropertyOS           = "terraform.os"
+	PropertyArch         = "terraform.arch"
+)
+
+// DefaultProtocols are assumed if a provider release contains no manifest
+var DefaultProtocols = []string{"5.0"}
+
+// https://developer.hashicorp.com/terraform/internals/provider-registry-protocol#provider-addresses
+var (
+	providerTypePattern = regexp.MustCompile(`\A[0-9a-z](?:[0-9a-z-]{0,62}[0-9a-z])?\z`)
+	platformPattern     = regexp.MustCompile(`\A[0-9a-z]+\z`)
+	protocolPattern     = regexp.MustCompile(`\A\d+\.\d+\z`)
+)
+
+const maxManifestFileSize = 64 * 1024
+
+// ProviderFileKind is the kind of file which is part of a provider release
+type ProviderFileKind int
+
+const (
+	ProviderFileArchive ProviderFileKind = iota
+	ProviderFileSHA256Sums
+	ProviderFileSignature
+	ProviderFileManifest
+)
+
+// ProviderFile represents a file of a provider release
+type ProviderFile struct {
+	Kind ProviderFileKind
+	OS   string
+	Arch string
+}
+
+// IsValidProviderType checks if the type is a valid provider type
+func IsValidProviderType(providerType string) bool {
+	return providerTypePattern.MatchString(providerType)
+}
+
+// ProviderFilePrefix returns the common prefix of all files of a provider release
+func ProviderFilePrefix(providerType, version string) string {
+	return "terraform-provider-" + providerType + "_" + version
+}
+
+// SHA256SumsFilename returns the name of the checksum file of a provider release
+func SHA256SumsFilename(providerType, version string) string {
+	return ProviderFilePrefix(providerType, version) + "_SHA256SUMS"
+}
+
+// SignatureFilename returns the name of the checksum signature file of a provider release
+func SignatureFilename(providerType, version string) string {
+	return SHA256SumsFilename(providerType, version) + ".sig"
+}
+
+// ParseProviderFilename parses the name of a file uploaded for a provider release.
+// The names follow the layout produced by the HashiCorp release tooling:
+// terraform-provider-{type}_{version}_{os}_{arch}.zip, terraform-provider-{type}_{version}_SHA256SUMS,
+// terraform-provider-{type}_{version}_SHA256SUMS.sig and terraform-provider-{type}_{version}_manifest.json
+func ParseProviderFilename(providerType, version, filename string) (*ProviderFile, error) {
+	if !IsValidProviderType(providerType) {
+		return nil, ErrInvalidProviderType
+	}
+
+	switch filename {
+	case SHA256SumsFilename(providerType, version):
+		return &ProviderFile{Kind: ProviderFileSHA256Sums}, nil
+	case SignatureFilename(providerType, version):
+		return &ProviderFile{Kind: ProviderFileSignature}, nil
+	case ProviderFilePrefix(providerType, version) + "_manifest.json":
+		return &ProviderFile{Kind: ProviderFileManifest}, nil
+	}
+
+	platform, ok := strings.CutPrefix(filename, ProviderFilePrefix(providerType, version)+"_")
+	if !ok {
+		return nil, ErrInvalidFilename
+	}
+	platform, ok = strings.CutSuffix(platform, ".zip")
+	if !ok {
+		return nil, ErrInvalidFilename
+	}
+	os, arch, ok := strings.Cut(platform, "_")
+	if !ok || !platformPattern.MatchString(os) || !platformPattern.MatchString(arch) {
+		return nil, ErrInvalidFilename
+	}
+
+	return &ProviderFile{
+		Kind: ProviderFileArchive,
+		OS:   os,
+		Arch: arch,
+	}, nil
+}
+
+// ParseProviderManifest parses the release manifest and returns the supported protocol versions
+// https://developer.hashicorp.com/terraform/registry/providers/publishing#terraform-registry-manifest-file
+func ParseProviderManifest(r io.Reader) ([]string, error) {
+	var manifest struct {
+		Version  int `json:"version"`
+		Metadata struct {
+			ProtocolVersions []string `json:"protocol_versions"`
+		} `json:"metadata"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r, maxManifestFileSize)).Decode(&manifest); err != nil {
+		return nil, ErrInvalidManifest
+	}
+	if manifest.Version != 1 || len(manifest.Metadata.ProtocolVersions) == 0 {
+		return nil, ErrInvalidManifest
+	}
+	for _, protocol := range manifest.Metadata.ProtocolVersions {
+		if !protocolPattern.MatchString(protocol) {
+			return nil, ErrInvalidManifest
+		}
+	}
+	return manifest.Metadata.ProtocolVersions, nil
+}
+
+// ParseSHA256Sums parses a SHA256SUMS file and returns the checksums keyed by filename
+func ParseSHA256Sums(r io.Reader) (map[string]string, error) {
+	sums := make(map[string]string)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, ErrInvalidSHA256Sums
+		}
+		if b, err := hex.DecodeString(fields[0]); err != nil || len(b) != 32 {
+			return nil, ErrInvalidSHA256Sums
+		}
+		sums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return sums, nil
+}
@@ -0,0 +1,87 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package terraform
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProviderFilename(t *testing.T) {
+	cases := []struct {
+		Filename string
+		Expected *ProviderFile
+	}{
+		{"terraform-provider-dummy_1.0.0_linux_amd64.zip", &ProviderFile{Kind: ProviderFileArchive, OS: "linux", Arch: "amd64"}},
+		{"terraform-provider-dummy_1.0.0_SHA256SUMS", &ProviderFile{Kind: ProviderFileSHA256Sums}},
+		{"terraform-provider-dummy_1.0.0_SHA256SUMS.sig", &ProviderFile{Kind: ProviderFileSignature}},
+		{"terraform-provider-dummy_1.0.0_manifest.json", &ProviderFile{Kind: ProviderFileManifest}},
+		{"terraform-provider-dummy_1.0.0_linux_amd64.tar.gz", nil},
+		{"terraform-provider-dummy_1.0.0_linux.zip", nil},
+		{"terraform-provider-dummy_1.0.0_Linux_amd64.zip", nil},
+		{"terraform-provider-other_1.0.0_linux_amd64.zip", nil},
+		{"terraform-provider-dummy_1.0.1_linux_amd64.zip", nil},
+	}
+	for _, c := range cases {
+		pf, err := ParseProviderFilename("dummy", "1.0.0", c.Filename)
+		if c.Expected == nil {
+			assert.ErrorIs(t, err, ErrInvalidFilename, c.Filename)
+		} else {
+			assert.NoError(t, err, c.Filename)
+			assert.Equal(t, c.Expected, pf, c.Filename)
+		}
+	}
+
+	_, err := ParseProviderFilename("Dummy", "1.0.0", "terraform-provider-Dummy_1.0.0_linux_amd64.zip")
+	assert.ErrorIs(t, err, ErrInvalidProviderType)
+}
+
+func TestParseProviderManifest(t *testing.T) {
+	protocols, err := ParseProviderManifest(strings.NewReader(`{"version":1,"metadata":{"protocol_versions":["6.0"]}}`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"6.0"}, protocols)
+
+	for _, content := range []string{
+		``,
+		`{"version":2,"metadata":{"protocol_versions":["6.0"]}}`,
+		`{"version":1,"metadata":{"protocol_versions":[]}}`,
+		`{"version":1,"metadata":{"protocol_versions":["six"]}}`,
+	} {
+		_, err := ParseProviderManifest(strings.NewReader(content))
+		assert.ErrorIs(t, err, ErrInvalidManifest, content)
+	}
+}
+
+func TestParseSHA256Sums(t *testing.T) {
+	hash := strings.Repeat("ab", 32)
+
+	sums, err := ParseSHA256Sums(strings.NewReader(hash + "  terraform-provider-dummy_1.0.0_linux_amd64.zip\n\n" + strings.ToUpper(hash) + " *terraform-provider-dummy_1.0.0_darwin_arm64.zip\n"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"terraform-provider-dummy_1.0.0_linux_amd64.zip":  hash,
+		"terraform-provider-dummy_1.0.0_darwin_arm64.zip": hash,
+	}, sums)
+
+	_, err = ParseSHA256Sums(strings.NewReader("abc  file.zip"))
+	assert.ErrorIs(t, err, ErrInvalidSHA256Sums)
+	_, err = ParseSHA256Sums(strings.NewReader(hash))
+	assert.ErrorIs(t, err, ErrInvalidSHA256Sums)
+}
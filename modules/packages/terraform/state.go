@@ -0,0 +1,103 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package terraform
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+
+	"github.com/kumose/kmup/modules/json"
+	"github.com/kumose/kmup/modules/util"
+)
+
+var (
+	ErrInvalidStateName = util.NewInvalidArgumentErrorf("state name is invalid")
+	ErrInvalidState     = util.NewInvalidArgumentErrorf("state is invalid")
+	ErrInvalidLockInfo  = util.NewInvalidArgumentErrorf("lock info is invalid")
+)
+
+// PropertyStateLock holds the JSON encoded LockInfo of a locked state
+const PropertyStateLock = "terraform.state.lock"
+
+// StateFilename is the name of the file which stores a state version
+const StateFilename = "terraform.tfstate"
+
+var stateNamePattern = regexp.MustCompile(`\A[A-Za-z0-9][A-Za-z0-9._-]{0,127}\z`)
+
+// StateMetadata represents the metadata of a Terraform state version
+type StateMetadata struct {
+	Serial           int64  `json:"serial"`
+	Lineage          string `json:"lineage,omitempty"`
+	TerraformVersion string `json:"terraform_version,omitempty"`
+}
+
+// LockInfo represents the lock of a Terraform state as sent by the http backend
+// https://github.com/hashicorp/terraform/blob/main/internal/states/statemgr/locker.go
+type LockInfo struct {
+	ID        string    `json:"ID"`
+	Operation string    `json:"Operation"`
+	Info      string    `json:"Info"`
+	Who       string    `json:"Who"`
+	Version   string    `json:"Version"`
+	Created   time.Time `json:"Created"`
+	Path      string    `json:"Path"`
+}
+
+// IsValidStateName checks if the name is a valid state name
+func IsValidStateName(name string) bool {
+	return stateNamePattern.MatchString(name)
+}
+
+// StatePackageName returns the name of the internal package which stores a state.
+// States of a repository are prefixed with the repository id, so they are not shared
+// with a repository which is created later with the same name.
+func StatePackageName(repoID int64, name string) string {
+	if repoID == 0 {
+		return name
+	}
+	return fmt.Sprintf("%d/%s", repoID, name)
+}
+
+// ParseState validates a state file and extracts its metadata
+func ParseState(r io.Reader) (*StateMetadata, error) {
+	var state struct {
+		Version          *int   `json:"version"`
+		Serial           int64  `json:"serial"`
+		Lineage          string `json:"lineage"`
+		TerraformVersion string `json:"terraform_version"`
+	}
+	if err := json.NewDecoder(r).Decode(&state); err != nil || state.Version == nil {
+		return nil, ErrInvalidState
+	}
+	return &StateMetadata{
+		Serial:           state.Serial,
+		Lineage:          state.Lineage,
+		TerraformVersion: state.TerraformVersion,
+	}, nil
+}
+
+// ParseLockInfo parses the lock info sent with a lock or unlock request
+func ParseLockInfo(r io.Reader) (*LockInfo, error) {
+	var info LockInfo
+	if err := json.NewDecoder(r).Decode(&info); err != nil || info.ID == "" {
+		return nil, ErrInvalidLockInfo
+	}
+	return &info, nil
+}
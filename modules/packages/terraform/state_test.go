@@ -0,0 +1,62 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package terraform
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsValidStateName(t *testing.T) {
+	assert.True(t, IsValidStateName("production"))
+	assert.True(t, IsValidStateName("eu-west-1.network_v2"))
+	assert.False(t, IsValidStateName(""))
+	assert.False(t, IsValidStateName(".hidden"))
+	assert.False(t, IsValidStateName("a/b"))
+	assert.False(t, IsValidStateName(strings.Repeat("a", 129)))
+}
+
+func TestStatePackageName(t *testing.T) {
+	assert.Equal(t, "production", StatePackageName(0, "production"))
+	assert.Equal(t, "42/production", StatePackageName(42, "production"))
+}
+
+func TestParseState(t *testing.T) {
+	m, err := ParseState(strings.NewReader(`{"version":4,"terraform_version":"1.9.0","serial":3,"lineage":"abc","outputs":{},"resources":[]}`))
+	require.NoError(t, err)
+	assert.Equal(t, &StateMetadata{Serial: 3, Lineage: "abc", TerraformVersion: "1.9.0"}, m)
+
+	for _, content := range []string{``, `[]`, `{"serial":1}`, `not json`} {
+		_, err := ParseState(strings.NewReader(content))
+		assert.ErrorIs(t, err, ErrInvalidState, content)
+	}
+}
+
+func TestParseLockInfo(t *testing.T) {
+	info, err := ParseLockInfo(strings.NewReader(`{"ID":"1234","Operation":"OperationTypePlan","Who":"user@host","Version":"1.9.0","Created":"2026-01-02T03:04:05Z","Path":""}`))
+	require.NoError(t, err)
+	assert.Equal(t, "1234", info.ID)
+	assert.Equal(t, "OperationTypePlan", info.Operation)
+	assert.Equal(t, "user@host", info.Who)
+
+	_, err = ParseLockInfo(strings.NewReader(`{"Operation":"OperationTypePlan"}`))
+	assert.ErrorIs(t, err, ErrInvalidLockInfo)
+}
@@ -37,3 +37,55 @@ func GenerateActionsRunnerToken(ctx context.Context, scope string) (*ResponseTex
 
 	return requestJSONResp(req, &ResponseText{})
 }
+
+// RunnerGroupOptions are the options to create, update or delete a runner group.
+// When updating, the empty NewName and the nil Description and lists are unchanged.
+type RunnerGroupOptions struct {
+	Name             string
+	NewName          string
+	Description      *string
+	AllowedOwners    []string
+	AllowedRepos     []string
+	AllowedWorkflows []string
+}
+
+// SetRunnerGroupOptions moves a global runner into a runner group, or out of its group if Group is empty
+type SetRunnerGroupOptions struct {
+	RunnerID int64
+	Group    string
+}
+
+// ListActionsRunnerGroups calls the internal ListActionsRunnerGroups function
+func ListActionsRunnerGroups(ctx context.Context) (*ResponseText, ResponseExtra) {
+	reqURL := setting.LocalURL + "api/internal/actions/runner_groups/list"
+	req := newInternalRequestAPI(ctx, reqURL, "POST")
+	return requestJSONResp(req, &ResponseText{})
+}
+
+// CreateActionsRunnerGroup calls the internal CreateActionsRunnerGroup function
+func CreateActionsRunnerGroup(ctx context.Context, opts *RunnerGroupOptions) ResponseExtra {
+	reqURL := setting.LocalURL + "api/internal/actions/runner_groups/create"
+	req := newInternalRequestAPI(ctx, reqURL, "POST", opts)
+	return requestJSONClientMsg(req, "Runner group "+opts.Name+" has been created")
+}
+
+// UpdateActionsRunnerGroup calls the internal UpdateActionsRunnerGroup function
+func UpdateActionsRunnerGroup(ctx context.Context, opts *RunnerGroupOptions) ResponseExtra {
+	reqURL := setting.LocalURL + "api/internal/actions/runner_groups/update"
+	req := newInternalRequestAPI(ctx, reqURL, "POST", opts)
+	return requestJSONClientMsg(req, "Runner group "+opts.Name+" has been updated")
+}
+
+// DeleteActionsRunnerGroup calls the internal DeleteActionsRunnerGroup function
+func DeleteActionsRunnerGroup(ctx context.Context, name string) ResponseExtra {
+	reqURL := setting.LocalURL + "api/internal/actions/runner_groups/delete"
+	req := newInternalRequestAPI(ctx, reqURL, "POST", RunnerGroupOptions{Name: name})
+	return requestJSONClientMsg(req, "Runner group "+name+" has been deleted")
+}
+
+// SetActionsRunnerGroup calls the internal SetActionsRunnerGroup function
+func SetActionsRunnerGroup(ctx context.Context, opts *SetRunnerGroupOptions) ResponseExtra {
+	reqURL := setting.LocalURL + "api/internal/actions/runner_groups/set_runner"
+	req := newInternalRequestAPI(ctx, reqURL, "POST", opts)
+	return requestJSONClientMsg(req, "Runner group of the runner has been set")
+}
@@ -60,6 +60,11 @@ type SSHLogOption struct {
 	Message string
 }
 
+// HookPreReceiveResult represents the result of PreReceive when the push is allowed
+type HookPreReceiveResult struct {
+	Warnings []string
+}
+
 // HookPostReceiveResult represents an individual result from PostReceive
 type HookPostReceiveResult struct {
 	Results      []HookPostReceiveBranchResult
@@ -107,10 +112,9 @@ func newInternalRequestAPIForHooks(ctx context.Context, hookName, ownerName, rep
 }
 
 // HookPreReceive check whether the provided commits are allowed
-func HookPreReceive(ctx context.Context, ownerName, repoName string, opts HookOptions) ResponseExtra {
+func HookPreReceive(ctx context.Context, ownerName, repoName string, opts HookOptions) (*HookPreReceiveResult, ResponseExtra) {
 	req := newInternalRequestAPIForHooks(ctx, "pre-receive", ownerName, repoName, opts)
-	_, extra := requestJSONResp(req, &ResponseText{})
-	return extra
+	return requestJSONResp(req, &HookPreReceiveResult{})
 }
 
 // HookPostReceive updates services and users
@@ -50,6 +50,7 @@ type PushTrigger string
 const (
 	PushTriggerPRMergeToBase    PushTrigger = "pr-merge-to-base"
 	PushTriggerPRUpdateWithBase PushTrigger = "pr-update-with-base"
+	PushTriggerMergeQueueToBase PushTrigger = "merge-queue-to-base"
 )
 
 // InternalPushingEnvironment returns an os environment to switch off hooks on push
@@ -0,0 +1,244 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package secretscan
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/kumose/kmup/modules/json"
+	"github.com/kumose/kmup/modules/log"
+	"github.com/kumose/kmup/modules/setting"
+)
+
+// KmupAccessTokenRuleID is the ID of the rule which detects the access tokens of kmup itself
+const KmupAccessTokenRuleID = "kmup-access-token"
+
+// Rule describes a kind of secret
+type Rule struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	// Regex matches the secret, if it has capturing groups, SecretGroup is the one containing the secret itself
+	Regex       string `json:"regex"`
+	SecretGroup int    `json:"secret_group"`
+	// Entropy is the minimum Shannon entropy of the secret, it filters out placeholders like "xxxxxxxx"
+	Entropy float64 `json:"entropy"`
+	// Keywords make the rule only apply to the contents containing one of them (case-insensitive), it makes scanning faster
+	Keywords []string `json:"keywords"`
+	// Allowlist are regular expressions matching the secrets which must not be reported
+	Allowlist []string `json:"allowlist"`
+
+	regex     *regexp.Regexp
+	allowlist []*regexp.Regexp
+}
+
+func (r *Rule) compile() error {
+	var err error
+	if r.ID == "" {
+		return fmt.Errorf("rule without id")
+	}
+	if r.regex, err = regexp.Compile(r.Regex); err != nil {
+		return fmt.Errorf("rule %s: invalid regex: %w", r.ID, err)
+	}
+	if r.SecretGroup < 0 || r.SecretGroup > r.regex.NumSubexp() {
+		return fmt.Errorf("rule %s: invalid secret group %d", r.ID, r.SecretGroup)
+	}
+	r.allowlist = make([]*regexp.Regexp, 0, len(r.Allowlist))
+	for _, s := range r.Allowlist {
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return fmt.Errorf("rule %s: invalid allowlist regex: %w", r.ID, err)
+		}
+		r.allowlist = append(r.allowlist, re)
+	}
+	for i := range r.Keywords {
+		r.Keywords[i] = toLowerASCII(r.Keywords[i])
+	}
+	return nil
+}
+
+// builtinRules are the rules which are always available unless they are disabled or overridden by custom rules with the same ID.
+// The rules are checked in order and a secret is only reported by the first rule matching it.
+var builtinRules = []*Rule{
+	{
+		ID:          KmupAccessTokenRuleID,
+		Description: "Kmup access token",
+		Regex:       `(?i:kmup|gitea|token|authorization|auth|passw(?:or)?d|secret)[^\n]{0,40}?\b([0-9a-f]{40})\b`,
+		SecretGroup: 1,
+		Entropy:     3,
+		Keywords:    []string{"kmup", "gitea", "token", "auth", "passw", "secret"},
+	},
+	{
+		ID:          "private-key",
+		Description: "Private key",
+		Regex:       `-----BEGIN (?:RSA |DSA |EC |OPENSSH |PGP |ENCRYPTED )?PRIVATE KEY(?: BLOCK)?-----`,
+		Keywords:    []string{"private key"},
+	},
+	{
+		ID:          "aws-access-key-id",
+		Description: "AWS access key ID",
+		Regex:       `\b((?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA|ANVA|AIPA)[0-9A-Z]{16})\b`,
+		SecretGroup: 1,
+		Entropy:     3,
+		Keywords:    []string{"akia", "asia", "agpa", "aida", "aroa", "anpa", "anva", "aipa"},
+	},
+	{
+		ID:          "aws-secret-access-key",
+		Description: "AWS secret access key",
+		Regex:       `(?i:aws)[^\n]{0,20}?(?i:secret)[^\n]{0,20}?["'=:\s]([0-9A-Za-z/+]{40})\b`,
+		SecretGroup: 1,
+		Entropy:     4,
+		Keywords:    []string{"aws"},
+	},
+	{
+		ID:          "github-token",
+		Description: "GitHub token",
+		Regex:       `\b(gh[pousr]_[0-9A-Za-z]{36,255})\b`,
+		SecretGroup: 1,
+		Entropy:     3,
+		Keywords:    []string{"ghp_", "gho_", "ghu_", "ghs_", "ghr_"},
+	},
+	{
+		ID:          "github-fine-grained-token",
+		Description: "GitHub fine-grained personal access token",
+		Regex:       `\b(github_pat_[0-9A-Za-z_]{82})\b`,
+		SecretGroup: 1,
+		Entropy:     3,
+		Keywords:    []string{"github_pat_"},
+	},
+	{
+		ID:          "gitlab-token",
+		Description: "GitLab personal access token",
+		Regex:       `\b(glpat-[0-9A-Za-z_-]{20,})\b`,
+		SecretGroup: 1,
+		Entropy:     3,
+		Keywords:    []string{"glpat-"},
+	},
+	{
+		ID:          "slack-token",
+		Description: "Slack token",
+		Regex:       `\b(xox[abposr]-[0-9A-Za-z-]{10,})\b`,
+		SecretGroup: 1,
+		Entropy:     3,
+		Keywords:    []string{"xoxa-", "xoxb-", "xoxp-", "xoxo-", "xoxs-", "xoxr-"},
+	},
+	{
+		ID:          "google-api-key",
+		Description: "Google API key",
+		Regex:       `\b(AIza[0-9A-Za-z_-]{35})\b`,
+		SecretGroup: 1,
+		Entropy:     3,
+		Keywords:    []string{"aiza"},
+	},
+	{
+		ID:          "stripe-secret-key",
+		Description: "Stripe secret key",
+		Regex:       `\b((?:sk|rk)_live_[0-9A-Za-z]{24,})\b`,
+		SecretGroup: 1,
+		Entropy:     3,
+		Keywords:    []string{"sk_live_", "rk_live_"},
+	},
+	{
+		ID:          "generic-secret",
+		Description: "Generic secret",
+		Regex:       `(?i:api[_-]?key|secret|passw(?:or)?d|token)["']?\s*[:=]\s*["']?([0-9A-Za-z_\-+/=.]{20,})`,
+		SecretGroup: 1,
+		Entropy:     4.2,
+		Keywords:    []string{"api_key", "api-key", "apikey", "secret", "passw", "token"},
+	},
+}
+
+func init() {
+	for _, r := range builtinRules {
+		if err := r.compile(); err != nil {
+			panic(err)
+		}
+	}
+}
+
+var customRules struct {
+	sync.Mutex
+	path    string
+	modTime time.Time
+	rules   []*Rule
+}
+
+// LoadCustomRules reads the custom rules from a JSON file, the rules are an array of objects with the fields of Rule
+func LoadCustomRules(path string) ([]*Rule, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []*Rule
+	if err := json.Unmarshal(content, &rules); err != nil {
+		return nil, fmt.Errorf("invalid secret scanning rules file %s: %w", path, err)
+	}
+	for _, r := range rules {
+		if err := r.compile(); err != nil {
+			return nil, err
+		}
+	}
+	return rules, nil
+}
+
+// getCustomRules returns the rules of the custom rules file and reloads it when it changes
+func getCustomRules() []*Rule {
+	path := setting.SecretScanning.RulesPath
+	if path == "" {
+		return nil
+	}
+
+	customRules.Lock()
+	defer customRules.Unlock()
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		log.Error("Unable to stat the secret scanning rules file %s: %v", path, err)
+		return customRules.rules
+	}
+	if customRules.path == path && customRules.modTime.Equal(fi.ModTime()) {
+		return customRules.rules
+	}
+	rules, err := LoadCustomRules(path)
+	if err != nil {
+		// keep using the last valid rules
+		log.Error("Unable to load the secret scanning rules: %v", err)
+		return customRules.rules
+	}
+	customRules.path, customRules.modTime, customRules.rules = path, fi.ModTime(), rules
+	return rules
+}
+
+// Rules returns the enabled rules: the built-in rules, overridden or extended by the custom rules
+func Rules() []*Rule {
+	custom := getCustomRules()
+	rules := make([]*Rule, 0, len(builtinRules)+len(custom))
+	for _, r := range builtinRules {
+		if !slices.ContainsFunc(custom, func(c *Rule) bool { return c.ID == r.ID }) {
+			rules = append(rules, r)
+		}
+	}
+	rules = append(rules, custom...)
+	return slices.DeleteFunc(rules, func(r *Rule) bool {
+		return slices.Contains(setting.SecretScanning.DisabledRules, r.ID)
+	})
+}
@@ -0,0 +1,151 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package secretscan
+
+import (
+	"bytes"
+	"math"
+	"strings"
+)
+
+// Finding is a secret found in a content
+type Finding struct {
+	RuleID      string
+	Description string
+	Secret      string
+	Line        int // 1-based
+}
+
+// Redacted returns the secret with most of its characters masked, so it can be displayed
+func (f *Finding) Redacted() string {
+	return Redact(f.Secret)
+}
+
+// Redact masks most of the characters of a secret
+func Redact(secret string) string {
+	if len(secret) <= 8 {
+		return strings.Repeat("*", len(secret))
+	}
+	return secret[:4] + strings.Repeat("*", min(len(secret)-4, 16))
+}
+
+// IsBinary reports whether the content looks like a binary file, binary content is never scanned
+func IsBinary(content []byte) bool {
+	return bytes.IndexByte(content[:min(len(content), 8000)], 0) >= 0
+}
+
+// Scan returns the secrets found in the content by the enabled rules
+func Scan(content []byte) []*Finding {
+	return ScanWithRules(content, Rules())
+}
+
+// ScanWithRules returns the secrets found in the content by the given rules.
+// A secret found on a line is only reported once, by the first rule matching it.
+func ScanWithRules(content []byte, rules []*Rule) []*Finding {
+	if len(content) == 0 || IsBinary(content) {
+		return nil
+	}
+
+	lowerContent := toLowerASCII(string(content))
+	type secretLine struct {
+		secret string
+		line   int
+	}
+	seen := make(map[secretLine]bool)
+
+	var findings []*Finding
+	for _, rule := range rules {
+		if len(rule.Keywords) > 0 && !containsAny(lowerContent, rule.Keywords) {
+			continue
+		}
+		for _, loc := range rule.regex.FindAllSubmatchIndex(content, -1) {
+			start, end := loc[2*rule.SecretGroup], loc[2*rule.SecretGroup+1]
+			if start < 0 {
+				continue
+			}
+			secret := string(content[start:end])
+			if rule.Entropy > 0 && ShannonEntropy(secret) < rule.Entropy {
+				continue
+			}
+			if isAllowed(rule, secret) {
+				continue
+			}
+			key := secretLine{secret: secret, line: bytes.Count(content[:start], []byte{'\n'}) + 1}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			findings = append(findings, &Finding{
+				RuleID:      rule.ID,
+				Description: rule.Description,
+				Secret:      secret,
+				Line:        key.line,
+			})
+		}
+	}
+	return findings
+}
+
+func isAllowed(rule *Rule, secret string) bool {
+	for _, re := range rule.allowlist {
+		if re.MatchString(secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// ShannonEntropy returns the Shannon entropy of the string in bits per character
+func ShannonEntropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	var counts [256]int
+	for i := 0; i < len(s); i++ {
+		counts[s[i]]++
+	}
+	var entropy float64
+	n := float64(len(s))
+	for _, c := range counts {
+		if c > 0 {
+			p := float64(c) / n
+			entropy -= p * math.Log2(p)
+		}
+	}
+	return entropy
+}
+
+func containsAny(s string, keywords []string) bool {
+	for _, k := range keywords {
+		if strings.Contains(s, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// toLowerASCII lowers the ASCII letters only, so the byte offsets don't change
+func toLowerASCII(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
@@ -19,10 +19,13 @@ package setting
 
 import (
 	"fmt"
+	"math"
 	"strings"
 	"time"
 
 	"github.com/kumose/kmup/modules/log"
+
+	"github.com/dustin/go-humanize"
 )
 
 // Actions settings
@@ -34,15 +37,22 @@ var (
 		LogCompression        logCompression    `ini:"LOG_COMPRESSION"`
 		ArtifactStorage       *Storage          // how the created artifacts should be stored
 		ArtifactRetentionDays int64             `ini:"ARTIFACT_RETENTION_DAYS"`
+		CacheEnabled          bool              `ini:"CACHE_ENABLED"`
+		CacheStorage          *Storage          // how the caches of actions/cache should be stored
+		CacheRetentionDays    int64             `ini:"CACHE_RETENTION_DAYS"`
+		CacheMaxRepoSize      int64             `ini:"-"`
 		DefaultActionsURL     defaultActionsURL `ini:"DEFAULT_ACTIONS_URL"`
 		ZombieTaskTimeout     time.Duration     `ini:"ZOMBIE_TASK_TIMEOUT"`
 		EndlessTaskTimeout    time.Duration     `ini:"ENDLESS_TASK_TIMEOUT"`
 		AbandonedJobTimeout   time.Duration     `ini:"ABANDONED_JOB_TIMEOUT"`
 		SkipWorkflowStrings   []string          `ini:"SKIP_WORKFLOW_STRINGS"`
+		TestReportArtifacts   []string          `ini:"TEST_REPORT_ARTIFACTS"` // glob patterns of the names of the artifacts containing test reports
 	}{
 		Enabled:             true,
+		CacheEnabled:        true,
 		DefaultActionsURL:   defaultActionsURLGitHub,
 		SkipWorkflowStrings: []string{"[skip ci]", "[ci skip]", "[no ci]", "[skip actions]", "[actions skip]"},
+		TestReportArtifacts: []string{"test-results*", "test-reports*"},
 	}
 )
 
@@ -125,6 +135,26 @@ func loadActionsFrom(rootCfg ConfigProvider) error {
 		Actions.ArtifactRetentionDays = 90
 	}
 
+	cacheSec, _ := rootCfg.GetSection("actions.cache")
+
+	Actions.CacheStorage, err = getStorage(rootCfg, "actions_cache", "", cacheSec)
+	if err != nil {
+		return err
+	}
+
+	// default to 7 days and 10GB per repository in Github Actions
+	if Actions.CacheRetentionDays <= 0 {
+		Actions.CacheRetentionDays = 7
+	}
+	Actions.CacheMaxRepoSize = 10 * 1024 * 1024 * 1024
+	if v := sec.Key("CACHE_MAX_REPO_SIZE").String(); v != "" {
+		size, err := humanize.ParseBytes(v)
+		if err != nil || size > math.MaxInt64 {
+			return fmt.Errorf("invalid [actions] CACHE_MAX_REPO_SIZE: %q", v)
+		}
+		Actions.CacheMaxRepoSize = int64(size)
+	}
+
 	Actions.ZombieTaskTimeout = sec.Key("ZOMBIE_TASK_TIMEOUT").MustDuration(10 * time.Minute)
 	Actions.EndlessTaskTimeout = sec.Key("ENDLESS_TASK_TIMEOUT").MustDuration(3 * time.Hour)
 	Actions.AbandonedJobTimeout = sec.Key("ABANDONED_JOB_TIMEOUT").MustDuration(24 * time.Hour)
@@ -193,3 +193,40 @@ DEFAULT_ACTIONS_URL = kmup
 		})
 	}
 }
+
+func Test_loadActionsCacheFrom(t *testing.T) {
+	cfg, err := NewConfigProviderFromData(`
+[storage]
+STORAGE_TYPE = minio
+`)
+	require.NoError(t, err)
+	require.NoError(t, loadActionsFrom(cfg))
+	assert.True(t, Actions.CacheEnabled)
+	assert.EqualValues(t, "minio", Actions.CacheStorage.Type)
+	assert.Equal(t, "actions_cache/", Actions.CacheStorage.MinioConfig.BasePath)
+	assert.EqualValues(t, 7, Actions.CacheRetentionDays)
+	assert.EqualValues(t, 10*1024*1024*1024, Actions.CacheMaxRepoSize)
+
+	cfg, err = NewConfigProviderFromData(`
+[actions]
+CACHE_RETENTION_DAYS = 30
+CACHE_MAX_REPO_SIZE = 1 GiB
+
+[actions.cache]
+STORAGE_TYPE = local
+PATH = /tmp/kmup_actions_cache
+`)
+	require.NoError(t, err)
+	require.NoError(t, loadActionsFrom(cfg))
+	assert.EqualValues(t, "local", Actions.CacheStorage.Type)
+	assert.Equal(t, "/tmp/kmup_actions_cache", Actions.CacheStorage.Path)
+	assert.EqualValues(t, 30, Actions.CacheRetentionDays)
+	assert.EqualValues(t, 1024*1024*1024, Actions.CacheMaxRepoSize)
+
+	cfg, err = NewConfigProviderFromData(`
+[actions]
+CACHE_MAX_REPO_SIZE = many
+`)
+	require.NoError(t, err)
+	assert.Error(t, loadActionsFrom(cfg))
+}
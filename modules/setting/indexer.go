@@ -46,6 +46,13 @@ var Indexer = struct {
 	ExcludePatterns      []*GlobMatcher
 	ExcludeVendored      bool
 
+	ActionsLogIndexerEnabled bool
+	ActionsLogType           string
+	ActionsLogPath           string
+	ActionsLogConnStr        string
+	ActionsLogConnAuth       string
+	ActionsLogIndexerName    string
+
 	TypeBleveMaxFuzzniess int
 }{
 	IssueType:        "bleve",
@@ -62,6 +69,13 @@ var Indexer = struct {
 	RepoIndexerName:      "kmup_codes",
 	MaxIndexerFileSize:   1024 * 1024,
 	ExcludeVendored:      true,
+
+	ActionsLogIndexerEnabled: false,
+	ActionsLogType:           "bleve",
+	ActionsLogPath:           "indexers/actions_logs.bleve",
+	ActionsLogConnStr:        "",
+	ActionsLogConnAuth:       "",
+	ActionsLogIndexerName:    "kmup_actions_logs",
 }
 
 func loadIndexerFrom(rootCfg ConfigProvider) {
@@ -102,6 +116,31 @@ func loadIndexerFrom(rootCfg ConfigProvider) {
 	Indexer.IncludePatterns = IndexerGlobFromString(sec.Key("REPO_INDEXER_INCLUDE").MustString(""))
 	Indexer.ExcludePatterns = IndexerGlobFromString(sec.Key("REPO_INDEXER_EXCLUDE").MustString(""))
 	Indexer.ExcludeVendored = sec.Key("REPO_INDEXER_EXCLUDE_VENDORED").MustBool(true)
+	Indexer.ActionsLogIndexerEnabled = sec.Key("ACTIONS_LOG_INDEXER_ENABLED").MustBool(false)
+	Indexer.ActionsLogType = sec.Key("ACTIONS_LOG_INDEXER_TYPE").MustString("bleve")
+	if Indexer.ActionsLogType == "bleve" {
+		Indexer.ActionsLogPath = filepath.ToSlash(sec.Key("ACTIONS_LOG_INDEXER_PATH").MustString(filepath.ToSlash(filepath.Join(AppDataPath, "indexers/actions_logs.bleve"))))
+		if !filepath.IsAbs(Indexer.ActionsLogPath) {
+			Indexer.ActionsLogPath = filepath.ToSlash(filepath.Join(AppWorkPath, Indexer.ActionsLogPath))
+		}
+		if Indexer.ActionsLogIndexerEnabled {
+			checkOverlappedPath("[indexer].ACTIONS_LOG_INDEXER_PATH", Indexer.ActionsLogPath)
+		}
+	} else {
+		Indexer.ActionsLogConnStr = sec.Key("ACTIONS_LOG_INDEXER_CONN_STR").MustString("")
+		if Indexer.ActionsLogType == "meilisearch" {
+			u, err := url.Parse(Indexer.ActionsLogConnStr)
+			if err != nil {
+				log.Warn("Failed to parse ACTIONS_LOG_INDEXER_CONN_STR: %v", err)
+				u = &url.URL{}
+			}
+			Indexer.ActionsLogConnAuth, _ = u.User.Password()
+			u.User = nil
+			Indexer.ActionsLogConnStr = u.String()
+		}
+	}
+	Indexer.ActionsLogIndexerName = sec.Key("ACTIONS_LOG_INDEXER_NAME").MustString("kmup_actions_logs")
+
 	Indexer.MaxIndexerFileSize = sec.Key("MAX_FILE_SIZE").MustInt64(1024 * 1024)
 	Indexer.StartupTimeout = sec.Key("STARTUP_TIMEOUT").MustDuration(30 * time.Second)
 	Indexer.TypeBleveMaxFuzzniess = sec.Key("TYPE_BLEVE_MAX_FUZZINESS").MustInt(0)
@@ -20,6 +20,7 @@ package setting
 import (
 	"fmt"
 	"math"
+	"path/filepath"
 
 	"github.com/dustin/go-humanize"
 )
@@ -30,32 +31,44 @@ var (
 		Storage *Storage
 		Enabled bool
 
-		LimitTotalOwnerCount int64
-		LimitTotalOwnerSize  int64
-		LimitSizeAlpine      int64
-		LimitSizeArch        int64
-		LimitSizeCargo       int64
-		LimitSizeChef        int64
-		LimitSizeComposer    int64
-		LimitSizeConan       int64
-		LimitSizeConda       int64
-		LimitSizeContainer   int64
-		LimitSizeCran        int64
-		LimitSizeDebian      int64
-		LimitSizeGeneric     int64
-		LimitSizeGo          int64
-		LimitSizeHelm        int64
-		LimitSizeMaven       int64
-		LimitSizeNpm         int64
-		LimitSizeNuGet       int64
-		LimitSizePub         int64
-		LimitSizePyPI        int64
-		LimitSizeRpm         int64
-		LimitSizeRubyGems    int64
-		LimitSizeSwift       int64
-		LimitSizeVagrant     int64
+		LimitTotalOwnerCount       int64
+		LimitTotalOwnerSize        int64
+		LimitSizeAlpine            int64
+		LimitSizeArch              int64
+		LimitSizeCargo             int64
+		LimitSizeChef              int64
+		LimitSizeComposer          int64
+		LimitSizeConan             int64
+		LimitSizeConda             int64
+		LimitSizeContainer         int64
+		LimitSizeCran              int64
+		LimitSizeDebian            int64
+		LimitSizeGeneric           int64
+		LimitSizeGo                int64
+		LimitSizeHelm              int64
+		LimitSizeHex               int64
+		LimitSizeMaven             int64
+		LimitSizeNpm               int64
+		LimitSizeNuGet             int64
+		LimitSizePub               int64
+		LimitSizePyPI              int64
+		LimitSizeRpm               int64
+		LimitSizeRubyGems          int64
+		LimitSizeSwift             int64
+		LimitSizeTerraformModule   int64
+		LimitSizeTerraformProvider int64
+		LimitSizeTerraformState    int64
+		LimitSizeVagrant           int64
 
 		DefaultRPMSignEnabled bool
+
+		RemoteAllowedHostList string
+
+		// SigstoreRootsFile is a PEM file with the certificate authorities trusted to issue keyless signing certificates
+		SigstoreRootsFile string
+
+		// AdvisoryDatabasePath is a directory, JSON or zip file with advisories in the OSV format
+		AdvisoryDatabasePath string
 	}{
 		Enabled:              true,
 		LimitTotalOwnerCount: -1,
@@ -92,6 +105,7 @@ func loadPackagesFrom(rootCfg ConfigProvider) (err error) {
 	Packages.LimitSizeGeneric = mustBytes(sec, "LIMIT_SIZE_GENERIC")
 	Packages.LimitSizeGo = mustBytes(sec, "LIMIT_SIZE_GO")
 	Packages.LimitSizeHelm = mustBytes(sec, "LIMIT_SIZE_HELM")
+	Packages.LimitSizeHex = mustBytes(sec, "LIMIT_SIZE_HEX")
 	Packages.LimitSizeMaven = mustBytes(sec, "LIMIT_SIZE_MAVEN")
 	Packages.LimitSizeNpm = mustBytes(sec, "LIMIT_SIZE_NPM")
 	Packages.LimitSizeNuGet = mustBytes(sec, "LIMIT_SIZE_NUGET")
@@ -100,8 +114,17 @@ func loadPackagesFrom(rootCfg ConfigProvider) (err error) {
 	Packages.LimitSizeRpm = mustBytes(sec, "LIMIT_SIZE_RPM")
 	Packages.LimitSizeRubyGems = mustBytes(sec, "LIMIT_SIZE_RUBYGEMS")
 	Packages.LimitSizeSwift = mustBytes(sec, "LIMIT_SIZE_SWIFT")
+	Packages.LimitSizeTerraformModule = mustBytes(sec, "LIMIT_SIZE_TERRAFORM_MODULE")
+	Packages.LimitSizeTerraformProvider = mustBytes(sec, "LIMIT_SIZE_TERRAFORM_PROVIDER")
+	Packages.LimitSizeTerraformState = mustBytes(sec, "LIMIT_SIZE_TERRAFORM_STATE")
 	Packages.LimitSizeVagrant = mustBytes(sec, "LIMIT_SIZE_VAGRANT")
 	Packages.DefaultRPMSignEnabled = sec.Key("DEFAULT_RPM_SIGN_ENABLED").MustBool(false)
+	Packages.RemoteAllowedHostList = sec.Key("REMOTE_ALLOWED_HOST_LIST").MustString("")
+	Packages.SigstoreRootsFile = sec.Key("SIGSTORE_ROOTS_FILE").MustString("")
+	Packages.AdvisoryDatabasePath = sec.Key("ADVISORY_DATABASE_PATH").MustString("")
+	if Packages.AdvisoryDatabasePath != "" && !filepath.IsAbs(Packages.AdvisoryDatabasePath) {
+		Packages.AdvisoryDatabasePath = filepath.Join(AppDataPath, Packages.AdvisoryDatabasePath)
+	}
 	return nil
 }
 
@@ -0,0 +1,57 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package setting
+
+import (
+	"path/filepath"
+
+	"github.com/kumose/kmup/modules/log"
+)
+
+// SecretScanning settings
+var SecretScanning = struct {
+	Enabled bool
+	// RulesPath is the path of a JSON file with additional rules, it's reloaded when it changes
+	RulesPath     string
+	DisabledRules []string
+	// DefaultPushProtection is used by the repositories which don't choose a push protection mode, one of "off", "warn" or "block"
+	DefaultPushProtection string
+	MaxFileSize           int64
+}{
+	Enabled:               true,
+	DefaultPushProtection: "warn",
+	MaxFileSize:           1024 * 1024,
+}
+
+func loadSecretScanningFrom(rootCfg ConfigProvider) {
+	sec := rootCfg.Section("secret_scanning")
+	if err := sec.MapTo(&SecretScanning); err != nil {
+		log.Fatal("Failed to map SecretScanning settings: %v", err)
+	}
+
+	if SecretScanning.RulesPath != "" && !filepath.IsAbs(SecretScanning.RulesPath) {
+		SecretScanning.RulesPath = filepath.Join(CustomPath, SecretScanning.RulesPath)
+	}
+
+	switch SecretScanning.DefaultPushProtection {
+	case "off", "warn", "block":
+	default:
+		log.Warn("Invalid [secret_scanning].DEFAULT_PUSH_PROTECTION %q, set to warn", SecretScanning.DefaultPushProtection)
+		SecretScanning.DefaultPushProtection = "warn"
+	}
+}
@@ -160,6 +160,7 @@ func loadCommonSettingsFrom(cfg ConfigProvider) error {
 	loadI18nFrom(cfg)
 	loadGitFrom(cfg)
 	loadMirrorFrom(cfg)
+	loadSecretScanningFrom(cfg)
 	loadMarkupFrom(cfg)
 	loadGlobalLockFrom(cfg)
 	loadOtherFrom(cfg)
@@ -539,6 +539,10 @@ const (
 	HookPackageCreated HookPackageAction = "created"
 	// HookPackageDeleted deleted
 	HookPackageDeleted HookPackageAction = "deleted"
+	// HookPackageVulnerable new advisories match the package
+	HookPackageVulnerable HookPackageAction = "vulnerable"
+	// HookPackageCleanedUp a cleanup rule removed versions of the owner's packages
+	HookPackageCleanedUp HookPackageAction = "cleaned_up"
 )
 
 // PackagePayload represents a package payload
@@ -553,6 +557,10 @@ type PackagePayload struct {
 	Organization *Organization `json:"organization"`
 	// The user who performed the action
 	Sender *User `json:"sender"`
+	// The advisories which newly match the package
+	Vulnerabilities []*PackageVulnerability `json:"vulnerabilities,omitempty"`
+	// The summary of the cleanup rule execution, the package is empty in this case
+	CleanupRun *PackageCleanupRun `json:"cleanup_run,omitempty"`
 }
 
 // JSONPayload implements Payload
@@ -656,3 +664,75 @@ type WorkflowJobPayload struct {
 func (p *WorkflowJobPayload) JSONPayload() ([]byte, error) {
 	return json.MarshalIndent(p, "", "  ")
 }
+
+// HookMergeGroupAction an action that happens to a merge group
+type HookMergeGroupAction string
+
+const (
+	// HookMergeGroupChecksRequested a merge group was built and its status checks should run
+	HookMergeGroupChecksRequested HookMergeGroupAction = "checks_requested"
+	// HookMergeGroupDestroyed a merge group was merged or removed from the merge queue
+	HookMergeGroupDestroyed HookMergeGroupAction = "destroyed"
+)
+
+// MergeGroup represents a temporary branch of a merge queue which stacks a pull request on top of the queued pull requests before it
+type MergeGroup struct {
+	// The SHA of the merge group commit
+	HeadSHA string `json:"head_sha"`
+	// The full ref of the merge group branch
+	HeadRef string `json:"head_ref"`
+	// The SHA of the parent commit of the merge group
+	BaseSHA string `json:"base_sha"`
+	// The full ref of the target branch
+	BaseRef string `json:"base_ref"`
+	// The pull request which is tested by the merge group
+	PullRequest *PullRequest `json:"pull_request"`
+}
+
+// MergeGroupPayload represents a payload information of merge group event.
+type MergeGroupPayload struct {
+	// The action performed on the merge group
+	Action HookMergeGroupAction `json:"action"`
+	// The reason why the merge group was destroyed, one of "merged", "invalidated" or "dequeued"
+	Reason string `json:"reason,omitempty"`
+	// The merge group that was acted upon
+	MergeGroup *MergeGroup `json:"merge_group"`
+	// The repository containing the merge queue
+	Repository *Repository `json:"repository"`
+	// The user who added the pull request to the merge queue
+	Sender *User `json:"sender"`
+}
+
+// JSONPayload implements Payload
+func (p *MergeGroupPayload) JSONPayload() ([]byte, error) {
+	return json.MarshalIndent(p, "", "  ")
+}
+
+// HookSecretScanningAlertAction an action that happens to a secret scanning alert
+type HookSecretScanningAlertAction string
+
+const (
+	// HookSecretScanningAlertCreated a secret was found
+	HookSecretScanningAlertCreated HookSecretScanningAlertAction = "created"
+	// HookSecretScanningAlertResolved an alert was marked as revoked, false positive or used in tests
+	HookSecretScanningAlertResolved HookSecretScanningAlertAction = "resolved"
+	// HookSecretScanningAlertReopened a resolved alert was opened again
+	HookSecretScanningAlertReopened HookSecretScanningAlertAction = "reopened"
+)
+
+// SecretScanningAlertPayload represents a payload information of secret scanning alert event.
+type SecretScanningAlertPayload struct {
+	// The action performed on the alert
+	Action HookSecretScanningAlertAction `json:"action"`
+	// The alert that was acted upon
+	Alert *SecretScanningAlert `json:"alert"`
+	// The repository containing the secret
+	Repository *Repository `json:"repository"`
+	// The user who pushed the secret, started the scan or changed the state of the alert
+	Sender *User `json:"sender"`
+}
+
+// JSONPayload implements Payload
+func (p *SecretScanningAlertPayload) JSONPayload() ([]byte, error) {
+	return json.MarshalIndent(p, "", "  ")
+}
@@ -61,3 +61,104 @@ type PackageFile struct {
 	// The SHA512 hash of the package file
 	HashSHA512 string `json:"sha512"`
 }
+
+// PackageVerification represents the signature verification of a package version
+type PackageVerification struct {
+	// Whether the package version is signed by a trusted signer of the owner
+	Verified bool `json:"verified"`
+	// Whether the owner rejects downloads of package versions which are not signed by a trusted signer
+	SignatureRequired bool `json:"signature_required"`
+	// The signature and attestation bundles of the package version
+	Attestations []*PackageAttestation `json:"attestations"`
+}
+
+// PackageAttestation represents a Sigstore signature or attestation bundle of a package version
+type PackageAttestation struct {
+	// The unique identifier of the bundle
+	ID int64 `json:"id"`
+	// The kind of the bundle, either "signature" or "attestation"
+	Kind string `json:"kind"`
+	// The predicate type of an in-toto attestation
+	PredicateType string `json:"predicate_type,omitempty"`
+	// The names of the package files which are signed by the bundle
+	Files []string `json:"files"`
+	// Whether the bundle is signed by a trusted signer of the owner
+	Verified bool `json:"verified"`
+	// The name of the trusted signer
+	Signer string `json:"signer,omitempty"`
+	// The identity of the keyless signing certificate
+	Identity string `json:"identity,omitempty"`
+	// The OIDC issuer of the keyless signing certificate
+	Issuer string `json:"issuer,omitempty"`
+	// The reason why the verification failed
+	Error string `json:"error,omitempty"`
+}
+
+// PackageVulnerability represents an advisory which matches a package version or one of its dependencies
+type PackageVulnerability struct {
+	// The identifier of the advisory
+	AdvisoryID string `json:"advisory_id"`
+	// Other identifiers of the advisory like CVE ids
+	Aliases []string `json:"aliases"`
+	// The summary of the advisory
+	Summary string `json:"summary"`
+	// The severity of the advisory, one of "critical", "high", "moderate", "low" or empty if unknown
+	Severity string `json:"severity"`
+	// The web page of the advisory
+	URL string `json:"url,omitempty"`
+	// The name of the affected package
+	Package string `json:"package"`
+	// The affected version of the package
+	Version string `json:"version"`
+	// The declared requirement if the affected package is a dependency
+	Requirement string `json:"requirement,omitempty"`
+	// The versions which fix the advisory
+	FixedVersions []string `json:"fixed_versions"`
+	// swagger:strfmt date-time
+	PublishedAt time.Time `json:"published_at"`
+	// swagger:strfmt date-time
+	ModifiedAt time.Time `json:"modified_at"`
+	// swagger:strfmt date-time
+	FoundAt time.Time `json:"found_at"`
+}
+
+// PackageCleanupRule represents a rule which describes when to clean up package versions
+type PackageCleanupRule struct {
+	ID int64 `json:"id"`
+	// Whether the rule is executed by the cleanup task
+	Enabled bool `json:"enabled"`
+	// The package type the rule applies to
+	Type string `json:"type"`
+	// The number of most recent versions per package which are kept
+	KeepCount int `json:"keep_count"`
+	// Versions matching this pattern are kept
+	KeepPattern string `json:"keep_pattern"`
+	// Versions older than this number of days are removed
+	RemoveDays int `json:"remove_days"`
+	// Versions matching this pattern are removed
+	RemovePattern string `json:"remove_pattern"`
+	// Whether the patterns are applied to the full package name
+	MatchFullName bool `json:"match_full_name"`
+	// swagger:strfmt date-time
+	CreatedAt time.Time `json:"created_at"`
+	// swagger:strfmt date-time
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PackageCleanupRun represents a single execution of a cleanup rule
+type PackageCleanupRun struct {
+	ID     int64 `json:"id"`
+	RuleID int64 `json:"rule_id"`
+	// The package type the rule applies to
+	Type string `json:"type"`
+	// The number of removed versions
+	VersionCount int `json:"version_count"`
+	// The number of versions which could not be removed
+	FailedCount int `json:"failed_count"`
+	// The size in bytes of the files of the removed versions
+	Size int64 `json:"size"`
+	// The removed versions as "name@version"
+	Versions []string `json:"versions"`
+	// swagger:strfmt date-time
+	ExecutedAt time.Time `json:"executed_at"`
+}
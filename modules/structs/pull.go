@@ -168,6 +168,38 @@ type EditPullRequestOption struct {
 	AllowMaintainerEdit *bool `json:"allow_maintainer_edit"`
 }
 
+// MissingCodeOwnerApproval lists the code owners of a changed file which have not approved the pull request yet
+type MissingCodeOwnerApproval struct {
+	// The path of the changed file
+	Path string `json:"path"`
+	// The owning users which have not approved
+	Users []*User `json:"users"`
+	// The owning teams none of whose members have approved
+	Teams []*Team `json:"teams"`
+}
+
+// MergeQueueEntry represents a pull request in the merge queue of a branch
+type MergeQueueEntry struct {
+	// The position in the queue, 1 is the next pull request to be merged
+	Position int `json:"position"`
+	// The queued pull request
+	PullRequest *PullRequest `json:"pull_request"`
+	// The merge style which is used to merge the pull request
+	MergeStyle string `json:"merge_style"`
+	// The user who added the pull request to the queue
+	Enqueuer *User `json:"enqueuer"`
+	// The full ref of the merge group, empty if it hasn't been built yet
+	GroupRef string `json:"group_ref"`
+	// The SHA of the merge group commit
+	GroupSHA string `json:"group_sha"`
+	// The SHA of the parent commit of the merge group
+	BaseSHA string `json:"base_sha"`
+	// "queued" if the merge group hasn't been built yet, otherwise the combined state of the required checks of the merge group
+	State string `json:"state"`
+	// swagger:strfmt date-time
+	Created time.Time `json:"created_at"`
+}
+
 // ChangedFile store information about files affected by the pull request
 type ChangedFile struct {
 	// The name of the changed file
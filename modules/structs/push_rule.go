@@ -0,0 +1,53 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package structs
+
+import "time"
+
+// PushRule represents the rules which the commits pushed to a repository must follow
+type PushRule struct {
+	// The maximum size of a pushed file in bytes, 0 means no limit
+	MaxFileSize int64 `json:"max_file_size"`
+	// Glob patterns of the forbidden file paths, matched case-insensitively
+	ForbiddenPaths []string `json:"forbidden_paths"`
+	// The forbidden file extensions without the leading dot
+	ForbiddenExtensions []string `json:"forbidden_extensions"`
+	// A regular expression which the commit messages must match
+	CommitMessagePattern string `json:"commit_message_pattern"`
+	// The domains one of which the commit author emails must belong to
+	AuthorEmailDomains []string `json:"author_email_domains"`
+	// Whether merge commits are rejected
+	RejectMergeCommits bool `json:"reject_merge_commits"`
+	// Whether files which look like they contain secrets are rejected
+	RejectSecrets bool `json:"reject_secrets"`
+	// swagger:strfmt date-time
+	Created time.Time `json:"created_at"`
+	// swagger:strfmt date-time
+	Updated time.Time `json:"updated_at"`
+}
+
+// EditPushRuleOption options for setting a push rule, it replaces the existing push rule
+type EditPushRuleOption struct {
+	MaxFileSize          int64    `json:"max_file_size"`
+	ForbiddenPaths       []string `json:"forbidden_paths"`
+	ForbiddenExtensions  []string `json:"forbidden_extensions"`
+	CommitMessagePattern string   `json:"commit_message_pattern"`
+	AuthorEmailDomains   []string `json:"author_email_domains"`
+	RejectMergeCommits   bool     `json:"reject_merge_commits"`
+	RejectSecrets        bool     `json:"reject_secrets"`
+}
@@ -69,6 +69,13 @@ type CreateActionWorkflowDispatch struct {
 	Inputs map[string]string `json:"inputs,omitempty"`
 }
 
+// RerunActionRunOption represents the options to rerun the jobs of a workflow run
+// swagger:model
+type RerunActionRunOption struct {
+	// run the jobs with the debug logs of the runner and the steps enabled
+	EnableDebugLogging bool `json:"enable_debug_logging"`
+}
+
 // ActionWorkflow represents a ActionWorkflow
 type ActionWorkflow struct {
 	// ID is the unique identifier for the workflow
@@ -197,6 +204,51 @@ type ActionWorkflowJob struct {
 	CompletedAt time.Time `json:"completed_at"`
 }
 
+// ActionTaskAnnotation represents an annotation created by a workflow job
+type ActionTaskAnnotation struct {
+	Path string `json:"path"`
+	// HTML URL of the file at the commit of the job, empty if the annotation isn't bound to a file
+	BlobHref    string `json:"blob_href"`
+	StartLine   int    `json:"start_line"`
+	EndLine     int    `json:"end_line"`
+	StartColumn int    `json:"start_column"`
+	EndColumn   int    `json:"end_column"`
+	// enum: notice,warning,failure
+	AnnotationLevel string `json:"annotation_level"`
+	Title           string `json:"title"`
+	Message         string `json:"message"`
+}
+
+// ActionTestSummary represents the counts of the test results of a workflow run
+type ActionTestSummary struct {
+	Total   int64 `json:"total"`
+	Passed  int64 `json:"passed"`
+	Failed  int64 `json:"failed"`
+	Skipped int64 `json:"skipped"`
+}
+
+// ActionTestCase represents a test case reported by a workflow job
+type ActionTestCase struct {
+	JobID     int64  `json:"job_id"`
+	Suite     string `json:"suite"`
+	ClassName string `json:"class_name"`
+	Name      string `json:"name"`
+	// enum: passed,failed,skipped
+	Status string `json:"status"`
+	// duration in milliseconds
+	Duration int64  `json:"duration"`
+	Message  string `json:"message"`
+	// whether the test case both passed and failed in the repository recently
+	Flaky bool `json:"flaky"`
+}
+
+// ActionTestResultsResponse returns the test results of a workflow run
+type ActionTestResultsResponse struct {
+	Summary    *ActionTestSummary `json:"summary"`
+	TestCases  []*ActionTestCase  `json:"test_cases"`
+	TotalCount int64              `json:"total_count"`
+}
+
 // ActionRunnerLabel represents a Runner Label
 type ActionRunnerLabel struct {
 	ID   int64  `json:"id"`
@@ -212,6 +264,41 @@ type ActionRunner struct {
 	Busy      bool                 `json:"busy"`
 	Ephemeral bool                 `json:"ephemeral"`
 	Labels    []*ActionRunnerLabel `json:"labels"`
+	// id of the runner group of a global runner, 0 if the runner isn't in a group
+	RunnerGroupID int64 `json:"runner_group_id"`
+}
+
+// ActionRunnerGroup represents a group of global runners which can only run the jobs of the allowed owners, repositories and workflows
+type ActionRunnerGroup struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// names of the orgs or users whose repositories can use the runners, all repositories can use them if both allowed_owners and allowed_repos are empty
+	AllowedOwners []string `json:"allowed_owners"`
+	// full names of the repositories which can use the runners
+	AllowedRepos []string `json:"allowed_repos"`
+	// workflow files whose jobs can run on the runners, qualified by the repositories and optionally the refs, like "org/deploy/.kmup/workflows/deploy.yml@main", all workflows if empty
+	AllowedWorkflows []string `json:"allowed_workflows"`
+	RunnersCount     int64    `json:"runners_count"`
+}
+
+// CreateActionRunnerGroupOption options for creating a runner group
+type CreateActionRunnerGroupOption struct {
+	// required: true
+	Name             string   `json:"name" binding:"Required;MaxSize(255)"`
+	Description      string   `json:"description"`
+	AllowedOwners    []string `json:"allowed_owners"`
+	AllowedRepos     []string `json:"allowed_repos"`
+	AllowedWorkflows []string `json:"allowed_workflows"`
+}
+
+// EditActionRunnerGroupOption options for editing a runner group, the omitted fields are unchanged
+type EditActionRunnerGroupOption struct {
+	Name             *string  `json:"name" binding:"MaxSize(255)"`
+	Description      *string  `json:"description"`
+	AllowedOwners    []string `json:"allowed_owners"`
+	AllowedRepos     []string `json:"allowed_repos"`
+	AllowedWorkflows []string `json:"allowed_workflows"`
 }
 
 // ActionRunnersResponse returns Runners
@@ -65,6 +65,9 @@ type BranchProtection struct {
 	ProtectedFilePatterns         string   `json:"protected_file_patterns"`
 	UnprotectedFilePatterns       string   `json:"unprotected_file_patterns"`
 	BlockAdminMergeOverride       bool     `json:"block_admin_merge_override"`
+	RequireRequiredWorkflows      bool     `json:"require_required_workflows"`
+	RequireCodeOwnerApproval      bool     `json:"require_code_owner_approval"`
+	EnableMergeQueue              bool     `json:"enable_merge_queue"`
 	// swagger:strfmt date-time
 	Created time.Time `json:"created_at"`
 	// swagger:strfmt date-time
@@ -105,6 +108,9 @@ type CreateBranchProtectionOption struct {
 	ProtectedFilePatterns         string   `json:"protected_file_patterns"`
 	UnprotectedFilePatterns       string   `json:"unprotected_file_patterns"`
 	BlockAdminMergeOverride       bool     `json:"block_admin_merge_override"`
+	RequireRequiredWorkflows      bool     `json:"require_required_workflows"`
+	RequireCodeOwnerApproval      bool     `json:"require_code_owner_approval"`
+	EnableMergeQueue              bool     `json:"enable_merge_queue"`
 }
 
 // EditBranchProtectionOption options for editing a branch protection
@@ -138,6 +144,9 @@ type EditBranchProtectionOption struct {
 	ProtectedFilePatterns         *string  `json:"protected_file_patterns"`
 	UnprotectedFilePatterns       *string  `json:"unprotected_file_patterns"`
 	BlockAdminMergeOverride       *bool    `json:"block_admin_merge_override"`
+	RequireRequiredWorkflows      *bool    `json:"require_required_workflows"`
+	RequireCodeOwnerApproval      *bool    `json:"require_code_owner_approval"`
+	EnableMergeQueue              *bool    `json:"enable_merge_queue"`
 }
 
 // UpdateBranchProtectionPriories a list to update the branch protection rule priorities
@@ -0,0 +1,108 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package structs
+
+import "time"
+
+// Ruleset represents the protection rules which an organization applies to the branches and tags of its repositories
+type Ruleset struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	// The enforcement of the ruleset, "evaluate" only records the pushes which would have been rejected
+	// enum: active,evaluate,disabled
+	Enforcement string `json:"enforcement"`
+	// Glob patterns of the names of the repositories the ruleset applies to, empty for all the repositories
+	RepoNamePatterns []string `json:"repo_name_patterns"`
+	// The topics one of which the repositories must have, empty for all the repositories
+	RepoTopics []string `json:"repo_topics"`
+	// Glob patterns of the full ref names, like refs/heads/main or refs/tags/v*, ~DEFAULT_BRANCH matches the default branch
+	RefPatterns        []string `json:"ref_patterns"`
+	ExcludeRefPatterns []string `json:"exclude_ref_patterns"`
+	BlockCreation      bool     `json:"block_creation"`
+	BlockDeletion      bool     `json:"block_deletion"`
+	// Whether the force pushes to the branches and the moves of the tags are blocked
+	BlockForcePush        bool     `json:"block_force_push"`
+	RequirePullRequest    bool     `json:"require_pull_request"`
+	RequiredApprovals     int64    `json:"required_approvals"`
+	EnableStatusCheck     bool     `json:"enable_status_check"`
+	StatusCheckContexts   []string `json:"status_check_contexts"`
+	RequireSignedCommits  bool     `json:"require_signed_commits"`
+	ProtectedFilePatterns string   `json:"protected_file_patterns"`
+	BypassUsers           []string `json:"bypass_users"`
+	BypassTeams           []string `json:"bypass_teams"`
+	// swagger:strfmt date-time
+	Created time.Time `json:"created_at"`
+	// swagger:strfmt date-time
+	Updated time.Time `json:"updated_at"`
+}
+
+// CreateRulesetOption options for creating a ruleset
+type CreateRulesetOption struct {
+	// required: true
+	Name string `json:"name" binding:"Required;MaxSize(255)"`
+	// enum: active,evaluate,disabled
+	Enforcement           string   `json:"enforcement"`
+	RepoNamePatterns      []string `json:"repo_name_patterns"`
+	RepoTopics            []string `json:"repo_topics"`
+	RefPatterns           []string `json:"ref_patterns"`
+	ExcludeRefPatterns    []string `json:"exclude_ref_patterns"`
+	BlockCreation         bool     `json:"block_creation"`
+	BlockDeletion         bool     `json:"block_deletion"`
+	BlockForcePush        bool     `json:"block_force_push"`
+	RequirePullRequest    bool     `json:"require_pull_request"`
+	RequiredApprovals     int64    `json:"required_approvals"`
+	EnableStatusCheck     bool     `json:"enable_status_check"`
+	StatusCheckContexts   []string `json:"status_check_contexts"`
+	RequireSignedCommits  bool     `json:"require_signed_commits"`
+	ProtectedFilePatterns string   `json:"protected_file_patterns"`
+	BypassUsers           []string `json:"bypass_users"`
+	BypassTeams           []string `json:"bypass_teams"`
+}
+
+// EditRulesetOption options for editing a ruleset, the omitted fields are not changed
+type EditRulesetOption struct {
+	Name *string `json:"name" binding:"MaxSize(255)"`
+	// enum: active,evaluate,disabled
+	Enforcement           *string  `json:"enforcement"`
+	RepoNamePatterns      []string `json:"repo_name_patterns"`
+	RepoTopics            []string `json:"repo_topics"`
+	RefPatterns           []string `json:"ref_patterns"`
+	ExcludeRefPatterns    []string `json:"exclude_ref_patterns"`
+	BlockCreation         *bool    `json:"block_creation"`
+	BlockDeletion         *bool    `json:"block_deletion"`
+	BlockForcePush        *bool    `json:"block_force_push"`
+	RequirePullRequest    *bool    `json:"require_pull_request"`
+	RequiredApprovals     *int64   `json:"required_approvals"`
+	EnableStatusCheck     *bool    `json:"enable_status_check"`
+	StatusCheckContexts   []string `json:"status_check_contexts"`
+	RequireSignedCommits  *bool    `json:"require_signed_commits"`
+	ProtectedFilePatterns *string  `json:"protected_file_patterns"`
+	BypassUsers           []string `json:"bypass_users"`
+	BypassTeams           []string `json:"bypass_teams"`
+}
+
+// RulesetEvaluation represents a push which a ruleset in evaluate mode would have rejected
+type RulesetEvaluation struct {
+	ID         int64       `json:"id"`
+	Repository *Repository `json:"repository"`
+	RefName    string      `json:"ref_name"`
+	Pusher     *User       `json:"pusher"`
+	Reason     string      `json:"reason"`
+	// swagger:strfmt date-time
+	Created time.Time `json:"created_at"`
+}
@@ -0,0 +1,54 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package structs
+
+import "time"
+
+// SecretScanningAlert represents a secret found in a repository
+type SecretScanningAlert struct {
+	ID int64 `json:"id"`
+	// The ID of the rule which found the secret
+	RuleID string `json:"rule_id"`
+	// The kind of the secret
+	Description string `json:"description"`
+	// The secret with most of its characters masked
+	RedactedSecret string `json:"redacted_secret"`
+	// The commit where the secret was first found
+	CommitID string `json:"commit_id"`
+	// The file where the secret was first found
+	Path string `json:"path"`
+	// The line of the file where the secret was first found
+	Line int `json:"line"`
+	// enum: open,revoked,false_positive,used_in_tests
+	State    string `json:"state"`
+	Resolver *User  `json:"resolver"`
+	HTMLURL  string `json:"html_url"`
+	// swagger:strfmt date-time
+	Resolved *time.Time `json:"resolved_at"`
+	// swagger:strfmt date-time
+	Created time.Time `json:"created_at"`
+	// swagger:strfmt date-time
+	Updated time.Time `json:"updated_at"`
+}
+
+// EditSecretScanningAlertOption options for changing the state of a secret scanning alert
+type EditSecretScanningAlertOption struct {
+	// required: true
+	// enum: open,revoked,false_positive,used_in_tests
+	State string `json:"state" binding:"Required"`
+}
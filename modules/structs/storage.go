@@ -0,0 +1,38 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package structs
+
+// StorageUsage represents the storage used by an owner
+type StorageUsage struct {
+	// The id of the owner
+	OwnerID int64 `json:"owner_id"`
+	// The name of the owner, empty if the owner does not exist anymore
+	OwnerName string `json:"owner_name"`
+	// The size of the git data of the repositories in bytes
+	GitSize int64 `json:"git_size"`
+	// The size of the distinct LFS objects in bytes
+	LFSSize int64 `json:"lfs_size"`
+	// The size of the distinct package blobs in bytes
+	PackageSize int64 `json:"package_size"`
+	// The size of the attachments in bytes
+	AttachmentSize int64 `json:"attachment_size"`
+	// The size of the actions artifacts in bytes
+	ArtifactSize int64 `json:"artifact_size"`
+	// The total size in bytes
+	TotalSize int64 `json:"total_size"`
+}
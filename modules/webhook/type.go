@@ -46,6 +46,8 @@ const (
 	HookEventRelease                   HookEventType = "release"
 	HookEventPackage                   HookEventType = "package"
 	HookEventStatus                    HookEventType = "status"
+	HookEventMergeGroup                HookEventType = "merge_group"
+	HookEventSecretScanningAlert       HookEventType = "secret_scanning_alert"
 	// once a new event added here, please also added to AllEvents() function
 
 	// FIXME: This event should be a group of pull_request_review_xxx events
@@ -82,6 +84,8 @@ func AllEvents() []HookEventType {
 		HookEventRelease,
 		HookEventPackage,
 		HookEventStatus,
+		HookEventMergeGroup,
+		HookEventSecretScanningAlert,
 		HookEventWorkflowRun,
 		HookEventWorkflowJob,
 	}
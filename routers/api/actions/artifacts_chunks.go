@@ -35,6 +35,7 @@ import (
 	"github.com/kumose/kmup/models/db"
 	"github.com/kumose/kmup/modules/log"
 	"github.com/kumose/kmup/modules/storage"
+	actions_service "github.com/kumose/kmup/services/actions"
 )
 
 func saveUploadChunkBase(st storage.ObjectStorage, ctx *ArtifactContext,
@@ -189,6 +190,7 @@ func mergeChunksForRun(ctx *ArtifactContext, st storage.ObjectStorage, runID int
 	// read all db artifacts by name
 	artifacts, err := db.Find[actions.ActionArtifact](ctx, actions.FindArtifactsOptions{
 		RunID:        runID,
+		RunAttempt:   ctx.ActionTask.RunAttempt,
 		ArtifactName: artifactName,
 	})
 	if err != nil {
@@ -311,5 +313,7 @@ func mergeChunksForArtifact(ctx *ArtifactContext, chunks []*chunkFileItem, st st
 		return fmt.Errorf("update artifact error: %v", err)
 	}
 
+	actions_service.IngestTestReportArtifact(ctx, ctx.ActionTask, artifact)
+
 	return nil
 }
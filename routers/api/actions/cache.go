@@ -0,0 +1,302 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+// GitHub Actions Cache API Simple Description
+//
+// The jobs get ACTIONS_CACHE_URL=/api/actions_cache/ and authenticate with Bearer ACTIONS_RUNTIME_TOKEN.
+//
+// 1. Restore a cache
+// GET: /api/actions_cache/_apis/artifactcache/cache?keys=key1,restore-key2&version=hash
+// Response: 204 No Content if there is no matching cache, or
+// {
+//     "scope": "refs/heads/main",
+//     "cacheKey": "key1",
+//     "archiveLocation": "http://localhost:3326/api/actions_cache/download?repoID=1&cacheID=2&expires=...&sig=..."
+// }
+// 2. Save a cache
+// 2.1. Reserve the cache, 409 Conflict if the cache exists in the ref
+// POST: /api/actions_cache/_apis/artifactcache/caches
+// Request:
+// {
+//     "key": "key1",
+//     "version": "hash",
+//     "cacheSize": 1024
+// }
+// Response:
+// {
+//     "cacheId": 2
+// }
+// 2.2. Upload the chunks in any order
+// PATCH: /api/actions_cache/_apis/artifactcache/caches/2
+// Content-Range: bytes 0-1023/*
+// 2.3. Commit the cache
+// POST: /api/actions_cache/_apis/artifactcache/caches/2
+// Request:
+// {
+//     "size": 1024
+// }
+//
+// The cache service v2 in cachev2.go shares the signed upload and download URLs below.
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kumose/kmup/models/actions"
+	"github.com/kumose/kmup/modules/httplib"
+	"github.com/kumose/kmup/modules/json"
+	"github.com/kumose/kmup/modules/log"
+	"github.com/kumose/kmup/modules/setting"
+	"github.com/kumose/kmup/modules/storage"
+	"github.com/kumose/kmup/modules/util"
+	"github.com/kumose/kmup/modules/web"
+	actions_service "github.com/kumose/kmup/services/actions"
+)
+
+const cacheURLExpiresLayout = "2006-01-02 15:04:05.999999999 -0700 MST"
+
+func CacheRoutes(prefix string) *web.Router {
+	m := web.NewRouter()
+
+	r := cacheRoutes{prefix: prefix}
+
+	m.Group("/_apis/artifactcache", func() {
+		m.Get("/cache", r.findCache)
+		m.Post("/caches", r.reserveCache)
+		m.Patch("/caches/{cache_id}", r.uploadCacheChunk)
+		m.Post("/caches/{cache_id}", r.commitCache)
+	}, ArtifactContexter())
+	m.Group("", func() {
+		m.Put("/upload", r.uploadCacheBlob)
+		m.Get("/download", r.downloadCache)
+	}, ArtifactV4Contexter())
+
+	return m
+}
+
+type cacheRoutes struct {
+	prefix string
+}
+
+func buildCacheSignature(endp, expires string, repoID, cacheID int64) []byte {
+	mac := hmac.New(sha256.New, setting.GetGeneralTokenSigningSecret())
+	mac.Write([]byte("actions_cache"))
+	mac.Write([]byte(endp))
+	mac.Write([]byte(expires))
+	fmt.Fprint(mac, repoID)
+	fmt.Fprint(mac, cacheID)
+	return mac.Sum(nil)
+}
+
+// buildCacheURL returns the signed url to upload or download the cache, the runners access it without the runtime token
+func buildCacheURL(ctx *ArtifactContext, prefix, endp string, c *actions.ActionCache) string {
+	expires := time.Now().Add(60 * time.Minute).Format(cacheURLExpiresLayout)
+	return strings.TrimSuffix(httplib.GuessCurrentAppURL(ctx), "/") + strings.TrimSuffix(prefix, "/") + "/" + endp +
+		"?repoID=" + strconv.FormatInt(c.RepoID, 10) + "&cacheID=" + strconv.FormatInt(c.ID, 10) +
+		"&expires=" + url.QueryEscape(expires) +
+		"&sig=" + base64.URLEncoding.EncodeToString(buildCacheSignature(endp, expires, c.RepoID, c.ID))
+}
+
+func verifyCacheSignature(ctx *ArtifactContext, endp string) (*actions.ActionCache, bool) {
+	query := ctx.Req.URL.Query()
+	repoID, _ := strconv.ParseInt(query.Get("repoID"), 10, 64)
+	cacheID, _ := strconv.ParseInt(query.Get("cacheID"), 10, 64)
+	expires := query.Get("expires")
+	sig, _ := base64.URLEncoding.DecodeString(query.Get("sig"))
+
+	if !hmac.Equal(sig, buildCacheSignature(endp, expires, repoID, cacheID)) {
+		ctx.HTTPError(http.StatusUnauthorized, "Error unauthorized")
+		return nil, false
+	}
+	t, err := time.Parse(cacheURLExpiresLayout, expires)
+	if err != nil || t.Before(time.Now()) {
+		ctx.HTTPError(http.StatusUnauthorized, "Error link expired")
+		return nil, false
+	}
+	c, err := actions.GetCacheByID(ctx, repoID, cacheID)
+	if errors.Is(err, util.ErrNotExist) {
+		ctx.HTTPError(http.StatusNotFound, "Error cache not found")
+		return nil, false
+	} else if err != nil {
+		log.Error("Error getting cache %d: %v", cacheID, err)
+		ctx.HTTPError(http.StatusInternalServerError, "Error getting cache")
+		return nil, false
+	}
+	return c, true
+}
+
+// handleCacheError writes the response of the error returned by the cache service
+func handleCacheError(ctx *ArtifactContext, action string, err error) {
+	switch {
+	case errors.Is(err, util.ErrNotExist):
+		ctx.HTTPError(http.StatusNotFound, err.Error())
+	case errors.Is(err, util.ErrAlreadyExist):
+		ctx.HTTPError(http.StatusConflict, err.Error())
+	case errors.Is(err, util.ErrInvalidArgument):
+		ctx.HTTPError(http.StatusBadRequest, err.Error())
+	default:
+		log.Error("Error %s: %v", action, err)
+		ctx.HTTPError(http.StatusInternalServerError, "Error "+action)
+	}
+}
+
+func (r *cacheRoutes) findCache(ctx *ArtifactContext) {
+	keys := strings.Split(ctx.Req.URL.Query().Get("keys"), ",")
+	for i := range keys {
+		keys[i] = strings.TrimSpace(keys[i])
+	}
+	c, err := actions_service.RestoreCache(ctx, ctx.ActionTask, keys, ctx.Req.URL.Query().Get("version"))
+	if errors.Is(err, util.ErrNotExist) {
+		ctx.Status(http.StatusNoContent)
+		return
+	} else if err != nil {
+		handleCacheError(ctx, "restoring cache", err)
+		return
+	}
+	ctx.JSON(http.StatusOK, map[string]any{
+		"scope":           c.Scope,
+		"cacheKey":        c.CacheKey,
+		"cacheVersion":    c.Version,
+		"creationTime":    c.CreatedUnix.AsTime(),
+		"archiveLocation": buildCacheURL(ctx, r.prefix, "download", c),
+	})
+}
+
+func (r *cacheRoutes) reserveCache(ctx *ArtifactContext) {
+	var req struct {
+		Key       string `json:"key"`
+		Version   string `json:"version"`
+		CacheSize int64  `json:"cacheSize"`
+	}
+	if err := json.NewDecoder(ctx.Req.Body).Decode(&req); err != nil {
+		ctx.HTTPError(http.StatusBadRequest, "Error decode request body")
+		return
+	}
+	c, err := actions_service.ReserveCache(ctx, ctx.ActionTask, req.Key, req.Version, req.CacheSize)
+	if err != nil {
+		handleCacheError(ctx, "reserving cache", err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, map[string]int64{"cacheId": c.ID})
+}
+
+func (r *cacheRoutes) uploadCacheChunk(ctx *ArtifactContext) {
+	c, err := actions_service.GetCacheToUpload(ctx, ctx.ActionTask, ctx.PathParamInt64("cache_id"))
+	if err != nil {
+		handleCacheError(ctx, "getting cache", err)
+		return
+	}
+	// Content-Range: bytes 0-1023/*
+	var start, end int64
+	if _, err := fmt.Sscanf(ctx.Req.Header.Get("Content-Range"), "bytes %d-%d/", &start, &end); err != nil || end < start {
+		ctx.HTTPError(http.StatusBadRequest, "Error invalid content range")
+		return
+	}
+	if err := actions_service.UploadCacheChunk(c, start, ctx.Req.Body, end-start+1); err != nil {
+		handleCacheError(ctx, "uploading cache chunk", err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+func (r *cacheRoutes) commitCache(ctx *ArtifactContext) {
+	var req struct {
+		Size int64 `json:"size"`
+	}
+	if err := json.NewDecoder(ctx.Req.Body).Decode(&req); err != nil {
+		ctx.HTTPError(http.StatusBadRequest, "Error decode request body")
+		return
+	}
+	c, err := actions_service.GetCacheToUpload(ctx, ctx.ActionTask, ctx.PathParamInt64("cache_id"))
+	if err != nil {
+		handleCacheError(ctx, "getting cache", err)
+		return
+	}
+	if err := actions_service.CommitCache(ctx, c, req.Size); err != nil {
+		handleCacheError(ctx, "committing cache", err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// uploadCacheBlob implements the block blob API of Azure Blob Storage used by the cache service v2:
+// "Put Blob" without comp uploads the whole cache, "Put Block" with comp=block uploads a block and "Put Block List" with comp=blocklist orders them.
+func (r *cacheRoutes) uploadCacheBlob(ctx *ArtifactContext) {
+	c, ok := verifyCacheSignature(ctx, "upload")
+	if !ok {
+		return
+	}
+	if c.Complete {
+		ctx.HTTPError(http.StatusConflict, "Error cache has been committed")
+		return
+	}
+
+	var err error
+	switch ctx.Req.URL.Query().Get("comp") {
+	case "":
+		err = actions_service.UploadCacheChunk(c, 0, ctx.Req.Body, ctx.Req.ContentLength)
+	case "block":
+		err = actions_service.UploadCacheBlock(c, ctx.Req.URL.Query().Get("blockid"), ctx.Req.Body, ctx.Req.ContentLength)
+	case "blocklist":
+		var blockList BlockList
+		if err := xml.NewDecoder(ctx.Req.Body).Decode(&blockList); err != nil {
+			ctx.HTTPError(http.StatusBadRequest, "Error decode block list")
+			return
+		}
+		err = actions_service.UploadCacheBlockList(c, blockList.Latest)
+	default:
+		ctx.HTTPError(http.StatusBadRequest, "Error unsupported comp")
+		return
+	}
+	if err != nil {
+		handleCacheError(ctx, "uploading cache", err)
+		return
+	}
+	ctx.Status(http.StatusCreated)
+}
+
+func (r *cacheRoutes) downloadCache(ctx *ArtifactContext) {
+	c, ok := verifyCacheSignature(ctx, "download")
+	if !ok {
+		return
+	}
+	if !c.Complete {
+		ctx.HTTPError(http.StatusNotFound, "Error cache not found")
+		return
+	}
+	obj, err := storage.ActionsCache.Open(c.StoragePath())
+	if err != nil {
+		log.Error("Error opening cache %d: %v", c.ID, err)
+		ctx.HTTPError(http.StatusInternalServerError, "Error opening cache")
+		return
+	}
+	defer obj.Close()
+
+	ctx.Resp.Header().Set("Content-Type", "application/octet-stream")
+	http.ServeContent(ctx.Resp, ctx.Req, "cache", c.CreatedUnix.AsTime(), obj)
+}
@@ -0,0 +1,206 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+// GitHub Actions Cache Service V2 Simple Description
+//
+// It is used by actions/cache v4 when ACTIONS_CACHE_SERVICE_V2 is set, the requests and responses use the proto field names.
+//
+// 1. Restore a cache
+// Post: /twirp/github.actions.results.api.v1.CacheService/GetCacheEntryDownloadURL
+// Request:
+// {
+//     "key": "key1",
+//     "restore_keys": ["restore-key2"],
+//     "version": "hash"
+// }
+// Response, "ok" is false if there is no matching cache:
+// {
+//     "ok": true,
+//     "signed_download_url": "http://localhost:3326/api/actions_cache/download?repoID=1&cacheID=2&expires=...&sig=...",
+//     "matched_key": "key1"
+// }
+// 2. Save a cache
+// 2.1. CreateCacheEntry, "ok" is false if the cache exists in the ref
+// Post: /twirp/github.actions.results.api.v1.CacheService/CreateCacheEntry
+// Request:
+// {
+//     "key": "key1",
+//     "version": "hash"
+// }
+// Response:
+// {
+//     "ok": true,
+//     "signed_upload_url": "http://localhost:3326/api/actions_cache/upload?repoID=1&cacheID=2&expires=...&sig=..."
+// }
+// 2.2. Upload the cache to the signed url with the block blob API of Azure Blob Storage (unauthenticated request)
+// 2.3. FinalizeCacheEntryUpload
+// Post: /twirp/github.actions.results.api.v1.CacheService/FinalizeCacheEntryUpload
+// Request:
+// {
+//     "key": "key1",
+//     "size_bytes": "1024",
+//     "version": "hash"
+// }
+// Response:
+// {
+//     "ok": true,
+//     "entry_id": "2"
+// }
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/kumose/kmup/modules/json"
+	"github.com/kumose/kmup/modules/log"
+	"github.com/kumose/kmup/modules/util"
+	"github.com/kumose/kmup/modules/web"
+	actions_service "github.com/kumose/kmup/services/actions"
+)
+
+const CacheV2RouteBase = "/twirp/github.actions.results.api.v1.CacheService"
+
+// CacheV2Routes returns the routes of the cache service v2, the signed urls point to the routes of CacheRoutes with the prefix
+func CacheV2Routes(prefix string) *web.Router {
+	m := web.NewRouter()
+
+	r := cacheV2Routes{prefix: prefix}
+
+	m.Group("", func() {
+		m.Post("CreateCacheEntry", r.createCacheEntry)
+		m.Post("FinalizeCacheEntryUpload", r.finalizeCacheEntryUpload)
+		m.Post("GetCacheEntryDownloadURL", r.getCacheEntryDownloadURL)
+	}, ArtifactContexter())
+
+	return m
+}
+
+type cacheV2Routes struct {
+	prefix string
+}
+
+// twirpInt64 is an int64 field of protojson, it is encoded as a string but a number is accepted too
+type twirpInt64 int64
+
+func (v *twirpInt64) UnmarshalJSON(b []byte) error {
+	i, err := strconv.ParseInt(strings.Trim(string(b), `"`), 10, 64)
+	*v = twirpInt64(i)
+	return err
+}
+
+func (v twirpInt64) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + strconv.FormatInt(int64(v), 10) + `"`), nil
+}
+
+func (r *cacheV2Routes) parseBody(ctx *ArtifactContext, req any) bool {
+	if err := json.NewDecoder(ctx.Req.Body).Decode(req); err != nil {
+		r.sendError(ctx, http.StatusBadRequest, "malformed", "Error decode request body")
+		return false
+	}
+	return true
+}
+
+// sendError writes a twirp error
+func (r *cacheV2Routes) sendError(ctx *ArtifactContext, status int, code, msg string) {
+	ctx.JSON(status, map[string]string{"code": code, "msg": msg})
+}
+
+func (r *cacheV2Routes) handleError(ctx *ArtifactContext, action string, err error) {
+	switch {
+	case errors.Is(err, util.ErrNotExist):
+		r.sendError(ctx, http.StatusNotFound, "not_found", err.Error())
+	case errors.Is(err, util.ErrInvalidArgument):
+		r.sendError(ctx, http.StatusBadRequest, "invalid_argument", err.Error())
+	default:
+		log.Error("Error %s: %v", action, err)
+		r.sendError(ctx, http.StatusInternalServerError, "internal", "Error "+action)
+	}
+}
+
+func (r *cacheV2Routes) createCacheEntry(ctx *ArtifactContext) {
+	var req struct {
+		Key     string `json:"key"`
+		Version string `json:"version"`
+	}
+	if !r.parseBody(ctx, &req) {
+		return
+	}
+	c, err := actions_service.ReserveCache(ctx, ctx.ActionTask, req.Key, req.Version, 0)
+	if errors.Is(err, util.ErrAlreadyExist) {
+		ctx.JSON(http.StatusOK, map[string]any{"ok": false, "message": err.Error()})
+		return
+	} else if err != nil {
+		r.handleError(ctx, "reserving cache", err)
+		return
+	}
+	ctx.JSON(http.StatusOK, map[string]any{
+		"ok":                true,
+		"signed_upload_url": buildCacheURL(ctx, r.prefix, "upload", c),
+	})
+}
+
+func (r *cacheV2Routes) finalizeCacheEntryUpload(ctx *ArtifactContext) {
+	var req struct {
+		Key       string     `json:"key"`
+		SizeBytes twirpInt64 `json:"size_bytes"`
+		Version   string     `json:"version"`
+	}
+	if !r.parseBody(ctx, &req) {
+		return
+	}
+	c, err := actions_service.FindCacheToUpload(ctx, ctx.ActionTask, req.Key, req.Version)
+	if err != nil {
+		r.handleError(ctx, "getting cache", err)
+		return
+	}
+	if err := actions_service.CommitCache(ctx, c, int64(req.SizeBytes)); err != nil {
+		r.handleError(ctx, "committing cache", err)
+		return
+	}
+	ctx.JSON(http.StatusOK, map[string]any{
+		"ok":       true,
+		"entry_id": twirpInt64(c.ID),
+	})
+}
+
+func (r *cacheV2Routes) getCacheEntryDownloadURL(ctx *ArtifactContext) {
+	var req struct {
+		Key         string   `json:"key"`
+		RestoreKeys []string `json:"restore_keys"`
+		Version     string   `json:"version"`
+	}
+	if !r.parseBody(ctx, &req) {
+		return
+	}
+	c, err := actions_service.RestoreCache(ctx, ctx.ActionTask, append([]string{req.Key}, req.RestoreKeys...), req.Version)
+	if errors.Is(err, util.ErrNotExist) {
+		ctx.JSON(http.StatusOK, map[string]any{"ok": false})
+		return
+	} else if err != nil {
+		r.handleError(ctx, "restoring cache", err)
+		return
+	}
+	ctx.JSON(http.StatusOK, map[string]any{
+		"ok":                  true,
+		"signed_download_url": buildCacheURL(ctx, r.prefix, "download", c),
+		"matched_key":         c.CacheKey,
+	})
+}
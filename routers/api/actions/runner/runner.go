@@ -26,6 +26,7 @@ import (
 	repo_model "github.com/kumose/kmup/models/repo"
 	user_model "github.com/kumose/kmup/models/user"
 	"github.com/kumose/kmup/modules/actions"
+	actions_indexer "github.com/kumose/kmup/modules/indexer/actions"
 	"github.com/kumose/kmup/modules/log"
 	"github.com/kumose/kmup/modules/util"
 	actions_service "github.com/kumose/kmup/services/actions"
@@ -235,6 +236,7 @@ func (s *Service) UpdateTask(
 
 	if task.Status.IsDone() {
 		notify_service.WorkflowJobStatusUpdate(ctx, task.Job.Run.Repo, task.Job.Run.TriggerUser, task.Job, task)
+		actions_indexer.UpdateTaskIndexer(task.ID)
 	}
 
 	if req.Msg.State.Result != runnerv1.Result_RESULT_UNSPECIFIED {
@@ -308,7 +310,10 @@ func (s *Service) UpdateLog(
 	}
 	if remove != nil {
 		remove()
+		actions_indexer.UpdateTaskIndexer(task.ID)
 	}
 
+	actions_service.HandleWorkflowCommands(ctx, task, ack, rows)
+
 	return res, nil
 }
@@ -0,0 +1,59 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/kumose/kmup/modules/log"
+	"github.com/kumose/kmup/modules/util"
+	"github.com/kumose/kmup/modules/web"
+	actions_service "github.com/kumose/kmup/services/actions"
+)
+
+// TestReportRoutes serves the endpoint for the jobs to upload JUnit XML or TRX test reports,
+// the results are shown on the run page and the pull request, see actions_service.TestReportRouteBase.
+func TestReportRoutes() *web.Router {
+	m := web.NewRouter()
+	m.Post("/upload", ArtifactContexter(), uploadTestReport)
+	return m
+}
+
+func uploadTestReport(ctx *ArtifactContext) {
+	name := ctx.Req.URL.Query().Get("name")
+	if name == "" {
+		ctx.HTTPError(http.StatusBadRequest, "name is required")
+		return
+	}
+	report, err := actions_service.UploadTestReport(ctx, ctx.ActionTask, name, ctx.Req.Body)
+	if errors.Is(err, util.ErrInvalidArgument) {
+		ctx.HTTPError(http.StatusBadRequest, err.Error())
+		return
+	} else if err != nil {
+		log.Error("Error uploading test report: %v", err)
+		ctx.HTTPError(http.StatusInternalServerError, "Error uploading test report")
+		return
+	}
+	ctx.JSON(http.StatusCreated, map[string]int64{
+		"total":   report.Total,
+		"passed":  report.Passed,
+		"failed":  report.Failed,
+		"skipped": report.Skipped,
+	})
+}
@@ -38,6 +38,7 @@ import (
 	"github.com/kumose/kmup/routers/api/packages/generic"
 	"github.com/kumose/kmup/routers/api/packages/goproxy"
 	"github.com/kumose/kmup/routers/api/packages/helm"
+	"github.com/kumose/kmup/routers/api/packages/hex"
 	"github.com/kumose/kmup/routers/api/packages/maven"
 	"github.com/kumose/kmup/routers/api/packages/npm"
 	"github.com/kumose/kmup/routers/api/packages/nuget"
@@ -46,6 +47,7 @@ import (
 	"github.com/kumose/kmup/routers/api/packages/rpm"
 	"github.com/kumose/kmup/routers/api/packages/rubygems"
 	"github.com/kumose/kmup/routers/api/packages/swift"
+	"github.com/kumose/kmup/routers/api/packages/terraform"
 	"github.com/kumose/kmup/routers/api/packages/vagrant"
 	"github.com/kumose/kmup/services/auth"
 	"github.com/kumose/kmup/services/context"
@@ -131,10 +133,33 @@ func CommonRoutes() *web.Router {
 		&auth.OAuth2{},
 		&auth.Basic{},
 		&nuget.Auth{},
+		&hex.Auth{},
 		&conan.Auth{},
 		&chef.Auth{},
 	})
 
+	// "-" is not a valid username, the Terraform registry protocols need the namespace after a fixed base path
+	r.Group("/-/terraform", func() {
+		r.Group("/modules/v1/{username}/{name}/{system}", func() {
+			r.Get("/versions", terraform.EnumerateModuleVersions)
+			r.Group("/{version}", func() {
+				r.Get("/download", terraform.DownloadModule)
+				r.Get("/archive.tar.gz", terraform.DownloadModuleArchive)
+				r.Put("", reqPackageAccess(perm.AccessModeWrite), terraform.UploadModule)
+				r.Delete("", reqPackageAccess(perm.AccessModeWrite), terraform.DeleteModuleVersion)
+			})
+		}, context.UserAssignmentWeb(), context.PackageAssignment(), reqPackageAccess(perm.AccessModeRead))
+		r.Group("/providers/v1/{username}/{provider}", func() {
+			r.Get("/versions", terraform.EnumerateProviderVersions)
+			r.Group("/{version}", func() {
+				r.Get("/download/{os}/{arch}", terraform.DownloadProviderPlatform)
+				r.Get("/files/{filename}", terraform.DownloadProviderFile)
+				r.Put("/files/{filename}", reqPackageAccess(perm.AccessModeWrite), terraform.UploadProviderFile)
+				r.Delete("", reqPackageAccess(perm.AccessModeWrite), terraform.DeleteProviderVersion)
+			})
+		}, context.UserAssignmentWeb(), context.PackageAssignment(), reqPackageAccess(perm.AccessModeRead))
+	})
+
 	r.Group("/{username}", func() {
 		r.Group("/alpine", func() {
 			r.Get("/key", alpine.GetRepositoryKey)
@@ -366,6 +391,20 @@ func CommonRoutes() *web.Router {
 			r.Get("/{filename}", helm.DownloadPackageFile)
 			r.Post("/api/charts", reqPackageAccess(perm.AccessModeWrite), helm.UploadPackage)
 		}, reqPackageAccess(perm.AccessModeRead))
+		r.Group("/hex", func() {
+			r.Get("/public_key", hex.GetPublicKey)
+			r.Get("/names", hex.GetNames)
+			r.Get("/versions", hex.GetVersions)
+			r.Get("/packages/{name}", hex.GetPackage)
+			r.Get("/tarballs/{filename}", hex.DownloadTarball)
+			r.Group("/api", func() {
+				r.Post("/publish", hex.UploadPackage)
+				r.Group("/packages/{name}/releases", func() {
+					r.Post("", hex.UploadPackage)
+					r.Delete("/{version}", hex.DeletePackageVersion)
+				})
+			}, reqPackageAccess(perm.AccessModeWrite))
+		}, reqPackageAccess(perm.AccessModeRead))
 		r.Group("/maven", func() {
 			r.Put("/*", reqPackageAccess(perm.AccessModeWrite), maven.UploadPackageFile)
 			r.Get("/*", maven.DownloadPackageFile)
@@ -471,7 +510,12 @@ func CommonRoutes() *web.Router {
 		r.Group("/pypi", func() {
 			r.Post("/", reqPackageAccess(perm.AccessModeWrite), pypi.UploadPackageFile)
 			r.Get("/files/{id}/{version}/{filename}", pypi.DownloadPackageFile)
+			r.Get("/simple", pypi.ProjectIndex)
 			r.Get("/simple/{id}", pypi.PackageMetadata)
+			r.Group("/yank/{id}/{version}", func() {
+				r.Post("", pypi.YankPackage)
+				r.Delete("", pypi.UnyankPackage)
+			}, reqPackageAccess(perm.AccessModeWrite))
 		}, reqPackageAccess(perm.AccessModeRead))
 
 		r.Methods("HEAD,GET", "/rpm.repo", reqPackageAccess(perm.AccessModeRead), rpm.GetRepositoryConfig)
@@ -523,6 +567,22 @@ func CommonRoutes() *web.Router {
 				r.Get("/identifiers", swift.CheckAcceptMediaType(swift.AcceptJSON), swift.LookupPackageIdentifiers)
 			}, reqPackageAccess(perm.AccessModeRead))
 		})
+		r.Group("/terraform", func() {
+			stateRoutes := func() {
+				r.Get("", terraform.GetState)
+				r.Post("", terraform.UpdateState)
+				r.Delete("", terraform.DeleteState)
+				r.Methods("LOCK", "", terraform.LockState)
+				r.Methods("UNLOCK", "", terraform.UnlockState)
+				r.Post("/lock", terraform.LockState)
+				r.Delete("/lock", terraform.UnlockState)
+				r.Get("/versions", terraform.ListStateVersions)
+				r.Get("/versions/{version}", terraform.DownloadStateVersion)
+				r.Post("/versions/{version}/rollback", terraform.RollbackState)
+			}
+			r.Group("/state/{statename}", stateRoutes, reqPackageAccess(perm.AccessModeWrite))
+			r.Group("/repos/{reponame}/state/{statename}", stateRoutes, terraform.ReqRepositoryStateAccess)
+		})
 		r.Group("/vagrant", func() {
 			r.Group("/authenticate", func() {
 				r.Get("", vagrant.CheckAuthenticate)
@@ -581,6 +641,8 @@ func ContainerRoutes() *web.Router {
 			g.MatchPath("GET", `/<image:*>/manifests/<reference>`, container.VerifyImageName, container.GetManifest)
 			g.MatchPath("PUT", `/<image:*>/manifests/<reference>`, container.VerifyImageName, reqPackageAccess(perm.AccessModeWrite), container.PutManifest)
 			g.MatchPath("DELETE", `/<image:*>/manifests/<reference>`, container.VerifyImageName, reqPackageAccess(perm.AccessModeWrite), container.DeleteManifest)
+
+			g.MatchPath("GET", `/<image:*>/referrers/<digest>`, container.VerifyImageName, container.GetReferrers)
 		})
 	}, container.ReqContainerAccess, context.UserAssignmentWeb(), context.PackageAssignment(), reqPackageAccess(perm.AccessModeRead))
 
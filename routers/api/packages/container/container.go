@@ -48,6 +48,7 @@ import (
 	container_service "github.com/kumose/kmup/services/packages/container"
 
 	"github.com/opencontainers/go-digest"
+	oci "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
 // maximum size of a container manifest
@@ -519,6 +520,9 @@ func getBlobFromContext(ctx *context.Context) (*packages_model.PackageFileDescri
 // https://github.com/opencontainers/distribution-spec/blob/main/spec.md#checking-if-content-exists-in-the-registry
 func HeadBlob(ctx *context.Context) {
 	blob, err := getBlobFromContext(ctx)
+	if errors.Is(err, container_model.ErrContainerBlobNotExist) {
+		blob, err = getRemoteBlob(ctx)
+	}
 	if err != nil {
 		if errors.Is(err, container_model.ErrContainerBlobNotExist) {
 			apiErrorDefined(ctx, errBlobUnknown)
@@ -538,6 +542,9 @@ func HeadBlob(ctx *context.Context) {
 // https://github.com/opencontainers/distribution-spec/blob/main/spec.md#pulling-blobs
 func GetBlob(ctx *context.Context) {
 	blob, err := getBlobFromContext(ctx)
+	if errors.Is(err, container_model.ErrContainerBlobNotExist) {
+		blob, err = getRemoteBlob(ctx)
+	}
 	if err != nil {
 		if errors.Is(err, container_model.ErrContainerBlobNotExist) {
 			apiErrorDefined(ctx, errBlobUnknown)
@@ -617,6 +624,11 @@ func PutManifest(ctx *context.Context) {
 		return
 	}
 
+	// https://github.com/opencontainers/distribution-spec/blob/main/spec.md#pushing-manifests-with-subject
+	if mci.Subject != "" {
+		ctx.Resp.Header().Set("OCI-Subject", mci.Subject)
+	}
+
 	setResponseHeaders(ctx.Resp, &containerHeaders{
 		Location:      fmt.Sprintf("/v2/%s/%s/manifests/%s", ctx.Package.Owner.LowerName, mci.Image, reference),
 		ContentDigest: digest,
@@ -653,12 +665,68 @@ func getManifestFromContext(ctx *context.Context) (*packages_model.PackageFileDe
 	return workaroundGetContainerBlob(ctx, opts)
 }
 
+// getReferrers returns the referrers of the subject in the image of the request
+func getReferrers(ctx *context.Context, subject digest.Digest, artifactType string) ([]oci.Descriptor, error) {
+	p, err := packages_model.GetPackageByName(ctx, ctx.Package.Owner.ID, packages_model.TypeContainer, ctx.PathParam("image"))
+	if err != nil {
+		if errors.Is(err, packages_model.ErrPackageNotExist) {
+			return []oci.Descriptor{}, nil
+		}
+		return nil, err
+	}
+	return container_service.GetReferrers(ctx, p, subject, artifactType)
+}
+
+// getReferrersTagIndexFromContext creates the image index of the referrers tag schema
+// for clients which do not support the referrers API and request a referrers tag which was never pushed
+func getReferrersTagIndexFromContext(ctx *context.Context) ([]byte, error) {
+	subject, ok := container_module.ParseReferrersTag(ctx.PathParam("reference"))
+	if !ok {
+		return nil, container_model.ErrContainerBlobNotExist
+	}
+	descriptors, err := getReferrers(ctx, subject, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(descriptors) == 0 {
+		return nil, container_model.ErrContainerBlobNotExist
+	}
+	return json.Marshal(container_service.NewReferrersIndex(descriptors))
+}
+
+// serveReferrersTagIndex serves the generated image index of the referrers tag schema
+func serveReferrersTagIndex(ctx *context.Context) {
+	content, err := getReferrersTagIndexFromContext(ctx)
+	if err != nil {
+		if errors.Is(err, container_model.ErrContainerBlobNotExist) {
+			apiErrorDefined(ctx, errManifestUnknown)
+		} else {
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	setResponseHeaders(ctx.Resp, &containerHeaders{
+		ContentDigest: digest.FromBytes(content).String(),
+		ContentType:   oci.MediaTypeImageIndex,
+		ContentLength: optional.Some(int64(len(content))),
+		Status:        http.StatusOK,
+	})
+	if ctx.Req.Method != http.MethodHead {
+		_, _ = ctx.Resp.Write(content)
+	}
+}
+
 // https://github.com/opencontainers/distribution-spec/blob/main/spec.md#checking-if-content-exists-in-the-registry
 func HeadManifest(ctx *context.Context) {
+	if serveRemoteManifest(ctx) {
+		return
+	}
+
 	manifest, err := getManifestFromContext(ctx)
 	if err != nil {
 		if errors.Is(err, container_model.ErrContainerBlobNotExist) {
-			apiErrorDefined(ctx, errManifestUnknown)
+			serveReferrersTagIndex(ctx)
 		} else {
 			apiError(ctx, http.StatusInternalServerError, err)
 		}
@@ -675,10 +743,24 @@ func HeadManifest(ctx *context.Context) {
 
 // https://github.com/opencontainers/distribution-spec/blob/main/spec.md#pulling-manifests
 func GetManifest(ctx *context.Context) {
+	if serveRemoteManifest(ctx) {
+		return
+	}
+
 	manifest, err := getManifestFromContext(ctx)
 	if err != nil {
 		if errors.Is(err, container_model.ErrContainerBlobNotExist) {
-			apiErrorDefined(ctx, errManifestUnknown)
+			serveReferrersTagIndex(ctx)
+		} else {
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	if err := checkManifestDownloadAllowed(ctx, manifest); err != nil {
+		var namedError *namedError
+		if errors.As(err, &namedError) {
+			apiErrorDefined(ctx, namedError)
 		} else {
 			apiError(ctx, http.StatusInternalServerError, err)
 		}
@@ -715,11 +797,44 @@ func DeleteManifest(ctx *context.Context) {
 		}
 	}
 
+	if err := container_service.RemoveOrphanedReferrers(ctx, ctx.Doer, pvs[0].PackageID); err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
 	setResponseHeaders(ctx.Resp, &containerHeaders{
 		Status: http.StatusAccepted,
 	})
 }
 
+// https://github.com/opencontainers/distribution-spec/blob/main/spec.md#listing-referrers
+func GetReferrers(ctx *context.Context) {
+	subject := digest.Digest(ctx.PathParam("digest"))
+	if subject.Validate() != nil {
+		apiErrorDefined(ctx, errDigestInvalid)
+		return
+	}
+
+	artifactType := ctx.FormTrim("artifactType")
+
+	descriptors, err := getReferrers(ctx, subject, artifactType)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	if artifactType != "" {
+		ctx.Resp.Header().Set("OCI-Filters-Applied", "artifactType")
+	}
+
+	setResponseHeaders(ctx.Resp, &containerHeaders{
+		Status:      http.StatusOK,
+		ContentType: oci.MediaTypeImageIndex,
+	})
+	_ = json.NewEncoder(ctx.Resp).Encode(container_service.NewReferrersIndex(descriptors)) // ignore network errors
+}
+
+// serveBlob serves a manifest or a blob. The signature policy of the owner must be checked for the manifests before, the blobs are exempted.
 func serveBlob(ctx *context.Context, pfd *packages_model.PackageFileDescriptor) {
 	serveDirectReqParams := make(url.Values)
 	serveDirectReqParams.Set("response-content-type", pfd.Properties.GetByName(container_module.PropertyMediaType))
@@ -39,6 +39,7 @@ import (
 	notify_service "github.com/kumose/kmup/services/notify"
 	packages_service "github.com/kumose/kmup/services/packages"
 	container_service "github.com/kumose/kmup/services/packages/container"
+	signing_service "github.com/kumose/kmup/services/packages/signing"
 
 	"github.com/opencontainers/go-digest"
 	oci "github.com/opencontainers/image-spec/specs-go/v1"
@@ -53,6 +54,19 @@ type manifestCreationInfo struct {
 	Reference  string
 	IsTagged   bool
 	Properties map[string]string
+	Subject    string
+	IsRemote   bool
+}
+
+// checkManifestDownloadAllowed checks the signature policy of the owner before the manifest gets served
+func checkManifestDownloadAllowed(ctx context.Context, manifest *packages_model.PackageFileDescriptor) error {
+	if err := signing_service.CheckManifestDownloadAllowed(ctx, manifest); err != nil {
+		if errors.Is(err, signing_service.ErrSignatureRequired) {
+			return errDenied.WithMessage(err.Error())
+		}
+		return err
+	}
+	return nil
 }
 
 func processManifest(ctx context.Context, mci *manifestCreationInfo, buf *packages_module.HashedBuffer) (string, error) {
@@ -63,6 +77,12 @@ func processManifest(ctx context.Context, mci *manifestCreationInfo, buf *packag
 	if index.SchemaVersion != 2 {
 		return "", errUnsupported.WithMessage("Schema version is not supported")
 	}
+	if index.Subject != nil {
+		if index.Subject.Digest.Validate() != nil {
+			return "", errManifestInvalid.WithMessage("Subject digest is invalid")
+		}
+		mci.Subject = index.Subject.Digest.String()
+	}
 	if _, err := buf.Seek(0, io.SeekStart); err != nil {
 		return "", err
 	}
@@ -186,8 +206,16 @@ func processOciImageIndex(ctx context.Context, mci *manifestCreationInfo, buf *p
 	var txRet processManifestTxRet
 	err := db.WithTx(ctx, func(ctx context.Context) (err error) {
 		metadata := &container_module.Metadata{
-			Type:      container_module.TypeOCI,
-			Manifests: make([]*container_module.Manifest, 0, len(index.Manifests)),
+			Type:         container_module.TypeOCI,
+			Manifests:    make([]*container_module.Manifest, 0, len(index.Manifests)),
+			ArtifactType: index.ArtifactType,
+			Annotations:  index.Annotations,
+		}
+		if index.ArtifactType != "" {
+			metadata.Type = container_module.TypeArtifact
+		}
+		if index.Subject != nil {
+			metadata.Subject = index.Subject.Digest.String()
 		}
 
 		for _, manifest := range index.Manifests {
@@ -279,6 +307,7 @@ func createPackageAndVersion(ctx context.Context, mci *manifestCreationInfo, met
 		CreatorID:    mci.Creator.ID,
 		Version:      strings.ToLower(mci.Reference),
 		LowerVersion: strings.ToLower(mci.Reference),
+		IsRemote:     mci.IsRemote,
 		MetadataJSON: string(metadataJSON),
 	}
 	pv, err := packages_model.GetOrInsertVersion(ctx, _pv)
@@ -333,6 +362,15 @@ func createPackageAndVersion(ctx context.Context, mci *manifestCreationInfo, met
 		}
 	}
 
+	if err = packages_model.DeletePropertiesByName(ctx, packages_model.PropertyTypeVersion, pv.ID, container_module.PropertyManifestSubject); err != nil {
+		return nil, err
+	}
+	if metadata.Subject != "" {
+		if _, err = packages_model.InsertProperty(ctx, packages_model.PropertyTypeVersion, pv.ID, container_module.PropertyManifestSubject, metadata.Subject); err != nil {
+			return nil, err
+		}
+	}
+
 	return pv, nil
 }
 
@@ -0,0 +1,309 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package container
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	packages_model "github.com/kumose/kmup/models/packages"
+	container_model "github.com/kumose/kmup/models/packages/container"
+	"github.com/kumose/kmup/modules/json"
+	"github.com/kumose/kmup/modules/log"
+	"github.com/kumose/kmup/modules/optional"
+	packages_module "github.com/kumose/kmup/modules/packages"
+	container_module "github.com/kumose/kmup/modules/packages/container"
+	"github.com/kumose/kmup/modules/util"
+	"github.com/kumose/kmup/services/context"
+	packages_service "github.com/kumose/kmup/services/packages"
+	remote_service "github.com/kumose/kmup/services/packages/remote"
+
+	"github.com/opencontainers/go-digest"
+	oci "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+var remoteManifestAccept = strings.Join([]string{
+	oci.MediaTypeImageManifest,
+	oci.MediaTypeImageIndex,
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+}, ", ")
+
+// remoteManifest is an upstream manifest which is served without being stored
+type remoteManifest struct {
+	Content   []byte
+	MediaType string
+	Digest    digest.Digest
+}
+
+// getRemoteClient returns a client for the upstream registry if missing manifests and blobs of the image should be fetched from there
+func getRemoteClient(ctx *context.Context) (*remote_service.Client, error) {
+	pr, err := remote_service.GetRemoteForPackage(ctx, ctx.Package.Owner.ID, packages_model.TypeContainer, ctx.PathParam("image"))
+	if err != nil || pr == nil {
+		return nil, err
+	}
+	return remote_service.NewClient(pr)
+}
+
+// upstreamImagePath returns the path of the image in the upstream registry
+func upstreamImagePath(client *remote_service.Client, image string) string {
+	// Docker Hub keeps the official images in the "library" namespace
+	if !strings.Contains(image, "/") {
+		if u, err := url.Parse(client.Remote().URL); err == nil && strings.HasSuffix(u.Hostname(), "docker.io") {
+			return "library/" + image
+		}
+	}
+	return image
+}
+
+// serveRemoteManifest fetches missing manifests from upstream and revalidates cached tags after the TTL of the remote.
+// Image indexes are served from upstream directly, the referenced manifests get cached when they are requested.
+// It returns true if the response has been written.
+func serveRemoteManifest(ctx *context.Context) bool {
+	client, err := getRemoteClient(ctx)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return true
+	}
+	if client == nil {
+		return false
+	}
+
+	rm, err := syncRemoteManifest(ctx, client)
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			return false
+		}
+		var namedError *namedError
+		if errors.As(err, &namedError) {
+			apiErrorDefined(ctx, namedError)
+		} else {
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return true
+	}
+	if rm == nil {
+		return false
+	}
+
+	setResponseHeaders(ctx.Resp, &containerHeaders{
+		ContentDigest: rm.Digest.String(),
+		ContentType:   rm.MediaType,
+		ContentLength: optional.Some(int64(len(rm.Content))),
+		Status:        http.StatusOK,
+	})
+	if ctx.Req.Method != http.MethodHead {
+		_, _ = ctx.Resp.Write(rm.Content)
+	}
+	return true
+}
+
+func syncRemoteManifest(ctx *context.Context, client *remote_service.Client) (*remoteManifest, error) {
+	image := ctx.PathParam("image")
+	reference := ctx.PathParam("reference")
+
+	isDigest := digest.Digest(reference).Validate() == nil
+	if !isDigest && !globalVars().referencePattern.MatchString(reference) {
+		return nil, nil
+	}
+
+	var pv *packages_model.PackageVersion
+	local, err := getManifestFromContext(ctx)
+	if err != nil && !errors.Is(err, container_model.ErrContainerBlobNotExist) {
+		return nil, err
+	}
+	if local != nil {
+		if isDigest {
+			return nil, nil
+		}
+		if pv, err = packages_model.GetVersionByID(ctx, local.File.VersionID); err != nil {
+			return nil, err
+		}
+		if !pv.IsRemote || !client.NeedsRevalidation(pv) {
+			return nil, nil
+		}
+	}
+
+	content, err := client.GetDocument(ctx, fmt.Sprintf("v2/%s/manifests/%s", upstreamImagePath(client, image), reference), http.Header{"Accept": []string{remoteManifestAccept}})
+	if err != nil {
+		if local != nil {
+			// serve the cached manifest if upstream is not available
+			log.Warn("Revalidating container manifest %s:%s with upstream failed: %v", image, reference, err)
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(content) > maxManifestSize {
+		return nil, errManifestInvalid.WithMessage("Manifest exceeds maximum size")
+	}
+
+	manifestDigest := digest.FromBytes(content)
+	if isDigest && manifestDigest != digest.Digest(reference) {
+		return nil, errManifestInvalid.WithMessage("Upstream manifest does not match the digest")
+	}
+
+	if local != nil {
+		if local.Properties.GetByName(container_module.PropertyDigest) == manifestDigest.String() {
+			client.MarkRevalidated(pv)
+			return nil, nil
+		}
+	}
+
+	mediaType, err := detectManifestMediaType(content)
+	if err != nil {
+		return nil, err
+	}
+
+	if container_module.IsMediaTypeImageIndex(mediaType) {
+		return &remoteManifest{
+			Content:   content,
+			MediaType: mediaType,
+			Digest:    manifestDigest,
+		}, nil
+	}
+
+	// the tag points to a different manifest upstream, replace the outdated cached version
+	if local != nil {
+		if err := packages_service.DeletePackageVersionAndReferences(ctx, pv); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, cacheRemoteImageManifest(ctx, client, reference, !isDigest, mediaType, content)
+}
+
+// detectManifestMediaType gets the media type of a manifest. Old manifests may omit it, then it's derived from the content.
+func detectManifestMediaType(content []byte) (string, error) {
+	var manifest struct {
+		MediaType string     `json:"mediaType"`
+		Manifests []struct{} `json:"manifests"`
+		Layers    []struct{} `json:"layers"`
+	}
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return "", errManifestInvalid.WithMessage("Upstream manifest is invalid")
+	}
+
+	mediaType := manifest.MediaType
+	if mediaType == "" {
+		if manifest.Manifests != nil {
+			mediaType = oci.MediaTypeImageIndex
+		} else if manifest.Layers != nil {
+			mediaType = oci.MediaTypeImageManifest
+		}
+	}
+	if !container_module.IsMediaTypeImageManifest(mediaType) && !container_module.IsMediaTypeImageIndex(mediaType) {
+		return "", errUnsupported.WithMessage("Upstream manifest media type is not supported")
+	}
+	return mediaType, nil
+}
+
+// cacheRemoteImageManifest fetches the blobs of an upstream image manifest and stores the manifest as cached version
+func cacheRemoteImageManifest(ctx *context.Context, client *remote_service.Client, reference string, isTagged bool, mediaType string, content []byte) error {
+	var manifest oci.Manifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return errManifestInvalid.WithMessage("Upstream manifest is invalid")
+	}
+
+	for _, descriptor := range append([]oci.Descriptor{manifest.Config}, manifest.Layers...) {
+		if err := cacheRemoteBlob(ctx, client, descriptor.Digest); err != nil {
+			return err
+		}
+	}
+
+	buf, err := packages_module.CreateHashedBufferFromReader(bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	defer buf.Close()
+
+	_, err = processManifest(ctx, &manifestCreationInfo{
+		MediaType: mediaType,
+		Owner:     ctx.Package.Owner,
+		Creator:   remote_service.Creator(ctx.Doer),
+		Image:     ctx.PathParam("image"),
+		Reference: reference,
+		IsTagged:  isTagged,
+		IsRemote:  true,
+	}, buf)
+	return err
+}
+
+// cacheRemoteBlob fetches a blob of the image from upstream if it does not exist locally
+func cacheRemoteBlob(ctx *context.Context, client *remote_service.Client, d digest.Digest) error {
+	image := ctx.PathParam("image")
+
+	if d.Validate() != nil || d.Algorithm() != digest.SHA256 {
+		return errDigestInvalid
+	}
+
+	_, err := workaroundGetContainerBlob(ctx, &container_model.BlobSearchOptions{
+		OwnerID: ctx.Package.Owner.ID,
+		Image:   image,
+		Digest:  string(d),
+	})
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, container_model.ErrContainerBlobNotExist) {
+		return err
+	}
+
+	buf, err := client.Download(ctx, fmt.Sprintf("v2/%s/blobs/%s", upstreamImagePath(client, image), d), nil)
+	if err != nil {
+		return err
+	}
+	defer buf.Close()
+
+	if digestFromHashSummer(buf) != d.String() {
+		return errDigestInvalid.WithMessage("Upstream blob does not match the digest")
+	}
+
+	_, err = saveAsPackageBlob(ctx, buf, &packages_service.PackageCreationInfo{
+		PackageInfo: packages_service.PackageInfo{
+			Owner:       ctx.Package.Owner,
+			PackageType: packages_model.TypeContainer,
+			Name:        image,
+		},
+		Creator: remote_service.Creator(ctx.Doer),
+	})
+	return err
+}
+
+// getRemoteBlob fetches a missing blob from upstream, if the owner has configured a remote
+func getRemoteBlob(ctx *context.Context) (*packages_model.PackageFileDescriptor, error) {
+	client, err := getRemoteClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, container_model.ErrContainerBlobNotExist
+	}
+
+	if err := cacheRemoteBlob(ctx, client, digest.Digest(ctx.PathParam("digest"))); err != nil {
+		if errors.Is(err, util.ErrNotExist) || errors.Is(err, errDigestInvalid) {
+			return nil, container_model.ErrContainerBlobNotExist
+		}
+		return nil, err
+	}
+
+	return getBlobFromContext(ctx)
+}
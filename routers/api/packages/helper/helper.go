@@ -18,6 +18,7 @@
 package helper
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -26,7 +27,9 @@ import (
 	packages_model "github.com/kumose/kmup/models/packages"
 	"github.com/kumose/kmup/modules/log"
 	"github.com/kumose/kmup/modules/setting"
+	"github.com/kumose/kmup/modules/util"
 	"github.com/kumose/kmup/services/context"
+	signing_service "github.com/kumose/kmup/services/packages/signing"
 )
 
 // ProcessErrorForUser logs the error and returns a user-error message for the end user.
@@ -53,7 +56,20 @@ func ProcessErrorForUser(ctx *context.Context, status int, errObj any) string {
 
 // ServePackageFile the content of the package file
 // If the url is set it will redirect the request, otherwise the content is copied to the response.
+// Downloads are rejected if the owner requires signed package versions and the version is not signed by a trusted signer.
 func ServePackageFile(ctx *context.Context, s io.ReadSeekCloser, u *url.URL, pf *packages_model.PackageFile, forceOpts ...*context.ServeHeaderOptions) {
+	if err := signing_service.CheckDownloadAllowed(ctx, pf); err != nil {
+		if s != nil {
+			s.Close()
+		}
+		if errors.Is(err, util.ErrPermissionDenied) {
+			ctx.HTTPError(http.StatusForbidden, ProcessErrorForUser(ctx, http.StatusForbidden, err))
+		} else {
+			ctx.HTTPError(http.StatusInternalServerError, ProcessErrorForUser(ctx, http.StatusInternalServerError, err))
+		}
+		return
+	}
+
 	if u != nil {
 		ctx.Redirect(u.String())
 		return
@@ -0,0 +1,297 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package hex
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	packages_model "github.com/kumose/kmup/models/packages"
+	"github.com/kumose/kmup/modules/json"
+	packages_module "github.com/kumose/kmup/modules/packages"
+	hex_module "github.com/kumose/kmup/modules/packages/hex"
+	"github.com/kumose/kmup/modules/setting"
+	"github.com/kumose/kmup/modules/util"
+	"github.com/kumose/kmup/routers/api/packages/helper"
+	"github.com/kumose/kmup/services/context"
+	packages_service "github.com/kumose/kmup/services/packages"
+	hex_service "github.com/kumose/kmup/services/packages/hex"
+)
+
+func jsonResponse(ctx *context.Context, status int, obj any) {
+	resp := ctx.Resp
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(status)
+	_ = json.NewEncoder(resp).Encode(obj)
+}
+
+func apiError(ctx *context.Context, status int, obj any) {
+	message := helper.ProcessErrorForUser(ctx, status, obj)
+	jsonResponse(ctx, status, map[string]any{
+		"status":  status,
+		"message": message,
+	})
+}
+
+func serveResource(ctx *context.Context, data []byte, err error) {
+	if err != nil {
+		if errors.Is(err, packages_model.ErrPackageNotExist) {
+			apiError(ctx, http.StatusNotFound, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.Resp.Header().Set("Content-Type", "application/octet-stream")
+	ctx.Resp.WriteHeader(http.StatusOK)
+	_, _ = ctx.Resp.Write(data)
+}
+
+// GetPublicKey serves the public key used to verify the signed registry resources
+func GetPublicKey(ctx *context.Context) {
+	_, pub, err := hex_service.GetOrCreateKeyPair(ctx, ctx.Package.Owner.ID)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.PlainText(http.StatusOK, pub)
+}
+
+// GetNames serves the signed list of all packages
+// https://github.com/hexpm/specifications/blob/main/registry-v2.md#names
+func GetNames(ctx *context.Context) {
+	data, err := hex_service.BuildNames(ctx, ctx.Package.Owner)
+	serveResource(ctx, data, err)
+}
+
+// GetVersions serves the signed list of all packages and their versions
+// https://github.com/hexpm/specifications/blob/main/registry-v2.md#versions
+func GetVersions(ctx *context.Context) {
+	data, err := hex_service.BuildVersions(ctx, ctx.Package.Owner)
+	serveResource(ctx, data, err)
+}
+
+// GetPackage serves the signed releases of a package
+// https://github.com/hexpm/specifications/blob/main/registry-v2.md#package
+func GetPackage(ctx *context.Context) {
+	data, err := hex_service.BuildPackage(ctx, ctx.Package.Owner, ctx.PathParam("name"))
+	serveResource(ctx, data, err)
+}
+
+// DownloadTarball serves the package tarball
+func DownloadTarball(ctx *context.Context) {
+	filename := ctx.PathParam("filename")
+
+	name, version, ok := hex_module.ParseTarballFilename(filename)
+	if !ok {
+		apiError(ctx, http.StatusNotFound, nil)
+		return
+	}
+
+	s, u, pf, err := packages_service.OpenFileForDownloadByPackageNameAndVersion(
+		ctx,
+		&packages_service.PackageInfo{
+			Owner:       ctx.Package.Owner,
+			PackageType: packages_model.TypeHex,
+			Name:        name,
+			Version:     version,
+		},
+		&packages_service.PackageFileInfo{
+			Filename: filename,
+		},
+		ctx.Req.Method,
+	)
+	if err != nil {
+		if errors.Is(err, packages_model.ErrPackageNotExist) || errors.Is(err, packages_model.ErrPackageFileNotExist) {
+			apiError(ctx, http.StatusNotFound, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	helper.ServePackageFile(ctx, s, u, pf)
+}
+
+type releaseResponse struct {
+	Name          string            `json:"name"`
+	Version       string            `json:"version"`
+	Checksum      string            `json:"checksum"`
+	InnerChecksum string            `json:"inner_checksum"`
+	URL           string            `json:"url"`
+	HTMLURL       string            `json:"html_url"`
+	PackageURL    string            `json:"package_url"`
+	HasDocs       bool              `json:"has_docs"`
+	Meta          map[string]any    `json:"meta"`
+	Requirements  map[string]any    `json:"requirements"`
+	InsertedAt    time.Time         `json:"inserted_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+	Links         map[string]string `json:"links,omitempty"`
+}
+
+// UploadPackage publishes a package tarball
+// https://github.com/hexpm/specifications/blob/main/endpoints.md#publish-a-release
+func UploadPackage(ctx *context.Context) {
+	upload, needsClose, err := ctx.UploadStream()
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if needsClose {
+		defer upload.Close()
+	}
+
+	buf, err := packages_module.CreateHashedBufferFromReader(upload)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	defer buf.Close()
+
+	pck, err := hex_module.ParsePackage(buf)
+	if err != nil {
+		if errors.Is(err, util.ErrInvalidArgument) {
+			apiError(ctx, http.StatusBadRequest, err)
+		} else {
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	if name := ctx.PathParam("name"); name != "" && name != pck.Name {
+		apiError(ctx, http.StatusBadRequest, "package name does not match the tarball")
+		return
+	}
+
+	if _, err := buf.Seek(0, io.SeekStart); err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	pvi := &packages_service.PackageInfo{
+		Owner:       ctx.Package.Owner,
+		PackageType: packages_model.TypeHex,
+		Name:        pck.Name,
+		Version:     pck.Version,
+	}
+
+	if ctx.FormBool("replace") {
+		if err := packages_service.RemovePackageVersionByNameAndVersion(ctx, ctx.Doer, pvi); err != nil && !errors.Is(err, packages_model.ErrPackageNotExist) {
+			apiError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	pv, _, err := packages_service.CreatePackageAndAddFile(
+		ctx,
+		&packages_service.PackageCreationInfo{
+			PackageInfo:      *pvi,
+			SemverCompatible: true,
+			Creator:          ctx.Doer,
+			Metadata:         pck.Metadata,
+		},
+		&packages_service.PackageFileCreationInfo{
+			PackageFileInfo: packages_service.PackageFileInfo{
+				Filename: hex_module.TarballFilename(pck.Name, pck.Version),
+			},
+			Creator: ctx.Doer,
+			Data:    buf,
+			IsLead:  true,
+		},
+	)
+	if err != nil {
+		switch err {
+		case packages_model.ErrDuplicatePackageVersion:
+			apiError(ctx, http.StatusConflict, "package version already exists, use --replace to overwrite it")
+		case packages_service.ErrQuotaTotalCount, packages_service.ErrQuotaTypeSize, packages_service.ErrQuotaTotalSize:
+			apiError(ctx, http.StatusForbidden, err)
+		default:
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	pd, err := packages_model.GetPackageDescriptor(ctx, pv)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	requirements := make(map[string]any, len(pck.Metadata.Requirements))
+	for _, r := range pck.Metadata.Requirements {
+		app := r.App
+		if app == "" {
+			app = r.Name
+		}
+		requirements[r.Name] = map[string]any{
+			"app":         app,
+			"optional":    r.Optional,
+			"requirement": r.Requirement,
+		}
+	}
+
+	baseURL := setting.AppURL + "api/packages/" + ctx.Package.Owner.Name + "/hex"
+
+	jsonResponse(ctx, http.StatusCreated, &releaseResponse{
+		Name:          pd.Package.Name,
+		Version:       pd.Version.Version,
+		Checksum:      pd.Files[0].Blob.HashSHA256,
+		InnerChecksum: pck.Metadata.InnerChecksum,
+		URL:           baseURL + "/tarballs/" + hex_module.TarballFilename(pck.Name, pck.Version),
+		HTMLURL:       pd.VersionHTMLURL(ctx),
+		PackageURL:    pd.PackageHTMLURL(ctx),
+		Meta: map[string]any{
+			"app":         pck.Metadata.App,
+			"build_tools": pck.Metadata.BuildTools,
+			"elixir":      pck.Metadata.Elixir,
+		},
+		Requirements: requirements,
+		InsertedAt:   pd.Version.CreatedUnix.AsLocalTime(),
+		UpdatedAt:    pd.Version.CreatedUnix.AsLocalTime(),
+		Links:        pck.Metadata.Links,
+	})
+}
+
+// DeletePackageVersion reverts a published release
+// https://github.com/hexpm/specifications/blob/main/endpoints.md#revert-a-release
+func DeletePackageVersion(ctx *context.Context) {
+	err := packages_service.RemovePackageVersionByNameAndVersion(
+		ctx,
+		ctx.Doer,
+		&packages_service.PackageInfo{
+			Owner:       ctx.Package.Owner,
+			PackageType: packages_model.TypeHex,
+			Name:        ctx.PathParam("name"),
+			Version:     ctx.PathParam("version"),
+		},
+	)
+	if err != nil {
+		if errors.Is(err, packages_model.ErrPackageNotExist) {
+			apiError(ctx, http.StatusNotFound, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -36,6 +36,7 @@ import (
 	packages_model "github.com/kumose/kmup/models/packages"
 	"github.com/kumose/kmup/modules/globallock"
 	"github.com/kumose/kmup/modules/json"
+	"github.com/kumose/kmup/modules/log"
 	packages_module "github.com/kumose/kmup/modules/packages"
 	maven_module "github.com/kumose/kmup/modules/packages/maven"
 	"github.com/kumose/kmup/modules/util"
@@ -84,6 +85,22 @@ func handlePackageFile(ctx *context.Context, serveContent bool) {
 		return
 	}
 
+	if params.IsMeta {
+		client, err := getRemoteClient(ctx, params)
+		if err != nil {
+			apiError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+		if client != nil {
+			err := serveRemoteMavenMetadata(ctx, client, serveContent)
+			if err == nil {
+				return
+			}
+			// serve the cached versions if upstream is not available
+			log.Warn("Fetching maven metadata %s from upstream failed: %v", ctx.PathParam("*"), err)
+		}
+	}
+
 	if params.IsMeta && params.Version == "" {
 		serveMavenMetadata(ctx, params)
 	} else {
@@ -162,19 +179,6 @@ func serveMavenMetadata(ctx *context.Context, params parameters) {
 }
 
 func servePackageFile(ctx *context.Context, params parameters, serveContent bool) {
-	pv, err := packages_model.GetVersionByNameAndVersion(ctx, ctx.Package.Owner.ID, packages_model.TypeMaven, params.toInternalPackageName(), params.Version)
-	if errors.Is(err, util.ErrNotExist) {
-		pv, err = packages_model.GetVersionByNameAndVersion(ctx, ctx.Package.Owner.ID, packages_model.TypeMaven, params.toInternalPackageNameLegacy(), params.Version)
-	}
-	if err != nil {
-		if errors.Is(err, packages_model.ErrPackageNotExist) {
-			apiError(ctx, http.StatusNotFound, err)
-		} else {
-			apiError(ctx, http.StatusInternalServerError, err)
-		}
-		return
-	}
-
 	filename := params.Filename
 
 	ext := strings.ToLower(path.Ext(filename))
@@ -182,9 +186,12 @@ func servePackageFile(ctx *context.Context, params parameters, serveContent bool
 		filename = filename[:len(filename)-len(ext)]
 	}
 
-	pf, err := packages_model.GetFileForVersionByName(ctx, pv.ID, filename, packages_model.EmptyFileKey)
+	pf, err := getPackageFile(ctx, params, filename)
+	if errors.Is(err, util.ErrNotExist) {
+		pf, err = downloadRemotePackageFile(ctx, params, filename)
+	}
 	if err != nil {
-		if errors.Is(err, packages_model.ErrPackageFileNotExist) {
+		if errors.Is(err, util.ErrNotExist) {
 			apiError(ctx, http.StatusNotFound, err)
 		} else {
 			apiError(ctx, http.StatusInternalServerError, err)
@@ -242,6 +249,31 @@ func servePackageFile(ctx *context.Context, params parameters, serveContent bool
 	helper.ServePackageFile(ctx, s, u, pf, opts)
 }
 
+func getPackageFile(ctx *context.Context, params parameters, filename string) (*packages_model.PackageFile, error) {
+	pv, err := packages_model.GetVersionByNameAndVersion(ctx, ctx.Package.Owner.ID, packages_model.TypeMaven, params.toInternalPackageName(), params.Version)
+	if errors.Is(err, util.ErrNotExist) {
+		pv, err = packages_model.GetVersionByNameAndVersion(ctx, ctx.Package.Owner.ID, packages_model.TypeMaven, params.toInternalPackageNameLegacy(), params.Version)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return packages_model.GetFileForVersionByName(ctx, pv.ID, filename, packages_model.EmptyFileKey)
+}
+
+// downloadRemotePackageFile fetches a missing package file from the upstream registry, if the owner has configured one
+func downloadRemotePackageFile(ctx *context.Context, params parameters, filename string) (*packages_model.PackageFile, error) {
+	client, err := getRemoteClient(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, packages_model.ErrPackageFileNotExist
+	}
+
+	return cacheRemotePackageFile(ctx, client, params, filename)
+}
+
 func mavenPkgNameKey(packageName string) string {
 	return "pkg_maven_" + packageName
 }
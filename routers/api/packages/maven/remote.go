@@ -0,0 +1,158 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package maven
+
+import (
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	packages_model "github.com/kumose/kmup/models/packages"
+	"github.com/kumose/kmup/modules/globallock"
+	"github.com/kumose/kmup/modules/json"
+	maven_module "github.com/kumose/kmup/modules/packages/maven"
+	"github.com/kumose/kmup/services/context"
+	packages_service "github.com/kumose/kmup/services/packages"
+	remote_service "github.com/kumose/kmup/services/packages/remote"
+)
+
+// getRemoteClient returns a client for the upstream registry if missing files of the package should be fetched from there.
+// nil is returned if the package has local versions under its name or its legacy name.
+func getRemoteClient(ctx *context.Context, params parameters) (*remote_service.Client, error) {
+	pr, err := remote_service.GetRemoteForPackage(ctx, ctx.Package.Owner.ID, packages_model.TypeMaven, params.toInternalPackageName(), params.toInternalPackageNameLegacy())
+	if err != nil || pr == nil {
+		return nil, err
+	}
+	return remote_service.NewClient(pr)
+}
+
+// serveRemoteMavenMetadata proxies the metadata files from the upstream registry. They are cached for the TTL of the remote but not stored.
+// The signature policy isn't checked because the metadata only lists the versions, the files of the versions are checked when they are downloaded.
+func serveRemoteMavenMetadata(ctx *context.Context, client *remote_service.Client, serveContent bool) error {
+	data, err := client.GetDocument(ctx, ctx.PathParam("*"), nil)
+	if err != nil {
+		return err
+	}
+
+	if isChecksumExtension(strings.ToLower(path.Ext(ctx.PathParam("*")))) {
+		ctx.PlainText(http.StatusOK, strings.TrimSpace(string(data)))
+		return nil
+	}
+
+	ctx.Resp.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	ctx.Resp.Header().Set("Content-Type", contentTypeXML)
+	ctx.Status(http.StatusOK)
+	if serveContent {
+		_, _ = ctx.Resp.Write(data)
+	}
+	return nil
+}
+
+// cacheRemotePackageFile downloads a file from the upstream registry and stores it in a cached version
+func cacheRemotePackageFile(ctx *context.Context, client *remote_service.Client, params parameters, filename string) (*packages_model.PackageFile, error) {
+	packageName := params.toInternalPackageName()
+
+	releaser, err := globallock.Lock(ctx, mavenPkgNameKey(packageName))
+	if err != nil {
+		return nil, err
+	}
+	defer releaser()
+
+	// the file may have been fetched by a concurrent request
+	if pf, err := getPackageFile(ctx, params, filename); err == nil {
+		return pf, nil
+	}
+
+	filePath := path.Join(path.Dir(ctx.PathParam("*")), filename)
+
+	buf, err := client.Download(ctx, filePath, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer buf.Close()
+
+	// verify the file if upstream provides a checksum
+	if checksum, err := client.GetDocument(ctx, filePath+extensionSHA1, nil); err == nil {
+		_, hashSHA1, _, _ := buf.Sums()
+		expected, _, _ := strings.Cut(strings.TrimSpace(string(checksum)), " ")
+		if !strings.EqualFold(expected, hex.EncodeToString(hashSHA1)) {
+			return nil, errors.New("checksum of the upstream file does not match")
+		}
+	} else if !errors.Is(err, remote_service.ErrUpstreamNotFound) {
+		return nil, err
+	}
+
+	creator := remote_service.Creator(ctx.Doer)
+
+	pvci := &packages_service.PackageCreationInfo{
+		PackageInfo: packages_service.PackageInfo{
+			Owner:       ctx.Package.Owner,
+			PackageType: packages_model.TypeMaven,
+			Name:        packageName,
+			Version:     params.Version,
+		},
+		SemverCompatible: false,
+		IsRemote:         true,
+		Creator:          creator,
+	}
+	pfci := &packages_service.PackageFileCreationInfo{
+		PackageFileInfo: packages_service.PackageFileInfo{
+			Filename: filename,
+		},
+		Creator: creator,
+		Data:    buf,
+	}
+
+	if strings.ToLower(path.Ext(filename)) == extensionPom {
+		pfci.IsLead = true
+
+		metadata, err := maven_module.ParsePackageMetaData(buf)
+		if err == nil && metadata != nil {
+			pvci.Metadata = metadata
+
+			pv, err := packages_model.GetVersionByNameAndVersion(ctx, pvci.Owner.ID, pvci.PackageType, pvci.Name, pvci.Version)
+			if err != nil && !errors.Is(err, packages_model.ErrPackageNotExist) {
+				return nil, err
+			}
+			if pv != nil {
+				raw, err := json.Marshal(metadata)
+				if err != nil {
+					return nil, err
+				}
+				pv.MetadataJSON = string(raw)
+				if err := packages_model.UpdateVersion(ctx, pv); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		if _, err := buf.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+
+	_, pf, err := packages_service.CreatePackageOrAddFileToExisting(ctx, pvci, pfci)
+	if err != nil {
+		return nil, err
+	}
+	return pf, nil
+}
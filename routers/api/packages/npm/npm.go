@@ -24,6 +24,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/kumose/kmup/models/db"
@@ -31,6 +32,7 @@ import (
 	access_model "github.com/kumose/kmup/models/perm/access"
 	repo_model "github.com/kumose/kmup/models/repo"
 	"github.com/kumose/kmup/models/unit"
+	"github.com/kumose/kmup/modules/log"
 	"github.com/kumose/kmup/modules/optional"
 	packages_module "github.com/kumose/kmup/modules/packages"
 	npm_module "github.com/kumose/kmup/modules/packages/npm"
@@ -39,6 +41,7 @@ import (
 	"github.com/kumose/kmup/routers/api/packages/helper"
 	"github.com/kumose/kmup/services/context"
 	packages_service "github.com/kumose/kmup/services/packages"
+	remote_service "github.com/kumose/kmup/services/packages/remote"
 
 	"github.com/hashicorp/go-version"
 )
@@ -68,6 +71,29 @@ func packageNameFromParams(ctx *context.Context) string {
 func PackageMetadata(ctx *context.Context) {
 	packageName := packageNameFromParams(ctx)
 
+	pr, err := remote_service.GetRemoteForPackage(ctx, ctx.Package.Owner.ID, packages_model.TypeNpm, packageName)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if pr != nil {
+		client, err := remote_service.NewClient(pr)
+		if err != nil {
+			apiError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+		metadata, err := getRemotePackageMetadata(ctx, client, packageName)
+		if err == nil {
+			ctx.JSON(http.StatusOK, createRemotePackageMetadataResponse(
+				setting.AppURL+"api/packages/"+ctx.Package.Owner.Name+"/npm",
+				metadata,
+			))
+			return
+		}
+		// serve the cached versions if upstream is not available
+		log.Warn("Fetching npm package %s from upstream failed: %v", packageName, err)
+	}
+
 	pvs, err := packages_model.GetVersionsByPackageName(ctx, ctx.Package.Owner.ID, packages_model.TypeNpm, packageName)
 	if err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
@@ -111,8 +137,11 @@ func DownloadPackageFile(ctx *context.Context) {
 		},
 		ctx.Req.Method,
 	)
+	if errors.Is(err, packages_model.ErrPackageNotExist) || errors.Is(err, packages_model.ErrPackageFileNotExist) {
+		s, u, pf, err = downloadRemotePackageFile(ctx, packageName, packageVersion, filename)
+	}
 	if err != nil {
-		if errors.Is(err, packages_model.ErrPackageNotExist) || errors.Is(err, packages_model.ErrPackageFileNotExist) {
+		if errors.Is(err, util.ErrNotExist) {
 			apiError(ctx, http.StatusNotFound, err)
 			return
 		}
@@ -123,6 +152,36 @@ func DownloadPackageFile(ctx *context.Context) {
 	helper.ServePackageFile(ctx, s, u, pf)
 }
 
+// downloadRemotePackageFile fetches a missing package version from the upstream registry, if the owner has configured one
+func downloadRemotePackageFile(ctx *context.Context, packageName, packageVersion, filename string) (io.ReadSeekCloser, *url.URL, *packages_model.PackageFile, error) {
+	pr, err := remote_service.GetRemoteForPackage(ctx, ctx.Package.Owner.ID, packages_model.TypeNpm, packageName)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if pr == nil {
+		return nil, nil, nil, packages_model.ErrPackageNotExist
+	}
+
+	client, err := remote_service.NewClient(pr)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	pv, err := cacheRemotePackageVersion(ctx, client, packageName, packageVersion)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return packages_service.OpenFileForDownloadByPackageVersion(
+		ctx,
+		pv,
+		&packages_service.PackageFileInfo{
+			Filename: filename,
+		},
+		ctx.Req.Method,
+	)
+}
+
 // DownloadPackageFileByName finds the version and serves the contents of a package
 func DownloadPackageFileByName(ctx *context.Context) {
 	filename := ctx.PathParam("filename")
@@ -0,0 +1,139 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package npm
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+
+	packages_model "github.com/kumose/kmup/models/packages"
+	"github.com/kumose/kmup/modules/json"
+	npm_module "github.com/kumose/kmup/modules/packages/npm"
+	"github.com/kumose/kmup/services/context"
+	packages_service "github.com/kumose/kmup/services/packages"
+	remote_service "github.com/kumose/kmup/services/packages/remote"
+)
+
+// getRemotePackageMetadata fetches the package metadata from the upstream registry
+func getRemotePackageMetadata(ctx *context.Context, client *remote_service.Client, packageName string) (*npm_module.PackageMetadata, error) {
+	data, err := client.GetDocument(ctx, url.PathEscape(packageName), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata npm_module.PackageMetadata
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, err
+	}
+	return &metadata, nil
+}
+
+// createRemotePackageMetadataResponse rewrites the upstream package metadata so that clients download the tarballs through this registry
+func createRemotePackageMetadataResponse(registryURL string, metadata *npm_module.PackageMetadata) *npm_module.PackageMetadata {
+	versions := make(map[string]*npm_module.PackageMetadataVersion, len(metadata.Versions))
+	for key, pmv := range metadata.Versions {
+		p, err := npm_module.NewPackageFromMetadataVersion(pmv)
+		if err != nil {
+			continue
+		}
+		pmv.Dist.Tarball = fmt.Sprintf("%s/%s/-/%s/%s", registryURL, url.QueryEscape(p.Name), url.PathEscape(p.Version), url.PathEscape(p.Filename))
+		versions[key] = pmv
+	}
+	metadata.Versions = versions
+	return metadata
+}
+
+// cacheRemotePackageVersion downloads a package version from the upstream registry and stores it as cached version
+func cacheRemotePackageVersion(ctx *context.Context, client *remote_service.Client, packageName, packageVersion string) (*packages_model.PackageVersion, error) {
+	metadata, err := getRemotePackageMetadata(ctx, client, packageName)
+	if err != nil {
+		return nil, err
+	}
+
+	var pmv *npm_module.PackageMetadataVersion
+	var p *npm_module.Package
+	for _, candidate := range metadata.Versions {
+		if candidate.Name != packageName {
+			continue
+		}
+		if tmp, err := npm_module.NewPackageFromMetadataVersion(candidate); err == nil && tmp.Version == packageVersion {
+			pmv, p = candidate, tmp
+			break
+		}
+	}
+	if p == nil {
+		return nil, packages_model.ErrPackageNotExist
+	}
+
+	buf, err := client.Download(ctx, pmv.Dist.Tarball, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer buf.Close()
+
+	_, hashSHA1, _, hashSHA512 := buf.Sums()
+	if err := pmv.Dist.Verify(hashSHA1, hashSHA512); err != nil {
+		return nil, err
+	}
+
+	creator := remote_service.Creator(ctx.Doer)
+
+	pv, _, err := packages_service.CreatePackageOrAddFileToExisting(
+		ctx,
+		&packages_service.PackageCreationInfo{
+			PackageInfo: packages_service.PackageInfo{
+				Owner:       ctx.Package.Owner,
+				PackageType: packages_model.TypeNpm,
+				Name:        p.Name,
+				Version:     p.Version,
+			},
+			SemverCompatible: true,
+			IsRemote:         true,
+			Creator:          creator,
+			Metadata:         p.Metadata,
+		},
+		&packages_service.PackageFileCreationInfo{
+			PackageFileInfo: packages_service.PackageFileInfo{
+				Filename: p.Filename,
+			},
+			Creator: creator,
+			Data:    buf,
+			IsLead:  true,
+		},
+	)
+	if err != nil {
+		if !errors.Is(err, packages_model.ErrDuplicatePackageFile) {
+			return nil, err
+		}
+		if pv, err = packages_model.GetVersionByNameAndVersion(ctx, ctx.Package.Owner.ID, packages_model.TypeNpm, p.Name, p.Version); err != nil {
+			return nil, err
+		}
+	}
+
+	// keep the dist tags so that the cached versions can be installed if upstream is unavailable
+	for tag, tagVersion := range metadata.DistTags {
+		if tagVersion == pmv.Version {
+			if err := setPackageTag(ctx, tag, pv, false); err != nil && !errors.Is(err, errInvalidTagName) {
+				return nil, err
+			}
+		}
+	}
+
+	return pv, nil
+}
@@ -18,23 +18,32 @@
 package pypi
 
 import (
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"regexp"
 	"sort"
 	"strings"
+	"time"
 	"unicode"
 
 	packages_model "github.com/kumose/kmup/models/packages"
+	"github.com/kumose/kmup/modules/json"
+	"github.com/kumose/kmup/modules/log"
 	packages_module "github.com/kumose/kmup/modules/packages"
 	pypi_module "github.com/kumose/kmup/modules/packages/pypi"
 	"github.com/kumose/kmup/modules/setting"
+	"github.com/kumose/kmup/modules/templates"
+	"github.com/kumose/kmup/modules/util"
 	"github.com/kumose/kmup/modules/validation"
 	"github.com/kumose/kmup/routers/api/packages/helper"
 	"github.com/kumose/kmup/services/context"
 	packages_service "github.com/kumose/kmup/services/packages"
+	remote_service "github.com/kumose/kmup/services/packages/remote"
 )
 
 // https://peps.python.org/pep-0426/#name
@@ -53,15 +62,78 @@ var versionMatcher = regexp.MustCompile(`\Av?` +
 	`(?:\+[a-z0-9]+(?:[-_\.][a-z0-9]+)*)?` + // local version
 	`\z`)
 
+const coreMetadataSuffix = ".metadata"
+
 func apiError(ctx *context.Context, status int, obj any) {
 	message := helper.ProcessErrorForUser(ctx, status, obj)
 	ctx.PlainText(status, message)
 }
 
+// ProjectIndex returns the list of all projects
+// https://peps.python.org/pep-0503/ and https://peps.python.org/pep-0691/
+func ProjectIndex(ctx *context.Context) {
+	contentType := pypi_module.NegotiateContentType(ctx.Req.Header.Get("Accept"))
+	if contentType == "" {
+		apiError(ctx, http.StatusNotAcceptable, "unsupported content type")
+		return
+	}
+
+	ps, err := packages_model.GetPackagesByType(ctx, ctx.Package.Owner.ID, packages_model.TypePyPI)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	sort.Slice(ps, func(i, j int) bool {
+		return ps[i].LowerName < ps[j].LowerName
+	})
+
+	index := &pypi_module.SimpleIndex{
+		Meta:     pypi_module.SimpleMeta{APIVersion: pypi_module.SimpleAPIVersion},
+		Projects: make([]*pypi_module.SimpleIndexProject, 0, len(ps)),
+	}
+	for _, p := range ps {
+		index.Projects = append(index.Projects, &pypi_module.SimpleIndexProject{Name: p.Name})
+	}
+
+	ctx.Data["RegistryURL"] = setting.AppURL + "api/packages/" + ctx.Package.Owner.Name + "/pypi"
+	ctx.Data["Index"] = index
+
+	serveSimpleResponse(ctx, contentType, "api/packages/pypi/index", index)
+}
+
 // PackageMetadata returns the metadata for a single package
 func PackageMetadata(ctx *context.Context) {
 	packageName := normalizer.Replace(ctx.PathParam("id"))
 
+	contentType := pypi_module.NegotiateContentType(ctx.Req.Header.Get("Accept"))
+	if contentType == "" {
+		apiError(ctx, http.StatusNotAcceptable, "unsupported content type")
+		return
+	}
+
+	registryURL := setting.AppURL + "api/packages/" + ctx.Package.Owner.Name + "/pypi"
+
+	pr, err := remote_service.GetRemoteForPackage(ctx, ctx.Package.Owner.ID, packages_model.TypePyPI, packageName)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if pr != nil {
+		client, err := remote_service.NewClient(pr)
+		if err != nil {
+			apiError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+		files, err := getRemoteProjectFiles(ctx, client, packageName)
+		if err == nil {
+			serveSimpleProject(ctx, contentType, createRemoteSimpleProject(registryURL, packageName, files))
+			return
+		}
+		// serve the cached versions if upstream is not available
+		log.Warn("Fetching PyPI package %s from upstream failed: %v", packageName, err)
+	}
+
 	pvs, err := packages_model.GetVersionsByPackageName(ctx, ctx.Package.Owner.ID, packages_model.TypePyPI, packageName)
 	if err != nil {
 		apiError(ctx, http.StatusInternalServerError, err)
@@ -83,10 +155,80 @@ func PackageMetadata(ctx *context.Context) {
 		return strings.Compare(pds[i].Version.Version, pds[j].Version.Version) < 0
 	})
 
-	ctx.Data["RegistryURL"] = setting.AppURL + "api/packages/" + ctx.Package.Owner.Name + "/pypi"
-	ctx.Data["PackageDescriptor"] = pds[0]
-	ctx.Data["PackageDescriptors"] = pds
-	ctx.HTML(http.StatusOK, "api/packages/pypi/simple")
+	serveSimpleProject(ctx, contentType, createSimpleProject(registryURL, pds))
+}
+
+func createSimpleProject(registryURL string, pds []*packages_model.PackageDescriptor) *pypi_module.SimpleProject {
+	project := &pypi_module.SimpleProject{
+		Meta:     pypi_module.SimpleMeta{APIVersion: pypi_module.SimpleAPIVersion},
+		Name:     pds[0].Package.Name,
+		Files:    make([]*pypi_module.SimpleFile, 0, len(pds)),
+		Versions: make([]string, 0, len(pds)),
+	}
+
+	for _, pd := range pds {
+		project.Versions = append(project.Versions, pd.Version.Version)
+
+		metadata := pd.Metadata.(*pypi_module.Metadata)
+
+		var yanked any
+		if pd.VersionProperties.Has(pypi_module.PropertyYanked) {
+			yanked = true
+			if reason := pd.VersionProperties.GetByName(pypi_module.PropertyYanked); reason != "" {
+				yanked = reason
+			}
+		}
+
+		for _, pfd := range pd.Files {
+			f := &pypi_module.SimpleFile{
+				Filename:       pfd.File.Name,
+				URL:            fmt.Sprintf("%s/files/%s/%s/%s", registryURL, pd.Package.LowerName, pd.Version.Version, pfd.File.Name),
+				Hashes:         map[string]string{"sha256": pfd.Blob.HashSHA256},
+				RequiresPython: metadata.RequiresPython,
+				Yanked:         yanked,
+				Size:           pfd.Blob.Size,
+				UploadTime:     pfd.File.CreatedUnix.AsLocalTime().UTC().Format(time.RFC3339),
+			}
+			if pfd.Properties.Has(pypi_module.PropertyCoreMetadata) {
+				hash := sha256.Sum256([]byte(pfd.Properties.GetByName(pypi_module.PropertyCoreMetadata)))
+				f.CoreMetadata = map[string]string{"sha256": hex.EncodeToString(hash[:])}
+				f.DistInfoMetadata = f.CoreMetadata
+			}
+			project.Files = append(project.Files, f)
+		}
+	}
+
+	return project
+}
+
+func serveSimpleProject(ctx *context.Context, contentType string, project *pypi_module.SimpleProject) {
+	ctx.Data["Project"] = project
+
+	serveSimpleResponse(ctx, contentType, "api/packages/pypi/simple", project)
+}
+
+// serveSimpleResponse writes the JSON or HTML variant of a Simple Repository API response
+func serveSimpleResponse(ctx *context.Context, contentType string, tpl templates.TplName, obj any) {
+	ctx.Resp.Header().Add("Vary", "Accept")
+
+	if contentType == pypi_module.SimpleContentType {
+		ctx.Resp.Header().Set("Content-Type", contentType)
+		ctx.Resp.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(ctx.Resp).Encode(obj); err != nil {
+			log.Error("JSON encode: %v", err)
+		}
+		return
+	}
+
+	content, err := ctx.RenderToHTML(tpl, ctx.Data)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.Resp.Header().Set("Content-Type", contentType+"; charset=utf-8")
+	ctx.Resp.WriteHeader(http.StatusOK)
+	_, _ = ctx.Resp.Write([]byte(content))
 }
 
 // DownloadPackageFile serves the content of a package
@@ -95,6 +237,11 @@ func DownloadPackageFile(ctx *context.Context) {
 	packageVersion := ctx.PathParam("version")
 	filename := ctx.PathParam("filename")
 
+	if strings.HasSuffix(filename, coreMetadataSuffix) {
+		downloadCoreMetadata(ctx, packageName, packageVersion, strings.TrimSuffix(filename, coreMetadataSuffix))
+		return
+	}
+
 	s, u, pf, err := packages_service.OpenFileForDownloadByPackageNameAndVersion(
 		ctx,
 		&packages_service.PackageInfo{
@@ -108,8 +255,11 @@ func DownloadPackageFile(ctx *context.Context) {
 		},
 		ctx.Req.Method,
 	)
+	if errors.Is(err, packages_model.ErrPackageNotExist) || errors.Is(err, packages_model.ErrPackageFileNotExist) {
+		s, u, pf, err = downloadRemotePackageFile(ctx, packageName, packageVersion, filename)
+	}
 	if err != nil {
-		if errors.Is(err, packages_model.ErrPackageNotExist) || errors.Is(err, packages_model.ErrPackageFileNotExist) {
+		if errors.Is(err, util.ErrNotExist) {
 			apiError(ctx, http.StatusNotFound, err)
 			return
 		}
@@ -120,6 +270,76 @@ func DownloadPackageFile(ctx *context.Context) {
 	helper.ServePackageFile(ctx, s, u, pf)
 }
 
+// downloadCoreMetadata serves the METADATA file of a wheel
+// https://peps.python.org/pep-0658/
+func downloadCoreMetadata(ctx *context.Context, packageName, packageVersion, filename string) {
+	pv, err := packages_model.GetVersionByNameAndVersion(ctx, ctx.Package.Owner.ID, packages_model.TypePyPI, packageName, packageVersion)
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			apiError(ctx, http.StatusNotFound, err)
+		} else {
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	pf, err := packages_model.GetFileForVersionByName(ctx, pv.ID, filename, packages_model.EmptyFileKey)
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			apiError(ctx, http.StatusNotFound, err)
+		} else {
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	pps, err := packages_model.GetPropertiesByName(ctx, packages_model.PropertyTypeFile, pf.ID, pypi_module.PropertyCoreMetadata)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if len(pps) == 0 {
+		apiError(ctx, http.StatusNotFound, nil)
+		return
+	}
+
+	ctx.ServeContent(strings.NewReader(pps[0].Value), &context.ServeHeaderOptions{
+		Filename:     filename + coreMetadataSuffix,
+		ContentType:  "text/plain; charset=utf-8",
+		LastModified: pf.CreatedUnix.AsLocalTime(),
+	})
+}
+
+// downloadRemotePackageFile fetches a missing package file from the upstream registry, if the owner has configured one
+func downloadRemotePackageFile(ctx *context.Context, packageName, packageVersion, filename string) (io.ReadSeekCloser, *url.URL, *packages_model.PackageFile, error) {
+	pr, err := remote_service.GetRemoteForPackage(ctx, ctx.Package.Owner.ID, packages_model.TypePyPI, packageName)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if pr == nil {
+		return nil, nil, nil, packages_model.ErrPackageNotExist
+	}
+
+	client, err := remote_service.NewClient(pr)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	pv, err := cacheRemotePackageFile(ctx, client, packageName, packageVersion, filename)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return packages_service.OpenFileForDownloadByPackageVersion(
+		ctx,
+		pv,
+		&packages_service.PackageFileInfo{
+			Filename: filename,
+		},
+		ctx.Req.Method,
+	)
+}
+
 // UploadPackageFile adds a file to the package. If the package does not exist, it gets created.
 func UploadPackageFile(ctx *context.Context) {
 	file, fileHeader, err := ctx.Req.FormFile("content")
@@ -181,6 +401,12 @@ func UploadPackageFile(ctx *context.Context) {
 		homepageURL = ""
 	}
 
+	fileProperties, err := extractFileProperties(fileHeader.Filename, buf)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
 	_, _, err = packages_service.CreatePackageOrAddFileToExisting(
 		ctx,
 		&packages_service.PackageCreationInfo{
@@ -206,9 +432,10 @@ func UploadPackageFile(ctx *context.Context) {
 			PackageFileInfo: packages_service.PackageFileInfo{
 				Filename: fileHeader.Filename,
 			},
-			Creator: ctx.Doer,
-			Data:    buf,
-			IsLead:  true,
+			Creator:    ctx.Doer,
+			Data:       buf,
+			IsLead:     true,
+			Properties: fileProperties,
 		},
 	)
 	if err != nil {
@@ -226,6 +453,64 @@ func UploadPackageFile(ctx *context.Context) {
 	ctx.Status(http.StatusCreated)
 }
 
+// extractFileProperties extracts the METADATA file of a wheel to serve it separately.
+// Files which are not a valid wheel are accepted without it.
+func extractFileProperties(filename string, buf *packages_module.HashedBuffer) (map[string]string, error) {
+	if !pypi_module.IsWheel(filename) {
+		return nil, nil
+	}
+
+	metadata, err := pypi_module.ExtractCoreMetadata(buf, buf.Size())
+	if _, seekErr := buf.Seek(0, io.SeekStart); seekErr != nil {
+		return nil, seekErr
+	}
+	if err != nil {
+		log.Debug("Extracting METADATA from %s failed: %v", filename, err)
+		return nil, nil
+	}
+
+	return map[string]string{
+		pypi_module.PropertyCoreMetadata: string(metadata),
+	}, nil
+}
+
+// YankPackage marks a version as yanked, the optional reason is shown to the users
+// https://peps.python.org/pep-0592/
+func YankPackage(ctx *context.Context) {
+	yankPackage(ctx, true)
+}
+
+// UnyankPackage removes the yanked mark of a version
+func UnyankPackage(ctx *context.Context) {
+	yankPackage(ctx, false)
+}
+
+func yankPackage(ctx *context.Context, yank bool) {
+	pv, err := packages_model.GetVersionByNameAndVersion(ctx, ctx.Package.Owner.ID, packages_model.TypePyPI, normalizer.Replace(ctx.PathParam("id")), ctx.PathParam("version"))
+	if err != nil {
+		if errors.Is(err, packages_model.ErrPackageNotExist) {
+			apiError(ctx, http.StatusNotFound, err)
+		} else {
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	if err := packages_model.DeletePropertiesByName(ctx, packages_model.PropertyTypeVersion, pv.ID, pypi_module.PropertyYanked); err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	if yank {
+		if _, err := packages_model.InsertProperty(ctx, packages_model.PropertyTypeVersion, pv.ID, pypi_module.PropertyYanked, strings.TrimSpace(ctx.Req.FormValue("reason"))); err != nil {
+			apiError(ctx, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
 // Normalizes a Project-URL label.
 // See https://packaging.python.org/en/latest/specifications/well-known-project-urls/#label-normalization.
 func normalizeLabel(label string) string {
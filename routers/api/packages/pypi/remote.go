@@ -0,0 +1,180 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package pypi
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	packages_model "github.com/kumose/kmup/models/packages"
+	"github.com/kumose/kmup/modules/container"
+	"github.com/kumose/kmup/modules/json"
+	pypi_module "github.com/kumose/kmup/modules/packages/pypi"
+	"github.com/kumose/kmup/services/context"
+	packages_service "github.com/kumose/kmup/services/packages"
+	remote_service "github.com/kumose/kmup/services/packages/remote"
+)
+
+// remoteFile is a file listed on the upstream project page
+type remoteFile struct {
+	*pypi_module.SimpleFile
+	Version string
+	URL     string
+}
+
+// getRemoteProjectFiles fetches the project page from the upstream registry.
+// The remote URL is the index URL of the upstream registry, for example https://pypi.org/simple
+func getRemoteProjectFiles(ctx *context.Context, client *remote_service.Client, packageName string) ([]*remoteFile, error) {
+	pagePath := pypi_module.NormalizeName(packageName) + "/"
+
+	data, err := client.GetDocument(ctx, pagePath, http.Header{"Accept": []string{pypi_module.SimpleContentType}})
+	if err != nil {
+		return nil, err
+	}
+
+	var project pypi_module.SimpleProject
+	if err := json.Unmarshal(data, &project); err != nil {
+		return nil, err
+	}
+
+	pageURL, err := client.ResolveURL(pagePath)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]*remoteFile, 0, len(project.Files))
+	for _, f := range project.Files {
+		version, ok := pypi_module.ParseFilenameVersion(packageName, f.Filename)
+		if !ok || !isValidNameAndVersion(packageName, version) || f.Hashes["sha256"] == "" {
+			continue
+		}
+		fileURL, err := pageURL.Parse(f.URL)
+		if err != nil {
+			continue
+		}
+		files = append(files, &remoteFile{
+			SimpleFile: f,
+			Version:    version,
+			URL:        fileURL.String(),
+		})
+	}
+	return files, nil
+}
+
+// createRemoteSimpleProject creates the project page for the upstream files which are downloaded through this registry.
+// The core metadata files are not proxied, so they are not advertised.
+func createRemoteSimpleProject(registryURL, packageName string, files []*remoteFile) *pypi_module.SimpleProject {
+	project := &pypi_module.SimpleProject{
+		Meta:  pypi_module.SimpleMeta{APIVersion: pypi_module.SimpleAPIVersion},
+		Name:  packageName,
+		Files: make([]*pypi_module.SimpleFile, 0, len(files)),
+	}
+
+	versions := make(container.Set[string])
+	for _, f := range files {
+		if versions.Add(f.Version) {
+			project.Versions = append(project.Versions, f.Version)
+		}
+
+		project.Files = append(project.Files, &pypi_module.SimpleFile{
+			Filename:       f.Filename,
+			URL:            fmt.Sprintf("%s/files/%s/%s/%s", registryURL, strings.ToLower(packageName), f.Version, f.Filename),
+			Hashes:         map[string]string{"sha256": f.Hashes["sha256"]},
+			RequiresPython: f.RequiresPython,
+			Yanked:         f.Yanked,
+			Size:           f.Size,
+			UploadTime:     f.UploadTime,
+		})
+	}
+
+	return project
+}
+
+// cacheRemotePackageFile downloads a file from the upstream registry and stores it in a cached version
+func cacheRemotePackageFile(ctx *context.Context, client *remote_service.Client, packageName, packageVersion, filename string) (*packages_model.PackageVersion, error) {
+	files, err := getRemoteProjectFiles(ctx, client, packageName)
+	if err != nil {
+		return nil, err
+	}
+
+	var rf *remoteFile
+	for _, f := range files {
+		if f.Filename == filename && f.Version == packageVersion {
+			rf = f
+			break
+		}
+	}
+	if rf == nil {
+		return nil, packages_model.ErrPackageFileNotExist
+	}
+
+	buf, err := client.Download(ctx, rf.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer buf.Close()
+
+	_, _, hashSHA256, _ := buf.Sums()
+	if !strings.EqualFold(rf.Hashes["sha256"], hex.EncodeToString(hashSHA256)) {
+		return nil, errors.New("hash of the upstream file does not match")
+	}
+
+	fileProperties, err := extractFileProperties(rf.Filename, buf)
+	if err != nil {
+		return nil, err
+	}
+
+	creator := remote_service.Creator(ctx.Doer)
+
+	pv, _, err := packages_service.CreatePackageOrAddFileToExisting(
+		ctx,
+		&packages_service.PackageCreationInfo{
+			PackageInfo: packages_service.PackageInfo{
+				Owner:       ctx.Package.Owner,
+				PackageType: packages_model.TypePyPI,
+				Name:        packageName,
+				Version:     packageVersion,
+			},
+			SemverCompatible: false,
+			IsRemote:         true,
+			Creator:          creator,
+			Metadata: &pypi_module.Metadata{
+				RequiresPython: rf.RequiresPython,
+			},
+		},
+		&packages_service.PackageFileCreationInfo{
+			PackageFileInfo: packages_service.PackageFileInfo{
+				Filename: rf.Filename,
+			},
+			Creator:    creator,
+			Data:       buf,
+			IsLead:     true,
+			Properties: fileProperties,
+		},
+	)
+	if err != nil {
+		if !errors.Is(err, packages_model.ErrDuplicatePackageFile) {
+			return nil, err
+		}
+		return packages_model.GetVersionByNameAndVersion(ctx, ctx.Package.Owner.ID, packages_model.TypePyPI, packageName, packageVersion)
+	}
+	return pv, nil
+}
@@ -0,0 +1,256 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package terraform
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	packages_model "github.com/kumose/kmup/models/packages"
+	packages_module "github.com/kumose/kmup/modules/packages"
+	terraform_module "github.com/kumose/kmup/modules/packages/terraform"
+	"github.com/kumose/kmup/modules/util"
+	"github.com/kumose/kmup/routers/api/packages/helper"
+	"github.com/kumose/kmup/services/context"
+	packages_service "github.com/kumose/kmup/services/packages"
+
+	"github.com/hashicorp/go-version"
+)
+
+func modulePackageName(ctx *context.Context) (string, bool) {
+	name := ctx.PathParam("name")
+	system := ctx.PathParam("system")
+	if !terraform_module.IsValidModuleName(name) || !terraform_module.IsValidModuleSystem(system) {
+		return "", false
+	}
+	return terraform_module.ModulePackageName(name, system), true
+}
+
+// https://developer.hashicorp.com/terraform/internals/module-registry-protocol#list-available-versions-for-a-specific-module
+func EnumerateModuleVersions(ctx *context.Context) {
+	packageName, ok := modulePackageName(ctx)
+	if !ok {
+		apiError(ctx, http.StatusNotFound, packages_model.ErrPackageNotExist)
+		return
+	}
+
+	pvs, err := packages_model.GetVersionsByPackageName(ctx, ctx.Package.Owner.ID, packages_model.TypeTerraformModule, packageName)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if len(pvs) == 0 {
+		apiError(ctx, http.StatusNotFound, packages_model.ErrPackageNotExist)
+		return
+	}
+
+	type moduleVersion struct {
+		Version string `json:"version"`
+	}
+	type module struct {
+		Versions []moduleVersion `json:"versions"`
+	}
+
+	versions := make([]moduleVersion, 0, len(pvs))
+	for _, pv := range pvs {
+		versions = append(versions, moduleVersion{Version: pv.Version})
+	}
+
+	jsonResponse(ctx, http.StatusOK, struct {
+		Modules []module `json:"modules"`
+	}{
+		Modules: []module{{Versions: versions}},
+	})
+}
+
+func getModuleDescriptor(ctx *context.Context) (*packages_model.PackageDescriptor, error) {
+	packageName, ok := modulePackageName(ctx)
+	if !ok {
+		return nil, packages_model.ErrPackageNotExist
+	}
+
+	pv, err := packages_model.GetVersionByNameAndVersion(ctx, ctx.Package.Owner.ID, packages_model.TypeTerraformModule, packageName, ctx.PathParam("version"))
+	if err != nil {
+		return nil, err
+	}
+
+	return packages_model.GetPackageDescriptor(ctx, pv)
+}
+
+// https://developer.hashicorp.com/terraform/internals/module-registry-protocol#download-source-code-for-a-specific-module-version
+func DownloadModule(ctx *context.Context) {
+	pd, err := getModuleDescriptor(ctx)
+	if err != nil {
+		if errors.Is(err, packages_model.ErrPackageNotExist) {
+			apiError(ctx, http.StatusNotFound, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	metadata := pd.Metadata.(*terraform_module.ModuleMetadata)
+
+	ctx.Resp.Header().Set("X-Terraform-Get", fmt.Sprintf(
+		"%s%s/%s/%s/%s/archive.tar.gz",
+		modulesBaseURL(),
+		url.PathEscape(pd.Owner.Name),
+		url.PathEscape(metadata.Name),
+		url.PathEscape(metadata.System),
+		url.PathEscape(pd.Version.Version),
+	))
+	ctx.Status(http.StatusNoContent)
+}
+
+// DownloadModuleArchive serves the archive referenced by the X-Terraform-Get header
+func DownloadModuleArchive(ctx *context.Context) {
+	pd, err := getModuleDescriptor(ctx)
+	if err != nil {
+		if errors.Is(err, packages_model.ErrPackageNotExist) {
+			apiError(ctx, http.StatusNotFound, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	pf := pd.Files[0].File
+
+	s, u, _, err := packages_service.OpenFileForDownload(ctx, pf, ctx.Req.Method)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	helper.ServePackageFile(ctx, s, u, pf)
+}
+
+// UploadModule publishes a new module version from a .tar.gz archive
+func UploadModule(ctx *context.Context) {
+	name := ctx.PathParam("name")
+	system := ctx.PathParam("system")
+
+	v, err := version.NewSemver(ctx.PathParam("version"))
+	if err != nil {
+		apiError(ctx, http.StatusBadRequest, terraform_module.ErrInvalidVersion)
+		return
+	}
+
+	upload, needsClose, err := ctx.UploadStream()
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if needsClose {
+		defer upload.Close()
+	}
+
+	buf, err := packages_module.CreateHashedBufferFromReader(upload)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	defer buf.Close()
+
+	metadata, err := terraform_module.ParseModuleArchive(buf, name, system)
+	if err != nil {
+		if errors.Is(err, util.ErrInvalidArgument) {
+			apiError(ctx, http.StatusBadRequest, err)
+		} else {
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	if _, err := buf.Seek(0, io.SeekStart); err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	packageName := terraform_module.ModulePackageName(name, system)
+
+	_, _, err = packages_service.CreatePackageAndAddFile(
+		ctx,
+		&packages_service.PackageCreationInfo{
+			PackageInfo: packages_service.PackageInfo{
+				Owner:       ctx.Package.Owner,
+				PackageType: packages_model.TypeTerraformModule,
+				Name:        packageName,
+				Version:     v.String(),
+			},
+			SemverCompatible: true,
+			Creator:          ctx.Doer,
+			Metadata:         metadata,
+		},
+		&packages_service.PackageFileCreationInfo{
+			PackageFileInfo: packages_service.PackageFileInfo{
+				Filename: strings.ToLower(fmt.Sprintf("%s-%s.tar.gz", packageName, v.String())),
+			},
+			Creator: ctx.Doer,
+			Data:    buf,
+			IsLead:  true,
+		},
+	)
+	if err != nil {
+		switch err {
+		case packages_model.ErrDuplicatePackageVersion:
+			apiError(ctx, http.StatusConflict, err)
+		case packages_service.ErrQuotaTotalCount, packages_service.ErrQuotaTypeSize, packages_service.ErrQuotaTotalSize:
+			apiError(ctx, http.StatusForbidden, err)
+		default:
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	ctx.Status(http.StatusCreated)
+}
+
+// DeleteModuleVersion deletes a module version
+func DeleteModuleVersion(ctx *context.Context) {
+	packageName, ok := modulePackageName(ctx)
+	if !ok {
+		apiError(ctx, http.StatusNotFound, packages_model.ErrPackageNotExist)
+		return
+	}
+
+	err := packages_service.RemovePackageVersionByNameAndVersion(
+		ctx,
+		ctx.Doer,
+		&packages_service.PackageInfo{
+			Owner:       ctx.Package.Owner,
+			PackageType: packages_model.TypeTerraformModule,
+			Name:        packageName,
+			Version:     ctx.PathParam("version"),
+		},
+	)
+	if err != nil {
+		if errors.Is(err, packages_model.ErrPackageNotExist) {
+			apiError(ctx, http.StatusNotFound, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,449 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package terraform
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	asymkey_model "github.com/kumose/kmup/models/asymkey"
+	"github.com/kumose/kmup/models/db"
+	packages_model "github.com/kumose/kmup/models/packages"
+	"github.com/kumose/kmup/modules/log"
+	packages_module "github.com/kumose/kmup/modules/packages"
+	terraform_module "github.com/kumose/kmup/modules/packages/terraform"
+	"github.com/kumose/kmup/modules/util"
+	"github.com/kumose/kmup/routers/api/packages/helper"
+	"github.com/kumose/kmup/services/context"
+	packages_service "github.com/kumose/kmup/services/packages"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/hashicorp/go-version"
+)
+
+var (
+	errMissingSHA256Sums      = util.NewInvalidArgumentErrorf("the SHA256SUMS file must be uploaded before its signature")
+	errInvalidSignature       = util.NewInvalidArgumentErrorf("the signature could not be verified with the GPG keys of the uploader")
+	errProviderNotPublished   = util.NewNotExistErrorf("the provider version has no verified signature")
+	errPlatformNotAvailable   = util.NewNotExistErrorf("the provider version is not available for this platform")
+	errSigningKeyNotAvailable = util.NewNotExistErrorf("the signing key of the provider version is not available")
+)
+
+type providerPlatform struct {
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+}
+
+func providerPlatforms(pd *packages_model.PackageDescriptor) []providerPlatform {
+	platforms := make([]providerPlatform, 0, len(pd.Files))
+	for _, pfd := range pd.Files {
+		if os := pfd.Properties.GetByName(terraform_module.PropertyOS); os != "" {
+			platforms = append(platforms, providerPlatform{
+				OS:   os,
+				Arch: pfd.Properties.GetByName(terraform_module.PropertyArch),
+			})
+		}
+	}
+	return platforms
+}
+
+func providerProtocols(pd *packages_model.PackageDescriptor) []string {
+	if protocols := pd.VersionProperties.GetByName(terraform_module.PropertyProtocols); protocols != "" {
+		return strings.Split(protocols, ",")
+	}
+	return terraform_module.DefaultProtocols
+}
+
+// https://developer.hashicorp.com/terraform/internals/provider-registry-protocol#list-available-versions
+func EnumerateProviderVersions(ctx *context.Context) {
+	pvs, err := packages_model.GetVersionsByPackageName(ctx, ctx.Package.Owner.ID, packages_model.TypeTerraformProvider, ctx.PathParam("provider"))
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	pds, err := packages_model.GetPackageDescriptors(ctx, pvs)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	sort.Slice(pds, func(i, j int) bool {
+		return pds[i].SemVer.LessThan(pds[j].SemVer)
+	})
+
+	type providerVersion struct {
+		Version   string             `json:"version"`
+		Protocols []string           `json:"protocols"`
+		Platforms []providerPlatform `json:"platforms"`
+	}
+
+	versions := make([]providerVersion, 0, len(pds))
+	for _, pd := range pds {
+		// only versions with a verified signature can be installed
+		if !pd.VersionProperties.Has(terraform_module.PropertySigningKeyID) {
+			continue
+		}
+		versions = append(versions, providerVersion{
+			Version:   pd.Version.Version,
+			Protocols: providerProtocols(pd),
+			Platforms: providerPlatforms(pd),
+		})
+	}
+	if len(versions) == 0 {
+		apiError(ctx, http.StatusNotFound, packages_model.ErrPackageNotExist)
+		return
+	}
+
+	jsonResponse(ctx, http.StatusOK, struct {
+		Versions []providerVersion `json:"versions"`
+	}{
+		Versions: versions,
+	})
+}
+
+// https://developer.hashicorp.com/terraform/internals/provider-registry-protocol#find-a-provider-package
+func DownloadProviderPlatform(ctx *context.Context) {
+	pv, err := packages_model.GetVersionByNameAndVersion(ctx, ctx.Package.Owner.ID, packages_model.TypeTerraformProvider, ctx.PathParam("provider"), ctx.PathParam("version"))
+	if err != nil {
+		if errors.Is(err, packages_model.ErrPackageNotExist) {
+			apiError(ctx, http.StatusNotFound, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	pd, err := packages_model.GetPackageDescriptor(ctx, pv)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	keyID := pd.VersionProperties.GetByName(terraform_module.PropertySigningKeyID)
+	if keyID == "" {
+		apiError(ctx, http.StatusNotFound, errProviderNotPublished)
+		return
+	}
+
+	os, arch := ctx.PathParam("os"), ctx.PathParam("arch")
+
+	var archive *packages_model.PackageFileDescriptor
+	for _, pfd := range pd.Files {
+		if pfd.Properties.GetByName(terraform_module.PropertyOS) == os && pfd.Properties.GetByName(terraform_module.PropertyArch) == arch {
+			archive = pfd
+			break
+		}
+	}
+	if archive == nil {
+		apiError(ctx, http.StatusNotFound, errPlatformNotAvailable)
+		return
+	}
+
+	keyImport, err := asymkey_model.GetGPGImportByKeyID(ctx, keyID)
+	if err != nil {
+		if asymkey_model.IsErrGPGKeyImportNotExist(err) {
+			apiError(ctx, http.StatusNotFound, errSigningKeyNotAvailable)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	type gpgPublicKey struct {
+		KeyID      string `json:"key_id"`
+		ASCIIArmor string `json:"ascii_armor"`
+	}
+	type signingKeys struct {
+		GPGPublicKeys []gpgPublicKey `json:"gpg_public_keys"`
+	}
+	type providerPackage struct {
+		Protocols           []string    `json:"protocols"`
+		OS                  string      `json:"os"`
+		Arch                string      `json:"arch"`
+		Filename            string      `json:"filename"`
+		DownloadURL         string      `json:"download_url"`
+		SHASumsURL          string      `json:"shasums_url"`
+		SHASumsSignatureURL string      `json:"shasums_signature_url"`
+		SHASum              string      `json:"shasum"`
+		SigningKeys         signingKeys `json:"signing_keys"`
+	}
+
+	filesURL := fmt.Sprintf(
+		"%s%s/%s/%s/files/",
+		providersBaseURL(),
+		url.PathEscape(pd.Owner.Name),
+		url.PathEscape(pd.Package.Name),
+		url.PathEscape(pd.Version.Version),
+	)
+
+	jsonResponse(ctx, http.StatusOK, &providerPackage{
+		Protocols:           providerProtocols(pd),
+		OS:                  os,
+		Arch:                arch,
+		Filename:            archive.File.Name,
+		DownloadURL:         filesURL + url.PathEscape(archive.File.Name),
+		SHASumsURL:          filesURL + url.PathEscape(terraform_module.SHA256SumsFilename(pd.Package.Name, pd.Version.Version)),
+		SHASumsSignatureURL: filesURL + url.PathEscape(terraform_module.SignatureFilename(pd.Package.Name, pd.Version.Version)),
+		SHASum:              archive.Blob.HashSHA256,
+		SigningKeys: signingKeys{
+			GPGPublicKeys: []gpgPublicKey{
+				{
+					KeyID:      keyID,
+					ASCIIArmor: keyImport.Content,
+				},
+			},
+		},
+	})
+}
+
+// DownloadProviderFile serves a file of a provider release
+func DownloadProviderFile(ctx *context.Context) {
+	s, u, pf, err := packages_service.OpenFileForDownloadByPackageNameAndVersion(
+		ctx,
+		&packages_service.PackageInfo{
+			Owner:       ctx.Package.Owner,
+			PackageType: packages_model.TypeTerraformProvider,
+			Name:        ctx.PathParam("provider"),
+			Version:     ctx.PathParam("version"),
+		},
+		&packages_service.PackageFileInfo{
+			Filename: ctx.PathParam("filename"),
+		},
+		ctx.Req.Method,
+	)
+	if err != nil {
+		if errors.Is(err, packages_model.ErrPackageNotExist) || errors.Is(err, packages_model.ErrPackageFileNotExist) {
+			apiError(ctx, http.StatusNotFound, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	helper.ServePackageFile(ctx, s, u, pf)
+}
+
+// UploadProviderFile adds a file to a provider release.
+// The release is installable once the SHA256SUMS file and its signature are uploaded.
+// The signature must be created by one of the GPG keys of the uploader.
+func UploadProviderFile(ctx *context.Context) {
+	providerType := ctx.PathParam("provider")
+	providerVersion := ctx.PathParam("version")
+	filename := ctx.PathParam("filename")
+
+	v, err := version.NewSemver(providerVersion)
+	if err != nil || v.String() != providerVersion {
+		apiError(ctx, http.StatusBadRequest, terraform_module.ErrInvalidVersion)
+		return
+	}
+
+	providerFile, err := terraform_module.ParseProviderFilename(providerType, providerVersion, filename)
+	if err != nil {
+		apiError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	upload, needsClose, err := ctx.UploadStream()
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if needsClose {
+		defer upload.Close()
+	}
+
+	buf, err := packages_module.CreateHashedBufferFromReader(upload)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	defer buf.Close()
+
+	var protocols []string
+	var signingKeyID string
+	properties := map[string]string{}
+
+	switch providerFile.Kind {
+	case terraform_module.ProviderFileArchive:
+		properties[terraform_module.PropertyOS] = providerFile.OS
+		properties[terraform_module.PropertyArch] = providerFile.Arch
+	case terraform_module.ProviderFileManifest:
+		protocols, err = terraform_module.ParseProviderManifest(buf)
+	case terraform_module.ProviderFileSHA256Sums:
+		_, err = terraform_module.ParseSHA256Sums(buf)
+	case terraform_module.ProviderFileSignature:
+		signingKeyID, err = verifySHA256SumsSignature(ctx, providerType, providerVersion, buf)
+	}
+	if err != nil {
+		if errors.Is(err, util.ErrInvalidArgument) {
+			apiError(ctx, http.StatusBadRequest, err)
+		} else {
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	if _, err := buf.Seek(0, io.SeekStart); err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	pv, _, err := packages_service.CreatePackageOrAddFileToExisting(
+		ctx,
+		&packages_service.PackageCreationInfo{
+			PackageInfo: packages_service.PackageInfo{
+				Owner:       ctx.Package.Owner,
+				PackageType: packages_model.TypeTerraformProvider,
+				Name:        providerType,
+				Version:     providerVersion,
+			},
+			SemverCompatible: true,
+			Creator:          ctx.Doer,
+		},
+		&packages_service.PackageFileCreationInfo{
+			PackageFileInfo: packages_service.PackageFileInfo{
+				Filename: filename,
+			},
+			Creator:    ctx.Doer,
+			Data:       buf,
+			Properties: properties,
+		},
+	)
+	if err != nil {
+		switch err {
+		case packages_model.ErrDuplicatePackageFile:
+			apiError(ctx, http.StatusConflict, err)
+		case packages_service.ErrQuotaTotalCount, packages_service.ErrQuotaTypeSize, packages_service.ErrQuotaTotalSize:
+			apiError(ctx, http.StatusForbidden, err)
+		default:
+			apiError(ctx, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	if len(protocols) > 0 {
+		err = packages_model.InsertOrUpdateProperty(ctx, packages_model.PropertyTypeVersion, pv.ID, terraform_module.PropertyProtocols, strings.Join(protocols, ","))
+	} else if signingKeyID != "" {
+		err = packages_model.InsertOrUpdateProperty(ctx, packages_model.PropertyTypeVersion, pv.ID, terraform_module.PropertySigningKeyID, signingKeyID)
+	}
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.Status(http.StatusCreated)
+}
+
+// verifySHA256SumsSignature checks the detached signature of the already uploaded SHA256SUMS file
+// against the GPG keys of the uploader and returns the id of the primary key which created it
+func verifySHA256SumsSignature(ctx *context.Context, providerType, providerVersion string, signature io.Reader) (string, error) {
+	pv, err := packages_model.GetVersionByNameAndVersion(ctx, ctx.Package.Owner.ID, packages_model.TypeTerraformProvider, providerType, providerVersion)
+	if err != nil {
+		if errors.Is(err, packages_model.ErrPackageNotExist) {
+			return "", errMissingSHA256Sums
+		}
+		return "", err
+	}
+
+	pf, err := packages_model.GetFileForVersionByName(ctx, pv.ID, terraform_module.SHA256SumsFilename(providerType, providerVersion), packages_model.EmptyFileKey)
+	if err != nil {
+		if errors.Is(err, packages_model.ErrPackageFileNotExist) {
+			return "", errMissingSHA256Sums
+		}
+		return "", err
+	}
+
+	pb, err := packages_model.GetBlobByID(ctx, pf.BlobID)
+	if err != nil {
+		return "", err
+	}
+
+	s, err := packages_service.OpenBlobStream(pb)
+	if err != nil {
+		return "", err
+	}
+	defer s.Close()
+
+	sums, err := io.ReadAll(s)
+	if err != nil {
+		return "", err
+	}
+
+	sig, err := io.ReadAll(signature)
+	if err != nil {
+		return "", err
+	}
+
+	keys, err := db.Find[asymkey_model.GPGKey](ctx, asymkey_model.FindGPGKeyOptions{OwnerID: ctx.Doer.ID})
+	if err != nil {
+		return "", err
+	}
+
+	keyring := make(openpgp.EntityList, 0, len(keys))
+	for _, key := range keys {
+		entity, err := asymkey_model.GPGKeyToEntity(ctx, key)
+		if err != nil {
+			log.Warn("Unable to load GPG key %s of user %d: %v", key.KeyID, key.OwnerID, err)
+			continue
+		}
+		keyring = append(keyring, entity)
+	}
+
+	var signer *openpgp.Entity
+	if bytes.HasPrefix(bytes.TrimSpace(sig), []byte("-----BEGIN")) {
+		signer, err = openpgp.CheckArmoredDetachedSignature(keyring, bytes.NewReader(sums), bytes.NewReader(sig), nil)
+	} else {
+		signer, err = openpgp.CheckDetachedSignature(keyring, bytes.NewReader(sums), bytes.NewReader(sig), nil)
+	}
+	if err != nil {
+		return "", errInvalidSignature
+	}
+
+	return signer.PrimaryKey.KeyIdString(), nil
+}
+
+// DeleteProviderVersion deletes a provider version with all its files
+func DeleteProviderVersion(ctx *context.Context) {
+	err := packages_service.RemovePackageVersionByNameAndVersion(
+		ctx,
+		ctx.Doer,
+		&packages_service.PackageInfo{
+			Owner:       ctx.Package.Owner,
+			PackageType: packages_model.TypeTerraformProvider,
+			Name:        ctx.PathParam("provider"),
+			Version:     ctx.PathParam("version"),
+		},
+	)
+	if err != nil {
+		if errors.Is(err, packages_model.ErrPackageNotExist) {
+			apiError(ctx, http.StatusNotFound, err)
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
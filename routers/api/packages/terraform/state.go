@@ -0,0 +1,380 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package terraform
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	auth_model "github.com/kumose/kmup/models/auth"
+	packages_model "github.com/kumose/kmup/models/packages"
+	access_model "github.com/kumose/kmup/models/perm/access"
+	repo_model "github.com/kumose/kmup/models/repo"
+	"github.com/kumose/kmup/models/unit"
+	packages_module "github.com/kumose/kmup/modules/packages"
+	terraform_module "github.com/kumose/kmup/modules/packages/terraform"
+	"github.com/kumose/kmup/modules/util"
+	"github.com/kumose/kmup/routers/api/packages/helper"
+	"github.com/kumose/kmup/services/context"
+	packages_service "github.com/kumose/kmup/services/packages"
+	terraform_service "github.com/kumose/kmup/services/packages/terraform"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func init() {
+	// the Terraform http backend locks and unlocks a state with these methods by default
+	chi.RegisterMethod("LOCK")
+	chi.RegisterMethod("UNLOCK")
+}
+
+// ReqRepositoryStateAccess checks if the doer can write to the repository the state belongs to.
+// States contain secrets, so read access to the repository is not enough.
+// The authentication is checked before the repository is resolved, and a repository without access is reported as not found,
+// so the existence of private repositories isn't revealed.
+func ReqRepositoryStateAccess(ctx *context.Context) {
+	if ctx.Doer == nil {
+		ctx.Resp.Header().Set("WWW-Authenticate", `Basic realm="Kmup Package API"`)
+		apiError(ctx, http.StatusUnauthorized, "user should have write permission to the repository")
+		return
+	}
+
+	publicOnly := false
+	if ctx.Data["IsApiToken"] == true {
+		if scope, ok := ctx.Data["ApiTokenScope"].(auth_model.AccessTokenScope); ok {
+			hasScope, err := scope.HasScope(auth_model.AccessTokenScopeWriteRepository)
+			if err != nil {
+				apiError(ctx, http.StatusInternalServerError, err)
+				return
+			}
+			if !hasScope {
+				ctx.Resp.Header().Set("WWW-Authenticate", `Basic realm="Kmup Package API"`)
+				apiError(ctx, http.StatusUnauthorized, "token should have the write:repository scope")
+				return
+			}
+
+			if publicOnly, err = scope.PublicOnly(); err != nil {
+				apiError(ctx, http.StatusInternalServerError, err)
+				return
+			}
+		}
+	}
+
+	repo, err := repo_model.GetRepositoryByName(ctx, ctx.Package.Owner.ID, ctx.PathParam("reponame"))
+	if err != nil {
+		if repo_model.IsErrRepoNotExist(err) {
+			apiError(ctx, http.StatusNotFound, "repository does not exist")
+			return
+		}
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if publicOnly && (repo.IsPrivate || ctx.Package.Owner.Visibility.IsPrivate()) {
+		apiError(ctx, http.StatusNotFound, "repository does not exist")
+		return
+	}
+
+	permission, err := access_model.GetUserRepoPermission(ctx, repo, ctx.Doer)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if !permission.CanWrite(unit.TypeCode) {
+		apiError(ctx, http.StatusNotFound, "repository does not exist")
+		return
+	}
+
+	ctx.Repo.Repository = repo
+	ctx.Repo.Permission = permission
+}
+
+func stateFromContext(ctx *context.Context) (*terraform_service.State, bool) {
+	name := ctx.PathParam("statename")
+	if !terraform_module.IsValidStateName(name) {
+		apiError(ctx, http.StatusBadRequest, terraform_module.ErrInvalidStateName)
+		return nil, false
+	}
+
+	s := &terraform_service.State{
+		Owner: ctx.Package.Owner,
+		Name:  name,
+	}
+	if ctx.Repo.Repository != nil {
+		s.RepoID = ctx.Repo.Repository.ID
+	}
+	return s, true
+}
+
+func stateError(ctx *context.Context, err error) {
+	var errLocked terraform_service.ErrStateLocked
+	switch {
+	case errors.As(err, &errLocked):
+		// the http backend expects the current lock in the body
+		jsonResponse(ctx, http.StatusLocked, errLocked.Lock)
+	case errors.Is(err, packages_model.ErrPackageNotExist):
+		apiError(ctx, http.StatusNotFound, err)
+	case errors.Is(err, util.ErrInvalidArgument):
+		apiError(ctx, http.StatusBadRequest, err)
+	case errors.Is(err, packages_service.ErrQuotaTotalCount), errors.Is(err, packages_service.ErrQuotaTypeSize), errors.Is(err, packages_service.ErrQuotaTotalSize):
+		apiError(ctx, http.StatusForbidden, err)
+	default:
+		apiError(ctx, http.StatusInternalServerError, err)
+	}
+}
+
+func serveStateVersion(ctx *context.Context, pv *packages_model.PackageVersion) {
+	s, u, pf, err := terraform_service.OpenVersion(ctx, pv, ctx.Req.Method)
+	if err != nil {
+		stateError(ctx, err)
+		return
+	}
+
+	helper.ServePackageFile(ctx, s, u, pf, &context.ServeHeaderOptions{
+		ContentType:  "application/json",
+		Filename:     pf.Name,
+		LastModified: pf.CreatedUnix.AsLocalTime(),
+	})
+}
+
+// GetState serves the current version of the state
+func GetState(ctx *context.Context) {
+	s, ok := stateFromContext(ctx)
+	if !ok {
+		return
+	}
+
+	pv, err := terraform_service.GetLatestVersion(ctx, s)
+	if err != nil {
+		stateError(ctx, err)
+		return
+	}
+
+	serveStateVersion(ctx, pv)
+}
+
+// UpdateState stores a new version of the state.
+// If the state is locked, the lock id must be provided with the ID query parameter.
+func UpdateState(ctx *context.Context) {
+	s, ok := stateFromContext(ctx)
+	if !ok {
+		return
+	}
+
+	upload, needsClose, err := ctx.UploadStream()
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	if needsClose {
+		defer upload.Close()
+	}
+
+	buf, err := packages_module.CreateHashedBufferFromReader(upload)
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+	defer buf.Close()
+
+	if _, err := terraform_service.SaveState(ctx, ctx.Doer, s, ctx.FormString("ID"), buf); err != nil {
+		stateError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+// DeleteState deletes the state with all its versions
+func DeleteState(ctx *context.Context) {
+	s, ok := stateFromContext(ctx)
+	if !ok {
+		return
+	}
+
+	if err := terraform_service.DeleteState(ctx, s, ctx.FormString("ID")); err != nil {
+		stateError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+// LockState locks the state with the lock info sent in the body
+func LockState(ctx *context.Context) {
+	s, ok := stateFromContext(ctx)
+	if !ok {
+		return
+	}
+
+	lock, err := terraform_module.ParseLockInfo(ctx.Req.Body)
+	if err != nil {
+		apiError(ctx, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := terraform_service.Lock(ctx, s, lock); err != nil {
+		stateError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+// UnlockState removes the lock of the state.
+// The body contains the lock info of the lock to remove. An empty body forces the unlock.
+func UnlockState(ctx *context.Context) {
+	s, ok := stateFromContext(ctx)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(ctx.Req.Body, 64*1024))
+	if err != nil {
+		apiError(ctx, http.StatusInternalServerError, err)
+		return
+	}
+
+	var lockID string
+	force := len(body) == 0
+	if !force {
+		lock, err := terraform_module.ParseLockInfo(bytes.NewReader(body))
+		if err != nil {
+			apiError(ctx, http.StatusBadRequest, err)
+			return
+		}
+		lockID = lock.ID
+	}
+
+	if err := terraform_service.Unlock(ctx, s, lockID, force); err != nil {
+		stateError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+type stateVersion struct {
+	Version          string    `json:"version"`
+	Serial           int64     `json:"serial"`
+	Lineage          string    `json:"lineage,omitempty"`
+	TerraformVersion string    `json:"terraform_version,omitempty"`
+	Size             int64     `json:"size"`
+	SHA256           string    `json:"sha256"`
+	Creator          string    `json:"creator"`
+	Created          time.Time `json:"created_at"`
+}
+
+func toStateVersion(pd *packages_model.PackageDescriptor) *stateVersion {
+	metadata := pd.Metadata.(*terraform_module.StateMetadata)
+	return &stateVersion{
+		Version:          pd.Version.Version,
+		Serial:           metadata.Serial,
+		Lineage:          metadata.Lineage,
+		TerraformVersion: metadata.TerraformVersion,
+		Size:             pd.Files[0].Blob.Size,
+		SHA256:           pd.Files[0].Blob.HashSHA256,
+		Creator:          pd.Creator.Name,
+		Created:          pd.Version.CreatedUnix.AsLocalTime(),
+	}
+}
+
+// ListStateVersions lists the history of the state, the newest version first
+func ListStateVersions(ctx *context.Context) {
+	s, ok := stateFromContext(ctx)
+	if !ok {
+		return
+	}
+
+	pvs, err := terraform_service.GetVersions(ctx, s)
+	if err != nil {
+		stateError(ctx, err)
+		return
+	}
+
+	pds, err := packages_model.GetPackageDescriptors(ctx, pvs)
+	if err != nil {
+		stateError(ctx, err)
+		return
+	}
+
+	lock, err := terraform_service.GetLock(ctx, s)
+	if err != nil {
+		stateError(ctx, err)
+		return
+	}
+
+	if len(pds) == 0 && lock == nil {
+		apiError(ctx, http.StatusNotFound, packages_model.ErrPackageNotExist)
+		return
+	}
+
+	versions := make([]*stateVersion, 0, len(pds))
+	for _, pd := range pds {
+		versions = append(versions, toStateVersion(pd))
+	}
+
+	jsonResponse(ctx, http.StatusOK, struct {
+		Lock     *terraform_module.LockInfo `json:"lock"`
+		Versions []*stateVersion            `json:"versions"`
+	}{
+		Lock:     lock,
+		Versions: versions,
+	})
+}
+
+// DownloadStateVersion serves a previous version of the state
+func DownloadStateVersion(ctx *context.Context) {
+	s, ok := stateFromContext(ctx)
+	if !ok {
+		return
+	}
+
+	pv, err := terraform_service.GetVersion(ctx, s, ctx.PathParam("version"))
+	if err != nil {
+		stateError(ctx, err)
+		return
+	}
+
+	serveStateVersion(ctx, pv)
+}
+
+// RollbackState restores a previous version of the state as new version.
+// If the state is locked, the lock id must be provided with the ID query parameter.
+func RollbackState(ctx *context.Context) {
+	s, ok := stateFromContext(ctx)
+	if !ok {
+		return
+	}
+
+	pv, err := terraform_service.RollbackState(ctx, ctx.Doer, s, ctx.FormString("ID"), ctx.PathParam("version"))
+	if err != nil {
+		stateError(ctx, err)
+		return
+	}
+
+	pd, err := packages_model.GetPackageDescriptor(ctx, pv)
+	if err != nil {
+		stateError(ctx, err)
+		return
+	}
+
+	jsonResponse(ctx, http.StatusCreated, toStateVersion(pd))
+}
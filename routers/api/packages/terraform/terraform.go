@@ -0,0 +1,56 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package terraform
+
+import (
+	"github.com/kumose/kmup/modules/json"
+	"github.com/kumose/kmup/modules/setting"
+	"github.com/kumose/kmup/routers/api/packages/helper"
+	"github.com/kumose/kmup/services/context"
+)
+
+// ModulesBasePath is the path of the module registry protocol (relative to the application URL)
+const ModulesBasePath = "api/packages/-/terraform/modules/v1/"
+
+// ProvidersBasePath is the path of the provider registry protocol (relative to the application URL)
+const ProvidersBasePath = "api/packages/-/terraform/providers/v1/"
+
+func jsonResponse(ctx *context.Context, status int, obj any) {
+	resp := ctx.Resp
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(status)
+	_ = json.NewEncoder(resp).Encode(obj)
+}
+
+// https://developer.hashicorp.com/terraform/internals/module-registry-protocol
+func apiError(ctx *context.Context, status int, obj any) {
+	message := helper.ProcessErrorForUser(ctx, status, obj)
+	jsonResponse(ctx, status, struct {
+		Errors []string `json:"errors"`
+	}{
+		Errors: []string{message},
+	})
+}
+
+func modulesBaseURL() string {
+	return setting.AppURL + ModulesBasePath
+}
+
+func providersBaseURL() string {
+	return setting.AppURL + ProvidersBasePath
+}
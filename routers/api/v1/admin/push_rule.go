@@ -0,0 +1,80 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package admin
+
+import (
+	"github.com/kumose/kmup/routers/api/v1/shared"
+	"github.com/kumose/kmup/services/context"
+)
+
+// GetPushRule gets the push rule of the instance
+func GetPushRule(ctx *context.APIContext) {
+	// swagger:operation GET /admin/push_rules admin adminGetPushRule
+	// ---
+	// summary: Get the push rule of the instance
+	// produces:
+	// - application/json
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/PushRule"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	shared.GetPushRule(ctx, 0, 0)
+}
+
+// SetPushRule creates or replaces the push rule of the instance
+func SetPushRule(ctx *context.APIContext) {
+	// swagger:operation PUT /admin/push_rules admin adminSetPushRule
+	// ---
+	// summary: Create or replace the push rule of the instance
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: body
+	//   in: body
+	//   schema:
+	//     "$ref": "#/definitions/EditPushRuleOption"
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/PushRule"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	shared.SetPushRule(ctx, 0, 0)
+}
+
+// DeletePushRule deletes the push rule of the instance
+func DeletePushRule(ctx *context.APIContext) {
+	// swagger:operation DELETE /admin/push_rules admin adminDeletePushRule
+	// ---
+	// summary: Delete the push rule of the instance
+	// produces:
+	// - application/json
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	shared.DeletePushRule(ctx, 0, 0)
+}
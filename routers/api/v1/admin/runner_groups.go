@@ -0,0 +1,422 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package admin
+
+import (
+	"errors"
+	"net/http"
+
+	actions_model "github.com/kumose/kmup/models/actions"
+	"github.com/kumose/kmup/models/db"
+	api "github.com/kumose/kmup/modules/structs"
+	"github.com/kumose/kmup/modules/util"
+	"github.com/kumose/kmup/modules/web"
+	"github.com/kumose/kmup/routers/api/v1/utils"
+	actions_service "github.com/kumose/kmup/services/actions"
+	"github.com/kumose/kmup/services/context"
+	"github.com/kumose/kmup/services/convert"
+)
+
+func handleRunnerGroupError(ctx *context.APIContext, err error) {
+	switch {
+	case errors.Is(err, util.ErrInvalidArgument):
+		ctx.APIError(http.StatusBadRequest, err)
+	case errors.Is(err, util.ErrAlreadyExist):
+		ctx.APIError(http.StatusConflict, err)
+	case errors.Is(err, util.ErrNotExist):
+		ctx.APIError(http.StatusNotFound, err)
+	default:
+		ctx.APIErrorInternal(err)
+	}
+}
+
+func getRunnerGroup(ctx *context.APIContext) *actions_model.ActionRunnerGroup {
+	group, err := actions_model.GetRunnerGroupByID(ctx, ctx.PathParamInt64("group_id"))
+	if err != nil {
+		handleRunnerGroupError(ctx, err)
+		return nil
+	}
+	return group
+}
+
+func writeRunnerGroup(ctx *context.APIContext, status int, group *actions_model.ActionRunnerGroup) {
+	counts, err := actions_model.CountRunnersOfGroups(ctx)
+	if err != nil {
+		ctx.APIErrorInternal(err)
+		return
+	}
+	apiGroup, err := convert.ToActionRunnerGroup(ctx, group, counts[group.ID])
+	if err != nil {
+		ctx.APIErrorInternal(err)
+		return
+	}
+	ctx.JSON(status, apiGroup)
+}
+
+// ListRunnerGroups lists the runner groups
+func ListRunnerGroups(ctx *context.APIContext) {
+	// swagger:operation GET /admin/actions/runner-groups admin adminListRunnerGroups
+	// ---
+	// summary: List the runner groups
+	// produces:
+	// - application/json
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/ActionRunnerGroupList"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+
+	groups, err := actions_model.FindRunnerGroups(ctx)
+	if err != nil {
+		ctx.APIErrorInternal(err)
+		return
+	}
+	counts, err := actions_model.CountRunnersOfGroups(ctx)
+	if err != nil {
+		ctx.APIErrorInternal(err)
+		return
+	}
+	apiGroups := make([]*api.ActionRunnerGroup, 0, len(groups))
+	for _, group := range groups {
+		apiGroup, err := convert.ToActionRunnerGroup(ctx, group, counts[group.ID])
+		if err != nil {
+			ctx.APIErrorInternal(err)
+			return
+		}
+		apiGroups = append(apiGroups, apiGroup)
+	}
+	ctx.JSON(http.StatusOK, apiGroups)
+}
+
+// CreateRunnerGroup creates a runner group
+func CreateRunnerGroup(ctx *context.APIContext) {
+	// swagger:operation POST /admin/actions/runner-groups admin adminCreateRunnerGroup
+	// ---
+	// summary: Create a runner group
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: body
+	//   in: body
+	//   schema:
+	//     "$ref": "#/definitions/CreateActionRunnerGroupOption"
+	// responses:
+	//   "201":
+	//     "$ref": "#/responses/ActionRunnerGroup"
+	//   "400":
+	//     "$ref": "#/responses/error"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "409":
+	//     "$ref": "#/responses/conflict"
+
+	form := web.GetForm(ctx).(*api.CreateActionRunnerGroupOption)
+	group, err := actions_service.CreateRunnerGroup(ctx, &actions_service.RunnerGroupOptions{
+		Name:             form.Name,
+		Description:      form.Description,
+		AllowedOwners:    form.AllowedOwners,
+		AllowedRepos:     form.AllowedRepos,
+		AllowedWorkflows: form.AllowedWorkflows,
+	})
+	if err != nil {
+		handleRunnerGroupError(ctx, err)
+		return
+	}
+	writeRunnerGroup(ctx, http.StatusCreated, group)
+}
+
+// GetRunnerGroup gets a runner group
+func GetRunnerGroup(ctx *context.APIContext) {
+	// swagger:operation GET /admin/actions/runner-groups/{group_id} admin adminGetRunnerGroup
+	// ---
+	// summary: Get a runner group
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: group_id
+	//   in: path
+	//   description: id of the runner group
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/ActionRunnerGroup"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	group := getRunnerGroup(ctx)
+	if ctx.Written() {
+		return
+	}
+	writeRunnerGroup(ctx, http.StatusOK, group)
+}
+
+// EditRunnerGroup edits a runner group
+func EditRunnerGroup(ctx *context.APIContext) {
+	// swagger:operation PATCH /admin/actions/runner-groups/{group_id} admin adminEditRunnerGroup
+	// ---
+	// summary: Edit a runner group
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: group_id
+	//   in: path
+	//   description: id of the runner group
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// - name: body
+	//   in: body
+	//   schema:
+	//     "$ref": "#/definitions/EditActionRunnerGroupOption"
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/ActionRunnerGroup"
+	//   "400":
+	//     "$ref": "#/responses/error"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+	//   "409":
+	//     "$ref": "#/responses/conflict"
+
+	group := getRunnerGroup(ctx)
+	if ctx.Written() {
+		return
+	}
+	owners, repos, err := group.GetAllowedNames(ctx)
+	if err != nil {
+		ctx.APIErrorInternal(err)
+		return
+	}
+	opts := &actions_service.RunnerGroupOptions{
+		Name:             group.Name,
+		Description:      group.Description,
+		AllowedOwners:    owners,
+		AllowedRepos:     repos,
+		AllowedWorkflows: group.AllowedWorkflows,
+	}
+
+	form := web.GetForm(ctx).(*api.EditActionRunnerGroupOption)
+	if form.Name != nil {
+		opts.Name = *form.Name
+	}
+	if form.Description != nil {
+		opts.Description = *form.Description
+	}
+	if form.AllowedOwners != nil {
+		opts.AllowedOwners = form.AllowedOwners
+	}
+	if form.AllowedRepos != nil {
+		opts.AllowedRepos = form.AllowedRepos
+	}
+	if form.AllowedWorkflows != nil {
+		opts.AllowedWorkflows = form.AllowedWorkflows
+	}
+	if err := actions_service.UpdateRunnerGroup(ctx, group, opts); err != nil {
+		handleRunnerGroupError(ctx, err)
+		return
+	}
+	writeRunnerGroup(ctx, http.StatusOK, group)
+}
+
+// DeleteRunnerGroup deletes a runner group
+func DeleteRunnerGroup(ctx *context.APIContext) {
+	// swagger:operation DELETE /admin/actions/runner-groups/{group_id} admin adminDeleteRunnerGroup
+	// ---
+	// summary: Delete a runner group, it must have no runners
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: group_id
+	//   in: path
+	//   description: id of the runner group
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "400":
+	//     "$ref": "#/responses/error"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	if err := actions_model.DeleteRunnerGroup(ctx, ctx.PathParamInt64("group_id")); err != nil {
+		handleRunnerGroupError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// ListRunnerGroupRunners lists the runners of a runner group
+func ListRunnerGroupRunners(ctx *context.APIContext) {
+	// swagger:operation GET /admin/actions/runner-groups/{group_id}/runners admin adminListRunnerGroupRunners
+	// ---
+	// summary: List the runners of a runner group
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: group_id
+	//   in: path
+	//   description: id of the runner group
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// - name: page
+	//   in: query
+	//   description: page number of results to return (1-based)
+	//   type: integer
+	// - name: limit
+	//   in: query
+	//   description: page size of results
+	//   type: integer
+	// responses:
+	//   "200":
+	//     "$ref": "#/definitions/ActionRunnersResponse"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	group := getRunnerGroup(ctx)
+	if ctx.Written() {
+		return
+	}
+	runners, total, err := db.FindAndCount[actions_model.ActionRunner](ctx, &actions_model.FindRunnerOptions{
+		GroupID:     group.ID,
+		ListOptions: utils.GetListOptions(ctx),
+	})
+	if err != nil {
+		ctx.APIErrorInternal(err)
+		return
+	}
+
+	res := &api.ActionRunnersResponse{
+		TotalCount: total,
+		Entries:    make([]*api.ActionRunner, len(runners)),
+	}
+	for i, runner := range runners {
+		res.Entries[i] = convert.ToActionRunner(ctx, runner)
+	}
+	ctx.JSON(http.StatusOK, res)
+}
+
+// AddRunnerGroupRunner adds a global runner to a runner group
+func AddRunnerGroupRunner(ctx *context.APIContext) {
+	// swagger:operation PUT /admin/actions/runner-groups/{group_id}/runners/{runner_id} admin adminAddRunnerGroupRunner
+	// ---
+	// summary: Add a global runner to a runner group, it's removed from its previous group
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: group_id
+	//   in: path
+	//   description: id of the runner group
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// - name: runner_id
+	//   in: path
+	//   description: id of the runner
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "400":
+	//     "$ref": "#/responses/error"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	group := getRunnerGroup(ctx)
+	if ctx.Written() {
+		return
+	}
+	runner, err := actions_model.GetRunnerByID(ctx, ctx.PathParamInt64("runner_id"))
+	if err != nil {
+		handleRunnerGroupError(ctx, err)
+		return
+	}
+	if err := actions_model.SetRunnerGroup(ctx, runner, group.ID); err != nil {
+		handleRunnerGroupError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// RemoveRunnerGroupRunner removes a runner from a runner group
+func RemoveRunnerGroupRunner(ctx *context.APIContext) {
+	// swagger:operation DELETE /admin/actions/runner-groups/{group_id}/runners/{runner_id} admin adminRemoveRunnerGroupRunner
+	// ---
+	// summary: Remove a runner from a runner group, the runner can be used by all repositories afterwards
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: group_id
+	//   in: path
+	//   description: id of the runner group
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// - name: runner_id
+	//   in: path
+	//   description: id of the runner
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	group := getRunnerGroup(ctx)
+	if ctx.Written() {
+		return
+	}
+	runner, err := actions_model.GetRunnerByID(ctx, ctx.PathParamInt64("runner_id"))
+	if err != nil {
+		handleRunnerGroupError(ctx, err)
+		return
+	}
+	if runner.GroupID != group.ID {
+		ctx.APIErrorNotFound("runner isn't in the runner group")
+		return
+	}
+	if err := actions_model.SetRunnerGroup(ctx, runner, 0); err != nil {
+		handleRunnerGroupError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
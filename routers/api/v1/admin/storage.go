@@ -0,0 +1,70 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package admin
+
+import (
+	"net/http"
+
+	api "github.com/kumose/kmup/modules/structs"
+	"github.com/kumose/kmup/modules/util"
+	"github.com/kumose/kmup/routers/api/v1/utils"
+	"github.com/kumose/kmup/services/context"
+	"github.com/kumose/kmup/services/convert"
+	storage_service "github.com/kumose/kmup/services/storage"
+)
+
+// ListStorageUsage api for getting the storage usage of all owners
+func ListStorageUsage(ctx *context.APIContext) {
+	// swagger:operation GET /admin/storage/usage admin adminListStorageUsage
+	// ---
+	// summary: List the storage used by every owner, the owners using the most storage come first
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: page
+	//   in: query
+	//   description: page number of results to return (1-based)
+	//   type: integer
+	// - name: limit
+	//   in: query
+	//   description: page size of results
+	//   type: integer
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/StorageUsageList"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+
+	usages, err := storage_service.GetOwnerUsages(ctx)
+	if err != nil {
+		ctx.APIErrorInternal(err)
+		return
+	}
+	count := len(usages)
+
+	listOpts := utils.GetListOptions(ctx)
+	usages = util.PaginateSlice(usages, listOpts.Page, listOpts.PageSize).([]*storage_service.OwnerUsage)
+
+	res := make([]*api.StorageUsage, 0, len(usages))
+	for _, u := range usages {
+		res = append(res, convert.ToStorageUsage(u))
+	}
+
+	ctx.SetTotalCountHeader(int64(count))
+	ctx.JSON(http.StatusOK, res)
+}
@@ -1194,6 +1194,8 @@ func Routes() *web.Router {
 				m.Group("/actions/jobs", func() {
 					m.Get("/{job_id}", repo.GetWorkflowJob)
 					m.Get("/{job_id}/logs", repo.DownloadActionsRunJobLogs)
+					m.Get("/{job_id}/annotations", repo.ListWorkflowJobAnnotations)
+					m.Post("/{job_id}/rerun", reqRepoWriter(unit.TypeActions), bind(api.RerunActionRunOption{}), repo.RerunWorkflowJob)
 				}, reqToken(), reqRepoReader(unit.TypeActions))
 
 				m.Group("/hooks/git", func() {
@@ -1269,6 +1271,15 @@ func Routes() *web.Router {
 							Delete(repo.DeleteTagProtection)
 					})
 				}, reqToken(), reqAdmin())
+				m.Combo("/push_rules", reqToken(), reqAdmin()).Get(repo.GetPushRule).
+					Put(bind(api.EditPushRuleOption{}), mustNotBeArchived, repo.SetPushRule).
+					Delete(repo.DeletePushRule)
+				m.Group("/secret_scanning", func() {
+					m.Get("/alerts", repo.ListSecretScanningAlerts)
+					m.Combo("/alerts/{id}").Get(repo.GetSecretScanningAlert).
+						Patch(bind(api.EditSecretScanningAlertOption{}), repo.EditSecretScanningAlert)
+					m.Post("/scans", repo.ScanSecrets)
+				}, reqToken(), reqAdmin())
 				m.Group("/actions", func() {
 					m.Get("/tasks", repo.ListActionTasks)
 					m.Group("/runs", func() {
@@ -1277,6 +1288,9 @@ func Routes() *web.Router {
 							m.Delete("", reqToken(), reqRepoWriter(unit.TypeActions), repo.DeleteActionRun)
 							m.Get("/jobs", repo.ListWorkflowRunJobs)
 							m.Get("/artifacts", repo.GetArtifactsOfRun)
+							m.Get("/test-results", repo.GetWorkflowRunTestResults)
+							m.Post("/rerun", reqToken(), reqRepoWriter(unit.TypeActions), bind(api.RerunActionRunOption{}), repo.RerunWorkflowRun)
+							m.Post("/rerun-failed-jobs", reqToken(), reqRepoWriter(unit.TypeActions), bind(api.RerunActionRunOption{}), repo.RerunFailedWorkflowRunJobs)
 						})
 					})
 					m.Get("/artifacts", repo.GetArtifacts)
@@ -1352,6 +1366,7 @@ func Routes() *web.Router {
 					m.Combo("").Get(repo.ListPullRequests).
 						Post(reqToken(), mustNotBeArchived, bind(api.CreatePullRequestOption{}), repo.CreatePullRequest)
 					m.Get("/pinned", repo.ListPinnedPullRequests)
+					m.Get("/merge_queue", repo.ListMergeQueue)
 					m.Group("/{index}", func() {
 						m.Combo("").Get(repo.GetPullRequest).
 							Patch(reqToken(), bind(api.EditPullRequestOption{}), repo.EditPullRequest)
@@ -1359,6 +1374,8 @@ func Routes() *web.Router {
 						m.Post("/update", reqToken(), repo.UpdatePullRequest)
 						m.Get("/commits", repo.GetPullRequestCommits)
 						m.Get("/files", repo.GetPullRequestFiles)
+						m.Get("/code_owners", repo.GetPullRequestMissingCodeOwnerApprovals)
+						m.Delete("/merge_queue", reqToken(), mustNotBeArchived, reqRepoWriter(unit.TypeCode), repo.RemoveFromMergeQueue)
 						m.Combo("/merge").Get(repo.IsPullRequestMerged).
 							Post(reqToken(), mustNotBeArchived, bind(forms.MergePullRequestForm{}), repo.MergePullRequest).
 							Delete(reqToken(), mustNotBeArchived, repo.CancelScheduledAutoMerge)
@@ -1597,6 +1614,13 @@ func Routes() *web.Router {
 					m.Get("", packages.GetPackage)
 					m.Delete("", reqPackageAccess(perm.AccessModeWrite), packages.DeletePackage)
 					m.Get("/files", packages.ListPackageFiles)
+					m.Group("/attestations", func() {
+						m.Combo("").Get(packages.ListPackageAttestations).
+							Post(reqPackageAccess(perm.AccessModeWrite), packages.UploadPackageAttestation)
+						m.Combo("/{id}").Get(packages.GetPackageAttestation).
+							Delete(reqPackageAccess(perm.AccessModeWrite), packages.DeletePackageAttestation)
+					})
+					m.Get("/vulnerabilities", packages.ListPackageVulnerabilities)
 				})
 
 				m.Group("/-", func() {
@@ -1606,6 +1630,14 @@ func Routes() *web.Router {
 				})
 			})
 
+			m.Group("/-/cleanup-rules", func() {
+				m.Get("", packages.ListPackageCleanupRules)
+				m.Group("/{id}", func() {
+					m.Get("/preview", packages.PreviewPackageCleanupRule)
+					m.Get("/runs", packages.ListPackageCleanupRuns)
+				})
+			}, reqPackageAccess(perm.AccessModeOwner))
+
 			m.Get("/", packages.ListPackages)
 		}, reqToken(), tokenRequiresScopes(auth_model.AccessTokenScopeCategoryPackage), context.UserAssignmentAPI(), context.PackageAssignmentAPI(), reqPackageAccess(perm.AccessModeRead), checkTokenPublicOnly())
 
@@ -1663,6 +1695,19 @@ func Routes() *web.Router {
 				m.Post("", bind(api.UpdateUserAvatarOption{}), org.UpdateAvatar)
 				m.Delete("", org.DeleteAvatar)
 			}, reqToken(), reqOrgOwnership())
+			m.Combo("/push_rules", reqToken(), reqOrgOwnership()).Get(org.GetPushRule).
+				Put(bind(api.EditPushRuleOption{}), org.SetPushRule).
+				Delete(org.DeletePushRule)
+			m.Group("/rulesets", func() {
+				m.Combo("").Get(org.ListRulesets).
+					Post(bind(api.CreateRulesetOption{}), org.CreateRuleset)
+				m.Group("/{id}", func() {
+					m.Combo("").Get(org.GetRuleset).
+						Patch(bind(api.EditRulesetOption{}), org.EditRuleset).
+						Delete(org.DeleteRuleset)
+					m.Get("/evaluations", org.ListRulesetEvaluations)
+				})
+			}, reqToken(), reqOrgOwnership())
 			m.Get("/activities/feeds", org.ListOrgActivityFeeds)
 
 			m.Group("/blocks", func() {
@@ -1701,6 +1746,10 @@ func Routes() *web.Router {
 				m.Post("/{task}", admin.PostCronTask)
 			})
 			m.Get("/orgs", admin.GetAllOrgs)
+			m.Get("/storage/usage", admin.ListStorageUsage)
+			m.Combo("/push_rules").Get(admin.GetPushRule).
+				Put(bind(api.EditPushRuleOption{}), admin.SetPushRule).
+				Delete(admin.DeletePushRule)
 			m.Group("/users", func() {
 				m.Get("", admin.SearchUsers)
 				m.Post("", bind(api.CreateUserOption{}), admin.CreateUser)
@@ -1743,6 +1792,16 @@ func Routes() *web.Router {
 					m.Get("/{runner_id}", admin.GetRunner)
 					m.Delete("/{runner_id}", admin.DeleteRunner)
 				})
+				m.Group("/runner-groups", func() {
+					m.Combo("").Get(admin.ListRunnerGroups).
+						Post(bind(api.CreateActionRunnerGroupOption{}), admin.CreateRunnerGroup)
+					m.Combo("/{group_id}").Get(admin.GetRunnerGroup).
+						Patch(bind(api.EditActionRunnerGroupOption{}), admin.EditRunnerGroup).
+						Delete(admin.DeleteRunnerGroup)
+					m.Get("/{group_id}/runners", admin.ListRunnerGroupRunners)
+					m.Combo("/{group_id}/runners/{runner_id}").Put(admin.AddRunnerGroupRunner).
+						Delete(admin.RemoveRunnerGroupRunner)
+				})
 				m.Get("/runs", admin.ListWorkflowRuns)
 				m.Get("/jobs", admin.ListWorkflowJobs)
 			})
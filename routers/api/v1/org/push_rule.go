@@ -0,0 +1,97 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package org
+
+import (
+	"github.com/kumose/kmup/routers/api/v1/shared"
+	"github.com/kumose/kmup/services/context"
+)
+
+// GetPushRule gets the push rule of an organization
+func GetPushRule(ctx *context.APIContext) {
+	// swagger:operation GET /orgs/{org}/push_rules organization orgGetPushRule
+	// ---
+	// summary: Get the push rule of an organization
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: org
+	//   in: path
+	//   description: name of the organization
+	//   type: string
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/PushRule"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	shared.GetPushRule(ctx, ctx.Org.Organization.ID, 0)
+}
+
+// SetPushRule creates or replaces the push rule of an organization
+func SetPushRule(ctx *context.APIContext) {
+	// swagger:operation PUT /orgs/{org}/push_rules organization orgSetPushRule
+	// ---
+	// summary: Create or replace the push rule of an organization
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: org
+	//   in: path
+	//   description: name of the organization
+	//   type: string
+	//   required: true
+	// - name: body
+	//   in: body
+	//   schema:
+	//     "$ref": "#/definitions/EditPushRuleOption"
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/PushRule"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	shared.SetPushRule(ctx, ctx.Org.Organization.ID, 0)
+}
+
+// DeletePushRule deletes the push rule of an organization
+func DeletePushRule(ctx *context.APIContext) {
+	// swagger:operation DELETE /orgs/{org}/push_rules organization orgDeletePushRule
+	// ---
+	// summary: Delete the push rule of an organization
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: org
+	//   in: path
+	//   description: name of the organization
+	//   type: string
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	shared.DeletePushRule(ctx, ctx.Org.Organization.ID, 0)
+}
@@ -0,0 +1,434 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package org
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/kumose/kmup/models/db"
+	git_model "github.com/kumose/kmup/models/git"
+	"github.com/kumose/kmup/models/organization"
+	user_model "github.com/kumose/kmup/models/user"
+	api "github.com/kumose/kmup/modules/structs"
+	"github.com/kumose/kmup/modules/util"
+	"github.com/kumose/kmup/modules/web"
+	"github.com/kumose/kmup/routers/api/v1/utils"
+	"github.com/kumose/kmup/services/context"
+	"github.com/kumose/kmup/services/convert"
+)
+
+// ListRulesets lists the rulesets of an organization
+func ListRulesets(ctx *context.APIContext) {
+	// swagger:operation GET /orgs/{org}/rulesets organization orgListRulesets
+	// ---
+	// summary: List the rulesets of an organization
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: org
+	//   in: path
+	//   description: name of the organization
+	//   type: string
+	//   required: true
+	// - name: page
+	//   in: query
+	//   description: page number of results to return (1-based)
+	//   type: integer
+	// - name: limit
+	//   in: query
+	//   description: page size of results
+	//   type: integer
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/RulesetList"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	rulesets, count, err := db.FindAndCount[git_model.Ruleset](ctx, git_model.FindRulesetsOptions{
+		ListOptions: utils.GetListOptions(ctx),
+		OwnerID:     ctx.Org.Organization.ID,
+	})
+	if err != nil {
+		ctx.APIErrorInternal(err)
+		return
+	}
+
+	apiRulesets := make([]*api.Ruleset, 0, len(rulesets))
+	for _, rs := range rulesets {
+		apiRuleset, err := convert.ToRuleset(ctx, rs)
+		if err != nil {
+			ctx.APIErrorInternal(err)
+			return
+		}
+		apiRulesets = append(apiRulesets, apiRuleset)
+	}
+
+	ctx.SetTotalCountHeader(count)
+	ctx.JSON(http.StatusOK, apiRulesets)
+}
+
+// GetRuleset gets a ruleset of an organization
+func GetRuleset(ctx *context.APIContext) {
+	// swagger:operation GET /orgs/{org}/rulesets/{id} organization orgGetRuleset
+	// ---
+	// summary: Get a ruleset of an organization
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: org
+	//   in: path
+	//   description: name of the organization
+	//   type: string
+	//   required: true
+	// - name: id
+	//   in: path
+	//   description: id of the ruleset
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/Ruleset"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	rs := getRulesetByParams(ctx)
+	if ctx.Written() {
+		return
+	}
+	respondRuleset(ctx, http.StatusOK, rs)
+}
+
+// CreateRuleset creates a ruleset for an organization
+func CreateRuleset(ctx *context.APIContext) {
+	// swagger:operation POST /orgs/{org}/rulesets organization orgCreateRuleset
+	// ---
+	// summary: Create a ruleset for an organization
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: org
+	//   in: path
+	//   description: name of the organization
+	//   type: string
+	//   required: true
+	// - name: body
+	//   in: body
+	//   schema:
+	//     "$ref": "#/definitions/CreateRulesetOption"
+	// responses:
+	//   "201":
+	//     "$ref": "#/responses/Ruleset"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+	//   "409":
+	//     "$ref": "#/responses/conflict"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	form := web.GetForm(ctx).(*api.CreateRulesetOption)
+	bypassUserIDs, bypassTeamIDs := getRulesetBypassIDs(ctx, form.BypassUsers, form.BypassTeams)
+	if ctx.Written() {
+		return
+	}
+
+	rs := &git_model.Ruleset{
+		OwnerID:               ctx.Org.Organization.ID,
+		Name:                  form.Name,
+		Enforcement:           git_model.RulesetEnforcement(form.Enforcement),
+		RepoNamePatterns:      form.RepoNamePatterns,
+		RepoTopics:            form.RepoTopics,
+		RefPatterns:           form.RefPatterns,
+		ExcludeRefPatterns:    form.ExcludeRefPatterns,
+		BlockCreation:         form.BlockCreation,
+		BlockDeletion:         form.BlockDeletion,
+		BlockForcePush:        form.BlockForcePush,
+		RequirePullRequest:    form.RequirePullRequest,
+		RequiredApprovals:     form.RequiredApprovals,
+		EnableStatusCheck:     form.EnableStatusCheck,
+		StatusCheckContexts:   form.StatusCheckContexts,
+		RequireSignedCommits:  form.RequireSignedCommits,
+		ProtectedFilePatterns: form.ProtectedFilePatterns,
+		BypassUserIDs:         bypassUserIDs,
+		BypassTeamIDs:         bypassTeamIDs,
+	}
+	if err := git_model.CreateRuleset(ctx, rs); err != nil {
+		handleRulesetError(ctx, err)
+		return
+	}
+	respondRuleset(ctx, http.StatusCreated, rs)
+}
+
+// EditRuleset edits a ruleset of an organization
+func EditRuleset(ctx *context.APIContext) {
+	// swagger:operation PATCH /orgs/{org}/rulesets/{id} organization orgEditRuleset
+	// ---
+	// summary: Edit a ruleset of an organization
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: org
+	//   in: path
+	//   description: name of the organization
+	//   type: string
+	//   required: true
+	// - name: id
+	//   in: path
+	//   description: id of the ruleset
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// - name: body
+	//   in: body
+	//   schema:
+	//     "$ref": "#/definitions/EditRulesetOption"
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/Ruleset"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+	//   "409":
+	//     "$ref": "#/responses/conflict"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	rs := getRulesetByParams(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	form := web.GetForm(ctx).(*api.EditRulesetOption)
+	if form.Name != nil {
+		rs.Name = *form.Name
+	}
+	if form.Enforcement != nil {
+		rs.Enforcement = git_model.RulesetEnforcement(*form.Enforcement)
+	}
+	if form.RepoNamePatterns != nil {
+		rs.RepoNamePatterns = form.RepoNamePatterns
+	}
+	if form.RepoTopics != nil {
+		rs.RepoTopics = form.RepoTopics
+	}
+	if form.RefPatterns != nil {
+		rs.RefPatterns = form.RefPatterns
+	}
+	if form.ExcludeRefPatterns != nil {
+		rs.ExcludeRefPatterns = form.ExcludeRefPatterns
+	}
+	if form.BlockCreation != nil {
+		rs.BlockCreation = *form.BlockCreation
+	}
+	if form.BlockDeletion != nil {
+		rs.BlockDeletion = *form.BlockDeletion
+	}
+	if form.BlockForcePush != nil {
+		rs.BlockForcePush = *form.BlockForcePush
+	}
+	if form.RequirePullRequest != nil {
+		rs.RequirePullRequest = *form.RequirePullRequest
+	}
+	if form.RequiredApprovals != nil {
+		rs.RequiredApprovals = *form.RequiredApprovals
+	}
+	if form.EnableStatusCheck != nil {
+		rs.EnableStatusCheck = *form.EnableStatusCheck
+	}
+	if form.StatusCheckContexts != nil {
+		rs.StatusCheckContexts = form.StatusCheckContexts
+	}
+	if form.RequireSignedCommits != nil {
+		rs.RequireSignedCommits = *form.RequireSignedCommits
+	}
+	if form.ProtectedFilePatterns != nil {
+		rs.ProtectedFilePatterns = *form.ProtectedFilePatterns
+	}
+	if form.BypassUsers != nil || form.BypassTeams != nil {
+		bypassUserIDs, bypassTeamIDs := getRulesetBypassIDs(ctx, form.BypassUsers, form.BypassTeams)
+		if ctx.Written() {
+			return
+		}
+		if form.BypassUsers != nil {
+			rs.BypassUserIDs = bypassUserIDs
+		}
+		if form.BypassTeams != nil {
+			rs.BypassTeamIDs = bypassTeamIDs
+		}
+	}
+
+	if err := git_model.UpdateRuleset(ctx, rs); err != nil {
+		handleRulesetError(ctx, err)
+		return
+	}
+	respondRuleset(ctx, http.StatusOK, rs)
+}
+
+// DeleteRuleset deletes a ruleset of an organization
+func DeleteRuleset(ctx *context.APIContext) {
+	// swagger:operation DELETE /orgs/{org}/rulesets/{id} organization orgDeleteRuleset
+	// ---
+	// summary: Delete a ruleset of an organization
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: org
+	//   in: path
+	//   description: name of the organization
+	//   type: string
+	//   required: true
+	// - name: id
+	//   in: path
+	//   description: id of the ruleset
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	if err := git_model.DeleteRuleset(ctx, ctx.Org.Organization.ID, ctx.PathParamInt64("id")); err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			ctx.APIErrorNotFound(err)
+		} else {
+			ctx.APIErrorInternal(err)
+		}
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// ListRulesetEvaluations lists the pushes which a ruleset in evaluate mode would have rejected
+func ListRulesetEvaluations(ctx *context.APIContext) {
+	// swagger:operation GET /orgs/{org}/rulesets/{id}/evaluations organization orgListRulesetEvaluations
+	// ---
+	// summary: List the pushes which a ruleset in evaluate mode would have rejected
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: org
+	//   in: path
+	//   description: name of the organization
+	//   type: string
+	//   required: true
+	// - name: id
+	//   in: path
+	//   description: id of the ruleset
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// - name: page
+	//   in: query
+	//   description: page number of results to return (1-based)
+	//   type: integer
+	// - name: limit
+	//   in: query
+	//   description: page size of results
+	//   type: integer
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/RulesetEvaluationList"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	rs := getRulesetByParams(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	evaluations, count, err := db.FindAndCount[git_model.RulesetEvaluation](ctx, git_model.FindRulesetEvaluationsOptions{
+		ListOptions: utils.GetListOptions(ctx),
+		RulesetID:   rs.ID,
+	})
+	if err != nil {
+		ctx.APIErrorInternal(err)
+		return
+	}
+	if err := git_model.RulesetEvaluationList(evaluations).LoadAttributes(ctx); err != nil {
+		ctx.APIErrorInternal(err)
+		return
+	}
+
+	apiEvaluations := make([]*api.RulesetEvaluation, 0, len(evaluations))
+	for _, evaluation := range evaluations {
+		apiEvaluations = append(apiEvaluations, convert.ToRulesetEvaluation(ctx, evaluation, ctx.Doer))
+	}
+
+	ctx.SetTotalCountHeader(count)
+	ctx.JSON(http.StatusOK, apiEvaluations)
+}
+
+func getRulesetByParams(ctx *context.APIContext) *git_model.Ruleset {
+	rs, err := git_model.GetRulesetByID(ctx, ctx.Org.Organization.ID, ctx.PathParamInt64("id"))
+	if errors.Is(err, util.ErrNotExist) {
+		ctx.APIErrorNotFound(err)
+		return nil
+	} else if err != nil {
+		ctx.APIErrorInternal(err)
+		return nil
+	}
+	return rs
+}
+
+func getRulesetBypassIDs(ctx *context.APIContext, userNames, teamNames []string) (userIDs, teamIDs []int64) {
+	userIDs, err := user_model.GetUserIDsByNames(ctx, userNames, false)
+	if err != nil {
+		if user_model.IsErrUserNotExist(err) {
+			ctx.APIError(http.StatusUnprocessableEntity, err)
+		} else {
+			ctx.APIErrorInternal(err)
+		}
+		return nil, nil
+	}
+	teamIDs, err = organization.GetTeamIDsByNames(ctx, ctx.Org.Organization.ID, teamNames, false)
+	if err != nil {
+		if organization.IsErrTeamNotExist(err) {
+			ctx.APIError(http.StatusUnprocessableEntity, err)
+		} else {
+			ctx.APIErrorInternal(err)
+		}
+		return nil, nil
+	}
+	return userIDs, teamIDs
+}
+
+func handleRulesetError(ctx *context.APIContext, err error) {
+	switch {
+	case errors.Is(err, util.ErrInvalidArgument):
+		ctx.APIError(http.StatusUnprocessableEntity, err)
+	case errors.Is(err, util.ErrAlreadyExist):
+		ctx.APIError(http.StatusConflict, err)
+	default:
+		ctx.APIErrorInternal(err)
+	}
+}
+
+func respondRuleset(ctx *context.APIContext, status int, rs *git_model.Ruleset) {
+	apiRuleset, err := convert.ToRuleset(ctx, rs)
+	if err != nil {
+		ctx.APIErrorInternal(err)
+		return
+	}
+	ctx.JSON(status, apiRuleset)
+}
@@ -0,0 +1,249 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package packages
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	sigstore_module "github.com/kumose/kmup/modules/packages/sigstore"
+	"github.com/kumose/kmup/modules/util"
+	"github.com/kumose/kmup/services/context"
+	"github.com/kumose/kmup/services/convert"
+	signing_service "github.com/kumose/kmup/services/packages/signing"
+)
+
+// ListPackageAttestations gets the signature verification and all signature and attestation bundles of a package
+func ListPackageAttestations(ctx *context.APIContext) {
+	// swagger:operation GET /packages/{owner}/{type}/{name}/{version}/attestations package listPackageAttestations
+	// ---
+	// summary: Gets the signature verification and all signature and attestation bundles of a package
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the package
+	//   type: string
+	//   required: true
+	// - name: type
+	//   in: path
+	//   description: type of the package
+	//   type: string
+	//   required: true
+	// - name: name
+	//   in: path
+	//   description: name of the package
+	//   type: string
+	//   required: true
+	// - name: version
+	//   in: path
+	//   description: version of the package
+	//   type: string
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/PackageVerification"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	v, err := signing_service.GetVersionVerification(ctx, ctx.Package.Descriptor)
+	if err != nil {
+		ctx.APIErrorInternal(err)
+		return
+	}
+
+	required, err := signing_service.IsSignatureRequired(ctx, ctx.Package.Owner.ID)
+	if err != nil {
+		ctx.APIErrorInternal(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, convert.ToPackageVerification(v, required))
+}
+
+// UploadPackageAttestation adds a signature or attestation bundle to a package
+func UploadPackageAttestation(ctx *context.APIContext) {
+	// swagger:operation POST /packages/{owner}/{type}/{name}/{version}/attestations package uploadPackageAttestation
+	// ---
+	// summary: Adds a Sigstore signature or attestation bundle to a package
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the package
+	//   type: string
+	//   required: true
+	// - name: type
+	//   in: path
+	//   description: type of the package
+	//   type: string
+	//   required: true
+	// - name: name
+	//   in: path
+	//   description: name of the package
+	//   type: string
+	//   required: true
+	// - name: version
+	//   in: path
+	//   description: version of the package
+	//   type: string
+	//   required: true
+	// - name: body
+	//   in: body
+	//   description: Sigstore bundle or DSSE envelope which signs a file of the package
+	//   required: true
+	//   schema:
+	//     type: object
+	// responses:
+	//   "201":
+	//     "$ref": "#/responses/PackageAttestation"
+	//   "400":
+	//     "$ref": "#/responses/error"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+	//   "409":
+	//     "$ref": "#/responses/conflict"
+
+	data, err := io.ReadAll(io.LimitReader(ctx.Req.Body, sigstore_module.MaxBundleSize+1))
+	if err != nil {
+		ctx.APIErrorInternal(err)
+		return
+	}
+
+	b, err := signing_service.AddBundle(ctx, ctx.Package.Descriptor, data)
+	if err != nil {
+		switch {
+		case errors.Is(err, util.ErrInvalidArgument):
+			ctx.APIError(http.StatusBadRequest, err)
+		case errors.Is(err, util.ErrAlreadyExist):
+			ctx.APIError(http.StatusConflict, err)
+		default:
+			ctx.APIErrorInternal(err)
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, convert.ToPackageAttestation(b))
+}
+
+// GetPackageAttestation gets a signature or attestation bundle of a package
+func GetPackageAttestation(ctx *context.APIContext) {
+	// swagger:operation GET /packages/{owner}/{type}/{name}/{version}/attestations/{id} package getPackageAttestation
+	// ---
+	// summary: Gets the content of a signature or attestation bundle of a package
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the package
+	//   type: string
+	//   required: true
+	// - name: type
+	//   in: path
+	//   description: type of the package
+	//   type: string
+	//   required: true
+	// - name: name
+	//   in: path
+	//   description: name of the package
+	//   type: string
+	//   required: true
+	// - name: version
+	//   in: path
+	//   description: version of the package
+	//   type: string
+	//   required: true
+	// - name: id
+	//   in: path
+	//   description: id of the attestation
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "200":
+	//     description: the Sigstore bundle or DSSE envelope
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	id := ctx.PathParamInt64("id")
+	for _, pp := range ctx.Package.Descriptor.VersionProperties {
+		if pp.ID == id && pp.Name == sigstore_module.PropertyBundle {
+			ctx.Resp.Header().Set("Content-Type", "application/json")
+			ctx.Resp.WriteHeader(http.StatusOK)
+			_, _ = ctx.Resp.Write([]byte(pp.Value))
+			return
+		}
+	}
+	ctx.APIErrorNotFound()
+}
+
+// DeletePackageAttestation deletes a signature or attestation bundle of a package
+func DeletePackageAttestation(ctx *context.APIContext) {
+	// swagger:operation DELETE /packages/{owner}/{type}/{name}/{version}/attestations/{id} package deletePackageAttestation
+	// ---
+	// summary: Deletes a signature or attestation bundle of a package
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the package
+	//   type: string
+	//   required: true
+	// - name: type
+	//   in: path
+	//   description: type of the package
+	//   type: string
+	//   required: true
+	// - name: name
+	//   in: path
+	//   description: name of the package
+	//   type: string
+	//   required: true
+	// - name: version
+	//   in: path
+	//   description: version of the package
+	//   type: string
+	//   required: true
+	// - name: id
+	//   in: path
+	//   description: id of the attestation
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	if err := signing_service.DeleteBundle(ctx, ctx.Package.Descriptor, ctx.PathParamInt64("id")); err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			ctx.APIErrorNotFound(err)
+		} else {
+			ctx.APIErrorInternal(err)
+		}
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,192 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package packages
+
+import (
+	"errors"
+	"net/http"
+
+	packages_model "github.com/kumose/kmup/models/packages"
+	api "github.com/kumose/kmup/modules/structs"
+	"github.com/kumose/kmup/modules/util"
+	"github.com/kumose/kmup/routers/api/v1/utils"
+	"github.com/kumose/kmup/services/context"
+	"github.com/kumose/kmup/services/convert"
+	packages_cleanup_service "github.com/kumose/kmup/services/packages/cleanup"
+)
+
+// ListPackageCleanupRules gets the cleanup rules of an owner
+func ListPackageCleanupRules(ctx *context.APIContext) {
+	// swagger:operation GET /packages/{owner}/-/cleanup-rules package listPackageCleanupRules
+	// ---
+	// summary: Gets the package cleanup rules of an owner
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the packages
+	//   type: string
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/PackageCleanupRuleList"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	pcrs, err := packages_model.GetCleanupRulesByOwner(ctx, ctx.Package.Owner.ID)
+	if err != nil {
+		ctx.APIErrorInternal(err)
+		return
+	}
+
+	apiRules := make([]*api.PackageCleanupRule, 0, len(pcrs))
+	for _, pcr := range pcrs {
+		apiRules = append(apiRules, convert.ToPackageCleanupRule(pcr))
+	}
+
+	ctx.JSON(http.StatusOK, apiRules)
+}
+
+// PreviewPackageCleanupRule gets the package versions a cleanup rule would remove if it was executed now
+func PreviewPackageCleanupRule(ctx *context.APIContext) {
+	// swagger:operation GET /packages/{owner}/-/cleanup-rules/{id}/preview package previewPackageCleanupRule
+	// ---
+	// summary: Gets the package versions a cleanup rule would remove if it was executed now
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the packages
+	//   type: string
+	//   required: true
+	// - name: id
+	//   in: path
+	//   description: id of the cleanup rule
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/PackageList"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	pcr := getCleanupRuleByParams(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	pds, err := packages_cleanup_service.PreviewCleanupRule(ctx, pcr)
+	if err != nil {
+		ctx.APIErrorInternal(err)
+		return
+	}
+
+	apiPackages := make([]*api.Package, 0, len(pds))
+	for _, pd := range pds {
+		apiPackage, err := convert.ToPackage(ctx, pd, ctx.Doer)
+		if err != nil {
+			ctx.APIErrorInternal(err)
+			return
+		}
+		apiPackages = append(apiPackages, apiPackage)
+	}
+
+	ctx.JSON(http.StatusOK, apiPackages)
+}
+
+// ListPackageCleanupRuns gets the execution history of a cleanup rule
+func ListPackageCleanupRuns(ctx *context.APIContext) {
+	// swagger:operation GET /packages/{owner}/-/cleanup-rules/{id}/runs package listPackageCleanupRuns
+	// ---
+	// summary: Gets the execution history of a cleanup rule, most recent first
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the packages
+	//   type: string
+	//   required: true
+	// - name: id
+	//   in: path
+	//   description: id of the cleanup rule
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// - name: page
+	//   in: query
+	//   description: page number of results to return (1-based)
+	//   type: integer
+	// - name: limit
+	//   in: query
+	//   description: page size of results
+	//   type: integer
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/PackageCleanupRunList"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	pcr := getCleanupRuleByParams(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	listOptions := utils.GetListOptions(ctx)
+
+	runs, count, err := packages_model.GetCleanupRunsByRuleID(ctx, pcr.ID, listOptions)
+	if err != nil {
+		ctx.APIErrorInternal(err)
+		return
+	}
+
+	apiRuns := make([]*api.PackageCleanupRun, 0, len(runs))
+	for _, run := range runs {
+		apiRuns = append(apiRuns, convert.ToPackageCleanupRun(run))
+	}
+
+	ctx.SetLinkHeader(int(count), listOptions.PageSize)
+	ctx.SetTotalCountHeader(count)
+	ctx.JSON(http.StatusOK, apiRuns)
+}
+
+func getCleanupRuleByParams(ctx *context.APIContext) *packages_model.PackageCleanupRule {
+	pcr, err := packages_model.GetCleanupRuleByID(ctx, ctx.PathParamInt64("id"))
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			ctx.APIErrorNotFound()
+		} else {
+			ctx.APIErrorInternal(err)
+		}
+		return nil
+	}
+	if pcr.OwnerID != ctx.Package.Owner.ID {
+		ctx.APIErrorNotFound()
+		return nil
+	}
+	return pcr
+}
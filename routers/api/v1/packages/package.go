@@ -30,6 +30,7 @@ import (
 	"github.com/kumose/kmup/services/context"
 	"github.com/kumose/kmup/services/convert"
 	packages_service "github.com/kumose/kmup/services/packages"
+	container_service "github.com/kumose/kmup/services/packages/container"
 )
 
 // ListPackages gets all packages of an owner
@@ -57,7 +58,7 @@ func ListPackages(ctx *context.APIContext) {
 	//   in: query
 	//   description: package type filter
 	//   type: string
-	//   enum: [alpine, cargo, chef, composer, conan, conda, container, cran, debian, generic, go, helm, maven, npm, nuget, pub, pypi, rpm, rubygems, swift, vagrant]
+	//   enum: [alpine, cargo, chef, composer, conan, conda, container, cran, debian, generic, go, helm, hex, maven, npm, nuget, pub, pypi, rpm, rubygems, swift, terraform_module, terraform_provider, vagrant]
 	// - name: q
 	//   in: query
 	//   description: name filter
@@ -167,6 +168,12 @@ func DeletePackage(ctx *context.APIContext) {
 		ctx.APIErrorInternal(err)
 		return
 	}
+	if ctx.Package.Descriptor.Package.Type == packages.TypeContainer {
+		if err := container_service.RemoveOrphanedReferrers(ctx, ctx.Doer, ctx.Package.Descriptor.Package.ID); err != nil {
+			ctx.APIErrorInternal(err)
+			return
+		}
+	}
 	ctx.Status(http.StatusNoContent)
 }
 
@@ -0,0 +1,75 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package packages
+
+import (
+	"net/http"
+
+	api "github.com/kumose/kmup/modules/structs"
+	"github.com/kumose/kmup/services/context"
+	"github.com/kumose/kmup/services/convert"
+	vulnerability_service "github.com/kumose/kmup/services/packages/vulnerability"
+)
+
+// ListPackageVulnerabilities gets the advisories which match a package or its dependencies
+func ListPackageVulnerabilities(ctx *context.APIContext) {
+	// swagger:operation GET /packages/{owner}/{type}/{name}/{version}/vulnerabilities package listPackageVulnerabilities
+	// ---
+	// summary: Gets the advisories which match a package or its dependencies
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the package
+	//   type: string
+	//   required: true
+	// - name: type
+	//   in: path
+	//   description: type of the package
+	//   type: string
+	//   required: true
+	// - name: name
+	//   in: path
+	//   description: name of the package
+	//   type: string
+	//   required: true
+	// - name: version
+	//   in: path
+	//   description: version of the package
+	//   type: string
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/PackageVulnerabilityList"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	vulnerabilities, err := vulnerability_service.GetVersionVulnerabilities(ctx, ctx.Package.Descriptor)
+	if err != nil {
+		ctx.APIErrorInternal(err)
+		return
+	}
+
+	apiVulnerabilities := make([]*api.PackageVulnerability, 0, len(vulnerabilities))
+	for _, v := range vulnerabilities {
+		apiVulnerabilities = append(apiVulnerabilities, convert.ToPackageVulnerability(v))
+	}
+
+	ctx.JSON(http.StatusOK, apiVulnerabilities)
+}
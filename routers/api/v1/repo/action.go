@@ -1275,6 +1275,162 @@ func GetWorkflowJob(ctx *context.APIContext) {
 	ctx.JSON(http.StatusOK, convertedWorkflowJob)
 }
 
+// ListWorkflowJobAnnotations lists the annotations of a workflow job
+func ListWorkflowJobAnnotations(ctx *context.APIContext) {
+	// swagger:operation GET /repos/{owner}/{repo}/actions/jobs/{job_id}/annotations repository listWorkflowJobAnnotations
+	// ---
+	// summary: Lists the annotations of the latest attempt of a workflow job
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the repo
+	//   type: string
+	//   required: true
+	// - name: repo
+	//   in: path
+	//   description: name of the repository
+	//   type: string
+	//   required: true
+	// - name: job_id
+	//   in: path
+	//   description: id of the job
+	//   type: integer
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/ActionTaskAnnotationList"
+	//   "400":
+	//     "$ref": "#/responses/error"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	jobID := ctx.PathParamInt64("job_id")
+	job, has, err := db.GetByID[actions_model.ActionRunJob](ctx, jobID)
+	if err != nil {
+		ctx.APIErrorInternal(err)
+		return
+	}
+
+	if !has || job.RepoID != ctx.Repo.Repository.ID {
+		ctx.APIErrorNotFound(util.ErrNotExist)
+		return
+	}
+
+	apiAnnotations := make([]*api.ActionTaskAnnotation, 0)
+	if job.TaskID > 0 {
+		annotations, err := actions_model.FindTaskAnnotations(ctx, job.TaskID)
+		if err != nil {
+			ctx.APIErrorInternal(err)
+			return
+		}
+		for _, annotation := range annotations {
+			apiAnnotations = append(apiAnnotations, convert.ToActionTaskAnnotation(ctx.Repo.Repository, annotation))
+		}
+	}
+	ctx.JSON(http.StatusOK, apiAnnotations)
+}
+
+// GetWorkflowRunTestResults lists the test results of a workflow run
+func GetWorkflowRunTestResults(ctx *context.APIContext) {
+	// swagger:operation GET /repos/{owner}/{repo}/actions/runs/{run}/test-results repository getWorkflowRunTestResults
+	// ---
+	// summary: Lists the test results reported by the latest attempts of the jobs of a workflow run
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the repo
+	//   type: string
+	//   required: true
+	// - name: repo
+	//   in: path
+	//   description: name of the repository
+	//   type: string
+	//   required: true
+	// - name: run
+	//   in: path
+	//   description: id of the run
+	//   type: integer
+	//   required: true
+	// - name: status
+	//   in: query
+	//   description: only return the test cases with the status
+	//   type: string
+	//   enum: [passed, failed, skipped]
+	// - name: page
+	//   in: query
+	//   description: page number of results to return (1-based)
+	//   type: integer
+	// - name: limit
+	//   in: query
+	//   description: page size of results
+	//   type: integer
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/ActionTestResultsResponse"
+	//   "400":
+	//     "$ref": "#/responses/error"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	runID := ctx.PathParamInt64("run")
+	run, has, err := db.GetByID[actions_model.ActionRun](ctx, runID)
+	if err != nil {
+		ctx.APIErrorInternal(err)
+		return
+	}
+	if !has || run.RepoID != ctx.Repo.Repository.ID {
+		ctx.APIErrorNotFound(util.ErrNotExist)
+		return
+	}
+
+	status := actions_model.TestStatus(ctx.FormString("status"))
+	switch status {
+	case "", actions_model.TestStatusPassed, actions_model.TestStatusFailed, actions_model.TestStatusSkipped:
+	default:
+		ctx.APIError(http.StatusBadRequest, util.NewInvalidArgumentErrorf("invalid status %q", status))
+		return
+	}
+
+	summary, err := actions_model.GetRunTestSummary(ctx, run.ID)
+	if err != nil {
+		ctx.APIErrorInternal(err)
+		return
+	}
+	cases, total, err := db.FindAndCount[actions_model.ActionTestCase](ctx, actions_model.FindTestCasesOptions{
+		ListOptions: utils.GetListOptions(ctx),
+		RunID:       run.ID,
+		Status:      status,
+	})
+	if err != nil {
+		ctx.APIErrorInternal(err)
+		return
+	}
+	if err := actions_model.LoadFlaky(ctx, run.RepoID, cases); err != nil {
+		ctx.APIErrorInternal(err)
+		return
+	}
+
+	res := &api.ActionTestResultsResponse{
+		Summary: &api.ActionTestSummary{
+			Total:   summary.Total,
+			Passed:  summary.Passed,
+			Failed:  summary.Failed,
+			Skipped: summary.Skipped,
+		},
+		TestCases:  make([]*api.ActionTestCase, 0, len(cases)),
+		TotalCount: total,
+	}
+	for _, c := range cases {
+		res.TestCases = append(res.TestCases, convert.ToActionTestCase(c))
+	}
+	ctx.SetTotalCountHeader(total)
+	ctx.JSON(http.StatusOK, res)
+}
+
 // GetArtifactsOfRun Lists all artifacts for a repository.
 func GetArtifactsOfRun(ctx *context.APIContext) {
 	// swagger:operation GET /repos/{owner}/{repo}/actions/runs/{run}/artifacts repository getArtifactsOfRun
@@ -1303,6 +1459,11 @@ func GetArtifactsOfRun(ctx *context.APIContext) {
 	//   description: name of the artifact
 	//   type: string
 	//   required: false
+	// - name: attempt
+	//   in: query
+	//   description: attempt of the workflow run, the artifacts of the latest attempt are returned if it is not given
+	//   type: integer
+	//   required: false
 	// responses:
 	//   "200":
 	//     "$ref": "#/responses/ArtifactsList"
@@ -1316,9 +1477,23 @@ func GetArtifactsOfRun(ctx *context.APIContext) {
 
 	runID := ctx.PathParamInt64("run")
 
+	// a run has no artifacts if it does not exist, the artifacts of the latest attempt are returned by default
+	var attempt int64
+	run, err := actions_model.GetRunByRepoAndID(ctx, repoID, runID)
+	if err == nil {
+		attempt = ctx.FormInt64("attempt")
+		if attempt <= 0 || attempt > run.Attempt {
+			attempt = run.Attempt
+		}
+	} else if !errors.Is(err, util.ErrNotExist) {
+		ctx.APIErrorInternal(err)
+		return
+	}
+
 	artifacts, total, err := db.FindAndCount[actions_model.ActionArtifact](ctx, actions_model.FindArtifactsOptions{
 		RepoID:               repoID,
 		RunID:                runID,
+		RunAttempt:           attempt,
 		ArtifactName:         artifactName,
 		FinalizedArtifactsV4: true,
 		ListOptions:          utils.GetListOptions(ctx),
@@ -1543,7 +1718,7 @@ func DeleteArtifact(ctx *context.APIContext) {
 	}
 
 	if actions.IsArtifactV4(art) {
-		if err := actions_model.SetArtifactNeedDelete(ctx, art.RunID, art.ArtifactName); err != nil {
+		if err := actions_model.SetArtifactNeedDelete(ctx, art.RunID, art.RunAttempt, art.ArtifactName); err != nil {
 			ctx.APIErrorInternal(err)
 			return
 		}
@@ -19,11 +19,17 @@ package repo
 
 import (
 	"errors"
+	"net/http"
 
 	actions_model "github.com/kumose/kmup/models/actions"
+	"github.com/kumose/kmup/models/db"
+	api "github.com/kumose/kmup/modules/structs"
 	"github.com/kumose/kmup/modules/util"
+	"github.com/kumose/kmup/modules/web"
 	"github.com/kumose/kmup/routers/common"
+	actions_service "github.com/kumose/kmup/services/actions"
 	"github.com/kumose/kmup/services/context"
+	"github.com/kumose/kmup/services/convert"
 )
 
 func DownloadActionsRunJobLogs(ctx *context.APIContext) {
@@ -48,6 +54,10 @@ func DownloadActionsRunJobLogs(ctx *context.APIContext) {
 	//   description: id of the job
 	//   type: integer
 	//   required: true
+	// - name: attempt
+	//   in: query
+	//   description: attempt of the workflow run, the logs of the latest attempt are downloaded if it is not given
+	//   type: integer
 	// responses:
 	//   "200":
 	//     description: output blob content
@@ -67,7 +77,7 @@ func DownloadActionsRunJobLogs(ctx *context.APIContext) {
 		return
 	}
 
-	err = common.DownloadActionsRunJobLogs(ctx.Base, ctx.Repo.Repository, curJob)
+	err = common.DownloadActionsRunJobLogs(ctx.Base, ctx.Repo.Repository, curJob, ctx.FormInt64("attempt"))
 	if err != nil {
 		if errors.Is(err, util.ErrNotExist) {
 			ctx.APIErrorNotFound(err)
@@ -76,3 +86,193 @@ func DownloadActionsRunJobLogs(ctx *context.APIContext) {
 		}
 	}
 }
+
+// RerunWorkflowRun reruns all jobs of a workflow run
+func RerunWorkflowRun(ctx *context.APIContext) {
+	// swagger:operation POST /repos/{owner}/{repo}/actions/runs/{run}/rerun repository rerunWorkflowRun
+	// ---
+	// summary: Reruns all jobs of a workflow run
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the repo
+	//   type: string
+	//   required: true
+	// - name: repo
+	//   in: path
+	//   description: name of the repository
+	//   type: string
+	//   required: true
+	// - name: run
+	//   in: path
+	//   description: id of the run
+	//   type: integer
+	//   required: true
+	// - name: body
+	//   in: body
+	//   schema:
+	//     "$ref": "#/definitions/RerunActionRunOption"
+	// responses:
+	//   "201":
+	//     "$ref": "#/responses/WorkflowRun"
+	//   "400":
+	//     "$ref": "#/responses/error"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	run, jobs := getRunWithJobs(ctx, ctx.PathParamInt64("run"))
+	if ctx.Written() {
+		return
+	}
+
+	form := web.GetForm(ctx).(*api.RerunActionRunOption)
+	rerunRun(ctx, run, jobs, actions_service.RerunOptions{DebugLogging: form.EnableDebugLogging})
+}
+
+// RerunFailedWorkflowRunJobs reruns the failed jobs of a workflow run and the jobs depending on them
+func RerunFailedWorkflowRunJobs(ctx *context.APIContext) {
+	// swagger:operation POST /repos/{owner}/{repo}/actions/runs/{run}/rerun-failed-jobs repository rerunFailedWorkflowRunJobs
+	// ---
+	// summary: Reruns the failed jobs of a workflow run and the jobs depending on them
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the repo
+	//   type: string
+	//   required: true
+	// - name: repo
+	//   in: path
+	//   description: name of the repository
+	//   type: string
+	//   required: true
+	// - name: run
+	//   in: path
+	//   description: id of the run
+	//   type: integer
+	//   required: true
+	// - name: body
+	//   in: body
+	//   schema:
+	//     "$ref": "#/definitions/RerunActionRunOption"
+	// responses:
+	//   "201":
+	//     "$ref": "#/responses/WorkflowRun"
+	//   "400":
+	//     "$ref": "#/responses/error"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	run, jobs := getRunWithJobs(ctx, ctx.PathParamInt64("run"))
+	if ctx.Written() {
+		return
+	}
+
+	form := web.GetForm(ctx).(*api.RerunActionRunOption)
+	rerunRun(ctx, run, jobs, actions_service.RerunOptions{FailedOnly: true, DebugLogging: form.EnableDebugLogging})
+}
+
+// RerunWorkflowJob reruns a job of a workflow run and the jobs depending on it
+func RerunWorkflowJob(ctx *context.APIContext) {
+	// swagger:operation POST /repos/{owner}/{repo}/actions/jobs/{job_id}/rerun repository rerunWorkflowJob
+	// ---
+	// summary: Reruns a job of a workflow run and the jobs depending on it
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the repo
+	//   type: string
+	//   required: true
+	// - name: repo
+	//   in: path
+	//   description: name of the repository
+	//   type: string
+	//   required: true
+	// - name: job_id
+	//   in: path
+	//   description: id of the job
+	//   type: integer
+	//   required: true
+	// - name: body
+	//   in: body
+	//   schema:
+	//     "$ref": "#/definitions/RerunActionRunOption"
+	// responses:
+	//   "201":
+	//     "$ref": "#/responses/WorkflowRun"
+	//   "400":
+	//     "$ref": "#/responses/error"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	job, has, err := db.GetByID[actions_model.ActionRunJob](ctx, ctx.PathParamInt64("job_id"))
+	if err != nil {
+		ctx.APIErrorInternal(err)
+		return
+	}
+	if !has || job.RepoID != ctx.Repo.Repository.ID {
+		ctx.APIErrorNotFound(util.ErrNotExist)
+		return
+	}
+
+	run, jobs := getRunWithJobs(ctx, job.RunID)
+	if ctx.Written() {
+		return
+	}
+
+	form := web.GetForm(ctx).(*api.RerunActionRunOption)
+	rerunRun(ctx, run, jobs, actions_service.RerunOptions{Job: job, DebugLogging: form.EnableDebugLogging})
+}
+
+func getRunWithJobs(ctx *context.APIContext, runID int64) (*actions_model.ActionRun, []*actions_model.ActionRunJob) {
+	run, err := actions_model.GetRunByRepoAndID(ctx, ctx.Repo.Repository.ID, runID)
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			ctx.APIErrorNotFound(err)
+		} else {
+			ctx.APIErrorInternal(err)
+		}
+		return nil, nil
+	}
+	jobs, err := actions_model.GetRunJobsByRunID(ctx, run.ID)
+	if err != nil {
+		ctx.APIErrorInternal(err)
+		return nil, nil
+	}
+	return run, jobs
+}
+
+func rerunRun(ctx *context.APIContext, run *actions_model.ActionRun, jobs []*actions_model.ActionRunJob, opts actions_service.RerunOptions) {
+	if err := actions_service.RerunRun(ctx, run, jobs, opts); err != nil {
+		if errors.Is(err, util.ErrInvalidArgument) {
+			ctx.APIError(http.StatusBadRequest, err)
+		} else {
+			ctx.APIErrorInternal(err)
+		}
+		return
+	}
+
+	convertedRun, err := convert.ToActionWorkflowRun(ctx, ctx.Repo.Repository, run)
+	if err != nil {
+		ctx.APIErrorInternal(err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, convertedRun)
+}
@@ -36,6 +36,7 @@ import (
 	"github.com/kumose/kmup/routers/api/v1/utils"
 	"github.com/kumose/kmup/services/context"
 	"github.com/kumose/kmup/services/convert"
+	"github.com/kumose/kmup/services/mergequeue"
 	pull_service "github.com/kumose/kmup/services/pull"
 	release_service "github.com/kumose/kmup/services/release"
 	repo_service "github.com/kumose/kmup/services/repository"
@@ -87,7 +88,7 @@ func GetBranch(ctx *context.APIContext) {
 		return
 	}
 
-	branchProtection, err := git_model.GetFirstMatchProtectedBranchRule(ctx, ctx.Repo.Repository.ID, branchName)
+	branchProtection, err := git_model.GetFirstMatchProtectedBranchRuleForUser(ctx, ctx.Repo.Repository.ID, branchName, ctx.Doer)
 	if err != nil {
 		ctx.APIErrorInternal(err)
 		return
@@ -278,7 +279,7 @@ func CreateBranch(ctx *context.APIContext) {
 		return
 	}
 
-	branchProtection, err := git_model.GetFirstMatchProtectedBranchRule(ctx, ctx.Repo.Repository.ID, opt.BranchName)
+	branchProtection, err := git_model.GetFirstMatchProtectedBranchRuleForUser(ctx, ctx.Repo.Repository.ID, opt.BranchName, ctx.Doer)
 	if err != nil {
 		ctx.APIErrorInternal(err)
 		return
@@ -353,7 +354,7 @@ func ListBranches(ctx *context.APIContext) {
 			}
 		}
 
-		rules, err := git_model.FindRepoProtectedBranchRules(ctx, ctx.Repo.Repository.ID)
+		rules, err := git_model.FindEffectiveBranchRules(ctx, ctx.Repo.Repository.ID)
 		if err != nil {
 			ctx.APIErrorInternal(err)
 			return
@@ -378,7 +379,7 @@ func ListBranches(ctx *context.APIContext) {
 				return
 			}
 
-			branchProtection := rules.GetFirstMatched(branches[i].Name)
+			branchProtection := rules.GetFirstMatchedForUser(ctx, branches[i].Name, ctx.Doer)
 			apiBranch, err := convert.ToBranch(ctx, ctx.Repo.Repository, branches[i].Name, c, branchProtection, ctx.Doer, ctx.Repo.IsAdmin())
 			if err != nil {
 				ctx.APIErrorInternal(err)
@@ -713,6 +714,9 @@ func CreateBranchProtection(ctx *context.APIContext) {
 		UnprotectedFilePatterns:       form.UnprotectedFilePatterns,
 		BlockOnOutdatedBranch:         form.BlockOnOutdatedBranch,
 		BlockAdminMergeOverride:       form.BlockAdminMergeOverride,
+		RequireRequiredWorkflows:      form.RequireRequiredWorkflows,
+		RequireCodeOwnerApproval:      form.RequireCodeOwnerApproval,
+		EnableMergeQueue:              form.EnableMergeQueue,
 	}
 
 	if err := pull_service.CreateOrUpdateProtectedBranch(ctx, ctx.Repo.Repository, protectBranch, git_model.WhitelistOptions{
@@ -896,6 +900,18 @@ func EditBranchProtection(ctx *context.APIContext) {
 		protectBranch.BlockAdminMergeOverride = *form.BlockAdminMergeOverride
 	}
 
+	if form.RequireRequiredWorkflows != nil {
+		protectBranch.RequireRequiredWorkflows = *form.RequireRequiredWorkflows
+	}
+
+	if form.RequireCodeOwnerApproval != nil {
+		protectBranch.RequireCodeOwnerApproval = *form.RequireCodeOwnerApproval
+	}
+
+	if form.EnableMergeQueue != nil {
+		protectBranch.EnableMergeQueue = *form.EnableMergeQueue
+	}
+
 	var whitelistUsers, forcePushAllowlistUsers, mergeWhitelistUsers, approvalsWhitelistUsers []int64
 	if form.PushWhitelistUsernames != nil {
 		whitelistUsers, err = user_model.GetUserIDsByNames(ctx, form.PushWhitelistUsernames, false)
@@ -1062,6 +1078,9 @@ func EditBranchProtection(ctx *context.APIContext) {
 		}
 	}
 
+	// the merge queues of the branches which don't require one anymore have to be emptied
+	mergequeue.StartRepoMergeQueuesProcessing(ctx, ctx.Repo.Repository.ID)
+
 	// Reload from db to ensure get all whitelists
 	bp, err := git_model.GetProtectedBranchRuleByName(ctx, repo.ID, bpName)
 	if err != nil {
@@ -1121,6 +1140,7 @@ func DeleteBranchProtection(ctx *context.APIContext) {
 		ctx.APIErrorInternal(err)
 		return
 	}
+	mergequeue.StartRepoMergeQueuesProcessing(ctx, ctx.Repo.Repository.ID)
 
 	ctx.Status(http.StatusNoContent)
 }
@@ -42,6 +42,7 @@ import (
 	"github.com/kumose/kmup/modules/setting"
 	api "github.com/kumose/kmup/modules/structs"
 	"github.com/kumose/kmup/modules/timeutil"
+	"github.com/kumose/kmup/modules/util"
 	"github.com/kumose/kmup/modules/web"
 	"github.com/kumose/kmup/routers/api/v1/utils"
 	asymkey_service "github.com/kumose/kmup/services/asymkey"
@@ -51,6 +52,7 @@ import (
 	"github.com/kumose/kmup/services/forms"
 	"github.com/kumose/kmup/services/gitdiff"
 	issue_service "github.com/kumose/kmup/services/issue"
+	"github.com/kumose/kmup/services/mergequeue"
 	notify_service "github.com/kumose/kmup/services/notify"
 	pull_service "github.com/kumose/kmup/services/pull"
 	repo_service "github.com/kumose/kmup/services/repository"
@@ -900,6 +902,8 @@ func MergePullRequest(ctx *context.APIContext) {
 	// responses:
 	//   "200":
 	//     "$ref": "#/responses/empty"
+	//   "202":
+	//     "$ref": "#/responses/empty"
 	//   "404":
 	//     "$ref": "#/responses/notFound"
 	//   "405":
@@ -950,6 +954,20 @@ func MergePullRequest(ctx *context.APIContext) {
 		mergeCheckType = pull_service.MergeCheckTypeManually
 	}
 
+	// a branch which requires a merge queue gets the pull request queued, unless an admin forces the merge
+	addToMergeQueue := false
+	if mergeCheckType == pull_service.MergeCheckTypeGeneral && !form.ForceMerge {
+		queueRequired, err := pull_service.IsMergeQueueRequired(ctx, pr)
+		if err != nil {
+			ctx.APIErrorInternal(err)
+			return
+		}
+		if queueRequired {
+			addToMergeQueue = true
+			mergeCheckType = pull_service.MergeCheckTypeQueue
+		}
+	}
+
 	// start with merging by checking
 	if err := pull_service.CheckPullMergeable(ctx, ctx.Doer, &ctx.Repo.Permission, pr, mergeCheckType, form.ForceMerge); err != nil {
 		if errors.Is(err, pull_service.ErrIsClosed) {
@@ -1030,6 +1048,19 @@ func MergePullRequest(ctx *context.APIContext) {
 		}
 	}
 
+	if addToMergeQueue {
+		if err := mergequeue.AddToMergeQueue(ctx, ctx.Doer, pr, repo_model.MergeStyle(form.Do), message, deleteBranchAfterMerge); err != nil {
+			if pull_model.IsErrAlreadyInMergeQueue(err) {
+				ctx.APIError(http.StatusConflict, err)
+				return
+			}
+			ctx.APIErrorInternal(err)
+			return
+		}
+		ctx.Status(http.StatusAccepted)
+		return
+	}
+
 	if err := pull_service.Merge(ctx, pr, ctx.Doer, repo_model.MergeStyle(form.Do), form.HeadCommitID, message, false); err != nil {
 		if pull_service.IsErrInvalidMergeStyle(err) {
 			ctx.APIError(http.StatusMethodNotAllowed, fmt.Errorf("%s is not allowed an allowed merge style for this repository", repo_model.MergeStyle(form.Do)))
@@ -1642,3 +1673,180 @@ func GetPullRequestFiles(ctx *context.APIContext) {
 
 	ctx.JSON(http.StatusOK, &apiFiles)
 }
+
+// GetPullRequestMissingCodeOwnerApprovals lists the changed files whose code owners have not approved the pull request
+func GetPullRequestMissingCodeOwnerApprovals(ctx *context.APIContext) {
+	// swagger:operation GET /repos/{owner}/{repo}/pulls/{index}/code_owners repository repoGetPullRequestMissingCodeOwnerApprovals
+	// ---
+	// summary: List the changed files of a pull request whose code owners have not approved it yet
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the repo
+	//   type: string
+	//   required: true
+	// - name: repo
+	//   in: path
+	//   description: name of the repo
+	//   type: string
+	//   required: true
+	// - name: index
+	//   in: path
+	//   description: index of the pull request
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/MissingCodeOwnerApprovalList"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	pr, err := issues_model.GetPullRequestByIndex(ctx, ctx.Repo.Repository.ID, ctx.PathParamInt64("index"))
+	if err != nil {
+		if issues_model.IsErrPullRequestNotExist(err) {
+			ctx.APIErrorNotFound()
+		} else {
+			ctx.APIErrorInternal(err)
+		}
+		return
+	}
+
+	pb, err := git_model.GetFirstMatchProtectedBranchRule(ctx, pr.BaseRepoID, pr.BaseBranch)
+	if err != nil {
+		ctx.APIErrorInternal(err)
+		return
+	}
+
+	missing, err := pull_service.GetMissingCodeOwnerApprovals(ctx, pb, pr)
+	if err != nil {
+		ctx.APIErrorInternal(err)
+		return
+	}
+
+	apiMissing := make([]*api.MissingCodeOwnerApproval, 0, len(missing))
+	for _, m := range missing {
+		teams, err := convert.ToTeams(ctx, m.Teams, true)
+		if err != nil {
+			ctx.APIErrorInternal(err)
+			return
+		}
+		apiMissing = append(apiMissing, &api.MissingCodeOwnerApproval{
+			Path:  m.Path,
+			Users: convert.ToUsers(ctx, ctx.Doer, m.Users),
+			Teams: teams,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, apiMissing)
+}
+
+// ListMergeQueue lists the pull requests in the merge queue of a branch
+func ListMergeQueue(ctx *context.APIContext) {
+	// swagger:operation GET /repos/{owner}/{repo}/pulls/merge_queue repository repoListMergeQueue
+	// ---
+	// summary: List the pull requests in the merge queue of a branch in queue order
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the repo
+	//   type: string
+	//   required: true
+	// - name: repo
+	//   in: path
+	//   description: name of the repo
+	//   type: string
+	//   required: true
+	// - name: branch
+	//   in: query
+	//   description: target branch of the merge queue, defaults to the default branch of the repo
+	//   type: string
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/MergeQueueEntryList"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	branch := ctx.FormTrim("branch")
+	if branch == "" {
+		branch = ctx.Repo.Repository.DefaultBranch
+	}
+
+	queued, err := mergequeue.GetMergeQueue(ctx, ctx.Repo.Repository, branch)
+	if err != nil {
+		ctx.APIErrorInternal(err)
+		return
+	}
+
+	apiEntries := make([]*api.MergeQueueEntry, 0, len(queued))
+	for _, q := range queued {
+		apiEntry, err := convert.ToAPIMergeQueueEntry(ctx, q.Entry, q.PullRequest, q.Position, q.State, ctx.Doer)
+		if err != nil {
+			ctx.APIErrorInternal(err)
+			return
+		}
+		apiEntries = append(apiEntries, apiEntry)
+	}
+
+	ctx.JSON(http.StatusOK, apiEntries)
+}
+
+// RemoveFromMergeQueue removes a pull request from the merge queue of its base branch
+func RemoveFromMergeQueue(ctx *context.APIContext) {
+	// swagger:operation DELETE /repos/{owner}/{repo}/pulls/{index}/merge_queue repository repoRemoveFromMergeQueue
+	// ---
+	// summary: Remove a pull request from the merge queue of its base branch
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the repo
+	//   type: string
+	//   required: true
+	// - name: repo
+	//   in: path
+	//   description: name of the repo
+	//   type: string
+	//   required: true
+	// - name: index
+	//   in: path
+	//   description: index of the pull request
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+	//   "423":
+	//     "$ref": "#/responses/repoArchivedError"
+
+	pr, err := issues_model.GetPullRequestByIndex(ctx, ctx.Repo.Repository.ID, ctx.PathParamInt64("index"))
+	if err != nil {
+		if issues_model.IsErrPullRequestNotExist(err) {
+			ctx.APIErrorNotFound()
+		} else {
+			ctx.APIErrorInternal(err)
+		}
+		return
+	}
+
+	if err := mergequeue.RemoveFromMergeQueue(ctx, ctx.Doer, pr); err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			ctx.APIErrorNotFound()
+			return
+		}
+		ctx.APIErrorInternal(err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
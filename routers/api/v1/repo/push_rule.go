@@ -0,0 +1,112 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package repo
+
+import (
+	"github.com/kumose/kmup/routers/api/v1/shared"
+	"github.com/kumose/kmup/services/context"
+)
+
+// GetPushRule gets the push rule of a repository
+func GetPushRule(ctx *context.APIContext) {
+	// swagger:operation GET /repos/{owner}/{repo}/push_rules repository repoGetPushRule
+	// ---
+	// summary: Get the push rule of a repository
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the repo
+	//   type: string
+	//   required: true
+	// - name: repo
+	//   in: path
+	//   description: name of the repo
+	//   type: string
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/PushRule"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	shared.GetPushRule(ctx, 0, ctx.Repo.Repository.ID)
+}
+
+// SetPushRule creates or replaces the push rule of a repository
+func SetPushRule(ctx *context.APIContext) {
+	// swagger:operation PUT /repos/{owner}/{repo}/push_rules repository repoSetPushRule
+	// ---
+	// summary: Create or replace the push rule of a repository
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the repo
+	//   type: string
+	//   required: true
+	// - name: repo
+	//   in: path
+	//   description: name of the repo
+	//   type: string
+	//   required: true
+	// - name: body
+	//   in: body
+	//   schema:
+	//     "$ref": "#/definitions/EditPushRuleOption"
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/PushRule"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	shared.SetPushRule(ctx, 0, ctx.Repo.Repository.ID)
+}
+
+// DeletePushRule deletes the push rule of a repository
+func DeletePushRule(ctx *context.APIContext) {
+	// swagger:operation DELETE /repos/{owner}/{repo}/push_rules repository repoDeletePushRule
+	// ---
+	// summary: Delete the push rule of a repository
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the repo
+	//   type: string
+	//   required: true
+	// - name: repo
+	//   in: path
+	//   description: name of the repo
+	//   type: string
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	shared.DeletePushRule(ctx, 0, ctx.Repo.Repository.ID)
+}
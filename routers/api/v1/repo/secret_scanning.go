@@ -0,0 +1,240 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package repo
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/kumose/kmup/models/db"
+	secretscan_model "github.com/kumose/kmup/models/secretscan"
+	api "github.com/kumose/kmup/modules/structs"
+	"github.com/kumose/kmup/modules/util"
+	"github.com/kumose/kmup/modules/web"
+	"github.com/kumose/kmup/routers/api/v1/utils"
+	"github.com/kumose/kmup/services/context"
+	"github.com/kumose/kmup/services/convert"
+	secretscan_service "github.com/kumose/kmup/services/secretscan"
+)
+
+// ListSecretScanningAlerts lists the secret scanning alerts of a repository
+func ListSecretScanningAlerts(ctx *context.APIContext) {
+	// swagger:operation GET /repos/{owner}/{repo}/secret_scanning/alerts repository repoListSecretScanningAlerts
+	// ---
+	// summary: List the secret scanning alerts of a repository
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the repo
+	//   type: string
+	//   required: true
+	// - name: repo
+	//   in: path
+	//   description: name of the repo
+	//   type: string
+	//   required: true
+	// - name: state
+	//   in: query
+	//   description: only return the alerts in this state
+	//   type: string
+	//   enum: [open, revoked, false_positive, used_in_tests]
+	// - name: page
+	//   in: query
+	//   description: page number of results to return (1-based)
+	//   type: integer
+	// - name: limit
+	//   in: query
+	//   description: page size of results
+	//   type: integer
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/SecretScanningAlertList"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	state := secretscan_model.AlertState(ctx.FormString("state"))
+	if state != "" && !state.IsValid() {
+		ctx.APIError(http.StatusUnprocessableEntity, "invalid state "+string(state))
+		return
+	}
+
+	alerts, count, err := db.FindAndCount[secretscan_model.Alert](ctx, secretscan_model.FindAlertsOptions{
+		ListOptions: utils.GetListOptions(ctx),
+		RepoID:      ctx.Repo.Repository.ID,
+		State:       state,
+	})
+	if err != nil {
+		ctx.APIErrorInternal(err)
+		return
+	}
+
+	apiAlerts := make([]*api.SecretScanningAlert, len(alerts))
+	for i, alert := range alerts {
+		apiAlerts[i] = convert.ToSecretScanningAlert(ctx, ctx.Repo.Repository, alert)
+	}
+
+	ctx.SetTotalCountHeader(count)
+	ctx.JSON(http.StatusOK, apiAlerts)
+}
+
+func getSecretScanningAlert(ctx *context.APIContext) *secretscan_model.Alert {
+	alert, err := secretscan_model.GetAlertByID(ctx, ctx.Repo.Repository.ID, ctx.PathParamInt64("id"))
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			ctx.APIErrorNotFound()
+		} else {
+			ctx.APIErrorInternal(err)
+		}
+		return nil
+	}
+	return alert
+}
+
+// GetSecretScanningAlert gets a secret scanning alert of a repository
+func GetSecretScanningAlert(ctx *context.APIContext) {
+	// swagger:operation GET /repos/{owner}/{repo}/secret_scanning/alerts/{id} repository repoGetSecretScanningAlert
+	// ---
+	// summary: Get a secret scanning alert of a repository
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the repo
+	//   type: string
+	//   required: true
+	// - name: repo
+	//   in: path
+	//   description: name of the repo
+	//   type: string
+	//   required: true
+	// - name: id
+	//   in: path
+	//   description: id of the alert
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/SecretScanningAlert"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	alert := getSecretScanningAlert(ctx)
+	if ctx.Written() {
+		return
+	}
+	ctx.JSON(http.StatusOK, convert.ToSecretScanningAlert(ctx, ctx.Repo.Repository, alert))
+}
+
+// EditSecretScanningAlert changes the state of a secret scanning alert of a repository
+func EditSecretScanningAlert(ctx *context.APIContext) {
+	// swagger:operation PATCH /repos/{owner}/{repo}/secret_scanning/alerts/{id} repository repoEditSecretScanningAlert
+	// ---
+	// summary: Change the state of a secret scanning alert of a repository
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the repo
+	//   type: string
+	//   required: true
+	// - name: repo
+	//   in: path
+	//   description: name of the repo
+	//   type: string
+	//   required: true
+	// - name: id
+	//   in: path
+	//   description: id of the alert
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// - name: body
+	//   in: body
+	//   schema:
+	//     "$ref": "#/definitions/EditSecretScanningAlertOption"
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/SecretScanningAlert"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	form := web.GetForm(ctx).(*api.EditSecretScanningAlertOption)
+	state := secretscan_model.AlertState(form.State)
+	if !state.IsValid() {
+		ctx.APIError(http.StatusUnprocessableEntity, "invalid state "+form.State)
+		return
+	}
+
+	alert := getSecretScanningAlert(ctx)
+	if ctx.Written() {
+		return
+	}
+	if err := secretscan_service.UpdateAlertState(ctx, ctx.Doer, ctx.Repo.Repository, alert, state); err != nil {
+		ctx.APIErrorInternal(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, convert.ToSecretScanningAlert(ctx, ctx.Repo.Repository, alert))
+}
+
+// ScanSecrets starts a secret scan of the whole history of a repository
+func ScanSecrets(ctx *context.APIContext) {
+	// swagger:operation POST /repos/{owner}/{repo}/secret_scanning/scans repository repoScanSecrets
+	// ---
+	// summary: Start a secret scan of the whole history of a repository
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the repo
+	//   type: string
+	//   required: true
+	// - name: repo
+	//   in: path
+	//   description: name of the repo
+	//   type: string
+	//   required: true
+	// responses:
+	//   "202":
+	//     "$ref": "#/responses/empty"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	if err := secretscan_service.ScanHistory(ctx.Repo.Repository, ctx.Doer); err != nil {
+		if errors.Is(err, util.ErrInvalidArgument) {
+			ctx.APIError(http.StatusUnprocessableEntity, err)
+		} else {
+			ctx.APIErrorInternal(err)
+		}
+		return
+	}
+	ctx.Status(http.StatusAccepted)
+}
@@ -98,8 +98,10 @@ func ListJobs(ctx *context.APIContext, ownerID, repoID, runID int64) {
 
 func convertToInternal(s string) ([]actions_model.Status, error) {
 	switch s {
-	case "pending", "waiting", "requested", "action_required":
+	case "pending", "requested":
 		return []actions_model.Status{actions_model.StatusBlocked}, nil
+	case "waiting", "action_required":
+		return []actions_model.Status{actions_model.StatusBlocked, actions_model.StatusPendingApproval}, nil
 	case "queued":
 		return []actions_model.Status{actions_model.StatusWaiting}, nil
 	case "in_progress":
@@ -0,0 +1,81 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package shared
+
+import (
+	"errors"
+	"net/http"
+
+	git_model "github.com/kumose/kmup/models/git"
+	api "github.com/kumose/kmup/modules/structs"
+	"github.com/kumose/kmup/modules/util"
+	"github.com/kumose/kmup/modules/web"
+	"github.com/kumose/kmup/services/context"
+	"github.com/kumose/kmup/services/convert"
+)
+
+// GetPushRule responds with the push rule of the repository, the owner or the instance if both of them are 0
+func GetPushRule(ctx *context.APIContext, ownerID, repoID int64) {
+	rule, err := git_model.GetPushRule(ctx, ownerID, repoID)
+	if errors.Is(err, util.ErrNotExist) {
+		ctx.APIErrorNotFound(err)
+		return
+	} else if err != nil {
+		ctx.APIErrorInternal(err)
+		return
+	}
+	ctx.JSON(http.StatusOK, convert.ToPushRule(rule))
+}
+
+// SetPushRule creates or replaces the push rule of the repository, the owner or the instance if both of them are 0
+func SetPushRule(ctx *context.APIContext, ownerID, repoID int64) {
+	form := web.GetForm(ctx).(*api.EditPushRuleOption)
+	rule := &git_model.PushRule{
+		OwnerID:              ownerID,
+		RepoID:               repoID,
+		MaxFileSize:          form.MaxFileSize,
+		ForbiddenPaths:       form.ForbiddenPaths,
+		ForbiddenExtensions:  form.ForbiddenExtensions,
+		CommitMessagePattern: form.CommitMessagePattern,
+		AuthorEmailDomains:   form.AuthorEmailDomains,
+		RejectMergeCommits:   form.RejectMergeCommits,
+		RejectSecrets:        form.RejectSecrets,
+	}
+	if err := git_model.SetPushRule(ctx, rule); err != nil {
+		if errors.Is(err, util.ErrInvalidArgument) {
+			ctx.APIError(http.StatusUnprocessableEntity, err)
+		} else {
+			ctx.APIErrorInternal(err)
+		}
+		return
+	}
+	GetPushRule(ctx, ownerID, repoID)
+}
+
+// DeletePushRule deletes the push rule of the repository, the owner or the instance if both of them are 0
+func DeletePushRule(ctx *context.APIContext, ownerID, repoID int64) {
+	if err := git_model.DeletePushRule(ctx, ownerID, repoID); err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			ctx.APIErrorNotFound(err)
+		} else {
+			ctx.APIErrorInternal(err)
+		}
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
@@ -239,4 +239,25 @@ type swaggerParameterBodies struct {
 
 	// in:body
 	LockIssueOption api.LockIssueOption
+
+	// in:body
+	CreateActionRunnerGroupOption api.CreateActionRunnerGroupOption
+
+	// in:body
+	EditActionRunnerGroupOption api.EditActionRunnerGroupOption
+
+	// in:body
+	RerunActionRunOption api.RerunActionRunOption
+
+	// in:body
+	EditPushRuleOption api.EditPushRuleOption
+
+	// in:body
+	EditSecretScanningAlertOption api.EditSecretScanningAlertOption
+
+	// in:body
+	CreateRulesetOption api.CreateRulesetOption
+
+	// in:body
+	EditRulesetOption api.EditRulesetOption
 }
@@ -41,3 +41,38 @@ type swaggerResponsePackageFileList struct {
 	// in:body
 	Body []api.PackageFile `json:"body"`
 }
+
+// PackageVerification
+// swagger:response PackageVerification
+type swaggerResponsePackageVerification struct {
+	// in:body
+	Body api.PackageVerification `json:"body"`
+}
+
+// PackageAttestation
+// swagger:response PackageAttestation
+type swaggerResponsePackageAttestation struct {
+	// in:body
+	Body api.PackageAttestation `json:"body"`
+}
+
+// PackageVulnerabilityList
+// swagger:response PackageVulnerabilityList
+type swaggerResponsePackageVulnerabilityList struct {
+	// in:body
+	Body []api.PackageVulnerability `json:"body"`
+}
+
+// PackageCleanupRuleList
+// swagger:response PackageCleanupRuleList
+type swaggerResponsePackageCleanupRuleList struct {
+	// in:body
+	Body []api.PackageCleanupRule `json:"body"`
+}
+
+// PackageCleanupRunList
+// swagger:response PackageCleanupRunList
+type swaggerResponsePackageCleanupRunList struct {
+	// in:body
+	Body []api.PackageCleanupRun `json:"body"`
+}
@@ -168,6 +168,13 @@ type swaggerResponsePullRequestList struct {
 	Body []api.PullRequest `json:"body"`
 }
 
+// MergeQueueEntryList
+// swagger:response MergeQueueEntryList
+type swaggerResponseMergeQueueEntryList struct {
+	// in:body
+	Body []api.MergeQueueEntry `json:"body"`
+}
+
 // PullReview
 // swagger:response PullReview
 type swaggerResponsePullReview struct {
@@ -281,6 +288,13 @@ type swaggerCommitList struct {
 	Body []api.Commit `json:"body"`
 }
 
+// MissingCodeOwnerApprovalList
+// swagger:response MissingCodeOwnerApprovalList
+type swaggerMissingCodeOwnerApprovalList struct {
+	// in: body
+	Body []api.MissingCodeOwnerApproval `json:"body"`
+}
+
 // ChangedFileList
 // swagger:response ChangedFileList
 type swaggerChangedFileList struct {
@@ -491,6 +505,20 @@ type swaggerWorkflowJob struct {
 	Body api.ActionWorkflowJob `json:"body"`
 }
 
+// ActionTaskAnnotationList
+// swagger:response ActionTaskAnnotationList
+type swaggerActionTaskAnnotationList struct {
+	// in:body
+	Body []api.ActionTaskAnnotation `json:"body"`
+}
+
+// ActionTestResultsResponse
+// swagger:response ActionTestResultsResponse
+type swaggerActionTestResultsResponse struct {
+	// in:body
+	Body api.ActionTestResultsResponse `json:"body"`
+}
+
 // ArtifactsList
 // swagger:response ArtifactsList
 type swaggerRepoArtifactsList struct {
@@ -28,7 +28,7 @@ import (
 	"github.com/kumose/kmup/services/context"
 )
 
-func DownloadActionsRunJobLogsWithIndex(ctx *context.Base, ctxRepo *repo_model.Repository, runID, jobIndex int64) error {
+func DownloadActionsRunJobLogsWithIndex(ctx *context.Base, ctxRepo *repo_model.Repository, runID, jobIndex, runAttempt int64) error {
 	runJobs, err := actions_model.GetRunJobsByRunID(ctx, runID)
 	if err != nil {
 		return fmt.Errorf("GetRunJobsByRunID: %w", err)
@@ -39,25 +39,31 @@ func DownloadActionsRunJobLogsWithIndex(ctx *context.Base, ctxRepo *repo_model.R
 	if jobIndex < 0 || jobIndex >= int64(len(runJobs)) {
 		return util.NewNotExistErrorf("job index is out of range: %d", jobIndex)
 	}
-	return DownloadActionsRunJobLogs(ctx, ctxRepo, runJobs[jobIndex])
+	return DownloadActionsRunJobLogs(ctx, ctxRepo, runJobs[jobIndex], runAttempt)
 }
 
-func DownloadActionsRunJobLogs(ctx *context.Base, ctxRepo *repo_model.Repository, curJob *actions_model.ActionRunJob) error {
+// DownloadActionsRunJobLogs serves the logs of the job in the attempt of its run, runAttempt 0 means the latest attempt
+func DownloadActionsRunJobLogs(ctx *context.Base, ctxRepo *repo_model.Repository, curJob *actions_model.ActionRunJob, runAttempt int64) error {
 	if curJob.Repo.ID != ctxRepo.ID {
 		return util.NewNotExistErrorf("job not found")
 	}
 
-	if curJob.TaskID == 0 {
-		return util.NewNotExistErrorf("job not started")
-	}
-
 	if err := curJob.LoadRun(ctx); err != nil {
 		return fmt.Errorf("LoadRun: %w", err)
 	}
 
-	task, err := actions_model.GetTaskByID(ctx, curJob.TaskID)
+	var task *actions_model.ActionTask
+	var err error
+	if runAttempt > 0 && runAttempt < curJob.Run.Attempt {
+		task, err = actions_model.GetTaskOfJobInRunAttempt(ctx, curJob.ID, runAttempt)
+	} else {
+		if curJob.TaskID == 0 {
+			return util.NewNotExistErrorf("job not started")
+		}
+		task, err = actions_model.GetTaskByID(ctx, curJob.TaskID)
+	}
 	if err != nil {
-		return fmt.Errorf("GetTaskByID: %w", err)
+		return fmt.Errorf("get task: %w", err)
 	}
 
 	if task.LogExpired {
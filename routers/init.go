@@ -46,13 +46,16 @@ import (
 	"github.com/kumose/kmup/services/mailer"
 	mailer_incoming "github.com/kumose/kmup/services/mailer/incoming"
 	markup_service "github.com/kumose/kmup/services/markup"
+	"github.com/kumose/kmup/services/mergequeue"
 	repo_migrations "github.com/kumose/kmup/services/migrations"
 	mirror_service "github.com/kumose/kmup/services/mirror"
 	"github.com/kumose/kmup/services/oauth2_provider"
+	packages_vulnerability_service "github.com/kumose/kmup/services/packages/vulnerability"
 	pull_service "github.com/kumose/kmup/services/pull"
 	release_service "github.com/kumose/kmup/services/release"
 	repo_service "github.com/kumose/kmup/services/repository"
 	"github.com/kumose/kmup/services/repository/archiver"
+	secretscan_service "github.com/kumose/kmup/services/secretscan"
 	"github.com/kumose/kmup/services/task"
 	"github.com/kumose/kmup/services/uinotification"
 	"github.com/kumose/kmup/services/webhook"
@@ -160,6 +163,11 @@ func InitWebInstalled(ctx context.Context) {
 	mustInit(webhook.Init)
 	mustInit(pull_service.Init)
 	mustInit(automerge.Init)
+	mustInit(mergequeue.Init)
+	mustInit(secretscan_service.Init)
+	if setting.Packages.Enabled {
+		mustInit(packages_vulnerability_service.Init)
+	}
 	mustInit(task.Init)
 	mustInit(repo_migrations.Init)
 	eventsource.GetManager().Init()
@@ -211,6 +219,13 @@ func NormalRoutes() *web.Router {
 		r.Mount(prefix, actions_router.ArtifactsRoutes(prefix))
 		prefix = actions_router.ArtifactV4RouteBase
 		r.Mount(prefix, actions_router.ArtifactsV4Routes(prefix))
+		prefix = actions_service.IDTokenRouteBase
+		r.Mount(prefix, actions_router.IDTokenRoutes())
+		r.Mount(actions_service.TestReportRouteBase, actions_router.TestReportRoutes())
+		if setting.Actions.CacheEnabled {
+			r.Mount(actions_service.CacheRouteBase, actions_router.CacheRoutes(actions_service.CacheRouteBase))
+			r.Mount(actions_router.CacheV2RouteBase, actions_router.CacheV2Routes(actions_service.CacheRouteBase))
+		}
 	}
 
 	r.NotFound(func(w http.ResponseWriter, req *http.Request) {
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"text/tabwriter"
 
 	actions_model "github.com/kumose/kmup/models/actions"
 	repo_model "github.com/kumose/kmup/models/repo"
@@ -30,6 +31,8 @@ import (
 	"github.com/kumose/kmup/modules/log"
 	"github.com/kumose/kmup/modules/private"
 	"github.com/kumose/kmup/modules/util"
+	"github.com/kumose/kmup/modules/web"
+	actions_service "github.com/kumose/kmup/services/actions"
 	"github.com/kumose/kmup/services/context"
 )
 
@@ -104,3 +107,145 @@ func parseScope(ctx *context.PrivateContext, scope string) (ownerID, repoID int6
 	repoID = r.ID
 	return ownerID, repoID, nil
 }
+
+func runnerGroupErrorResponse(ctx *context.PrivateContext, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, util.ErrInvalidArgument):
+		status = http.StatusBadRequest
+	case errors.Is(err, util.ErrNotExist):
+		status = http.StatusNotFound
+	case errors.Is(err, util.ErrAlreadyExist):
+		status = http.StatusConflict
+	}
+	if status == http.StatusInternalServerError {
+		log.Error("Runner group operation failed: %v", err)
+	}
+	ctx.JSON(status, private.Response{Err: err.Error(), UserMsg: err.Error()})
+}
+
+// ListActionsRunnerGroups lists the runner groups as a table
+func ListActionsRunnerGroups(ctx *context.PrivateContext) {
+	groups, err := actions_model.FindRunnerGroups(ctx)
+	if err != nil {
+		runnerGroupErrorResponse(ctx, err)
+		return
+	}
+	counts, err := actions_model.CountRunnersOfGroups(ctx)
+	if err != nil {
+		runnerGroupErrorResponse(ctx, err)
+		return
+	}
+
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ID\tName\tRunners\tOwners\tRepositories\tWorkflows")
+	for _, group := range groups {
+		owners, repos, err := group.GetAllowedNames(ctx)
+		if err != nil {
+			runnerGroupErrorResponse(ctx, err)
+			return
+		}
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%d\t%s\t%s\t%s\n", group.ID, group.Name, counts[group.ID],
+			strings.Join(owners, ","), strings.Join(repos, ","), strings.Join(group.AllowedWorkflows, ","))
+	}
+	_ = w.Flush()
+	ctx.PlainText(http.StatusOK, sb.String())
+}
+
+// CreateActionsRunnerGroup creates a runner group
+func CreateActionsRunnerGroup(ctx *context.PrivateContext) {
+	opts := web.GetForm(ctx).(*private.RunnerGroupOptions)
+	serviceOpts := &actions_service.RunnerGroupOptions{
+		Name:             opts.Name,
+		AllowedOwners:    opts.AllowedOwners,
+		AllowedRepos:     opts.AllowedRepos,
+		AllowedWorkflows: opts.AllowedWorkflows,
+	}
+	if opts.Description != nil {
+		serviceOpts.Description = *opts.Description
+	}
+	if _, err := actions_service.CreateRunnerGroup(ctx, serviceOpts); err != nil {
+		runnerGroupErrorResponse(ctx, err)
+		return
+	}
+	ctx.PlainText(http.StatusOK, "success")
+}
+
+// UpdateActionsRunnerGroup updates the given fields of a runner group
+func UpdateActionsRunnerGroup(ctx *context.PrivateContext) {
+	opts := web.GetForm(ctx).(*private.RunnerGroupOptions)
+	group, err := actions_model.GetRunnerGroupByName(ctx, opts.Name)
+	if err != nil {
+		runnerGroupErrorResponse(ctx, err)
+		return
+	}
+	owners, repos, err := group.GetAllowedNames(ctx)
+	if err != nil {
+		runnerGroupErrorResponse(ctx, err)
+		return
+	}
+	serviceOpts := &actions_service.RunnerGroupOptions{
+		Name:             util.IfZero(opts.NewName, group.Name),
+		Description:      group.Description,
+		AllowedOwners:    owners,
+		AllowedRepos:     repos,
+		AllowedWorkflows: group.AllowedWorkflows,
+	}
+	if opts.Description != nil {
+		serviceOpts.Description = *opts.Description
+	}
+	if opts.AllowedOwners != nil {
+		serviceOpts.AllowedOwners = opts.AllowedOwners
+	}
+	if opts.AllowedRepos != nil {
+		serviceOpts.AllowedRepos = opts.AllowedRepos
+	}
+	if opts.AllowedWorkflows != nil {
+		serviceOpts.AllowedWorkflows = opts.AllowedWorkflows
+	}
+	if err := actions_service.UpdateRunnerGroup(ctx, group, serviceOpts); err != nil {
+		runnerGroupErrorResponse(ctx, err)
+		return
+	}
+	ctx.PlainText(http.StatusOK, "success")
+}
+
+// DeleteActionsRunnerGroup deletes a runner group which has no runners
+func DeleteActionsRunnerGroup(ctx *context.PrivateContext) {
+	opts := web.GetForm(ctx).(*private.RunnerGroupOptions)
+	group, err := actions_model.GetRunnerGroupByName(ctx, opts.Name)
+	if err != nil {
+		runnerGroupErrorResponse(ctx, err)
+		return
+	}
+	if err := actions_model.DeleteRunnerGroup(ctx, group.ID); err != nil {
+		runnerGroupErrorResponse(ctx, err)
+		return
+	}
+	ctx.PlainText(http.StatusOK, "success")
+}
+
+// SetActionsRunnerGroup moves a global runner into a runner group, or out of its group
+func SetActionsRunnerGroup(ctx *context.PrivateContext) {
+	opts := web.GetForm(ctx).(*private.SetRunnerGroupOptions)
+	runner, err := actions_model.GetRunnerByID(ctx, opts.RunnerID)
+	if err != nil {
+		runnerGroupErrorResponse(ctx, err)
+		return
+	}
+	var groupID int64
+	if opts.Group != "" {
+		group, err := actions_model.GetRunnerGroupByName(ctx, opts.Group)
+		if err != nil {
+			runnerGroupErrorResponse(ctx, err)
+			return
+		}
+		groupID = group.ID
+	}
+	if err := actions_model.SetRunnerGroup(ctx, runner, groupID); err != nil {
+		runnerGroupErrorResponse(ctx, err)
+		return
+	}
+	ctx.PlainText(http.StatusOK, "success")
+}
@@ -177,7 +177,7 @@ func HookPostReceive(ctx *kmup_context.PrivateContext) {
 	}
 
 	// handle pull request merging, a pull request action should push at least 1 commit
-	if opts.PushTrigger == repo_module.PushTriggerPRMergeToBase {
+	if opts.PushTrigger == repo_module.PushTriggerPRMergeToBase || opts.PushTrigger == repo_module.PushTriggerMergeQueueToBase {
 		handlePullRequestMerging(ctx, opts, ownerName, repoName, updates)
 		if ctx.Written() {
 			return
@@ -22,12 +22,15 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 
 	asymkey_model "github.com/kumose/kmup/models/asymkey"
 	git_model "github.com/kumose/kmup/models/git"
 	issues_model "github.com/kumose/kmup/models/issues"
 	perm_model "github.com/kumose/kmup/models/perm"
 	access_model "github.com/kumose/kmup/models/perm/access"
+	pull_model "github.com/kumose/kmup/models/pull"
+	secretscan_model "github.com/kumose/kmup/models/secretscan"
 	"github.com/kumose/kmup/models/unit"
 	user_model "github.com/kumose/kmup/models/user"
 	"github.com/kumose/kmup/modules/git"
@@ -35,11 +38,14 @@ import (
 	"github.com/kumose/kmup/modules/gitrepo"
 	"github.com/kumose/kmup/modules/log"
 	"github.com/kumose/kmup/modules/private"
+	repo_module "github.com/kumose/kmup/modules/repository"
 	"github.com/kumose/kmup/modules/util"
 	"github.com/kumose/kmup/modules/web"
 	"github.com/kumose/kmup/services/agit"
 	kmup_context "github.com/kumose/kmup/services/context"
 	pull_service "github.com/kumose/kmup/services/pull"
+	pushrule_service "github.com/kumose/kmup/services/pushrule"
+	secretscan_service "github.com/kumose/kmup/services/secretscan"
 )
 
 type preReceiveContext struct {
@@ -60,6 +66,17 @@ type preReceiveContext struct {
 	protectedTags    []*git_model.ProtectedTag
 	gotProtectedTags bool
 
+	pushRules    []*git_model.PushRule
+	gotPushRules bool
+
+	rulesets    []*git_model.Ruleset
+	gotRulesets bool
+
+	secretScanSetting *secretscan_model.RepoSetting
+
+	// warnings are shown to the pusher when the push is allowed
+	warnings []string
+
 	env []string
 
 	opts *private.HookOptions
@@ -148,9 +165,24 @@ func HookPreReceive(ctx *kmup_context.PrivateContext) {
 		if ctx.Written() {
 			return
 		}
+
+		if refFullName.IsBranch() || refFullName.IsTag() {
+			preReceiveRulesets(ourCtx, oldCommitID, newCommitID, refFullName)
+			if ctx.Written() {
+				return
+			}
+			preReceivePushRules(ourCtx, newCommitID, refFullName)
+			if ctx.Written() {
+				return
+			}
+			preReceiveSecretScanning(ourCtx, newCommitID, refFullName)
+			if ctx.Written() {
+				return
+			}
+		}
 	}
 
-	ctx.PlainText(http.StatusOK, "ok")
+	ctx.JSON(http.StatusOK, private.HookPreReceiveResult{Warnings: ourCtx.warnings})
 }
 
 func preReceiveBranch(ctx *preReceiveContext, oldCommitID, newCommitID string, refFullName git.RefName) {
@@ -173,7 +205,16 @@ func preReceiveBranch(ctx *preReceiveContext, oldCommitID, newCommitID string, r
 		return
 	}
 
-	protectBranch, err := git_model.GetFirstMatchProtectedBranchRule(ctx, repo.ID, branchName)
+	// the merge group branches are managed by the merge queues, internal pushes to them don't run the hooks
+	if strings.HasPrefix(branchName, pull_model.MergeQueueBranchPrefix) {
+		log.Warn("Forbidden: Branch: %s in %-v is a merge group branch", branchName, repo)
+		ctx.JSON(http.StatusForbidden, private.Response{
+			UserMsg: fmt.Sprintf("branch %s is managed by a merge queue", branchName),
+		})
+		return
+	}
+
+	protectBranch, err := git_model.GetFirstMatchProtectedBranchRuleForUser(ctx, repo.ID, branchName, ctx.rulesetActor())
 	if err != nil {
 		log.Error("Unable to get protected branch: %s in %-v Error: %v", branchName, repo, err)
 		ctx.JSON(http.StatusInternalServerError, private.Response{
@@ -192,8 +233,11 @@ func preReceiveBranch(ctx *preReceiveContext, oldCommitID, newCommitID string, r
 	//
 	// First of all we need to enforce absolutely:
 	//
-	// 1. Detect and prevent deletion of the branch
+	// 1. Detect and prevent deletion of the branch, and its creation if an org ruleset blocks it
 	if newCommitID == objectFormat.EmptyObjectID().String() {
+		if !protectBranch.IsDeletionProtected() {
+			return
+		}
 		log.Warn("Forbidden: Branch: %s in %-v is protected from deletion", branchName, repo)
 		ctx.JSON(http.StatusForbidden, private.Response{
 			UserMsg: fmt.Sprintf("branch %s is protected from deletion", branchName),
@@ -201,23 +245,26 @@ func preReceiveBranch(ctx *preReceiveContext, oldCommitID, newCommitID string, r
 		return
 	}
 
+	if oldCommitID == objectFormat.EmptyObjectID().String() && protectBranch.IsCreationProtected() {
+		log.Warn("Forbidden: Branch: %s in %-v is protected from creation", branchName, repo)
+		ctx.JSON(http.StatusForbidden, private.Response{
+			UserMsg: fmt.Sprintf("branch %s is protected from creation", branchName),
+		})
+		return
+	}
+
 	isForcePush := false
 
 	// 2. Disallow force pushes to protected branches
 	if oldCommitID != objectFormat.EmptyObjectID().String() {
-		output, err := gitrepo.RunCmdString(ctx,
-			repo,
-			gitcmd.NewCommand("rev-list", "--max-count=1").
-				AddDynamicArguments(oldCommitID, "^"+newCommitID).
-				WithEnv(ctx.env),
-		)
+		forcePush, err := ctx.isForcePush(oldCommitID, newCommitID)
 		if err != nil {
 			log.Error("Unable to detect force push between: %s and %s in %-v Error: %v", oldCommitID, newCommitID, repo, err)
 			ctx.JSON(http.StatusInternalServerError, private.Response{
 				Err: fmt.Sprintf("Fail to detect force push: %v", err),
 			})
 			return
-		} else if len(output) > 0 {
+		} else if forcePush {
 			if protectBranch.CanForcePush {
 				isForcePush = true
 			} else {
@@ -396,7 +443,19 @@ func preReceiveBranch(ctx *preReceiveContext, oldCommitID, newCommitID string, r
 		}
 
 		// Check all status checks and reviews are ok
-		if err := pull_service.CheckPullBranchProtections(ctx, pr, true); err != nil {
+		pb, err := git_model.GetFirstMatchProtectedBranchRuleForUser(ctx, repo.ID, branchName, ctx.rulesetActor())
+		if err != nil {
+			log.Error("Unable to load the protected branch rule of branch %s in %-v: %v", branchName, repo, err)
+			ctx.JSON(http.StatusInternalServerError, private.Response{
+				Err: fmt.Sprintf("Unable to load the protected branch rule of branch %s: %v", branchName, err),
+			})
+			return
+		}
+		// a merge group of a merge queue is built on top of the base branch, so the pull request can't be outdated
+		if pb != nil && ctx.opts.PushTrigger == repo_module.PushTriggerMergeQueueToBase {
+			pb.BlockOnOutdatedBranch = false
+		}
+		if err := pull_service.CheckPullProtectedBranchRule(ctx, pr, pb, true); err != nil {
 			if errors.Is(err, pull_service.ErrNotReadyToMerge) {
 				log.Warn("Forbidden: User %d is not allowed push to protected branch %s in %-v and pr #%d is not ready to be merged: %s", ctx.opts.UserID, branchName, repo, pr.Index, err.Error())
 				ctx.JSON(http.StatusForbidden, private.Response{
@@ -449,6 +508,257 @@ func preReceiveTag(ctx *preReceiveContext, refFullName git.RefName) {
 	}
 }
 
+// preReceiveRulesets enforces the active org rulesets which apply to the tag, the ones which apply to a branch are merged into its
+// protected branch rule, and records the pushes which the rulesets in evaluate mode would have rejected
+func preReceiveRulesets(ctx *preReceiveContext, oldCommitID, newCommitID string, refFullName git.RefName) {
+	if ctx.opts.IsWiki {
+		return
+	}
+
+	repo := ctx.Repo.Repository
+	if !ctx.gotRulesets {
+		var err error
+		ctx.rulesets, err = git_model.FindRulesetsForRepo(ctx, repo)
+		if err != nil {
+			log.Error("Unable to get rulesets for %-v Error: %v", repo, err)
+			ctx.JSON(http.StatusInternalServerError, private.Response{
+				Err: err.Error(),
+			})
+			return
+		}
+		ctx.gotRulesets = true
+	}
+
+	for _, rs := range ctx.rulesets {
+		if rs.Enforcement == git_model.RulesetEnforcementActive && refFullName.IsBranch() {
+			continue
+		}
+		if !rs.MatchRef(repo, refFullName) || rs.CanUserBypass(ctx, ctx.rulesetActor()) {
+			continue
+		}
+
+		reason, err := checkRuleset(ctx, rs, oldCommitID, newCommitID, refFullName)
+		if err != nil {
+			log.Error("Unable to check ruleset %q for %s in %-v Error: %v", rs.Name, refFullName, repo, err)
+			ctx.JSON(http.StatusInternalServerError, private.Response{
+				Err: fmt.Sprintf("Unable to check ruleset %q: %v", rs.Name, err),
+			})
+			return
+		}
+		if reason == "" {
+			continue
+		}
+
+		if rs.Enforcement == git_model.RulesetEnforcementActive {
+			log.Warn("Forbidden: push to %s in %-v violates ruleset %q: %s", refFullName, repo, rs.Name, reason)
+			ctx.JSON(http.StatusForbidden, private.Response{
+				UserMsg: fmt.Sprintf("push to %s rejected by ruleset %q: %s", refFullName.ShortName(), rs.Name, reason),
+			})
+			return
+		}
+
+		log.Info("Ruleset %q would have rejected the push to %s in %-v: %s", rs.Name, refFullName, repo, reason)
+		if err := git_model.InsertRulesetEvaluation(ctx, &git_model.RulesetEvaluation{
+			RulesetID: rs.ID,
+			RepoID:    repo.ID,
+			RefName:   refFullName.String(),
+			DoerID:    ctx.opts.UserID,
+			Reason:    reason,
+		}); err != nil {
+			log.Error("Unable to record the evaluation of ruleset %q for %s in %-v Error: %v", rs.Name, refFullName, repo, err)
+		}
+	}
+}
+
+// checkRuleset returns why the ruleset rejects the push to the ref, or an empty string if it allows it
+func checkRuleset(ctx *preReceiveContext, rs *git_model.Ruleset, oldCommitID, newCommitID string, refFullName git.RefName) (string, error) {
+	emptyCommitID := ctx.Repo.GetObjectFormat().EmptyObjectID().String()
+	switch {
+	case oldCommitID == emptyCommitID:
+		if rs.BlockCreation {
+			return "creation is blocked", nil
+		}
+	case newCommitID == emptyCommitID:
+		if rs.BlockDeletion {
+			return "deletion is blocked", nil
+		}
+		return "", nil
+	case refFullName.IsTag():
+		if rs.BlockForcePush {
+			return "moving the tag is blocked", nil
+		}
+	}
+	// only the creation, the deletion and the moves of the tags can be blocked
+	if refFullName.IsTag() {
+		return "", nil
+	}
+
+	if oldCommitID != emptyCommitID && rs.BlockForcePush {
+		isForcePush, err := ctx.isForcePush(oldCommitID, newCommitID)
+		if err != nil {
+			return "", err
+		} else if isForcePush {
+			return "force push is blocked", nil
+		}
+	}
+
+	if rs.RequireSignedCommits {
+		if err := verifyCommits(oldCommitID, newCommitID, ctx.Repo.GitRepo, ctx.env); err != nil {
+			if !isErrUnverifiedCommit(err) {
+				return "", err
+			}
+			return "unverified commit " + err.(*errUnverifiedCommit).sha, nil
+		}
+	}
+
+	branchName := refFullName.BranchName()
+	pb := rs.ToProtectedBranch(ctx.Repo.Repository.ID, branchName)
+	if ctx.opts.PullRequestID != 0 {
+		pr, err := issues_model.GetPullRequestByID(ctx, ctx.opts.PullRequestID)
+		if err != nil {
+			return "", err
+		}
+		if err := pull_service.CheckPullProtectedBranchRule(ctx, pr, pb, true); err != nil {
+			if !errors.Is(err, pull_service.ErrNotReadyToMerge) {
+				return "", err
+			}
+			return fmt.Sprintf("pull request #%d is not ready to be merged: %v", pr.Index, err), nil
+		}
+		return "", nil
+	}
+
+	if globs := pb.GetProtectedFilePatterns(); len(globs) > 0 {
+		if _, err := pull_service.CheckFileProtection(ctx.Repo.GitRepo, branchName, oldCommitID, newCommitID, globs, 1, ctx.env); err != nil {
+			if !pull_service.IsErrFilePathProtected(err) {
+				return "", err
+			}
+			return "changing file " + err.(pull_service.ErrFilePathProtected).Path + " is blocked", nil
+		}
+	}
+	if rs.RequirePullRequest {
+		return "the changes must be merged through pull requests", nil
+	}
+	return "", nil
+}
+
+// preReceivePushRules rejects the pushed commits which don't follow the push rules of the repository, its owner and the instance
+func preReceivePushRules(ctx *preReceiveContext, newCommitID string, refFullName git.RefName) {
+	// the commits created by merging pull requests from the UI/API and the deletions are not checked
+	if ctx.opts.PullRequestID != 0 || ctx.opts.IsWiki || newCommitID == ctx.Repo.GetObjectFormat().EmptyObjectID().String() {
+		return
+	}
+
+	repo := ctx.Repo.Repository
+	if !ctx.gotPushRules {
+		var err error
+		ctx.pushRules, err = git_model.GetPushRulesForRepo(ctx, repo)
+		if err != nil {
+			log.Error("Unable to get push rules for %-v Error: %v", repo, err)
+			ctx.JSON(http.StatusInternalServerError, private.Response{
+				Err: err.Error(),
+			})
+			return
+		}
+		ctx.gotPushRules = true
+	}
+	if len(ctx.pushRules) == 0 {
+		return
+	}
+
+	violations, err := pushrule_service.CheckPush(ctx, repo, ctx.pushRules, newCommitID, ctx.env)
+	if err != nil {
+		log.Error("Unable to check push rules for %s in %-v Error: %v", refFullName, repo, err)
+		ctx.JSON(http.StatusInternalServerError, private.Response{
+			Err: fmt.Sprintf("Unable to check push rules: %v", err),
+		})
+		return
+	}
+	if len(violations) == 0 {
+		return
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "push to %s rejected by push rules:", refFullName.ShortName())
+	for _, v := range violations {
+		msg.WriteString("\n- " + v.String())
+	}
+	log.Warn("Forbidden: push to %s in %-v violates %d push rules", refFullName, repo, len(violations))
+	ctx.JSON(http.StatusForbidden, private.Response{
+		UserMsg: msg.String(),
+	})
+}
+
+func preReceiveSecretScanning(ctx *preReceiveContext, newCommitID string, refFullName git.RefName) {
+	// the commits created by merging pull requests from the UI/API and the deletions are not checked
+	if ctx.opts.PullRequestID != 0 || ctx.opts.IsWiki || newCommitID == ctx.Repo.GetObjectFormat().EmptyObjectID().String() {
+		return
+	}
+
+	repo := ctx.Repo.Repository
+	if ctx.secretScanSetting == nil {
+		var err error
+		ctx.secretScanSetting, err = secretscan_model.GetRepoSetting(ctx, repo.ID)
+		if err != nil {
+			log.Error("Unable to get secret scanning setting for %-v Error: %v", repo, err)
+			ctx.JSON(http.StatusInternalServerError, private.Response{
+				Err: err.Error(),
+			})
+			return
+		}
+	}
+	pushProtection := ctx.secretScanSetting.EffectivePushProtection()
+	if pushProtection == secretscan_model.PushProtectionOff {
+		return
+	}
+
+	leaks, err := secretscan_service.CheckPush(ctx, repo, newCommitID, ctx.env)
+	if err != nil {
+		log.Error("Unable to scan the push to %s in %-v for secrets Error: %v", refFullName, repo, err)
+		ctx.JSON(http.StatusInternalServerError, private.Response{
+			Err: fmt.Sprintf("Unable to scan the push for secrets: %v", err),
+		})
+		return
+	}
+	if len(leaks) == 0 {
+		return
+	}
+
+	var msg strings.Builder
+	if pushProtection == secretscan_model.PushProtectionBlock {
+		fmt.Fprintf(&msg, "push to %s rejected because it contains secrets:", refFullName.ShortName())
+	} else {
+		fmt.Fprintf(&msg, "warning: the push to %s contains secrets, they must be revoked:", refFullName.ShortName())
+	}
+	for _, leak := range leaks {
+		msg.WriteString("\n- " + leak.String())
+	}
+
+	// the leaked access tokens are revoked at once rather than after the push is scanned again by the queue
+	if !ctx.loadPusherAndPermission() {
+		return
+	}
+	revoked, err := secretscan_service.RevokeLeakedAccessTokens(ctx, repo, ctx.user, leaks, pushProtection == secretscan_model.PushProtectionWarn)
+	if err != nil {
+		log.Error("Unable to revoke the access tokens leaked by the push to %s in %-v Error: %v", refFullName, repo, err)
+		ctx.JSON(http.StatusInternalServerError, private.Response{
+			Err: fmt.Sprintf("Unable to revoke the leaked access tokens: %v", err),
+		})
+		return
+	}
+	if revoked > 0 {
+		fmt.Fprintf(&msg, "\n%d leaked access tokens have been revoked", revoked)
+	}
+
+	if pushProtection == secretscan_model.PushProtectionWarn {
+		ctx.warnings = append(ctx.warnings, msg.String())
+		return
+	}
+	log.Warn("Forbidden: push to %s in %-v contains %d secrets", refFullName, repo, len(leaks))
+	ctx.JSON(http.StatusForbidden, private.Response{
+		UserMsg: msg.String(),
+	})
+}
+
 func preReceiveFor(ctx *preReceiveContext, refFullName git.RefName) {
 	if !ctx.AssertCreatePullRequest() {
 		return
@@ -499,6 +809,29 @@ func generateGitEnv(opts *private.HookOptions) (env []string) {
 	return env
 }
 
+// rulesetActor returns the pusher to check against the bypass lists of the rulesets, the deploy keys can't bypass them.
+// The pusher must have been loaded by checking the permissions.
+func (ctx *preReceiveContext) rulesetActor() *user_model.User {
+	if ctx.opts.DeployKeyID != 0 {
+		return nil
+	}
+	return ctx.user
+}
+
+// isForcePush returns whether updating the ref from oldCommitID to newCommitID drops some commits
+func (ctx *preReceiveContext) isForcePush(oldCommitID, newCommitID string) (bool, error) {
+	output, err := gitrepo.RunCmdString(ctx,
+		ctx.Repo.Repository,
+		gitcmd.NewCommand("rev-list", "--max-count=1").
+			AddDynamicArguments(oldCommitID, "^"+newCommitID).
+			WithEnv(ctx.env),
+	)
+	if err != nil {
+		return false, err
+	}
+	return len(output) > 0, nil
+}
+
 // loadPusherAndPermission returns false if an error occurs, and it writes the error response
 func (ctx *preReceiveContext) loadPusherAndPermission() bool {
 	if ctx.loadedPusher {
@@ -96,6 +96,11 @@ func Routes() *web.Router {
 	r.Post("/mail/send", SendEmail)
 	r.Post("/restore_repo", RestoreRepo)
 	r.Post("/actions/generate_actions_runner_token", GenerateActionsRunnerToken)
+	r.Post("/actions/runner_groups/list", ListActionsRunnerGroups)
+	r.Post("/actions/runner_groups/create", bind(private.RunnerGroupOptions{}), CreateActionsRunnerGroup)
+	r.Post("/actions/runner_groups/update", bind(private.RunnerGroupOptions{}), UpdateActionsRunnerGroup)
+	r.Post("/actions/runner_groups/delete", bind(private.RunnerGroupOptions{}), DeleteActionsRunnerGroup)
+	r.Post("/actions/runner_groups/set_runner", bind(private.SetRunnerGroupOptions{}), SetActionsRunnerGroup)
 
 	r.Group("/repo", func() {
 		// FIXME: it is not right to use context.Contexter here because all routes here should use PrivateContext
@@ -16,6 +16,7 @@ import (
 	"github.com/kumose/kmup/services/context"
 	packages_service "github.com/kumose/kmup/services/packages"
 	packages_cleanup_service "github.com/kumose/kmup/services/packages/cleanup"
+	container_service "github.com/kumose/kmup/services/packages/container"
 )
 
 const (
@@ -93,6 +94,18 @@ func DeletePackageVersion(ctx *context.Context) {
 		return
 	}
 
+	p, err := packages_model.GetPackageByID(ctx, pv.PackageID)
+	if err != nil {
+		ctx.ServerError("GetPackageByID", err)
+		return
+	}
+	if p.Type == packages_model.TypeContainer {
+		if err := container_service.RemoveOrphanedReferrers(ctx, ctx.Doer, p.ID); err != nil {
+			ctx.ServerError("RemoveOrphanedReferrers", err)
+			return
+		}
+	}
+
 	ctx.Flash.Success(ctx.Tr("packages.settings.delete.success"))
 	ctx.JSONRedirect(setting.AppSubURL + "/-/admin/packages?page=" + url.QueryEscape(ctx.FormString("page")) + "&q=" + url.QueryEscape(ctx.FormString("q")) + "&type=" + url.QueryEscape(ctx.FormString("type")))
 }
@@ -0,0 +1,195 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	actions_model "github.com/kumose/kmup/models/actions"
+	"github.com/kumose/kmup/modules/setting"
+	"github.com/kumose/kmup/modules/templates"
+	"github.com/kumose/kmup/modules/util"
+	"github.com/kumose/kmup/modules/web"
+	actions_service "github.com/kumose/kmup/services/actions"
+	"github.com/kumose/kmup/services/context"
+	"github.com/kumose/kmup/services/forms"
+)
+
+const (
+	tplRunnerGroups    templates.TplName = "admin/actions"
+	tplRunnerGroupEdit templates.TplName = "admin/runners/group_edit"
+)
+
+// runnerGroupItem is a runner group with the names of its allowed owners and repositories for rendering
+type runnerGroupItem struct {
+	*actions_model.ActionRunnerGroup
+	Owners       []string
+	Repos        []string
+	RunnersCount int64
+}
+
+func runnerGroupsLink() string {
+	return setting.AppSubURL + "/-/admin/actions/runner_groups"
+}
+
+func splitLines(s string) []string {
+	var ret []string
+	for line := range strings.SplitSeq(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			ret = append(ret, line)
+		}
+	}
+	return ret
+}
+
+func runnerGroupOptionsFromForm(form *forms.RunnerGroupForm) *actions_service.RunnerGroupOptions {
+	return &actions_service.RunnerGroupOptions{
+		Name:             form.Name,
+		Description:      form.Description,
+		AllowedOwners:    splitLines(form.AllowedOwners),
+		AllowedRepos:     splitLines(form.AllowedRepos),
+		AllowedWorkflows: splitLines(form.AllowedWorkflows),
+	}
+}
+
+// RunnerGroups lists the runner groups
+func RunnerGroups(ctx *context.Context) {
+	ctx.Data["Title"] = ctx.Tr("actions.runners.groups")
+	ctx.Data["PageType"] = "runner_groups"
+	ctx.Data["PageIsAdminRunnerGroups"] = true
+
+	groups, err := actions_model.FindRunnerGroups(ctx)
+	if err != nil {
+		ctx.ServerError("FindRunnerGroups", err)
+		return
+	}
+	counts, err := actions_model.CountRunnersOfGroups(ctx)
+	if err != nil {
+		ctx.ServerError("CountRunnersOfGroups", err)
+		return
+	}
+	items := make([]*runnerGroupItem, 0, len(groups))
+	for _, group := range groups {
+		owners, repos, err := group.GetAllowedNames(ctx)
+		if err != nil {
+			ctx.ServerError("GetAllowedNames", err)
+			return
+		}
+		items = append(items, &runnerGroupItem{ActionRunnerGroup: group, Owners: owners, Repos: repos, RunnersCount: counts[group.ID]})
+	}
+	ctx.Data["RunnerGroups"] = items
+	ctx.HTML(http.StatusOK, tplRunnerGroups)
+}
+
+// RunnerGroupCreate creates a runner group
+func RunnerGroupCreate(ctx *context.Context) {
+	if ctx.HasError() { // form binding validation error
+		ctx.JSONError(ctx.GetErrMsg())
+		return
+	}
+
+	form := web.GetForm(ctx).(*forms.RunnerGroupForm)
+	group, err := actions_service.CreateRunnerGroup(ctx, runnerGroupOptionsFromForm(form))
+	if errors.Is(err, util.ErrInvalidArgument) || errors.Is(err, util.ErrAlreadyExist) {
+		ctx.JSONError(ctx.Tr("actions.runners.groups.failed", err.Error()))
+		return
+	} else if err != nil {
+		ctx.ServerError("CreateRunnerGroup", err)
+		return
+	}
+
+	ctx.Flash.Success(ctx.Tr("actions.runners.groups.creation.success", group.Name))
+	ctx.JSONRedirect(runnerGroupsLink())
+}
+
+func getRunnerGroup(ctx *context.Context) *actions_model.ActionRunnerGroup {
+	group, err := actions_model.GetRunnerGroupByID(ctx, ctx.PathParamInt64("group_id"))
+	if errors.Is(err, util.ErrNotExist) {
+		ctx.NotFound(err)
+		return nil
+	} else if err != nil {
+		ctx.ServerError("GetRunnerGroupByID", err)
+		return nil
+	}
+	return group
+}
+
+// RunnerGroupEdit renders the page to edit a runner group
+func RunnerGroupEdit(ctx *context.Context) {
+	ctx.Data["Title"] = ctx.Tr("actions.runners.groups.edit")
+	ctx.Data["PageIsAdminRunnerGroups"] = true
+
+	group := getRunnerGroup(ctx)
+	if ctx.Written() {
+		return
+	}
+	owners, repos, err := group.GetAllowedNames(ctx)
+	if err != nil {
+		ctx.ServerError("GetAllowedNames", err)
+		return
+	}
+	ctx.Data["RunnerGroup"] = group
+	ctx.Data["AllowedOwners"] = strings.Join(owners, "\n")
+	ctx.Data["AllowedRepos"] = strings.Join(repos, "\n")
+	ctx.Data["AllowedWorkflows"] = strings.Join(group.AllowedWorkflows, "\n")
+	ctx.HTML(http.StatusOK, tplRunnerGroupEdit)
+}
+
+// RunnerGroupEditPost updates a runner group
+func RunnerGroupEditPost(ctx *context.Context) {
+	group := getRunnerGroup(ctx)
+	if ctx.Written() {
+		return
+	}
+	if ctx.HasError() { // form binding validation error
+		ctx.JSONError(ctx.GetErrMsg())
+		return
+	}
+
+	form := web.GetForm(ctx).(*forms.RunnerGroupForm)
+	err := actions_service.UpdateRunnerGroup(ctx, group, runnerGroupOptionsFromForm(form))
+	if errors.Is(err, util.ErrInvalidArgument) || errors.Is(err, util.ErrAlreadyExist) {
+		ctx.JSONError(ctx.Tr("actions.runners.groups.failed", err.Error()))
+		return
+	} else if err != nil {
+		ctx.ServerError("UpdateRunnerGroup", err)
+		return
+	}
+
+	ctx.Flash.Success(ctx.Tr("actions.runners.groups.update.success", group.Name))
+	ctx.JSONRedirect(runnerGroupsLink())
+}
+
+// RunnerGroupDelete deletes a runner group which has no runners
+func RunnerGroupDelete(ctx *context.Context) {
+	err := actions_model.DeleteRunnerGroup(ctx, ctx.PathParamInt64("group_id"))
+	if errors.Is(err, util.ErrNotExist) {
+		ctx.NotFound(err)
+		return
+	} else if errors.Is(err, util.ErrInvalidArgument) {
+		ctx.Flash.Error(ctx.Tr("actions.runners.groups.deletion.failed", err.Error()))
+	} else if err != nil {
+		ctx.ServerError("DeleteRunnerGroup", err)
+		return
+	} else {
+		ctx.Flash.Success(ctx.Tr("actions.runners.groups.deletion.success"))
+	}
+	ctx.JSONRedirect(runnerGroupsLink())
+}
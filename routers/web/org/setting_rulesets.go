@@ -0,0 +1,235 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package org
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/kumose/kmup/models/db"
+	git_model "github.com/kumose/kmup/models/git"
+	"github.com/kumose/kmup/modules/base"
+	"github.com/kumose/kmup/modules/templates"
+	"github.com/kumose/kmup/modules/util"
+	"github.com/kumose/kmup/modules/web"
+	shared_user "github.com/kumose/kmup/routers/web/shared/user"
+	"github.com/kumose/kmup/services/context"
+	"github.com/kumose/kmup/services/forms"
+)
+
+const (
+	tplSettingsRulesets    templates.TplName = "org/settings/rulesets"
+	tplSettingsRulesetEdit templates.TplName = "org/settings/rulesets_edit"
+)
+
+// Rulesets shows the rulesets of the organization
+func Rulesets(ctx *context.Context) {
+	ctx.Data["Title"] = ctx.Tr("org.settings.rulesets")
+	ctx.Data["PageIsOrgSettings"] = true
+	ctx.Data["PageIsSettingsRulesets"] = true
+
+	if _, err := shared_user.RenderUserOrgHeader(ctx); err != nil {
+		ctx.ServerError("RenderUserOrgHeader", err)
+		return
+	}
+
+	rulesets, err := db.Find[git_model.Ruleset](ctx, git_model.FindRulesetsOptions{OwnerID: ctx.Org.Organization.ID})
+	if err != nil {
+		ctx.ServerError("FindRulesets", err)
+		return
+	}
+	ctx.Data["Rulesets"] = rulesets
+
+	ctx.HTML(http.StatusOK, tplSettingsRulesets)
+}
+
+func prepareRulesetEdit(ctx *context.Context) bool {
+	ctx.Data["Title"] = ctx.Tr("org.settings.rulesets")
+	ctx.Data["PageIsOrgSettings"] = true
+	ctx.Data["PageIsSettingsRulesets"] = true
+	ctx.Data["Enforcements"] = git_model.RulesetEnforcements
+
+	if _, err := shared_user.RenderUserOrgHeader(ctx); err != nil {
+		ctx.ServerError("RenderUserOrgHeader", err)
+		return false
+	}
+
+	users, _, err := ctx.Org.Organization.GetMembers(ctx, ctx.Doer)
+	if err != nil {
+		ctx.ServerError("GetMembers", err)
+		return false
+	}
+	ctx.Data["Users"] = users
+
+	teams, err := ctx.Org.Organization.LoadTeams(ctx)
+	if err != nil {
+		ctx.ServerError("LoadTeams", err)
+		return false
+	}
+	ctx.Data["Teams"] = teams
+	return true
+}
+
+func getRulesetByParams(ctx *context.Context) *git_model.Ruleset {
+	rs, err := git_model.GetRulesetByID(ctx, ctx.Org.Organization.ID, ctx.PathParamInt64("id"))
+	if errors.Is(err, util.ErrNotExist) {
+		ctx.NotFound(err)
+		return nil
+	} else if err != nil {
+		ctx.ServerError("GetRulesetByID", err)
+		return nil
+	}
+	return rs
+}
+
+func setRulesetData(ctx *context.Context, rs *git_model.Ruleset) {
+	ctx.Data["Ruleset"] = rs
+	ctx.Data["bypass_users"] = strings.Join(base.Int64sToStrings(rs.BypassUserIDs), ",")
+	ctx.Data["bypass_teams"] = strings.Join(base.Int64sToStrings(rs.BypassTeamIDs), ",")
+}
+
+func splitRulesetList(s, sep string) []string {
+	var list []string
+	for item := range strings.SplitSeq(s, sep) {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func applyRulesetForm(rs *git_model.Ruleset, form *forms.RulesetForm) {
+	rs.Name = form.Name
+	rs.Enforcement = git_model.RulesetEnforcement(form.Enforcement)
+	rs.RepoNamePatterns = splitRulesetList(form.RepoNamePatterns, "\n")
+	rs.RepoTopics = splitRulesetList(form.RepoTopics, ",")
+	rs.RefPatterns = splitRulesetList(form.RefPatterns, "\n")
+	rs.ExcludeRefPatterns = splitRulesetList(form.ExcludeRefPatterns, "\n")
+	rs.BlockCreation = form.BlockCreation
+	rs.BlockDeletion = form.BlockDeletion
+	rs.BlockForcePush = form.BlockForcePush
+	rs.RequirePullRequest = form.RequirePullRequest
+	rs.RequiredApprovals = form.RequiredApprovals
+	rs.EnableStatusCheck = form.EnableStatusCheck
+	rs.StatusCheckContexts = splitRulesetList(form.StatusCheckContexts, "\n")
+	rs.RequireSignedCommits = form.RequireSignedCommits
+	rs.ProtectedFilePatterns = form.ProtectedFilePatterns
+	rs.BypassUserIDs, _ = base.StringsToInt64s(splitRulesetList(form.BypassUsers, ","))
+	rs.BypassTeamIDs, _ = base.StringsToInt64s(splitRulesetList(form.BypassTeams, ","))
+}
+
+// saveRuleset creates or updates the ruleset from the submitted form, it renders the form again if the ruleset is invalid
+func saveRuleset(ctx *context.Context, rs *git_model.Ruleset) {
+	form := web.GetForm(ctx).(*forms.RulesetForm)
+	applyRulesetForm(rs, form)
+	setRulesetData(ctx, rs)
+
+	if ctx.HasError() {
+		ctx.HTML(http.StatusOK, tplSettingsRulesetEdit)
+		return
+	}
+
+	var err error
+	if rs.ID == 0 {
+		err = git_model.CreateRuleset(ctx, rs)
+	} else {
+		err = git_model.UpdateRuleset(ctx, rs)
+	}
+	if errors.Is(err, util.ErrInvalidArgument) || errors.Is(err, util.ErrAlreadyExist) {
+		ctx.RenderWithErr(ctx.Tr("org.settings.rulesets.update_failed", err.Error()), tplSettingsRulesetEdit, nil)
+		return
+	} else if err != nil {
+		ctx.ServerError("SaveRuleset", err)
+		return
+	}
+
+	ctx.Flash.Success(ctx.Tr("org.settings.rulesets.update_success", rs.Name))
+	ctx.Redirect(ctx.Org.OrgLink + "/settings/rulesets")
+}
+
+// RulesetNew shows the form to create a ruleset
+func RulesetNew(ctx *context.Context) {
+	if !prepareRulesetEdit(ctx) {
+		return
+	}
+	setRulesetData(ctx, &git_model.Ruleset{
+		Enforcement: git_model.RulesetEnforcementActive,
+		RefPatterns: []string{git_model.RulesetDefaultBranchPattern},
+	})
+	ctx.HTML(http.StatusOK, tplSettingsRulesetEdit)
+}
+
+// RulesetNewPost creates a ruleset
+func RulesetNewPost(ctx *context.Context) {
+	if !prepareRulesetEdit(ctx) {
+		return
+	}
+	saveRuleset(ctx, &git_model.Ruleset{OwnerID: ctx.Org.Organization.ID})
+}
+
+// RulesetEdit shows the form to edit a ruleset and the pushes it would have rejected
+func RulesetEdit(ctx *context.Context) {
+	if !prepareRulesetEdit(ctx) {
+		return
+	}
+	rs := getRulesetByParams(ctx)
+	if ctx.Written() {
+		return
+	}
+	setRulesetData(ctx, rs)
+
+	evaluations, err := db.Find[git_model.RulesetEvaluation](ctx, git_model.FindRulesetEvaluationsOptions{
+		ListOptions: db.ListOptions{PageSize: 20},
+		RulesetID:   rs.ID,
+	})
+	if err != nil {
+		ctx.ServerError("FindRulesetEvaluations", err)
+		return
+	}
+	if err := git_model.RulesetEvaluationList(evaluations).LoadAttributes(ctx); err != nil {
+		ctx.ServerError("LoadAttributes", err)
+		return
+	}
+	ctx.Data["Evaluations"] = evaluations
+
+	ctx.HTML(http.StatusOK, tplSettingsRulesetEdit)
+}
+
+// RulesetEditPost updates a ruleset
+func RulesetEditPost(ctx *context.Context) {
+	if !prepareRulesetEdit(ctx) {
+		return
+	}
+	rs := getRulesetByParams(ctx)
+	if ctx.Written() {
+		return
+	}
+	saveRuleset(ctx, rs)
+}
+
+// RulesetDelete deletes a ruleset
+func RulesetDelete(ctx *context.Context) {
+	if err := git_model.DeleteRuleset(ctx, ctx.Org.Organization.ID, ctx.PathParamInt64("id")); err != nil && !errors.Is(err, util.ErrNotExist) {
+		ctx.ServerError("DeleteRuleset", err)
+		return
+	}
+
+	ctx.Flash.Success(ctx.Tr("org.settings.rulesets.deletion_success"))
+	ctx.JSONRedirect(ctx.Org.OrgLink + "/settings/rulesets")
+}
@@ -33,6 +33,7 @@ import (
 	"github.com/kumose/kmup/modules/actions"
 	"github.com/kumose/kmup/modules/container"
 	"github.com/kumose/kmup/modules/git"
+	actions_indexer "github.com/kumose/kmup/modules/indexer/actions"
 	"github.com/kumose/kmup/modules/log"
 	"github.com/kumose/kmup/modules/optional"
 	"github.com/kumose/kmup/modules/setting"
@@ -81,6 +82,7 @@ func MustEnableActions(ctx *context.Context) {
 func List(ctx *context.Context) {
 	ctx.Data["Title"] = ctx.Tr("actions.actions")
 	ctx.Data["PageIsActions"] = true
+	ctx.Data["IsActionsLogIndexerEnabled"] = actions_indexer.IsEnabled()
 
 	commit, err := ctx.Repo.GitRepo.GetBranchCommit(ctx.Repo.Repository.DefaultBranch)
 	if errors.Is(err, util.ErrNotExist) {
@@ -0,0 +1,105 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"errors"
+	"net/http"
+
+	actions_model "github.com/kumose/kmup/models/actions"
+	"github.com/kumose/kmup/models/db"
+	"github.com/kumose/kmup/models/unit"
+	"github.com/kumose/kmup/modules/templates"
+	"github.com/kumose/kmup/modules/util"
+	"github.com/kumose/kmup/services/context"
+	"github.com/kumose/kmup/services/convert"
+)
+
+const tplEnvironments templates.TplName = "repo/actions/environments"
+
+// Environments lists the deployment environments of the repository and their deployment history
+func Environments(ctx *context.Context) {
+	renderEnvironments(ctx, nil)
+}
+
+// EnvironmentDeployments lists the deployment history of one environment
+func EnvironmentDeployments(ctx *context.Context) {
+	env, err := actions_model.GetEnvironmentByRepoIDAndID(ctx, ctx.Repo.Repository.ID, ctx.PathParamInt64("environment_id"))
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			ctx.NotFound(nil)
+		} else {
+			ctx.ServerError("GetEnvironmentByRepoIDAndID", err)
+		}
+		return
+	}
+	renderEnvironments(ctx, env)
+}
+
+func renderEnvironments(ctx *context.Context, env *actions_model.ActionEnvironment) {
+	ctx.Data["Title"] = ctx.Tr("actions.environments")
+	ctx.Data["PageIsActions"] = true
+	ctx.Data["PageIsActionsEnvironments"] = true
+	ctx.Data["CurEnvironment"] = env
+
+	envs, err := db.Find[actions_model.ActionEnvironment](ctx, actions_model.FindEnvironmentsOptions{
+		RepoID: ctx.Repo.Repository.ID,
+	})
+	if err != nil {
+		ctx.ServerError("FindEnvironments", err)
+		return
+	}
+	ctx.Data["Environments"] = envs
+
+	page := ctx.FormInt("page")
+	if page <= 0 {
+		page = 1
+	}
+	opts := actions_model.FindDeploymentsOptions{
+		ListOptions: db.ListOptions{
+			Page:     page,
+			PageSize: convert.ToCorrectPageSize(ctx.FormInt("limit")),
+		},
+		RepoID: ctx.Repo.Repository.ID,
+	}
+	if env != nil {
+		opts.EnvironmentID = env.ID
+	}
+	deployments, total, err := db.FindAndCount[actions_model.ActionDeployment](ctx, opts)
+	if err != nil {
+		ctx.ServerError("FindAndCount", err)
+		return
+	}
+	if err := actions_model.DeploymentList(deployments).LoadAttributes(ctx); err != nil {
+		ctx.ServerError("LoadAttributes", err)
+		return
+	}
+	for _, d := range deployments {
+		if d.Run != nil {
+			d.Run.Repo = ctx.Repo.Repository
+		}
+	}
+	ctx.Data["Deployments"] = deployments
+	ctx.Data["CanWriteRepoUnitActions"] = ctx.Repo.CanWrite(unit.TypeActions)
+
+	pager := context.NewPagination(int(total), opts.PageSize, opts.Page, 5)
+	pager.AddParamFromRequest(ctx.Req)
+	ctx.Data["Page"] = pager
+
+	ctx.HTML(http.StatusOK, tplEnvironments)
+}
@@ -0,0 +1,47 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"net/http"
+
+	actions_indexer "github.com/kumose/kmup/modules/indexer/actions"
+	"github.com/kumose/kmup/modules/templates"
+	shared_actions "github.com/kumose/kmup/routers/web/shared/actions"
+	"github.com/kumose/kmup/services/context"
+)
+
+const tplLogsSearch templates.TplName = "repo/actions/logs_search"
+
+// LogsSearch renders the page to search the actions logs of the repository
+func LogsSearch(ctx *context.Context) {
+	if !actions_indexer.IsEnabled() {
+		ctx.NotFound(nil)
+		return
+	}
+
+	ctx.Data["Title"] = ctx.Tr("actions.logs_search")
+	ctx.Data["PageIsActions"] = true
+
+	shared_actions.SearchLogs(ctx, []int64{ctx.Repo.Repository.ID})
+	if ctx.Written() {
+		return
+	}
+
+	ctx.HTML(http.StatusOK, tplLogsSearch)
+}
@@ -49,8 +49,6 @@ import (
 	notify_service "github.com/kumose/kmup/services/notify"
 
 	"github.com/nektos/act/pkg/model"
-	"gopkg.in/yaml.v3"
-	"xorm.io/builder"
 )
 
 func getRunIndex(ctx *context_module.Context) int64 {
@@ -69,6 +67,7 @@ func View(ctx *context_module.Context) {
 	jobIndex := ctx.PathParamInt64("job")
 	ctx.Data["RunIndex"] = runIndex
 	ctx.Data["JobIndex"] = jobIndex
+	ctx.Data["RunAttempt"] = ctx.FormInt64("attempt")
 	ctx.Data["ActionsURL"] = ctx.Repo.RepoLink + "/actions"
 
 	if getRunJobs(ctx, runIndex, jobIndex); ctx.Written() {
@@ -87,6 +86,24 @@ func ViewWorkflowFile(ctx *context_module.Context) {
 		}, err)
 		return
 	}
+	if run.RequiredWorkflowID > 0 {
+		// the workflow file of a required workflow is in the central repository
+		rw, exist, err := db.GetByID[actions_model.ActionRequiredWorkflow](ctx, run.RequiredWorkflowID)
+		if err != nil {
+			ctx.ServerError("GetRequiredWorkflowByID", err)
+			return
+		} else if !exist {
+			ctx.NotFound(nil)
+			return
+		}
+		if err := rw.LoadRepo(ctx); err != nil {
+			ctx.NotFoundOrServerError("LoadRepo", repo_model.IsErrRepoNotExist, err)
+			return
+		}
+		ref := util.IfZero(rw.Ref, rw.Repo.DefaultBranch)
+		ctx.Redirect(fmt.Sprintf("%s/src/branch/%s/%s", rw.Repo.Link(), util.PathEscapeSegments(ref), util.PathEscapeSegments(rw.WorkflowPath)))
+		return
+	}
 	commit, err := ctx.Repo.GitRepo.GetCommit(run.CommitSHA)
 	if err != nil {
 		ctx.NotFoundOrServerError("GetCommit", func(err error) bool {
@@ -116,6 +133,7 @@ type LogCursor struct {
 
 type ViewRequest struct {
 	LogCursors []LogCursor `json:"logCursors"`
+	Attempt    int64       `json:"attempt"` // the attempt of the run to view, 0 means the latest attempt
 }
 
 type ArtifactsViewItem struct {
@@ -129,25 +147,33 @@ type ViewResponse struct {
 
 	State struct {
 		Run struct {
-			Link              string        `json:"link"`
-			Title             string        `json:"title"`
-			TitleHTML         template.HTML `json:"titleHTML"`
-			Status            string        `json:"status"`
-			CanCancel         bool          `json:"canCancel"`
-			CanApprove        bool          `json:"canApprove"` // the run needs an approval and the doer has permission to approve
-			CanRerun          bool          `json:"canRerun"`
-			CanDeleteArtifact bool          `json:"canDeleteArtifact"`
-			Done              bool          `json:"done"`
-			WorkflowID        string        `json:"workflowID"`
-			WorkflowLink      string        `json:"workflowLink"`
-			IsSchedule        bool          `json:"isSchedule"`
-			Jobs              []*ViewJob    `json:"jobs"`
-			Commit            ViewCommit    `json:"commit"`
+			Link              string           `json:"link"`
+			Title             string           `json:"title"`
+			TitleHTML         template.HTML    `json:"titleHTML"`
+			Status            string           `json:"status"`
+			CanCancel         bool             `json:"canCancel"`
+			CanApprove        bool             `json:"canApprove"` // the run needs an approval and the doer has permission to approve
+			CanRerun          bool             `json:"canRerun"`
+			CanRerunFailed    bool             `json:"canRerunFailed"`
+			CanDeleteArtifact bool             `json:"canDeleteArtifact"`
+			Done              bool             `json:"done"`
+			WorkflowID        string           `json:"workflowID"`
+			WorkflowLink      string           `json:"workflowLink"`
+			IsSchedule        bool             `json:"isSchedule"`
+			Attempt           int64            `json:"attempt"`
+			ViewingAttempt    int64            `json:"viewingAttempt"`
+			Jobs              []*ViewJob       `json:"jobs"`
+			Commit            ViewCommit       `json:"commit"`
+			TestSummary       *ViewTestSummary `json:"testSummary"`
 		} `json:"run"`
 		CurrentJob struct {
-			Title  string         `json:"title"`
-			Detail string         `json:"detail"`
-			Steps  []*ViewJobStep `json:"steps"`
+			Title               string               `json:"title"`
+			Detail              string               `json:"detail"`
+			Steps               []*ViewJobStep       `json:"steps"`
+			CanReviewDeployment bool                 `json:"canReviewDeployment"`
+			Annotations         []*ViewJobAnnotation `json:"annotations"`
+			TestSummary         *ViewTestSummary     `json:"testSummary"`
+			TestFailures        []*ViewTestCase      `json:"testFailures"`
 		} `json:"currentJob"`
 	} `json:"state"`
 	Logs struct {
@@ -187,6 +213,31 @@ type ViewJobStep struct {
 	Status   string `json:"status"`
 }
 
+type ViewJobAnnotation struct {
+	Step      string `json:"step"`
+	Level     string `json:"level"`
+	Title     string `json:"title"`
+	Message   string `json:"message"`
+	Path      string `json:"path"`
+	StartLine int    `json:"startLine"`
+	EndLine   int    `json:"endLine"`
+	Link      string `json:"link"`
+}
+
+type ViewTestSummary struct {
+	Total   int64 `json:"total"`
+	Passed  int64 `json:"passed"`
+	Failed  int64 `json:"failed"`
+	Skipped int64 `json:"skipped"`
+}
+
+type ViewTestCase struct {
+	Name    string `json:"name"`
+	Suite   string `json:"suite"`
+	Message string `json:"message"`
+	Flaky   bool   `json:"flaky"`
+}
+
 type ViewStepLog struct {
 	Step    int                `json:"step"`
 	Cursor  int64              `json:"cursor"`
@@ -200,12 +251,8 @@ type ViewStepLogLine struct {
 	Timestamp float64 `json:"timestamp"`
 }
 
-func getActionsViewArtifacts(ctx context.Context, repoID, runIndex int64) (artifactsViewItems []*ArtifactsViewItem, err error) {
-	run, err := actions_model.GetRunByIndex(ctx, repoID, runIndex)
-	if err != nil {
-		return nil, err
-	}
-	artifacts, err := actions_model.ListUploadedArtifactsMeta(ctx, run.ID)
+func getActionsViewArtifacts(ctx context.Context, run *actions_model.ActionRun, runAttempt int64) (artifactsViewItems []*ArtifactsViewItem, err error) {
+	artifacts, err := actions_model.ListUploadedArtifactsMeta(ctx, run.ID, runAttempt)
 	if err != nil {
 		return nil, err
 	}
@@ -234,14 +281,19 @@ func ViewPost(ctx *context_module.Context) {
 		return
 	}
 
+	// the previous attempts of the run are read-only, their jobs are shown with the tasks of the attempt
+	attempt := run.Attempt
+	if req.Attempt > 0 && req.Attempt < run.Attempt {
+		attempt = req.Attempt
+	}
+	isLatestAttempt := attempt == run.Attempt
+
 	var err error
 	resp := &ViewResponse{}
-	resp.Artifacts, err = getActionsViewArtifacts(ctx, ctx.Repo.Repository.ID, runIndex)
+	resp.Artifacts, err = getActionsViewArtifacts(ctx, run, attempt)
 	if err != nil {
-		if !errors.Is(err, util.ErrNotExist) {
-			ctx.ServerError("getActionsViewArtifacts", err)
-			return
-		}
+		ctx.ServerError("getActionsViewArtifacts", err)
+		return
 	}
 
 	// the title for the "run" is from the commit message
@@ -250,22 +302,38 @@ func ViewPost(ctx *context_module.Context) {
 	resp.State.Run.Link = run.Link()
 	resp.State.Run.CanCancel = !run.Status.IsDone() && ctx.Repo.CanWrite(unit.TypeActions)
 	resp.State.Run.CanApprove = run.NeedApproval && ctx.Repo.CanWrite(unit.TypeActions)
-	resp.State.Run.CanRerun = run.Status.IsDone() && ctx.Repo.CanWrite(unit.TypeActions)
-	resp.State.Run.CanDeleteArtifact = run.Status.IsDone() && ctx.Repo.CanWrite(unit.TypeActions)
+	resp.State.Run.CanRerun = run.Status.IsDone() && isLatestAttempt && ctx.Repo.CanWrite(unit.TypeActions)
+	resp.State.Run.CanRerunFailed = resp.State.Run.CanRerun && len(actions_service.GetFailedRerunJobs(jobs)) > 0
+	resp.State.Run.CanDeleteArtifact = run.Status.IsDone() && isLatestAttempt && ctx.Repo.CanWrite(unit.TypeActions)
 	resp.State.Run.Done = run.Status.IsDone()
 	resp.State.Run.WorkflowID = run.WorkflowID
 	resp.State.Run.WorkflowLink = run.WorkflowLink()
 	resp.State.Run.IsSchedule = run.IsSchedule()
+	resp.State.Run.Attempt = run.Attempt
+	resp.State.Run.ViewingAttempt = attempt
 	resp.State.Run.Jobs = make([]*ViewJob, 0, len(jobs)) // marshal to '[]' instead fo 'null' in json
 	resp.State.Run.Status = run.Status.String()
 	for _, v := range jobs {
-		resp.State.Run.Jobs = append(resp.State.Run.Jobs, &ViewJob{
+		viewJob := &ViewJob{
 			ID:       v.ID,
 			Name:     v.Name,
 			Status:   v.Status.String(),
 			CanRerun: resp.State.Run.CanRerun,
 			Duration: v.Duration().String(),
-		})
+		}
+		if !isLatestAttempt {
+			task, err := getTaskOfJobInAttempt(ctx, v, attempt)
+			if err != nil {
+				ctx.ServerError("getTaskOfJobInAttempt", err)
+				return
+			}
+			// the job did not run in the attempt
+			viewJob.Status, viewJob.Duration = actions_model.StatusUnknown.String(), ""
+			if task != nil {
+				viewJob.Status, viewJob.Duration = task.Status.String(), task.Duration().String()
+			}
+		}
+		resp.State.Run.Jobs = append(resp.State.Run.Jobs, viewJob)
 	}
 
 	pusher := ViewUser{
@@ -293,14 +361,19 @@ func ViewPost(ctx *context_module.Context) {
 		Branch:   branch,
 	}
 
-	var task *actions_model.ActionTask
-	if current.TaskID > 0 {
-		var err error
-		task, err = actions_model.GetTaskByID(ctx, current.TaskID)
-		if err != nil {
-			ctx.ServerError("actions_model.GetTaskByID", err)
-			return
-		}
+	runTestSummary, err := actions_model.GetRunTestSummary(ctx, run.ID)
+	if err != nil {
+		ctx.ServerError("GetRunTestSummary", err)
+		return
+	}
+	resp.State.Run.TestSummary = toViewTestSummary(runTestSummary)
+
+	task, err := getTaskOfJobInAttempt(ctx, current, util.Iif(isLatestAttempt, 0, attempt))
+	if err != nil {
+		ctx.ServerError("getTaskOfJobInAttempt", err)
+		return
+	}
+	if task != nil {
 		task.Job = current
 		if err := task.LoadAttributes(ctx); err != nil {
 			ctx.ServerError("task.LoadAttributes", err)
@@ -310,11 +383,25 @@ func ViewPost(ctx *context_module.Context) {
 
 	resp.State.CurrentJob.Title = current.Name
 	resp.State.CurrentJob.Detail = current.Status.LocaleString(ctx.Locale)
+	if !isLatestAttempt {
+		resp.State.CurrentJob.Detail = util.Iif(task != nil, task.Status, actions_model.StatusUnknown).LocaleString(ctx.Locale)
+	}
 	if run.NeedApproval {
 		resp.State.CurrentJob.Detail = ctx.Locale.TrString("actions.need_approval_desc")
 	}
+	if current.Status.IsPendingApproval() && current.EnvironmentID > 0 {
+		env, err := actions_model.GetEnvironmentByRepoIDAndID(ctx, current.RepoID, current.EnvironmentID)
+		if err != nil {
+			ctx.ServerError("GetEnvironmentByRepoIDAndID", err)
+			return
+		}
+		resp.State.CurrentJob.Detail = ctx.Locale.TrString("actions.environments.deployment.waiting_desc", env.Name)
+		resp.State.CurrentJob.CanReviewDeployment = ctx.Doer != nil && env.IsReviewer(ctx.Doer.ID) && ctx.Repo.CanWrite(unit.TypeActions)
+	}
 	resp.State.CurrentJob.Steps = make([]*ViewJobStep, 0) // marshal to '[]' instead fo 'null' in json
 	resp.Logs.StepsLog = make([]*ViewStepLog, 0)          // marshal to '[]' instead fo 'null' in json
+	resp.State.CurrentJob.Annotations = make([]*ViewJobAnnotation, 0)
+	resp.State.CurrentJob.TestFailures = make([]*ViewTestCase, 0)
 	if task != nil {
 		steps, logs, err := convertToViewModel(ctx, req.LogCursors, task)
 		if err != nil {
@@ -323,11 +410,129 @@ func ViewPost(ctx *context_module.Context) {
 		}
 		resp.State.CurrentJob.Steps = append(resp.State.CurrentJob.Steps, steps...)
 		resp.Logs.StepsLog = append(resp.Logs.StepsLog, logs...)
+
+		resp.State.CurrentJob.Annotations, err = getJobAnnotations(ctx, run, task)
+		if err != nil {
+			ctx.ServerError("getJobAnnotations", err)
+			return
+		}
+
+		resp.State.CurrentJob.TestSummary, resp.State.CurrentJob.TestFailures, err = getJobTestResults(ctx, run, task)
+		if err != nil {
+			ctx.ServerError("getJobTestResults", err)
+			return
+		}
 	}
 
 	ctx.JSON(http.StatusOK, resp)
 }
 
+// getTaskOfJobInAttempt returns the task of the job in the attempt of its run, or the latest task of the job if runAttempt is 0.
+// It returns nil if the job has no task in the attempt.
+func getTaskOfJobInAttempt(ctx context.Context, job *actions_model.ActionRunJob, runAttempt int64) (*actions_model.ActionTask, error) {
+	if runAttempt == 0 {
+		if job.TaskID == 0 {
+			return nil, nil
+		}
+		return actions_model.GetTaskByID(ctx, job.TaskID)
+	}
+	task, err := actions_model.GetTaskOfJobInRunAttempt(ctx, job.ID, runAttempt)
+	if errors.Is(err, util.ErrNotExist) {
+		return nil, nil
+	}
+	return task, err
+}
+
+// getJobAnnotations returns the annotations of a task
+func getJobAnnotations(ctx *context_module.Context, run *actions_model.ActionRun, task *actions_model.ActionTask) ([]*ViewJobAnnotation, error) {
+	steps := actions.FullSteps(task)
+	stepName := func(logIndex int64) string {
+		for _, step := range steps {
+			if logIndex >= step.LogIndex && logIndex < step.LogIndex+step.LogLength {
+				return step.Name
+			}
+		}
+		return ""
+	}
+
+	annotations, err := actions_model.FindTaskAnnotations(ctx, task.ID)
+	if err != nil {
+		return nil, err
+	}
+	viewAnnotations := make([]*ViewJobAnnotation, 0, len(annotations))
+	for _, annotation := range annotations {
+		viewAnnotation := &ViewJobAnnotation{
+			Step:      stepName(annotation.LogIndex),
+			Level:     string(annotation.Level),
+			Title:     annotation.Title,
+			Message:   annotation.Message,
+			Path:      annotation.Path,
+			StartLine: annotation.StartLine,
+			EndLine:   annotation.EndLine,
+		}
+		if annotation.Path != "" {
+			viewAnnotation.Link = fmt.Sprintf("%s/src/commit/%s/%s", run.Repo.Link(), run.CommitSHA, util.PathEscapeSegments(annotation.Path))
+			if annotation.StartLine > 0 {
+				viewAnnotation.Link += fmt.Sprintf("#L%d", annotation.StartLine)
+				if annotation.EndLine > annotation.StartLine {
+					viewAnnotation.Link += fmt.Sprintf("-L%d", annotation.EndLine)
+				}
+			}
+		}
+		viewAnnotations = append(viewAnnotations, viewAnnotation)
+	}
+	return viewAnnotations, nil
+}
+
+func toViewTestSummary(summary *actions_model.TestSummary) *ViewTestSummary {
+	if summary.Total == 0 {
+		return nil
+	}
+	return &ViewTestSummary{
+		Total:   summary.Total,
+		Passed:  summary.Passed,
+		Failed:  summary.Failed,
+		Skipped: summary.Skipped,
+	}
+}
+
+// maxViewTestFailures is the max number of failed test cases shown for a job
+const maxViewTestFailures = 50
+
+// getJobTestResults returns the test summary and the failed test cases of a task
+func getJobTestResults(ctx *context_module.Context, run *actions_model.ActionRun, task *actions_model.ActionTask) (*ViewTestSummary, []*ViewTestCase, error) {
+	summary, err := actions_model.GetTaskTestSummary(ctx, task.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	viewFailures := make([]*ViewTestCase, 0) // marshal to '[]' instead fo 'null' in json
+	if summary.Failed == 0 {
+		return toViewTestSummary(summary), viewFailures, nil
+	}
+
+	failures, err := db.Find[actions_model.ActionTestCase](ctx, actions_model.FindTestCasesOptions{
+		ListOptions: db.ListOptions{PageSize: maxViewTestFailures},
+		RunID:       run.ID,
+		TaskID:      task.ID,
+		Status:      actions_model.TestStatusFailed,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := actions_model.LoadFlaky(ctx, run.RepoID, failures); err != nil {
+		return nil, nil, err
+	}
+	for _, c := range failures {
+		viewFailures = append(viewFailures, &ViewTestCase{
+			Name:    c.FullName(),
+			Suite:   c.Suite,
+			Message: c.Message,
+			Flaky:   c.Flaky,
+		})
+	}
+	return toViewTestSummary(summary), viewFailures, nil
+}
+
 func convertToViewModel(ctx *context_module.Context, cursors []LogCursor, task *actions_model.ActionTask) ([]*ViewJobStep, []*ViewStepLog, error) {
 	var viewJobs []*ViewJobStep
 	var logs []*ViewStepLog
@@ -420,148 +625,46 @@ func Rerun(ctx *context_module.Context) {
 		jobIndex, _ = strconv.ParseInt(jobIndexStr, 10, 64)
 	}
 
-	run, err := actions_model.GetRunByIndex(ctx, ctx.Repo.Repository.ID, runIndex)
-	if err != nil {
-		ctx.ServerError("GetRunByIndex", err)
-		return
-	}
-
-	// rerun is not allowed if the run is not done
-	if !run.Status.IsDone() {
-		ctx.JSONError(ctx.Locale.Tr("actions.runs.not_done"))
-		return
-	}
-
-	// can not rerun job when workflow is disabled
-	cfgUnit := ctx.Repo.Repository.MustGetUnit(ctx, unit.TypeActions)
-	cfg := cfgUnit.ActionsConfig()
-	if cfg.IsWorkflowDisabled(run.WorkflowID) {
-		ctx.JSONError(ctx.Locale.Tr("actions.workflow.disabled"))
-		return
-	}
-
-	// reset run's start and stop time
-	run.PreviousDuration = run.Duration()
-	run.Started = 0
-	run.Stopped = 0
-	run.Status = actions_model.StatusWaiting
-
-	vars, err := actions_model.GetVariablesOfRun(ctx, run)
-	if err != nil {
-		ctx.ServerError("GetVariablesOfRun", fmt.Errorf("get run %d variables: %w", run.ID, err))
-		return
-	}
-
-	if run.RawConcurrency != "" {
-		var rawConcurrency model.RawConcurrency
-		if err := yaml.Unmarshal([]byte(run.RawConcurrency), &rawConcurrency); err != nil {
-			ctx.ServerError("UnmarshalRawConcurrency", fmt.Errorf("unmarshal raw concurrency: %w", err))
-			return
-		}
-
-		err = actions_service.EvaluateRunConcurrencyFillModel(ctx, run, &rawConcurrency, vars)
-		if err != nil {
-			ctx.ServerError("EvaluateRunConcurrencyFillModel", err)
-			return
-		}
-
-		run.Status, err = actions_service.PrepareToStartRunWithConcurrency(ctx, run)
-		if err != nil {
-			ctx.ServerError("PrepareToStartRunWithConcurrency", err)
-			return
-		}
-	}
-	if err := actions_model.UpdateRun(ctx, run, "started", "stopped", "previous_duration", "status", "concurrency_group", "concurrency_cancel"); err != nil {
-		ctx.ServerError("UpdateRun", err)
-		return
-	}
-
-	if err := run.LoadAttributes(ctx); err != nil {
-		ctx.ServerError("run.LoadAttributes", err)
-		return
-	}
-	notify_service.WorkflowRunStatusUpdate(ctx, run.Repo, run.TriggerUser, run)
-
 	job, jobs := getRunJobs(ctx, runIndex, jobIndex)
 	if ctx.Written() {
 		return
 	}
 
-	isRunBlocked := run.Status == actions_model.StatusBlocked
-	if jobIndexStr == "" { // rerun all jobs
-		for _, j := range jobs {
-			// if the job has needs, it should be set to "blocked" status to wait for other jobs
-			shouldBlockJob := len(j.Needs) > 0 || isRunBlocked
-			if err := rerunJob(ctx, j, shouldBlockJob); err != nil {
-				ctx.ServerError("RerunJob", err)
-				return
-			}
-		}
-		ctx.JSONOK()
-		return
-	}
-
-	rerunJobs := actions_service.GetAllRerunJobs(job, jobs)
-
-	for _, j := range rerunJobs {
-		// jobs other than the specified one should be set to "blocked" status
-		shouldBlockJob := j.JobID != job.JobID || isRunBlocked
-		if err := rerunJob(ctx, j, shouldBlockJob); err != nil {
-			ctx.ServerError("RerunJob", err)
-			return
-		}
+	opts := actions_service.RerunOptions{DebugLogging: ctx.FormBool("debug")}
+	if jobIndexStr != "" {
+		opts.Job = job
 	}
-
-	ctx.JSONOK()
+	rerunRun(ctx, job.Run, jobs, opts)
 }
 
-func rerunJob(ctx *context_module.Context, job *actions_model.ActionRunJob, shouldBlock bool) error {
-	status := job.Status
-	if !status.IsDone() {
-		return nil
-	}
-
-	job.TaskID = 0
-	job.Status = util.Iif(shouldBlock, actions_model.StatusBlocked, actions_model.StatusWaiting)
-	job.Started = 0
-	job.Stopped = 0
-
-	job.ConcurrencyGroup = ""
-	job.ConcurrencyCancel = false
-	job.IsConcurrencyEvaluated = false
-	if err := job.LoadRun(ctx); err != nil {
-		return err
-	}
+// RerunFailed reruns the failed jobs in the given run and the jobs depending on them
+func RerunFailed(ctx *context_module.Context) {
+	runIndex := getRunIndex(ctx)
 
-	vars, err := actions_model.GetVariablesOfRun(ctx, job.Run)
-	if err != nil {
-		return fmt.Errorf("get run %d variables: %w", job.Run.ID, err)
+	job, jobs := getRunJobs(ctx, runIndex, -1)
+	if ctx.Written() {
+		return
 	}
 
-	if job.RawConcurrency != "" && !shouldBlock {
-		err = actions_service.EvaluateJobConcurrencyFillModel(ctx, job.Run, job, vars)
-		if err != nil {
-			return fmt.Errorf("evaluate job concurrency: %w", err)
-		}
+	rerunRun(ctx, job.Run, jobs, actions_service.RerunOptions{FailedOnly: true, DebugLogging: ctx.FormBool("debug")})
+}
 
-		job.Status, err = actions_service.PrepareToStartJobWithConcurrency(ctx, job)
-		if err != nil {
-			return err
+func rerunRun(ctx *context_module.Context, run *actions_model.ActionRun, jobs []*actions_model.ActionRunJob, opts actions_service.RerunOptions) {
+	if err := actions_service.RerunRun(ctx, run, jobs, opts); err != nil {
+		switch {
+		case errors.Is(err, actions_service.ErrRerunRunNotDone):
+			ctx.JSONError(ctx.Locale.Tr("actions.runs.not_done"))
+		case errors.Is(err, actions_service.ErrRerunWorkflowDisabled):
+			ctx.JSONError(ctx.Locale.Tr("actions.workflow.disabled"))
+		case errors.Is(err, actions_service.ErrRerunNoFailedJobs):
+			ctx.JSONError(ctx.Locale.Tr("actions.runs.no_failed_jobs"))
+		default:
+			ctx.ServerError("RerunRun", err)
 		}
+		return
 	}
 
-	if err := db.WithTx(ctx, func(ctx context.Context) error {
-		updateCols := []string{"task_id", "status", "started", "stopped", "concurrency_group", "concurrency_cancel", "is_concurrency_evaluated"}
-		_, err := actions_model.UpdateRunJob(ctx, job, builder.Eq{"status": status}, updateCols...)
-		return err
-	}); err != nil {
-		return err
-	}
-
-	actions_service.CreateCommitStatusForRunJobs(ctx, job.Run, job)
-	notify_service.WorkflowJobStatusUpdate(ctx, job.Run.Repo, job.Run.TriggerUser, job, nil)
-
-	return nil
+	ctx.JSONOK()
 }
 
 func Logs(ctx *context_module.Context) {
@@ -576,7 +679,7 @@ func Logs(ctx *context_module.Context) {
 		return
 	}
 
-	if err = common.DownloadActionsRunJobLogsWithIndex(ctx.Base, ctx.Repo.Repository, run.ID, jobIndex); err != nil {
+	if err = common.DownloadActionsRunJobLogsWithIndex(ctx.Base, ctx.Repo.Repository, run.ID, jobIndex, ctx.FormInt64("attempt")); err != nil {
 		ctx.NotFoundOrServerError("DownloadActionsRunJobLogsWithIndex", func(err error) bool {
 			return errors.Is(err, util.ErrNotExist)
 		}, err)
@@ -630,6 +733,32 @@ func Approve(ctx *context_module.Context) {
 	ctx.JSONOK()
 }
 
+// ReviewDeployment approves or rejects the deployment of a job waiting for the protection rules of its environment
+func ReviewDeployment(ctx *context_module.Context) {
+	runIndex := getRunIndex(ctx)
+	jobIndex := ctx.PathParamInt64("job")
+
+	job, _ := getRunJobs(ctx, runIndex, jobIndex)
+	if ctx.Written() {
+		return
+	}
+
+	approve := ctx.PathParam("action") == "approve"
+	if err := actions_service.ReviewDeployment(ctx, job, ctx.Doer, approve, ctx.FormString("comment")); err != nil {
+		switch {
+		case errors.Is(err, util.ErrPermissionDenied):
+			ctx.JSONError(ctx.Tr("actions.environments.deployment.not_reviewer"))
+		case errors.Is(err, util.ErrNotExist), errors.Is(err, util.ErrInvalidArgument):
+			ctx.JSONError(ctx.Tr("actions.environments.deployment.not_waiting"))
+		default:
+			ctx.ServerError("ReviewDeployment", err)
+		}
+		return
+	}
+
+	ctx.JSONOK()
+}
+
 func approveRuns(ctx *context_module.Context, runIndexes []int64) {
 	doer := ctx.Doer
 	repo := ctx.Repo.Repository
@@ -657,6 +786,10 @@ func approveRuns(ctx *context_module.Context, runIndexes []int64) {
 			}
 			runJobs[run.ID] = jobs
 			for _, job := range jobs {
+				if job.RequiresJobEmitter() {
+					// it will be expanded or checked by the job emitter
+					continue
+				}
 				job.Status, err = actions_service.PrepareToStartJobWithConcurrency(ctx, job)
 				if err != nil {
 					return err
@@ -681,6 +814,7 @@ func approveRuns(ctx *context_module.Context, runIndexes []int64) {
 
 	for runID, run := range runMap {
 		actions_service.CreateCommitStatusForRunJobs(ctx, run, runJobs[runID]...)
+		actions_service.EmitJobsIfRequired(run, runJobs[runID])
 	}
 
 	if len(updatedJobs) > 0 {
@@ -766,7 +900,20 @@ func ArtifactsDeleteView(ctx *context_module.Context) {
 		}, err)
 		return
 	}
-	if err = actions_model.SetArtifactNeedDelete(ctx, run.ID, artifactName); err != nil {
+	artifacts, err := db.Find[actions_model.ActionArtifact](ctx, actions_model.FindArtifactsOptions{
+		RunID:        run.ID,
+		RunAttempt:   run.Attempt,
+		ArtifactName: artifactName,
+	})
+	if err != nil {
+		ctx.ServerError("FindArtifacts", err)
+		return
+	}
+	if len(artifacts) == 0 {
+		ctx.JSONErrorNotFound()
+		return
+	}
+	if err = actions_model.SetArtifactNeedDelete(ctx, run.ID, artifacts[0].RunAttempt, artifactName); err != nil {
 		ctx.ServerError("SetArtifactNeedDelete", err)
 		return
 	}
@@ -787,8 +934,14 @@ func ArtifactsDownloadView(ctx *context_module.Context) {
 		return
 	}
 
+	// the artifacts of a previous attempt can be downloaded with the "attempt" query
+	attempt := ctx.FormInt64("attempt")
+	if attempt <= 0 || attempt > run.Attempt {
+		attempt = run.Attempt
+	}
 	artifacts, err := db.Find[actions_model.ActionArtifact](ctx, actions_model.FindArtifactsOptions{
 		RunID:        run.ID,
+		RunAttempt:   attempt,
 		ArtifactName: artifactName,
 	})
 	if err != nil {
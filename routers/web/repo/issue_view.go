@@ -51,6 +51,7 @@ import (
 	"github.com/kumose/kmup/services/context"
 	"github.com/kumose/kmup/services/context/upload"
 	issue_service "github.com/kumose/kmup/services/issue"
+	"github.com/kumose/kmup/services/mergequeue"
 	pull_service "github.com/kumose/kmup/services/pull"
 	user_service "github.com/kumose/kmup/services/user"
 )
@@ -955,7 +956,7 @@ func preparePullViewReviewAndMerge(ctx *context.Context, issue *issues_model.Iss
 	ctx.Data["DefaultSquashMergeMessage"] = defaultSquashMergeMessage
 	ctx.Data["DefaultSquashMergeBody"] = defaultSquashMergeBody
 
-	pb, err := git_model.GetFirstMatchProtectedBranchRule(ctx, pull.BaseRepoID, pull.BaseBranch)
+	pb, err := git_model.GetFirstMatchProtectedBranchRuleForUser(ctx, pull.BaseRepoID, pull.BaseBranch, ctx.Doer)
 	if err != nil {
 		ctx.ServerError("LoadProtectedBranch", err)
 		return
@@ -974,6 +975,16 @@ func preparePullViewReviewAndMerge(ctx *context.Context, issue *issues_model.Iss
 		ctx.Data["IsBlockedByChangedProtectedFiles"] = len(pull.ChangedProtectedFiles) != 0
 		ctx.Data["ChangedProtectedFilesNum"] = len(pull.ChangedProtectedFiles)
 		ctx.Data["RequireApprovalsWhitelist"] = pb.EnableApprovalsWhitelist
+
+		if pb.RequireCodeOwnerApproval && !pull.HasMerged && !issue.IsClosed {
+			missing, err := pull_service.GetMissingCodeOwnerApprovals(ctx, pb, pull)
+			if err != nil {
+				ctx.ServerError("GetMissingCodeOwnerApprovals", err)
+				return
+			}
+			ctx.Data["IsBlockedByCodeOwners"] = len(missing) > 0
+			ctx.Data["MissingCodeOwnerApprovals"] = missing
+		}
 	}
 
 	preparePullViewSigning(ctx, issue)
@@ -1008,6 +1019,15 @@ func preparePullViewReviewAndMerge(ctx *context.Context, issue *issues_model.Iss
 		ctx.ServerError("GetScheduledMergeByPullID", err)
 		return
 	}
+
+	if pb != nil && pb.EnableMergeQueue && !pull.HasMerged && !issue.IsClosed {
+		ctx.Data["RequireMergeQueue"] = true
+		ctx.Data["QueuedPullRequest"], err = mergequeue.GetQueuedPullRequest(ctx, pull)
+		if err != nil {
+			ctx.ServerError("GetQueuedPullRequest", err)
+			return
+		}
+	}
 }
 
 func prepareIssueViewContent(ctx *context.Context, issue *issues_model.Issue) {
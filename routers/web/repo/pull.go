@@ -26,6 +26,7 @@ import (
 	"strings"
 	"time"
 
+	actions_model "github.com/kumose/kmup/models/actions"
 	activities_model "github.com/kumose/kmup/models/activities"
 	"github.com/kumose/kmup/models/db"
 	git_model "github.com/kumose/kmup/models/git"
@@ -35,6 +36,7 @@ import (
 	repo_model "github.com/kumose/kmup/models/repo"
 	"github.com/kumose/kmup/models/unit"
 	user_model "github.com/kumose/kmup/models/user"
+	"github.com/kumose/kmup/modules/commitstatus"
 	"github.com/kumose/kmup/modules/emoji"
 	"github.com/kumose/kmup/modules/fileicon"
 	"github.com/kumose/kmup/modules/git"
@@ -57,6 +59,7 @@ import (
 	"github.com/kumose/kmup/services/context/upload"
 	"github.com/kumose/kmup/services/forms"
 	"github.com/kumose/kmup/services/gitdiff"
+	"github.com/kumose/kmup/services/mergequeue"
 	notify_service "github.com/kumose/kmup/services/notify"
 	pull_service "github.com/kumose/kmup/services/pull"
 	repo_service "github.com/kumose/kmup/services/repository"
@@ -325,11 +328,12 @@ func prepareMergedViewPullInfo(ctx *context.Context, issue *issues_model.Issue)
 }
 
 type pullCommitStatusCheckData struct {
-	MissingRequiredChecks   []string          // list of missing required checks
-	IsContextRequired       func(string) bool // function to check whether a context is required
-	RequireApprovalRunCount int               // number of workflow runs that require approval
-	CanApprove              bool              // whether the user can approve workflow runs
-	ApproveLink             string            // link to approve all checks
+	MissingRequiredChecks   []string                   // list of missing required checks
+	IsContextRequired       func(string) bool          // function to check whether a context is required
+	RequireApprovalRunCount int                        // number of workflow runs that require approval
+	CanApprove              bool                       // whether the user can approve workflow runs
+	ApproveLink             string                     // link to approve all checks
+	TestSummary             *actions_model.TestSummary // test results reported by the workflow runs of the head commit
 }
 
 // prepareViewPullInfo show meta information for a pull request preview page
@@ -351,12 +355,13 @@ func prepareViewPullInfo(ctx *context.Context, issue *issues_model.Issue) *pull_
 
 	setMergeTarget(ctx, pull)
 
-	pb, err := git_model.GetFirstMatchProtectedBranchRule(ctx, repo.ID, pull.BaseBranch)
+	pb, err := git_model.GetFirstMatchProtectedBranchRuleForUser(ctx, repo.ID, pull.BaseBranch, ctx.Doer)
 	if err != nil {
 		ctx.ServerError("LoadProtectedBranch", err)
 		return nil
 	}
-	ctx.Data["EnableStatusCheck"] = pb != nil && pb.EnableStatusCheck
+	// with a merge queue the required checks run on the merge group, so they don't block the pull request itself
+	ctx.Data["EnableStatusCheck"] = pb != nil && !pb.EnableMergeQueue && (pb.EnableStatusCheck || pb.RequireRequiredWorkflows)
 
 	var baseGitRepo *git.Repository
 	if pull.BaseRepoID == ctx.Repo.Repository.ID && ctx.Repo.GitRepo != nil {
@@ -505,6 +510,17 @@ func prepareViewPullInfo(ctx *context.Context, issue *issues_model.Issue) *pull_
 		statusCheckData.CanApprove = ctx.Repo.CanWrite(unit.TypeActions)
 	}
 
+	if ctx.Repo.CanRead(unit.TypeActions) {
+		testSummary, err := actions_model.GetCommitTestSummary(ctx, repo.ID, sha)
+		if err != nil {
+			ctx.ServerError("GetCommitTestSummary", err)
+			return nil
+		}
+		if testSummary.Total > 0 {
+			statusCheckData.TestSummary = testSummary
+		}
+	}
+
 	if len(commitStatuses) > 0 {
 		ctx.Data["LatestCommitStatuses"] = commitStatuses
 		ctx.Data["LatestCommitStatus"] = git_model.CalcCommitStatus(commitStatuses)
@@ -547,6 +563,18 @@ func prepareViewPullInfo(ctx *context.Context, issue *issues_model.Issue) *pull_
 		ctx.Data["RequiredStatusCheckState"] = pull_service.MergeRequiredContextsCommitStatus(commitStatuses, pb.StatusCheckContexts)
 	}
 
+	if pb != nil && pb.RequireRequiredWorkflows {
+		state, err := pull_service.GetRequiredWorkflowsState(ctx, pull.BaseRepo, sha)
+		if err != nil {
+			ctx.ServerError("GetRequiredWorkflowsState", err)
+			return nil
+		}
+		if statusCheckState, ok := ctx.Data["RequiredStatusCheckState"].(commitstatus.CommitStatusState); ok {
+			state = commitstatus.CommitStatusStates{statusCheckState, state}.Combine()
+		}
+		ctx.Data["RequiredStatusCheckState"] = state
+	}
+
 	ctx.Data["HeadBranchMovedOn"] = headBranchSha != sha
 	ctx.Data["HeadBranchCommitID"] = headBranchSha
 	ctx.Data["PullHeadCommitID"] = sha
@@ -824,6 +852,13 @@ func viewPullFiles(ctx *context.Context, beforeCommitID, afterCommitID string) {
 		return
 	}
 
+	if ctx.Repo.CanRead(unit.TypeActions) {
+		if err = diff.LoadAnnotations(ctx, ctx.Repo.Repository.ID, afterCommitID); err != nil {
+			ctx.ServerError("LoadAnnotations", err)
+			return
+		}
+	}
+
 	allComments := issues_model.CommentList{}
 	for _, file := range diff.Files {
 		for _, section := range file.Sections {
@@ -1083,6 +1118,20 @@ func MergePullRequest(ctx *context.Context) {
 		mergeCheckType = pull_service.MergeCheckTypeManually
 	}
 
+	// a branch which requires a merge queue gets the pull request queued, unless an admin forces the merge
+	addToMergeQueue := false
+	if mergeCheckType == pull_service.MergeCheckTypeGeneral && !form.ForceMerge {
+		queueRequired, err := pull_service.IsMergeQueueRequired(ctx, pr)
+		if err != nil {
+			ctx.ServerError("IsMergeQueueRequired", err)
+			return
+		}
+		if queueRequired {
+			addToMergeQueue = true
+			mergeCheckType = pull_service.MergeCheckTypeQueue
+		}
+	}
+
 	// start with merging by checking
 	if err := pull_service.CheckPullMergeable(ctx, ctx.Doer, &ctx.Repo.Permission, pr, mergeCheckType, form.ForceMerge); err != nil {
 		switch {
@@ -1169,6 +1218,20 @@ func MergePullRequest(ctx *context.Context) {
 		}
 	}
 
+	if addToMergeQueue {
+		if err := mergequeue.AddToMergeQueue(ctx, ctx.Doer, pr, repo_model.MergeStyle(form.Do), message, deleteBranchAfterMerge); err != nil {
+			if pull_model.IsErrAlreadyInMergeQueue(err) {
+				ctx.JSONError(ctx.Tr("repo.pulls.merge_queue_already_added"))
+				return
+			}
+			ctx.ServerError("AddToMergeQueue", err)
+			return
+		}
+		ctx.Flash.Success(ctx.Tr("repo.pulls.merge_queue_added"))
+		ctx.JSONRedirect(issue.Link())
+		return
+	}
+
 	if err := pull_service.Merge(ctx, pr, ctx.Doer, repo_model.MergeStyle(form.Do), form.HeadCommitID, message, false); err != nil {
 		if pull_service.IsErrInvalidMergeStyle(err) {
 			ctx.JSONError(ctx.Tr("repo.pulls.invalid_merge_option"))
@@ -1286,6 +1349,25 @@ func CancelAutoMergePullRequest(ctx *context.Context) {
 	ctx.Redirect(fmt.Sprintf("%s/pulls/%d", ctx.Repo.RepoLink, issue.Index))
 }
 
+// RemoveFromMergeQueue removes a pull request from the merge queue of its base branch
+func RemoveFromMergeQueue(ctx *context.Context) {
+	issue, ok := getPullInfo(ctx)
+	if !ok {
+		return
+	}
+
+	if err := mergequeue.RemoveFromMergeQueue(ctx, ctx.Doer, issue.PullRequest); err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			ctx.JSONError(ctx.Tr("repo.pulls.merge_queue_not_added"))
+			return
+		}
+		ctx.ServerError("RemoveFromMergeQueue", err)
+		return
+	}
+	ctx.Flash.Success(ctx.Tr("repo.pulls.merge_queue_removed"))
+	ctx.JSONRedirect(issue.Link())
+}
+
 func stopTimerIfAvailable(ctx *context.Context, user *user_model.User, issue *issues_model.Issue) error {
 	_, err := issues_model.FinishIssueStopwatch(ctx, user, issue)
 	return err
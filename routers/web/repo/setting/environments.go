@@ -0,0 +1,188 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package setting
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	actions_model "github.com/kumose/kmup/models/actions"
+	"github.com/kumose/kmup/models/db"
+	"github.com/kumose/kmup/models/perm"
+	access_model "github.com/kumose/kmup/models/perm/access"
+	"github.com/kumose/kmup/models/unit"
+	"github.com/kumose/kmup/modules/base"
+	"github.com/kumose/kmup/modules/templates"
+	"github.com/kumose/kmup/modules/util"
+	"github.com/kumose/kmup/modules/web"
+	actions_service "github.com/kumose/kmup/services/actions"
+	"github.com/kumose/kmup/services/context"
+	"github.com/kumose/kmup/services/forms"
+)
+
+const (
+	tplRepoEnvironments    templates.TplName = "repo/settings/actions"
+	tplRepoEnvironmentEdit templates.TplName = "repo/settings/actions_environment_edit"
+)
+
+// Environments lists the deployment environments of the repository
+func Environments(ctx *context.Context) {
+	ctx.Data["Title"] = ctx.Tr("actions.environments")
+	ctx.Data["PageType"] = "environments"
+	ctx.Data["PageIsSharedSettingsEnvironments"] = true
+
+	envs, err := db.Find[actions_model.ActionEnvironment](ctx, actions_model.FindEnvironmentsOptions{RepoID: ctx.Repo.Repository.ID})
+	if err != nil {
+		ctx.ServerError("FindEnvironments", err)
+		return
+	}
+	ctx.Data["Environments"] = envs
+	ctx.Data["NameMaxLength"] = actions_model.EnvironmentNameMaxLength
+	ctx.HTML(http.StatusOK, tplRepoEnvironments)
+}
+
+// EnvironmentCreate creates a deployment environment without protection rules
+func EnvironmentCreate(ctx *context.Context) {
+	name := ctx.FormTrim("name")
+	env, err := actions_service.CreateEnvironment(ctx, ctx.Repo.Repository, actions_service.EnvironmentOptions{Name: name})
+	if errors.Is(err, util.ErrAlreadyExist) {
+		ctx.JSONError(ctx.Tr("actions.environments.name_exists", name))
+		return
+	} else if errors.Is(err, util.ErrInvalidArgument) {
+		ctx.JSONError(ctx.Tr("actions.environments.invalid", err.Error()))
+		return
+	} else if err != nil {
+		ctx.ServerError("CreateEnvironment", err)
+		return
+	}
+
+	ctx.Flash.Success(ctx.Tr("actions.environments.creation.success", env.Name))
+	ctx.JSONRedirect(environmentSettingsLink(ctx, env))
+}
+
+func environmentSettingsLink(ctx *context.Context, env *actions_model.ActionEnvironment) string {
+	return fmt.Sprintf("%s/settings/actions/environments/%d", ctx.Repo.RepoLink, env.ID)
+}
+
+// getActionsEnvironment returns the environment of the repository in the path, it returns nil if there is not one
+func getActionsEnvironment(ctx *context.Context) *actions_model.ActionEnvironment {
+	envID := ctx.PathParamInt64("environment_id")
+	if envID == 0 {
+		return nil
+	}
+	env, err := actions_model.GetEnvironmentByRepoIDAndID(ctx, ctx.Repo.Repository.ID, envID)
+	if errors.Is(err, util.ErrNotExist) {
+		ctx.NotFound(nil)
+		return nil
+	} else if err != nil {
+		ctx.ServerError("GetEnvironmentByRepoIDAndID", err)
+		return nil
+	}
+	ctx.Data["Environment"] = env
+	ctx.Data["EnvironmentLink"] = environmentSettingsLink(ctx, env)
+	return env
+}
+
+// EnvironmentEdit shows the protection rules of an environment
+func EnvironmentEdit(ctx *context.Context) {
+	env := getActionsEnvironment(ctx)
+	if ctx.Written() {
+		return
+	}
+	ctx.Data["Title"] = ctx.Locale.TrString("actions.environments") + " - " + env.Name
+	ctx.Data["PageIsSharedSettingsEnvironments"] = true
+
+	users, err := access_model.GetUsersWithUnitAccess(ctx, ctx.Repo.Repository, perm.AccessModeWrite, unit.TypeActions)
+	if err != nil {
+		ctx.ServerError("GetUsersWithUnitAccess", err)
+		return
+	}
+	ctx.Data["Users"] = users
+	ctx.Data["reviewers"] = strings.Join(base.Int64sToStrings(env.ReviewerIDs), ",")
+	ctx.Data["branch_patterns"] = strings.Join(env.BranchPatterns, "\n")
+	ctx.Data["tag_patterns"] = strings.Join(env.TagPatterns, "\n")
+	ctx.Data["NameMaxLength"] = actions_model.EnvironmentNameMaxLength
+	ctx.Data["MaxWaitTimer"] = actions_model.EnvironmentMaxWaitTimer
+	ctx.HTML(http.StatusOK, tplRepoEnvironmentEdit)
+}
+
+func splitRefPatterns(s string) []string {
+	var patterns []string
+	for line := range strings.SplitSeq(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			patterns = append(patterns, line)
+		}
+	}
+	return patterns
+}
+
+// EnvironmentEditPost updates the protection rules of an environment
+func EnvironmentEditPost(ctx *context.Context) {
+	env := getActionsEnvironment(ctx)
+	if ctx.Written() {
+		return
+	}
+	if ctx.HasError() {
+		ctx.JSONError(ctx.GetErrMsg())
+		return
+	}
+
+	form := web.GetForm(ctx).(*forms.EditEnvironmentForm)
+	var reviewerIDs []int64
+	if strings.TrimSpace(form.Reviewers) != "" {
+		reviewerIDs, _ = base.StringsToInt64s(strings.Split(form.Reviewers, ","))
+	}
+	err := actions_service.UpdateEnvironment(ctx, ctx.Repo.Repository, env, actions_service.EnvironmentOptions{
+		Name:           form.Name,
+		ReviewerIDs:    reviewerIDs,
+		WaitTimer:      form.WaitTimer,
+		BranchPatterns: splitRefPatterns(form.BranchPatterns),
+		TagPatterns:    splitRefPatterns(form.TagPatterns),
+	})
+	if errors.Is(err, util.ErrAlreadyExist) {
+		ctx.JSONError(ctx.Tr("actions.environments.name_exists", form.Name))
+		return
+	} else if errors.Is(err, util.ErrInvalidArgument) {
+		ctx.JSONError(ctx.Tr("actions.environments.invalid", err.Error()))
+		return
+	} else if err != nil {
+		ctx.ServerError("UpdateEnvironment", err)
+		return
+	}
+
+	ctx.Flash.Success(ctx.Tr("actions.environments.update.success"))
+	ctx.JSONRedirect(environmentSettingsLink(ctx, env))
+}
+
+// EnvironmentDelete deletes an environment with its secrets and variables
+func EnvironmentDelete(ctx *context.Context) {
+	env := getActionsEnvironment(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	if err := actions_service.DeleteEnvironment(ctx, env); err != nil {
+		ctx.ServerError("DeleteEnvironment", err)
+		return
+	}
+
+	ctx.Flash.Success(ctx.Tr("actions.environments.deletion.success"))
+	ctx.JSONRedirect(ctx.Repo.RepoLink + "/settings/actions/environments")
+}
@@ -39,6 +39,7 @@ import (
 	"github.com/kumose/kmup/routers/web/repo"
 	"github.com/kumose/kmup/services/context"
 	"github.com/kumose/kmup/services/forms"
+	"github.com/kumose/kmup/services/mergequeue"
 	pull_service "github.com/kumose/kmup/services/pull"
 	"github.com/kumose/kmup/services/repository"
 )
@@ -274,6 +275,9 @@ func SettingsProtectedBranchPost(ctx *context.Context) {
 	protectBranch.UnprotectedFilePatterns = f.UnprotectedFilePatterns
 	protectBranch.BlockOnOutdatedBranch = f.BlockOnOutdatedBranch
 	protectBranch.BlockAdminMergeOverride = f.BlockAdminMergeOverride
+	protectBranch.RequireRequiredWorkflows = f.RequireRequiredWorkflows
+	protectBranch.RequireCodeOwnerApproval = f.RequireCodeOwnerApproval
+	protectBranch.EnableMergeQueue = f.EnableMergeQueue
 
 	if err = pull_service.CreateOrUpdateProtectedBranch(ctx, ctx.Repo.Repository, protectBranch, git_model.WhitelistOptions{
 		UserIDs:          whitelistUsers,
@@ -288,6 +292,8 @@ func SettingsProtectedBranchPost(ctx *context.Context) {
 		ctx.ServerError("CreateOrUpdateProtectedBranch", err)
 		return
 	}
+	// the merge queues of the branches which don't require one anymore have to be emptied
+	mergequeue.StartRepoMergeQueuesProcessing(ctx, ctx.Repo.Repository.ID)
 
 	ctx.Flash.Success(ctx.Tr("repo.settings.update_protect_branch_success", protectBranch.RuleName))
 	ctx.Redirect(fmt.Sprintf("%s/settings/branches?rule_name=%s", ctx.Repo.RepoLink, protectBranch.RuleName))
@@ -321,6 +327,8 @@ func DeleteProtectedBranchRulePost(ctx *context.Context) {
 		return
 	}
 
+	mergequeue.StartRepoMergeQueuesProcessing(ctx, ctx.Repo.Repository.ID)
+
 	ctx.Flash.Success(ctx.Tr("repo.settings.remove_protected_branch_success", rule.RuleName))
 	ctx.JSONRedirect(ctx.Repo.RepoLink + "/settings/branches")
 }
@@ -0,0 +1,144 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package setting
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/kumose/kmup/models/db"
+	secretscan_model "github.com/kumose/kmup/models/secretscan"
+	"github.com/kumose/kmup/modules/setting"
+	"github.com/kumose/kmup/modules/templates"
+	"github.com/kumose/kmup/modules/util"
+	"github.com/kumose/kmup/modules/web"
+	"github.com/kumose/kmup/services/context"
+	"github.com/kumose/kmup/services/forms"
+	secretscan_service "github.com/kumose/kmup/services/secretscan"
+)
+
+const tplSecretScanning templates.TplName = "repo/settings/secret_scanning"
+
+// SecretScanning shows the secret scanning setting and the alerts of a repository
+func SecretScanning(ctx *context.Context) {
+	ctx.Data["Title"] = ctx.Tr("repo.settings.secret_scanning")
+	ctx.Data["PageIsSettingsSecretScanning"] = true
+	ctx.Data["SecretScanningEnabled"] = setting.SecretScanning.Enabled
+	ctx.Data["DefaultPushProtection"] = setting.SecretScanning.DefaultPushProtection
+
+	repo := ctx.Repo.Repository
+	s, err := secretscan_model.GetRepoSetting(ctx, repo.ID)
+	if err != nil {
+		ctx.ServerError("GetRepoSetting", err)
+		return
+	}
+	ctx.Data["SecretScanSetting"] = s
+
+	state := secretscan_model.AlertState(ctx.FormString("state"))
+	if !state.IsValid() {
+		state = secretscan_model.AlertStateOpen
+	}
+	ctx.Data["State"] = state
+	ctx.Data["AlertStates"] = secretscan_model.AlertStates
+
+	counts, err := secretscan_model.CountAlertsByState(ctx, repo.ID)
+	if err != nil {
+		ctx.ServerError("CountAlertsByState", err)
+		return
+	}
+	ctx.Data["AlertCounts"] = counts
+
+	page := max(ctx.FormInt("page"), 1)
+	alerts, total, err := db.FindAndCount[secretscan_model.Alert](ctx, secretscan_model.FindAlertsOptions{
+		ListOptions: db.ListOptions{Page: page, PageSize: setting.UI.ExplorePagingNum},
+		RepoID:      repo.ID,
+		State:       state,
+	})
+	if err != nil {
+		ctx.ServerError("FindAlerts", err)
+		return
+	}
+	ctx.Data["Alerts"] = alerts
+
+	pager := context.NewPagination(int(total), setting.UI.ExplorePagingNum, page, 5)
+	pager.AddParamFromRequest(ctx.Req)
+	ctx.Data["Page"] = pager
+
+	ctx.HTML(http.StatusOK, tplSecretScanning)
+}
+
+// SecretScanningPost changes the push protection of a repository
+func SecretScanningPost(ctx *context.Context) {
+	form := web.GetForm(ctx).(*forms.SecretScanningForm)
+	pushProtection := secretscan_model.PushProtection(form.PushProtection)
+	if !pushProtection.IsValid() {
+		ctx.HTTPError(http.StatusBadRequest)
+		return
+	}
+
+	if err := secretscan_model.SetRepoPushProtection(ctx, ctx.Repo.Repository.ID, pushProtection); err != nil {
+		ctx.ServerError("SetRepoPushProtection", err)
+		return
+	}
+
+	ctx.Flash.Success(ctx.Tr("repo.settings.update_settings_success"))
+	ctx.Redirect(ctx.Repo.RepoLink + "/settings/secret_scanning")
+}
+
+// SecretScanningScanPost starts a secret scan of the whole history of a repository
+func SecretScanningScanPost(ctx *context.Context) {
+	if err := secretscan_service.ScanHistory(ctx.Repo.Repository, ctx.Doer); err != nil {
+		if errors.Is(err, util.ErrInvalidArgument) {
+			ctx.Flash.Error(err.Error())
+			ctx.Redirect(ctx.Repo.RepoLink + "/settings/secret_scanning")
+			return
+		}
+		ctx.ServerError("ScanHistory", err)
+		return
+	}
+
+	ctx.Flash.Success(ctx.Tr("repo.settings.secret_scanning.scan_queued"))
+	ctx.Redirect(ctx.Repo.RepoLink + "/settings/secret_scanning")
+}
+
+// SecretScanningAlertPost changes the state of a secret scanning alert
+func SecretScanningAlertPost(ctx *context.Context) {
+	form := web.GetForm(ctx).(*forms.SecretScanningAlertForm)
+	state := secretscan_model.AlertState(form.State)
+	if ctx.HasError() || !state.IsValid() {
+		ctx.HTTPError(http.StatusBadRequest)
+		return
+	}
+
+	alert, err := secretscan_model.GetAlertByID(ctx, ctx.Repo.Repository.ID, ctx.PathParamInt64("id"))
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			ctx.NotFound(err)
+		} else {
+			ctx.ServerError("GetAlertByID", err)
+		}
+		return
+	}
+	if err := secretscan_service.UpdateAlertState(ctx, ctx.Doer, ctx.Repo.Repository, alert, state); err != nil {
+		ctx.ServerError("UpdateAlertState", err)
+		return
+	}
+
+	ctx.Flash.Success(ctx.Tr("repo.settings.secret_scanning.alert_updated"))
+	ctx.Redirect(ctx.Repo.RepoLink + "/settings/secret_scanning?state=" + string(state))
+}
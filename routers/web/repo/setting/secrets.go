@@ -39,6 +39,7 @@ const (
 type secretsCtx struct {
 	OwnerID         int64
 	RepoID          int64
+	EnvironmentID   int64
 	IsRepo          bool
 	IsOrg           bool
 	IsUser          bool
@@ -48,13 +49,21 @@ type secretsCtx struct {
 
 func getSecretsCtx(ctx *context.Context) (*secretsCtx, error) {
 	if ctx.Data["PageIsRepoSettings"] == true {
-		return &secretsCtx{
+		sCtx := &secretsCtx{
 			OwnerID:         0,
 			RepoID:          ctx.Repo.Repository.ID,
 			IsRepo:          true,
 			SecretsTemplate: tplRepoSecrets,
 			RedirectLink:    ctx.Repo.RepoLink + "/settings/actions/secrets",
-		}, nil
+		}
+		// the secrets of an environment
+		if env := getActionsEnvironment(ctx); env != nil {
+			sCtx.EnvironmentID = env.ID
+			sCtx.RedirectLink = environmentSettingsLink(ctx, env) + "/secrets"
+		} else if ctx.Written() {
+			return nil, nil
+		}
+		return sCtx, nil
 	}
 
 	if ctx.Data["PageIsOrgSettings"] == true {
@@ -87,20 +96,26 @@ func getSecretsCtx(ctx *context.Context) (*secretsCtx, error) {
 func Secrets(ctx *context.Context) {
 	ctx.Data["Title"] = ctx.Tr("actions.actions")
 	ctx.Data["PageType"] = "secrets"
-	ctx.Data["PageIsSharedSettingsSecrets"] = true
 	ctx.Data["UserDisabledFeatures"] = user_model.DisabledFeaturesWithLoginType(ctx.Doer)
 
 	sCtx, err := getSecretsCtx(ctx)
 	if err != nil {
 		ctx.ServerError("getSecretsCtx", err)
 		return
+	} else if ctx.Written() {
+		return
+	}
+	if sCtx.EnvironmentID > 0 {
+		ctx.Data["PageIsSharedSettingsEnvironments"] = true
+	} else {
+		ctx.Data["PageIsSharedSettingsSecrets"] = true
 	}
 
 	if sCtx.IsRepo {
 		ctx.Data["DisableSSH"] = setting.SSH.Disabled
 	}
 
-	shared.SetSecretsContext(ctx, sCtx.OwnerID, sCtx.RepoID)
+	shared.SetSecretsContext(ctx, sCtx.OwnerID, sCtx.RepoID, sCtx.EnvironmentID)
 	if ctx.Written() {
 		return
 	}
@@ -112,6 +127,8 @@ func SecretsPost(ctx *context.Context) {
 	if err != nil {
 		ctx.ServerError("getSecretsCtx", err)
 		return
+	} else if ctx.Written() {
+		return
 	}
 
 	if ctx.HasError() {
@@ -123,6 +140,7 @@ func SecretsPost(ctx *context.Context) {
 		ctx,
 		sCtx.OwnerID,
 		sCtx.RepoID,
+		sCtx.EnvironmentID,
 		sCtx.RedirectLink,
 	)
 }
@@ -132,11 +150,14 @@ func SecretsDelete(ctx *context.Context) {
 	if err != nil {
 		ctx.ServerError("getSecretsCtx", err)
 		return
+	} else if ctx.Written() {
+		return
 	}
 	shared.PerformSecretsDelete(
 		ctx,
 		sCtx.OwnerID,
 		sCtx.RepoID,
+		sCtx.EnvironmentID,
 		sCtx.RedirectLink,
 	)
 }
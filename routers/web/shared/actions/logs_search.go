@@ -0,0 +1,94 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"time"
+
+	"github.com/kumose/kmup/models/db"
+	"github.com/kumose/kmup/modules/git"
+	"github.com/kumose/kmup/modules/indexer"
+	actions_indexer "github.com/kumose/kmup/modules/indexer/actions"
+	"github.com/kumose/kmup/modules/optional"
+	"github.com/kumose/kmup/modules/setting"
+	actions_service "github.com/kumose/kmup/services/actions"
+	"github.com/kumose/kmup/services/context"
+)
+
+const logsSearchDateFormat = "2006-01-02"
+
+// SearchLogs searches the actions logs of the given repositories with the keyword and filters of the request,
+// and prepares the results for the "shared/actions/logs_search" template
+func SearchLogs(ctx *context.Context, repoIDs []int64) {
+	keyword := ctx.FormTrim("q")
+	searchMode := indexer.SearchModeType(ctx.FormTrim("search_mode"))
+	workflow := ctx.FormTrim("workflow")
+	branch := ctx.FormTrim("branch")
+	since := ctx.FormTrim("since")
+	until := ctx.FormTrim("until")
+
+	ctx.Data["Keyword"] = keyword
+	ctx.Data["SelectedSearchMode"] = string(searchMode)
+	ctx.Data["SearchModes"] = actions_indexer.SupportedSearchModes()
+	ctx.Data["Workflow"] = workflow
+	ctx.Data["Branch"] = branch
+	ctx.Data["Since"] = since
+	ctx.Data["Until"] = until
+
+	if keyword == "" {
+		return
+	}
+
+	page := max(ctx.FormInt("page"), 1)
+	opts := &actions_indexer.SearchOptions{
+		Keyword:    keyword,
+		RepoIDs:    repoIDs,
+		WorkflowID: workflow,
+		SearchMode: searchMode,
+		Paginator: &db.ListOptions{
+			Page:     page,
+			PageSize: setting.UI.RepoSearchPagingNum,
+		},
+	}
+	if branch != "" {
+		opts.Ref = git.RefNameFromBranch(branch).String()
+	}
+	if t, err := time.ParseInLocation(logsSearchDateFormat, since, setting.DefaultUILocation); err == nil {
+		opts.CreatedAfterUnix = optional.Some(t.Unix())
+	}
+	if t, err := time.ParseInLocation(logsSearchDateFormat, until, setting.DefaultUILocation); err == nil {
+		// the whole day of "until" is included
+		opts.CreatedBeforeUnix = optional.Some(t.AddDate(0, 0, 1).Unix() - 1)
+	}
+
+	results, total, err := actions_service.SearchLogs(ctx, opts)
+	if err != nil {
+		if actions_indexer.IsAvailable(ctx) {
+			ctx.ServerError("SearchLogs", err)
+			return
+		}
+		ctx.Data["ActionsLogIndexerUnavailable"] = true
+	} else {
+		ctx.Data["ActionsLogIndexerUnavailable"] = !actions_indexer.IsAvailable(ctx)
+	}
+	ctx.Data["SearchResults"] = results
+
+	pager := context.NewPagination(int(total), setting.UI.RepoSearchPagingNum, page, 5)
+	pager.AddParamFromRequest(ctx.Req)
+	ctx.Data["Page"] = pager
+}
@@ -0,0 +1,186 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	actions_model "github.com/kumose/kmup/models/actions"
+	"github.com/kumose/kmup/models/db"
+	repo_model "github.com/kumose/kmup/models/repo"
+	"github.com/kumose/kmup/modules/setting"
+	"github.com/kumose/kmup/modules/templates"
+	"github.com/kumose/kmup/modules/util"
+	"github.com/kumose/kmup/modules/web"
+	shared_user "github.com/kumose/kmup/routers/web/shared/user"
+	actions_service "github.com/kumose/kmup/services/actions"
+	"github.com/kumose/kmup/services/context"
+	"github.com/kumose/kmup/services/forms"
+)
+
+const (
+	tplOrgRequiredWorkflows   templates.TplName = "org/settings/actions"
+	tplAdminRequiredWorkflows templates.TplName = "admin/actions"
+)
+
+type requiredWorkflowsCtx struct {
+	OwnerID      int64 // 0 means instance-level
+	IsOrg        bool
+	IsGlobal     bool
+	Template     templates.TplName
+	RedirectLink string
+}
+
+func getRequiredWorkflowsCtx(ctx *context.Context) (*requiredWorkflowsCtx, error) {
+	if ctx.Data["PageIsOrgSettings"] == true {
+		if _, err := shared_user.RenderUserOrgHeader(ctx); err != nil {
+			ctx.ServerError("RenderUserOrgHeader", err)
+			return nil, nil
+		}
+		return &requiredWorkflowsCtx{
+			OwnerID:      ctx.ContextUser.ID,
+			IsOrg:        true,
+			Template:     tplOrgRequiredWorkflows,
+			RedirectLink: ctx.Org.OrgLink + "/settings/actions/required_workflows",
+		}, nil
+	}
+
+	if ctx.Data["PageIsAdmin"] == true {
+		return &requiredWorkflowsCtx{
+			OwnerID:      0,
+			IsGlobal:     true,
+			Template:     tplAdminRequiredWorkflows,
+			RedirectLink: setting.AppSubURL + "/-/admin/actions/required_workflows",
+		}, nil
+	}
+
+	return nil, errors.New("unable to set RequiredWorkflows context")
+}
+
+// RequiredWorkflows lists the required workflows of the org or the instance
+func RequiredWorkflows(ctx *context.Context) {
+	ctx.Data["Title"] = ctx.Tr("actions.required_workflows")
+	ctx.Data["PageType"] = "required_workflows"
+	ctx.Data["PageIsSharedSettingsRequiredWorkflows"] = true
+
+	rCtx, err := getRequiredWorkflowsCtx(ctx)
+	if err != nil {
+		ctx.ServerError("getRequiredWorkflowsCtx", err)
+		return
+	} else if ctx.Written() {
+		return
+	}
+
+	rws, err := db.Find[actions_model.ActionRequiredWorkflow](ctx, actions_model.FindRequiredWorkflowsOptions{
+		OwnerIDs: []int64{rCtx.OwnerID},
+	})
+	if err != nil {
+		ctx.ServerError("FindRequiredWorkflows", err)
+		return
+	}
+	for _, rw := range rws {
+		if err := rw.LoadRepo(ctx); err != nil {
+			ctx.ServerError("LoadRepo", err)
+			return
+		}
+	}
+	ctx.Data["RequiredWorkflows"] = rws
+	ctx.Data["IsGlobal"] = rCtx.IsGlobal
+	ctx.HTML(http.StatusOK, rCtx.Template)
+}
+
+// RequiredWorkflowCreate adds a required workflow to the org or the instance
+func RequiredWorkflowCreate(ctx *context.Context) {
+	rCtx, err := getRequiredWorkflowsCtx(ctx)
+	if err != nil {
+		ctx.ServerError("getRequiredWorkflowsCtx", err)
+		return
+	} else if ctx.Written() {
+		return
+	}
+
+	if ctx.HasError() { // form binding validation error
+		ctx.JSONError(ctx.GetErrMsg())
+		return
+	}
+
+	form := web.GetForm(ctx).(*forms.RequiredWorkflowForm)
+
+	var repo *repo_model.Repository
+	if rCtx.IsGlobal {
+		ownerName, repoName, _ := strings.Cut(strings.TrimSpace(form.RepoName), "/")
+		repo, err = repo_model.GetRepositoryByOwnerAndName(ctx, ownerName, repoName)
+	} else {
+		repo, err = repo_model.GetRepositoryByName(ctx, rCtx.OwnerID, strings.TrimSpace(form.RepoName))
+	}
+	if repo_model.IsErrRepoNotExist(err) {
+		ctx.JSONError(ctx.Tr("actions.required_workflows.repo_not_exist", form.RepoName))
+		return
+	} else if err != nil {
+		ctx.ServerError("GetRepository", err)
+		return
+	}
+
+	var repoPatterns []string
+	for line := range strings.SplitSeq(form.RepoPatterns, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			repoPatterns = append(repoPatterns, line)
+		}
+	}
+	topics, invalidTopics := repo_model.SanitizeAndValidateTopics(strings.Split(form.Topics, ","))
+	if len(invalidTopics) > 0 {
+		ctx.JSONError(ctx.Tr("repo.topic.format_prompt"))
+		return
+	}
+
+	rw, err := actions_service.CreateRequiredWorkflow(ctx, rCtx.OwnerID, repo, form.WorkflowPath, form.Ref, repoPatterns, topics)
+	if errors.Is(err, util.ErrInvalidArgument) || errors.Is(err, util.ErrNotExist) {
+		ctx.JSONError(ctx.Tr("actions.required_workflows.creation.failed", err.Error()))
+		return
+	} else if err != nil {
+		ctx.ServerError("CreateRequiredWorkflow", err)
+		return
+	}
+
+	ctx.Flash.Success(ctx.Tr("actions.required_workflows.creation.success", rw.WorkflowPath))
+	ctx.JSONRedirect(rCtx.RedirectLink)
+}
+
+// RequiredWorkflowDelete removes a required workflow of the org or the instance
+func RequiredWorkflowDelete(ctx *context.Context) {
+	rCtx, err := getRequiredWorkflowsCtx(ctx)
+	if err != nil {
+		ctx.ServerError("getRequiredWorkflowsCtx", err)
+		return
+	} else if ctx.Written() {
+		return
+	}
+
+	id := ctx.PathParamInt64("required_workflow_id")
+	if err := actions_model.DeleteRequiredWorkflow(ctx, rCtx.OwnerID, id); errors.Is(err, util.ErrNotExist) {
+		ctx.NotFound(nil)
+		return
+	} else if err != nil {
+		ctx.ServerError("DeleteRequiredWorkflow", err)
+		return
+	}
+	ctx.Flash.Success(ctx.Tr("actions.required_workflows.deletion.success"))
+	ctx.JSONRedirect(rCtx.RedirectLink)
+}
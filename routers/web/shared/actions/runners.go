@@ -212,6 +212,14 @@ func RunnersEdit(ctx *context.Context) {
 	}
 
 	ctx.Data["Runner"] = runner
+	if rCtx.IsAdmin && runner.OwnerID == 0 && runner.RepoID == 0 {
+		groups, err := actions_model.FindRunnerGroups(ctx)
+		if err != nil {
+			ctx.ServerError("FindRunnerGroups", err)
+			return
+		}
+		ctx.Data["RunnerGroups"] = groups
+	}
 
 	opts := actions_model.FindTaskOptions{
 		ListOptions: db.ListOptions{
@@ -274,6 +282,16 @@ func RunnersEditPost(ctx *context.Context) {
 		return
 	}
 
+	// only admins can move global runners into or out of runner groups
+	if rCtx.IsAdmin && runner.OwnerID == 0 && runner.RepoID == 0 && form.GroupID != runner.GroupID {
+		if err := actions_model.SetRunnerGroup(ctx, runner, form.GroupID); err != nil {
+			log.Warn("RunnerDetailsEditPost.SetRunnerGroup failed: %v, url: %s", err, ctx.Req.URL)
+			ctx.Flash.Warning(ctx.Tr("actions.runners.update_runner_failed"))
+			ctx.Redirect(redirectTo)
+			return
+		}
+	}
+
 	log.Debug("RunnerDetailsEditPost success: %s", ctx.Req.URL)
 
 	ctx.Flash.Success(ctx.Tr("actions.runners.update_runner_success"))
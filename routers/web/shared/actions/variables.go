@@ -19,6 +19,7 @@ package actions
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 
 	actions_model "github.com/kumose/kmup/models/actions"
@@ -26,6 +27,7 @@ import (
 	"github.com/kumose/kmup/modules/log"
 	"github.com/kumose/kmup/modules/setting"
 	"github.com/kumose/kmup/modules/templates"
+	"github.com/kumose/kmup/modules/util"
 	"github.com/kumose/kmup/modules/web"
 	shared_user "github.com/kumose/kmup/routers/web/shared/user"
 	actions_service "github.com/kumose/kmup/services/actions"
@@ -43,6 +45,7 @@ const (
 type variablesCtx struct {
 	OwnerID           int64
 	RepoID            int64
+	EnvironmentID     int64
 	IsRepo            bool
 	IsOrg             bool
 	IsUser            bool
@@ -53,13 +56,29 @@ type variablesCtx struct {
 
 func getVariablesCtx(ctx *context.Context) (*variablesCtx, error) {
 	if ctx.Data["PageIsRepoSettings"] == true {
-		return &variablesCtx{
+		vCtx := &variablesCtx{
 			OwnerID:           0,
 			RepoID:            ctx.Repo.Repository.ID,
 			IsRepo:            true,
 			VariablesTemplate: tplRepoVariables,
 			RedirectLink:      ctx.Repo.RepoLink + "/settings/actions/variables",
-		}, nil
+		}
+		// the variables of an environment
+		if envID := ctx.PathParamInt64("environment_id"); envID > 0 {
+			env, err := actions_model.GetEnvironmentByRepoIDAndID(ctx, ctx.Repo.Repository.ID, envID)
+			if errors.Is(err, util.ErrNotExist) {
+				ctx.NotFound(nil)
+				return nil, nil
+			} else if err != nil {
+				return nil, err
+			}
+			envLink := fmt.Sprintf("%s/settings/actions/environments/%d", ctx.Repo.RepoLink, env.ID)
+			ctx.Data["Environment"] = env
+			ctx.Data["EnvironmentLink"] = envLink
+			vCtx.EnvironmentID = env.ID
+			vCtx.RedirectLink = envLink + "/variables"
+		}
+		return vCtx, nil
 	}
 
 	if ctx.Data["PageIsOrgSettings"] == true {
@@ -102,17 +121,24 @@ func getVariablesCtx(ctx *context.Context) (*variablesCtx, error) {
 func Variables(ctx *context.Context) {
 	ctx.Data["Title"] = ctx.Tr("actions.variables")
 	ctx.Data["PageType"] = "variables"
-	ctx.Data["PageIsSharedSettingsVariables"] = true
 
 	vCtx, err := getVariablesCtx(ctx)
 	if err != nil {
 		ctx.ServerError("getVariablesCtx", err)
 		return
+	} else if ctx.Written() {
+		return
+	}
+	if vCtx.EnvironmentID > 0 {
+		ctx.Data["PageIsSharedSettingsEnvironments"] = true
+	} else {
+		ctx.Data["PageIsSharedSettingsVariables"] = true
 	}
 
 	variables, err := db.Find[actions_model.ActionVariable](ctx, actions_model.FindVariablesOpts{
-		OwnerID: vCtx.OwnerID,
-		RepoID:  vCtx.RepoID,
+		OwnerID:       vCtx.OwnerID,
+		RepoID:        vCtx.RepoID,
+		EnvironmentID: vCtx.EnvironmentID,
 	})
 	if err != nil {
 		ctx.ServerError("FindVariables", err)
@@ -129,6 +155,8 @@ func VariableCreate(ctx *context.Context) {
 	if err != nil {
 		ctx.ServerError("getVariablesCtx", err)
 		return
+	} else if ctx.Written() {
+		return
 	}
 
 	if ctx.HasError() { // form binding validation error
@@ -138,7 +166,12 @@ func VariableCreate(ctx *context.Context) {
 
 	form := web.GetForm(ctx).(*forms.EditVariableForm)
 
-	v, err := actions_service.CreateVariable(ctx, vCtx.OwnerID, vCtx.RepoID, form.Name, form.Data, form.Description)
+	var v *actions_model.ActionVariable
+	if vCtx.EnvironmentID > 0 {
+		v, err = actions_service.CreateEnvironmentVariable(ctx, vCtx.RepoID, vCtx.EnvironmentID, form.Name, form.Data, form.Description)
+	} else {
+		v, err = actions_service.CreateVariable(ctx, vCtx.OwnerID, vCtx.RepoID, form.Name, form.Data, form.Description)
+	}
 	if err != nil {
 		log.Error("CreateVariable: %v", err)
 		ctx.JSONError(ctx.Tr("actions.variables.creation.failed"))
@@ -154,6 +187,8 @@ func VariableUpdate(ctx *context.Context) {
 	if err != nil {
 		ctx.ServerError("getVariablesCtx", err)
 		return
+	} else if ctx.Written() {
+		return
 	}
 
 	if ctx.HasError() { // form binding validation error
@@ -189,6 +224,7 @@ func findActionsVariable(ctx *context.Context, id int64, vCtx *variablesCtx) *ac
 	switch {
 	case vCtx.IsRepo:
 		opts.RepoID = vCtx.RepoID
+		opts.EnvironmentID = vCtx.EnvironmentID
 		if opts.RepoID == 0 {
 			panic("RepoID is 0")
 		}
@@ -219,6 +255,8 @@ func VariableDelete(ctx *context.Context) {
 	if err != nil {
 		ctx.ServerError("getVariablesCtx", err)
 		return
+	} else if ctx.Written() {
+		return
 	}
 
 	id := ctx.PathParamInt64("variable_id")
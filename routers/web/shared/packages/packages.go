@@ -20,18 +20,21 @@ package packages
 import (
 	"fmt"
 	"net/http"
-	"time"
+	"strings"
 
+	"github.com/kumose/kmup/models/db"
 	packages_model "github.com/kumose/kmup/models/packages"
 	user_model "github.com/kumose/kmup/models/user"
+	"github.com/kumose/kmup/modules/glob"
 	"github.com/kumose/kmup/modules/log"
-	"github.com/kumose/kmup/modules/optional"
+	sigstore_module "github.com/kumose/kmup/modules/packages/sigstore"
 	"github.com/kumose/kmup/modules/templates"
 	"github.com/kumose/kmup/modules/web"
 	"github.com/kumose/kmup/services/context"
 	"github.com/kumose/kmup/services/forms"
 	cargo_service "github.com/kumose/kmup/services/packages/cargo"
-	container_service "github.com/kumose/kmup/services/packages/container"
+	packages_cleanup_service "github.com/kumose/kmup/services/packages/cleanup"
+	signing_service "github.com/kumose/kmup/services/packages/signing"
 )
 
 func SetPackagesContext(ctx *context.Context, owner *user_model.User) {
@@ -42,6 +45,30 @@ func SetPackagesContext(ctx *context.Context, owner *user_model.User) {
 	}
 
 	ctx.Data["CleanupRules"] = pcrs
+
+	prs, err := packages_model.GetRemotesByOwner(ctx, owner.ID)
+	if err != nil {
+		ctx.ServerError("GetRemotesByOwner", err)
+		return
+	}
+
+	ctx.Data["Remotes"] = prs
+
+	pss, err := packages_model.GetSignersByOwner(ctx, owner.ID)
+	if err != nil {
+		ctx.ServerError("GetSignersByOwner", err)
+		return
+	}
+
+	ctx.Data["Signers"] = pss
+
+	required, err := signing_service.IsSignatureRequired(ctx, owner.ID)
+	if err != nil {
+		ctx.ServerError("IsSignatureRequired", err)
+		return
+	}
+
+	ctx.Data["SignatureRequired"] = required
 }
 
 func SetRuleAddContext(ctx *context.Context) {
@@ -55,6 +82,14 @@ func SetRuleEditContext(ctx *context.Context, owner *user_model.User) {
 	}
 
 	setRuleEditContext(ctx, pcr)
+
+	runs, _, err := packages_model.GetCleanupRunsByRuleID(ctx, pcr.ID, db.ListOptions{PageSize: 10, Page: 1})
+	if err != nil {
+		ctx.ServerError("GetCleanupRunsByRuleID", err)
+		return
+	}
+
+	ctx.Data["CleanupRuns"] = runs
 }
 
 func setRuleEditContext(ctx *context.Context, pcr *packages_model.PackageCleanupRule) {
@@ -152,98 +187,316 @@ func SetRulePreviewContext(ctx *context.Context, owner *user_model.User) {
 		return
 	}
 
-	if err := pcr.CompiledPattern(); err != nil {
-		ctx.ServerError("CompiledPattern", err)
+	versionsToRemove, err := packages_cleanup_service.PreviewCleanupRule(ctx, pcr)
+	if err != nil {
+		ctx.ServerError("PreviewCleanupRule", err)
 		return
 	}
 
-	olderThan := time.Now().AddDate(0, 0, -pcr.RemoveDays)
+	ctx.Data["CleanupRule"] = pcr
+	ctx.Data["VersionsToRemove"] = versionsToRemove
+}
+
+func getCleanupRuleByContext(ctx *context.Context, owner *user_model.User) *packages_model.PackageCleanupRule {
+	id := ctx.FormInt64("id")
+	if id == 0 {
+		id = ctx.PathParamInt64("id")
+	}
 
-	packages, err := packages_model.GetPackagesByType(ctx, pcr.OwnerID, pcr.Type)
+	pcr, err := packages_model.GetCleanupRuleByID(ctx, id)
 	if err != nil {
-		ctx.ServerError("GetPackagesByType", err)
+		if err == packages_model.ErrPackageCleanupRuleNotExist {
+			ctx.NotFound(err)
+		} else {
+			ctx.ServerError("GetCleanupRuleByID", err)
+		}
+		return nil
+	}
+
+	if pcr != nil && pcr.OwnerID == owner.ID {
+		return pcr
+	}
+
+	ctx.NotFound(fmt.Errorf("PackageCleanupRule[%v] not associated to owner %v", id, owner))
+
+	return nil
+}
+
+func SetRemoteAddContext(ctx *context.Context) {
+	setRemoteEditContext(ctx, nil)
+}
+
+func SetRemoteEditContext(ctx *context.Context, owner *user_model.User) {
+	pr := getRemoteByContext(ctx, owner)
+	if pr == nil {
 		return
 	}
 
-	versionsToRemove := make([]*packages_model.PackageDescriptor, 0, 10)
+	setRemoteEditContext(ctx, pr)
+}
+
+func setRemoteEditContext(ctx *context.Context, pr *packages_model.PackageRemote) {
+	ctx.Data["IsEditRemote"] = pr != nil
+
+	if pr == nil {
+		pr = &packages_model.PackageRemote{Enabled: true, TTLMinutes: 30}
+	}
+	ctx.Data["Remote"] = pr
+	ctx.Data["AvailableTypes"] = packages_model.RemoteTypeList
+}
+
+func PerformRemoteAddPost(ctx *context.Context, owner *user_model.User, redirectURL string, template templates.TplName) {
+	performRemoteEditPost(ctx, owner, nil, redirectURL, template)
+}
 
-	for _, p := range packages {
-		pvs, _, err := packages_model.SearchVersions(ctx, &packages_model.PackageSearchOptions{
-			PackageID:  p.ID,
-			IsInternal: optional.Some(false),
-			Sort:       packages_model.SortCreatedDesc,
-		})
-		if err != nil {
-			ctx.ServerError("SearchVersions", err)
+func PerformRemoteEditPost(ctx *context.Context, owner *user_model.User, redirectURL string, template templates.TplName) {
+	pr := getRemoteByContext(ctx, owner)
+	if pr == nil {
+		return
+	}
+
+	form := web.GetForm(ctx).(*forms.PackageRemoteForm)
+
+	if form.Action == "remove" {
+		if err := packages_model.DeleteRemoteByID(ctx, pr.ID); err != nil {
+			ctx.ServerError("DeleteRemoteByID", err)
 			return
 		}
-		if pcr.KeepCount > 0 {
-			if pcr.KeepCount < len(pvs) {
-				pvs = pvs[pcr.KeepCount:]
-			} else {
-				pvs = nil
-			}
+
+		ctx.Flash.Success(ctx.Tr("packages.owner.settings.remotes.success.delete"))
+		ctx.Redirect(redirectURL)
+	} else {
+		performRemoteEditPost(ctx, owner, pr, redirectURL, template)
+	}
+}
+
+func performRemoteEditPost(ctx *context.Context, owner *user_model.User, pr *packages_model.PackageRemote, redirectURL string, template templates.TplName) {
+	isEditRemote := pr != nil
+
+	if pr == nil {
+		pr = &packages_model.PackageRemote{}
+	}
+
+	form := web.GetForm(ctx).(*forms.PackageRemoteForm)
+
+	pr.Enabled = form.Enabled
+	pr.OwnerID = owner.ID
+	pr.URL = strings.TrimSuffix(form.URL, "/")
+	pr.TTLMinutes = form.TTLMinutes
+	pr.KeepCount = form.KeepCount
+	pr.RemoveDays = form.RemoveDays
+
+	// an empty password keeps the stored one unless the credentials are removed
+	if pr.Username != form.Username || form.Password != "" || form.Username == "" {
+		if err := pr.SetPassword(form.Password); err != nil {
+			ctx.ServerError("SetPassword", err)
+			return
+		}
+	}
+	pr.Username = form.Username
+
+	ctx.Data["IsEditRemote"] = isEditRemote
+	ctx.Data["Remote"] = pr
+	ctx.Data["AvailableTypes"] = packages_model.RemoteTypeList
+
+	if ctx.HasError() {
+		ctx.HTML(http.StatusOK, template)
+		return
+	}
+
+	if isEditRemote {
+		if err := packages_model.UpdateRemote(ctx, pr); err != nil {
+			ctx.ServerError("UpdateRemote", err)
+			return
 		}
-		for _, pv := range pvs {
-			if skip, err := container_service.ShouldBeSkipped(ctx, pcr, p, pv); err != nil {
-				ctx.ServerError("ShouldBeSkipped", err)
-				return
-			} else if skip {
-				continue
-			}
-
-			toMatch := pv.LowerVersion
-			if pcr.MatchFullName {
-				toMatch = p.LowerName + "/" + pv.LowerVersion
-			}
-			if pcr.KeepPatternMatcher != nil && pcr.KeepPatternMatcher.MatchString(toMatch) {
-				continue
-			}
-			if pv.CreatedUnix.AsLocalTime().After(olderThan) {
-				continue
-			}
-			if pcr.RemovePatternMatcher != nil && !pcr.RemovePatternMatcher.MatchString(toMatch) {
-				continue
-			}
-
-			pd, err := packages_model.GetPackageDescriptor(ctx, pv)
-			if err != nil {
-				ctx.ServerError("GetPackageDescriptor", err)
-				return
-			}
-			versionsToRemove = append(versionsToRemove, pd)
+	} else {
+		pr.Type = packages_model.Type(form.Type)
+
+		if has, err := packages_model.HasOwnerRemoteForPackageType(ctx, owner.ID, pr.Type); err != nil {
+			ctx.ServerError("HasOwnerRemoteForPackageType", err)
+			return
+		} else if has {
+			ctx.Data["Err_Type"] = true
+			ctx.HTML(http.StatusOK, template)
+			return
+		}
+
+		var err error
+		if pr, err = packages_model.InsertRemote(ctx, pr); err != nil {
+			ctx.ServerError("InsertRemote", err)
+			return
 		}
 	}
 
-	ctx.Data["CleanupRule"] = pcr
-	ctx.Data["VersionsToRemove"] = versionsToRemove
+	ctx.Flash.Success(ctx.Tr("packages.owner.settings.remotes.success.update"))
+	ctx.Redirect(fmt.Sprintf("%s/remotes/%d", redirectURL, pr.ID))
 }
 
-func getCleanupRuleByContext(ctx *context.Context, owner *user_model.User) *packages_model.PackageCleanupRule {
+func getRemoteByContext(ctx *context.Context, owner *user_model.User) *packages_model.PackageRemote {
 	id := ctx.FormInt64("id")
 	if id == 0 {
 		id = ctx.PathParamInt64("id")
 	}
 
-	pcr, err := packages_model.GetCleanupRuleByID(ctx, id)
+	pr, err := packages_model.GetRemoteByID(ctx, id)
 	if err != nil {
-		if err == packages_model.ErrPackageCleanupRuleNotExist {
+		if err == packages_model.ErrPackageRemoteNotExist {
 			ctx.NotFound(err)
 		} else {
-			ctx.ServerError("GetCleanupRuleByID", err)
+			ctx.ServerError("GetRemoteByID", err)
 		}
 		return nil
 	}
 
-	if pcr != nil && pcr.OwnerID == owner.ID {
-		return pcr
+	if pr != nil && pr.OwnerID == owner.ID {
+		return pr
 	}
 
-	ctx.NotFound(fmt.Errorf("PackageCleanupRule[%v] not associated to owner %v", id, owner))
+	ctx.NotFound(fmt.Errorf("PackageRemote[%v] not associated to owner %v", id, owner))
+
+	return nil
+}
+
+func SetSignerAddContext(ctx *context.Context) {
+	setSignerEditContext(ctx, nil)
+}
+
+func SetSignerEditContext(ctx *context.Context, owner *user_model.User) {
+	ps := getSignerByContext(ctx, owner)
+	if ps == nil {
+		return
+	}
+
+	setSignerEditContext(ctx, ps)
+}
+
+func setSignerEditContext(ctx *context.Context, ps *packages_model.PackageSigner) {
+	ctx.Data["IsEditSigner"] = ps != nil
+
+	if ps == nil {
+		ps = &packages_model.PackageSigner{}
+	}
+	ctx.Data["Signer"] = ps
+}
+
+func PerformSignerAddPost(ctx *context.Context, owner *user_model.User, redirectURL string, template templates.TplName) {
+	performSignerEditPost(ctx, owner, nil, redirectURL, template)
+}
+
+func PerformSignerEditPost(ctx *context.Context, owner *user_model.User, redirectURL string, template templates.TplName) {
+	ps := getSignerByContext(ctx, owner)
+	if ps == nil {
+		return
+	}
+
+	form := web.GetForm(ctx).(*forms.PackageSignerForm)
+
+	if form.Action == "remove" {
+		if err := packages_model.DeleteSignerByID(ctx, ps.ID); err != nil {
+			ctx.ServerError("DeleteSignerByID", err)
+			return
+		}
+
+		ctx.Flash.Success(ctx.Tr("packages.owner.settings.signers.success.delete"))
+		ctx.Redirect(redirectURL)
+	} else {
+		performSignerEditPost(ctx, owner, ps, redirectURL, template)
+	}
+}
+
+func performSignerEditPost(ctx *context.Context, owner *user_model.User, ps *packages_model.PackageSigner, redirectURL string, template templates.TplName) {
+	isEditSigner := ps != nil
+
+	if ps == nil {
+		ps = &packages_model.PackageSigner{}
+	}
+
+	form := web.GetForm(ctx).(*forms.PackageSignerForm)
+
+	ps.OwnerID = owner.ID
+	ps.Name = form.Name
+	ps.PublicKey = strings.TrimSpace(form.PublicKey)
+	ps.Identity = form.Identity
+	ps.Issuer = form.Issuer
+
+	ctx.Data["IsEditSigner"] = isEditSigner
+	ctx.Data["Signer"] = ps
+
+	if ctx.HasError() {
+		ctx.HTML(http.StatusOK, template)
+		return
+	}
+
+	if (ps.PublicKey == "") == (ps.Identity == "") {
+		ctx.Data["Err_PublicKey"] = true
+		ctx.Data["Err_Identity"] = true
+		ctx.RenderWithErr(ctx.Tr("packages.owner.settings.signers.error.key_or_identity"), template, form)
+		return
+	}
+	if ps.PublicKey != "" {
+		if _, err := sigstore_module.ParsePublicKey(ps.PublicKey); err != nil {
+			ctx.Data["Err_PublicKey"] = true
+			ctx.RenderWithErr(ctx.Tr("packages.owner.settings.signers.error.public_key"), template, form)
+			return
+		}
+		ps.Issuer = ""
+	} else if _, err := glob.Compile(ps.Identity); err != nil {
+		ctx.Data["Err_Identity"] = true
+		ctx.RenderWithErr(ctx.Tr("packages.owner.settings.signers.error.identity"), template, form)
+		return
+	}
+
+	if isEditSigner {
+		if err := packages_model.UpdateSigner(ctx, ps); err != nil {
+			ctx.ServerError("UpdateSigner", err)
+			return
+		}
+	} else {
+		var err error
+		if ps, err = packages_model.InsertSigner(ctx, ps); err != nil {
+			ctx.ServerError("InsertSigner", err)
+			return
+		}
+	}
+
+	ctx.Flash.Success(ctx.Tr("packages.owner.settings.signers.success.update"))
+	ctx.Redirect(fmt.Sprintf("%s/signers/%d", redirectURL, ps.ID))
+}
+
+func getSignerByContext(ctx *context.Context, owner *user_model.User) *packages_model.PackageSigner {
+	id := ctx.FormInt64("id")
+	if id == 0 {
+		id = ctx.PathParamInt64("id")
+	}
+
+	ps, err := packages_model.GetSignerByID(ctx, id)
+	if err != nil {
+		if err == packages_model.ErrPackageSignerNotExist {
+			ctx.NotFound(err)
+		} else {
+			ctx.ServerError("GetSignerByID", err)
+		}
+		return nil
+	}
+
+	if ps != nil && ps.OwnerID == owner.ID {
+		return ps
+	}
+
+	ctx.NotFound(fmt.Errorf("PackageSigner[%v] not associated to owner %v", id, owner))
 
 	return nil
 }
 
+func SetSignaturePolicy(ctx *context.Context, owner *user_model.User) {
+	if err := signing_service.SetSignatureRequired(ctx, owner.ID, ctx.FormBool("signature_required")); err != nil {
+		ctx.ServerError("SetSignatureRequired", err)
+		return
+	}
+
+	ctx.Flash.Success(ctx.Tr("packages.owner.settings.signing.success"))
+}
+
 func InitializeCargoIndex(ctx *context.Context, owner *user_model.User) {
 	err := cargo_service.InitializeIndexRepository(ctx, owner, owner)
 	if err != nil {
@@ -0,0 +1,180 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package pushrule
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	git_model "github.com/kumose/kmup/models/git"
+	"github.com/kumose/kmup/modules/setting"
+	"github.com/kumose/kmup/modules/templates"
+	"github.com/kumose/kmup/modules/util"
+	"github.com/kumose/kmup/modules/web"
+	shared_user "github.com/kumose/kmup/routers/web/shared/user"
+	"github.com/kumose/kmup/services/context"
+	"github.com/kumose/kmup/services/forms"
+)
+
+const (
+	tplRepoPushRules  templates.TplName = "repo/settings/push_rules"
+	tplOrgPushRules   templates.TplName = "org/settings/push_rules"
+	tplAdminPushRules templates.TplName = "admin/push_rules"
+)
+
+type pushRulesCtx struct {
+	OwnerID      int64
+	RepoID       int64
+	IsRepo       bool
+	IsOrg        bool
+	IsGlobal     bool
+	Template     templates.TplName
+	RedirectLink string
+}
+
+func getPushRulesCtx(ctx *context.Context) (*pushRulesCtx, error) {
+	if ctx.Data["PageIsRepoSettings"] == true {
+		return &pushRulesCtx{
+			RepoID:       ctx.Repo.Repository.ID,
+			IsRepo:       true,
+			Template:     tplRepoPushRules,
+			RedirectLink: ctx.Repo.RepoLink + "/settings/push_rules",
+		}, nil
+	}
+
+	if ctx.Data["PageIsOrgSettings"] == true {
+		if _, err := shared_user.RenderUserOrgHeader(ctx); err != nil {
+			ctx.ServerError("RenderUserOrgHeader", err)
+			return nil, nil
+		}
+		return &pushRulesCtx{
+			OwnerID:      ctx.ContextUser.ID,
+			IsOrg:        true,
+			Template:     tplOrgPushRules,
+			RedirectLink: ctx.Org.OrgLink + "/settings/push_rules",
+		}, nil
+	}
+
+	if ctx.Data["PageIsAdmin"] == true {
+		return &pushRulesCtx{
+			IsGlobal:     true,
+			Template:     tplAdminPushRules,
+			RedirectLink: setting.AppSubURL + "/-/admin/push_rules",
+		}, nil
+	}
+
+	return nil, errors.New("unable to set PushRules context")
+}
+
+// PushRules shows the push rule of the repo, the org or the instance
+func PushRules(ctx *context.Context) {
+	ctx.Data["Title"] = ctx.Tr("repo.settings.push_rules")
+	ctx.Data["PageIsSharedSettingsPushRules"] = true
+
+	pCtx, err := getPushRulesCtx(ctx)
+	if err != nil {
+		ctx.ServerError("getPushRulesCtx", err)
+		return
+	} else if ctx.Written() {
+		return
+	}
+
+	rule, err := git_model.GetPushRule(ctx, pCtx.OwnerID, pCtx.RepoID)
+	if errors.Is(err, util.ErrNotExist) {
+		rule = &git_model.PushRule{OwnerID: pCtx.OwnerID, RepoID: pCtx.RepoID}
+	} else if err != nil {
+		ctx.ServerError("GetPushRule", err)
+		return
+	}
+	ctx.Data["PushRule"] = rule
+	ctx.Data["PushRuleExists"] = rule.ID != 0
+	ctx.Data["IsGlobal"] = pCtx.IsGlobal
+	ctx.Data["IsOrg"] = pCtx.IsOrg
+	ctx.HTML(http.StatusOK, pCtx.Template)
+}
+
+func splitList(s, sep string) []string {
+	var list []string
+	for item := range strings.SplitSeq(s, sep) {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// PushRulesPost creates or replaces the push rule of the repo, the org or the instance
+func PushRulesPost(ctx *context.Context) {
+	pCtx, err := getPushRulesCtx(ctx)
+	if err != nil {
+		ctx.ServerError("getPushRulesCtx", err)
+		return
+	} else if ctx.Written() {
+		return
+	}
+
+	if ctx.HasError() { // form binding validation error
+		ctx.Flash.Error(ctx.GetErrMsg())
+		ctx.Redirect(pCtx.RedirectLink)
+		return
+	}
+
+	form := web.GetForm(ctx).(*forms.PushRuleForm)
+	rule := &git_model.PushRule{
+		OwnerID:              pCtx.OwnerID,
+		RepoID:               pCtx.RepoID,
+		MaxFileSize:          form.MaxFileSize,
+		ForbiddenPaths:       splitList(form.ForbiddenPaths, "\n"),
+		ForbiddenExtensions:  splitList(form.ForbiddenExtensions, ","),
+		CommitMessagePattern: form.CommitMessagePattern,
+		AuthorEmailDomains:   splitList(form.AuthorEmailDomains, ","),
+		RejectMergeCommits:   form.RejectMergeCommits,
+		RejectSecrets:        form.RejectSecrets,
+	}
+	if err := git_model.SetPushRule(ctx, rule); errors.Is(err, util.ErrInvalidArgument) {
+		ctx.Flash.Error(ctx.Tr("repo.settings.push_rules.update_failed", err.Error()))
+		ctx.Redirect(pCtx.RedirectLink)
+		return
+	} else if err != nil {
+		ctx.ServerError("SetPushRule", err)
+		return
+	}
+
+	ctx.Flash.Success(ctx.Tr("repo.settings.push_rules.update_success"))
+	ctx.Redirect(pCtx.RedirectLink)
+}
+
+// PushRulesDelete deletes the push rule of the repo, the org or the instance
+func PushRulesDelete(ctx *context.Context) {
+	pCtx, err := getPushRulesCtx(ctx)
+	if err != nil {
+		ctx.ServerError("getPushRulesCtx", err)
+		return
+	} else if ctx.Written() {
+		return
+	}
+
+	if err := git_model.DeletePushRule(ctx, pCtx.OwnerID, pCtx.RepoID); err != nil && !errors.Is(err, util.ErrNotExist) {
+		ctx.ServerError("DeletePushRule", err)
+		return
+	}
+
+	ctx.Flash.Success(ctx.Tr("repo.settings.push_rules.deletion_success"))
+	ctx.JSONRedirect(pCtx.RedirectLink)
+}
@@ -28,8 +28,8 @@ import (
 	secret_service "github.com/kumose/kmup/services/secrets"
 )
 
-func SetSecretsContext(ctx *context.Context, ownerID, repoID int64) {
-	secrets, err := db.Find[secret_model.Secret](ctx, secret_model.FindSecretsOptions{OwnerID: ownerID, RepoID: repoID})
+func SetSecretsContext(ctx *context.Context, ownerID, repoID, environmentID int64) {
+	secrets, err := db.Find[secret_model.Secret](ctx, secret_model.FindSecretsOptions{OwnerID: ownerID, RepoID: repoID, EnvironmentID: environmentID})
 	if err != nil {
 		ctx.ServerError("FindSecrets", err)
 		return
@@ -40,10 +40,16 @@ func SetSecretsContext(ctx *context.Context, ownerID, repoID int64) {
 	ctx.Data["DescriptionMaxLength"] = secret_model.SecretDescriptionMaxLength
 }
 
-func PerformSecretsPost(ctx *context.Context, ownerID, repoID int64, redirectURL string) {
+func PerformSecretsPost(ctx *context.Context, ownerID, repoID, environmentID int64, redirectURL string) {
 	form := web.GetForm(ctx).(*forms.AddSecretForm)
 
-	s, _, err := secret_service.CreateOrUpdateSecret(ctx, ownerID, repoID, form.Name, util.ReserveLineBreakForTextarea(form.Data), form.Description)
+	var s *secret_model.Secret
+	var err error
+	if environmentID > 0 {
+		s, _, err = secret_service.CreateOrUpdateEnvironmentSecret(ctx, repoID, environmentID, form.Name, util.ReserveLineBreakForTextarea(form.Data), form.Description)
+	} else {
+		s, _, err = secret_service.CreateOrUpdateSecret(ctx, ownerID, repoID, form.Name, util.ReserveLineBreakForTextarea(form.Data), form.Description)
+	}
 	if err != nil {
 		log.Error("CreateOrUpdateSecret failed: %v", err)
 		ctx.JSONError(ctx.Tr("secrets.save_failed"))
@@ -54,10 +60,15 @@ func PerformSecretsPost(ctx *context.Context, ownerID, repoID int64, redirectURL
 	ctx.JSONRedirect(redirectURL)
 }
 
-func PerformSecretsDelete(ctx *context.Context, ownerID, repoID int64, redirectURL string) {
+func PerformSecretsDelete(ctx *context.Context, ownerID, repoID, environmentID int64, redirectURL string) {
 	id := ctx.FormInt64("id")
 
-	err := secret_service.DeleteSecretByID(ctx, ownerID, repoID, id)
+	var err error
+	if environmentID > 0 {
+		err = secret_service.DeleteEnvironmentSecretByID(ctx, repoID, environmentID, id)
+	} else {
+		err = secret_service.DeleteSecretByID(ctx, ownerID, repoID, id)
+	}
 	if err != nil {
 		log.Error("DeleteSecretByID(%d) failed: %v", id, err)
 		ctx.JSONError(ctx.Tr("secrets.deletion.failed"))
@@ -0,0 +1,59 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package user
+
+import (
+	"net/http"
+
+	repo_model "github.com/kumose/kmup/models/repo"
+	actions_indexer "github.com/kumose/kmup/modules/indexer/actions"
+	"github.com/kumose/kmup/modules/templates"
+	shared_actions "github.com/kumose/kmup/routers/web/shared/actions"
+	shared_user "github.com/kumose/kmup/routers/web/shared/user"
+	"github.com/kumose/kmup/services/context"
+)
+
+const tplUserActionsLogsSearch templates.TplName = "user/actions_logs_search"
+
+// ActionsLogsSearch renders the page to search the actions logs of all repositories of the user or organization
+func ActionsLogsSearch(ctx *context.Context) {
+	if !actions_indexer.IsEnabled() {
+		ctx.NotFound(nil)
+		return
+	}
+	if _, err := shared_user.RenderUserOrgHeader(ctx); err != nil {
+		ctx.ServerError("RenderUserOrgHeader", err)
+		return
+	}
+
+	ctx.Data["Title"] = ctx.Tr("actions.logs_search")
+	ctx.Data["IsActionsLogsSearchPage"] = true
+
+	repoIDs, err := repo_model.FindUserActionsAccessibleOwnerRepoIDs(ctx, ctx.ContextUser.ID, ctx.Doer)
+	if err != nil {
+		ctx.ServerError("FindUserActionsAccessibleOwnerRepoIDs", err)
+		return
+	}
+
+	shared_actions.SearchLogs(ctx, repoIDs)
+	if ctx.Written() {
+		return
+	}
+
+	ctx.HTML(http.StatusOK, tplUserActionsLogsSearch)
+}
@@ -49,6 +49,10 @@ import (
 	"github.com/kumose/kmup/services/forms"
 	packages_service "github.com/kumose/kmup/services/packages"
 	container_service "github.com/kumose/kmup/services/packages/container"
+	signing_service "github.com/kumose/kmup/services/packages/signing"
+	vulnerability_service "github.com/kumose/kmup/services/packages/vulnerability"
+
+	"github.com/opencontainers/go-digest"
 )
 
 const (
@@ -198,6 +202,65 @@ func viewPackageContainerImage(ctx gocontext.Context, pd *packages_model.Package
 	return metadata, err
 }
 
+type containerReferrer struct {
+	Digest       string
+	ArtifactType string
+	Size         int64
+	Link         string
+}
+
+// containerManifestLink returns the link to a version of the package which contains the manifest
+func containerManifestLink(ctx gocontext.Context, pd *packages_model.PackageDescriptor, manifestDigest string) (string, error) {
+	pvs, err := container_model.GetManifestVersions(ctx, &container_model.BlobSearchOptions{
+		OwnerID:    pd.Owner.ID,
+		Image:      pd.Package.LowerName,
+		Digest:     manifestDigest,
+		IsManifest: true,
+	})
+	if err != nil || len(pvs) == 0 {
+		return "", err
+	}
+	return pd.PackageWebLink() + "/" + url.PathEscape(pvs[0].LowerVersion), nil
+}
+
+// prepareContainerReferrers loads the subject and the referrers of a container manifest
+func prepareContainerReferrers(ctx *context.Context, pd *packages_model.PackageDescriptor) error {
+	metadata := pd.Metadata.(*container_module.Metadata)
+	if metadata.Subject != "" {
+		link, err := containerManifestLink(ctx, pd, metadata.Subject)
+		if err != nil {
+			return err
+		}
+		ctx.Data["ContainerSubjectLink"] = link
+	}
+
+	for _, pfd := range pd.Files {
+		if !pfd.File.IsLead || pfd.File.LowerName != container_module.ManifestFilename {
+			continue
+		}
+		descriptors, err := container_service.GetReferrers(ctx, pd.Package, digest.Digest(pfd.Properties.GetByName(container_module.PropertyDigest)), "")
+		if err != nil {
+			return err
+		}
+		referrers := make([]*containerReferrer, 0, len(descriptors))
+		for _, desc := range descriptors {
+			link, err := containerManifestLink(ctx, pd, desc.Digest.String())
+			if err != nil {
+				return err
+			}
+			referrers = append(referrers, &containerReferrer{
+				Digest:       desc.Digest.String(),
+				ArtifactType: desc.ArtifactType,
+				Size:         desc.Size,
+				Link:         link,
+			})
+		}
+		ctx.Data["ContainerReferrers"] = referrers
+		break
+	}
+	return nil
+}
+
 // ViewPackageVersion displays a single package version
 func ViewPackageVersion(ctx *context.Context) {
 	if _, err := shared_user.RenderUserOrgHeader(ctx); err != nil {
@@ -307,6 +370,11 @@ func ViewPackageVersion(ctx *context.Context) {
 			}
 		}
 		ctx.Data["ContainerImageMetadata"] = imageMetadata
+
+		if err := prepareContainerReferrers(ctx, pd); err != nil {
+			ctx.ServerError("prepareContainerReferrers", err)
+			return
+		}
 	}
 	var pvs []*packages_model.PackageVersion
 	var pvsTotal int64
@@ -330,6 +398,27 @@ func ViewPackageVersion(ctx *context.Context) {
 	ctx.Data["LatestVersions"] = pvs
 	ctx.Data["TotalVersionCount"] = pvsTotal
 
+	verification, err := signing_service.GetVersionVerification(ctx, pd)
+	if err != nil {
+		ctx.ServerError("GetVersionVerification", err)
+		return
+	}
+	ctx.Data["PackageVerification"] = verification
+
+	signatureRequired, err := signing_service.IsSignatureRequired(ctx, pd.Owner.ID)
+	if err != nil {
+		ctx.ServerError("IsSignatureRequired", err)
+		return
+	}
+	ctx.Data["PackageSignatureRequired"] = signatureRequired
+
+	vulnerabilities, err := vulnerability_service.GetVersionVulnerabilities(ctx, pd)
+	if err != nil {
+		ctx.ServerError("GetVersionVulnerabilities", err)
+		return
+	}
+	ctx.Data["PackageVulnerabilities"] = vulnerabilities
+
 	ctx.Data["CanWritePackages"] = ctx.Package.AccessMode >= perm.AccessModeWrite || ctx.IsUserSiteAdmin()
 
 	hasRepositoryAccess := false
@@ -506,6 +595,9 @@ func packageSettingsPostActionLink(ctx *context.Context, form *forms.PackageSett
 
 func packageSettingsPostActionDelete(ctx *context.Context) {
 	err := packages_service.RemovePackageVersion(ctx, ctx.Doer, ctx.Package.Descriptor.Version)
+	if err == nil && ctx.Package.Descriptor.Package.Type == packages_model.TypeContainer {
+		err = container_service.RemoveOrphanedReferrers(ctx, ctx.Doer, ctx.Package.Descriptor.Package.ID)
+	}
 	if err != nil {
 		log.Error("Error deleting package: %v", err)
 		ctx.Flash.Error(ctx.Tr("packages.settings.delete.error"))
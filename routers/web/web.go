@@ -53,6 +53,7 @@ import (
 	repo_setting "github.com/kumose/kmup/routers/web/repo/setting"
 	shared_actions "github.com/kumose/kmup/routers/web/shared/actions"
 	"github.com/kumose/kmup/routers/web/shared/project"
+	shared_pushrule "github.com/kumose/kmup/routers/web/shared/pushrule"
 	"github.com/kumose/kmup/routers/web/user"
 	user_setting "github.com/kumose/kmup/routers/web/user/setting"
 	"github.com/kumose/kmup/routers/web/user/setting/security"
@@ -474,6 +475,22 @@ func registerWebRoutes(m *web.Router) {
 		})
 	}
 
+	addSettingsRequiredWorkflowsRoutes := func() {
+		m.Group("/required_workflows", func() {
+			m.Get("", shared_actions.RequiredWorkflows)
+			m.Post("/new", web.Bind(forms.RequiredWorkflowForm{}), shared_actions.RequiredWorkflowCreate)
+			m.Post("/{required_workflow_id}/delete", shared_actions.RequiredWorkflowDelete)
+		})
+	}
+
+	addSettingsPushRulesRoutes := func() {
+		m.Group("/push_rules", func() {
+			m.Get("", shared_pushrule.PushRules)
+			m.Post("", web.Bind(forms.PushRuleForm{}), shared_pushrule.PushRulesPost)
+			m.Post("/delete", shared_pushrule.PushRulesDelete)
+		})
+	}
+
 	addSettingsRunnersRoutes := func() {
 		m.Group("/runners", func() {
 			m.Get("", shared_actions.Runners)
@@ -499,6 +516,7 @@ func registerWebRoutes(m *web.Router) {
 			ctx.Redirect(setting.AppSubURL + "/user/settings/account")
 		})
 		m.Get("/passkey-endpoints", passkeyEndpoints)
+		m.Get("/terraform.json", packagesEnabled, TerraformServiceDiscovery)
 		m.Methods("GET, HEAD", "/*", public.FileHandlerFunc())
 	}, optionsCorsHandler())
 
@@ -668,6 +686,27 @@ func registerWebRoutes(m *web.Router) {
 					m.Get("/preview", user_setting.PackagesRulePreview)
 				})
 			})
+			m.Group("/remotes", func() {
+				m.Group("/add", func() {
+					m.Get("", user_setting.PackagesRemoteAdd)
+					m.Post("", web.Bind(forms.PackageRemoteForm{}), user_setting.PackagesRemoteAddPost)
+				})
+				m.Group("/{id}", func() {
+					m.Get("", user_setting.PackagesRemoteEdit)
+					m.Post("", web.Bind(forms.PackageRemoteForm{}), user_setting.PackagesRemoteEditPost)
+				})
+			})
+			m.Group("/signers", func() {
+				m.Group("/add", func() {
+					m.Get("", user_setting.PackagesSignerAdd)
+					m.Post("", web.Bind(forms.PackageSignerForm{}), user_setting.PackagesSignerAddPost)
+				})
+				m.Group("/{id}", func() {
+					m.Get("", user_setting.PackagesSignerEdit)
+					m.Post("", web.Bind(forms.PackageSignerForm{}), user_setting.PackagesSignerEditPost)
+				})
+			})
+			m.Post("/signing", user_setting.PackagesSigningPost)
 			m.Group("/cargo", func() {
 				m.Post("/initialize", user_setting.InitializeCargoIndex)
 				m.Post("/rebuild", user_setting.RebuildCargoIndex)
@@ -812,6 +851,8 @@ func registerWebRoutes(m *web.Router) {
 			m.Post("/{authid}/delete", admin.DeleteAuthSource)
 		})
 
+		addSettingsPushRulesRoutes()
+
 		m.Group("/notices", func() {
 			m.Get("", admin.Notices)
 			m.Post("/delete", admin.DeleteNotices)
@@ -831,7 +872,15 @@ func registerWebRoutes(m *web.Router) {
 		m.Group("/actions", func() {
 			m.Get("", admin.RedirectToDefaultSetting)
 			addSettingsRunnersRoutes()
+			m.Group("/runner_groups", func() {
+				m.Get("", admin.RunnerGroups)
+				m.Post("/new", web.Bind(forms.RunnerGroupForm{}), admin.RunnerGroupCreate)
+				m.Combo("/{group_id}").Get(admin.RunnerGroupEdit).
+					Post(web.Bind(forms.RunnerGroupForm{}), admin.RunnerGroupEditPost)
+				m.Post("/{group_id}/delete", admin.RunnerGroupDelete)
+			})
 			addSettingsVariablesRoutes()
+			addSettingsRequiredWorkflowsRoutes()
 		})
 	}, adminReq, ctxDataSet("EnableOAuth2", setting.OAuth2.Enabled, "EnablePackages", setting.Packages.Enabled))
 	// ***** END: Admin *****
@@ -981,8 +1030,20 @@ func registerWebRoutes(m *web.Router) {
 					addSettingsRunnersRoutes()
 					addSettingsSecretsRoutes()
 					addSettingsVariablesRoutes()
+					addSettingsRequiredWorkflowsRoutes()
 				}, actions.MustEnableActions)
 
+				addSettingsPushRulesRoutes()
+
+				m.Group("/rulesets", func() {
+					m.Get("", org.Rulesets)
+					m.Combo("/new").Get(org.RulesetNew).Post(web.Bind(forms.RulesetForm{}), org.RulesetNewPost)
+					m.Group("/{id}", func() {
+						m.Combo("").Get(org.RulesetEdit).Post(web.Bind(forms.RulesetForm{}), org.RulesetEditPost)
+						m.Post("/delete", org.RulesetDelete)
+					})
+				})
+
 				m.Post("/rename", web.Bind(forms.RenameOrgForm{}), org.SettingsRenamePost)
 				m.Post("/delete", org.SettingsDeleteOrgPost)
 				m.Post("/visibility", org.SettingsChangeVisibilityPost)
@@ -1000,6 +1061,27 @@ func registerWebRoutes(m *web.Router) {
 							m.Get("/preview", org.PackagesRulePreview)
 						})
 					})
+					m.Group("/remotes", func() {
+						m.Group("/add", func() {
+							m.Get("", org.PackagesRemoteAdd)
+							m.Post("", web.Bind(forms.PackageRemoteForm{}), org.PackagesRemoteAddPost)
+						})
+						m.Group("/{id}", func() {
+							m.Get("", org.PackagesRemoteEdit)
+							m.Post("", web.Bind(forms.PackageRemoteForm{}), org.PackagesRemoteEditPost)
+						})
+					})
+					m.Group("/signers", func() {
+						m.Group("/add", func() {
+							m.Get("", org.PackagesSignerAdd)
+							m.Post("", web.Bind(forms.PackageSignerForm{}), org.PackagesSignerAddPost)
+						})
+						m.Group("/{id}", func() {
+							m.Get("", org.PackagesSignerEdit)
+							m.Post("", web.Bind(forms.PackageSignerForm{}), org.PackagesSignerEditPost)
+						})
+					})
+					m.Post("/signing", org.PackagesSigningPost)
 					m.Group("/cargo", func() {
 						m.Post("/initialize", org.InitializeCargoIndex)
 						m.Post("/rebuild", org.RebuildCargoIndex)
@@ -1082,6 +1164,10 @@ func registerWebRoutes(m *web.Router) {
 		m.Group("", func() {
 			m.Get("/code", user.CodeSearch)
 		}, reqUnitAccess(unit.TypeCode, perm.AccessModeRead, false), individualPermsChecker)
+
+		m.Group("", func() {
+			m.Get("/actions/logs", user.ActionsLogsSearch)
+		}, reqUnitAccess(unit.TypeActions, perm.AccessModeRead, false), individualPermsChecker)
 	}, optSignIn, context.UserAssignmentWeb(), context.OrgAssignment(context.OrgAssignmentOptions{}))
 	// end "/{username}/-": packages, projects, code
 
@@ -1132,6 +1218,15 @@ func registerWebRoutes(m *web.Router) {
 			m.Post("/{id}", web.Bind(forms.ProtectTagForm{}), context.RepoMustNotBeArchived(), repo_setting.EditProtectedTagPost)
 		})
 
+		addSettingsPushRulesRoutes()
+
+		m.Group("/secret_scanning", func() {
+			m.Combo("").Get(repo_setting.SecretScanning).
+				Post(web.Bind(forms.SecretScanningForm{}), repo_setting.SecretScanningPost)
+			m.Post("/scan", repo_setting.SecretScanningScanPost)
+			m.Post("/alerts/{id}", web.Bind(forms.SecretScanningAlertForm{}), repo_setting.SecretScanningAlertPost)
+		})
+
 		m.Group("/hooks/git", func() {
 			m.Get("", repo_setting.GitHooks)
 			m.Combo("/{name}").Get(repo_setting.GitHooksEdit).
@@ -1178,6 +1273,17 @@ func registerWebRoutes(m *web.Router) {
 			addSettingsRunnersRoutes()
 			addSettingsSecretsRoutes()
 			addSettingsVariablesRoutes()
+			m.Group("/environments", func() {
+				m.Get("", repo_setting.Environments)
+				m.Post("/new", repo_setting.EnvironmentCreate)
+				m.Group("/{environment_id}", func() {
+					m.Combo("").Get(repo_setting.EnvironmentEdit).
+						Post(web.Bind(forms.EditEnvironmentForm{}), repo_setting.EnvironmentEditPost)
+					m.Post("/delete", repo_setting.EnvironmentDelete)
+					addSettingsSecretsRoutes()
+					addSettingsVariablesRoutes()
+				})
+			})
 			m.Group("/general", func() {
 				m.Group("/collaborative_owner", func() {
 					m.Post("/add", repo_setting.AddCollaborativeOwner)
@@ -1480,6 +1586,9 @@ func registerWebRoutes(m *web.Router) {
 		m.Post("/run", reqRepoActionsWriter, actions.Run)
 		m.Get("/workflow-dispatch-inputs", reqRepoActionsWriter, actions.WorkflowDispatchInputs)
 		m.Post("/approve-all-checks", reqRepoActionsWriter, actions.ApproveAllChecks)
+		m.Get("/environments", actions.Environments)
+		m.Get("/environments/{environment_id}", actions.EnvironmentDeployments)
+		m.Get("/logs", actions.LogsSearch)
 
 		m.Group("/runs/{run}", func() {
 			m.Combo("").
@@ -1491,6 +1600,7 @@ func registerWebRoutes(m *web.Router) {
 					Post(web.Bind(actions.ViewRequest{}), actions.ViewPost)
 				m.Post("/rerun", reqRepoActionsWriter, actions.Rerun)
 				m.Get("/logs", actions.Logs)
+				m.Post("/deployment/{action:approve|reject}", reqRepoActionsWriter, actions.ReviewDeployment)
 			})
 			m.Get("/workflow", actions.ViewWorkflowFile)
 			m.Post("/cancel", reqRepoActionsWriter, actions.Cancel)
@@ -1499,6 +1609,7 @@ func registerWebRoutes(m *web.Router) {
 			m.Get("/artifacts/{artifact_name}", actions.ArtifactsDownloadView)
 			m.Delete("/artifacts/{artifact_name}", reqRepoActionsWriter, actions.ArtifactsDeleteView)
 			m.Post("/rerun", reqRepoActionsWriter, actions.Rerun)
+			m.Post("/rerun-failed", reqRepoActionsWriter, actions.RerunFailed)
 		})
 		m.Group("/workflows/{workflow_name}", func() {
 			m.Get("/badge.svg", actions.GetWorkflowBadge)
@@ -1562,6 +1673,7 @@ func registerWebRoutes(m *web.Router) {
 			})
 			m.Post("/merge", context.RepoMustNotBeArchived(), web.Bind(forms.MergePullRequestForm{}), repo.MergePullRequest)
 			m.Post("/cancel_auto_merge", context.RepoMustNotBeArchived(), repo.CancelAutoMergePullRequest)
+			m.Post("/merge_queue/remove", context.RepoMustNotBeArchived(), reqRepoCodeWriter, repo.RemoveFromMergeQueue)
 			m.Post("/update", repo.UpdatePullRequest)
 			m.Post("/set_allow_maintainer_edit", web.Bind(forms.UpdateAllowEditsForm{}), repo.SetAllowEdits)
 			m.Post("/cleanup", context.RepoMustNotBeArchived(), repo.CleanUpPullRequest)
@@ -0,0 +1,502 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	actions_model "github.com/kumose/kmup/models/actions"
+	"github.com/kumose/kmup/models/db"
+	"github.com/kumose/kmup/modules/git"
+	"github.com/kumose/kmup/modules/log"
+	"github.com/kumose/kmup/modules/optional"
+	"github.com/kumose/kmup/modules/setting"
+	"github.com/kumose/kmup/modules/storage"
+	"github.com/kumose/kmup/modules/timeutil"
+	"github.com/kumose/kmup/modules/util"
+)
+
+// CacheRouteBase is the route of the cache server for actions/cache, it is the ACTIONS_CACHE_URL of the jobs
+const CacheRouteBase = "/api/actions_cache"
+
+// CacheKeyMaxLength is the max length of a cache key, the same as GitHub
+const CacheKeyMaxLength = 512
+
+// staleCacheReservation is how long an incomplete cache is kept before it's considered abandoned
+const staleCacheReservation = 24 * time.Hour
+
+// CacheURL returns the url of the cache server with a trailing slash
+func CacheURL() string {
+	return setting.AppURL + CacheRouteBase[1:] + "/"
+}
+
+// CacheScopes returns the refs whose caches can be restored by the run, in the order they are searched:
+// the ref of the run, the base branch of a pull request, and the default branch of the repository.
+func CacheScopes(ctx context.Context, run *actions_model.ActionRun) ([]string, error) {
+	if err := run.LoadRepo(ctx); err != nil {
+		return nil, err
+	}
+	scopes := []string{run.Ref}
+	addScope := func(ref string) {
+		for _, s := range scopes {
+			if s == ref {
+				return
+			}
+		}
+		scopes = append(scopes, ref)
+	}
+	if run.Event.IsPullRequest() {
+		payload, err := run.GetPullRequestEventPayload()
+		if err != nil {
+			return nil, err
+		}
+		if payload.PullRequest != nil && payload.PullRequest.Base != nil && payload.PullRequest.Base.Ref != "" {
+			addScope(git.RefNameFromBranch(payload.PullRequest.Base.Ref).String())
+		}
+	}
+	addScope(git.RefNameFromBranch(run.Repo.DefaultBranch).String())
+	return scopes, nil
+}
+
+// ReserveCache creates an incomplete cache for the ref of the task, the job uploads the content and commits it later.
+// The size is the one declared by the job, 0 if it is unknown, the uploaded content can't exceed it.
+func ReserveCache(ctx context.Context, task *actions_model.ActionTask, key, version string, size int64) (*actions_model.ActionCache, error) {
+	if key == "" || len(key) > CacheKeyMaxLength || strings.Contains(key, ",") {
+		return nil, util.NewInvalidArgumentErrorf("invalid cache key %q", key)
+	}
+	if version == "" {
+		return nil, util.NewInvalidArgumentErrorf("cache version is required")
+	}
+	if size < 0 || size > setting.Actions.CacheMaxRepoSize {
+		return nil, util.NewInvalidArgumentErrorf("cache size %d exceeds the limit %d", size, setting.Actions.CacheMaxRepoSize)
+	}
+	if err := task.LoadJob(ctx); err != nil {
+		return nil, err
+	}
+	if err := task.Job.LoadRun(ctx); err != nil {
+		return nil, err
+	}
+	c := &actions_model.ActionCache{
+		RepoID:   task.RepoID,
+		Scope:    task.Job.Run.Ref,
+		CacheKey: key,
+		Version:  version,
+		Size:     size,
+	}
+	if err := actions_model.ReserveCache(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// GetCacheToUpload returns the incomplete cache of the repository of the task
+func GetCacheToUpload(ctx context.Context, task *actions_model.ActionTask, cacheID int64) (*actions_model.ActionCache, error) {
+	c, err := actions_model.GetCacheByID(ctx, task.RepoID, cacheID)
+	if err != nil {
+		return nil, err
+	}
+	if c.Complete {
+		return nil, util.NewInvalidArgumentErrorf("cache %d has been committed", c.ID)
+	}
+	return c, nil
+}
+
+// FindCacheToUpload returns the incomplete cache of the ref of the task with the key and version
+func FindCacheToUpload(ctx context.Context, task *actions_model.ActionTask, key, version string) (*actions_model.ActionCache, error) {
+	if err := task.LoadJob(ctx); err != nil {
+		return nil, err
+	}
+	if err := task.Job.LoadRun(ctx); err != nil {
+		return nil, err
+	}
+	caches, err := db.Find[actions_model.ActionCache](ctx, actions_model.FindCachesOptions{
+		RepoID:   task.RepoID,
+		Scope:    task.Job.Run.Ref,
+		CacheKey: key,
+		Version:  version,
+		Complete: optional.Some(false),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(caches) == 0 {
+		return nil, util.NewNotExistErrorf("cache %q is not being uploaded", key)
+	}
+	return caches[0], nil
+}
+
+func cacheChunkPath(c *actions_model.ActionCache, start int64) string {
+	return fmt.Sprintf("%s/chunk-%d", c.TempStoragePath(), start)
+}
+
+func cacheBlockPath(c *actions_model.ActionCache, blockID string) string {
+	return fmt.Sprintf("%s/block-%s", c.TempStoragePath(), hex.EncodeToString([]byte(blockID)))
+}
+
+func cacheBlockListPath(c *actions_model.ActionCache) string {
+	return c.TempStoragePath() + "/blocklist"
+}
+
+func saveCacheObject(p string, r io.Reader, size int64) error {
+	written, err := storage.ActionsCache.Save(p, r, size)
+	if err != nil {
+		return err
+	}
+	if size >= 0 && written != size {
+		if err := storage.ActionsCache.Delete(p); err != nil {
+			log.Error("Failed to delete cache chunk %s: %v", p, err)
+		}
+		return util.NewInvalidArgumentErrorf("uploaded size %d doesn't match the content length %d", written, size)
+	}
+	return nil
+}
+
+// getCacheUploadedSize returns the size of the chunks and blocks of the cache which have been uploaded, except the one at the path
+func getCacheUploadedSize(c *actions_model.ActionCache, except string) (int64, error) {
+	var uploaded int64
+	err := storage.ActionsCache.IterateObjects(c.TempStoragePath(), func(p string, obj storage.Object) error {
+		defer obj.Close()
+		name := path.Base(p)
+		if name == path.Base(except) || name == path.Base(cacheBlockListPath(c)) {
+			return nil
+		}
+		fi, err := obj.Stat()
+		if err != nil {
+			return err
+		}
+		uploaded += fi.Size()
+		return nil
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		// nothing has been uploaded yet
+		return 0, nil
+	}
+	return uploaded, err
+}
+
+// saveCacheContent saves a part of the content of the cache, the content of the cache can't exceed its declared size and the size limit
+func saveCacheContent(c *actions_model.ActionCache, p string, r io.Reader, size int64) error {
+	limit := setting.Actions.CacheMaxRepoSize
+	if c.Size > 0 {
+		limit = min(limit, c.Size)
+	}
+	uploaded, err := getCacheUploadedSize(c, p)
+	if err != nil {
+		return err
+	}
+	remaining := limit - uploaded
+	if size > remaining {
+		return util.NewInvalidArgumentErrorf("cache content exceeds the limit %d", limit)
+	}
+	if size >= 0 {
+		return saveCacheObject(p, r, size)
+	}
+
+	// the length is unknown, read one more byte than allowed to detect a too large content
+	written, err := storage.ActionsCache.Save(p, io.LimitReader(r, remaining+1), -1)
+	if err != nil {
+		return err
+	}
+	if written > remaining {
+		if err := storage.ActionsCache.Delete(p); err != nil {
+			log.Error("Failed to delete cache chunk %s: %v", p, err)
+		}
+		return util.NewInvalidArgumentErrorf("cache content exceeds the limit %d", limit)
+	}
+	return nil
+}
+
+// UploadCacheChunk saves a chunk of the cache which starts at the offset, the chunks can be uploaded in any order
+func UploadCacheChunk(c *actions_model.ActionCache, start int64, r io.Reader, size int64) error {
+	if start < 0 {
+		return util.NewInvalidArgumentErrorf("invalid chunk offset %d", start)
+	}
+	return saveCacheContent(c, cacheChunkPath(c, start), r, size)
+}
+
+// UploadCacheBlock saves a block of the cache, the order of the blocks is given by UploadCacheBlockList
+func UploadCacheBlock(c *actions_model.ActionCache, blockID string, r io.Reader, size int64) error {
+	if blockID == "" {
+		return util.NewInvalidArgumentErrorf("block id is required")
+	}
+	return saveCacheContent(c, cacheBlockPath(c, blockID), r, size)
+}
+
+// UploadCacheBlockList saves the order of the uploaded blocks of the cache
+func UploadCacheBlockList(c *actions_model.ActionCache, blockIDs []string) error {
+	for _, id := range blockIDs {
+		if id == "" || strings.Contains(id, "\n") {
+			return util.NewInvalidArgumentErrorf("invalid block id %q", id)
+		}
+	}
+	list := strings.Join(blockIDs, "\n")
+	return saveCacheObject(cacheBlockListPath(c), strings.NewReader(list), int64(len(list)))
+}
+
+type cacheChunk struct {
+	path  string
+	start int64
+	size  int64
+}
+
+// listCacheChunks returns the uploaded parts of the cache in order, they are the blocks if there is a block list, or the chunks
+func listCacheChunks(c *actions_model.ActionCache) ([]*cacheChunk, error) {
+	var chunks []*cacheChunk
+
+	if listObj, err := storage.ActionsCache.Open(cacheBlockListPath(c)); err == nil {
+		content, err := io.ReadAll(listObj)
+		_ = listObj.Close()
+		if err != nil {
+			return nil, err
+		}
+		var start int64
+		for id := range strings.SplitSeq(string(content), "\n") {
+			p := cacheBlockPath(c, id)
+			fi, err := storage.ActionsCache.Stat(p)
+			if err != nil {
+				return nil, util.NewInvalidArgumentErrorf("block %q has not been uploaded", id)
+			}
+			chunks = append(chunks, &cacheChunk{path: p, start: start, size: fi.Size()})
+			start += fi.Size()
+		}
+		return chunks, nil
+	}
+
+	if err := storage.ActionsCache.IterateObjects(c.TempStoragePath(), func(p string, obj storage.Object) error {
+		defer obj.Close()
+		// when read chunks from storage, it only contains storage dir and basename,
+		// no matter the subdirectory setting in storage config
+		chunk := &cacheChunk{path: c.TempStoragePath() + "/" + path.Base(p)}
+		if _, err := fmt.Sscanf(path.Base(p), "chunk-%d", &chunk.start); err != nil {
+			return nil
+		}
+		fi, err := obj.Stat()
+		if err != nil {
+			return err
+		}
+		chunk.size = fi.Size()
+		chunks = append(chunks, chunk)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	sort.Slice(chunks, func(i, j int) bool {
+		return chunks[i].start < chunks[j].start
+	})
+	var offset int64
+	for _, chunk := range chunks {
+		if chunk.start != offset {
+			return nil, util.NewInvalidArgumentErrorf("missing cache content at offset %d", offset)
+		}
+		offset += chunk.size
+	}
+	return chunks, nil
+}
+
+// cacheChunksReader reads the chunks one after another, only one chunk is opened at a time
+type cacheChunksReader struct {
+	chunks []*cacheChunk
+	cur    io.ReadCloser
+}
+
+func (r *cacheChunksReader) Read(p []byte) (int, error) {
+	for {
+		if r.cur == nil {
+			if len(r.chunks) == 0 {
+				return 0, io.EOF
+			}
+			obj, err := storage.ActionsCache.Open(r.chunks[0].path)
+			if err != nil {
+				return 0, err
+			}
+			r.cur, r.chunks = obj, r.chunks[1:]
+		}
+		n, err := r.cur.Read(p)
+		if errors.Is(err, io.EOF) {
+			_ = r.cur.Close()
+			r.cur = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (r *cacheChunksReader) Close() error {
+	if r.cur != nil {
+		return r.cur.Close()
+	}
+	return nil
+}
+
+// CommitCache merges the uploaded content of the cache so it can be restored.
+// The size is checked if it's not negative. The least recently used caches of the repository are evicted if it uses too much space.
+func CommitCache(ctx context.Context, c *actions_model.ActionCache, size int64) error {
+	chunks, err := listCacheChunks(c)
+	if err != nil {
+		return err
+	}
+	var total int64
+	for _, chunk := range chunks {
+		total += chunk.size
+	}
+	if size >= 0 && total != size {
+		return util.NewInvalidArgumentErrorf("uploaded size %d doesn't match the cache size %d", total, size)
+	}
+	if total > setting.Actions.CacheMaxRepoSize {
+		return util.NewInvalidArgumentErrorf("cache size %d exceeds the limit %d", total, setting.Actions.CacheMaxRepoSize)
+	}
+
+	reader := &cacheChunksReader{chunks: chunks}
+	written, err := storage.ActionsCache.Save(c.StoragePath(), reader, total)
+	_ = reader.Close()
+	if err != nil {
+		return fmt.Errorf("save merged cache: %w", err)
+	}
+	if written != total {
+		return fmt.Errorf("merged cache size %d doesn't match the uploaded size %d", written, total)
+	}
+	removeCacheTempFiles(c)
+
+	if err := actions_model.CommitCache(ctx, c, total); err != nil {
+		return err
+	}
+	return EvictRepoCaches(ctx, c.RepoID)
+}
+
+func removeCacheTempFiles(c *actions_model.ActionCache) {
+	if err := storage.ActionsCache.IterateObjects(c.TempStoragePath(), func(p string, obj storage.Object) error {
+		_ = obj.Close()
+		return storage.ActionsCache.Delete(c.TempStoragePath() + "/" + path.Base(p))
+	}); err != nil {
+		log.Error("Failed to remove the uploaded chunks of cache %d: %v", c.ID, err)
+	}
+}
+
+// RestoreCache returns the cache matching the keys which the task can restore
+func RestoreCache(ctx context.Context, task *actions_model.ActionTask, keys []string, version string) (*actions_model.ActionCache, error) {
+	if err := task.LoadJob(ctx); err != nil {
+		return nil, err
+	}
+	if err := task.Job.LoadRun(ctx); err != nil {
+		return nil, err
+	}
+	scopes, err := CacheScopes(ctx, task.Job.Run)
+	if err != nil {
+		return nil, err
+	}
+	c, err := actions_model.FindCacheToRestore(ctx, task.RepoID, scopes, keys, version)
+	if err != nil {
+		return nil, err
+	}
+	if err := actions_model.UpdateCacheLastUsed(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// DeleteCache deletes the cache and its stored content
+func DeleteCache(ctx context.Context, c *actions_model.ActionCache) error {
+	if err := actions_model.DeleteCache(ctx, c); err != nil {
+		return err
+	}
+	if c.Complete {
+		if err := storage.ActionsCache.Delete(c.StoragePath()); err != nil {
+			log.Error("Failed to delete the content of cache %d: %v", c.ID, err)
+		}
+	} else {
+		removeCacheTempFiles(c)
+	}
+	return nil
+}
+
+// EvictRepoCaches deletes the least recently used caches of the repository until they fit in the size limit
+func EvictRepoCaches(ctx context.Context, repoID int64) error {
+	total, err := actions_model.GetRepoCacheSize(ctx, repoID)
+	if err != nil {
+		return err
+	}
+	if total <= setting.Actions.CacheMaxRepoSize {
+		return nil
+	}
+	caches, err := db.Find[actions_model.ActionCache](ctx, actions_model.FindCachesOptions{
+		RepoID:   repoID,
+		Complete: optional.Some(true),
+	})
+	if err != nil {
+		return err
+	}
+	for _, c := range caches {
+		if total <= setting.Actions.CacheMaxRepoSize {
+			break
+		}
+		if err := DeleteCache(ctx, c); err != nil {
+			return err
+		}
+		total -= c.Size
+		log.Trace("Cache %d of repo %d is evicted", c.ID, repoID)
+	}
+	return nil
+}
+
+// CleanupCaches deletes the caches which haven't been used for the retention days and the abandoned uploads,
+// then evicts the caches of the repositories which use too much space
+func CleanupCaches(ctx context.Context) error {
+	unused, err := db.Find[actions_model.ActionCache](ctx, actions_model.FindCachesOptions{
+		Complete:   optional.Some(true),
+		UsedBefore: timeutil.TimeStamp(time.Now().AddDate(0, 0, -int(setting.Actions.CacheRetentionDays)).Unix()),
+	})
+	if err != nil {
+		return err
+	}
+	abandoned, err := db.Find[actions_model.ActionCache](ctx, actions_model.FindCachesOptions{
+		Complete:   optional.Some(false),
+		UsedBefore: timeutil.TimeStamp(time.Now().Add(-staleCacheReservation).Unix()),
+	})
+	if err != nil {
+		return err
+	}
+	for _, c := range append(unused, abandoned...) {
+		if err := DeleteCache(ctx, c); err != nil {
+			return err
+		}
+	}
+	log.Info("Deleted %d unused and %d abandoned caches", len(unused), len(abandoned))
+
+	repoIDs, err := actions_model.GetReposExceedingCacheSize(ctx, setting.Actions.CacheMaxRepoSize)
+	if err != nil {
+		return err
+	}
+	for _, repoID := range repoIDs {
+		if err := EvictRepoCaches(ctx, repoID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
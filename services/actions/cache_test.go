@@ -0,0 +1,131 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	actions_model "github.com/kumose/kmup/models/actions"
+	"github.com/kumose/kmup/models/unittest"
+	"github.com/kumose/kmup/modules/setting"
+	"github.com/kumose/kmup/modules/storage"
+	"github.com/kumose/kmup/modules/test"
+	"github.com/kumose/kmup/modules/timeutil"
+	"github.com/kumose/kmup/modules/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readCache(t *testing.T, c *actions_model.ActionCache) string {
+	obj, err := storage.ActionsCache.Open(c.StoragePath())
+	require.NoError(t, err)
+	defer obj.Close()
+	content, err := io.ReadAll(obj)
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestCommitCache(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	task := unittest.AssertExistsAndLoadBean(t, &actions_model.ActionTask{ID: 47})
+
+	t.Run("Chunks", func(t *testing.T) {
+		c, err := ReserveCache(t.Context(), task, "chunks-key", "v1", 0)
+		require.NoError(t, err)
+
+		require.NoError(t, UploadCacheChunk(c, 6, strings.NewReader("world"), 5))
+		require.NoError(t, UploadCacheChunk(c, 0, strings.NewReader("hello "), 6))
+		assert.ErrorIs(t, CommitCache(t.Context(), c, 20), util.ErrInvalidArgument)
+		require.NoError(t, CommitCache(t.Context(), c, 11))
+		assert.Equal(t, "hello world", readCache(t, c))
+
+		restored, err := RestoreCache(t.Context(), task, []string{"unknown", "chunks-"}, "v1")
+		require.NoError(t, err)
+		assert.Equal(t, c.ID, restored.ID)
+	})
+
+	t.Run("MissingChunk", func(t *testing.T) {
+		c, err := ReserveCache(t.Context(), task, "missing-key", "v1", 0)
+		require.NoError(t, err)
+		require.NoError(t, UploadCacheChunk(c, 5, strings.NewReader("world"), 5))
+		assert.ErrorIs(t, CommitCache(t.Context(), c, -1), util.ErrInvalidArgument)
+	})
+
+	t.Run("Blocks", func(t *testing.T) {
+		c, err := ReserveCache(t.Context(), task, "blocks-key", "v1", 0)
+		require.NoError(t, err)
+
+		require.NoError(t, UploadCacheBlock(c, "b2", strings.NewReader("world"), 5))
+		require.NoError(t, UploadCacheBlock(c, "b1", strings.NewReader("hello "), 6))
+		require.NoError(t, UploadCacheBlockList(c, []string{"b1", "b2"}))
+		c, err = FindCacheToUpload(t.Context(), task, "blocks-key", "v1")
+		require.NoError(t, err)
+		require.NoError(t, CommitCache(t.Context(), c, 11))
+		assert.Equal(t, "hello world", readCache(t, c))
+
+		_, err = FindCacheToUpload(t.Context(), task, "blocks-key", "v1")
+		assert.ErrorIs(t, err, util.ErrNotExist)
+	})
+
+	t.Run("Evict", func(t *testing.T) {
+		defer test.MockVariableValue(&setting.Actions.CacheMaxRepoSize, 25)()
+		defer timeutil.MockSet(time.Now().Add(time.Hour))()
+
+		_, err := RestoreCache(t.Context(), task, []string{"chunks-key"}, "v1")
+		require.NoError(t, err)
+		c, err := ReserveCache(t.Context(), task, "evict-key", "v1", 0)
+		require.NoError(t, err)
+		require.NoError(t, UploadCacheChunk(c, 0, strings.NewReader("0123456789"), 10))
+		require.NoError(t, CommitCache(t.Context(), c, 10))
+
+		// the least recently used cache has been evicted
+		_, err = RestoreCache(t.Context(), task, []string{"blocks-key"}, "v1")
+		assert.ErrorIs(t, err, util.ErrNotExist)
+		_, err = RestoreCache(t.Context(), task, []string{"chunks-key"}, "v1")
+		assert.NoError(t, err)
+	})
+
+	t.Run("SizeLimit", func(t *testing.T) {
+		defer test.MockVariableValue(&setting.Actions.CacheMaxRepoSize, 20)()
+
+		_, err := ReserveCache(t.Context(), task, "large-key", "v1", 21)
+		assert.ErrorIs(t, err, util.ErrInvalidArgument)
+
+		// the uploaded content can't exceed the declared size
+		c, err := ReserveCache(t.Context(), task, "declared-key", "v1", 8)
+		require.NoError(t, err)
+		require.NoError(t, UploadCacheChunk(c, 0, strings.NewReader("hello "), 6))
+		assert.ErrorIs(t, UploadCacheChunk(c, 6, strings.NewReader("world"), 5), util.ErrInvalidArgument)
+		// uploading a chunk again replaces it
+		require.NoError(t, UploadCacheChunk(c, 0, strings.NewReader("hello "), 6))
+
+		// nor the size limit, even if the length of the chunks is unknown
+		c, err = ReserveCache(t.Context(), task, "unknown-key", "v1", 0)
+		require.NoError(t, err)
+		require.NoError(t, UploadCacheBlock(c, "b1", strings.NewReader("0123456789"), -1))
+		assert.ErrorIs(t, UploadCacheBlock(c, "b2", strings.NewReader("0123456789a"), -1), util.ErrInvalidArgument)
+		require.NoError(t, UploadCacheBlock(c, "b2", strings.NewReader("0123456789"), -1))
+		require.NoError(t, UploadCacheBlockList(c, []string{"b1", "b2"}))
+		require.NoError(t, CommitCache(t.Context(), c, 20))
+	})
+}
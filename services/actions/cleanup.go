@@ -27,6 +27,7 @@ import (
 	"github.com/kumose/kmup/models/db"
 	actions_module "github.com/kumose/kmup/modules/actions"
 	"github.com/kumose/kmup/modules/container"
+	actions_indexer "github.com/kumose/kmup/modules/indexer/actions"
 	"github.com/kumose/kmup/modules/log"
 	"github.com/kumose/kmup/modules/setting"
 	"github.com/kumose/kmup/modules/storage"
@@ -131,6 +132,7 @@ func CleanupExpiredLogs(ctx context.Context) error {
 		if err != nil {
 			return fmt.Errorf("find old tasks: %w", err)
 		}
+		expiredTaskIDs := make([]int64, 0, len(tasks))
 		for _, task := range tasks {
 			removeTaskLog(ctx, task)
 			task.LogIndexes = nil // clear log indexes since it's a heavy field
@@ -141,8 +143,10 @@ func CleanupExpiredLogs(ctx context.Context) error {
 				continue
 			}
 			count++
+			expiredTaskIDs = append(expiredTaskIDs, task.ID)
 			log.Trace("Removed log %s of task %v", task.LogFilename, task.ID)
 		}
+		actions_indexer.DeleteTasksIndexer(expiredTaskIDs...)
 		if len(tasks) < deleteLogBatchSize {
 			break
 		}
@@ -237,6 +241,18 @@ func DeleteRun(ctx context.Context, run *actions_model.ActionRun) error {
 		recordsToDelete = append(recordsToDelete, &actions_model.ActionTaskOutput{
 			TaskID: tas.ID,
 		})
+		recordsToDelete = append(recordsToDelete, &actions_model.ActionTaskAnnotation{
+			RepoID: repoID,
+			TaskID: tas.ID,
+		})
+		recordsToDelete = append(recordsToDelete, &actions_model.ActionTestReport{
+			RepoID: repoID,
+			TaskID: tas.ID,
+		})
+		recordsToDelete = append(recordsToDelete, &actions_model.ActionTestCase{
+			RepoID: repoID,
+			TaskID: tas.ID,
+		})
 	}
 	recordsToDelete = append(recordsToDelete, &actions_model.ActionArtifact{
 		RepoID: repoID,
@@ -260,9 +276,12 @@ func DeleteRun(ctx context.Context, run *actions_model.ActionRun) error {
 	}
 
 	// Delete files on storage
+	taskIDs := make([]int64, 0, len(tasks))
 	for _, tas := range tasks {
 		removeTaskLog(ctx, tas)
+		taskIDs = append(taskIDs, tas.ID)
 	}
+	actions_indexer.DeleteTasksIndexer(taskIDs...)
 	for _, art := range artifacts {
 		if err := storage.ActionsArtifacts.Delete(art.StoragePath); err != nil {
 			log.Error("remove artifact file %q: %v", art.StoragePath, err)
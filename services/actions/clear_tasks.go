@@ -26,6 +26,7 @@ import (
 	"github.com/kumose/kmup/models/db"
 	repo_model "github.com/kumose/kmup/models/repo"
 	"github.com/kumose/kmup/modules/actions"
+	actions_indexer "github.com/kumose/kmup/modules/indexer/actions"
 	"github.com/kumose/kmup/modules/log"
 	"github.com/kumose/kmup/modules/setting"
 	"github.com/kumose/kmup/modules/timeutil"
@@ -182,6 +183,7 @@ func stopTasks(ctx context.Context, opts actions_model.FindTaskOptions) error {
 			continue
 		}
 		remove()
+		actions_indexer.UpdateTaskIndexer(task.ID)
 	}
 
 	notifyWorkflowJobStatusUpdate(ctx, jobs)
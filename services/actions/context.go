@@ -31,6 +31,7 @@ import (
 	"github.com/kumose/kmup/modules/setting"
 	"github.com/kumose/kmup/modules/util"
 
+	"github.com/nektos/act/pkg/jobparser"
 	"github.com/nektos/act/pkg/model"
 )
 
@@ -110,6 +111,42 @@ func GenerateKmupContext(run *actions_model.ActionRun, job *actions_model.Action
 	return gitContext
 }
 
+// getInputsOfJob returns the `inputs` context of the workflow which the job belongs to
+func getInputsOfJob(ctx context.Context, run *actions_model.ActionRun, job *actions_model.ActionRunJob) (map[string]any, error) {
+	if job.ParentJobID == 0 {
+		return getInputsFromRun(run)
+	}
+	parent, err := actions_model.GetRunJobByID(ctx, job.ParentJobID)
+	if err != nil {
+		return nil, err
+	}
+	return parent.WorkflowCallInputs, nil
+}
+
+// newJobExpressionEvaluator returns an evaluator for the job-level expressions which can be evaluated on the server side,
+// it supports the github, needs, matrix, vars and inputs contexts.
+func newJobExpressionEvaluator(ctx context.Context, run *actions_model.ActionRun, job *actions_model.ActionRunJob, workflowJob *jobparser.Job, vars map[string]string) (*jobparser.ExpressionEvaluator, error) {
+	results, err := findJobNeedsAndFillJobResults(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+	inputs, err := getInputsOfJob(ctx, run, job)
+	if err != nil {
+		return nil, err
+	}
+
+	actJob := &model.Job{Strategy: &model.Strategy{RawMatrix: workflowJob.Strategy.RawMatrix}}
+	matrix := map[string]any{}
+	if matrixes, err := actJob.GetMatrixes(); err != nil {
+		return nil, util.NewInvalidArgumentErrorf("invalid matrix: %v", err)
+	} else if len(matrixes) > 0 {
+		matrix = matrixes[0]
+	}
+
+	gitCtx := GenerateKmupContext(run, job)
+	return jobparser.NewExpressionEvaluator(jobparser.NewInterpeter(job.JobID, actJob, matrix, gitCtx.ToGitHubContext(), results, vars, inputs)), nil
+}
+
 type TaskNeed struct {
 	Result  actions_model.Status
 	Outputs map[string]string
@@ -128,8 +165,11 @@ func FindTaskNeeds(ctx context.Context, job *actions_model.ActionRunJob) (map[st
 	}
 
 	jobIDJobs := make(map[string][]*actions_model.ActionRunJob)
-	for _, job := range jobs {
-		jobIDJobs[job.JobID] = append(jobIDJobs[job.JobID], job)
+	for _, j := range jobs {
+		// the needs can only refer to the jobs in the same workflow
+		if j.ParentJobID == job.ParentJobID {
+			jobIDJobs[j.JobID] = append(jobIDJobs[j.JobID], j)
+		}
 	}
 
 	ret := make(map[string]*TaskNeed, len(needs))
@@ -139,17 +179,13 @@ func FindTaskNeeds(ctx context.Context, job *actions_model.ActionRunJob) (map[st
 		}
 		var jobOutputs map[string]string
 		for _, job := range jobsWithSameID {
-			if job.TaskID == 0 || !job.Status.IsDone() {
-				// it shouldn't happen, or the job has been rerun
-				continue
-			}
-			got, err := actions_model.FindTaskOutputByTaskID(ctx, job.TaskID)
+			outputs, err := getJobOutputs(ctx, job, jobs)
 			if err != nil {
-				return nil, fmt.Errorf("FindTaskOutputByTaskID: %w", err)
+				return nil, err
 			}
-			outputs := make(map[string]string, len(got))
-			for _, v := range got {
-				outputs[v.OutputKey] = v.OutputValue
+			if outputs == nil {
+				// it shouldn't happen, or the job has been rerun
+				continue
 			}
 			if len(jobOutputs) == 0 {
 				jobOutputs = outputs
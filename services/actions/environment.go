@@ -0,0 +1,352 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	actions_model "github.com/kumose/kmup/models/actions"
+	"github.com/kumose/kmup/models/db"
+	access_model "github.com/kumose/kmup/models/perm/access"
+	repo_model "github.com/kumose/kmup/models/repo"
+	secret_model "github.com/kumose/kmup/models/secret"
+	"github.com/kumose/kmup/models/unit"
+	user_model "github.com/kumose/kmup/models/user"
+	"github.com/kumose/kmup/modules/git"
+	"github.com/kumose/kmup/modules/log"
+	"github.com/kumose/kmup/modules/timeutil"
+	"github.com/kumose/kmup/modules/util"
+
+	"xorm.io/builder"
+)
+
+// EnvironmentOptions are the settings of an environment
+type EnvironmentOptions struct {
+	Name           string
+	ReviewerIDs    []int64
+	WaitTimer      int64
+	BranchPatterns []string
+	TagPatterns    []string
+}
+
+func validateEnvironmentOptions(ctx context.Context, repo *repo_model.Repository, opts *EnvironmentOptions) error {
+	opts.Name = strings.TrimSpace(opts.Name)
+	if opts.Name == "" || len(opts.Name) > actions_model.EnvironmentNameMaxLength {
+		return util.NewInvalidArgumentErrorf("invalid environment name %q", opts.Name)
+	}
+	if opts.WaitTimer < 0 || opts.WaitTimer > actions_model.EnvironmentMaxWaitTimer {
+		return util.NewInvalidArgumentErrorf("wait timer must be between 0 and %d minutes", actions_model.EnvironmentMaxWaitTimer)
+	}
+	if err := actions_model.ValidateRefPatterns(opts.BranchPatterns); err != nil {
+		return err
+	}
+	if err := actions_model.ValidateRefPatterns(opts.TagPatterns); err != nil {
+		return err
+	}
+
+	// the reviewers must be able to write the actions of the repository
+	reviewers, err := user_model.GetUsersByIDs(ctx, opts.ReviewerIDs)
+	if err != nil {
+		return err
+	}
+	if len(reviewers) != len(opts.ReviewerIDs) {
+		return util.NewInvalidArgumentErrorf("some reviewers don't exist")
+	}
+	for _, reviewer := range reviewers {
+		perm, err := access_model.GetUserRepoPermission(ctx, repo, reviewer)
+		if err != nil {
+			return err
+		}
+		if !perm.CanWrite(unit.TypeActions) {
+			return util.NewInvalidArgumentErrorf("reviewer %s doesn't have write permission to actions", reviewer.Name)
+		}
+	}
+	return nil
+}
+
+// CreateEnvironment creates an environment for the repository
+func CreateEnvironment(ctx context.Context, repo *repo_model.Repository, opts EnvironmentOptions) (*actions_model.ActionEnvironment, error) {
+	if err := validateEnvironmentOptions(ctx, repo, &opts); err != nil {
+		return nil, err
+	}
+	if _, err := actions_model.GetEnvironmentByRepoIDAndName(ctx, repo.ID, opts.Name); err == nil {
+		return nil, util.NewAlreadyExistErrorf("environment %q already exists", opts.Name)
+	} else if !errors.Is(err, util.ErrNotExist) {
+		return nil, err
+	}
+
+	env := &actions_model.ActionEnvironment{
+		RepoID:         repo.ID,
+		Name:           opts.Name,
+		ReviewerIDs:    opts.ReviewerIDs,
+		WaitTimer:      opts.WaitTimer,
+		BranchPatterns: opts.BranchPatterns,
+		TagPatterns:    opts.TagPatterns,
+	}
+	return env, actions_model.CreateEnvironment(ctx, env)
+}
+
+// UpdateEnvironment updates the settings of an environment, the new protection rules are applied to the waiting deployments too
+func UpdateEnvironment(ctx context.Context, repo *repo_model.Repository, env *actions_model.ActionEnvironment, opts EnvironmentOptions) error {
+	if err := validateEnvironmentOptions(ctx, repo, &opts); err != nil {
+		return err
+	}
+	if !strings.EqualFold(env.Name, opts.Name) {
+		if _, err := actions_model.GetEnvironmentByRepoIDAndName(ctx, repo.ID, opts.Name); err == nil {
+			return util.NewAlreadyExistErrorf("environment %q already exists", opts.Name)
+		} else if !errors.Is(err, util.ErrNotExist) {
+			return err
+		}
+	}
+
+	env.Name = opts.Name
+	env.ReviewerIDs = opts.ReviewerIDs
+	env.WaitTimer = opts.WaitTimer
+	env.BranchPatterns = opts.BranchPatterns
+	env.TagPatterns = opts.TagPatterns
+	if err := actions_model.UpdateEnvironment(ctx, env, "name", "reviewer_ids", "wait_timer", "branch_patterns", "tag_patterns"); err != nil {
+		return err
+	}
+	return ReleaseWaitingDeployments(ctx, env.ID)
+}
+
+// DeleteEnvironment deletes an environment with its secrets, variables and deployments.
+// The jobs waiting for the protection rules of the environment are cancelled.
+func DeleteEnvironment(ctx context.Context, env *actions_model.ActionEnvironment) error {
+	var cancelledJobs []*actions_model.ActionRunJob
+	if err := db.WithTx(ctx, func(ctx context.Context) error {
+		jobs, err := findWaitingDeploymentJobs(ctx, env.ID)
+		if err != nil {
+			return err
+		}
+		if cancelledJobs, err = actions_model.CancelJobs(ctx, jobs); err != nil {
+			return err
+		}
+
+		if _, err := db.DeleteByBean(ctx, &secret_model.Secret{RepoID: env.RepoID, EnvironmentID: env.ID}); err != nil {
+			return err
+		}
+		if _, err := db.DeleteByBean(ctx, &actions_model.ActionVariable{RepoID: env.RepoID, EnvironmentID: env.ID}); err != nil {
+			return err
+		}
+		if _, err := db.DeleteByBean(ctx, &actions_model.ActionDeployment{EnvironmentID: env.ID}); err != nil {
+			return err
+		}
+		_, err = db.DeleteByID[actions_model.ActionEnvironment](ctx, env.ID)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	notifyWorkflowJobStatusUpdate(ctx, cancelledJobs)
+	EmitJobsIfReadyByJobs(cancelledJobs)
+	return nil
+}
+
+func findWaitingDeploymentJobs(ctx context.Context, envID int64) ([]*actions_model.ActionRunJob, error) {
+	var jobs []*actions_model.ActionRunJob
+	return jobs, db.GetEngine(ctx).
+		Where(builder.In("id", builder.Select("run_job_id").From("action_deployment").
+			Where(builder.Eq{"environment_id": envID, "status": actions_model.DeploymentStatusWaiting}))).
+		And(builder.Eq{"status": actions_model.StatusPendingApproval}).
+		Find(&jobs)
+}
+
+// prepareJobDeployment checks the protection rules of the environment which the job deploys to, it's called before the job runs.
+// It returns StatusWaiting if the job can run now, StatusPendingApproval if the deployment has to wait for the reviewers or the wait timer,
+// or StatusFailure if the environment can't be deployed by the ref of the run.
+// The environment is created if it doesn't exist, and the deployment is recorded.
+func prepareJobDeployment(ctx context.Context, job *actions_model.ActionRunJob) (actions_model.Status, error) {
+	name, err := evaluateJobEnvironment(ctx, job)
+	if errors.Is(err, util.ErrInvalidArgument) {
+		log.Warn("Unable to evaluate the environment of job %d: %v", job.ID, err)
+		return actions_model.StatusFailure, nil
+	} else if err != nil {
+		return actions_model.StatusBlocked, err
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return actions_model.StatusWaiting, nil
+	}
+	if len(name) > actions_model.EnvironmentNameMaxLength {
+		log.Warn("The environment name of job %d is too long", job.ID)
+		return actions_model.StatusFailure, nil
+	}
+
+	env, err := actions_model.GetEnvironmentByRepoIDAndName(ctx, job.RepoID, name)
+	if errors.Is(err, util.ErrNotExist) {
+		// like GitHub, an environment is created without protection rules when a job deploys to it for the first time
+		env = &actions_model.ActionEnvironment{RepoID: job.RepoID, Name: name}
+		err = actions_model.CreateEnvironment(ctx, env)
+	}
+	if err != nil {
+		return actions_model.StatusBlocked, err
+	}
+	job.EnvironmentID = env.ID
+
+	deployment := &actions_model.ActionDeployment{
+		RepoID:        job.RepoID,
+		EnvironmentID: env.ID,
+		RunID:         job.RunID,
+		RunJobID:      job.ID,
+		Ref:           job.Run.Ref,
+		CommitSHA:     job.CommitSHA,
+		CreatorID:     job.Run.TriggerUserID,
+	}
+	status := actions_model.StatusWaiting
+	switch {
+	case !env.CanDeployRef(git.RefName(job.Run.Ref)):
+		deployment.Status = actions_model.DeploymentStatusRejected
+		deployment.ReviewComment = fmt.Sprintf("%s is not allowed to deploy to the environment", job.Run.Ref)
+		status = actions_model.StatusFailure
+	case env.IsProtected():
+		deployment.Status = actions_model.DeploymentStatusWaiting
+		status = actions_model.StatusPendingApproval
+	default:
+		deployment.Status = actions_model.DeploymentStatusApproved
+	}
+	if err := db.Insert(ctx, deployment); err != nil {
+		return actions_model.StatusBlocked, err
+	}
+	return status, nil
+}
+
+// ReviewDeployment approves or rejects the waiting deployment of a job by a reviewer of the environment.
+// A rejected job fails, an approved job is released after the wait timer of the environment.
+func ReviewDeployment(ctx context.Context, job *actions_model.ActionRunJob, doer *user_model.User, approve bool, comment string) error {
+	deployment, err := actions_model.GetWaitingDeploymentByJobID(ctx, job.ID)
+	if err != nil {
+		return err
+	}
+	env, err := actions_model.GetEnvironmentByRepoIDAndID(ctx, deployment.RepoID, deployment.EnvironmentID)
+	if err != nil {
+		return err
+	}
+	if !env.IsReviewer(doer.ID) {
+		return util.NewPermissionDeniedErrorf("user %s is not a reviewer of environment %s", doer.Name, env.Name)
+	}
+	if deployment.ReviewerID > 0 {
+		return util.NewInvalidArgumentErrorf("the deployment has been reviewed")
+	}
+
+	deployment.ReviewerID = doer.ID
+	deployment.ReviewComment = comment
+	deployment.Reviewed = timeutil.TimeStampNow()
+	if approve {
+		if _, err := actions_model.UpdateDeployment(ctx, deployment, "reviewer_id", "review_comment", "reviewed"); err != nil {
+			return err
+		}
+		return ReleaseWaitingDeployments(ctx, env.ID)
+	}
+
+	var updatedJobs []*actions_model.ActionRunJob
+	if err := db.WithTx(ctx, func(ctx context.Context) error {
+		deployment.Status = actions_model.DeploymentStatusRejected
+		if ok, err := actions_model.UpdateDeployment(ctx, deployment, "status", "reviewer_id", "review_comment", "reviewed"); err != nil {
+			return err
+		} else if !ok {
+			return util.NewInvalidArgumentErrorf("the deployment has been reviewed")
+		}
+		job.Status = actions_model.StatusFailure
+		if n, err := actions_model.UpdateRunJob(ctx, job, builder.Eq{"status": actions_model.StatusPendingApproval}, "status"); err != nil {
+			return err
+		} else if n > 0 {
+			updatedJobs = append(updatedJobs, job)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	notifyWorkflowJobStatusUpdate(ctx, updatedJobs)
+	EmitJobsIfReadyByJobs(updatedJobs)
+	return nil
+}
+
+// isDeploymentReady returns whether a waiting deployment satisfies the protection rules of the environment
+func isDeploymentReady(env *actions_model.ActionEnvironment, deployment *actions_model.ActionDeployment) bool {
+	if len(env.ReviewerIDs) > 0 && !deployment.IsApprovedByReviewer() {
+		return false
+	}
+	return !time.Now().Before(deployment.Created.AsTime().Add(env.WaitTimerDuration()))
+}
+
+// ReleaseWaitingDeployments releases the jobs of the waiting deployments which satisfy the protection rules of their environments.
+// The deployments of all environments are checked if envID is 0, it's used by the cron task of the wait timers.
+func ReleaseWaitingDeployments(ctx context.Context, envID int64) error {
+	deployments, err := db.Find[actions_model.ActionDeployment](ctx, actions_model.FindDeploymentsOptions{
+		EnvironmentID: envID,
+		Status:        []actions_model.DeploymentStatus{actions_model.DeploymentStatusWaiting},
+	})
+	if err != nil {
+		return err
+	}
+
+	envs := make(map[int64]*actions_model.ActionEnvironment)
+	var updatedJobs []*actions_model.ActionRunJob
+	for _, deployment := range deployments {
+		env, ok := envs[deployment.EnvironmentID]
+		if !ok {
+			if env, err = actions_model.GetEnvironmentByRepoIDAndID(ctx, deployment.RepoID, deployment.EnvironmentID); err != nil {
+				return err
+			}
+			envs[env.ID] = env
+		}
+		if !isDeploymentReady(env, deployment) {
+			continue
+		}
+		if err := db.WithTx(ctx, func(ctx context.Context) error {
+			job, err := actions_model.GetRunJobByID(ctx, deployment.RunJobID)
+			if err != nil {
+				return err
+			}
+			if !job.Status.IsPendingApproval() {
+				// the job has been cancelled while waiting
+				deployment.Status = actions_model.DeploymentStatusCancelled
+				_, err = actions_model.UpdateDeployment(ctx, deployment, "status")
+				return err
+			}
+
+			deployment.Status = actions_model.DeploymentStatusApproved
+			if ok, err := actions_model.UpdateDeployment(ctx, deployment, "status"); err != nil || !ok {
+				return err
+			}
+			status, err := PrepareToStartJobWithConcurrency(ctx, job)
+			if err != nil {
+				return err
+			}
+			// the job blocked by the concurrency will be checked by the job emitter again, its protection rules have been satisfied
+			job.Status = status
+			if n, err := actions_model.UpdateRunJob(ctx, job, builder.Eq{"status": actions_model.StatusPendingApproval}, "status"); err != nil {
+				return err
+			} else if n > 0 {
+				updatedJobs = append(updatedJobs, job)
+			}
+			return nil
+		}); err != nil {
+			return fmt.Errorf("release deployment %d: %w", deployment.ID, err)
+		}
+	}
+
+	notifyWorkflowJobStatusUpdate(ctx, updatedJobs)
+	return nil
+}
@@ -0,0 +1,226 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	actions_model "github.com/kumose/kmup/models/actions"
+	actions_module "github.com/kumose/kmup/modules/actions"
+	"github.com/kumose/kmup/modules/setting"
+	"github.com/kumose/kmup/modules/util"
+	"github.com/kumose/kmup/services/oauth2_provider"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/nektos/act/pkg/jobparser"
+	"gopkg.in/yaml.v3"
+)
+
+// IDTokenRouteBase is the route of the OIDC issuer for Actions jobs
+const IDTokenRouteBase = "/api/actions_id_token"
+
+// idTokenExpiration is how long an ID token is valid, it should be exchanged for other credentials immediately
+const idTokenExpiration = 10 * time.Minute
+
+// IDTokenClaims are the claims of an OIDC ID token issued to an Actions job.
+// See https://docs.github.com/en/actions/security-for-github-actions/security-hardening-your-deployments/about-security-hardening-with-openid-connect#understanding-the-oidc-token
+type IDTokenClaims struct {
+	jwt.RegisteredClaims
+
+	Repository           string `json:"repository"`
+	RepositoryID         string `json:"repository_id"`
+	RepositoryOwner      string `json:"repository_owner"`
+	RepositoryOwnerID    string `json:"repository_owner_id"`
+	RepositoryVisibility string `json:"repository_visibility"`
+	Actor                string `json:"actor"`
+	ActorID              string `json:"actor_id"`
+	Ref                  string `json:"ref"`
+	RefType              string `json:"ref_type"`
+	Sha                  string `json:"sha"`
+	BaseRef              string `json:"base_ref"`
+	HeadRef              string `json:"head_ref"`
+	EventName            string `json:"event_name"`
+	Workflow             string `json:"workflow"`
+	RunID                string `json:"run_id"`
+	RunNumber            string `json:"run_number"`
+	RunAttempt           string `json:"run_attempt"`
+	Job                  string `json:"job"`
+	Environment          string `json:"environment,omitempty"`
+}
+
+// IDTokenIssuer returns the issuer of the ID tokens, the discovery document is served at "{issuer}/.well-known/openid-configuration"
+func IDTokenIssuer() string {
+	return setting.AppURL + IDTokenRouteBase[1:]
+}
+
+// IDTokenSigningKey returns the key to sign the ID tokens, it is the key of the OAuth2 provider.
+// It returns nil if the OAuth2 provider is disabled or uses a symmetric algorithm, since the tokens couldn't be verified by others.
+func IDTokenSigningKey() oauth2_provider.JWTSigningKey {
+	key := oauth2_provider.DefaultSigningKey
+	if key == nil || key.IsSymmetric() {
+		return nil
+	}
+	return key
+}
+
+// CanJobRequestIDToken returns true if the job has the `id-token: write` permission.
+// The jobs of a called workflow also need all their caller jobs to have the permission,
+// so a called workflow can't request ID tokens which its callers didn't grant.
+// Jobs triggered by pull requests from forks can never request ID tokens.
+func CanJobRequestIDToken(ctx context.Context, job *actions_model.ActionRunJob) (bool, error) {
+	if job.IsForkPullRequest || !hasIDTokenWritePermission(job) {
+		return false, nil
+	}
+	for parentID := job.ParentJobID; parentID > 0; {
+		parent, err := actions_model.GetRunJobByID(ctx, parentID)
+		if err != nil {
+			return false, err
+		}
+		if !hasIDTokenWritePermission(parent) {
+			return false, nil
+		}
+		parentID = parent.ParentJobID
+	}
+	return true, nil
+}
+
+func hasIDTokenWritePermission(job *actions_model.ActionRunJob) bool {
+	var swf jobparser.SingleWorkflow
+	if err := yaml.Unmarshal(job.WorkflowPayload, &swf); err != nil {
+		return false
+	}
+	_, workflowJob := swf.Job()
+	if workflowJob == nil {
+		return false
+	}
+	return actions_module.HasIDTokenWritePermission(swf.RawPermissions, workflowJob.RawPermissions)
+}
+
+// evaluateJobEnvironment evaluates the name of the environment of the job
+func evaluateJobEnvironment(ctx context.Context, job *actions_model.ActionRunJob) (string, error) {
+	if job.Environment == "" {
+		return "", nil
+	}
+	workflowJob, err := job.ParseJob()
+	if err != nil {
+		return "", err
+	}
+	vars, err := actions_model.GetVariablesOfRun(ctx, job.Run)
+	if err != nil {
+		return "", err
+	}
+	evaluator, err := newJobExpressionEvaluator(ctx, job.Run, job, workflowJob, vars)
+	if err != nil {
+		return "", err
+	}
+	return evaluator.Interpolate(job.Environment), nil
+}
+
+// CreateIDToken creates a signed OIDC ID token for the running task.
+// The audience defaults to the URL of the repository owner like GitHub does.
+func CreateIDToken(ctx context.Context, task *actions_model.ActionTask, audience string) (string, error) {
+	signingKey := IDTokenSigningKey()
+	if signingKey == nil {
+		return "", util.NewNotExistErrorf("OIDC ID tokens are not available, an asymmetric JWT signing algorithm for OAuth2 is required")
+	}
+	if err := task.LoadAttributes(ctx); err != nil {
+		return "", err
+	}
+	job := task.Job
+	run := job.Run
+	if ok, err := CanJobRequestIDToken(ctx, job); err != nil {
+		return "", err
+	} else if !ok {
+		return "", util.NewPermissionDeniedErrorf("job %d doesn't have the id-token: write permission", job.ID)
+	}
+	if err := run.Repo.LoadOwner(ctx); err != nil {
+		return "", err
+	}
+	environment, err := evaluateJobEnvironment(ctx, job)
+	if err != nil {
+		return "", fmt.Errorf("evaluate environment: %w", err)
+	}
+
+	gitCtx := GenerateKmupContext(run, job)
+	ref := gitCtx["ref"].(string)
+	if audience == "" {
+		audience = run.Repo.Owner.HTMLURL(ctx)
+	}
+	now := time.Now()
+	claims := &IDTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Issuer:    IDTokenIssuer(),
+			Subject:   idTokenSubject(run, ref, environment),
+			Audience:  jwt.ClaimStrings{audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(idTokenExpiration)),
+		},
+		Repository:           run.Repo.FullName(),
+		RepositoryID:         strconv.FormatInt(run.RepoID, 10),
+		RepositoryOwner:      run.Repo.OwnerName,
+		RepositoryOwnerID:    strconv.FormatInt(run.Repo.OwnerID, 10),
+		RepositoryVisibility: idTokenRepositoryVisibility(run),
+		Actor:                run.TriggerUser.Name,
+		ActorID:              strconv.FormatInt(run.TriggerUserID, 10),
+		Ref:                  ref,
+		RefType:              gitCtx["ref_type"].(string),
+		Sha:                  gitCtx["sha"].(string),
+		BaseRef:              gitCtx["base_ref"].(string),
+		HeadRef:              gitCtx["head_ref"].(string),
+		EventName:            run.TriggerEvent,
+		Workflow:             run.WorkflowID,
+		RunID:                strconv.FormatInt(run.ID, 10),
+		RunNumber:            strconv.FormatInt(run.Index, 10),
+		RunAttempt:           strconv.FormatInt(task.Attempt, 10),
+		Job:                  job.JobID,
+		Environment:          environment,
+	}
+
+	token := jwt.NewWithClaims(signingKey.SigningMethod(), claims)
+	signingKey.PreProcessToken(token)
+	return token.SignedString(signingKey.SignKey())
+}
+
+// idTokenSubject returns the `sub` claim in the same format as GitHub, so the trust policies of cloud providers can be reused
+func idTokenSubject(run *actions_model.ActionRun, ref, environment string) string {
+	prefix := "repo:" + run.Repo.FullName()
+	switch {
+	case environment != "":
+		return prefix + ":environment:" + environment
+	case run.TriggerEvent == actions_module.GithubEventPullRequest || run.TriggerEvent == actions_module.GithubEventPullRequestTarget:
+		return prefix + ":pull_request"
+	default:
+		return prefix + ":ref:" + ref
+	}
+}
+
+func idTokenRepositoryVisibility(run *actions_model.ActionRun) string {
+	if run.Repo.IsPrivate {
+		return "private"
+	}
+	if run.Repo.Owner != nil && !run.Repo.Owner.Visibility.IsPublic() {
+		return "internal"
+	}
+	return "public"
+}
@@ -0,0 +1,157 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	actions_model "github.com/kumose/kmup/models/actions"
+	"github.com/kumose/kmup/models/db"
+	"github.com/kumose/kmup/models/unittest"
+	"github.com/kumose/kmup/modules/test"
+	"github.com/kumose/kmup/services/oauth2_provider"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanJobRequestIDToken(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	canRequest := func(t *testing.T, job *actions_model.ActionRunJob) bool {
+		ok, err := CanJobRequestIDToken(t.Context(), job)
+		require.NoError(t, err)
+		return ok
+	}
+
+	payload := []byte(`
+on: push
+permissions:
+  id-token: write
+jobs:
+  job1:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo
+`)
+	assert.True(t, canRequest(t, &actions_model.ActionRunJob{WorkflowPayload: payload}))
+	assert.False(t, canRequest(t, &actions_model.ActionRunJob{WorkflowPayload: payload, IsForkPullRequest: true}))
+
+	assert.False(t, canRequest(t, &actions_model.ActionRunJob{WorkflowPayload: []byte(`
+on: push
+permissions:
+  id-token: write
+jobs:
+  job1:
+    runs-on: ubuntu-latest
+    permissions:
+      contents: read
+    steps:
+      - run: echo
+`)}))
+
+	t.Run("CalledWorkflow", func(t *testing.T) {
+		caller := &actions_model.ActionRunJob{RunID: 791, RepoID: 4, OwnerID: 1, JobID: "call", WorkflowPayload: []byte(`
+on: push
+jobs:
+  call:
+    uses: ./.kmup/workflows/called.yml
+`)}
+		require.NoError(t, db.Insert(t.Context(), caller))
+
+		// the caller didn't grant the id-token permission
+		called := &actions_model.ActionRunJob{ParentJobID: caller.ID, WorkflowPayload: payload}
+		assert.False(t, canRequest(t, called))
+
+		caller.WorkflowPayload = []byte(`
+on: push
+jobs:
+  call:
+    permissions:
+      id-token: write
+    uses: ./.kmup/workflows/called.yml
+`)
+		_, err := db.GetEngine(t.Context()).ID(caller.ID).Cols("workflow_payload").Update(caller)
+		require.NoError(t, err)
+		assert.True(t, canRequest(t, called))
+
+		// the nested callers must grant it too
+		outerCaller := &actions_model.ActionRunJob{RunID: 791, RepoID: 4, OwnerID: 1, JobID: "outer", WorkflowPayload: []byte(`
+on: push
+permissions:
+  contents: read
+jobs:
+  outer:
+    uses: ./.kmup/workflows/caller.yml
+`)}
+		require.NoError(t, db.Insert(t.Context(), outerCaller))
+		caller.ParentJobID = outerCaller.ID
+		_, err = db.GetEngine(t.Context()).ID(caller.ID).Cols("parent_job_id").Update(caller)
+		require.NoError(t, err)
+		assert.False(t, canRequest(t, called))
+	})
+}
+
+func TestCreateIDToken(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	task := unittest.AssertExistsAndLoadBean(t, &actions_model.ActionTask{ID: 51})
+	require.NoError(t, task.LoadAttributes(t.Context()))
+
+	_, err := CreateIDToken(t.Context(), task, "")
+	assert.Error(t, err, "no asymmetric signing key")
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	signingKey, err := oauth2_provider.CreateJWTSigningKey("RS256", privateKey)
+	require.NoError(t, err)
+	defer test.MockVariableValue(&oauth2_provider.DefaultSigningKey, signingKey)()
+
+	_, err = CreateIDToken(t.Context(), task, "")
+	assert.Error(t, err, "no id-token permission")
+
+	task.Job.WorkflowPayload = []byte(`
+on: push
+jobs:
+  job2:
+    runs-on: ubuntu-latest
+    permissions:
+      id-token: write
+    steps:
+      - run: echo
+`)
+	task.Job.Environment = "production-${{ github.job }}"
+	token, err := CreateIDToken(t.Context(), task, "sts.example.com")
+	require.NoError(t, err)
+
+	claims := &IDTokenClaims{}
+	_, err = jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		return signingKey.VerifyKey(), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, IDTokenIssuer(), claims.Issuer)
+	assert.Equal(t, jwt.ClaimStrings{"sts.example.com"}, claims.Audience)
+	assert.Equal(t, "repo:user5/repo4:environment:production-job2", claims.Subject)
+	assert.Equal(t, "user5/repo4", claims.Repository)
+	assert.Equal(t, "793", claims.RunID)
+	assert.Equal(t, "job2", claims.Job)
+	assert.Equal(t, "production-job2", claims.Environment)
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/kumose/kmup/modules/log"
 	"github.com/kumose/kmup/modules/queue"
 	"github.com/kumose/kmup/modules/setting"
+	"github.com/kumose/kmup/modules/timeutil"
 	"github.com/kumose/kmup/modules/util"
 	notify_service "github.com/kumose/kmup/services/notify"
 
@@ -84,6 +85,18 @@ func checkJobsByRunID(ctx context.Context, runID int64) error {
 	if err != nil {
 		return fmt.Errorf("get action run: %w", err)
 	}
+	if run.NeedApproval {
+		// the jobs will be checked again after the run is approved
+		return nil
+	}
+	if run.Status.IsBlocked() {
+		// the run blocked by the workflow-level concurrency will be checked when the concurrent run is done
+		if shouldBlock, err := shouldBlockRunByConcurrency(ctx, run); err != nil {
+			return err
+		} else if shouldBlock {
+			return nil
+		}
+	}
 	var jobs, updatedJobs []*actions_model.ActionRunJob
 	if err := db.WithTx(ctx, func(ctx context.Context) error {
 		// check jobs of the current run
@@ -212,30 +225,73 @@ func checkRunConcurrency(ctx context.Context, run *actions_model.ActionRun) (job
 }
 
 func checkJobsOfRun(ctx context.Context, run *actions_model.ActionRun) (jobs, updatedJobs []*actions_model.ActionRunJob, err error) {
-	jobs, err = db.Find[actions_model.ActionRunJob](ctx, actions_model.FindRunJobOptions{RunID: run.ID})
-	if err != nil {
-		return nil, nil, err
-	}
 	vars, err := actions_model.GetVariablesOfRun(ctx, run)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	if err = db.WithTx(ctx, func(ctx context.Context) error {
-		for _, job := range jobs {
-			job.Run = run
-		}
+		// the jobs of called reusable workflows are inserted while checking, so check again until nothing changes
+		for changed := true; changed; {
+			changed = false
+			jobs, err = db.Find[actions_model.ActionRunJob](ctx, actions_model.FindRunJobOptions{RunID: run.ID})
+			if err != nil {
+				return err
+			}
+			jobMap := make(map[int64]*actions_model.ActionRunJob, len(jobs))
+			for _, job := range jobs {
+				job.Run = run
+				jobMap[job.ID] = job
+			}
 
-		updates := newJobStatusResolver(jobs, vars).Resolve(ctx)
-		for _, job := range jobs {
-			if status, ok := updates[job.ID]; ok {
+			syncedJobs, err := syncReusableWorkflowCallers(ctx, jobs)
+			if err != nil {
+				return err
+			}
+			updatedJobs = append(updatedJobs, syncedJobs...)
+			changed = len(syncedJobs) > 0
+
+			updates := newJobStatusResolver(jobs, vars).Resolve(ctx)
+			for _, job := range jobs {
+				status, ok := updates[job.ID]
+				if !ok {
+					continue
+				}
+				var childJobs []*actions_model.ActionRunJob
+				if status == actions_model.StatusWaiting && job.Environment != "" && job.EnvironmentID == 0 && !job.IsReusableWorkflowCaller() {
+					// the job has to satisfy the protection rules of its environment before running
+					status, err = prepareJobDeployment(ctx, job)
+					if err != nil {
+						return fmt.Errorf("prepare deployment of job %d: %w", job.ID, err)
+					}
+				} else if status == actions_model.StatusWaiting && job.IsReusableWorkflowCaller() && hasChildJobs(job, jobs) {
+					// the called workflow has been expanded before the job is rerun, its child jobs are rerun together with it
+					status = actions_model.StatusRunning
+					job.Started = timeutil.TimeStampNow()
+				} else if status == actions_model.StatusWaiting && job.IsReusableWorkflowCaller() {
+					childJobs, err = expandReusableWorkflowJob(ctx, job, jobMap, vars)
+					if err != nil && !isReusableWorkflowUserError(err) {
+						return fmt.Errorf("expand reusable workflow of job %d: %w", job.ID, err)
+					} else if err != nil {
+						log.Warn("Unable to call reusable workflow %q of job %d: %v", job.UsesWorkflow, job.ID, err)
+						status = actions_model.StatusFailure
+					} else {
+						status = actions_model.StatusRunning
+						job.Started = timeutil.TimeStampNow()
+					}
+				}
 				job.Status = status
-				if n, err := actions_model.UpdateRunJob(ctx, job, builder.Eq{"status": actions_model.StatusBlocked}, "status"); err != nil {
+				if status.IsDone() && !job.Started.IsZero() {
+					job.Stopped = timeutil.TimeStampNow()
+				}
+				if n, err := actions_model.UpdateRunJob(ctx, job, builder.Eq{"status": actions_model.StatusBlocked}, "status", "started", "stopped", "environment_id"); err != nil {
 					return err
 				} else if n != 1 {
 					return fmt.Errorf("no affected for updating blocked job %v", job.ID)
 				}
 				updatedJobs = append(updatedJobs, job)
+				updatedJobs = append(updatedJobs, childJobs...)
+				changed = true
 			}
 		}
 		return nil
@@ -262,11 +318,18 @@ type jobStatusResolver struct {
 	vars     map[string]string
 }
 
+// workflowJobKey identifies the jobs with the same job id in a workflow, the jobs of called reusable workflows are scoped by their caller
+type workflowJobKey struct {
+	ParentJobID int64
+	JobID       string
+}
+
 func newJobStatusResolver(jobs actions_model.ActionJobList, vars map[string]string) *jobStatusResolver {
-	idToJobs := make(map[string][]*actions_model.ActionRunJob, len(jobs))
+	idToJobs := make(map[workflowJobKey][]*actions_model.ActionRunJob, len(jobs))
 	jobMap := make(map[int64]*actions_model.ActionRunJob)
 	for _, job := range jobs {
-		idToJobs[job.JobID] = append(idToJobs[job.JobID], job)
+		key := workflowJobKey{ParentJobID: job.ParentJobID, JobID: job.JobID}
+		idToJobs[key] = append(idToJobs[key], job)
 		jobMap[job.ID] = job
 	}
 
@@ -275,7 +338,7 @@ func newJobStatusResolver(jobs actions_model.ActionJobList, vars map[string]stri
 	for _, job := range jobs {
 		statuses[job.ID] = job.Status
 		for _, need := range job.Needs {
-			for _, v := range idToJobs[need] {
+			for _, v := range idToJobs[workflowJobKey{ParentJobID: job.ParentJobID, JobID: need}] {
 				needs[job.ID] = append(needs[job.ID], v.ID)
 			}
 		}
@@ -332,6 +395,10 @@ func (r *jobStatusResolver) resolve(ctx context.Context) map[int64]actions_model
 		if status != actions_model.StatusBlocked {
 			continue
 		}
+		if r.statuses[actionRunJob.ParentJobID] == actions_model.StatusBlocked {
+			// the job of a called workflow can't start before its caller
+			continue
+		}
 		allDone, allSucceed := r.resolveCheckNeeds(id)
 		if !allDone {
 			continue
@@ -140,6 +140,16 @@ jobs:
 			},
 			want: map[int64]actions_model.Status{2: actions_model.StatusSkipped},
 		},
+		{
+			name: "needs are scoped by the called reusable workflow",
+			jobs: actions_model.ActionJobList{
+				{ID: 1, JobID: "build", Status: actions_model.StatusRunning, Needs: []string{}, UsesWorkflow: "./.kmup/workflows/build.yml"},
+				{ID: 2, JobID: "test", Status: actions_model.StatusBlocked, Needs: []string{"build"}},
+				{ID: 3, JobID: "build", ParentJobID: 1, Status: actions_model.StatusSuccess, Needs: []string{}},
+				{ID: 4, JobID: "test", ParentJobID: 1, Status: actions_model.StatusBlocked, Needs: []string{"build"}},
+			},
+			want: map[int64]actions_model.Status{4: actions_model.StatusWaiting},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
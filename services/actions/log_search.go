@@ -0,0 +1,118 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	actions_model "github.com/kumose/kmup/models/actions"
+	actions_module "github.com/kumose/kmup/modules/actions"
+	actions_indexer "github.com/kumose/kmup/modules/indexer/actions"
+	"github.com/kumose/kmup/modules/log"
+	"github.com/kumose/kmup/modules/util"
+)
+
+// LogSearchResult is a log line found by searching the actions log indexer
+type LogSearchResult struct {
+	Run        *actions_model.ActionRun
+	Job        *actions_model.ActionRunJob
+	Task       *actions_model.ActionTask
+	JobIndex   int
+	Step       int64
+	StepName   string
+	LineNumber int64 // starts at 1, like the line numbers in the job view
+	Line       string
+}
+
+// Link returns the link to the line in the job view
+func (r *LogSearchResult) Link() string {
+	link := fmt.Sprintf("%s/jobs/%d", r.Run.Link(), r.JobIndex)
+	if r.Task.RunAttempt != r.Run.Attempt {
+		link += fmt.Sprintf("?attempt=%d", r.Task.RunAttempt)
+	}
+	return fmt.Sprintf("%s#jobstep-%d-%d", link, r.Step, r.LineNumber)
+}
+
+// SearchLogs searches the actions log indexer and locates the matched lines.
+// The matches whose tasks or logs don't exist anymore are skipped.
+func SearchLogs(ctx context.Context, opts *actions_indexer.SearchOptions) ([]*LogSearchResult, int64, error) {
+	matches, total, err := actions_indexer.SearchLogs(ctx, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	runJobs := make(map[int64]actions_model.ActionJobList)
+	results := make([]*LogSearchResult, 0, len(matches))
+	for _, m := range matches {
+		task, err := actions_model.GetTaskByID(ctx, m.TaskID)
+		if err != nil {
+			if errors.Is(err, util.ErrNotExist) {
+				continue
+			}
+			return nil, 0, err
+		}
+		if task.LogExpired {
+			continue
+		}
+		if err := task.LoadAttributes(ctx); err != nil {
+			return nil, 0, err
+		}
+
+		jobs, ok := runJobs[task.Job.RunID]
+		if !ok {
+			if jobs, err = actions_model.GetRunJobsByRunID(ctx, task.Job.RunID); err != nil {
+				return nil, 0, err
+			}
+			runJobs[task.Job.RunID] = jobs
+		}
+		jobIndex := -1
+		for i, job := range jobs {
+			if job.ID == task.JobID {
+				jobIndex = i
+				break
+			}
+		}
+		if jobIndex < 0 {
+			continue
+		}
+
+		lines, err := actions_indexer.ReadMatchedLines(ctx, task, m)
+		if err != nil {
+			log.Warn("Unable to read the matched logs of task %d: %v", task.ID, err)
+			continue
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		lineIndex := actions_indexer.MatchLine(lines, opts.Keyword)
+
+		results = append(results, &LogSearchResult{
+			Run:        task.Job.Run,
+			Job:        task.Job,
+			Task:       task,
+			JobIndex:   jobIndex,
+			Step:       m.Step,
+			StepName:   actions_module.FullSteps(task)[m.Step].Name,
+			LineNumber: m.LineStart + int64(lineIndex) + 1,
+			Line:       lines[lineIndex],
+		})
+	}
+	return results, total, nil
+}
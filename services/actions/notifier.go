@@ -26,6 +26,7 @@ import (
 	packages_model "github.com/kumose/kmup/models/packages"
 	perm_model "github.com/kumose/kmup/models/perm"
 	access_model "github.com/kumose/kmup/models/perm/access"
+	pull_model "github.com/kumose/kmup/models/pull"
 	repo_model "github.com/kumose/kmup/models/repo"
 	user_model "github.com/kumose/kmup/models/user"
 	"github.com/kumose/kmup/modules/git"
@@ -724,6 +725,37 @@ func (n *actionsNotifier) PullRequestSynchronized(ctx context.Context, doer *use
 		Notify(ctx)
 }
 
+func (n *actionsNotifier) MergeGroupChecksRequested(ctx context.Context, doer *user_model.User, pr *issues_model.PullRequest, entry *pull_model.MergeQueueEntry) {
+	ctx = withMethod(ctx, "MergeGroupChecksRequested")
+
+	if err := pr.LoadIssue(ctx); err != nil {
+		log.Error("LoadIssue: %v", err)
+		return
+	}
+
+	if err := pr.Issue.LoadRepo(ctx); err != nil {
+		log.Error("pr.Issue.LoadRepo: %v", err)
+		return
+	}
+
+	groupRef := git.RefNameFromBranch(entry.GroupBranchName(pr.Index))
+	newNotifyInput(pr.Issue.Repo, doer, webhook_module.HookEventMergeGroup).
+		WithRef(groupRef.String()).
+		WithPayload(&api.MergeGroupPayload{
+			Action: api.HookMergeGroupChecksRequested,
+			MergeGroup: &api.MergeGroup{
+				HeadSHA:     entry.GroupCommitID,
+				HeadRef:     groupRef.String(),
+				BaseSHA:     entry.BaseCommitID,
+				BaseRef:     git.RefNameFromBranch(entry.BaseBranch).String(),
+				PullRequest: convert.ToAPIPullRequest(ctx, pr, nil),
+			},
+			Repository: convert.ToRepo(ctx, pr.Issue.Repo, access_model.Permission{AccessMode: perm_model.AccessModeNone}),
+			Sender:     convert.ToUser(ctx, doer, nil),
+		}).
+		Notify(ctx)
+}
+
 func (n *actionsNotifier) PullRequestChangeTargetBranch(ctx context.Context, doer *user_model.User, pr *issues_model.PullRequest, oldBranch string) {
 	ctx = withMethod(ctx, "PullRequestChangeTargetBranch")
 
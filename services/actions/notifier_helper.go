@@ -247,13 +247,19 @@ func notify(ctx context.Context, input *notifyInput) error {
 		}
 	}
 
+	// the required workflows of the org and the instance run alongside the repo's own workflows, and they can't be disabled by the repo
+	requiredWorkflows, err := detectRequiredWorkflows(ctx, input, gitRepo, commit)
+	if err != nil {
+		return fmt.Errorf("detectRequiredWorkflows: %w", err)
+	}
+
 	if shouldDetectSchedules {
 		if err := handleSchedules(ctx, schedules, commit, input, ref); err != nil {
 			return err
 		}
 	}
 
-	return handleWorkflows(ctx, detectedWorkflows, commit, input, ref)
+	return handleWorkflows(ctx, detectedWorkflows, requiredWorkflows, commit, input, ref)
 }
 
 func skipWorkflows(ctx context.Context, input *notifyInput, commit *git.Commit) bool {
@@ -303,11 +309,12 @@ func skipWorkflows(ctx context.Context, input *notifyInput, commit *git.Commit)
 func handleWorkflows(
 	ctx context.Context,
 	detectedWorkflows []*actions_module.DetectedWorkflow,
+	requiredWorkflows []*detectedRequiredWorkflow,
 	commit *git.Commit,
 	input *notifyInput,
 	ref git.RefName,
 ) error {
-	if len(detectedWorkflows) == 0 {
+	if len(detectedWorkflows) == 0 && len(requiredWorkflows) == 0 {
 		log.Trace("repo %s with commit %s couldn't find workflows", input.Repo.RelativePath(), commit.ID)
 		return nil
 	}
@@ -332,37 +339,44 @@ func handleWorkflows(
 		}
 	}
 
-	for _, dwf := range detectedWorkflows {
+	createRun := func(dwf *actions_module.DetectedWorkflow, requiredWorkflowID int64) {
 		run := &actions_model.ActionRun{
-			Title:             strings.SplitN(commit.CommitMessage, "\n", 2)[0],
-			RepoID:            input.Repo.ID,
-			Repo:              input.Repo,
-			OwnerID:           input.Repo.OwnerID,
-			WorkflowID:        dwf.EntryName,
-			TriggerUserID:     input.Doer.ID,
-			TriggerUser:       input.Doer,
-			Ref:               ref.String(),
-			CommitSHA:         commit.ID.String(),
-			IsForkPullRequest: isForkPullRequest,
-			Event:             input.Event,
-			EventPayload:      string(p),
-			TriggerEvent:      dwf.TriggerEvent.Name,
-			Status:            actions_model.StatusWaiting,
+			Title:              strings.SplitN(commit.CommitMessage, "\n", 2)[0],
+			RepoID:             input.Repo.ID,
+			Repo:               input.Repo,
+			OwnerID:            input.Repo.OwnerID,
+			WorkflowID:         dwf.EntryName,
+			RequiredWorkflowID: requiredWorkflowID,
+			TriggerUserID:      input.Doer.ID,
+			TriggerUser:        input.Doer,
+			Ref:                ref.String(),
+			CommitSHA:          commit.ID.String(),
+			IsForkPullRequest:  isForkPullRequest,
+			Event:              input.Event,
+			EventPayload:       string(p),
+			TriggerEvent:       dwf.TriggerEvent.Name,
+			Status:             actions_model.StatusWaiting,
 		}
 
 		need, err := ifNeedApproval(ctx, run, input.Repo, input.Doer)
 		if err != nil {
 			log.Error("check if need approval for repo %d with user %d: %v", input.Repo.ID, input.Doer.ID, err)
-			continue
+			return
 		}
 
 		run.NeedApproval = need
 
 		if err := PrepareRunAndInsert(ctx, dwf.Content, run, nil); err != nil {
 			log.Error("PrepareRunAndInsert: %v", err)
-			continue
 		}
 	}
+
+	for _, dwf := range detectedWorkflows {
+		createRun(dwf, 0)
+	}
+	for _, rdwf := range requiredWorkflows {
+		createRun(rdwf.DetectedWorkflow, rdwf.RequiredWorkflow.ID)
+	}
 	return nil
 }
 
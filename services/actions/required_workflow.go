@@ -0,0 +1,128 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"context"
+	"strings"
+
+	actions_model "github.com/kumose/kmup/models/actions"
+	repo_model "github.com/kumose/kmup/models/repo"
+	actions_module "github.com/kumose/kmup/modules/actions"
+	"github.com/kumose/kmup/modules/git"
+	"github.com/kumose/kmup/modules/gitrepo"
+	"github.com/kumose/kmup/modules/log"
+	"github.com/kumose/kmup/modules/util"
+)
+
+// detectedRequiredWorkflow is a detected workflow of an org- or instance-level required workflow
+type detectedRequiredWorkflow struct {
+	*actions_module.DetectedWorkflow
+	RequiredWorkflow *actions_model.ActionRequiredWorkflow
+}
+
+// loadRequiredWorkflowContent loads the content of the workflow file from the central repository
+func loadRequiredWorkflowContent(ctx context.Context, rw *actions_model.ActionRequiredWorkflow) ([]byte, error) {
+	if err := rw.LoadRepo(ctx); err != nil {
+		return nil, err
+	}
+	gitRepo, err := gitrepo.OpenRepository(ctx, rw.Repo)
+	if err != nil {
+		return nil, err
+	}
+	defer gitRepo.Close()
+
+	ref := rw.Ref
+	if ref == "" {
+		ref = rw.Repo.DefaultBranch
+	}
+	commit, err := gitRepo.GetBranchCommit(ref)
+	if git.IsErrNotExist(err) {
+		return nil, util.NewNotExistErrorf("branch %q of repository %s doesn't exist", ref, rw.Repo.FullName())
+	} else if err != nil {
+		return nil, err
+	}
+	entry, err := commit.GetTreeEntryByPath(rw.WorkflowPath)
+	if git.IsErrNotExist(err) {
+		return nil, util.NewNotExistErrorf("workflow %q doesn't exist in repository %s", rw.WorkflowPath, rw.Repo.FullName())
+	} else if err != nil {
+		return nil, err
+	}
+	return actions_module.GetContentFromEntry(entry)
+}
+
+// detectRequiredWorkflows returns the required workflows of the repository which match the triggered event on the commit.
+// A required workflow which can't be loaded is skipped, so it doesn't stop the repository's own workflows.
+func detectRequiredWorkflows(ctx context.Context, input *notifyInput, gitRepo *git.Repository, commit *git.Commit) ([]*detectedRequiredWorkflow, error) {
+	rws, err := actions_model.GetRequiredWorkflowsForRepo(ctx, input.Repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var detected []*detectedRequiredWorkflow
+	for _, rw := range rws {
+		content, err := loadRequiredWorkflowContent(ctx, rw)
+		if err != nil {
+			log.Warn("ignore required workflow %d: %v", rw.ID, err)
+			continue
+		}
+		workflows, err := actions_module.DetectWorkflowContent(gitRepo, commit, rw.EntryName(), content, input.Event, input.Payload)
+		if err != nil {
+			log.Warn("ignore invalid required workflow %d: %v", rw.ID, err)
+			continue
+		}
+		for _, wf := range workflows {
+			detected = append(detected, &detectedRequiredWorkflow{DetectedWorkflow: wf, RequiredWorkflow: rw})
+		}
+	}
+	return detected, nil
+}
+
+// CreateRequiredWorkflow makes the workflow file of the repository required for the repositories of the owner,
+// or for the repositories of all owners if ownerID is 0
+func CreateRequiredWorkflow(ctx context.Context, ownerID int64, repo *repo_model.Repository, workflowPath, ref string, repoPatterns, topics []string) (*actions_model.ActionRequiredWorkflow, error) {
+	if ownerID != 0 && repo.OwnerID != ownerID {
+		return nil, util.NewInvalidArgumentErrorf("repository %s doesn't belong to the owner", repo.FullName())
+	}
+	workflowPath = strings.TrimPrefix(strings.TrimSpace(workflowPath), "/")
+	if !actions_module.IsWorkflow(workflowPath) {
+		return nil, util.NewInvalidArgumentErrorf("%q is not a workflow file", workflowPath)
+	}
+	if err := actions_model.ValidateRepoPatterns(repoPatterns); err != nil {
+		return nil, err
+	}
+
+	rw := &actions_model.ActionRequiredWorkflow{
+		OwnerID:      ownerID,
+		RepoID:       repo.ID,
+		Repo:         repo,
+		WorkflowPath: workflowPath,
+		Ref:          strings.TrimSpace(ref),
+		RepoPatterns: repoPatterns,
+		Topics:       topics,
+	}
+	content, err := loadRequiredWorkflowContent(ctx, rw)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := actions_module.GetEventsFromContent(content); err != nil {
+		return nil, util.NewInvalidArgumentErrorf("invalid workflow %q: %v", workflowPath, err)
+	}
+
+	return rw, actions_model.CreateRequiredWorkflow(ctx, rw)
+}
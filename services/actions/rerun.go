@@ -18,27 +18,229 @@
 package actions
 
 import (
+	"context"
+	"fmt"
+
 	actions_model "github.com/kumose/kmup/models/actions"
+	"github.com/kumose/kmup/models/db"
+	"github.com/kumose/kmup/models/unit"
 	"github.com/kumose/kmup/modules/container"
+	"github.com/kumose/kmup/modules/log"
+	"github.com/kumose/kmup/modules/util"
+	notify_service "github.com/kumose/kmup/services/notify"
+
+	act_model "github.com/nektos/act/pkg/model"
+	"gopkg.in/yaml.v3"
+	"xorm.io/builder"
 )
 
+var (
+	ErrRerunRunNotDone       = util.NewInvalidArgumentErrorf("run is not done")
+	ErrRerunWorkflowDisabled = util.NewInvalidArgumentErrorf("workflow is disabled")
+	ErrRerunNoFailedJobs     = util.NewInvalidArgumentErrorf("run has no failed jobs")
+)
+
+// RerunOptions are the options to rerun a run
+type RerunOptions struct {
+	// Job is the job to rerun with the jobs depending on it, all jobs are rerun if it is nil
+	Job *actions_model.ActionRunJob
+	// FailedOnly reruns only the failed or cancelled jobs and the jobs depending on them
+	FailedOnly bool
+	// DebugLogging enables the debug logs of the runner and the steps for the rerun jobs
+	DebugLogging bool
+}
+
+// RerunRun reruns the jobs of a done run as a new attempt of the run.
+// The tasks and the artifacts of the previous attempts are kept, so their logs and artifacts are still available.
+func RerunRun(ctx context.Context, run *actions_model.ActionRun, jobs []*actions_model.ActionRunJob, opts RerunOptions) error {
+	if !run.Status.IsDone() {
+		return ErrRerunRunNotDone
+	}
+	if err := run.LoadAttributes(ctx); err != nil {
+		return err
+	}
+
+	// can not rerun job when workflow is disabled
+	cfg := run.Repo.MustGetUnit(ctx, unit.TypeActions).ActionsConfig()
+	if run.RequiredWorkflowID == 0 && cfg.IsWorkflowDisabled(run.WorkflowID) {
+		return ErrRerunWorkflowDisabled
+	}
+
+	var rerunJobs []*actions_model.ActionRunJob
+	switch {
+	case opts.Job != nil:
+		rerunJobs = GetAllRerunJobs(opts.Job, jobs)
+	case opts.FailedOnly:
+		rerunJobs = GetFailedRerunJobs(jobs)
+		if len(rerunJobs) == 0 {
+			return ErrRerunNoFailedJobs
+		}
+	default:
+		rerunJobs = jobs
+	}
+
+	// reset run's start and stop time
+	run.PreviousDuration = run.Duration()
+	run.Started = 0
+	run.Stopped = 0
+	run.Status = actions_model.StatusWaiting
+	run.Attempt++
+
+	vars, err := actions_model.GetVariablesOfRun(ctx, run)
+	if err != nil {
+		return fmt.Errorf("get run %d variables: %w", run.ID, err)
+	}
+
+	if run.RawConcurrency != "" {
+		var rawConcurrency act_model.RawConcurrency
+		if err := yaml.Unmarshal([]byte(run.RawConcurrency), &rawConcurrency); err != nil {
+			return fmt.Errorf("unmarshal raw concurrency: %w", err)
+		}
+
+		if err := EvaluateRunConcurrencyFillModel(ctx, run, &rawConcurrency, vars); err != nil {
+			return fmt.Errorf("evaluate run concurrency: %w", err)
+		}
+
+		run.Status, err = PrepareToStartRunWithConcurrency(ctx, run)
+		if err != nil {
+			return err
+		}
+	}
+	if err := actions_model.UpdateRun(ctx, run, "started", "stopped", "previous_duration", "status", "attempt", "concurrency_group", "concurrency_cancel"); err != nil {
+		return err
+	}
+	notify_service.WorkflowRunStatusUpdate(ctx, run.Repo, run.TriggerUser, run)
+
+	rerunJobsKeySet := make(container.Set[workflowJobKey], len(rerunJobs))
+	rerunJobsIDSet := make(container.Set[int64], len(rerunJobs))
+	for _, j := range rerunJobs {
+		rerunJobsKeySet.Add(workflowJobKey{ParentJobID: j.ParentJobID, JobID: j.JobID})
+		rerunJobsIDSet.Add(j.ID)
+	}
+
+	isRunBlocked := run.Status == actions_model.StatusBlocked
+	for _, j := range rerunJobs {
+		// a job should wait for the rerun jobs it needs, and the jobs of a called reusable workflow should wait for their rerun caller
+		shouldBlockJob := isRunBlocked || rerunJobsIDSet.Contains(j.ParentJobID)
+		for _, need := range j.Needs {
+			shouldBlockJob = shouldBlockJob || rerunJobsKeySet.Contains(workflowJobKey{ParentJobID: j.ParentJobID, JobID: need})
+		}
+		j.Run = run
+		if err := rerunJob(ctx, j, shouldBlockJob, opts.DebugLogging); err != nil {
+			return fmt.Errorf("rerun job %d: %w", j.ID, err)
+		}
+	}
+	EmitJobsIfRequired(run, jobs)
+
+	return nil
+}
+
+// EmitJobsIfRequired lets the job emitter start the jobs calling reusable workflows and update their status
+func EmitJobsIfRequired(run *actions_model.ActionRun, jobs []*actions_model.ActionRunJob) {
+	for _, j := range jobs {
+		if j.IsReusableWorkflowCaller() {
+			if err := EmitJobsIfReadyByRun(run.ID); err != nil {
+				log.Error("Check jobs of run %d: %v", run.ID, err)
+			}
+			return
+		}
+	}
+}
+
+func rerunJob(ctx context.Context, job *actions_model.ActionRunJob, shouldBlock, debugLogging bool) error {
+	status := job.Status
+	if !status.IsDone() {
+		return nil
+	}
+
+	// a job calling a reusable workflow or deploying to an environment is started by the job emitter
+	shouldBlock = shouldBlock || job.RequiresJobEmitter()
+
+	job.TaskID = 0
+	job.EnvironmentID = 0
+	job.Status = util.Iif(shouldBlock, actions_model.StatusBlocked, actions_model.StatusWaiting)
+	job.Started = 0
+	job.Stopped = 0
+	job.DebugLogging = debugLogging
+
+	job.ConcurrencyGroup = ""
+	job.ConcurrencyCancel = false
+	job.IsConcurrencyEvaluated = false
+
+	vars, err := actions_model.GetVariablesOfRun(ctx, job.Run)
+	if err != nil {
+		return fmt.Errorf("get run %d variables: %w", job.Run.ID, err)
+	}
+
+	if job.RawConcurrency != "" && !shouldBlock {
+		err = EvaluateJobConcurrencyFillModel(ctx, job.Run, job, vars)
+		if err != nil {
+			return fmt.Errorf("evaluate job concurrency: %w", err)
+		}
+
+		job.Status, err = PrepareToStartJobWithConcurrency(ctx, job)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := db.WithTx(ctx, func(ctx context.Context) error {
+		updateCols := []string{"task_id", "environment_id", "status", "started", "stopped", "debug_logging", "concurrency_group", "concurrency_cancel", "is_concurrency_evaluated"}
+		_, err := actions_model.UpdateRunJob(ctx, job, builder.Eq{"status": status}, updateCols...)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	CreateCommitStatusForRunJobs(ctx, job.Run, job)
+	notify_service.WorkflowJobStatusUpdate(ctx, job.Run.Repo, job.Run.TriggerUser, job, nil)
+
+	return nil
+}
+
 // GetAllRerunJobs get all jobs that need to be rerun when job should be rerun
 func GetAllRerunJobs(job *actions_model.ActionRunJob, allJobs []*actions_model.ActionRunJob) []*actions_model.ActionRunJob {
 	rerunJobs := []*actions_model.ActionRunJob{job}
-	rerunJobsIDSet := make(container.Set[string])
-	rerunJobsIDSet.Add(job.JobID)
+	rerunJobsKeySet := make(container.Set[workflowJobKey])
+	rerunJobsKeySet.Add(workflowJobKey{ParentJobID: job.ParentJobID, JobID: job.JobID})
+
+	// the callers of the reusable workflow which the job belongs to will run again, so the jobs depending on them should be rerun too
+	jobsByID := make(map[int64]*actions_model.ActionRunJob, len(allJobs))
+	for _, j := range allJobs {
+		jobsByID[j.ID] = j
+	}
+	for parentID := job.ParentJobID; parentID > 0; {
+		parent, ok := jobsByID[parentID]
+		if !ok {
+			break
+		}
+		rerunJobsKeySet.Add(workflowJobKey{ParentJobID: parent.ParentJobID, JobID: parent.JobID})
+		parentID = parent.ParentJobID
+	}
+
+	rerunJobsIDSet := make(container.Set[int64])
+	rerunJobsIDSet.Add(job.ID)
 
 	for {
 		found := false
 		for _, j := range allJobs {
-			if rerunJobsIDSet.Contains(j.JobID) {
+			if rerunJobsKeySet.Contains(workflowJobKey{ParentJobID: j.ParentJobID, JobID: j.JobID}) {
+				continue
+			}
+			// the jobs of a called workflow are rerun with their caller
+			if j.ParentJobID > 0 && rerunJobsIDSet.Contains(j.ParentJobID) {
+				found = true
+				rerunJobs = append(rerunJobs, j)
+				rerunJobsKeySet.Add(workflowJobKey{ParentJobID: j.ParentJobID, JobID: j.JobID})
+				rerunJobsIDSet.Add(j.ID)
 				continue
 			}
 			for _, need := range j.Needs {
-				if rerunJobsIDSet.Contains(need) {
+				if rerunJobsKeySet.Contains(workflowJobKey{ParentJobID: j.ParentJobID, JobID: need}) {
 					found = true
 					rerunJobs = append(rerunJobs, j)
-					rerunJobsIDSet.Add(j.JobID)
+					rerunJobsKeySet.Add(workflowJobKey{ParentJobID: j.ParentJobID, JobID: j.JobID})
+					rerunJobsIDSet.Add(j.ID)
 					break
 				}
 			}
@@ -50,3 +252,32 @@ func GetAllRerunJobs(job *actions_model.ActionRunJob, allJobs []*actions_model.A
 
 	return rerunJobs
 }
+
+// GetFailedRerunJobs gets the failed or cancelled jobs of a run and all jobs that need to be rerun with them
+func GetFailedRerunJobs(allJobs []*actions_model.ActionRunJob) []*actions_model.ActionRunJob {
+	isFailed := func(j *actions_model.ActionRunJob) bool {
+		return j.Status == actions_model.StatusFailure || j.Status == actions_model.StatusCancelled
+	}
+
+	// a caller of a reusable workflow fails with its jobs, only the failed jobs of the called workflow are rerun then
+	callersWithFailedJobs := make(container.Set[int64])
+	for _, j := range allJobs {
+		if j.ParentJobID > 0 && isFailed(j) {
+			callersWithFailedJobs.Add(j.ParentJobID)
+		}
+	}
+
+	var rerunJobs []*actions_model.ActionRunJob
+	rerunJobsIDSet := make(container.Set[int64])
+	for _, j := range allJobs {
+		if !isFailed(j) || callersWithFailedJobs.Contains(j.ID) || rerunJobsIDSet.Contains(j.ID) {
+			continue
+		}
+		for _, rj := range GetAllRerunJobs(j, allJobs) {
+			if rerunJobsIDSet.Add(rj.ID) {
+				rerunJobs = append(rerunJobs, rj)
+			}
+		}
+	}
+	return rerunJobs
+}
@@ -21,8 +21,10 @@ import (
 	"testing"
 
 	actions_model "github.com/kumose/kmup/models/actions"
+	"github.com/kumose/kmup/models/unittest"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGetAllRerunJobs(t *testing.T) {
@@ -60,3 +62,93 @@ func TestGetAllRerunJobs(t *testing.T) {
 		assert.ElementsMatch(t, tc.rerunJobs, rerunJobs)
 	}
 }
+
+func TestGetAllRerunJobsOfReusableWorkflow(t *testing.T) {
+	build := &actions_model.ActionRunJob{ID: 1, JobID: "build", UsesWorkflow: "./.kmup/workflows/build.yml"}
+	deploy := &actions_model.ActionRunJob{ID: 2, JobID: "deploy", Needs: []string{"build"}}
+	compile := &actions_model.ActionRunJob{ID: 3, JobID: "compile", ParentJobID: 1}
+	test := &actions_model.ActionRunJob{ID: 4, JobID: "test", ParentJobID: 1, Needs: []string{"compile"}}
+	// a job of the caller's workflow with the same job id as a job of the called workflow
+	otherTest := &actions_model.ActionRunJob{ID: 5, JobID: "test", Needs: []string{"deploy"}}
+
+	jobs := []*actions_model.ActionRunJob{build, deploy, compile, test, otherTest}
+
+	testCases := []struct {
+		job       *actions_model.ActionRunJob
+		rerunJobs []*actions_model.ActionRunJob
+	}{
+		{
+			build,
+			[]*actions_model.ActionRunJob{build, deploy, compile, test, otherTest},
+		},
+		{
+			compile,
+			[]*actions_model.ActionRunJob{compile, test, deploy, otherTest},
+		},
+		{
+			test,
+			[]*actions_model.ActionRunJob{test, deploy, otherTest},
+		},
+		{
+			otherTest,
+			[]*actions_model.ActionRunJob{otherTest},
+		},
+	}
+
+	for _, tc := range testCases {
+		rerunJobs := GetAllRerunJobs(tc.job, jobs)
+		assert.ElementsMatch(t, tc.rerunJobs, rerunJobs)
+	}
+}
+
+func TestGetFailedRerunJobs(t *testing.T) {
+	job1 := &actions_model.ActionRunJob{ID: 1, JobID: "job1", Status: actions_model.StatusSuccess}
+	job2 := &actions_model.ActionRunJob{ID: 2, JobID: "job2", Needs: []string{"job1"}, Status: actions_model.StatusFailure}
+	job3 := &actions_model.ActionRunJob{ID: 3, JobID: "job3", Needs: []string{"job2"}, Status: actions_model.StatusSkipped}
+	job4 := &actions_model.ActionRunJob{ID: 4, JobID: "job4", Status: actions_model.StatusCancelled}
+	job5 := &actions_model.ActionRunJob{ID: 5, JobID: "job5", Needs: []string{"job1"}, Status: actions_model.StatusSuccess}
+
+	assert.ElementsMatch(t, []*actions_model.ActionRunJob{job2, job3, job4}, GetFailedRerunJobs([]*actions_model.ActionRunJob{job1, job2, job3, job4, job5}))
+	assert.Empty(t, GetFailedRerunJobs([]*actions_model.ActionRunJob{job1, job5}))
+
+	// only the failed jobs of a called reusable workflow are rerun, not all jobs of its caller
+	build := &actions_model.ActionRunJob{ID: 11, JobID: "build", UsesWorkflow: "./.kmup/workflows/build.yml", Status: actions_model.StatusFailure}
+	deploy := &actions_model.ActionRunJob{ID: 12, JobID: "deploy", Needs: []string{"build"}, Status: actions_model.StatusSkipped}
+	compile := &actions_model.ActionRunJob{ID: 13, JobID: "compile", ParentJobID: 11, Status: actions_model.StatusSuccess}
+	test := &actions_model.ActionRunJob{ID: 14, JobID: "test", ParentJobID: 11, Needs: []string{"compile"}, Status: actions_model.StatusFailure}
+
+	assert.ElementsMatch(t, []*actions_model.ActionRunJob{test, deploy}, GetFailedRerunJobs([]*actions_model.ActionRunJob{build, deploy, compile, test}))
+}
+
+func TestRerunRun(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	// the run has no failed jobs
+	run := unittest.AssertExistsAndLoadBean(t, &actions_model.ActionRun{ID: 791})
+	jobs, err := actions_model.GetRunJobsByRunID(t.Context(), run.ID)
+	require.NoError(t, err)
+	assert.ErrorIs(t, RerunRun(t.Context(), run, jobs, RerunOptions{FailedOnly: true}), ErrRerunNoFailedJobs)
+	unittest.AssertExistsAndLoadBean(t, &actions_model.ActionRun{ID: 791, Attempt: 1})
+
+	run = unittest.AssertExistsAndLoadBean(t, &actions_model.ActionRun{ID: 795})
+	jobs, err = actions_model.GetRunJobsByRunID(t.Context(), run.ID)
+	require.NoError(t, err)
+	require.NoError(t, RerunRun(t.Context(), run, jobs, RerunOptions{FailedOnly: true, DebugLogging: true}))
+	assert.EqualValues(t, 2, run.Attempt)
+
+	// only the failed job is rerun, with debug logging
+	succeeded := unittest.AssertExistsAndLoadBean(t, &actions_model.ActionRunJob{ID: 198})
+	assert.Equal(t, actions_model.StatusSuccess, succeeded.Status)
+	assert.EqualValues(t, 53, succeeded.TaskID)
+	assert.False(t, succeeded.DebugLogging)
+	failed := unittest.AssertExistsAndLoadBean(t, &actions_model.ActionRunJob{ID: 199})
+	assert.Equal(t, actions_model.StatusWaiting, failed.Status)
+	assert.Zero(t, failed.TaskID)
+	assert.True(t, failed.DebugLogging)
+
+	// the task of the previous attempt is kept
+	unittest.AssertExistsAndLoadBean(t, &actions_model.ActionTask{ID: 54, JobID: 199})
+
+	// the run is not done anymore
+	assert.ErrorIs(t, RerunRun(t.Context(), run, jobs, RerunOptions{}), ErrRerunRunNotDone)
+}
@@ -0,0 +1,400 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	actions_model "github.com/kumose/kmup/models/actions"
+	repo_model "github.com/kumose/kmup/models/repo"
+	"github.com/kumose/kmup/models/unit"
+	actions_module "github.com/kumose/kmup/modules/actions"
+	"github.com/kumose/kmup/modules/git"
+	"github.com/kumose/kmup/modules/gitrepo"
+	"github.com/kumose/kmup/modules/timeutil"
+	"github.com/kumose/kmup/modules/util"
+
+	"github.com/nektos/act/pkg/exprparser"
+	"github.com/nektos/act/pkg/jobparser"
+	act_model "github.com/nektos/act/pkg/model"
+	"gopkg.in/yaml.v3"
+	"xorm.io/builder"
+)
+
+// maxReusableWorkflowDepth is the max number of nested reusable workflows, the triggered workflow is not counted.
+// See https://docs.github.com/en/actions/sharing-automations/reusing-workflows#nesting-reusable-workflows
+const maxReusableWorkflowDepth = 4
+
+// isReusableWorkflowUserError returns true if the error is caused by the workflow files or the permissions rather than the system,
+// such an error fails the calling job instead of retrying.
+func isReusableWorkflowUserError(err error) bool {
+	return errors.Is(err, util.ErrInvalidArgument) || errors.Is(err, util.ErrNotExist) || errors.Is(err, util.ErrPermissionDenied)
+}
+
+// canCallReusableWorkflow checks whether the workflows of callerRepo can call the reusable workflows in calledRepo.
+// Like accessing the actions of a private repository with a task token, the caller repository must be private and
+// its owner must be a collaborative owner of the called repository if the called repository isn't public,
+// otherwise the content of a private workflow would be exposed by the logs of a public run.
+func canCallReusableWorkflow(ctx context.Context, callerRepo, calledRepo *repo_model.Repository) (bool, error) {
+	if callerRepo.ID == calledRepo.ID {
+		return true, nil
+	}
+	if err := calledRepo.LoadOwner(ctx); err != nil {
+		return false, err
+	}
+	if !calledRepo.IsPrivate && calledRepo.Owner.Visibility.IsPublic() {
+		return true, nil
+	}
+	actionsUnit, err := calledRepo.GetUnit(ctx, unit.TypeActions)
+	if repo_model.IsErrUnitTypeNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return callerRepo.IsPrivate && actionsUnit.ActionsConfig().IsCollaborativeOwner(callerRepo.OwnerID), nil
+}
+
+// loadReusableWorkflowContent loads the content of the called workflow, and returns the repository and the commit it's loaded from
+func loadReusableWorkflowContent(ctx context.Context, run *actions_model.ActionRun, ref *actions_module.ReusableWorkflowRef) ([]byte, *repo_model.Repository, string, error) {
+	repo, commitID := run.Repo, run.CommitSHA
+	if !ref.IsLocal() {
+		var err error
+		repo, err = repo_model.GetRepositoryByOwnerAndName(ctx, ref.OwnerName, ref.RepoName)
+		if repo_model.IsErrRepoNotExist(err) {
+			return nil, nil, "", util.NewNotExistErrorf("repository %s/%s doesn't exist", ref.OwnerName, ref.RepoName)
+		} else if err != nil {
+			return nil, nil, "", err
+		}
+		ok, err := canCallReusableWorkflow(ctx, run.Repo, repo)
+		if err != nil {
+			return nil, nil, "", err
+		} else if !ok {
+			return nil, nil, "", util.NewPermissionDeniedErrorf("repository %s is not allowed to call the workflows of %s", run.Repo.FullName(), repo.FullName())
+		}
+		commitID = ref.Ref
+	}
+
+	gitRepo, err := gitrepo.OpenRepository(ctx, repo)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	defer gitRepo.Close()
+
+	commit, err := gitRepo.GetCommit(commitID)
+	if git.IsErrNotExist(err) {
+		return nil, nil, "", util.NewNotExistErrorf("ref %q of repository %s doesn't exist", commitID, repo.FullName())
+	} else if err != nil {
+		return nil, nil, "", err
+	}
+	entry, err := commit.GetTreeEntryByPath(ref.Path)
+	if git.IsErrNotExist(err) {
+		return nil, nil, "", util.NewNotExistErrorf("workflow %q doesn't exist in repository %s", ref.Path, repo.FullName())
+	} else if err != nil {
+		return nil, nil, "", err
+	}
+	content, err := actions_module.GetContentFromEntry(entry)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return content, repo, commit.ID.String(), nil
+}
+
+// getReusableWorkflowDepth returns how many reusable workflows the job is nested in
+func getReusableWorkflowDepth(job *actions_model.ActionRunJob, jobMap map[int64]*actions_model.ActionRunJob) int {
+	depth := 0
+	for parentID := job.ParentJobID; parentID > 0; depth++ {
+		parent, ok := jobMap[parentID]
+		if !ok {
+			break
+		}
+		parentID = parent.ParentJobID
+	}
+	return depth
+}
+
+// evaluateWorkflowCallWith evaluates the expressions in the `with` of a job calling a reusable workflow
+func evaluateWorkflowCallWith(ctx context.Context, run *actions_model.ActionRun, caller *actions_model.ActionRunJob, workflowJob *jobparser.Job, vars map[string]string) (map[string]any, error) {
+	evaluator, err := newJobExpressionEvaluator(ctx, run, caller, workflowJob, vars)
+	if err != nil {
+		return nil, err
+	}
+	with := make(map[string]any, len(workflowJob.With))
+	for name, value := range workflowJob.With {
+		if s, ok := value.(string); ok {
+			with[name] = evaluator.Interpolate(s)
+		} else {
+			with[name] = value
+		}
+	}
+	return with, nil
+}
+
+// expandReusableWorkflowJob loads the workflow called by the job, and inserts the jobs of the called workflow as the child jobs of the caller.
+// The returned error is a user error (see isReusableWorkflowUserError) if the called workflow is invalid or not accessible.
+func expandReusableWorkflowJob(ctx context.Context, caller *actions_model.ActionRunJob, jobMap map[int64]*actions_model.ActionRunJob, vars map[string]string) ([]*actions_model.ActionRunJob, error) {
+	run := caller.Run
+	if err := run.LoadAttributes(ctx); err != nil {
+		return nil, err
+	}
+
+	if getReusableWorkflowDepth(caller, jobMap) >= maxReusableWorkflowDepth {
+		return nil, util.NewInvalidArgumentErrorf("reusable workflows can't be nested more than %d levels", maxReusableWorkflowDepth)
+	}
+
+	ref, err := actions_module.ParseReusableWorkflowRef(caller.UsesWorkflow)
+	if err != nil {
+		return nil, util.NewInvalidArgumentErrorf("%v", err)
+	}
+	content, calledRepo, calledCommitID, err := loadReusableWorkflowContent(ctx, run, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := actions_module.GetWorkflowCallConfig(content)
+	if err != nil {
+		return nil, util.NewInvalidArgumentErrorf("invalid workflow %s: %v", ref, err)
+	} else if config == nil {
+		return nil, util.NewInvalidArgumentErrorf("workflow %s is not triggered by %s", ref, actions_module.GithubEventWorkflowCall)
+	}
+
+	workflowJob, err := caller.ParseJob()
+	if err != nil {
+		return nil, util.NewInvalidArgumentErrorf("%v", err)
+	}
+	with, err := evaluateWorkflowCallWith(ctx, run, caller, workflowJob, vars)
+	if err != nil {
+		return nil, err
+	}
+	inputs, err := actions_module.ResolveWorkflowCallInputs(config, with)
+	if err != nil {
+		return nil, util.NewInvalidArgumentErrorf("call workflow %s: %v", ref, err)
+	}
+
+	// the runner doesn't know the caller, so the inputs are replaced with their values before parsing
+	content, err = actions_module.ReplaceWorkflowCallInputs(content, inputs)
+	if err != nil {
+		return nil, util.NewInvalidArgumentErrorf("invalid workflow %s: %v", ref, err)
+	}
+	environments, err := actions_module.GetJobEnvironments(content)
+	if err != nil {
+		return nil, util.NewInvalidArgumentErrorf("invalid workflow %s: %v", ref, err)
+	}
+	gitCtx := GenerateKmupContext(run, nil)
+	workflows, err := jobparser.Parse(content, jobparser.WithVars(vars), jobparser.WithGitContext(gitCtx.ToGitHubContext()), jobparser.WithInputs(inputs))
+	if err != nil {
+		return nil, util.NewInvalidArgumentErrorf("parse workflow %s: %v", ref, err)
+	}
+
+	// a nested workflow in the same repository as a called workflow from another repository
+	// should be loaded from the called repository rather than the repository of the run
+	if !ref.IsLocal() {
+		for _, swf := range workflows {
+			id, job := swf.Job()
+			if path, ok := strings.CutPrefix(job.Uses, "./"); ok {
+				job.Uses = fmt.Sprintf("%s/%s/%s@%s", calledRepo.OwnerName, calledRepo.Name, path, calledCommitID)
+				if err := swf.SetJob(id, job); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	caller.WorkflowCallInputs = inputs
+	if _, err := actions_model.UpdateRunJob(ctx, caller, nil, "workflow_call_inputs"); err != nil {
+		return nil, err
+	}
+
+	childJobs, hasWaitingJobs, err := insertRunJobs(ctx, run, caller, workflows, environments, vars)
+	if err != nil {
+		return nil, err
+	}
+	if hasWaitingJobs {
+		if err := actions_model.IncreaseTaskVersion(ctx, run.OwnerID, run.RepoID); err != nil {
+			return nil, err
+		}
+	}
+	return childJobs, nil
+}
+
+// syncReusableWorkflowCallers updates the status of the jobs calling reusable workflows according to their child jobs,
+// a caller is running until all its child jobs are done.
+func syncReusableWorkflowCallers(ctx context.Context, jobs []*actions_model.ActionRunJob) ([]*actions_model.ActionRunJob, error) {
+	childJobs := make(map[int64][]*actions_model.ActionRunJob)
+	for _, job := range jobs {
+		if job.ParentJobID > 0 {
+			childJobs[job.ParentJobID] = append(childJobs[job.ParentJobID], job)
+		}
+	}
+
+	var updatedJobs []*actions_model.ActionRunJob
+	for _, job := range jobs {
+		children := childJobs[job.ID]
+		if !job.IsReusableWorkflowCaller() || len(children) == 0 || job.Status == actions_model.StatusBlocked {
+			continue
+		}
+
+		status := actions_model.AggregateJobStatus(children)
+		if !status.IsDone() {
+			status = actions_model.StatusRunning
+		}
+		if job.Status == status {
+			continue
+		}
+
+		oldStatus := job.Status
+		job.Status = status
+		if status.IsDone() {
+			job.Stopped = timeutil.TimeStampNow()
+		} else {
+			job.Stopped = 0
+		}
+		if n, err := actions_model.UpdateRunJob(ctx, job, builder.Eq{"status": oldStatus}, "status", "stopped"); err != nil {
+			return nil, err
+		} else if n != 1 {
+			return nil, fmt.Errorf("no affected for updating reusable workflow caller %d", job.ID)
+		}
+		updatedJobs = append(updatedJobs, job)
+	}
+	return updatedJobs, nil
+}
+
+// getJobOutputs returns the outputs of a finished job, it returns nil if the job hasn't finished
+func getJobOutputs(ctx context.Context, job *actions_model.ActionRunJob, allJobs []*actions_model.ActionRunJob) (map[string]string, error) {
+	if !job.Status.IsDone() {
+		return nil, nil
+	}
+	if job.IsReusableWorkflowCaller() {
+		return getReusableWorkflowOutputs(ctx, job, allJobs)
+	}
+	if job.TaskID == 0 {
+		// the job has been skipped or cancelled before running
+		return nil, nil
+	}
+	got, err := actions_model.FindTaskOutputByTaskID(ctx, job.TaskID)
+	if err != nil {
+		return nil, fmt.Errorf("FindTaskOutputByTaskID: %w", err)
+	}
+	outputs := make(map[string]string, len(got))
+	for _, v := range got {
+		outputs[v.OutputKey] = v.OutputValue
+	}
+	return outputs, nil
+}
+
+// getReusableWorkflowOutputs evaluates the `on.workflow_call.outputs` of the workflow called by the job
+func getReusableWorkflowOutputs(ctx context.Context, caller *actions_model.ActionRunJob, allJobs []*actions_model.ActionRunJob) (map[string]string, error) {
+	var payload []byte
+	jobOutputs := make(map[string]map[string]string)
+	for _, job := range allJobs {
+		if job.ParentJobID != caller.ID {
+			continue
+		}
+		payload = job.WorkflowPayload
+		outputs, err := getJobOutputs(ctx, job, allJobs)
+		if err != nil {
+			return nil, err
+		}
+		if outputs == nil {
+			continue
+		}
+		if len(jobOutputs[job.JobID]) == 0 {
+			jobOutputs[job.JobID] = outputs
+		} else {
+			jobOutputs[job.JobID] = mergeTwoOutputs(outputs, jobOutputs[job.JobID])
+		}
+	}
+	if payload == nil {
+		return map[string]string{}, nil
+	}
+	return evaluateWorkflowCallOutputs(payload, jobOutputs)
+}
+
+// evaluateWorkflowCallOutputs evaluates the `on.workflow_call.outputs` of a called workflow with the outputs of its jobs,
+// payload can be the workflow payload of any job of the called workflow since they all contain the `on` of the workflow.
+func evaluateWorkflowCallOutputs(payload []byte, jobOutputs map[string]map[string]string) (map[string]string, error) {
+	var swf jobparser.SingleWorkflow
+	if err := yaml.Unmarshal(payload, &swf); err != nil {
+		return nil, err
+	}
+	config := (&act_model.Workflow{RawOn: swf.RawOn}).WorkflowCallConfig()
+
+	jobs := make(map[string]*act_model.WorkflowCallResult, len(jobOutputs))
+	for jobID, outputs := range jobOutputs {
+		jobs[jobID] = &act_model.WorkflowCallResult{Outputs: outputs}
+	}
+	evaluator := jobparser.NewExpressionEvaluator(exprparser.NewInterpeter(&exprparser.EvaluationEnvironment{Jobs: &jobs}, exprparser.Config{}))
+	outputs := make(map[string]string, len(config.Outputs))
+	for name, output := range config.Outputs {
+		outputs[name] = evaluator.Interpolate(output.Value)
+	}
+	return outputs, nil
+}
+
+// passSecretsToCalledWorkflow returns the secrets which can be used by a called workflow according to the `secrets` of the caller
+func passSecretsToCalledWorkflow(caller *jobparser.Job, secrets map[string]string) map[string]string {
+	actJob := &act_model.Job{RawSecrets: caller.RawSecrets}
+	if actJob.InheritSecrets() {
+		return secrets
+	}
+
+	// the automatically generated tokens are always available
+	passed := map[string]string{
+		"GITHUB_TOKEN": secrets["GITHUB_TOKEN"],
+		"KMUP_TOKEN":   secrets["KMUP_TOKEN"],
+	}
+	evaluator := jobparser.NewExpressionEvaluator(exprparser.NewInterpeter(&exprparser.EvaluationEnvironment{Secrets: secrets}, exprparser.Config{}))
+	for name, value := range actJob.Secrets() {
+		passed[strings.ToUpper(name)] = evaluator.Interpolate(value)
+	}
+	return passed
+}
+
+// getSecretsOfCalledWorkflowJob filters the secrets of the run for a job of a called workflow
+func getSecretsOfCalledWorkflowJob(ctx context.Context, job *actions_model.ActionRunJob, secrets map[string]string) (map[string]string, error) {
+	var callers []*jobparser.Job
+	for parentID := job.ParentJobID; parentID > 0; {
+		parent, err := actions_model.GetRunJobByID(ctx, parentID)
+		if err != nil {
+			return nil, err
+		}
+		workflowJob, err := parent.ParseJob()
+		if err != nil {
+			return nil, err
+		}
+		callers = append(callers, workflowJob)
+		parentID = parent.ParentJobID
+	}
+	// pass the secrets from the outermost caller to the innermost one
+	for i := len(callers) - 1; i >= 0; i-- {
+		secrets = passSecretsToCalledWorkflow(callers[i], secrets)
+	}
+	return secrets, nil
+}
+
+// hasChildJobs returns true if the workflow called by the job has been expanded
+func hasChildJobs(caller *actions_model.ActionRunJob, jobs []*actions_model.ActionRunJob) bool {
+	for _, job := range jobs {
+		if job.ParentJobID == caller.ID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"testing"
+
+	repo_model "github.com/kumose/kmup/models/repo"
+	"github.com/kumose/kmup/models/unit"
+	"github.com/kumose/kmup/models/unittest"
+
+	"github.com/nektos/act/pkg/jobparser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPassSecretsToCalledWorkflow(t *testing.T) {
+	secrets := map[string]string{
+		"GITHUB_TOKEN": "token",
+		"KMUP_TOKEN":   "token",
+		"DEPLOY_KEY":   "key",
+		"PASSWORD":     "pwd",
+	}
+	parseCaller := func(t *testing.T, content string) *jobparser.Job {
+		workflows, err := jobparser.Parse([]byte(content))
+		require.NoError(t, err)
+		require.Len(t, workflows, 1)
+		_, job := workflows[0].Job()
+		return job
+	}
+
+	t.Run("inherit", func(t *testing.T) {
+		caller := parseCaller(t, `
+on: push
+jobs:
+  call:
+    uses: ./.kmup/workflows/called.yml
+    secrets: inherit
+`)
+		assert.Equal(t, secrets, passSecretsToCalledWorkflow(caller, secrets))
+	})
+
+	t.Run("mapping", func(t *testing.T) {
+		caller := parseCaller(t, `
+on: push
+jobs:
+  call:
+    uses: ./.kmup/workflows/called.yml
+    secrets:
+      key: ${{ secrets.DEPLOY_KEY }}
+`)
+		assert.Equal(t, map[string]string{
+			"GITHUB_TOKEN": "token",
+			"KMUP_TOKEN":   "token",
+			"KEY":          "key",
+		}, passSecretsToCalledWorkflow(caller, secrets))
+	})
+
+	t.Run("none", func(t *testing.T) {
+		caller := parseCaller(t, `
+on: push
+jobs:
+  call:
+    uses: ./.kmup/workflows/called.yml
+`)
+		assert.Equal(t, map[string]string{
+			"GITHUB_TOKEN": "token",
+			"KMUP_TOKEN":   "token",
+		}, passSecretsToCalledWorkflow(caller, secrets))
+	})
+}
+
+func TestEvaluateWorkflowCallOutputs(t *testing.T) {
+	workflows, err := jobparser.Parse([]byte(`
+on:
+  workflow_call:
+    outputs:
+      version:
+        value: ${{ jobs.build.outputs.version }}
+      summary:
+        value: v${{ jobs.build.outputs.version }}-${{ jobs.test.outputs.result }}
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo
+`))
+	require.NoError(t, err)
+	payload, err := workflows[0].Marshal()
+	require.NoError(t, err)
+
+	outputs, err := evaluateWorkflowCallOutputs(payload, map[string]map[string]string{
+		"build": {"version": "1.2.3"},
+		"test":  {"result": "passed"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"version": "1.2.3",
+		"summary": "v1.2.3-passed",
+	}, outputs)
+}
+
+func TestCanCallReusableWorkflow(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	publicRepo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1})
+	privateRepo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 2})
+	otherRepo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 4})
+	calledRepo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 3})
+
+	actionsUnit, err := calledRepo.GetUnit(t.Context(), unit.TypeActions)
+	require.NoError(t, err)
+	actionsUnit.ActionsConfig().AddCollaborativeOwner(privateRepo.OwnerID)
+	require.NoError(t, repo_model.UpdateRepoUnit(t.Context(), actionsUnit))
+	calledRepo = unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 3})
+
+	cases := []struct {
+		caller, called *repo_model.Repository
+		allowed        bool
+	}{
+		{calledRepo, calledRepo, true},
+		{otherRepo, publicRepo, true},
+		{privateRepo, calledRepo, true},
+		// a public repository can't expose the private workflows by its runs although its owner is a collaborative owner
+		{publicRepo, calledRepo, false},
+		{otherRepo, calledRepo, false},
+	}
+	for _, c := range cases {
+		allowed, err := canCallReusableWorkflow(t.Context(), c.caller, c.called)
+		require.NoError(t, err)
+		assert.Equal(t, c.allowed, allowed, "%s calls %s", c.caller.FullName(), c.called.FullName())
+	}
+}
@@ -23,6 +23,8 @@ import (
 
 	actions_model "github.com/kumose/kmup/models/actions"
 	"github.com/kumose/kmup/models/db"
+	actions_module "github.com/kumose/kmup/modules/actions"
+	"github.com/kumose/kmup/modules/log"
 	"github.com/kumose/kmup/modules/util"
 	notify_service "github.com/kumose/kmup/services/notify"
 
@@ -66,7 +68,12 @@ func PrepareRunAndInsert(ctx context.Context, content []byte, run *actions_model
 		run.Title = jobs[0].RunName
 	}
 
-	if err = InsertRun(ctx, run, jobs, vars); err != nil {
+	environments, err := actions_module.GetJobEnvironments(content)
+	if err != nil {
+		return fmt.Errorf("GetJobEnvironments: %w", err)
+	}
+
+	if err = InsertRun(ctx, run, jobs, environments, vars); err != nil {
 		return fmt.Errorf("InsertRun: %w", err)
 	}
 
@@ -78,6 +85,16 @@ func PrepareRunAndInsert(ctx context.Context, content []byte, run *actions_model
 
 	CreateCommitStatusForRunJobs(ctx, run, allJobs...)
 
+	// the jobs calling reusable workflows are expanded by the job emitter, and the jobs with environments are checked by it
+	for _, job := range allJobs {
+		if job.RequiresJobEmitter() {
+			if err := EmitJobsIfReadyByRun(run.ID); err != nil {
+				log.Error("Check jobs of run %d: %v", run.ID, err)
+			}
+			break
+		}
+	}
+
 	notify_service.WorkflowRunStatusUpdate(ctx, run.Repo, run.TriggerUser, run)
 	for _, job := range allJobs {
 		notify_service.WorkflowJobStatusUpdate(ctx, run.Repo, run.TriggerUser, job, nil)
@@ -88,7 +105,8 @@ func PrepareRunAndInsert(ctx context.Context, content []byte, run *actions_model
 
 // InsertRun inserts a run
 // The title will be cut off at 255 characters if it's longer than 255 characters.
-func InsertRun(ctx context.Context, run *actions_model.ActionRun, jobs []*jobparser.SingleWorkflow, vars map[string]string) error {
+// environments are the environment names of the jobs, see actions_module.GetJobEnvironments.
+func InsertRun(ctx context.Context, run *actions_model.ActionRun, jobs []*jobparser.SingleWorkflow, environments, vars map[string]string) error {
 	return db.WithTx(ctx, func(ctx context.Context) error {
 		index, err := db.GetNextResourceIndex(ctx, "action_run_index", run.RepoID)
 		if err != nil {
@@ -96,6 +114,7 @@ func InsertRun(ctx context.Context, run *actions_model.ActionRun, jobs []*jobpar
 		}
 		run.Index = index
 		run.Title = util.EllipsisDisplayString(run.Title, 255)
+		run.Attempt = 1
 
 		// check run (workflow-level) concurrency
 		run.Status, err = PrepareToStartRunWithConcurrency(ctx, run)
@@ -115,64 +134,9 @@ func InsertRun(ctx context.Context, run *actions_model.ActionRun, jobs []*jobpar
 			return err
 		}
 
-		runJobs := make([]*actions_model.ActionRunJob, 0, len(jobs))
-		var hasWaitingJobs bool
-		for _, v := range jobs {
-			id, job := v.Job()
-			needs := job.Needs()
-			if err := v.SetJob(id, job.EraseNeeds()); err != nil {
-				return err
-			}
-			payload, _ := v.Marshal()
-
-			shouldBlockJob := len(needs) > 0 || run.NeedApproval || run.Status == actions_model.StatusBlocked
-
-			job.Name = util.EllipsisDisplayString(job.Name, 255)
-			runJob := &actions_model.ActionRunJob{
-				RunID:             run.ID,
-				RepoID:            run.RepoID,
-				OwnerID:           run.OwnerID,
-				CommitSHA:         run.CommitSHA,
-				IsForkPullRequest: run.IsForkPullRequest,
-				Name:              job.Name,
-				WorkflowPayload:   payload,
-				JobID:             id,
-				Needs:             needs,
-				RunsOn:            job.RunsOn(),
-				Status:            util.Iif(shouldBlockJob, actions_model.StatusBlocked, actions_model.StatusWaiting),
-			}
-			// check job concurrency
-			if job.RawConcurrency != nil {
-				rawConcurrency, err := yaml.Marshal(job.RawConcurrency)
-				if err != nil {
-					return fmt.Errorf("marshal raw concurrency: %w", err)
-				}
-				runJob.RawConcurrency = string(rawConcurrency)
-
-				// do not evaluate job concurrency when it requires `needs`, the jobs with `needs` will be evaluated later by job emitter
-				if len(needs) == 0 {
-					err = EvaluateJobConcurrencyFillModel(ctx, run, runJob, vars)
-					if err != nil {
-						return fmt.Errorf("evaluate job concurrency: %w", err)
-					}
-				}
-
-				// If a job needs other jobs ("needs" is not empty), its status is set to StatusBlocked at the entry of the loop
-				// No need to check job concurrency for a blocked job (it will be checked by job emitter later)
-				if runJob.Status == actions_model.StatusWaiting {
-					runJob.Status, err = PrepareToStartJobWithConcurrency(ctx, runJob)
-					if err != nil {
-						return fmt.Errorf("prepare to start job with concurrency: %w", err)
-					}
-				}
-			}
-
-			hasWaitingJobs = hasWaitingJobs || runJob.Status == actions_model.StatusWaiting
-			if err := db.Insert(ctx, runJob); err != nil {
-				return err
-			}
-
-			runJobs = append(runJobs, runJob)
+		runJobs, hasWaitingJobs, err := insertRunJobs(ctx, run, nil, jobs, environments, vars)
+		if err != nil {
+			return err
 		}
 
 		run.Status = actions_model.AggregateJobStatus(runJobs)
@@ -190,3 +154,80 @@ func InsertRun(ctx context.Context, run *actions_model.ActionRun, jobs []*jobpar
 		return nil
 	})
 }
+
+// insertRunJobs inserts the jobs of a workflow into the run.
+// parentJob is the job calling the reusable workflow which the jobs belong to, it is nil for the jobs of the triggered workflow.
+func insertRunJobs(ctx context.Context, run *actions_model.ActionRun, parentJob *actions_model.ActionRunJob, jobs []*jobparser.SingleWorkflow, environments, vars map[string]string) (runJobs []*actions_model.ActionRunJob, hasWaitingJobs bool, _ error) {
+	runJobs = make([]*actions_model.ActionRunJob, 0, len(jobs))
+	for _, v := range jobs {
+		id, job := v.Job()
+		needs := job.Needs()
+		if err := v.SetJob(id, job.EraseNeeds()); err != nil {
+			return nil, false, err
+		}
+		payload, _ := v.Marshal()
+
+		// a job calling a reusable workflow is always blocked, the job emitter will expand it into child jobs.
+		// a job with an environment is also blocked, the job emitter will check the protection rules of the environment.
+		isCaller := actions_module.IsReusableWorkflowCall(job)
+		shouldBlockJob := len(needs) > 0 || isCaller || environments[id] != "" || run.NeedApproval || run.Status == actions_model.StatusBlocked
+
+		if parentJob != nil {
+			job.Name = parentJob.Name + " / " + job.Name
+		}
+		job.Name = util.EllipsisDisplayString(job.Name, 255)
+		runJob := &actions_model.ActionRunJob{
+			RunID:             run.ID,
+			RepoID:            run.RepoID,
+			OwnerID:           run.OwnerID,
+			CommitSHA:         run.CommitSHA,
+			IsForkPullRequest: run.IsForkPullRequest,
+			Name:              job.Name,
+			WorkflowPayload:   payload,
+			JobID:             id,
+			Needs:             needs,
+			RunsOn:            job.RunsOn(),
+			Environment:       environments[id],
+			Status:            util.Iif(shouldBlockJob, actions_model.StatusBlocked, actions_model.StatusWaiting),
+		}
+		if parentJob != nil {
+			runJob.ParentJobID = parentJob.ID
+		}
+		if isCaller {
+			runJob.UsesWorkflow = job.Uses
+		}
+		// check job concurrency
+		if job.RawConcurrency != nil {
+			rawConcurrency, err := yaml.Marshal(job.RawConcurrency)
+			if err != nil {
+				return nil, false, fmt.Errorf("marshal raw concurrency: %w", err)
+			}
+			runJob.RawConcurrency = string(rawConcurrency)
+
+			// do not evaluate job concurrency when it requires `needs`, the jobs with `needs` will be evaluated later by job emitter
+			if len(needs) == 0 {
+				err = EvaluateJobConcurrencyFillModel(ctx, run, runJob, vars)
+				if err != nil {
+					return nil, false, fmt.Errorf("evaluate job concurrency: %w", err)
+				}
+			}
+
+			// If a job needs other jobs ("needs" is not empty), its status is set to StatusBlocked at the entry of the loop
+			// No need to check job concurrency for a blocked job (it will be checked by job emitter later)
+			if runJob.Status == actions_model.StatusWaiting {
+				runJob.Status, err = PrepareToStartJobWithConcurrency(ctx, runJob)
+				if err != nil {
+					return nil, false, fmt.Errorf("prepare to start job with concurrency: %w", err)
+				}
+			}
+		}
+
+		hasWaitingJobs = hasWaitingJobs || runJob.Status == actions_model.StatusWaiting
+		if err := db.Insert(ctx, runJob); err != nil {
+			return nil, false, err
+		}
+
+		runJobs = append(runJobs, runJob)
+	}
+	return runJobs, hasWaitingJobs, nil
+}
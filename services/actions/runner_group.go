@@ -0,0 +1,118 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	actions_model "github.com/kumose/kmup/models/actions"
+	repo_model "github.com/kumose/kmup/models/repo"
+	user_model "github.com/kumose/kmup/models/user"
+	actions_module "github.com/kumose/kmup/modules/actions"
+	"github.com/kumose/kmup/modules/util"
+)
+
+// RunnerGroupOptions are the options to create or update a runner group, the owners and repositories are referenced by their names
+type RunnerGroupOptions struct {
+	Name             string
+	Description      string
+	AllowedOwners    []string // the names of the orgs or users
+	AllowedRepos     []string // the full names "owner/repo" of the repositories
+	AllowedWorkflows []string // the workflow files qualified by the repositories and optionally the refs, like "org/deploy/.kmup/workflows/deploy.yml@main"
+}
+
+func applyRunnerGroupOptions(ctx context.Context, group *actions_model.ActionRunnerGroup, opts *RunnerGroupOptions) error {
+	group.Name = strings.TrimSpace(opts.Name)
+	if group.Name == "" {
+		return util.NewInvalidArgumentErrorf("runner group name is empty")
+	}
+	group.Description = opts.Description
+
+	group.AllowedOwnerIDs = nil
+	for _, name := range opts.AllowedOwners {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		owner, err := user_model.GetUserByName(ctx, name)
+		if user_model.IsErrUserNotExist(err) {
+			return util.NewInvalidArgumentErrorf("owner %s doesn't exist", name)
+		} else if err != nil {
+			return err
+		}
+		if !slices.Contains(group.AllowedOwnerIDs, owner.ID) {
+			group.AllowedOwnerIDs = append(group.AllowedOwnerIDs, owner.ID)
+		}
+	}
+
+	group.AllowedRepoIDs = nil
+	for _, fullName := range opts.AllowedRepos {
+		if fullName = strings.TrimSpace(fullName); fullName == "" {
+			continue
+		}
+		ownerName, repoName, ok := strings.Cut(fullName, "/")
+		if !ok {
+			return util.NewInvalidArgumentErrorf("repository %s isn't in the form owner/repo", fullName)
+		}
+		repo, err := repo_model.GetRepositoryByOwnerAndName(ctx, ownerName, repoName)
+		if repo_model.IsErrRepoNotExist(err) {
+			return util.NewInvalidArgumentErrorf("repository %s doesn't exist", fullName)
+		} else if err != nil {
+			return err
+		}
+		if !slices.Contains(group.AllowedRepoIDs, repo.ID) {
+			group.AllowedRepoIDs = append(group.AllowedRepoIDs, repo.ID)
+		}
+	}
+
+	group.AllowedWorkflows = nil
+	for _, workflow := range opts.AllowedWorkflows {
+		if workflow = strings.TrimSpace(workflow); workflow == "" {
+			continue
+		}
+		// the workflows are qualified by the repositories, so the jobs of a called workflow can't be matched by the file name of the caller
+		if ref, ok := actions_model.ParseQualifiedWorkflow(workflow); !ok || !actions_module.IsWorkflow(ref.Path) {
+			return util.NewInvalidArgumentErrorf("workflow %s isn't in the form owner/repo/.kmup/workflows/deploy.yml with an optional @ref", workflow)
+		}
+		if !slices.Contains(group.AllowedWorkflows, workflow) {
+			group.AllowedWorkflows = append(group.AllowedWorkflows, workflow)
+		}
+	}
+	return nil
+}
+
+// CreateRunnerGroup creates a runner group with the allowed owners, repositories and workflows
+func CreateRunnerGroup(ctx context.Context, opts *RunnerGroupOptions) (*actions_model.ActionRunnerGroup, error) {
+	group := &actions_model.ActionRunnerGroup{}
+	if err := applyRunnerGroupOptions(ctx, group, opts); err != nil {
+		return nil, err
+	}
+	if err := actions_model.CreateRunnerGroup(ctx, group); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// UpdateRunnerGroup replaces the name, the description and the allow-lists of a runner group
+func UpdateRunnerGroup(ctx context.Context, group *actions_model.ActionRunnerGroup, opts *RunnerGroupOptions) error {
+	if err := applyRunnerGroupOptions(ctx, group, opts); err != nil {
+		return err
+	}
+	return actions_model.UpdateRunnerGroup(ctx, group)
+}
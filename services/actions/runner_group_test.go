@@ -0,0 +1,69 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"testing"
+
+	"github.com/kumose/kmup/models/unittest"
+	"github.com/kumose/kmup/modules/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateRunnerGroup(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	group, err := CreateRunnerGroup(t.Context(), &RunnerGroupOptions{
+		Name:             " deploy ",
+		AllowedOwners:    []string{"org3", "", "org3"},
+		AllowedRepos:     []string{"user2/repo1"},
+		AllowedWorkflows: []string{"org3/repo3/.kmup/workflows/deploy.yml@main", " "},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "deploy", group.Name)
+	assert.Equal(t, []int64{3}, group.AllowedOwnerIDs)
+	assert.Equal(t, []int64{1}, group.AllowedRepoIDs)
+	assert.Equal(t, []string{"org3/repo3/.kmup/workflows/deploy.yml@main"}, group.AllowedWorkflows)
+
+	owners, repos, err := group.GetAllowedNames(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"org3"}, owners)
+	assert.Equal(t, []string{"user2/repo1"}, repos)
+
+	require.NoError(t, UpdateRunnerGroup(t.Context(), group, &RunnerGroupOptions{Name: "deploy", AllowedRepos: []string{"user2/repo2"}}))
+	assert.Empty(t, group.AllowedOwnerIDs)
+	assert.Equal(t, []int64{2}, group.AllowedRepoIDs)
+	assert.Empty(t, group.AllowedWorkflows)
+
+	for _, opts := range []*RunnerGroupOptions{
+		{Name: " "},
+		{Name: "g", AllowedOwners: []string{"no-such-user"}},
+		{Name: "g", AllowedRepos: []string{"repo1"}},
+		{Name: "g", AllowedRepos: []string{"user2/no-such-repo"}},
+		{Name: "g", AllowedWorkflows: []string{"deploy.yml"}},
+		{Name: "g", AllowedWorkflows: []string{".kmup/workflows/deploy.yml"}},
+		{Name: "g", AllowedWorkflows: []string{"org3/repo3/deploy.yml"}},
+	} {
+		_, err := CreateRunnerGroup(t.Context(), opts)
+		assert.ErrorIs(t, err, util.ErrInvalidArgument, "%+v", opts)
+	}
+	_, err = CreateRunnerGroup(t.Context(), &RunnerGroupOptions{Name: "deploy"})
+	assert.ErrorIs(t, err, util.ErrAlreadyExist)
+}
@@ -25,6 +25,7 @@ import (
 	actions_model "github.com/kumose/kmup/models/actions"
 	"github.com/kumose/kmup/models/db"
 	secret_model "github.com/kumose/kmup/models/secret"
+	"github.com/kumose/kmup/modules/setting"
 	notify_service "github.com/kumose/kmup/services/notify"
 
 	runnerv1 "github.com/kumose/actions-proto-go/runner/v1"
@@ -77,10 +78,21 @@ func PickTask(ctx context.Context, runner *actions_model.ActionRunner) (*runnerv
 		if err != nil {
 			return fmt.Errorf("GetSecretsOfTask: %w", err)
 		}
+		if job.ParentJobID > 0 {
+			secrets, err = getSecretsOfCalledWorkflowJob(ctx, job, secrets)
+			if err != nil {
+				return fmt.Errorf("getSecretsOfCalledWorkflowJob: %w", err)
+			}
+		}
 
-		vars, err := actions_model.GetVariablesOfRun(ctx, t.Job.Run)
+		vars, err := actions_model.GetVariablesOfJob(ctx, t.Job)
 		if err != nil {
-			return fmt.Errorf("GetVariablesOfRun: %w", err)
+			return fmt.Errorf("GetVariablesOfJob: %w", err)
+		}
+		if job.DebugLogging {
+			// the job is rerun with debug logging
+			vars["ACTIONS_RUNNER_DEBUG"] = "true"
+			vars["ACTIONS_STEP_DEBUG"] = "true"
 		}
 
 		needs, err := findTaskNeeds(ctx, job)
@@ -88,7 +100,7 @@ func PickTask(ctx context.Context, runner *actions_model.ActionRunner) (*runnerv
 			return fmt.Errorf("findTaskNeeds: %w", err)
 		}
 
-		taskContext, err := generateTaskContext(t)
+		taskContext, err := generateTaskContext(ctx, t)
 		if err != nil {
 			return fmt.Errorf("generateTaskContext: %w", err)
 		}
@@ -117,7 +129,7 @@ func PickTask(ctx context.Context, runner *actions_model.ActionRunner) (*runnerv
 	return task, true, nil
 }
 
-func generateTaskContext(t *actions_model.ActionTask) (*structpb.Struct, error) {
+func generateTaskContext(ctx context.Context, t *actions_model.ActionTask) (*structpb.Struct, error) {
 	kmupRuntimeToken, err := CreateAuthorizationToken(t.ID, t.Job.RunID, t.JobID)
 	if err != nil {
 		return nil, err
@@ -126,6 +138,21 @@ func generateTaskContext(t *actions_model.ActionTask) (*structpb.Struct, error)
 	gitCtx := GenerateKmupContext(t.Job.Run, t.Job)
 	gitCtx["token"] = t.Token
 	gitCtx["kmup_runtime_token"] = kmupRuntimeToken
+	if IDTokenSigningKey() != nil {
+		canRequestIDToken, err := CanJobRequestIDToken(ctx, t.Job)
+		if err != nil {
+			return nil, err
+		}
+		if canRequestIDToken {
+			// the runner requests ID tokens from this URL with kmup_runtime_token, like ACTIONS_ID_TOKEN_REQUEST_URL and ACTIONS_ID_TOKEN_REQUEST_TOKEN of GitHub
+			gitCtx["actions_id_token_request_url"] = IDTokenIssuer() + "/token?api-version=2.0"
+		}
+	}
+	if setting.Actions.CacheEnabled {
+		// the runner exposes it as ACTIONS_CACHE_URL, the cache service v2 is served on ACTIONS_RESULTS_URL like artifacts v4
+		gitCtx["actions_cache_url"] = CacheURL()
+	}
+	gitCtx["test_reports_url"] = TestReportURL()
 
 	return structpb.NewStruct(gitCtx)
 }
@@ -0,0 +1,179 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	actions_model "github.com/kumose/kmup/models/actions"
+	"github.com/kumose/kmup/modules/actions"
+	"github.com/kumose/kmup/modules/glob"
+	"github.com/kumose/kmup/modules/log"
+	"github.com/kumose/kmup/modules/setting"
+	"github.com/kumose/kmup/modules/storage"
+	"github.com/kumose/kmup/modules/util"
+)
+
+// TestReportRouteBase is the route to upload test reports, the jobs get it as "github.test_reports_url" and authenticate with their GITHUB_TOKEN.
+//
+//	curl -X POST -H "Authorization: Bearer ${{ github.token }}" --data-binary @report.xml "${{ github.test_reports_url }}?name=report.xml"
+const TestReportRouteBase = "/api/actions_test_reports"
+
+// MaxTestReportSize is the max size of a test report file, the larger ones are ignored
+const MaxTestReportSize = 32 * 1024 * 1024
+
+func TestReportURL() string {
+	return setting.AppURL + TestReportRouteBase[1:] + "/upload"
+}
+
+// IsTestReportFile returns whether a file could be a JUnit XML or a TRX report by its extension
+func IsTestReportFile(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	return ext == ".xml" || ext == ".trx"
+}
+
+// UploadTestReport parses a test report uploaded by a task and stores its results
+func UploadTestReport(ctx context.Context, task *actions_model.ActionTask, name string, r io.Reader) (*actions_model.ActionTestReport, error) {
+	if err := task.LoadJob(ctx); err != nil {
+		return nil, err
+	}
+	results, err := actions.ParseTestReport(io.LimitReader(r, MaxTestReportSize))
+	if errors.Is(err, actions.ErrUnknownTestReportFormat) {
+		return nil, util.NewInvalidArgumentErrorf("%s is neither a JUnit XML nor a TRX report", name)
+	} else if err != nil {
+		return nil, util.NewInvalidArgumentErrorf("parse test report %s: %v", name, err)
+	}
+
+	report := &actions_model.ActionTestReport{
+		RepoID:    task.RepoID,
+		CommitSHA: task.CommitSHA,
+		RunID:     task.Job.RunID,
+		JobID:     task.JobID,
+		TaskID:    task.ID,
+		Name:      util.EllipsisDisplayString(path.Base(name), 255),
+	}
+	cases := make([]*actions_model.ActionTestCase, 0, len(results))
+	for _, result := range results {
+		cases = append(cases, &actions_model.ActionTestCase{
+			Suite:     result.Suite,
+			ClassName: result.ClassName,
+			Name:      result.Name,
+			Status:    result.Status,
+			Duration:  result.Duration,
+			Message:   result.Message,
+		})
+	}
+	if err := actions_model.InsertTestReport(ctx, report, cases); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// IsTestReportArtifact returns whether an artifact contains test reports by its name, see setting.Actions.TestReportArtifacts
+func IsTestReportArtifact(name string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range setting.Actions.TestReportArtifacts {
+		g, err := glob.Compile(strings.ToLower(pattern))
+		if err != nil {
+			log.Warn("Invalid pattern %q in [actions] TEST_REPORT_ARTIFACTS: %v", pattern, err)
+			continue
+		}
+		if g.Match(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// IngestTestReportArtifact stores the results of the test reports in an uploaded artifact if it is designated to contain test reports.
+// Failures are only logged, they must not fail the upload of the artifact.
+func IngestTestReportArtifact(ctx context.Context, task *actions_model.ActionTask, artifact *actions_model.ActionArtifact) {
+	if !IsTestReportArtifact(artifact.ArtifactName) {
+		return
+	}
+	if err := ingestTestReportArtifact(ctx, task, artifact); err != nil {
+		log.Warn("Failed to ingest test reports of artifact %d: %v", artifact.ID, err)
+	}
+}
+
+func ingestTestReportArtifact(ctx context.Context, task *actions_model.ActionTask, artifact *actions_model.ActionArtifact) error {
+	if artifact.FileSize > MaxTestReportSize {
+		return fmt.Errorf("artifact is larger than %d bytes", MaxTestReportSize)
+	}
+	obj, err := storage.ActionsArtifacts.Open(artifact.StoragePath)
+	if err != nil {
+		return err
+	}
+	defer obj.Close()
+
+	switch artifact.ContentEncoding {
+	case "application/zip": // artifacts v4 are zip archives of the uploaded files
+		content, err := io.ReadAll(io.LimitReader(obj, MaxTestReportSize))
+		if err != nil {
+			return err
+		}
+		archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+		if err != nil {
+			return err
+		}
+		for _, file := range archive.File {
+			if file.FileInfo().IsDir() || !IsTestReportFile(file.Name) {
+				continue
+			}
+			if err := uploadTestReportFile(ctx, task, file.Name, file.Open); err != nil {
+				log.Warn("Failed to ingest test report %s of artifact %d: %v", file.Name, artifact.ID, err)
+			}
+		}
+		return nil
+	case "gzip": // artifacts v3 store the uploaded files separately, and they could be gzipped
+		if !IsTestReportFile(artifact.ArtifactPath) {
+			return nil
+		}
+		reader, err := gzip.NewReader(obj)
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+		_, err = UploadTestReport(ctx, task, artifact.ArtifactPath, reader)
+		return err
+	default:
+		if !IsTestReportFile(artifact.ArtifactPath) {
+			return nil
+		}
+		_, err = UploadTestReport(ctx, task, artifact.ArtifactPath, obj)
+		return err
+	}
+}
+
+func uploadTestReportFile(ctx context.Context, task *actions_model.ActionTask, name string, open func() (io.ReadCloser, error)) error {
+	f, err := open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = UploadTestReport(ctx, task, name, f)
+	return err
+}
@@ -0,0 +1,58 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"strings"
+	"testing"
+
+	actions_model "github.com/kumose/kmup/models/actions"
+	"github.com/kumose/kmup/models/unittest"
+	"github.com/kumose/kmup/modules/setting"
+	"github.com/kumose/kmup/modules/test"
+	"github.com/kumose/kmup/modules/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsTestReportArtifact(t *testing.T) {
+	defer test.MockVariableValue(&setting.Actions.TestReportArtifacts, []string{"test-results*", "*-junit"})()
+
+	assert.True(t, IsTestReportArtifact("test-results"))
+	assert.True(t, IsTestReportArtifact("Test-Results-linux"))
+	assert.True(t, IsTestReportArtifact("unit-junit"))
+	assert.False(t, IsTestReportArtifact("coverage"))
+}
+
+func TestUploadTestReport(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	task := unittest.AssertExistsAndLoadBean(t, &actions_model.ActionTask{ID: 47})
+	report, err := UploadTestReport(t.Context(), task, "out/junit.xml", strings.NewReader(
+		`<testsuite name="s"><testcase name="a"/><testcase name="b"><failure message="boom"/></testcase></testsuite>`))
+	require.NoError(t, err)
+	assert.Equal(t, "junit.xml", report.Name)
+	assert.EqualValues(t, 791, report.RunID)
+	assert.EqualValues(t, 192, report.JobID)
+	assert.EqualValues(t, 2, report.Total)
+	assert.EqualValues(t, 1, report.Failed)
+
+	_, err = UploadTestReport(t.Context(), task, "index.html", strings.NewReader(`<html/>`))
+	assert.ErrorIs(t, err, util.ErrInvalidArgument)
+}
@@ -38,6 +38,15 @@ func CreateVariable(ctx context.Context, ownerID, repoID int64, name, data, desc
 	return v, nil
 }
 
+// CreateEnvironmentVariable creates a variable of an environment of the repository
+func CreateEnvironmentVariable(ctx context.Context, repoID, environmentID int64, name, data, description string) (*actions_model.ActionVariable, error) {
+	if err := secret_service.ValidateName(name); err != nil {
+		return nil, err
+	}
+
+	return actions_model.InsertEnvironmentVariable(ctx, repoID, environmentID, name, util.ReserveLineBreakForTextarea(data), description)
+}
+
 func UpdateVariableNameData(ctx context.Context, variable *actions_model.ActionVariable) (bool, error) {
 	if err := secret_service.ValidateName(variable.Name); err != nil {
 		return false, err
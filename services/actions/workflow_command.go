@@ -0,0 +1,92 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	actions_model "github.com/kumose/kmup/models/actions"
+	"github.com/kumose/kmup/modules/actions"
+	"github.com/kumose/kmup/modules/log"
+	"github.com/kumose/kmup/modules/util"
+
+	runnerv1 "github.com/kumose/actions-proto-go/runner/v1"
+)
+
+// HandleWorkflowCommands stores the annotations reported by the workflow commands in the log rows of a task.
+// startIndex is the log index of the first row.
+// Failures are only logged, they must not prevent the runner from uploading the logs.
+func HandleWorkflowCommands(ctx context.Context, task *actions_model.ActionTask, startIndex int64, rows []*runnerv1.LogRow) {
+	var annotations []*actions_model.ActionTaskAnnotation
+	for i, row := range rows {
+		cmd, ok := actions.ParseWorkflowCommand(row.Content)
+		if !ok || !cmd.IsAnnotation() {
+			continue
+		}
+		annotations = append(annotations, newTaskAnnotation(task, startIndex+int64(i), cmd))
+	}
+	if len(annotations) == 0 {
+		return
+	}
+
+	if err := task.LoadJob(ctx); err != nil {
+		log.Error("LoadJob for task %d: %v", task.ID, err)
+		return
+	}
+	for _, annotation := range annotations {
+		annotation.RunID = task.Job.RunID
+	}
+	if err := actions_model.InsertTaskAnnotations(ctx, task.ID, annotations); err != nil {
+		log.Error("InsertTaskAnnotations for task %d: %v", task.ID, err)
+	}
+}
+
+func newTaskAnnotation(task *actions_model.ActionTask, logIndex int64, cmd *actions.WorkflowCommand) *actions_model.ActionTaskAnnotation {
+	level := actions_model.AnnotationLevelNotice
+	switch cmd.Command {
+	case actions.WorkflowCommandError:
+		level = actions_model.AnnotationLevelFailure
+	case actions.WorkflowCommandWarning:
+		level = actions_model.AnnotationLevelWarning
+	}
+
+	annotation := &actions_model.ActionTaskAnnotation{
+		TaskID:      task.ID,
+		JobID:       task.JobID,
+		RepoID:      task.RepoID,
+		CommitSHA:   task.CommitSHA,
+		LogIndex:    logIndex,
+		Level:       level,
+		Title:       util.EllipsisDisplayString(cmd.Properties["title"], 255),
+		Message:     cmd.Message,
+		StartLine:   cmd.IntProperty("line"),
+		EndLine:     cmd.IntProperty("endLine"),
+		StartColumn: cmd.IntProperty("col"),
+		EndColumn:   cmd.IntProperty("endColumn"),
+	}
+	if file := cmd.Properties["file"]; file != "" {
+		// the paths are relative to the workspace, which is the root of the repository
+		annotation.Path = path.Clean(strings.ReplaceAll(file, "\\", "/"))
+	}
+	if annotation.EndLine < annotation.StartLine {
+		annotation.EndLine = annotation.StartLine
+	}
+	return annotation
+}
@@ -0,0 +1,61 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package actions
+
+import (
+	"testing"
+
+	actions_model "github.com/kumose/kmup/models/actions"
+	"github.com/kumose/kmup/models/unittest"
+
+	runnerv1 "github.com/kumose/actions-proto-go/runner/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleWorkflowCommands(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+
+	task := unittest.AssertExistsAndLoadBean(t, &actions_model.ActionTask{ID: 47})
+	HandleWorkflowCommands(t.Context(), task, 10, []*runnerv1.LogRow{
+		{Content: "plain output"},
+		{Content: "::error file=./src/main.go,line=12,endLine=3,col=5,title=Build failed::undefined: foo"},
+		{Content: "::notice::done"},
+		{Content: "::group::not stored"},
+	})
+
+	annotations, err := actions_model.FindTaskAnnotations(t.Context(), task.ID)
+	require.NoError(t, err)
+	if assert.Len(t, annotations, 2) {
+		a := annotations[0]
+		assert.Equal(t, actions_model.AnnotationLevelFailure, a.Level)
+		assert.EqualValues(t, 11, a.LogIndex)
+		assert.EqualValues(t, 192, a.JobID)
+		assert.EqualValues(t, 791, a.RunID)
+		assert.Equal(t, task.CommitSHA, a.CommitSHA)
+		assert.Equal(t, "src/main.go", a.Path)
+		assert.Equal(t, "Build failed", a.Title)
+		assert.Equal(t, "undefined: foo", a.Message)
+		assert.Equal(t, 12, a.StartLine)
+		assert.Equal(t, 12, a.EndLine)
+		assert.Equal(t, 5, a.StartColumn)
+
+		assert.Equal(t, actions_model.AnnotationLevelNotice, annotations[1].Level)
+		assert.Empty(t, annotations[1].Path)
+	}
+}
@@ -38,6 +38,7 @@ import (
 	"github.com/kumose/kmup/modules/process"
 	"github.com/kumose/kmup/modules/queue"
 	"github.com/kumose/kmup/services/automergequeue"
+	"github.com/kumose/kmup/services/mergequeue"
 	notify_service "github.com/kumose/kmup/services/notify"
 	pull_service "github.com/kumose/kmup/services/pull"
 	repo_service "github.com/kumose/kmup/services/repository"
@@ -265,7 +266,18 @@ func handlePullRequestAutoMerge(pullID int64, sha string) {
 		return
 	}
 
-	if err := pull_service.CheckPullMergeable(ctx, doer, &perm, pr, pull_service.MergeCheckTypeGeneral, false); err != nil {
+	// a branch which requires a merge queue gets the pull request queued instead of merged
+	queueRequired, err := pull_service.IsMergeQueueRequired(ctx, pr)
+	if err != nil {
+		log.Error("IsMergeQueueRequired: %v", err)
+		return
+	}
+	mergeCheckType := pull_service.MergeCheckTypeGeneral
+	if queueRequired {
+		mergeCheckType = pull_service.MergeCheckTypeQueue
+	}
+
+	if err := pull_service.CheckPullMergeable(ctx, doer, &perm, pr, mergeCheckType, false); err != nil {
 		if errors.Is(err, pull_service.ErrNotReadyToMerge) {
 			log.Info("%-v was scheduled to automerge by an unauthorized user", pr)
 			return
@@ -274,6 +286,17 @@ func handlePullRequestAutoMerge(pullID int64, sha string) {
 		return
 	}
 
+	if queueRequired {
+		if err := mergequeue.AddToMergeQueue(ctx, doer, pr, scheduledPRM.MergeStyle, scheduledPRM.Message, scheduledPRM.DeleteBranchAfterMerge); err != nil {
+			log.Error("AddToMergeQueue: %v", err)
+			return
+		}
+		if err := pull_model.DeleteScheduledAutoMerge(ctx, pr.ID); err != nil {
+			log.Error("DeleteScheduledAutoMerge: %v", err)
+		}
+		return
+	}
+
 	if err := pull_service.Merge(ctx, pr, doer, scheduledPRM.MergeStyle, "", scheduledPRM.Message, true); err != nil {
 		log.Error("pull_service.Merge: %v", err)
 		// FIXME: if merge failed, we should display some error message to the pull request page.
@@ -162,6 +162,7 @@ func OrgAssignment(opts OrgAssignmentOptions) func(ctx *Context) {
 		ctx.Data["IsOrganizationMember"] = ctx.Org.IsMember
 		ctx.Data["IsPackageEnabled"] = setting.Packages.Enabled
 		ctx.Data["IsRepoIndexerEnabled"] = setting.Indexer.RepoIndexerEnabled
+		ctx.Data["IsActionsLogIndexerEnabled"] = setting.Actions.Enabled && setting.Indexer.ActionsLogIndexerEnabled
 		ctx.Data["IsPublicMember"] = func(uid int64) bool {
 			is, _ := organization.IsPublicMembership(ctx, ctx.Org.Organization.ID, uid)
 			return is
@@ -253,6 +254,7 @@ func OrgAssignment(opts OrgAssignmentOptions) func(ctx *Context) {
 		ctx.Data["CanReadProjects"] = ctx.Org.CanReadUnit(ctx, unit.TypeProjects)
 		ctx.Data["CanReadPackages"] = ctx.Org.CanReadUnit(ctx, unit.TypePackages)
 		ctx.Data["CanReadCode"] = ctx.Org.CanReadUnit(ctx, unit.TypeCode)
+		ctx.Data["CanReadActions"] = ctx.Org.CanReadUnit(ctx, unit.TypeActions)
 
 		ctx.Data["IsFollowing"] = ctx.Doer != nil && user_model.IsFollowing(ctx, ctx.Doer.ID, ctx.ContextUser.ID)
 		if len(ctx.ContextUser.Description) != 0 {
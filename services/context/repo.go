@@ -142,7 +142,7 @@ func PrepareCommitFormOptions(ctx *Context, doer *user_model.User, targetRepo *r
 		return nil, err
 	}
 
-	protectedBranch, err := git_model.GetFirstMatchProtectedBranchRule(ctx, targetRepo.ID, branchName)
+	protectedBranch, err := git_model.GetFirstMatchProtectedBranchRuleForUser(ctx, targetRepo.ID, branchName, doer)
 	if err != nil {
 		return nil, err
 	}
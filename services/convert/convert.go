@@ -210,11 +210,29 @@ func ToBranchProtection(ctx context.Context, bp *git_model.ProtectedBranch, repo
 		ProtectedFilePatterns:         bp.ProtectedFilePatterns,
 		UnprotectedFilePatterns:       bp.UnprotectedFilePatterns,
 		BlockAdminMergeOverride:       bp.BlockAdminMergeOverride,
+		RequireRequiredWorkflows:      bp.RequireRequiredWorkflows,
+		RequireCodeOwnerApproval:      bp.RequireCodeOwnerApproval,
+		EnableMergeQueue:              bp.EnableMergeQueue,
 		Created:                       bp.CreatedUnix.AsTime(),
 		Updated:                       bp.UpdatedUnix.AsTime(),
 	}
 }
 
+// ToPushRule convert a git_model.PushRule to an api.PushRule
+func ToPushRule(rule *git_model.PushRule) *api.PushRule {
+	return &api.PushRule{
+		MaxFileSize:          rule.MaxFileSize,
+		ForbiddenPaths:       util.SliceNilAsEmpty(rule.ForbiddenPaths),
+		ForbiddenExtensions:  util.SliceNilAsEmpty(rule.ForbiddenExtensions),
+		CommitMessagePattern: rule.CommitMessagePattern,
+		AuthorEmailDomains:   util.SliceNilAsEmpty(rule.AuthorEmailDomains),
+		RejectMergeCommits:   rule.RejectMergeCommits,
+		RejectSecrets:        rule.RejectSecrets,
+		Created:              rule.CreatedUnix.AsTime(),
+		Updated:              rule.UpdatedUnix.AsTime(),
+	}
+}
+
 // ToTag convert a git.Tag to an api.Tag
 func ToTag(repo *repo_model.Repository, t *git.Tag) *api.Tag {
 	tarballURL := util.URLJoin(repo.HTMLURL(), "archive", t.Name+".tar.gz")
@@ -272,6 +290,7 @@ func ToActionWorkflowRun(ctx context.Context, repo *repo_model.Repository, run *
 		URL:          fmt.Sprintf("%s/actions/runs/%d", repo.APIURL(), run.ID),
 		HTMLURL:      run.HTMLURL(),
 		RunNumber:    run.Index,
+		RunAttempt:   run.Attempt,
 		StartedAt:    run.Started.AsLocalTime(),
 		CompletedAt:  run.Stopped.AsLocalTime(),
 		Event:        string(run.Event),
@@ -291,7 +310,7 @@ func ToActionWorkflowRun(ctx context.Context, repo *repo_model.Repository, run *
 func ToWorkflowRunAction(status actions_model.Status) string {
 	var action string
 	switch status {
-	case actions_model.StatusWaiting, actions_model.StatusBlocked:
+	case actions_model.StatusWaiting, actions_model.StatusBlocked, actions_model.StatusPendingApproval:
 		action = "requested"
 	case actions_model.StatusRunning:
 		action = "in_progress"
@@ -309,7 +328,7 @@ func ToActionsStatus(status actions_model.Status) (string, string) {
 	// This is a naming conflict of the webhook between Kmup and GitHub Actions
 	case actions_model.StatusWaiting:
 		action = "queued"
-	case actions_model.StatusBlocked:
+	case actions_model.StatusBlocked, actions_model.StatusPendingApproval:
 		action = "waiting"
 	case actions_model.StatusRunning:
 		action = "in_progress"
@@ -404,6 +423,38 @@ func ToActionWorkflowJob(ctx context.Context, repo *repo_model.Repository, task
 	}, nil
 }
 
+// ToActionTestCase convert a actions_model.ActionTestCase to an api.ActionTestCase
+func ToActionTestCase(c *actions_model.ActionTestCase) *api.ActionTestCase {
+	return &api.ActionTestCase{
+		JobID:     c.JobID,
+		Suite:     c.Suite,
+		ClassName: c.ClassName,
+		Name:      c.Name,
+		Status:    string(c.Status),
+		Duration:  c.Duration.Milliseconds(),
+		Message:   c.Message,
+		Flaky:     c.Flaky,
+	}
+}
+
+// ToActionTaskAnnotation convert a actions_model.ActionTaskAnnotation to an api.ActionTaskAnnotation
+func ToActionTaskAnnotation(repo *repo_model.Repository, annotation *actions_model.ActionTaskAnnotation) *api.ActionTaskAnnotation {
+	apiAnnotation := &api.ActionTaskAnnotation{
+		Path:            annotation.Path,
+		StartLine:       annotation.StartLine,
+		EndLine:         annotation.EndLine,
+		StartColumn:     annotation.StartColumn,
+		EndColumn:       annotation.EndColumn,
+		AnnotationLevel: string(annotation.Level),
+		Title:           annotation.Title,
+		Message:         annotation.Message,
+	}
+	if annotation.Path != "" {
+		apiAnnotation.BlobHref = fmt.Sprintf("%s/src/commit/%s/%s", repo.HTMLURL(), annotation.CommitSHA, util.PathEscapeSegments(annotation.Path))
+	}
+	return apiAnnotation
+}
+
 func getActionWorkflowEntry(ctx context.Context, repo *repo_model.Repository, commit *git.Commit, folder string, entry *git.TreeEntry) *api.ActionWorkflow {
 	cfgUnit := repo.MustGetUnit(ctx, unit.TypeActions)
 	cfg := cfgUnit.ActionsConfig()
@@ -540,7 +591,26 @@ func ToActionRunner(ctx context.Context, runner *actions_model.ActionRunner) *ap
 		Busy:      status == runnerv1.RunnerStatus_RUNNER_STATUS_ACTIVE,
 		Ephemeral: runner.Ephemeral,
 		Labels:    labels,
+
+		RunnerGroupID: runner.GroupID,
+	}
+}
+
+// ToActionRunnerGroup convert a actions_model.ActionRunnerGroup to an api.ActionRunnerGroup
+func ToActionRunnerGroup(ctx context.Context, group *actions_model.ActionRunnerGroup, runnersCount int64) (*api.ActionRunnerGroup, error) {
+	owners, repos, err := group.GetAllowedNames(ctx)
+	if err != nil {
+		return nil, err
 	}
+	return &api.ActionRunnerGroup{
+		ID:               group.ID,
+		Name:             group.Name,
+		Description:      group.Description,
+		AllowedOwners:    owners,
+		AllowedRepos:     repos,
+		AllowedWorkflows: util.SliceNilAsEmpty(group.AllowedWorkflows),
+		RunnersCount:     runnersCount,
+	}, nil
 }
 
 // ToVerification convert a git.Commit.Signature to an api.PayloadCommitVerification
@@ -24,6 +24,8 @@ import (
 	access_model "github.com/kumose/kmup/models/perm/access"
 	user_model "github.com/kumose/kmup/models/user"
 	api "github.com/kumose/kmup/modules/structs"
+	"github.com/kumose/kmup/services/packages/signing"
+	"github.com/kumose/kmup/services/packages/vulnerability"
 )
 
 // ToPackage convert a packages.PackageDescriptor to api.Package
@@ -65,3 +67,106 @@ func ToPackageFile(pfd *packages.PackageFileDescriptor) *api.PackageFile {
 		HashSHA512: pfd.Blob.HashSHA512,
 	}
 }
+
+// ToPackageVerification converts signing.Verification to api.PackageVerification
+func ToPackageVerification(v *signing.Verification, signatureRequired bool) *api.PackageVerification {
+	attestations := make([]*api.PackageAttestation, 0, len(v.Bundles))
+	for _, b := range v.Bundles {
+		attestations = append(attestations, ToPackageAttestation(b))
+	}
+	return &api.PackageVerification{
+		Verified:          v.Verified,
+		SignatureRequired: signatureRequired,
+		Attestations:      attestations,
+	}
+}
+
+// ToPackageAttestation converts signing.Bundle to api.PackageAttestation
+func ToPackageAttestation(b *signing.Bundle) *api.PackageAttestation {
+	files := make([]string, 0, len(b.Files))
+	for _, pfd := range b.Files {
+		files = append(files, pfd.File.Name)
+	}
+
+	kind := "signature"
+	if b.Bundle.IsAttestation() {
+		kind = "attestation"
+	}
+
+	a := &api.PackageAttestation{
+		ID:            b.ID,
+		Kind:          kind,
+		PredicateType: b.Bundle.PredicateType(),
+		Files:         files,
+		Verified:      b.Result.Verified,
+		Signer:        b.Result.Signer,
+		Identity:      b.Result.Identity,
+		Issuer:        b.Result.Issuer,
+	}
+	if b.Result.Err != nil {
+		a.Error = b.Result.Err.Error()
+	}
+	return a
+}
+
+// ToPackageVulnerability converts vulnerability.Vulnerability to api.PackageVulnerability
+func ToPackageVulnerability(v *vulnerability.Vulnerability) *api.PackageVulnerability {
+	aliases := v.Advisory.Aliases
+	if aliases == nil {
+		aliases = []string{}
+	}
+	fixed := v.FixedVersions
+	if fixed == nil {
+		fixed = []string{}
+	}
+
+	return &api.PackageVulnerability{
+		AdvisoryID:    v.Advisory.ID,
+		Aliases:       aliases,
+		Summary:       v.Advisory.Summary,
+		Severity:      v.Severity,
+		URL:           v.Advisory.URL(),
+		Package:       v.Name,
+		Version:       v.Version,
+		Requirement:   v.Requirement,
+		FixedVersions: fixed,
+		PublishedAt:   v.Advisory.Published,
+		ModifiedAt:    v.Advisory.Modified,
+		FoundAt:       v.CreatedUnix.AsTime(),
+	}
+}
+
+// ToPackageCleanupRule converts a packages.PackageCleanupRule to api.PackageCleanupRule
+func ToPackageCleanupRule(pcr *packages.PackageCleanupRule) *api.PackageCleanupRule {
+	return &api.PackageCleanupRule{
+		ID:            pcr.ID,
+		Enabled:       pcr.Enabled,
+		Type:          string(pcr.Type),
+		KeepCount:     pcr.KeepCount,
+		KeepPattern:   pcr.KeepPattern,
+		RemoveDays:    pcr.RemoveDays,
+		RemovePattern: pcr.RemovePattern,
+		MatchFullName: pcr.MatchFullName,
+		CreatedAt:     pcr.CreatedUnix.AsTime(),
+		UpdatedAt:     pcr.UpdatedUnix.AsTime(),
+	}
+}
+
+// ToPackageCleanupRun converts a packages.PackageCleanupRun to api.PackageCleanupRun
+func ToPackageCleanupRun(run *packages.PackageCleanupRun) *api.PackageCleanupRun {
+	versions := run.Versions
+	if versions == nil {
+		versions = []string{}
+	}
+
+	return &api.PackageCleanupRun{
+		ID:           run.ID,
+		RuleID:       run.RuleID,
+		Type:         string(run.Type),
+		VersionCount: run.VersionCount,
+		FailedCount:  run.FailedCount,
+		Size:         run.Size,
+		Versions:     versions,
+		ExecutedAt:   run.CreatedUnix.AsTime(),
+	}
+}
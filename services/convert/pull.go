@@ -25,6 +25,7 @@ import (
 	issues_model "github.com/kumose/kmup/models/issues"
 	"github.com/kumose/kmup/models/perm"
 	access_model "github.com/kumose/kmup/models/perm/access"
+	pull_model "github.com/kumose/kmup/models/pull"
 	repo_model "github.com/kumose/kmup/models/repo"
 	user_model "github.com/kumose/kmup/models/user"
 	"github.com/kumose/kmup/modules/cache"
@@ -498,3 +499,29 @@ func ToAPIPullRequests(ctx context.Context, baseRepo *repo_model.Repository, prs
 
 	return apiPullRequests, nil
 }
+
+// ToAPIMergeQueueEntry converts a merge queue entry to api.MergeQueueEntry
+func ToAPIMergeQueueEntry(ctx context.Context, entry *pull_model.MergeQueueEntry, pr *issues_model.PullRequest, position int, state string, doer *user_model.User) (*api.MergeQueueEntry, error) {
+	if err := pr.LoadIssue(ctx); err != nil {
+		return nil, err
+	}
+	enqueuer, err := user_model.GetPossibleUserByID(ctx, entry.DoerID)
+	if err != nil {
+		return nil, err
+	}
+
+	apiEntry := &api.MergeQueueEntry{
+		Position:    position,
+		PullRequest: ToAPIPullRequest(ctx, pr, doer),
+		MergeStyle:  string(entry.MergeStyle),
+		Enqueuer:    ToUser(ctx, enqueuer, doer),
+		GroupSHA:    entry.GroupCommitID,
+		BaseSHA:     entry.BaseCommitID,
+		State:       state,
+		Created:     entry.CreatedUnix.AsTime(),
+	}
+	if entry.GroupCommitID != "" {
+		apiEntry.GroupRef = git.BranchPrefix + entry.GroupBranchName(pr.Index)
+	}
+	return apiEntry, nil
+}
@@ -0,0 +1,93 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package convert
+
+import (
+	"context"
+
+	git_model "github.com/kumose/kmup/models/git"
+	"github.com/kumose/kmup/models/organization"
+	"github.com/kumose/kmup/models/perm"
+	access_model "github.com/kumose/kmup/models/perm/access"
+	user_model "github.com/kumose/kmup/models/user"
+	api "github.com/kumose/kmup/modules/structs"
+	"github.com/kumose/kmup/modules/util"
+)
+
+// ToRuleset converts git_model.Ruleset to api.Ruleset
+func ToRuleset(ctx context.Context, rs *git_model.Ruleset) (*api.Ruleset, error) {
+	users, err := user_model.GetUsersMapByIDs(ctx, rs.BypassUserIDs)
+	if err != nil {
+		return nil, err
+	}
+	teams, err := organization.GetTeamsByIDs(ctx, rs.BypassTeamIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	bypassUsers := make([]string, 0, len(users))
+	for _, id := range rs.BypassUserIDs {
+		if user, ok := users[id]; ok {
+			bypassUsers = append(bypassUsers, user.Name)
+		}
+	}
+	bypassTeams := make([]string, 0, len(teams))
+	for _, id := range rs.BypassTeamIDs {
+		if team, ok := teams[id]; ok {
+			bypassTeams = append(bypassTeams, team.Name)
+		}
+	}
+
+	return &api.Ruleset{
+		ID:                    rs.ID,
+		Name:                  rs.Name,
+		Enforcement:           string(rs.Enforcement),
+		RepoNamePatterns:      util.SliceNilAsEmpty(rs.RepoNamePatterns),
+		RepoTopics:            util.SliceNilAsEmpty(rs.RepoTopics),
+		RefPatterns:           util.SliceNilAsEmpty(rs.RefPatterns),
+		ExcludeRefPatterns:    util.SliceNilAsEmpty(rs.ExcludeRefPatterns),
+		BlockCreation:         rs.BlockCreation,
+		BlockDeletion:         rs.BlockDeletion,
+		BlockForcePush:        rs.BlockForcePush,
+		RequirePullRequest:    rs.RequirePullRequest,
+		RequiredApprovals:     rs.RequiredApprovals,
+		EnableStatusCheck:     rs.EnableStatusCheck,
+		StatusCheckContexts:   util.SliceNilAsEmpty(rs.StatusCheckContexts),
+		RequireSignedCommits:  rs.RequireSignedCommits,
+		ProtectedFilePatterns: rs.ProtectedFilePatterns,
+		BypassUsers:           bypassUsers,
+		BypassTeams:           bypassTeams,
+		Created:               rs.CreatedUnix.AsTime(),
+		Updated:               rs.UpdatedUnix.AsTime(),
+	}, nil
+}
+
+// ToRulesetEvaluation converts git_model.RulesetEvaluation to api.RulesetEvaluation, its attributes must have been loaded
+func ToRulesetEvaluation(ctx context.Context, evaluation *git_model.RulesetEvaluation, doer *user_model.User) *api.RulesetEvaluation {
+	apiEvaluation := &api.RulesetEvaluation{
+		ID:      evaluation.ID,
+		RefName: evaluation.RefName,
+		Pusher:  ToUser(ctx, evaluation.Doer, doer),
+		Reason:  evaluation.Reason,
+		Created: evaluation.CreatedUnix.AsTime(),
+	}
+	if evaluation.Repo != nil {
+		apiEvaluation.Repository = ToRepo(ctx, evaluation.Repo, access_model.Permission{AccessMode: perm.AccessModeOwner})
+	}
+	return apiEvaluation
+}
@@ -0,0 +1,58 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package convert
+
+import (
+	"context"
+	"fmt"
+
+	repo_model "github.com/kumose/kmup/models/repo"
+	secretscan_model "github.com/kumose/kmup/models/secretscan"
+	user_model "github.com/kumose/kmup/models/user"
+	api "github.com/kumose/kmup/modules/structs"
+)
+
+// ToSecretScanningAlert converts secretscan_model.Alert to api.SecretScanningAlert
+func ToSecretScanningAlert(ctx context.Context, repo *repo_model.Repository, alert *secretscan_model.Alert) *api.SecretScanningAlert {
+	apiAlert := &api.SecretScanningAlert{
+		ID:             alert.ID,
+		RuleID:         alert.RuleID,
+		Description:    alert.Description,
+		RedactedSecret: alert.RedactedSecret,
+		CommitID:       alert.CommitID,
+		Path:           alert.Path,
+		Line:           alert.Line,
+		State:          string(alert.State),
+		HTMLURL:        fmt.Sprintf("%s/settings/secret_scanning#alert-%d", repo.HTMLURL(), alert.ID),
+		Created:        alert.CreatedUnix.AsTime(),
+		Updated:        alert.UpdatedUnix.AsTime(),
+	}
+
+	if alert.ResolvedUnix > 0 {
+		resolved := alert.ResolvedUnix.AsTime()
+		apiAlert.Resolved = &resolved
+	}
+	if alert.ResolverID != 0 {
+		resolver, _ := user_model.GetPossibleUserByID(ctx, alert.ResolverID)
+		if resolver != nil {
+			apiAlert.Resolver = ToUser(ctx, resolver, nil)
+		}
+	}
+
+	return apiAlert
+}
@@ -34,6 +34,8 @@ func initActionsTasks() {
 	registerCancelAbandonedJobs()
 	registerScheduleTasks()
 	registerActionsCleanup()
+	registerReleaseWaitingDeployments()
+	registerActionsCacheCleanup()
 }
 
 func registerStopZombieTasks() {
@@ -88,3 +90,26 @@ func registerActionsCleanup() {
 		return actions_service.Cleanup(ctx)
 	})
 }
+
+func registerReleaseWaitingDeployments() {
+	RegisterTaskFatal("release_waiting_deployments", &BaseConfig{
+		Enabled:    true,
+		RunAtStart: true,
+		Schedule:   "@every 1m",
+	}, func(ctx context.Context, _ *user_model.User, _ Config) error {
+		return actions_service.ReleaseWaitingDeployments(ctx, 0)
+	})
+}
+
+func registerActionsCacheCleanup() {
+	if !setting.Actions.CacheEnabled {
+		return
+	}
+	RegisterTaskFatal("cleanup_actions_cache", &BaseConfig{
+		Enabled:    true,
+		RunAtStart: false,
+		Schedule:   "@every 6h",
+	}, func(ctx context.Context, _ *user_model.User, _ Config) error {
+		return actions_service.CleanupCaches(ctx)
+	})
+}
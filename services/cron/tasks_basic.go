@@ -31,6 +31,7 @@ import (
 	"github.com/kumose/kmup/services/migrations"
 	mirror_service "github.com/kumose/kmup/services/mirror"
 	packages_cleanup_service "github.com/kumose/kmup/services/packages/cleanup"
+	packages_vulnerability_service "github.com/kumose/kmup/services/packages/vulnerability"
 	repo_service "github.com/kumose/kmup/services/repository"
 	archiver_service "github.com/kumose/kmup/services/repository/archiver"
 )
@@ -170,6 +171,16 @@ func registerCleanupPackages() {
 	})
 }
 
+func registerImportPackageAdvisories() {
+	RegisterTaskFatal("import_package_advisories", &BaseConfig{
+		Enabled:    true,
+		RunAtStart: false,
+		Schedule:   "@midnight",
+	}, func(ctx context.Context, _ *user_model.User, _ Config) error {
+		return packages_vulnerability_service.ImportAdvisoriesFromSetting(ctx)
+	})
+}
+
 func registerSyncRepoLicenses() {
 	RegisterTaskFatal("sync_repo_licenses", &BaseConfig{
 		Enabled:    false,
@@ -195,6 +206,7 @@ func initBasicTasks() {
 	registerCleanupHookTaskTable()
 	if setting.Packages.Enabled {
 		registerCleanupPackages()
+		registerImportPackageAdvisories()
 	}
 	registerSyncRepoLicenses()
 }
@@ -19,6 +19,8 @@ package cron
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
 	activities_model "github.com/kumose/kmup/models/activities"
@@ -31,6 +33,7 @@ import (
 	asymkey_service "github.com/kumose/kmup/services/asymkey"
 	repo_service "github.com/kumose/kmup/services/repository"
 	archiver_service "github.com/kumose/kmup/services/repository/archiver"
+	storage_service "github.com/kumose/kmup/services/storage"
 	user_service "github.com/kumose/kmup/services/user"
 )
 
@@ -228,6 +231,50 @@ func registerGCLFS() {
 	})
 }
 
+type GCStorageConfig struct {
+	BaseConfig
+	OlderThan  time.Duration
+	DryRun     bool
+	BatchSize  int
+	BatchPause time.Duration
+}
+
+func registerGCStorage() {
+	RegisterTaskFatal("gc_storage", &GCStorageConfig{
+		BaseConfig: BaseConfig{
+			Enabled:    false,
+			RunAtStart: false,
+			Schedule:   "@every 168h",
+		},
+		// Objects are written before the database rows which reference them are committed
+		OlderThan:  24 * time.Hour,
+		BatchSize:  100,
+		BatchPause: time.Second,
+	}, func(ctx context.Context, _ *user_model.User, config Config) error {
+		gcStorageConfig := config.(*GCStorageConfig)
+		reports, err := storage_service.GarbageCollect(ctx, &storage_service.GCOptions{
+			OlderThan:  gcStorageConfig.OlderThan,
+			DryRun:     gcStorageConfig.DryRun,
+			BatchSize:  gcStorageConfig.BatchSize,
+			BatchPause: gcStorageConfig.BatchPause,
+		})
+		if err != nil {
+			return err
+		}
+
+		var findings []string
+		for _, report := range reports {
+			if report.Orphaned > 0 || report.Missing > 0 {
+				findings = append(findings, fmt.Sprintf("%s: %d orphaned objects (%d deleted), %d missing objects", report.Name, report.Orphaned, report.Deleted, report.Missing))
+			}
+		}
+		if len(findings) == 0 {
+			return nil
+		}
+		return system.CreateNotice(ctx, system.NoticeTask, "Storage garbage collection: "+strings.Join(findings, "; "))
+	})
+}
+
 func registerRebuildIssueIndexer() {
 	RegisterTaskFatal("rebuild_issue_indexer", &BaseConfig{
 		Enabled:    false,
@@ -252,5 +299,6 @@ func initExtendedTasks() {
 	registerUpdateKmupChecker()
 	registerDeleteOldSystemNotices()
 	registerGCLFS()
+	registerGCStorage()
 	registerRebuildIssueIndexer()
 }
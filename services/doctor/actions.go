@@ -87,6 +87,7 @@ func fixUnfinishedRunStatus(ctx context.Context, logger log.Logger, autofix bool
 		actions_model.StatusWaiting,
 		actions_model.StatusRunning,
 		actions_model.StatusBlocked,
+		actions_model.StatusPendingApproval,
 	}).And(builder.Lt{"updated": timeutil.TimeStampNow().AddDuration(-setting.Actions.ZombieTaskTimeout)})
 
 	err := db.Iterate(
@@ -91,3 +91,30 @@ func (f *CreateTeamForm) Validate(req *http.Request, errs binding.Errors) bindin
 	ctx := context.GetValidateContext(req)
 	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
 }
+
+// RulesetForm form for creating or editing a ruleset
+type RulesetForm struct {
+	Name                  string `binding:"Required;MaxSize(255)"`
+	Enforcement           string `binding:"In(active,evaluate,disabled)"`
+	RepoNamePatterns      string
+	RepoTopics            string
+	RefPatterns           string `binding:"Required"`
+	ExcludeRefPatterns    string
+	BlockCreation         bool
+	BlockDeletion         bool
+	BlockForcePush        bool
+	RequirePullRequest    bool
+	RequiredApprovals     int64
+	EnableStatusCheck     bool
+	StatusCheckContexts   string
+	RequireSignedCommits  bool
+	ProtectedFilePatterns string
+	BypassUsers           string
+	BypassTeams           string
+}
+
+// Validate validates the fields
+func (f *RulesetForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	ctx := context.GetValidateContext(req)
+	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
+}
@@ -29,7 +29,7 @@ import (
 type PackageCleanupRuleForm struct {
 	ID            int64
 	Enabled       bool
-	Type          string `binding:"Required;In(alpine,arch,cargo,chef,composer,conan,conda,container,cran,debian,generic,go,helm,maven,npm,nuget,pub,pypi,rpm,rubygems,swift,vagrant)"`
+	Type          string `binding:"Required;In(alpine,arch,cargo,chef,composer,conan,conda,container,cran,debian,generic,go,helm,hex,maven,npm,nuget,pub,pypi,rpm,rubygems,swift,terraform_module,terraform_provider,vagrant)"`
 	KeepCount     int    `binding:"In(0,1,5,10,25,50,100)"`
 	KeepPattern   string `binding:"RegexPattern"`
 	RemoveDays    int    `binding:"In(0,7,14,30,60,90,180)"`
@@ -42,3 +42,35 @@ func (f *PackageCleanupRuleForm) Validate(req *http.Request, errs binding.Errors
 	ctx := context.GetValidateContext(req)
 	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
 }
+
+type PackageRemoteForm struct {
+	ID         int64
+	Enabled    bool
+	Type       string `binding:"Required;In(container,maven,npm,pypi)"`
+	URL        string `binding:"Required;ValidUrl"`
+	Username   string
+	Password   string
+	TTLMinutes int    `binding:"In(0,5,30,60,360,1440)"`
+	KeepCount  int    `binding:"In(0,1,5,10,25,50,100)"`
+	RemoveDays int    `binding:"In(0,7,14,30,60,90,180)"`
+	Action     string `binding:"Required;In(save,remove)"`
+}
+
+func (f *PackageRemoteForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	ctx := context.GetValidateContext(req)
+	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
+}
+
+type PackageSignerForm struct {
+	ID        int64
+	Name      string `binding:"Required;MaxSize(255)"`
+	PublicKey string
+	Identity  string
+	Issuer    string
+	Action    string `binding:"Required;In(save,remove)"`
+}
+
+func (f *PackageSignerForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	ctx := context.GetValidateContext(req)
+	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
+}
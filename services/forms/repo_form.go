@@ -175,6 +175,21 @@ func (f *RepoSettingForm) Validate(req *http.Request, errs binding.Errors) bindi
 	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
 }
 
+// EditEnvironmentForm form for changing the protection rules of an actions environment
+type EditEnvironmentForm struct {
+	Name           string `binding:"Required;MaxSize(255)"`
+	Reviewers      string
+	WaitTimer      int64
+	BranchPatterns string
+	TagPatterns    string
+}
+
+// Validate validates the fields
+func (f *EditEnvironmentForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	ctx := context.GetValidateContext(req)
+	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
+}
+
 // ProtectBranchForm form for changing protected branch settings
 type ProtectBranchForm struct {
 	RuleName                      string `binding:"Required"`
@@ -205,6 +220,9 @@ type ProtectBranchForm struct {
 	ProtectedFilePatterns         string
 	UnprotectedFilePatterns       string
 	BlockAdminMergeOverride       bool
+	RequireRequiredWorkflows      bool
+	RequireCodeOwnerApproval      bool
+	EnableMergeQueue              bool
 }
 
 // Validate validates the fields
@@ -217,6 +235,16 @@ type ProtectBranchPriorityForm struct {
 	IDs []int64
 }
 
+// SecretScanningForm form for changing the secret scanning setting of a repository
+type SecretScanningForm struct {
+	PushProtection string
+}
+
+// SecretScanningAlertForm form for changing the state of a secret scanning alert
+type SecretScanningAlertForm struct {
+	State string `binding:"Required"`
+}
+
 // WebhookForm form for changing web hook
 type WebhookForm struct {
 	Events                   string
@@ -242,6 +270,8 @@ type WebhookForm struct {
 	Release                  bool
 	Package                  bool
 	Status                   bool
+	MergeGroup               bool
+	SecretScanningAlert      bool
 	WorkflowRun              bool
 	WorkflowJob              bool
 	Active                   bool
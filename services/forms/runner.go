@@ -29,6 +29,7 @@ import (
 // EditRunnerForm form for admin to create runner
 type EditRunnerForm struct {
 	Description string
+	GroupID     int64 // only global runners edited by admins can be moved into runner groups
 }
 
 // Validate validates form fields
@@ -36,3 +37,18 @@ func (f *EditRunnerForm) Validate(req *http.Request, errs binding.Errors) bindin
 	ctx := context.GetValidateContext(req)
 	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
 }
+
+// RunnerGroupForm form for admin to create or edit a runner group
+type RunnerGroupForm struct {
+	Name             string `binding:"Required;MaxSize(255)"`
+	Description      string
+	AllowedOwners    string
+	AllowedRepos     string
+	AllowedWorkflows string
+}
+
+// Validate validates form fields
+func (f *RunnerGroupForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	ctx := context.GetValidateContext(req)
+	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
+}
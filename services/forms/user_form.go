@@ -359,6 +359,36 @@ func (f *EditVariableForm) Validate(req *http.Request, errs binding.Errors) bind
 	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
 }
 
+// RequiredWorkflowForm form for adding an org- or instance-level required workflow
+type RequiredWorkflowForm struct {
+	RepoName     string `binding:"Required;MaxSize(255)"`
+	WorkflowPath string `binding:"Required;MaxSize(255)"`
+	Ref          string `binding:"MaxSize(255)"`
+	RepoPatterns string
+	Topics       string
+}
+
+func (f *RequiredWorkflowForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	ctx := context.GetValidateContext(req)
+	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
+}
+
+// PushRuleForm form for setting a repo-, org- or instance-level push rule
+type PushRuleForm struct {
+	MaxFileSize          int64
+	ForbiddenPaths       string
+	ForbiddenExtensions  string
+	CommitMessagePattern string `binding:"MaxSize(255)"`
+	AuthorEmailDomains   string
+	RejectMergeCommits   bool
+	RejectSecrets        bool
+}
+
+func (f *PushRuleForm) Validate(req *http.Request, errs binding.Errors) binding.Errors {
+	ctx := context.GetValidateContext(req)
+	return middleware.Validate(errs, ctx.Data, f, ctx.Locale)
+}
+
 // NewAccessTokenForm form for creating access token
 type NewAccessTokenForm struct {
 	Name string `binding:"Required;MaxSize(255)" locale:"settings.token_name"`
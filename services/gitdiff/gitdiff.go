@@ -32,6 +32,7 @@ import (
 	"strings"
 	"time"
 
+	actions_model "github.com/kumose/kmup/models/actions"
 	"github.com/kumose/kmup/models/db"
 	git_model "github.com/kumose/kmup/models/git"
 	issues_model "github.com/kumose/kmup/models/issues"
@@ -90,7 +91,8 @@ type DiffLine struct {
 	Match       int // the diff matched index. -1: no match. 0: plain and no need to match. >0: for add/del, "Lines" slice index of the other side
 	Type        DiffLineType
 	Content     string
-	Comments    issues_model.CommentList // related PR code comments
+	Comments    issues_model.CommentList              // related PR code comments
+	Annotations []*actions_model.ActionTaskAnnotation // related annotations of the actions jobs
 	SectionInfo *DiffLineSectionInfo
 }
 
@@ -567,6 +569,42 @@ func (diff *Diff) LoadComments(ctx context.Context, issue *issues_model.Issue, c
 	return nil
 }
 
+// LoadAnnotations loads the annotations of the actions jobs which ran for the commit, and attaches them to the lines of the new files
+func (diff *Diff) LoadAnnotations(ctx context.Context, repoID int64, commitID string) error {
+	annotations, err := actions_model.FindCommitAnnotations(ctx, repoID, commitID)
+	if err != nil {
+		return err
+	}
+	if len(annotations) == 0 {
+		return nil
+	}
+	fileAnnotations := make(map[string]map[int][]*actions_model.ActionTaskAnnotation)
+	for _, annotation := range annotations {
+		if annotation.Line() <= 0 {
+			continue
+		}
+		if fileAnnotations[annotation.Path] == nil {
+			fileAnnotations[annotation.Path] = make(map[int][]*actions_model.ActionTaskAnnotation)
+		}
+		fileAnnotations[annotation.Path][annotation.Line()] = append(fileAnnotations[annotation.Path][annotation.Line()], annotation)
+	}
+	for _, file := range diff.Files {
+		lineAnnotations, ok := fileAnnotations[file.Name]
+		if !ok {
+			continue
+		}
+		for _, section := range file.Sections {
+			for _, line := range section.Lines {
+				if line.Type == DiffLineDel || line.Type == DiffLineSection {
+					continue
+				}
+				line.Annotations = lineAnnotations[line.RightIdx]
+			}
+		}
+	}
+	return nil
+}
+
 const cmdDiffHead = "diff --git "
 
 // ParsePatch builds a Diff object from a io.Reader and some parameters.
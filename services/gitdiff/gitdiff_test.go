@@ -23,6 +23,7 @@ import (
 	"strings"
 	"testing"
 
+	actions_model "github.com/kumose/kmup/models/actions"
 	issues_model "github.com/kumose/kmup/models/issues"
 	"github.com/kumose/kmup/models/unittest"
 	user_model "github.com/kumose/kmup/models/user"
@@ -599,6 +600,29 @@ func TestDiff_LoadCommentsWithOutdated(t *testing.T) {
 	assert.Len(t, diff.Files[0].Sections[0].Lines[0].Comments, 3)
 }
 
+func TestDiff_LoadAnnotations(t *testing.T) {
+	assert.NoError(t, unittest.PrepareTestDatabase())
+
+	// task 47 is the latest task of a job which ran for the commit in repo 4
+	const commitID = "c2d72f548424103f01ee1dc02889c1e2bff816b0"
+	require.NoError(t, actions_model.InsertTaskAnnotations(t.Context(), 47, []*actions_model.ActionTaskAnnotation{
+		{TaskID: 47, RepoID: 4, CommitSHA: commitID, Level: actions_model.AnnotationLevelFailure, Path: "README.md", StartLine: 2, EndLine: 4},
+		{TaskID: 47, RepoID: 4, CommitSHA: commitID, Level: actions_model.AnnotationLevelWarning, Path: "README.md", StartLine: 5},
+		{TaskID: 47, RepoID: 4, CommitSHA: commitID, Level: actions_model.AnnotationLevelNotice, Path: "other.md", StartLine: 4},
+	}))
+
+	diff := setupDefaultDiff()
+	assert.NoError(t, diff.LoadAnnotations(t.Context(), 4, commitID))
+	annotations := diff.Files[0].Sections[0].Lines[0].Annotations
+	if assert.Len(t, annotations, 1) {
+		assert.Equal(t, actions_model.AnnotationLevelFailure, annotations[0].Level)
+	}
+
+	diff = setupDefaultDiff()
+	assert.NoError(t, diff.LoadAnnotations(t.Context(), 4, "0000000000000000000000000000000000000000"))
+	assert.Empty(t, diff.Files[0].Sections[0].Lines[0].Annotations)
+}
+
 func TestDiffLine_CanComment(t *testing.T) {
 	assert.False(t, (&DiffLine{Type: DiffLineSection}).CanComment())
 	assert.False(t, (&DiffLine{Type: DiffLineAdd, Comments: []*issues_model.Comment{{Content: "bla"}}}).CanComment())
@@ -18,6 +18,7 @@
 package indexer
 
 import (
+	actions_indexer "github.com/kumose/kmup/modules/indexer/actions"
 	code_indexer "github.com/kumose/kmup/modules/indexer/code"
 	issue_indexer "github.com/kumose/kmup/modules/indexer/issues"
 	stats_indexer "github.com/kumose/kmup/modules/indexer/stats"
@@ -30,5 +31,6 @@ func Init() error {
 
 	issue_indexer.InitIssueIndexer(false)
 	code_indexer.Init()
+	actions_indexer.Init()
 	return stats_indexer.Init()
 }
@@ -63,6 +63,25 @@ func IsCodeOwnerFile(f string) bool {
 	return slices.Contains(codeOwnerFiles, f)
 }
 
+// GetCodeOwnerRules returns the rules of the first code owners file found in the commit
+func GetCodeOwnerRules(ctx context.Context, commit *git.Commit) []*issues_model.CodeOwnerRule {
+	var data string
+	for _, file := range codeOwnerFiles {
+		if blob, err := commit.GetBlobByPath(file); err == nil {
+			data, err = blob.GetBlobContent(setting.UI.MaxDisplayFileSize)
+			if err == nil {
+				break
+			}
+		}
+	}
+	if data == "" {
+		return nil
+	}
+
+	rules, _ := issues_model.GetCodeOwnersFromContent(ctx, data)
+	return rules
+}
+
 func PullRequestCodeOwnersReview(ctx context.Context, pr *issues_model.PullRequest) ([]*ReviewRequestNotifier, error) {
 	if err := pr.LoadIssue(ctx); err != nil {
 		return nil, err
@@ -94,20 +113,7 @@ func PullRequestCodeOwnersReview(ctx context.Context, pr *issues_model.PullReque
 		return nil, err
 	}
 
-	var data string
-	for _, file := range codeOwnerFiles {
-		if blob, err := commit.GetBlobByPath(file); err == nil {
-			data, err = blob.GetBlobContent(setting.UI.MaxDisplayFileSize)
-			if err == nil {
-				break
-			}
-		}
-	}
-	if data == "" {
-		return nil, nil
-	}
-
-	rules, _ := issues_model.GetCodeOwnersFromContent(ctx, data)
+	rules := GetCodeOwnerRules(ctx, commit)
 	if len(rules) == 0 {
 		return nil, nil
 	}
@@ -129,7 +135,7 @@ func PullRequestCodeOwnersReview(ctx context.Context, pr *issues_model.PullReque
 	uniqTeams := make(map[string]*org_model.Team)
 	for _, rule := range rules {
 		for _, f := range changedFiles {
-			if (rule.Rule.MatchString(f) && !rule.Negative) || (!rule.Rule.MatchString(f) && rule.Negative) {
+			if rule.Match(f) {
 				for _, u := range rule.Users {
 					uniqUsers[u.ID] = u
 				}
@@ -0,0 +1,440 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package mergequeue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kumose/kmup/models/db"
+	git_model "github.com/kumose/kmup/models/git"
+	issues_model "github.com/kumose/kmup/models/issues"
+	pull_model "github.com/kumose/kmup/models/pull"
+	repo_model "github.com/kumose/kmup/models/repo"
+	user_model "github.com/kumose/kmup/models/user"
+	"github.com/kumose/kmup/modules/git"
+	"github.com/kumose/kmup/modules/gitrepo"
+	"github.com/kumose/kmup/modules/globallock"
+	"github.com/kumose/kmup/modules/graceful"
+	"github.com/kumose/kmup/modules/log"
+	"github.com/kumose/kmup/modules/process"
+	"github.com/kumose/kmup/modules/queue"
+	"github.com/kumose/kmup/modules/util"
+	notify_service "github.com/kumose/kmup/services/notify"
+	pull_service "github.com/kumose/kmup/services/pull"
+	repo_service "github.com/kumose/kmup/services/repository"
+)
+
+// Reasons why a merge group is destroyed, they are sent with the merge_group webhook and actions event
+const (
+	GroupDestroyedMerged      = "merged"      // the merge group was merged into the target branch
+	GroupDestroyedInvalidated = "invalidated" // the merge group is outdated or its pull request was removed from the queue because of a failure
+	GroupDestroyedDequeued    = "dequeued"    // the pull request was removed from the queue by a user or closed
+)
+
+// States of a merge queue entry
+const (
+	StateQueued = "queued" // the merge group of the entry hasn't been built yet
+)
+
+var mergeQueue *queue.WorkerPoolQueue[string]
+
+// Init runs the task queue that processes the merge queues
+func Init() error {
+	notify_service.RegisterNotifier(NewNotifier())
+
+	mergeQueue = queue.CreateUniqueQueue(graceful.GetManager().ShutdownContext(), "pr_merge_queue", handler)
+	if mergeQueue == nil {
+		return errors.New("unable to create pr_merge_queue queue")
+	}
+	go graceful.GetManager().RunWithCancel(mergeQueue)
+	return nil
+}
+
+// handle passed "repoID/branch" items and process their merge queues,
+// the merge queues which are being processed by another worker are returned to be processed again later
+func handler(items ...string) (unhandled []string) {
+	for _, s := range items {
+		repoIDStr, branch, ok := strings.Cut(s, "/")
+		repoID, err := strconv.ParseInt(repoIDStr, 10, 64)
+		if !ok || err != nil {
+			log.Error("could not parse data from pr_merge_queue queue (%v)", s)
+			continue
+		}
+		if !processMergeQueue(repoID, branch) {
+			unhandled = append(unhandled, s)
+		}
+	}
+	return unhandled
+}
+
+// StartMergeQueueProcessing adds the merge queue of a branch to the processing queue
+func StartMergeQueueProcessing(repoID int64, branch string) {
+	if mergeQueue == nil {
+		return
+	}
+	if err := mergeQueue.Push(fmt.Sprintf("%d/%s", repoID, branch)); err != nil && !errors.Is(err, queue.ErrAlreadyInQueue) {
+		log.Error("Error adding merge queue of branch %s in repo %d to the processing queue: %v", branch, repoID, err)
+	}
+}
+
+// StartRepoMergeQueuesProcessing adds all non-empty merge queues of a repository to the processing queue
+func StartRepoMergeQueuesProcessing(ctx context.Context, repoID int64) {
+	branches, err := pull_model.GetMergeQueueBranches(ctx, repoID)
+	if err != nil {
+		log.Error("GetMergeQueueBranches: %v", err)
+		return
+	}
+	for _, branch := range branches {
+		StartMergeQueueProcessing(repoID, branch)
+	}
+}
+
+// AddToMergeQueue appends a pull request to the merge queue of its base branch
+func AddToMergeQueue(ctx context.Context, doer *user_model.User, pr *issues_model.PullRequest, style repo_model.MergeStyle, message string, deleteBranchAfterMerge bool) error {
+	if required, err := pull_service.IsMergeQueueRequired(ctx, pr); err != nil {
+		return err
+	} else if !required {
+		return util.NewInvalidArgumentErrorf("branch %s doesn't use a merge queue", pr.BaseBranch)
+	}
+
+	err := db.WithTx(ctx, func(ctx context.Context) error {
+		if err := pull_model.InsertMergeQueueEntry(ctx, &pull_model.MergeQueueEntry{
+			RepoID:                 pr.BaseRepoID,
+			BaseBranch:             pr.BaseBranch,
+			PullID:                 pr.ID,
+			DoerID:                 doer.ID,
+			MergeStyle:             style,
+			Message:                message,
+			DeleteBranchAfterMerge: deleteBranchAfterMerge,
+		}); err != nil {
+			return err
+		}
+		_, err := issues_model.CreateMergeQueueComment(ctx, issues_model.CommentTypePRAddedToMergeQueue, pr, doer, "")
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Trace("Pull request [%d] added to the merge queue of branch %s with style [%s]", pr.ID, pr.BaseBranch, style)
+	StartMergeQueueProcessing(pr.BaseRepoID, pr.BaseBranch)
+	return nil
+}
+
+// RemoveFromMergeQueue removes a pull request from the merge queue of its base branch
+func RemoveFromMergeQueue(ctx context.Context, doer *user_model.User, pr *issues_model.PullRequest) error {
+	entry, exists, err := pull_model.GetMergeQueueEntryByPullID(ctx, pr.ID)
+	if err != nil {
+		return err
+	} else if !exists {
+		return util.NewNotExistErrorf("pull request is not in a merge queue")
+	}
+
+	releaser, err := globallock.Lock(ctx, getMergeQueueLockKey(entry.RepoID, entry.BaseBranch))
+	if err != nil {
+		return fmt.Errorf("globallock.Lock: %w", err)
+	}
+	err = removeEntry(ctx, doer, pr, entry, pull_model.MergeQueueRemovedDequeued)
+	releaser()
+	if err != nil {
+		return err
+	}
+
+	// the merge groups behind the removed entry have to be rebuilt
+	StartMergeQueueProcessing(entry.RepoID, entry.BaseBranch)
+	return nil
+}
+
+// QueuedPullRequest is a pull request waiting in a merge queue
+type QueuedPullRequest struct {
+	Entry       *pull_model.MergeQueueEntry
+	PullRequest *issues_model.PullRequest
+	Position    int    // the position of the entry in the queue, starting from 1
+	State       string // StateQueued or the combined commit status state of the merge group
+}
+
+// GetMergeQueue returns the pull requests in the merge queue of a branch in queue order
+func GetMergeQueue(ctx context.Context, repo *repo_model.Repository, branch string) ([]*QueuedPullRequest, error) {
+	entries, err := pull_model.GetMergeQueueEntries(ctx, repo.ID, branch)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	pb, err := git_model.GetFirstMatchProtectedBranchRule(ctx, repo.ID, branch)
+	if err != nil {
+		return nil, err
+	}
+
+	queued := make([]*QueuedPullRequest, 0, len(entries))
+	for i, entry := range entries {
+		pr, err := issues_model.GetPullRequestByID(ctx, entry.PullID)
+		if err != nil {
+			return nil, err
+		}
+		state := StateQueued
+		if entry.GroupCommitID != "" && pb != nil {
+			groupState, err := pull_service.GetMergeGroupCommitStatusState(ctx, repo, pb, entry.GroupCommitID)
+			if err != nil {
+				return nil, err
+			}
+			state = string(groupState)
+		}
+		queued = append(queued, &QueuedPullRequest{
+			Entry:       entry,
+			PullRequest: pr,
+			Position:    i + 1,
+			State:       state,
+		})
+	}
+	return queued, nil
+}
+
+// GetQueuedPullRequest returns the merge queue entry of a pull request, or nil if it isn't queued
+func GetQueuedPullRequest(ctx context.Context, pr *issues_model.PullRequest) (*QueuedPullRequest, error) {
+	if err := pr.LoadBaseRepo(ctx); err != nil {
+		return nil, err
+	}
+	queued, err := GetMergeQueue(ctx, pr.BaseRepo, pr.BaseBranch)
+	if err != nil {
+		return nil, err
+	}
+	for _, q := range queued {
+		if q.PullRequest.ID == pr.ID {
+			return q, nil
+		}
+	}
+	return nil, nil
+}
+
+func getMergeQueueLockKey(repoID int64, branch string) string {
+	return fmt.Sprintf("merge_queue_%d_%s", repoID, branch)
+}
+
+// groupDestroyedReason maps the reason why an entry leaves the queue to the reason of the destroyed merge group event
+func groupDestroyedReason(reason string) string {
+	switch reason {
+	case pull_model.MergeQueueRemovedMerged:
+		return GroupDestroyedMerged
+	case pull_model.MergeQueueRemovedDequeued, pull_model.MergeQueueRemovedClosed:
+		return GroupDestroyedDequeued
+	default:
+		return GroupDestroyedInvalidated
+	}
+}
+
+// removeEntry removes an entry from its merge queue, deletes its merge group branch and records the reason on the pull request.
+// The caller must hold the lock of the merge queue.
+func removeEntry(ctx context.Context, doer *user_model.User, pr *issues_model.PullRequest, entry *pull_model.MergeQueueEntry, reason string) error {
+	if err := db.WithTx(ctx, func(ctx context.Context) error {
+		if err := pull_model.DeleteMergeQueueEntry(ctx, entry.ID); err != nil {
+			return err
+		}
+		_, err := issues_model.CreateMergeQueueComment(ctx, issues_model.CommentTypePRRemovedFromMergeQueue, pr, doer, reason)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	if entry.GroupCommitID == "" {
+		return nil
+	}
+	if err := pr.LoadBaseRepo(ctx); err != nil {
+		return err
+	}
+	if err := gitrepo.RemoveRef(ctx, pr.BaseRepo, git.BranchPrefix+entry.GroupBranchName(pr.Index)); err != nil {
+		log.Error("RemoveRef of merge group %s in %-v: %v", entry.GroupBranchName(pr.Index), pr.BaseRepo, err)
+	}
+	notify_service.MergeGroupDestroyed(ctx, doer, pr, entry, groupDestroyedReason(reason))
+	return nil
+}
+
+// processMergeQueue brings the merge queue of a branch up to date: entries which can't be merged anymore are removed,
+// outdated merge groups are rebuilt and the first entries are merged as soon as the required checks of their merge groups pass.
+// Merging a merge group sends notifications which may ask to process the same merge queue again while it is locked,
+// so it only returns false if the merge queue is already being processed.
+func processMergeQueue(repoID int64, branch string) bool {
+	ctx, _, finished := process.GetManager().AddContext(graceful.GetManager().HammerContext(),
+		fmt.Sprintf("Process merge queue of branch %s in repo %d", branch, repoID))
+	defer finished()
+
+	locked, releaser, err := globallock.TryLock(ctx, getMergeQueueLockKey(repoID, branch))
+	if err != nil {
+		log.Error("globallock.TryLock: %v", err)
+		return true
+	} else if !locked {
+		return false
+	}
+	defer releaser()
+
+	if err := processMergeQueueLocked(ctx, repoID, branch); err != nil {
+		log.Error("Processing merge queue of branch %s in repo %d: %v", branch, repoID, err)
+	}
+	return true
+}
+
+func processMergeQueueLocked(ctx context.Context, repoID int64, branch string) error {
+	entries, err := pull_model.GetMergeQueueEntries(ctx, repoID, branch)
+	if err != nil || len(entries) == 0 {
+		return err
+	}
+
+	repo, err := repo_model.GetRepositoryByID(ctx, repoID)
+	if err != nil {
+		return err
+	}
+	pb, err := git_model.GetFirstMatchProtectedBranchRule(ctx, repoID, branch)
+	if err != nil {
+		return err
+	}
+
+	gitRepo, err := gitrepo.OpenRepository(ctx, repo)
+	if err != nil {
+		return err
+	}
+	defer gitRepo.Close()
+
+	baseCommitID, err := gitRepo.GetBranchCommitID(branch)
+	if err != nil {
+		return err
+	}
+
+	// every merge group is built on top of the merge group of the previous entry which stays in the queue
+	parentBranch, parentCommitID := branch, baseCommitID
+	isHead := true
+	for _, entry := range entries {
+		pr, err := issues_model.GetPullRequestByID(ctx, entry.PullID)
+		if err != nil {
+			return err
+		}
+		if err := pr.LoadIssue(ctx); err != nil {
+			return err
+		}
+		doer, err := user_model.GetUserByID(ctx, entry.DoerID)
+		if err != nil {
+			return err
+		}
+
+		reason := ""
+		switch {
+		case pb == nil || !pb.EnableMergeQueue:
+			reason = pull_model.MergeQueueRemovedDisabled
+		case pr.HasMerged || pr.Issue.IsClosed:
+			reason = pull_model.MergeQueueRemovedClosed
+		case pr.BaseBranch != branch:
+			reason = pull_model.MergeQueueRemovedUpdated
+		}
+		if reason != "" {
+			if err := removeEntry(ctx, doer, pr, entry, reason); err != nil {
+				return err
+			}
+			continue
+		}
+
+		headCommitID, err := gitRepo.GetRefCommitID(pr.GetGitHeadRefName())
+		if err != nil {
+			return err
+		}
+		if entry.HeadCommitID != "" && entry.HeadCommitID != headCommitID {
+			if err := removeEntry(ctx, doer, pr, entry, pull_model.MergeQueueRemovedUpdated); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if entry.GroupCommitID == "" || entry.BaseCommitID != parentCommitID {
+			if entry.GroupCommitID != "" {
+				notify_service.MergeGroupDestroyed(ctx, doer, pr, entry, GroupDestroyedInvalidated)
+			}
+			groupCommitID, builtOnCommitID, err := pull_service.BuildMergeGroup(ctx, pr, doer, entry.MergeStyle, entry.Message, parentBranch, entry.GroupBranchName(pr.Index))
+			if err != nil {
+				if !pull_service.IsErrMergeGroupConflict(err) {
+					return err
+				}
+				log.Info("Unable to build the merge group of %-v on %s: %v", pr, parentBranch, err)
+				if err := removeEntry(ctx, doer, pr, entry, pull_model.MergeQueueRemovedConflict); err != nil {
+					return err
+				}
+				continue
+			}
+			entry.HeadCommitID, entry.BaseCommitID, entry.GroupCommitID = headCommitID, builtOnCommitID, groupCommitID
+			if err := pull_model.UpdateMergeQueueEntryGroup(ctx, entry); err != nil {
+				return err
+			}
+			notify_service.MergeGroupChecksRequested(ctx, doer, pr, entry)
+		}
+
+		// a failing merge group is removed at once wherever it is in the queue, so the groups behind it get rebuilt without waiting for it to reach the head
+		state, err := pull_service.GetMergeGroupCommitStatusState(ctx, repo, pb, entry.GroupCommitID)
+		if err != nil {
+			return err
+		}
+		if state.IsFailure() || state.IsError() {
+			if err := removeEntry(ctx, doer, pr, entry, pull_model.MergeQueueRemovedChecksFailed); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if isHead {
+			if state.IsSuccess() && entry.BaseCommitID == baseCommitID {
+				if err := mergeEntry(ctx, doer, pr, entry); err != nil {
+					log.Error("Merging the merge group of %-v: %v", pr, err)
+					if err := removeEntry(ctx, doer, pr, entry, pull_model.MergeQueueRemovedMergeFailed); err != nil {
+						return err
+					}
+					continue
+				}
+				// the merge group is the new head of the branch, so the merge groups behind it stay valid
+				baseCommitID = entry.GroupCommitID
+				parentBranch, parentCommitID = branch, baseCommitID
+				continue
+			}
+		}
+
+		isHead = false
+		parentBranch, parentCommitID = entry.GroupBranchName(pr.Index), entry.GroupCommitID
+	}
+	return nil
+}
+
+// mergeEntry fast-forwards the base branch to the merge group of the entry and removes the entry from the queue
+func mergeEntry(ctx context.Context, doer *user_model.User, pr *issues_model.PullRequest, entry *pull_model.MergeQueueEntry) error {
+	if err := pull_service.MergeMergeGroup(ctx, pr, doer, entry.GroupCommitID); err != nil {
+		return err
+	}
+	if err := removeEntry(ctx, doer, pr, entry, pull_model.MergeQueueRemovedMerged); err != nil {
+		return err
+	}
+
+	deleteBranchAfterMerge, err := pull_service.ShouldDeleteBranchAfterMerge(ctx, &entry.DeleteBranchAfterMerge, pr.BaseRepo, pr)
+	if err != nil {
+		log.Error("ShouldDeleteBranchAfterMerge: %v", err)
+	} else if deleteBranchAfterMerge {
+		if err = repo_service.DeleteBranchAfterMerge(ctx, doer, pr.ID, nil); err != nil {
+			log.Error("DeleteBranchAfterMerge: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package mergequeue
+
+import (
+	"context"
+	"slices"
+
+	git_model "github.com/kumose/kmup/models/git"
+	issues_model "github.com/kumose/kmup/models/issues"
+	pull_model "github.com/kumose/kmup/models/pull"
+	repo_model "github.com/kumose/kmup/models/repo"
+	user_model "github.com/kumose/kmup/models/user"
+	"github.com/kumose/kmup/modules/log"
+	"github.com/kumose/kmup/modules/repository"
+	notify_service "github.com/kumose/kmup/services/notify"
+)
+
+type mergeQueueNotifier struct {
+	notify_service.NullNotifier
+}
+
+var _ notify_service.Notifier = &mergeQueueNotifier{}
+
+// NewNotifier create a new mergeQueueNotifier notifier
+func NewNotifier() notify_service.Notifier {
+	return &mergeQueueNotifier{}
+}
+
+// startPullMergeQueueProcessing processes the merge queue which contains the pull request, if any.
+// The notifications can be sent while the merge queue is being processed, so they must not touch the queue directly.
+func startPullMergeQueueProcessing(ctx context.Context, pr *issues_model.PullRequest) {
+	entry, exists, err := pull_model.GetMergeQueueEntryByPullID(ctx, pr.ID)
+	if err != nil {
+		log.Error("GetMergeQueueEntryByPullID: %v", err)
+		return
+	}
+	if exists {
+		StartMergeQueueProcessing(entry.RepoID, entry.BaseBranch)
+	}
+}
+
+func (n *mergeQueueNotifier) CreateCommitStatus(ctx context.Context, repo *repo_model.Repository, commit *repository.PushCommit, sender *user_model.User, status *git_model.CommitStatus) {
+	entries, err := pull_model.GetMergeQueueEntriesByGroupCommitID(ctx, repo.ID, commit.Sha1)
+	if err != nil {
+		log.Error("GetMergeQueueEntriesByGroupCommitID[repo_id: %d, sha: %s]: %v", repo.ID, commit.Sha1, err)
+		return
+	}
+	for _, entry := range entries {
+		StartMergeQueueProcessing(entry.RepoID, entry.BaseBranch)
+	}
+}
+
+func (n *mergeQueueNotifier) PushCommits(ctx context.Context, pusher *user_model.User, repo *repo_model.Repository, opts *repository.PushUpdateOptions, commits *repository.PushCommits) {
+	if !opts.RefFullName.IsBranch() {
+		return
+	}
+	// a push to the target branch outdates the merge groups of its queue
+	branches, err := pull_model.GetMergeQueueBranches(ctx, repo.ID)
+	if err != nil {
+		log.Error("GetMergeQueueBranches: %v", err)
+		return
+	}
+	if branch := opts.RefFullName.BranchName(); slices.Contains(branches, branch) {
+		StartMergeQueueProcessing(repo.ID, branch)
+	}
+}
+
+func (n *mergeQueueNotifier) PullRequestSynchronized(ctx context.Context, doer *user_model.User, pr *issues_model.PullRequest) {
+	startPullMergeQueueProcessing(ctx, pr)
+}
+
+func (n *mergeQueueNotifier) PullRequestChangeTargetBranch(ctx context.Context, doer *user_model.User, pr *issues_model.PullRequest, oldBranch string) {
+	startPullMergeQueueProcessing(ctx, pr)
+}
+
+func (n *mergeQueueNotifier) IssueChangeStatus(ctx context.Context, doer *user_model.User, commitID string, issue *issues_model.Issue, actionComment *issues_model.Comment, closeOrReopen bool) {
+	if !issue.IsPull || !closeOrReopen {
+		return
+	}
+	if err := issue.LoadPullRequest(ctx); err != nil {
+		log.Error("LoadPullRequest: %v", err)
+		return
+	}
+	startPullMergeQueueProcessing(ctx, issue.PullRequest)
+}
+
+func (n *mergeQueueNotifier) MergePullRequest(ctx context.Context, doer *user_model.User, pr *issues_model.PullRequest) {
+	startPullMergeQueueProcessing(ctx, pr)
+}
+
+func (n *mergeQueueNotifier) AutoMergePullRequest(ctx context.Context, doer *user_model.User, pr *issues_model.PullRequest) {
+	startPullMergeQueueProcessing(ctx, pr)
+}
@@ -24,7 +24,9 @@ import (
 	git_model "github.com/kumose/kmup/models/git"
 	issues_model "github.com/kumose/kmup/models/issues"
 	packages_model "github.com/kumose/kmup/models/packages"
+	pull_model "github.com/kumose/kmup/models/pull"
 	repo_model "github.com/kumose/kmup/models/repo"
+	secretscan_model "github.com/kumose/kmup/models/secretscan"
 	user_model "github.com/kumose/kmup/models/user"
 	"github.com/kumose/kmup/modules/git"
 	"github.com/kumose/kmup/modules/repository"
@@ -65,6 +67,8 @@ type Notifier interface {
 	PullRequestChangeTargetBranch(ctx context.Context, doer *user_model.User, pr *issues_model.PullRequest, oldBranch string)
 	PullRequestPushCommits(ctx context.Context, doer *user_model.User, pr *issues_model.PullRequest, comment *issues_model.Comment)
 	PullReviewDismiss(ctx context.Context, doer *user_model.User, review *issues_model.Review, comment *issues_model.Comment)
+	MergeGroupChecksRequested(ctx context.Context, doer *user_model.User, pr *issues_model.PullRequest, entry *pull_model.MergeQueueEntry)
+	MergeGroupDestroyed(ctx context.Context, doer *user_model.User, pr *issues_model.PullRequest, entry *pull_model.MergeQueueEntry, reason string)
 
 	CreateIssueComment(ctx context.Context, doer *user_model.User, repo *repo_model.Repository,
 		issue *issues_model.Issue, comment *issues_model.Comment, mentions []*user_model.User)
@@ -88,11 +92,15 @@ type Notifier interface {
 
 	PackageCreate(ctx context.Context, doer *user_model.User, pd *packages_model.PackageDescriptor)
 	PackageDelete(ctx context.Context, doer *user_model.User, pd *packages_model.PackageDescriptor)
+	PackageVulnerable(ctx context.Context, pd *packages_model.PackageDescriptor, vulnerabilities []*packages_model.PackageVulnerability)
+	PackageCleanupRun(ctx context.Context, owner *user_model.User, run *packages_model.PackageCleanupRun)
 
 	ChangeDefaultBranch(ctx context.Context, repo *repo_model.Repository)
 
 	CreateCommitStatus(ctx context.Context, repo *repo_model.Repository, commit *repository.PushCommit, sender *user_model.User, status *git_model.CommitStatus)
 
+	SecretScanningAlert(ctx context.Context, doer *user_model.User, repo *repo_model.Repository, alert *secretscan_model.Alert, action string)
+
 	WorkflowRunStatusUpdate(ctx context.Context, repo *repo_model.Repository, sender *user_model.User, run *actions_model.ActionRun)
 
 	WorkflowJobStatusUpdate(ctx context.Context, repo *repo_model.Repository, sender *user_model.User, job *actions_model.ActionRunJob, task *actions_model.ActionTask)
@@ -24,7 +24,9 @@ import (
 	git_model "github.com/kumose/kmup/models/git"
 	issues_model "github.com/kumose/kmup/models/issues"
 	packages_model "github.com/kumose/kmup/models/packages"
+	pull_model "github.com/kumose/kmup/models/pull"
 	repo_model "github.com/kumose/kmup/models/repo"
+	secretscan_model "github.com/kumose/kmup/models/secretscan"
 	user_model "github.com/kumose/kmup/models/user"
 	"github.com/kumose/kmup/modules/git"
 	"github.com/kumose/kmup/modules/log"
@@ -183,6 +185,20 @@ func PullReviewDismiss(ctx context.Context, doer *user_model.User, review *issue
 	}
 }
 
+// MergeGroupChecksRequested notifies when a merge group was built and its status checks should run
+func MergeGroupChecksRequested(ctx context.Context, doer *user_model.User, pr *issues_model.PullRequest, entry *pull_model.MergeQueueEntry) {
+	for _, notifier := range notifiers {
+		notifier.MergeGroupChecksRequested(ctx, doer, pr, entry)
+	}
+}
+
+// MergeGroupDestroyed notifies when a merge group was merged or removed from its merge queue
+func MergeGroupDestroyed(ctx context.Context, doer *user_model.User, pr *issues_model.PullRequest, entry *pull_model.MergeQueueEntry, reason string) {
+	for _, notifier := range notifiers {
+		notifier.MergeGroupDestroyed(ctx, doer, pr, entry, reason)
+	}
+}
+
 // UpdateComment notifies update comment to notifiers
 func UpdateComment(ctx context.Context, doer *user_model.User, c *issues_model.Comment, oldContent string) {
 	if !shouldSendCommentChangeNotification(ctx, c) {
@@ -400,6 +416,20 @@ func PackageDelete(ctx context.Context, doer *user_model.User, pd *packages_mode
 	}
 }
 
+// PackageVulnerable notifies advisories which newly match a package to notifiers
+func PackageVulnerable(ctx context.Context, pd *packages_model.PackageDescriptor, vulnerabilities []*packages_model.PackageVulnerability) {
+	for _, notifier := range notifiers {
+		notifier.PackageVulnerable(ctx, pd, vulnerabilities)
+	}
+}
+
+// PackageCleanupRun notifies the execution of a package cleanup rule to notifiers
+func PackageCleanupRun(ctx context.Context, owner *user_model.User, run *packages_model.PackageCleanupRun) {
+	for _, notifier := range notifiers {
+		notifier.PackageCleanupRun(ctx, owner, run)
+	}
+}
+
 // ChangeDefaultBranch notifies change default branch to notifiers
 func ChangeDefaultBranch(ctx context.Context, repo *repo_model.Repository) {
 	for _, notifier := range notifiers {
@@ -413,6 +443,13 @@ func CreateCommitStatus(ctx context.Context, repo *repo_model.Repository, commit
 	}
 }
 
+// SecretScanningAlert notifies when a secret scanning alert was created, resolved or reopened
+func SecretScanningAlert(ctx context.Context, doer *user_model.User, repo *repo_model.Repository, alert *secretscan_model.Alert, action string) {
+	for _, notifier := range notifiers {
+		notifier.SecretScanningAlert(ctx, doer, repo, alert, action)
+	}
+}
+
 func WorkflowRunStatusUpdate(ctx context.Context, repo *repo_model.Repository, sender *user_model.User, run *actions_model.ActionRun) {
 	for _, notifier := range notifiers {
 		notifier.WorkflowRunStatusUpdate(ctx, repo, sender, run)
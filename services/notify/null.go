@@ -24,7 +24,9 @@ import (
 	git_model "github.com/kumose/kmup/models/git"
 	issues_model "github.com/kumose/kmup/models/issues"
 	packages_model "github.com/kumose/kmup/models/packages"
+	pull_model "github.com/kumose/kmup/models/pull"
 	repo_model "github.com/kumose/kmup/models/repo"
+	secretscan_model "github.com/kumose/kmup/models/secretscan"
 	user_model "github.com/kumose/kmup/models/user"
 	"github.com/kumose/kmup/modules/git"
 	"github.com/kumose/kmup/modules/repository"
@@ -92,6 +94,14 @@ func (*NullNotifier) PullRequestPushCommits(ctx context.Context, doer *user_mode
 func (*NullNotifier) PullReviewDismiss(ctx context.Context, doer *user_model.User, review *issues_model.Review, comment *issues_model.Comment) {
 }
 
+// MergeGroupChecksRequested places a place holder function
+func (*NullNotifier) MergeGroupChecksRequested(ctx context.Context, doer *user_model.User, pr *issues_model.PullRequest, entry *pull_model.MergeQueueEntry) {
+}
+
+// MergeGroupDestroyed places a place holder function
+func (*NullNotifier) MergeGroupDestroyed(ctx context.Context, doer *user_model.User, pr *issues_model.PullRequest, entry *pull_model.MergeQueueEntry, reason string) {
+}
+
 // UpdateComment places a place holder function
 func (*NullNotifier) UpdateComment(ctx context.Context, doer *user_model.User, c *issues_model.Comment, oldContent string) {
 }
@@ -221,6 +231,14 @@ func (*NullNotifier) PackageCreate(ctx context.Context, doer *user_model.User, p
 func (*NullNotifier) PackageDelete(ctx context.Context, doer *user_model.User, pd *packages_model.PackageDescriptor) {
 }
 
+// PackageVulnerable places a place holder function
+func (*NullNotifier) PackageVulnerable(ctx context.Context, pd *packages_model.PackageDescriptor, vulnerabilities []*packages_model.PackageVulnerability) {
+}
+
+// PackageCleanupRun places a place holder function
+func (*NullNotifier) PackageCleanupRun(ctx context.Context, owner *user_model.User, run *packages_model.PackageCleanupRun) {
+}
+
 // ChangeDefaultBranch places a place holder function
 func (*NullNotifier) ChangeDefaultBranch(ctx context.Context, repo *repo_model.Repository) {
 }
@@ -228,6 +246,10 @@ func (*NullNotifier) ChangeDefaultBranch(ctx context.Context, repo *repo_model.R
 func (*NullNotifier) CreateCommitStatus(ctx context.Context, repo *repo_model.Repository, commit *repository.PushCommit, sender *user_model.User, status *git_model.CommitStatus) {
 }
 
+// SecretScanningAlert places a place holder function
+func (*NullNotifier) SecretScanningAlert(ctx context.Context, doer *user_model.User, repo *repo_model.Repository, alert *secretscan_model.Alert, action string) {
+}
+
 func (*NullNotifier) WorkflowRunStatusUpdate(ctx context.Context, repo *repo_model.Repository, sender *user_model.User, run *actions_model.ActionRun) {
 }
 
@@ -28,6 +28,7 @@ import (
 	"github.com/kumose/kmup/modules/log"
 	"github.com/kumose/kmup/modules/optional"
 	packages_module "github.com/kumose/kmup/modules/packages"
+	notify_service "github.com/kumose/kmup/services/notify"
 	packages_service "github.com/kumose/kmup/services/packages"
 	alpine_service "github.com/kumose/kmup/services/packages/alpine"
 	arch_service "github.com/kumose/kmup/services/packages/arch"
@@ -43,18 +44,24 @@ func CleanupTask(ctx context.Context, olderThan time.Duration) error {
 		return err
 	}
 
+	if err := ExecuteRemoteCleanup(ctx); err != nil {
+		return err
+	}
+
 	return CleanupExpiredData(ctx, olderThan)
 }
 
-func executeCleanupOneRulePackage(ctx context.Context, pcr *packages_model.PackageCleanupRule, p *packages_model.Package) (versionDeleted bool, err error) {
+// selectVersionsToRemove returns the versions of the package which the rule removes
+func selectVersionsToRemove(ctx context.Context, pcr *packages_model.PackageCleanupRule, p *packages_model.Package) ([]*packages_model.PackageVersion, error) {
 	olderThan := time.Now().AddDate(0, 0, -pcr.RemoveDays)
 	pvs, _, err := packages_model.SearchVersions(ctx, &packages_model.PackageSearchOptions{
 		PackageID:  p.ID,
 		IsInternal: optional.Some(false),
+		IsRemote:   optional.Some(false), // cached versions are cleaned up by the settings of the remote
 		Sort:       packages_model.SortCreatedDesc,
 	})
 	if err != nil {
-		return false, fmt.Errorf("CleanupRule [%d]: SearchVersions failed: %w", pcr.ID, err)
+		return nil, fmt.Errorf("CleanupRule [%d]: SearchVersions failed: %w", pcr.ID, err)
 	}
 	if pcr.KeepCount > 0 {
 		if pcr.KeepCount < len(pvs) {
@@ -63,10 +70,11 @@ func executeCleanupOneRulePackage(ctx context.Context, pcr *packages_model.Packa
 			pvs = nil
 		}
 	}
+	toRemove := make([]*packages_model.PackageVersion, 0, len(pvs))
 	for _, pv := range pvs {
 		if pcr.Type == packages_model.TypeContainer {
 			if skip, err := container_service.ShouldBeSkipped(ctx, pcr, p, pv); err != nil {
-				return false, fmt.Errorf("CleanupRule [%d]: container.ShouldBeSkipped failed: %w", pcr.ID, err)
+				return nil, fmt.Errorf("CleanupRule [%d]: container.ShouldBeSkipped failed: %w", pcr.ID, err)
 			} else if skip {
 				log.Debug("Rule[%d]: keep '%s/%s' (container)", pcr.ID, p.Name, pv.Version)
 				continue
@@ -88,12 +96,61 @@ func executeCleanupOneRulePackage(ctx context.Context, pcr *packages_model.Packa
 			log.Debug("Rule[%d]: keep '%s/%s' (remove pattern)", pcr.ID, p.Name, pv.Version)
 			continue
 		}
+		toRemove = append(toRemove, pv)
+	}
+	return toRemove, nil
+}
+
+// PreviewCleanupRule returns the package versions the rule would remove if it was executed now
+func PreviewCleanupRule(ctx context.Context, pcr *packages_model.PackageCleanupRule) ([]*packages_model.PackageDescriptor, error) {
+	if err := pcr.CompiledPattern(); err != nil {
+		return nil, fmt.Errorf("CleanupRule [%d]: CompilePattern failed: %w", pcr.ID, err)
+	}
+
+	packages, err := packages_model.GetPackagesByType(ctx, pcr.OwnerID, pcr.Type)
+	if err != nil {
+		return nil, fmt.Errorf("CleanupRule [%d]: GetPackagesByType failed: %w", pcr.ID, err)
+	}
+
+	pds := make([]*packages_model.PackageDescriptor, 0, 10)
+	for _, p := range packages {
+		pvs, err := selectVersionsToRemove(ctx, pcr, p)
+		if err != nil {
+			return nil, err
+		}
+		for _, pv := range pvs {
+			pd, err := packages_model.GetPackageDescriptor(ctx, pv)
+			if err != nil {
+				return nil, fmt.Errorf("CleanupRule [%d]: GetPackageDescriptor failed: %w", pcr.ID, err)
+			}
+			pds = append(pds, pd)
+		}
+	}
+	return pds, nil
+}
+
+func executeCleanupOneRulePackage(ctx context.Context, pcr *packages_model.PackageCleanupRule, p *packages_model.Package, run *packages_model.PackageCleanupRun) (versionDeleted bool, err error) {
+	pvs, err := selectVersionsToRemove(ctx, pcr, p)
+	if err != nil {
+		return false, err
+	}
+	for _, pv := range pvs {
+		pd, err := packages_model.GetPackageDescriptor(ctx, pv)
+		if err != nil {
+			return false, fmt.Errorf("CleanupRule [%d]: GetPackageDescriptor failed: %w", pcr.ID, err)
+		}
+
 		log.Debug("Rule[%d]: remove '%s/%s'", pcr.ID, p.Name, pv.Version)
 		if err := packages_service.DeletePackageVersionAndReferences(ctx, pv); err != nil {
 			log.Error("CleanupRule [%d]: DeletePackageVersionAndReferences failed: %v", pcr.ID, err)
+			run.FailedCount++
 			continue
 		}
 		versionDeleted = true
+
+		run.VersionCount++
+		run.Size += pd.CalculateBlobSize()
+		run.Versions = append(run.Versions, p.Name+"@"+pv.Version)
 	}
 	return versionDeleted, nil
 }
@@ -103,29 +160,52 @@ func executeCleanupOneRule(ctx context.Context, pcr *packages_model.PackageClean
 		return fmt.Errorf("CleanupRule [%d]: CompilePattern failed: %w", pcr.ID, err)
 	}
 
+	owner, err := user_model.GetUserByID(ctx, pcr.OwnerID)
+	if err != nil {
+		return fmt.Errorf("GetUserByID failed: %w", err)
+	}
+
 	packages, err := packages_model.GetPackagesByType(ctx, pcr.OwnerID, pcr.Type)
 	if err != nil {
 		return fmt.Errorf("CleanupRule [%d]: GetPackagesByType failed: %w", pcr.ID, err)
 	}
 
+	run := &packages_model.PackageCleanupRun{
+		RuleID:   pcr.ID,
+		OwnerID:  pcr.OwnerID,
+		Type:     pcr.Type,
+		Versions: make([]string, 0, 10),
+	}
+	defer func() {
+		if err := packages_model.InsertCleanupRun(ctx, run); err != nil {
+			log.Error("CleanupRule [%d]: InsertCleanupRun failed: %v", pcr.ID, err)
+			return
+		}
+		if run.VersionCount > 0 || run.FailedCount > 0 {
+			notify_service.PackageCleanupRun(ctx, owner, run)
+		}
+	}()
+
 	anyVersionDeleted := false
 	for _, p := range packages {
+		packageRun := &packages_model.PackageCleanupRun{}
 		versionDeleted := false
 		err = db.WithTx(ctx, func(ctx context.Context) (err error) {
-			versionDeleted, err = executeCleanupOneRulePackage(ctx, pcr, p)
+			versionDeleted, err = executeCleanupOneRulePackage(ctx, pcr, p, packageRun)
 			return err
 		})
 		if err != nil {
 			log.Error("CleanupRule [%d]: executeCleanupOneRulePackage(%d) failed: %v", pcr.ID, p.ID, err)
 			continue
 		}
+		run.VersionCount += packageRun.VersionCount
+		run.FailedCount += packageRun.FailedCount
+		run.Size += packageRun.Size
+		run.Versions = append(run.Versions, packageRun.Versions...)
+
 		anyVersionDeleted = anyVersionDeleted || versionDeleted
 		if versionDeleted {
 			if pcr.Type == packages_model.TypeCargo {
-				owner, err := user_model.GetUserByID(ctx, pcr.OwnerID)
-				if err != nil {
-					return fmt.Errorf("GetUserByID failed: %w", err)
-				}
 				if err := cargo_service.UpdatePackageIndexIfExists(ctx, owner, owner, p.ID); err != nil {
 					return fmt.Errorf("CleanupRule [%d]: cargo.UpdatePackageIndexIfExists failed: %w", pcr.ID, err)
 				}
@@ -178,6 +258,66 @@ func ExecuteCleanupRules(ctx context.Context) error {
 	})
 }
 
+// ExecuteRemoteCleanup removes versions cached from upstream registries according to the settings of their remote
+func ExecuteRemoteCleanup(ctx context.Context) error {
+	return packages_model.IterateRemotesWithCleanup(ctx, func(ctx context.Context, pr *packages_model.PackageRemote) error {
+		select {
+		case <-ctx.Done():
+			return db.ErrCancelledf("While processing package remotes")
+		default:
+		}
+
+		if err := executeRemoteCleanup(ctx, pr); err != nil {
+			log.Error("PackageRemote [%d]: executeRemoteCleanup failed: %v", pr.ID, err)
+		}
+		return nil
+	})
+}
+
+func executeRemoteCleanup(ctx context.Context, pr *packages_model.PackageRemote) error {
+	packages, err := packages_model.GetPackagesByType(ctx, pr.OwnerID, pr.Type)
+	if err != nil {
+		return fmt.Errorf("GetPackagesByType failed: %w", err)
+	}
+
+	olderThan := time.Now().AddDate(0, 0, -pr.RemoveDays)
+
+	for _, p := range packages {
+		err := db.WithTx(ctx, func(ctx context.Context) error {
+			pvs, _, err := packages_model.SearchVersions(ctx, &packages_model.PackageSearchOptions{
+				PackageID:  p.ID,
+				IsInternal: optional.Some(false),
+				IsRemote:   optional.Some(true),
+				Sort:       packages_model.SortCreatedDesc,
+			})
+			if err != nil {
+				return fmt.Errorf("SearchVersions failed: %w", err)
+			}
+			if pr.KeepCount > 0 {
+				if pr.KeepCount < len(pvs) {
+					pvs = pvs[pr.KeepCount:]
+				} else {
+					pvs = nil
+				}
+			}
+			for _, pv := range pvs {
+				if pv.CreatedUnix.AsLocalTime().After(olderThan) {
+					continue
+				}
+				log.Debug("PackageRemote[%d]: remove cached '%s/%s'", pr.ID, p.Name, pv.Version)
+				if err := packages_service.DeletePackageVersionAndReferences(ctx, pv); err != nil {
+					return fmt.Errorf("DeletePackageVersionAndReferences failed: %w", err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			log.Error("PackageRemote [%d]: cleanup of package %d failed: %v", pr.ID, p.ID, err)
+		}
+	}
+	return nil
+}
+
 func CleanupExpiredData(ctx context.Context, olderThan time.Duration) error {
 	pbs := make([]*packages_model.PackageBlob, 0, 100)
 	if err := db.WithTx(ctx, func(ctx context.Context) error {
@@ -35,7 +35,11 @@ func Cleanup(ctx context.Context, olderThan time.Duration) error {
 	if err := cleanupExpiredBlobUploads(ctx, olderThan); err != nil {
 		return err
 	}
-	return cleanupExpiredUploadedBlobs(ctx, olderThan)
+	if err := cleanupExpiredUploadedBlobs(ctx, olderThan); err != nil {
+		return err
+	}
+	// referrers (signatures, SBOMs, attestations) are useless without their subject
+	return RemoveOrphanedReferrers(ctx, nil, 0)
 }
 
 // cleanupExpiredBlobUploads removes expired blob uploads
@@ -118,5 +122,6 @@ func ShouldBeSkipped(ctx context.Context, pcr *packages_model.PackageCleanupRule
 		}
 	}
 
-	return false, nil
+	// Keep referrers as long as their subject exists, they get removed together with it
+	return isReferrerOfExistingSubject(ctx, p, pv)
 }
@@ -74,5 +74,19 @@ func ParseManifestMetadata(ctx context.Context, rd io.Reader, ownerID int64, ima
 	}
 	defer configReader.Close()
 	metadata, err := container_module.ParseImageConfig(manifest.Config.MediaType, configReader)
-	return &manifest, configDescriptor, metadata, err
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if manifest.ArtifactType != "" && metadata.Type != container_module.TypeHelm {
+		metadata.Type = container_module.TypeArtifact
+		metadata.Platform = ""
+	}
+	if manifest.Subject != nil {
+		metadata.Subject = manifest.Subject.Digest.String()
+		metadata.ArtifactType = container_module.ArtifactTypeOf(manifest.ArtifactType, manifest.Config.MediaType)
+	} else {
+		metadata.ArtifactType = manifest.ArtifactType
+	}
+	metadata.Annotations = manifest.Annotations
+	return &manifest, configDescriptor, metadata, nil
 }
@@ -0,0 +1,200 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package container
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	packages_model "github.com/kumose/kmup/models/packages"
+	container_model "github.com/kumose/kmup/models/packages/container"
+	user_model "github.com/kumose/kmup/models/user"
+	"github.com/kumose/kmup/modules/container"
+	"github.com/kumose/kmup/modules/json"
+	"github.com/kumose/kmup/modules/optional"
+	container_module "github.com/kumose/kmup/modules/packages/container"
+	packages_service "github.com/kumose/kmup/services/packages"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	oci "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// GetReferrers returns the descriptors of all manifests of the package which refer to the subject.
+// The entries of an image index pushed with the referrers tag schema are included too.
+// If artifactType is not empty, only referrers with this artifact type are returned.
+func GetReferrers(ctx context.Context, p *packages_model.Package, subject digest.Digest, artifactType string) ([]oci.Descriptor, error) {
+	pvs, _, err := packages_model.SearchVersions(ctx, &packages_model.PackageSearchOptions{
+		PackageID:  p.ID,
+		IsInternal: optional.Some(false),
+		Properties: map[string]string{
+			container_module.PropertyManifestSubject: subject.String(),
+		},
+		Sort: packages_model.SortCreatedAsc,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	descriptors := make([]oci.Descriptor, 0, len(pvs))
+	seen := make(container.Set[digest.Digest])
+
+	for _, pv := range pvs {
+		pfd, err := container_model.GetContainerBlob(ctx, &container_model.BlobSearchOptions{
+			OwnerID:    p.OwnerID,
+			Image:      p.LowerName,
+			Tag:        pv.LowerVersion,
+			IsManifest: true,
+			OnlyLead:   true,
+		})
+		if err != nil {
+			if errors.Is(err, container_model.ErrContainerBlobNotExist) {
+				continue
+			}
+			return nil, err
+		}
+
+		var metadata container_module.Metadata
+		if err := json.Unmarshal([]byte(pv.MetadataJSON), &metadata); err != nil {
+			return nil, err
+		}
+
+		d := digest.Digest(pfd.Properties.GetByName(container_module.PropertyDigest))
+		if !seen.Add(d) {
+			continue
+		}
+		descriptors = append(descriptors, oci.Descriptor{
+			MediaType:    pfd.Properties.GetByName(container_module.PropertyMediaType),
+			ArtifactType: metadata.ArtifactType,
+			Digest:       d,
+			Size:         pfd.Blob.Size,
+			Annotations:  metadata.Annotations,
+		})
+	}
+
+	fallback, err := getReferrersTagIndex(ctx, p, subject)
+	if err != nil {
+		return nil, err
+	}
+	if fallback != nil {
+		for _, desc := range fallback.Manifests {
+			if seen.Add(desc.Digest) {
+				descriptors = append(descriptors, desc)
+			}
+		}
+	}
+
+	if artifactType != "" {
+		descriptors = slices.DeleteFunc(descriptors, func(desc oci.Descriptor) bool {
+			return desc.ArtifactType != artifactType
+		})
+	}
+
+	return descriptors, nil
+}
+
+// getReferrersTagIndex returns the image index pushed by clients which use the referrers tag schema instead of the referrers API
+func getReferrersTagIndex(ctx context.Context, p *packages_model.Package, subject digest.Digest) (*oci.Index, error) {
+	pfd, err := container_model.GetContainerBlob(ctx, &container_model.BlobSearchOptions{
+		OwnerID:    p.OwnerID,
+		Image:      p.LowerName,
+		Tag:        container_module.ReferrersTag(subject),
+		IsManifest: true,
+		OnlyLead:   true,
+	})
+	if err != nil {
+		if errors.Is(err, container_model.ErrContainerBlobNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if !container_module.IsMediaTypeImageIndex(pfd.Properties.GetByName(container_module.PropertyMediaType)) {
+		return nil, nil
+	}
+
+	r, err := packages_service.OpenBlobStream(pfd.Blob)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var index oci.Index
+	if err := json.NewDecoder(r).Decode(&index); err != nil {
+		return nil, err
+	}
+	return &index, nil
+}
+
+// NewReferrersIndex creates the image index returned by the referrers API
+func NewReferrersIndex(descriptors []oci.Descriptor) *oci.Index {
+	return &oci.Index{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: oci.MediaTypeImageIndex,
+		Manifests: descriptors,
+	}
+}
+
+// RemoveOrphanedReferrers removes all manifests of the package whose subject manifest does not exist anymore.
+// Referrers can refer to other referrers (e.g. a signature of an attestation), so it repeats until nothing is left.
+// If packageID is 0, all container packages are processed.
+func RemoveOrphanedReferrers(ctx context.Context, doer *user_model.User, packageID int64) error {
+	for {
+		pvs, err := container_model.FindOrphanedReferrers(ctx, packageID)
+		if err != nil {
+			return err
+		}
+		if len(pvs) == 0 {
+			return nil
+		}
+
+		for _, pv := range pvs {
+			if doer != nil {
+				err = packages_service.RemovePackageVersion(ctx, doer, pv)
+			} else {
+				err = packages_service.DeletePackageVersionAndReferences(ctx, pv)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// isReferrerOfExistingSubject checks if the version refers to a manifest which still exists
+func isReferrerOfExistingSubject(ctx context.Context, p *packages_model.Package, pv *packages_model.PackageVersion) (bool, error) {
+	pps, err := packages_model.GetPropertiesByName(ctx, packages_model.PropertyTypeVersion, pv.ID, container_module.PropertyManifestSubject)
+	if err != nil || len(pps) == 0 {
+		return false, err
+	}
+
+	_, err = container_model.GetContainerBlob(ctx, &container_model.BlobSearchOptions{
+		OwnerID:    p.OwnerID,
+		Image:      p.LowerName,
+		Digest:     pps[0].Value,
+		IsManifest: true,
+	})
+	if err != nil {
+		if errors.Is(err, container_model.ErrContainerBlobNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
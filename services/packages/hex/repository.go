@@ -0,0 +1,222 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package hex
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+
+	packages_model "github.com/kumose/kmup/models/packages"
+	user_model "github.com/kumose/kmup/models/user"
+	hex_module "github.com/kumose/kmup/modules/packages/hex"
+	"github.com/kumose/kmup/modules/util"
+
+	"github.com/hashicorp/go-version"
+)
+
+// GetOrCreateKeyPair gets or creates the RSA keys used to sign registry resources
+func GetOrCreateKeyPair(ctx context.Context, ownerID int64) (string, string, error) {
+	priv, err := user_model.GetSetting(ctx, ownerID, hex_module.SettingKeyPrivate)
+	if err != nil && !errors.Is(err, util.ErrNotExist) {
+		return "", "", err
+	}
+
+	pub, err := user_model.GetSetting(ctx, ownerID, hex_module.SettingKeyPublic)
+	if err != nil && !errors.Is(err, util.ErrNotExist) {
+		return "", "", err
+	}
+
+	if priv == "" || pub == "" {
+		priv, pub, err = util.GenerateKeyPair(4096)
+		if err != nil {
+			return "", "", err
+		}
+
+		if err := user_model.SetUserSetting(ctx, ownerID, hex_module.SettingKeyPrivate, priv); err != nil {
+			return "", "", err
+		}
+
+		if err := user_model.SetUserSetting(ctx, ownerID, hex_module.SettingKeyPublic, pub); err != nil {
+			return "", "", err
+		}
+	}
+
+	return priv, pub, nil
+}
+
+// RepositoryName returns the name of the Hex repository of the owner.
+// Clients verify that signed resources belong to the repository they were added as.
+func RepositoryName(owner *user_model.User) string {
+	return owner.Name
+}
+
+// BuildNames builds the signed /names resource
+func BuildNames(ctx context.Context, owner *user_model.User) ([]byte, error) {
+	versions, err := getVersionsByPackage(ctx, owner.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*hex_module.NamesEntry, 0, len(versions))
+	for name, pvs := range versions {
+		e := &hex_module.NamesEntry{Name: name}
+		for _, pv := range pvs {
+			if t := pv.CreatedUnix.AsTime(); t.After(e.UpdatedAt) {
+				e.UpdatedAt = t
+			}
+		}
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+
+	return sign(ctx, owner.ID, hex_module.EncodeNames(RepositoryName(owner), entries))
+}
+
+// BuildVersions builds the signed /versions resource
+func BuildVersions(ctx context.Context, owner *user_model.User) ([]byte, error) {
+	versions, err := getVersionsByPackage(ctx, owner.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*hex_module.VersionsEntry, 0, len(versions))
+	for name, pvs := range versions {
+		sortVersions(pvs)
+
+		e := &hex_module.VersionsEntry{Name: name, Versions: make([]string, 0, len(pvs))}
+		for _, pv := range pvs {
+			e.Versions = append(e.Versions, pv.Version)
+		}
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+
+	return sign(ctx, owner.ID, hex_module.EncodeVersions(RepositoryName(owner), entries))
+}
+
+// BuildPackage builds the signed /packages/<name> resource
+func BuildPackage(ctx context.Context, owner *user_model.User, name string) ([]byte, error) {
+	pvs, err := packages_model.GetVersionsByPackageName(ctx, owner.ID, packages_model.TypeHex, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(pvs) == 0 {
+		return nil, packages_model.ErrPackageNotExist
+	}
+
+	pds, err := packages_model.GetPackageDescriptors(ctx, pvs)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(pds, func(i, j int) bool {
+		return pds[i].SemVer.LessThan(pds[j].SemVer)
+	})
+
+	repository := RepositoryName(owner)
+
+	releases := make([]*hex_module.Release, 0, len(pds))
+	for _, pd := range pds {
+		metadata := pd.Metadata.(*hex_module.Metadata)
+
+		innerChecksum, err := hex.DecodeString(metadata.InnerChecksum)
+		if err != nil {
+			return nil, err
+		}
+
+		release := &hex_module.Release{
+			Version:       pd.Version.Version,
+			InnerChecksum: innerChecksum,
+		}
+		if len(pd.Files) > 0 {
+			if release.OuterChecksum, err = hex.DecodeString(pd.Files[0].Blob.HashSHA256); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, req := range metadata.Requirements {
+			dep := &hex_module.Dependency{
+				Package:     req.Name,
+				Requirement: req.Requirement,
+				Optional:    req.Optional,
+				App:         req.App,
+			}
+			// dependencies without repository are located in the same repository
+			if req.Repository != "" && !strings.EqualFold(req.Repository, repository) {
+				dep.Repository = req.Repository
+			}
+			release.Dependencies = append(release.Dependencies, dep)
+		}
+
+		releases = append(releases, release)
+	}
+
+	return sign(ctx, owner.ID, hex_module.EncodePackage(repository, pds[0].Package.Name, releases))
+}
+
+func sign(ctx context.Context, ownerID int64, payload []byte) ([]byte, error) {
+	priv, _, err := GetOrCreateKeyPair(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	return hex_module.SignResource(payload, priv)
+}
+
+// getVersionsByPackage gets all versions of the owner grouped by package name
+func getVersionsByPackage(ctx context.Context, ownerID int64) (map[string][]*packages_model.PackageVersion, error) {
+	ps, err := packages_model.GetPackagesByType(ctx, ownerID, packages_model.TypeHex)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[int64]string, len(ps))
+	for _, p := range ps {
+		names[p.ID] = p.Name
+	}
+
+	pvs, err := packages_model.GetVersionsByPackageType(ctx, ownerID, packages_model.TypeHex)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make(map[string][]*packages_model.PackageVersion, len(ps))
+	for _, pv := range pvs {
+		if name, ok := names[pv.PackageID]; ok {
+			versions[name] = append(versions[name], pv)
+		}
+	}
+	return versions, nil
+}
+
+func sortVersions(pvs []*packages_model.PackageVersion) {
+	sort.Slice(pvs, func(i, j int) bool {
+		vi, erri := version.NewSemver(pvs[i].Version)
+		vj, errj := version.NewSemver(pvs[j].Version)
+		if erri != nil || errj != nil {
+			return pvs[i].Version < pvs[j].Version
+		}
+		return vi.LessThan(vj)
+	})
+}
@@ -58,6 +58,7 @@ type PackageInfo struct {
 type PackageCreationInfo struct {
 	PackageInfo
 	SemverCompatible  bool
+	IsRemote          bool // the version is cached from the upstream registry of a package remote
 	Creator           *user_model.User
 	Metadata          any
 	PackageProperties map[string]string
@@ -174,6 +175,7 @@ func createPackageAndVersion(ctx context.Context, pvci *PackageCreationInfo, all
 		CreatorID:    pvci.Creator.ID,
 		Version:      pvci.Version,
 		LowerVersion: strings.ToLower(pvci.Version),
+		IsRemote:     pvci.IsRemote,
 		MetadataJSON: string(metadataJSON),
 	}
 	if pv, err = packages_model.GetOrInsertVersion(ctx, pv); err != nil {
@@ -392,6 +394,8 @@ func CheckSizeQuotaExceeded(ctx context.Context, doer, owner *user_model.User, p
 		typeSpecificSize = setting.Packages.LimitSizeGo
 	case packages_model.TypeHelm:
 		typeSpecificSize = setting.Packages.LimitSizeHelm
+	case packages_model.TypeHex:
+		typeSpecificSize = setting.Packages.LimitSizeHex
 	case packages_model.TypeMaven:
 		typeSpecificSize = setting.Packages.LimitSizeMaven
 	case packages_model.TypeNpm:
@@ -408,6 +412,12 @@ func CheckSizeQuotaExceeded(ctx context.Context, doer, owner *user_model.User, p
 		typeSpecificSize = setting.Packages.LimitSizeRubyGems
 	case packages_model.TypeSwift:
 		typeSpecificSize = setting.Packages.LimitSizeSwift
+	case packages_model.TypeTerraformModule:
+		typeSpecificSize = setting.Packages.LimitSizeTerraformModule
+	case packages_model.TypeTerraformProvider:
+		typeSpecificSize = setting.Packages.LimitSizeTerraformProvider
+	case packages_model.TypeTerraformState:
+		typeSpecificSize = setting.Packages.LimitSizeTerraformState
 	case packages_model.TypeVagrant:
 		typeSpecificSize = setting.Packages.LimitSizeVagrant
 	}
@@ -547,6 +557,10 @@ func DeletePackageVersionAndReferences(ctx context.Context, pv *packages_model.P
 		return err
 	}
 
+	if err := packages_model.DeleteVulnerabilitiesByVersionID(ctx, pv.ID); err != nil {
+		return err
+	}
+
 	pfs, err := packages_model.GetFilesByVersionID(ctx, pv.ID)
 	if err != nil {
 		return err
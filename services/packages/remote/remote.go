@@ -0,0 +1,341 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	packages_model "github.com/kumose/kmup/models/packages"
+	user_model "github.com/kumose/kmup/models/user"
+	"github.com/kumose/kmup/modules/cache"
+	"github.com/kumose/kmup/modules/hostmatcher"
+	"github.com/kumose/kmup/modules/json"
+	"github.com/kumose/kmup/modules/optional"
+	packages_module "github.com/kumose/kmup/modules/packages"
+	"github.com/kumose/kmup/modules/proxy"
+	"github.com/kumose/kmup/modules/setting"
+	"github.com/kumose/kmup/modules/util"
+)
+
+// maxDocumentSize is the maximum size of an upstream metadata document
+const maxDocumentSize = 64 * 1024 * 1024
+
+// ErrUpstreamNotFound indicates that the upstream registry does not know the requested file
+var ErrUpstreamNotFound = util.NewNotExistErrorf("file does not exist in the upstream registry")
+
+var httpClient = sync.OnceValue(func() *http.Client {
+	allowedHostListValue := setting.Packages.RemoteAllowedHostList
+	if allowedHostListValue == "" {
+		allowedHostListValue = hostmatcher.MatchBuiltinExternal
+	}
+	allowedHostMatcher := hostmatcher.ParseHostMatchList("packages.REMOTE_ALLOWED_HOST_LIST", allowedHostListValue)
+
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 proxy.Proxy(),
+			DialContext:           hostmatcher.NewDialContext("package remote", allowedHostMatcher, nil, setting.Proxy.ProxyURLFixed),
+			ResponseHeaderTimeout: time.Minute,
+		},
+	}
+})
+
+// GetRemoteForPackage returns the enabled remote of the owner if missing files of the package should be fetched from upstream.
+// Packages which have locally published versions under any of the names are never proxied, so an upstream package can't shadow a local one.
+// nil is returned if there is no remote to use.
+func GetRemoteForPackage(ctx context.Context, ownerID int64, packageType packages_model.Type, names ...string) (*packages_model.PackageRemote, error) {
+	pr, err := packages_model.GetEnabledRemoteByOwnerAndType(ctx, ownerID, packageType)
+	if err != nil {
+		if errors.Is(err, packages_model.ErrPackageRemoteNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	for _, name := range names {
+		count, err := packages_model.CountVersions(ctx, &packages_model.PackageSearchOptions{
+			OwnerID: ownerID,
+			Type:    packageType,
+			Name: packages_model.SearchValue{
+				ExactMatch: true,
+				Value:      name,
+			},
+			IsInternal: optional.Some(false),
+			IsRemote:   optional.Some(false),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, nil
+		}
+	}
+	return pr, nil
+}
+
+// Creator returns the user which is recorded as creator of cached versions
+func Creator(doer *user_model.User) *user_model.User {
+	if doer == nil {
+		return user_model.NewGhostUser()
+	}
+	return doer
+}
+
+// Client fetches files from the upstream registry of a package remote
+type Client struct {
+	remote   *packages_model.PackageRemote
+	baseURL  *url.URL
+	username string
+	password string
+	token    string
+}
+
+// NewClient creates a client for the upstream registry of the remote
+func NewClient(pr *packages_model.PackageRemote) (*Client, error) {
+	baseURL, err := url.Parse(strings.TrimSuffix(pr.URL, "/") + "/")
+	if err != nil {
+		return nil, err
+	}
+	password, err := pr.Password()
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		remote:   pr,
+		baseURL:  baseURL,
+		username: pr.Username,
+		password: password,
+	}, nil
+}
+
+// Remote returns the remote of the client
+func (c *Client) Remote() *packages_model.PackageRemote {
+	return c.remote
+}
+
+// ResolveURL resolves a path relative to the upstream URL. Absolute URLs are returned unchanged.
+func (c *Client) ResolveURL(ref string) (*url.URL, error) {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return nil, err
+	}
+	return c.baseURL.ResolveReference(u), nil
+}
+
+// Do sends a request to the upstream registry.
+// The credentials of the remote are only sent to the upstream host. If upstream asks for a bearer token
+// (like container registries do), the token is requested and the request is retried.
+func (c *Client) Do(ctx context.Context, method, ref string, header http.Header) (*http.Response, error) {
+	target, err := c.ResolveURL(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(ctx, method, target, header)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized && c.token == "" {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		if scheme, _, _ := strings.Cut(challenge, " "); strings.EqualFold(scheme, "bearer") {
+			resp.Body.Close()
+
+			if c.token, err = c.requestToken(ctx, challenge); err != nil {
+				return nil, err
+			}
+			if resp, err = c.do(ctx, method, target, header); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrUpstreamNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("upstream registry responded with status %d for %s", resp.StatusCode, target.Redacted())
+	}
+	return resp, nil
+}
+
+func (c *Client) do(ctx context.Context, method string, target *url.URL, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, vs := range header {
+		req.Header[k] = vs
+	}
+	if strings.EqualFold(target.Host, c.baseURL.Host) {
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		} else if c.username != "" || c.password != "" {
+			req.SetBasicAuth(c.username, c.password)
+		}
+	}
+	return httpClient().Do(req)
+}
+
+// requestToken requests a bearer token as described by the challenge of the upstream registry
+// https://distribution.github.io/distribution/spec/auth/token/
+func (c *Client) requestToken(ctx context.Context, challenge string) (string, error) {
+	params := parseChallenge(challenge)
+
+	realm, err := url.Parse(params["realm"])
+	if err != nil || (realm.Scheme != "http" && realm.Scheme != "https") {
+		return "", fmt.Errorf("invalid token realm in challenge: %s", challenge)
+	}
+	q := realm.Query()
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			q.Set(key, params[key])
+		}
+	}
+	realm.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if c.username != "" || c.password != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	resp, err := httpClient().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request to %s failed with status %d", realm.Redacted(), resp.StatusCode)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxDocumentSize)).Decode(&token); err != nil {
+		return "", err
+	}
+	if token.Token != "" {
+		return token.Token, nil
+	}
+	if token.AccessToken != "" {
+		return token.AccessToken, nil
+	}
+	return "", errors.New("token response contains no token")
+}
+
+// parseChallenge parses the parameters of a WWW-Authenticate header like
+// Bearer realm="https://auth.example.com/token",service="registry.example.com",scope="repository:image:pull"
+func parseChallenge(challenge string) map[string]string {
+	params := make(map[string]string)
+
+	_, rest, _ := strings.Cut(challenge, " ")
+	for {
+		rest = strings.TrimLeft(rest, " ,")
+		key, value, ok := strings.Cut(rest, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		if strings.HasPrefix(value, `"`) {
+			value, rest, _ = strings.Cut(value[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(value, ",")
+		}
+		params[key] = strings.TrimSpace(value)
+	}
+	return params
+}
+
+// GetDocument returns the content of an upstream metadata document.
+// The content is cached for the TTL of the remote, so upstream gets revalidated only after the TTL is expired.
+func (c *Client) GetDocument(ctx context.Context, ref string, header http.Header) ([]byte, error) {
+	key := c.cacheKey("document", ref+"|"+header.Get("Accept"))
+
+	if c.remote.TTLMinutes > 0 {
+		if data, ok := cache.GetCache().Get(key); ok {
+			return []byte(data), nil
+		}
+	}
+
+	resp, err := c.Do(ctx, http.MethodGet, ref, header)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDocumentSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxDocumentSize {
+		return nil, fmt.Errorf("upstream document %s exceeds the maximum size", ref)
+	}
+
+	if c.remote.TTLMinutes > 0 {
+		_ = cache.GetCache().Put(key, string(data), int64(c.remote.TTL().Seconds()))
+	}
+
+	return data, nil
+}
+
+// Download fetches an upstream file into a buffer which can be stored as package file
+func (c *Client) Download(ctx context.Context, ref string, header http.Header) (*packages_module.HashedBuffer, error) {
+	resp, err := c.Do(ctx, http.MethodGet, ref, header)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return packages_module.CreateHashedBufferFromReader(resp.Body)
+}
+
+// NeedsRevalidation checks if a cached version which may change upstream (like a container tag) has to be compared with upstream again
+func (c *Client) NeedsRevalidation(pv *packages_model.PackageVersion) bool {
+	if c.remote.TTLMinutes <= 0 {
+		return true
+	}
+	if time.Since(pv.CreatedUnix.AsTime()) < c.remote.TTL() {
+		return false
+	}
+	return !cache.GetCache().IsExist(c.cacheKey("validated", pv.ID))
+}
+
+// MarkRevalidated records that a cached version matches upstream, so it is not revalidated until the TTL is expired
+func (c *Client) MarkRevalidated(pv *packages_model.PackageVersion) {
+	if c.remote.TTLMinutes > 0 {
+		_ = cache.GetCache().Put(c.cacheKey("validated", pv.ID), "1", int64(c.remote.TTL().Seconds()))
+	}
+}
+
+func (c *Client) cacheKey(usage string, key any) string {
+	// the update time is part of the key so that changing the remote invalidates everything cached for it
+	return fmt.Sprintf("package_remote_%d_%d_%s_%v", c.remote.ID, c.remote.UpdatedUnix, usage, key)
+}
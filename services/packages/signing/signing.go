@@ -0,0 +1,380 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package signing
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	packages_model "github.com/kumose/kmup/models/packages"
+	container_model "github.com/kumose/kmup/models/packages/container"
+	user_model "github.com/kumose/kmup/models/user"
+	"github.com/kumose/kmup/modules/log"
+	container_module "github.com/kumose/kmup/modules/packages/container"
+	sigstore_module "github.com/kumose/kmup/modules/packages/sigstore"
+	"github.com/kumose/kmup/modules/setting"
+	"github.com/kumose/kmup/modules/util"
+)
+
+// SettingKeySignatureRequired is the owner setting which rejects downloads of unsigned package versions
+const SettingKeySignatureRequired = "packages.signing.required"
+
+var (
+	ErrBundleNotExist      = util.NewNotExistErrorf("bundle does not exist")
+	ErrBundleSubject       = util.NewInvalidArgumentErrorf("bundle does not sign a file of the package version")
+	ErrBundleAlreadyExists = util.NewAlreadyExistErrorf("bundle already exists")
+	ErrSignatureRequired   = util.NewPermissionDeniedErrorf("package version is not signed by a trusted signer")
+)
+
+// Bundle is a signature or attestation bundle stored for a package version
+type Bundle struct {
+	ID     int64
+	Bundle *sigstore_module.Bundle
+	Files  []*packages_model.PackageFileDescriptor
+	Result *sigstore_module.Result
+}
+
+// Verification is the verification result of all bundles of a package version
+type Verification struct {
+	Bundles []*Bundle
+	// Verified is true if at least one bundle is signed by a trusted signer
+	Verified bool
+}
+
+// AddBundle stores a signature or attestation bundle for the package version.
+// The bundle must sign at least one file of the version.
+func AddBundle(ctx context.Context, pd *packages_model.PackageDescriptor, data []byte) (*Bundle, error) {
+	b, err := sigstore_module.ParseBundle(data)
+	if err != nil {
+		return nil, err
+	}
+
+	files := subjectFiles(pd, b)
+	if len(files) == 0 {
+		return nil, ErrBundleSubject
+	}
+
+	content := string(data)
+	for _, pp := range pd.VersionProperties {
+		if pp.Name == sigstore_module.PropertyBundle && pp.Value == content {
+			return nil, ErrBundleAlreadyExists
+		}
+	}
+
+	pp, err := packages_model.InsertProperty(ctx, packages_model.PropertyTypeVersion, pd.Version.ID, sigstore_module.PropertyBundle, content)
+	if err != nil {
+		return nil, err
+	}
+	// the keyless signing certificates are verified at the upload time, since the transparency log entries aren't verified
+	uploadedAt := time.Now()
+	if _, err := packages_model.InsertProperty(ctx, packages_model.PropertyTypeVersion, pd.Version.ID, sigstore_module.PropertyBundleUploaded, fmt.Sprintf("%d:%d", pp.ID, uploadedAt.Unix())); err != nil {
+		return nil, err
+	}
+
+	opts, err := verifyOptions(ctx, pd.Owner.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Bundle{
+		ID:     pp.ID,
+		Bundle: b,
+		Files:  files,
+		Result: b.Verify(opts, uploadedAt),
+	}, nil
+}
+
+// DeleteBundle deletes a bundle of the package version
+func DeleteBundle(ctx context.Context, pd *packages_model.PackageDescriptor, bundleID int64) error {
+	for _, pp := range pd.VersionProperties {
+		if pp.ID == bundleID && pp.Name == sigstore_module.PropertyBundle {
+			for _, uploaded := range pd.VersionProperties {
+				if id, _, ok := parseBundleUploaded(uploaded); ok && id == bundleID {
+					if err := packages_model.DeletePropertyByID(ctx, uploaded.ID); err != nil {
+						return err
+					}
+				}
+			}
+			return packages_model.DeletePropertyByID(ctx, pp.ID)
+		}
+	}
+	return ErrBundleNotExist
+}
+
+// parseBundleUploaded parses a property which stores the upload time of a bundle
+func parseBundleUploaded(pp *packages_model.PackageProperty) (int64, time.Time, bool) {
+	if pp.Name != sigstore_module.PropertyBundleUploaded {
+		return 0, time.Time{}, false
+	}
+	idStr, unixStr, _ := strings.Cut(pp.Value, ":")
+	id, err1 := strconv.ParseInt(idStr, 10, 64)
+	unix, err2 := strconv.ParseInt(unixStr, 10, 64)
+	if err1 != nil || err2 != nil {
+		return 0, time.Time{}, false
+	}
+	return id, time.Unix(unix, 0), true
+}
+
+// GetVersionVerification verifies all bundles of the package version against the trusted signers of the owner
+func GetVersionVerification(ctx context.Context, pd *packages_model.PackageDescriptor) (*Verification, error) {
+	v := &Verification{}
+
+	uploadedAt := make(map[int64]time.Time)
+	for _, pp := range pd.VersionProperties {
+		if id, t, ok := parseBundleUploaded(pp); ok {
+			uploadedAt[id] = t
+		}
+	}
+
+	var opts *sigstore_module.VerifyOptions
+	for _, pp := range pd.VersionProperties {
+		if pp.Name != sigstore_module.PropertyBundle {
+			continue
+		}
+
+		b, err := sigstore_module.ParseBundle([]byte(pp.Value))
+		if err != nil {
+			log.Error("Invalid bundle %d of package version %d: %v", pp.ID, pd.Version.ID, err)
+			continue
+		}
+
+		if opts == nil {
+			if opts, err = verifyOptions(ctx, pd.Owner.ID); err != nil {
+				return nil, err
+			}
+		}
+
+		bundle := &Bundle{
+			ID:     pp.ID,
+			Bundle: b,
+			Files:  subjectFiles(pd, b),
+		}
+		if len(bundle.Files) > 0 {
+			// a bundle without an upload time is verified at the current time
+			bundle.Result = b.Verify(opts, uploadedAt[pp.ID])
+		} else {
+			// the signed files were removed from the version
+			bundle.Result = &sigstore_module.Result{Err: ErrBundleSubject}
+		}
+		v.Verified = v.Verified || bundle.Result.Verified
+		v.Bundles = append(v.Bundles, bundle)
+	}
+
+	return v, nil
+}
+
+// IsSignatureRequired checks if the owner rejects downloads of package versions which are not signed by a trusted signer
+func IsSignatureRequired(ctx context.Context, ownerID int64) (bool, error) {
+	value, err := user_model.GetSetting(ctx, ownerID, SettingKeySignatureRequired)
+	if err != nil {
+		if errors.Is(err, util.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	return strconv.ParseBool(value)
+}
+
+// SetSignatureRequired sets the signature policy of the owner
+func SetSignatureRequired(ctx context.Context, ownerID int64, required bool) error {
+	if !required {
+		return user_model.DeleteUserSetting(ctx, ownerID, SettingKeySignatureRequired)
+	}
+	return user_model.SetUserSetting(ctx, ownerID, SettingKeySignatureRequired, strconv.FormatBool(required))
+}
+
+// CheckDownloadAllowed checks the signature policy of the owner before a file of the package version gets served.
+// Only the files of the versions are checked, the documents which aren't files of a version are exempted:
+//   - the Maven metadata files, which only list the versions, whether they are generated or proxied from a remote
+//   - the container blobs, because the layers are shared by all versions of the image
+//
+// The container manifests are checked by CheckManifestDownloadAllowed instead.
+// The files of all other package types are served by helper.ServePackageFile, which calls this check.
+func CheckDownloadAllowed(ctx context.Context, pf *packages_model.PackageFile) error {
+	pv, err := packages_model.GetVersionByID(ctx, pf.VersionID)
+	if err != nil {
+		return err
+	}
+	if pv.IsInternal {
+		return nil
+	}
+
+	p, err := packages_model.GetPackageByID(ctx, pv.PackageID)
+	if err != nil {
+		return err
+	}
+	if p.Type == packages_model.TypeContainer {
+		return nil
+	}
+
+	required, err := IsSignatureRequired(ctx, p.OwnerID)
+	if err != nil || !required {
+		return err
+	}
+
+	verified, err := isVersionVerified(ctx, pv)
+	if err != nil {
+		return err
+	}
+	if !verified {
+		return ErrSignatureRequired
+	}
+	return nil
+}
+
+// CheckManifestDownloadAllowed checks the signature policy of the owner before a container manifest gets served.
+// A platform manifest is stored in its own version, so it counts as signed if its version or one of the
+// image indexes referencing it is verified. The referrers, e.g. the signatures of an image, are exempted.
+func CheckManifestDownloadAllowed(ctx context.Context, pfd *packages_model.PackageFileDescriptor) error {
+	pv, err := packages_model.GetVersionByID(ctx, pfd.File.VersionID)
+	if err != nil {
+		return err
+	}
+	if pv.IsInternal {
+		return nil
+	}
+
+	p, err := packages_model.GetPackageByID(ctx, pv.PackageID)
+	if err != nil {
+		return err
+	}
+
+	required, err := IsSignatureRequired(ctx, p.OwnerID)
+	if err != nil || !required {
+		return err
+	}
+
+	subjects, err := packages_model.GetPropertiesByName(ctx, packages_model.PropertyTypeVersion, pv.ID, container_module.PropertyManifestSubject)
+	if err != nil || len(subjects) > 0 {
+		return err
+	}
+
+	verified, err := isVersionVerified(ctx, pv)
+	if err != nil || verified {
+		return err
+	}
+
+	indexes, err := container_model.GetIndexVersionsReferencingManifest(ctx, p.ID, pfd.Properties.GetByName(container_module.PropertyDigest))
+	if err != nil {
+		return err
+	}
+	for _, index := range indexes {
+		verified, err := isVersionVerified(ctx, index)
+		if err != nil || verified {
+			return err
+		}
+	}
+	return ErrSignatureRequired
+}
+
+// isVersionVerified checks if the package version has a verified signature bundle
+func isVersionVerified(ctx context.Context, pv *packages_model.PackageVersion) (bool, error) {
+	pd, err := packages_model.GetPackageDescriptor(ctx, pv)
+	if err != nil {
+		return false, err
+	}
+
+	v, err := GetVersionVerification(ctx, pd)
+	if err != nil {
+		return false, err
+	}
+	return v.Verified, nil
+}
+
+// subjectFiles returns the files of the package version which are signed by the bundle
+func subjectFiles(pd *packages_model.PackageDescriptor, b *sigstore_module.Bundle) []*packages_model.PackageFileDescriptor {
+	var files []*packages_model.PackageFileDescriptor
+	for _, pfd := range pd.Files {
+		for _, d := range b.Digests() {
+			if (d.Algorithm == "sha256" && d.Value == pfd.Blob.HashSHA256) || (d.Algorithm == "sha512" && d.Value == pfd.Blob.HashSHA512) {
+				files = append(files, pfd)
+				break
+			}
+		}
+	}
+	return files
+}
+
+func verifyOptions(ctx context.Context, ownerID int64) (*sigstore_module.VerifyOptions, error) {
+	pss, err := packages_model.GetSignersByOwner(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &sigstore_module.VerifyOptions{
+		Signers: make([]*sigstore_module.Signer, 0, len(pss)),
+		Roots:   trustedRoots(),
+	}
+	for _, ps := range pss {
+		signer := &sigstore_module.Signer{
+			Name:     ps.Name,
+			Identity: ps.Identity,
+			Issuer:   ps.Issuer,
+		}
+		if !ps.IsKeyless() {
+			if signer.PublicKey, err = sigstore_module.ParsePublicKey(ps.PublicKey); err != nil {
+				log.Error("Invalid public key of package signer %d: %v", ps.ID, err)
+				continue
+			}
+		}
+		opts.Signers = append(opts.Signers, signer)
+	}
+	return opts, nil
+}
+
+var rootsCache struct {
+	sync.Mutex
+	path  string
+	roots *x509.CertPool
+}
+
+// trustedRoots returns the certificate authorities trusted to issue keyless signing certificates
+func trustedRoots() *x509.CertPool {
+	path := setting.Packages.SigstoreRootsFile
+	if path == "" {
+		return nil
+	}
+
+	rootsCache.Lock()
+	defer rootsCache.Unlock()
+
+	if rootsCache.path != path {
+		rootsCache.path = path
+		rootsCache.roots = nil
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Error("Unable to read SIGSTORE_ROOTS_FILE %s: %v", path, err)
+			return nil
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(data) {
+			log.Error("SIGSTORE_ROOTS_FILE %s does not contain any certificate", path)
+			return nil
+		}
+		rootsCache.roots = roots
+	}
+	return rootsCache.roots
+}
@@ -0,0 +1,369 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package terraform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/kumose/kmup/models/db"
+	packages_model "github.com/kumose/kmup/models/packages"
+	user_model "github.com/kumose/kmup/models/user"
+	"github.com/kumose/kmup/modules/globallock"
+	"github.com/kumose/kmup/modules/json"
+	"github.com/kumose/kmup/modules/log"
+	"github.com/kumose/kmup/modules/optional"
+	packages_module "github.com/kumose/kmup/modules/packages"
+	terraform_module "github.com/kumose/kmup/modules/packages/terraform"
+	packages_service "github.com/kumose/kmup/services/packages"
+)
+
+// ErrStateLocked is returned if a state is locked by another lock than the given one
+type ErrStateLocked struct {
+	Lock *terraform_module.LockInfo
+}
+
+func (err ErrStateLocked) Error() string {
+	return fmt.Sprintf("state is locked [id: %s]", err.Lock.ID)
+}
+
+// State identifies a Terraform state of an owner or of a repository of the owner
+type State struct {
+	Owner  *user_model.User
+	RepoID int64
+	Name   string
+}
+
+func (s *State) packageName() string {
+	return terraform_module.StatePackageName(s.RepoID, s.Name)
+}
+
+func (s *State) lockKey() string {
+	return fmt.Sprintf("terraform_state_%d_%s", s.Owner.ID, strings.ToLower(s.packageName()))
+}
+
+func (s *State) getPackage(ctx context.Context) (*packages_model.Package, error) {
+	return packages_model.GetInternalPackageByName(ctx, s.Owner.ID, packages_model.TypeTerraformState, s.packageName())
+}
+
+func (s *State) getOrInsertPackage(ctx context.Context) (*packages_model.Package, error) {
+	p, err := packages_model.TryInsertPackage(ctx, &packages_model.Package{
+		OwnerID:    s.Owner.ID,
+		RepoID:     s.RepoID,
+		Type:       packages_model.TypeTerraformState,
+		Name:       s.packageName(),
+		LowerName:  strings.ToLower(s.packageName()),
+		IsInternal: true,
+	})
+	if err != nil && !errors.Is(err, packages_model.ErrDuplicatePackage) {
+		return nil, err
+	}
+	return p, nil
+}
+
+// GetVersions returns all versions of the state, the newest version first
+func GetVersions(ctx context.Context, s *State) ([]*packages_model.PackageVersion, error) {
+	pvs, _, err := packages_model.SearchVersions(ctx, &packages_model.PackageSearchOptions{
+		OwnerID: s.Owner.ID,
+		Type:    packages_model.TypeTerraformState,
+		Name: packages_model.SearchValue{
+			ExactMatch: true,
+			Value:      s.packageName(),
+		},
+		IsInternal: optional.Some(true),
+	})
+	return pvs, err
+}
+
+// GetLatestVersion returns the current version of the state
+func GetLatestVersion(ctx context.Context, s *State) (*packages_model.PackageVersion, error) {
+	pvs, err := GetVersions(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	if len(pvs) == 0 {
+		return nil, packages_model.ErrPackageNotExist
+	}
+	return pvs[0], nil
+}
+
+// GetVersion returns a specific version of the state
+func GetVersion(ctx context.Context, s *State, version string) (*packages_model.PackageVersion, error) {
+	return packages_model.GetInternalVersionByNameAndVersion(ctx, s.Owner.ID, packages_model.TypeTerraformState, s.packageName(), version)
+}
+
+// OpenVersion returns the content of a state version
+func OpenVersion(ctx context.Context, pv *packages_model.PackageVersion, method string) (io.ReadSeekCloser, *url.URL, *packages_model.PackageFile, error) {
+	return packages_service.OpenFileForDownloadByPackageVersion(
+		ctx,
+		pv,
+		&packages_service.PackageFileInfo{
+			Filename: terraform_module.StateFilename,
+		},
+		method,
+	)
+}
+
+// GetLock returns the current lock of the state or nil if the state is not locked
+func GetLock(ctx context.Context, s *State) (*terraform_module.LockInfo, error) {
+	p, err := s.getPackage(ctx)
+	if err != nil {
+		if errors.Is(err, packages_model.ErrPackageNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	pps, err := packages_model.GetPropertiesByName(ctx, packages_model.PropertyTypePackage, p.ID, terraform_module.PropertyStateLock)
+	if err != nil {
+		return nil, err
+	}
+	if len(pps) == 0 {
+		return nil, nil
+	}
+
+	var lock terraform_module.LockInfo
+	if err := json.Unmarshal([]byte(pps[0].Value), &lock); err != nil {
+		return nil, err
+	}
+	return &lock, nil
+}
+
+// checkLock returns ErrStateLocked if the state is locked by another lock than lockID
+func checkLock(ctx context.Context, s *State, lockID string) error {
+	lock, err := GetLock(ctx, s)
+	if err != nil {
+		return err
+	}
+	if lock != nil && lock.ID != lockID {
+		return ErrStateLocked{Lock: lock}
+	}
+	return nil
+}
+
+// Lock locks the state. If the state is already locked by another lock, ErrStateLocked is returned.
+func Lock(ctx context.Context, s *State, lock *terraform_module.LockInfo) error {
+	return globallock.LockAndDo(ctx, s.lockKey(), func(ctx context.Context) error {
+		if err := checkLock(ctx, s, lock.ID); err != nil {
+			return err
+		}
+
+		p, err := s.getOrInsertPackage(ctx)
+		if err != nil {
+			return err
+		}
+
+		value, err := json.Marshal(lock)
+		if err != nil {
+			return err
+		}
+		return packages_model.InsertOrUpdateProperty(ctx, packages_model.PropertyTypePackage, p.ID, terraform_module.PropertyStateLock, string(value))
+	})
+}
+
+// Unlock removes the lock of the state. If force is false, the state must be locked by lockID.
+func Unlock(ctx context.Context, s *State, lockID string, force bool) error {
+	return globallock.LockAndDo(ctx, s.lockKey(), func(ctx context.Context) error {
+		if !force {
+			if err := checkLock(ctx, s, lockID); err != nil {
+				return err
+			}
+		}
+
+		p, err := s.getPackage(ctx)
+		if err != nil {
+			if errors.Is(err, packages_model.ErrPackageNotExist) {
+				return nil
+			}
+			return err
+		}
+		return packages_model.DeletePropertiesByName(ctx, packages_model.PropertyTypePackage, p.ID, terraform_module.PropertyStateLock)
+	})
+}
+
+// SaveState stores a new version of the state. If the state is locked, lockID must match the lock.
+func SaveState(ctx context.Context, doer *user_model.User, s *State, lockID string, data *packages_module.HashedBuffer) (*packages_model.PackageVersion, error) {
+	metadata, err := terraform_module.ParseState(data)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := data.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var pv *packages_model.PackageVersion
+	err = globallock.LockAndDo(ctx, s.lockKey(), func(ctx context.Context) error {
+		if err := checkLock(ctx, s, lockID); err != nil {
+			return err
+		}
+
+		pv, err = addVersion(ctx, doer, s, metadata, data)
+		return err
+	})
+	return pv, err
+}
+
+// RollbackState stores the content of a previous version as the new version of the state
+func RollbackState(ctx context.Context, doer *user_model.User, s *State, lockID, version string) (*packages_model.PackageVersion, error) {
+	var pv *packages_model.PackageVersion
+	err := globallock.LockAndDo(ctx, s.lockKey(), func(ctx context.Context) error {
+		if err := checkLock(ctx, s, lockID); err != nil {
+			return err
+		}
+
+		previous, err := GetVersion(ctx, s, version)
+		if err != nil {
+			return err
+		}
+
+		pd, err := packages_model.GetPackageDescriptor(ctx, previous)
+		if err != nil {
+			return err
+		}
+
+		r, err := packages_service.OpenBlobStream(pd.Files[0].Blob)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		buf, err := packages_module.CreateHashedBufferFromReader(r)
+		if err != nil {
+			return err
+		}
+		defer buf.Close()
+
+		pv, err = addVersion(ctx, doer, s, pd.Metadata.(*terraform_module.StateMetadata), buf)
+		return err
+	})
+	return pv, err
+}
+
+func addVersion(ctx context.Context, doer *user_model.User, s *State, metadata *terraform_module.StateMetadata, data packages_module.HashedSizeReader) (*packages_model.PackageVersion, error) {
+	if err := packages_service.CheckSizeQuotaExceeded(ctx, doer, s.Owner, packages_model.TypeTerraformState, data.Size()); err != nil {
+		return nil, err
+	}
+
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	var pv *packages_model.PackageVersion
+	err = db.WithTx(ctx, func(ctx context.Context) error {
+		p, err := s.getOrInsertPackage(ctx)
+		if err != nil {
+			return err
+		}
+
+		pvs, err := GetVersions(ctx, s)
+		if err != nil {
+			return err
+		}
+
+		// versions are numbered consecutively, a rollback creates a new version too
+		next := int64(1)
+		for _, pv := range pvs {
+			if n, err := strconv.ParseInt(pv.Version, 10, 64); err == nil && n >= next {
+				next = n + 1
+			}
+		}
+
+		pv = &packages_model.PackageVersion{
+			PackageID:    p.ID,
+			CreatorID:    doer.ID,
+			Version:      strconv.FormatInt(next, 10),
+			LowerVersion: strconv.FormatInt(next, 10),
+			IsInternal:   true,
+			MetadataJSON: string(metadataJSON),
+		}
+		if pv, err = packages_model.GetOrInsertVersion(ctx, pv); err != nil {
+			return err
+		}
+
+		_, err = packages_service.AddFileToPackageVersionInternal(ctx, pv, &packages_service.PackageFileCreationInfo{
+			PackageFileInfo: packages_service.PackageFileInfo{
+				Filename: terraform_module.StateFilename,
+			},
+			Creator: doer,
+			Data:    data,
+			IsLead:  true,
+		})
+		return err
+	})
+	return pv, err
+}
+
+// DeleteState deletes all versions and the lock of the state
+func DeleteState(ctx context.Context, s *State, lockID string) error {
+	return globallock.LockAndDo(ctx, s.lockKey(), func(ctx context.Context) error {
+		if err := checkLock(ctx, s, lockID); err != nil {
+			return err
+		}
+
+		p, err := s.getPackage(ctx)
+		if err != nil {
+			return err
+		}
+
+		return deletePackage(ctx, p)
+	})
+}
+
+func deletePackage(ctx context.Context, p *packages_model.Package) error {
+	return db.WithTx(ctx, func(ctx context.Context) error {
+		pvs, _, err := packages_model.SearchVersions(ctx, &packages_model.PackageSearchOptions{
+			PackageID:  p.ID,
+			IsInternal: optional.Some(true),
+		})
+		if err != nil {
+			return err
+		}
+		for _, pv := range pvs {
+			if err := packages_service.DeletePackageVersionAndReferences(ctx, pv); err != nil {
+				return err
+			}
+		}
+
+		if err := packages_model.DeleteAllProperties(ctx, packages_model.PropertyTypePackage, p.ID); err != nil {
+			return err
+		}
+		return packages_model.DeletePackageByID(ctx, p.ID)
+	})
+}
+
+// DeleteRepositoryStates deletes all states of a repository.
+// The states can't be accessed anymore because their names contain the id of the repository.
+func DeleteRepositoryStates(ctx context.Context, repoID int64) error {
+	ps, err := packages_model.GetPackagesByRepositoryAndType(ctx, repoID, packages_model.TypeTerraformState)
+	if err != nil {
+		return err
+	}
+	for _, p := range ps {
+		if err := deletePackage(ctx, p); err != nil {
+			log.Error("Unable to delete Terraform state %s: %v", p.Name, err)
+			return err
+		}
+	}
+	return nil
+}
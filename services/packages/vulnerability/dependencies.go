@@ -0,0 +1,131 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package vulnerability
+
+import (
+	"sort"
+	"strings"
+
+	packages_model "github.com/kumose/kmup/models/packages"
+	cargo_module "github.com/kumose/kmup/modules/packages/cargo"
+	maven_module "github.com/kumose/kmup/modules/packages/maven"
+	npm_module "github.com/kumose/kmup/modules/packages/npm"
+	nuget_module "github.com/kumose/kmup/modules/packages/nuget"
+	rubygems_module "github.com/kumose/kmup/modules/packages/rubygems"
+)
+
+// candidate is a package version which is matched against the advisories
+type candidate struct {
+	Name        string
+	Version     string
+	Requirement string // declared requirement if the candidate is a dependency
+}
+
+// collectCandidates returns the package version itself and the lowest versions
+// of the runtime dependencies declared in its metadata
+func collectCandidates(ecosystem string, pd *packages_model.PackageDescriptor) []candidate {
+	var dependencies []candidate
+	add := func(name, requirement string) {
+		if name == "" || requirement == "" {
+			return
+		}
+		if version := minimumVersion(requirement); version != "" {
+			dependencies = append(dependencies, candidate{Name: name, Version: version, Requirement: requirement})
+		}
+	}
+
+	switch m := pd.Metadata.(type) {
+	case *npm_module.Metadata:
+		for name, requirement := range m.Dependencies {
+			add(name, requirement)
+		}
+		for name, requirement := range m.OptionalDependencies {
+			add(name, requirement)
+		}
+	case *cargo_module.Metadata:
+		for _, dep := range m.Dependencies {
+			if dep.Kind == "dev" {
+				continue
+			}
+			name := dep.Name
+			if dep.Package != nil && *dep.Package != "" {
+				name = *dep.Package
+			}
+			add(name, dep.Req)
+		}
+	case *maven_module.Metadata:
+		for _, dep := range m.Dependencies {
+			add(dep.GroupID+":"+dep.ArtifactID, dep.Version)
+		}
+	case *nuget_module.Metadata:
+		for _, deps := range m.Dependencies {
+			for _, dep := range deps {
+				add(dep.ID, dep.Version)
+			}
+		}
+	case *rubygems_module.Metadata:
+		for _, dep := range m.RuntimeDependencies {
+			requirements := make([]string, 0, len(dep.Version))
+			for _, vr := range dep.Version {
+				requirements = append(requirements, vr.Restriction+" "+vr.Version)
+			}
+			add(dep.Name, strings.Join(requirements, ", "))
+		}
+	}
+
+	sort.Slice(dependencies, func(i, j int) bool {
+		return dependencies[i].Name < dependencies[j].Name
+	})
+
+	return append([]candidate{{Name: pd.Package.Name, Version: pd.Version.Version}}, dependencies...)
+}
+
+// minimumVersion returns the lowest version allowed by a requirement.
+// Only inclusive lower bounds are supported, other requirements return an empty string.
+func minimumVersion(requirement string) string {
+	req, _, _ := strings.Cut(requirement, "||")
+	req = strings.TrimSpace(req)
+
+	// NuGet and Maven version ranges
+	if strings.HasPrefix(req, "(") {
+		return ""
+	}
+	req = strings.TrimPrefix(req, "[")
+	req, _, _ = strings.Cut(req, ",")
+	req = strings.TrimSpace(req)
+
+	if strings.HasPrefix(req, ">") && !strings.HasPrefix(req, ">=") {
+		return ""
+	}
+	req = strings.TrimLeft(req, "=>^~ v")
+	if req == "" || req[0] < '0' || req[0] > '9' {
+		return ""
+	}
+
+	version, _, _ := strings.Cut(req, " ")
+	version = strings.TrimRight(version, "])")
+
+	parts := strings.Split(version, ".")
+	for i, part := range parts {
+		if part == "x" || part == "X" || part == "*" {
+			parts = parts[:i]
+			break
+		}
+	}
+	return strings.Join(parts, ".")
+}
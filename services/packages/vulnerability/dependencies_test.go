@@ -0,0 +1,52 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package vulnerability
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMinimumVersion(t *testing.T) {
+	cases := map[string]string{
+		"1.2.3":              "1.2.3",
+		"^1.2.3":             "1.2.3",
+		"~1.2.3":             "1.2.3",
+		">=1.2.3 <2.0.0":     "1.2.3",
+		"= 1.2.3":            "1.2.3",
+		"==1.2.3":            "1.2.3",
+		"~> 1.2":             "1.2",
+		"1.x":                "1",
+		"1.2.*":              "1.2",
+		"v1.2.3":             "1.2.3",
+		"^1.0.0 || ^2.0.0":   "1.0.0",
+		"[1.0,2.0)":          "1.0",
+		"[1.0]":              "1.0",
+		"(1.0,2.0)":          "",
+		">1.0.0":             "",
+		"<2.0.0":             "",
+		"*":                  "",
+		"latest":             "",
+		"${project.version}": "",
+		"":                   "",
+	}
+	for requirement, expected := range cases {
+		assert.Equal(t, expected, minimumVersion(requirement), "requirement %q", requirement)
+	}
+}
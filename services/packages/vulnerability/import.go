@@ -0,0 +1,206 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package vulnerability
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	packages_model "github.com/kumose/kmup/models/packages"
+	"github.com/kumose/kmup/modules/container"
+	"github.com/kumose/kmup/modules/log"
+	osv_module "github.com/kumose/kmup/modules/packages/osv"
+	"github.com/kumose/kmup/modules/setting"
+	"github.com/kumose/kmup/modules/timeutil"
+)
+
+type importer struct {
+	ctx      context.Context
+	imported int
+	changed  container.Set[packages_model.Type]
+}
+
+// ImportAdvisoriesFromSetting imports the advisories from the configured advisory database
+func ImportAdvisoriesFromSetting(ctx context.Context) error {
+	if setting.Packages.AdvisoryDatabasePath == "" {
+		log.Debug("No advisory database configured")
+		return nil
+	}
+	_, err := ImportAdvisories(ctx, setting.Packages.AdvisoryDatabasePath)
+	return err
+}
+
+// ImportAdvisories imports OSV advisories from a directory, a JSON file or a zip archive
+// and queues the package versions of the affected types for a rescan.
+// It returns the number of new or updated advisories.
+func ImportAdvisories(ctx context.Context, path string) (int, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+
+	imp := &importer{
+		ctx:     ctx,
+		changed: make(container.Set[packages_model.Type]),
+	}
+
+	if fi.IsDir() {
+		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			return imp.importPath(p)
+		})
+	} else {
+		err = imp.importPath(path)
+	}
+	if err != nil {
+		return imp.imported, err
+	}
+
+	if len(imp.changed) > 0 {
+		versionIDs, err := packages_model.GetVersionIDsByPackageTypes(ctx, imp.changed.Values())
+		if err != nil {
+			return imp.imported, err
+		}
+		QueueScan(versionIDs...)
+	}
+
+	log.Info("Imported %d package advisories from %s", imp.imported, path)
+
+	return imp.imported, nil
+}
+
+func (imp *importer) importPath(p string) error {
+	switch strings.ToLower(filepath.Ext(p)) {
+	case ".json":
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		return imp.importAdvisory(p, f)
+	case ".zip":
+		zr, err := zip.OpenReader(p)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+
+		for _, zf := range zr.File {
+			if zf.FileInfo().IsDir() || !strings.EqualFold(filepath.Ext(zf.Name), ".json") {
+				continue
+			}
+			if err := imp.importZipFile(zf); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (imp *importer) importZipFile(zf *zip.File) error {
+	f, err := zf.Open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return imp.importAdvisory(zf.Name, f)
+}
+
+func (imp *importer) importAdvisory(name string, r io.Reader) error {
+	if err := imp.ctx.Err(); err != nil {
+		return err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, osv_module.MaxAdvisorySize+1))
+	if err != nil {
+		return err
+	}
+
+	a, err := osv_module.ParseAdvisory(bytes.NewReader(data))
+	if err != nil {
+		log.Warn("Skipping invalid advisory %s: %v", name, err)
+		return nil
+	}
+
+	types := make(container.Set[packages_model.Type])
+	var affected []*packages_model.PackageAdvisoryAffected
+	seen := make(container.Set[string])
+	for packageType, ecosystem := range ecosystems {
+		for _, aff := range a.Affected {
+			if osv_module.BaseEcosystem(aff.Package.Ecosystem) != ecosystem {
+				continue
+			}
+			lowerName := strings.ToLower(osv_module.NormalizeName(ecosystem, aff.Package.Name))
+			if !seen.Add(string(packageType) + ":" + lowerName) {
+				continue
+			}
+			types.Add(packageType)
+			affected = append(affected, &packages_model.PackageAdvisoryAffected{
+				Type:      packageType,
+				LowerName: lowerName,
+			})
+		}
+	}
+
+	pa, err := packages_model.GetAdvisoryByAdvisoryID(imp.ctx, a.ID)
+	if err != nil && !errors.Is(err, packages_model.ErrPackageAdvisoryNotExist) {
+		return err
+	}
+
+	if a.IsWithdrawn() || len(affected) == 0 {
+		if pa != nil {
+			return packages_model.DeleteAdvisory(imp.ctx, pa)
+		}
+		return nil
+	}
+
+	modified := timeutil.TimeStamp(a.Modified.Unix())
+	if pa == nil {
+		pa = &packages_model.PackageAdvisory{AdvisoryID: a.ID}
+	} else if pa.ModifiedUnix >= modified {
+		return nil
+	}
+	pa.Summary = a.Summary
+	pa.Severity = a.SeverityLevel()
+	pa.Content = string(data)
+	pa.ModifiedUnix = modified
+
+	if err := packages_model.SaveAdvisory(imp.ctx, pa, affected); err != nil {
+		return err
+	}
+
+	imp.imported++
+	for t := range types {
+		imp.changed.Add(t)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package vulnerability
+
+import (
+	"context"
+
+	packages_model "github.com/kumose/kmup/models/packages"
+	user_model "github.com/kumose/kmup/models/user"
+	notify_service "github.com/kumose/kmup/services/notify"
+)
+
+type vulnerabilityNotifier struct {
+	notify_service.NullNotifier
+}
+
+var _ notify_service.Notifier = &vulnerabilityNotifier{}
+
+// NewNotifier creates a notifier which scans new package versions
+func NewNotifier() notify_service.Notifier {
+	return &vulnerabilityNotifier{}
+}
+
+func (n *vulnerabilityNotifier) PackageCreate(ctx context.Context, doer *user_model.User, pd *packages_model.PackageDescriptor) {
+	if pd.Version.IsInternal || !IsSupported(pd.Package.Type) {
+		return
+	}
+	QueueScan(pd.Version.ID)
+}
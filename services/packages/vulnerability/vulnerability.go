@@ -0,0 +1,279 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package vulnerability
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kumose/kmup/models/db"
+	packages_model "github.com/kumose/kmup/models/packages"
+	"github.com/kumose/kmup/modules/graceful"
+	"github.com/kumose/kmup/modules/log"
+	osv_module "github.com/kumose/kmup/modules/packages/osv"
+	"github.com/kumose/kmup/modules/queue"
+	notify_service "github.com/kumose/kmup/services/notify"
+)
+
+// ecosystems maps the package types which can be scanned to their OSV ecosystem
+var ecosystems = map[packages_model.Type]string{
+	packages_model.TypeCargo:    osv_module.EcosystemCargo,
+	packages_model.TypeGo:       osv_module.EcosystemGo,
+	packages_model.TypeMaven:    osv_module.EcosystemMaven,
+	packages_model.TypeNpm:      osv_module.EcosystemNpm,
+	packages_model.TypeNuGet:    osv_module.EcosystemNuGet,
+	packages_model.TypePyPI:     osv_module.EcosystemPyPI,
+	packages_model.TypeRubyGems: osv_module.EcosystemRubyGems,
+}
+
+var scanQueue *queue.WorkerPoolQueue[int64]
+
+// Vulnerability is a finding together with the matching advisory
+type Vulnerability struct {
+	*packages_model.PackageVulnerability
+	Advisory      *osv_module.Advisory
+	Severity      string
+	FixedVersions []string
+}
+
+// Init starts the queue which scans package versions
+func Init() error {
+	notify_service.RegisterNotifier(NewNotifier())
+
+	scanQueue = queue.CreateUniqueQueue(graceful.GetManager().ShutdownContext(), "package_vulnerability_scan", handler)
+	if scanQueue == nil {
+		return errors.New("unable to create package_vulnerability_scan queue")
+	}
+	go graceful.GetManager().RunWithCancel(scanQueue)
+	return nil
+}
+
+func handler(items ...int64) []int64 {
+	ctx := graceful.GetManager().ShutdownContext()
+	for _, versionID := range items {
+		if err := ScanVersion(ctx, versionID); err != nil {
+			log.Error("ScanVersion [%d]: %v", versionID, err)
+		}
+	}
+	return nil
+}
+
+// IsSupported checks if versions of the package type can be scanned
+func IsSupported(packageType packages_model.Type) bool {
+	_, ok := ecosystems[packageType]
+	return ok
+}
+
+// QueueScan adds the package versions to the scan queue
+func QueueScan(versionIDs ...int64) {
+	if scanQueue == nil {
+		return
+	}
+	for _, versionID := range versionIDs {
+		if err := scanQueue.Push(versionID); err != nil && !errors.Is(err, queue.ErrAlreadyInQueue) {
+			log.Error("Unable to push package version %d to the scan queue: %v", versionID, err)
+		}
+	}
+}
+
+func findingKey(advisoryID int64, name string) string {
+	return strconv.FormatInt(advisoryID, 10) + ":" + name
+}
+
+// ScanVersion matches the package version and its dependencies against the stored advisories
+func ScanVersion(ctx context.Context, versionID int64) error {
+	pv, err := packages_model.GetVersionByID(ctx, versionID)
+	if err != nil {
+		if errors.Is(err, packages_model.ErrPackageNotExist) {
+			return nil
+		}
+		return err
+	}
+	if pv.IsInternal {
+		return nil
+	}
+
+	pd, err := packages_model.GetPackageDescriptor(ctx, pv)
+	if err != nil {
+		return err
+	}
+
+	ecosystem, ok := ecosystems[pd.Package.Type]
+	if !ok {
+		return nil
+	}
+
+	advisories := make(map[string][]*storedAdvisory)
+	found := make(map[string]*packages_model.PackageVulnerability)
+	for _, c := range collectCandidates(ecosystem, pd) {
+		lowerName := strings.ToLower(osv_module.NormalizeName(ecosystem, c.Name))
+		sas, ok := advisories[lowerName]
+		if !ok {
+			sas, err = loadAdvisories(ctx, pd.Package.Type, lowerName)
+			if err != nil {
+				return err
+			}
+			advisories[lowerName] = sas
+		}
+
+		for _, sa := range sas {
+			if sa.Advisory.Match(ecosystem, c.Name, c.Version) == nil {
+				continue
+			}
+			key := findingKey(sa.ID, c.Name)
+			if _, has := found[key]; has {
+				continue
+			}
+			found[key] = &packages_model.PackageVulnerability{
+				VersionID:   pv.ID,
+				AdvisoryID:  sa.ID,
+				Name:        c.Name,
+				Version:     c.Version,
+				Requirement: c.Requirement,
+			}
+		}
+	}
+
+	var added []*packages_model.PackageVulnerability
+	err = db.WithTx(ctx, func(ctx context.Context) error {
+		existing, err := packages_model.GetVulnerabilitiesByVersionID(ctx, pv.ID)
+		if err != nil {
+			return err
+		}
+
+		var removed []int64
+		for _, e := range existing {
+			key := findingKey(e.AdvisoryID, e.Name)
+			if f, has := found[key]; has && f.Version == e.Version && f.Requirement == e.Requirement {
+				delete(found, key)
+				continue
+			}
+			removed = append(removed, e.ID)
+		}
+		if err := packages_model.DeleteVulnerabilitiesByIDs(ctx, removed); err != nil {
+			return err
+		}
+
+		added = make([]*packages_model.PackageVulnerability, 0, len(found))
+		for _, f := range found {
+			added = append(added, f)
+		}
+		sort.Slice(added, func(i, j int) bool {
+			if added[i].Name != added[j].Name {
+				return added[i].Name < added[j].Name
+			}
+			return added[i].AdvisoryID < added[j].AdvisoryID
+		})
+		return packages_model.InsertVulnerabilities(ctx, added)
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(added) > 0 {
+		notify_service.PackageVulnerable(ctx, pd, added)
+	}
+	return nil
+}
+
+type storedAdvisory struct {
+	*packages_model.PackageAdvisory
+	Advisory *osv_module.Advisory
+}
+
+func parseStoredAdvisory(pa *packages_model.PackageAdvisory) (*storedAdvisory, error) {
+	a, err := osv_module.ParseAdvisory(strings.NewReader(pa.Content))
+	if err != nil {
+		return nil, err
+	}
+	return &storedAdvisory{PackageAdvisory: pa, Advisory: a}, nil
+}
+
+func loadAdvisories(ctx context.Context, packageType packages_model.Type, lowerName string) ([]*storedAdvisory, error) {
+	pas, err := packages_model.GetAdvisoriesByPackage(ctx, packageType, lowerName)
+	if err != nil {
+		return nil, err
+	}
+
+	sas := make([]*storedAdvisory, 0, len(pas))
+	for _, pa := range pas {
+		sa, err := parseStoredAdvisory(pa)
+		if err != nil {
+			log.Error("Invalid stored advisory %s: %v", pa.AdvisoryID, err)
+			continue
+		}
+		sas = append(sas, sa)
+	}
+	return sas, nil
+}
+
+// GetVersionVulnerabilities gets the findings of the package version
+func GetVersionVulnerabilities(ctx context.Context, pd *packages_model.PackageDescriptor) ([]*Vulnerability, error) {
+	pvs, err := packages_model.GetVulnerabilitiesByVersionID(ctx, pd.Version.ID)
+	if err != nil {
+		return nil, err
+	}
+	return LoadVulnerabilities(ctx, pd.Package.Type, pvs)
+}
+
+// LoadVulnerabilities loads the advisories of the findings
+func LoadVulnerabilities(ctx context.Context, packageType packages_model.Type, pvs []*packages_model.PackageVulnerability) ([]*Vulnerability, error) {
+	if len(pvs) == 0 {
+		return []*Vulnerability{}, nil
+	}
+
+	ids := make([]int64, 0, len(pvs))
+	for _, pv := range pvs {
+		ids = append(ids, pv.AdvisoryID)
+	}
+	pas, err := packages_model.GetAdvisoriesByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	sas := make(map[int64]*storedAdvisory, len(pas))
+	for _, pa := range pas {
+		sa, err := parseStoredAdvisory(pa)
+		if err != nil {
+			return nil, err
+		}
+		sas[pa.ID] = sa
+	}
+
+	ecosystem := ecosystems[packageType]
+
+	vulnerabilities := make([]*Vulnerability, 0, len(pvs))
+	for _, pv := range pvs {
+		sa, ok := sas[pv.AdvisoryID]
+		if !ok {
+			continue
+		}
+		v := &Vulnerability{
+			PackageVulnerability: pv,
+			Advisory:             sa.Advisory,
+			Severity:             sa.Severity,
+		}
+		if affected := sa.Advisory.Match(ecosystem, pv.Name, pv.Version); affected != nil {
+			v.FixedVersions = affected.FixedVersions()
+		}
+		vulnerabilities = append(vulnerabilities, v)
+	}
+	return vulnerabilities, nil
+}
@@ -43,6 +43,7 @@ import (
 	"github.com/kumose/kmup/modules/queue"
 	"github.com/kumose/kmup/modules/setting"
 	"github.com/kumose/kmup/modules/timeutil"
+	"github.com/kumose/kmup/modules/util"
 	asymkey_service "github.com/kumose/kmup/services/asymkey"
 	"github.com/kumose/kmup/services/automergequeue"
 	notify_service "github.com/kumose/kmup/services/notify"
@@ -143,6 +144,7 @@ const (
 	MergeCheckTypeGeneral  MergeCheckType = iota // general merge checks for "merge", "rebase", "squash", etc
 	MergeCheckTypeManually                       // Manually Merged button (mark a PR as merged manually)
 	MergeCheckTypeAuto                           // Auto Merge (Scheduled Merge) After Checks Succeed
+	MergeCheckTypeQueue                          // Add to the merge queue of the base branch
 )
 
 // CheckPullMergeable check if the pull mergeable based on all conditions (branch protection, merge options, ...)
@@ -183,7 +185,16 @@ func CheckPullMergeable(stdCtx context.Context, doer *user_model.User, perm *acc
 			return ErrIsChecking
 		}
 
-		if err := CheckPullBranchProtections(ctx, pr, false); err != nil {
+		err := CheckPullBranchProtectionsForUser(ctx, pr, doer, false)
+		if err == nil && mergeCheckType != MergeCheckTypeQueue {
+			// a branch which requires a merge queue only accepts merges of tested merge groups
+			if queueRequired, errQueue := IsMergeQueueRequired(ctx, pr); errQueue != nil {
+				return errQueue
+			} else if queueRequired {
+				err = util.ErrorWrap(ErrNotReadyToMerge, "Pull requests must be merged through the merge queue")
+			}
+		}
+		if err != nil {
 			if !errors.Is(err, ErrNotReadyToMerge) {
 				log.Error("Error whilst checking pull branch protection for %-v: %v", pr, err)
 				return err
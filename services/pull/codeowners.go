@@ -0,0 +1,176 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package pull
+
+import (
+	"context"
+	"fmt"
+
+	git_model "github.com/kumose/kmup/models/git"
+	issues_model "github.com/kumose/kmup/models/issues"
+	org_model "github.com/kumose/kmup/models/organization"
+	user_model "github.com/kumose/kmup/models/user"
+	"github.com/kumose/kmup/modules/git"
+	"github.com/kumose/kmup/modules/gitrepo"
+	"github.com/kumose/kmup/modules/optional"
+	issue_service "github.com/kumose/kmup/services/issue"
+)
+
+// MissingCodeOwnerApproval lists the code owners of a changed file which have not approved the pull request yet
+type MissingCodeOwnerApproval struct {
+	Path  string
+	Users []*user_model.User
+	Teams []*org_model.Team
+}
+
+// GetMissingCodeOwnerApprovals returns the changed files of the pull request whose code owners have not approved it.
+// The code owners are read from the base branch, an approval counts if it is the latest review of its author,
+// and the stale approvals don't count if the protected branch rule ignores them, like for the required approvals.
+// The poster isn't required to approve the own pull request, as the poster isn't requested to review it either.
+func GetMissingCodeOwnerApprovals(ctx context.Context, pb *git_model.ProtectedBranch, pr *issues_model.PullRequest) ([]*MissingCodeOwnerApproval, error) {
+	if err := pr.LoadBaseRepo(ctx); err != nil {
+		return nil, err
+	}
+	if err := pr.LoadIssue(ctx); err != nil {
+		return nil, err
+	}
+
+	gitRepo, err := gitrepo.OpenRepository(ctx, pr.BaseRepo)
+	if err != nil {
+		return nil, err
+	}
+	defer gitRepo.Close()
+
+	commit, err := gitRepo.GetBranchCommit(pr.BaseBranch)
+	if err != nil {
+		return nil, fmt.Errorf("GetBranchCommit: %w", err)
+	}
+
+	rules := issue_service.GetCodeOwnerRules(ctx, commit)
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	mergeBase := pr.MergeBase
+	if mergeBase == "" {
+		mergeBase, _, err = gitRepo.GetMergeBase("", git.BranchPrefix+pr.BaseBranch, pr.GetGitHeadRefName())
+		if err != nil {
+			return nil, fmt.Errorf("GetMergeBase: %w", err)
+		}
+	}
+
+	changedFiles, err := gitRepo.GetFilesChangedBetween(mergeBase, pr.GetGitHeadRefName())
+	if err != nil {
+		return nil, fmt.Errorf("GetFilesChangedBetween: %w", err)
+	}
+
+	approvers, err := getCodeOwnerApprovers(ctx, pr, pb != nil && pb.IgnoreStaleApprovals)
+	if err != nil {
+		return nil, err
+	}
+
+	teamApproved := make(map[int64]bool)
+	isTeamApproved := func(team *org_model.Team) (bool, error) {
+		if approved, ok := teamApproved[team.ID]; ok {
+			return approved, nil
+		}
+		approved := false
+		for approverID := range approvers {
+			isMember, err := org_model.IsTeamMember(ctx, team.OrgID, team.ID, approverID)
+			if err != nil {
+				return false, err
+			}
+			if isMember {
+				approved = true
+				break
+			}
+		}
+		teamApproved[team.ID] = approved
+		return approved, nil
+	}
+
+	missing := make([]*MissingCodeOwnerApproval, 0, len(changedFiles))
+	for _, file := range changedFiles {
+		users := make([]*user_model.User, 0, 2)
+		teams := make([]*org_model.Team, 0, 2)
+		seenUsers := make(map[int64]bool)
+		seenTeams := make(map[int64]bool)
+
+		for _, rule := range rules {
+			if !rule.Match(file) {
+				continue
+			}
+			for _, u := range rule.Users {
+				if seenUsers[u.ID] || approvers[u.ID] || u.ID == pr.Issue.PosterID {
+					continue
+				}
+				seenUsers[u.ID] = true
+				users = append(users, u)
+			}
+			for _, t := range rule.Teams {
+				if seenTeams[t.ID] {
+					continue
+				}
+				seenTeams[t.ID] = true
+				approved, err := isTeamApproved(t)
+				if err != nil {
+					return nil, err
+				}
+				if !approved {
+					teams = append(teams, t)
+				}
+			}
+		}
+
+		if len(users) > 0 || len(teams) > 0 {
+			missing = append(missing, &MissingCodeOwnerApproval{
+				Path:  file,
+				Users: users,
+				Teams: teams,
+			})
+		}
+	}
+	return missing, nil
+}
+
+// getCodeOwnerApprovers returns the ids of the users whose latest review of the pull request is an approval, which mustn't be stale if ignoreStale is set
+func getCodeOwnerApprovers(ctx context.Context, pr *issues_model.PullRequest, ignoreStale bool) (map[int64]bool, error) {
+	reviews, err := issues_model.FindReviews(ctx, issues_model.FindReviewOptions{
+		Types:     []issues_model.ReviewType{issues_model.ReviewTypeApprove, issues_model.ReviewTypeReject},
+		IssueID:   pr.IssueID,
+		Dismissed: optional.Some(false),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// reviews are sorted by creation, so the latest review of a user wins
+	approvers := make(map[int64]bool)
+	for _, review := range reviews {
+		if review.ReviewerID == 0 || review.ReviewerTeamID != 0 {
+			continue
+		}
+		approvers[review.ReviewerID] = review.Type == issues_model.ReviewTypeApprove && !(ignoreStale && review.Stale)
+	}
+	for id, approved := range approvers {
+		if !approved {
+			delete(approvers, id)
+		}
+	}
+	return approvers, nil
+}
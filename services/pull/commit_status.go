@@ -19,10 +19,13 @@ package pull
 
 import (
 	"context"
+	"slices"
 
+	actions_model "github.com/kumose/kmup/models/actions"
 	"github.com/kumose/kmup/models/db"
 	git_model "github.com/kumose/kmup/models/git"
 	issues_model "github.com/kumose/kmup/models/issues"
+	repo_model "github.com/kumose/kmup/models/repo"
 	"github.com/kumose/kmup/modules/commitstatus"
 	"github.com/kumose/kmup/modules/gitrepo"
 	"github.com/kumose/kmup/modules/glob"
@@ -84,25 +87,44 @@ func IsPullCommitStatusPass(ctx context.Context, pr *issues_model.PullRequest) (
 	if err != nil {
 		return false, errors.Wrap(err, "GetLatestCommitStatus")
 	}
-	if pb == nil || !pb.EnableStatusCheck {
+	return IsPullCommitStatusPassForRule(ctx, pr, pb)
+}
+
+// IsPullCommitStatusPassForRule returns if all the status checks required by the protected branch rule PASS
+func IsPullCommitStatusPassForRule(ctx context.Context, pr *issues_model.PullRequest, pb *git_model.ProtectedBranch) (bool, error) {
+	if pb == nil || (!pb.EnableStatusCheck && !pb.RequireRequiredWorkflows) {
 		return true, nil
 	}
 
-	state, err := GetPullRequestCommitStatusState(ctx, pr)
-	if err != nil {
-		return false, err
+	if pb.EnableStatusCheck {
+		state, err := getPullRequestCommitStatusState(ctx, pr, pb.StatusCheckContexts)
+		if err != nil {
+			return false, err
+		}
+		if !state.IsSuccess() {
+			return false, nil
+		}
+	}
+
+	if pb.RequireRequiredWorkflows {
+		state, err := GetPullRequestRequiredWorkflowsState(ctx, pr)
+		if err != nil {
+			return false, err
+		}
+		if !state.IsSuccess() {
+			return false, nil
+		}
 	}
-	return state.IsSuccess(), nil
+	return true, nil
 }
 
-// GetPullRequestCommitStatusState returns pull request merged commit status state
-func GetPullRequestCommitStatusState(ctx context.Context, pr *issues_model.PullRequest) (commitstatus.CommitStatusState, error) {
+// getPullRequestHeadCommitID returns the commit ID of the head of the pull request
+func getPullRequestHeadCommitID(ctx context.Context, pr *issues_model.PullRequest) (string, error) {
 	// Ensure HeadRepo is loaded
 	if err := pr.LoadHeadRepo(ctx); err != nil {
 		return "", errors.Wrap(err, "LoadHeadRepo")
 	}
 
-	// check if all required status checks are successful
 	headGitRepo, closer, err := gitrepo.RepositoryFromContextOrOpen(ctx, pr.HeadRepo)
 	if err != nil {
 		return "", errors.Wrap(err, "OpenRepository")
@@ -120,12 +142,27 @@ func GetPullRequestCommitStatusState(ctx context.Context, pr *issues_model.PullR
 		return "", errors.New("Head branch does not exist, can not merge")
 	}
 
-	var sha string
 	if pr.Flow == issues_model.PullRequestFlowGithub {
-		sha, err = headGitRepo.GetBranchCommitID(pr.HeadBranch)
-	} else {
-		sha, err = headGitRepo.GetRefCommitID(pr.GetGitHeadRefName())
+		return headGitRepo.GetBranchCommitID(pr.HeadBranch)
+	}
+	return headGitRepo.GetRefCommitID(pr.GetGitHeadRefName())
+}
+
+// GetPullRequestCommitStatusState returns pull request merged commit status state
+func GetPullRequestCommitStatusState(ctx context.Context, pr *issues_model.PullRequest) (commitstatus.CommitStatusState, error) {
+	pb, err := git_model.GetFirstMatchProtectedBranchRule(ctx, pr.BaseRepoID, pr.BaseBranch)
+	if err != nil {
+		return "", errors.Wrap(err, "LoadProtectedBranch")
+	}
+	var requiredContexts []string
+	if pb != nil {
+		requiredContexts = pb.StatusCheckContexts
 	}
+	return getPullRequestCommitStatusState(ctx, pr, requiredContexts)
+}
+
+func getPullRequestCommitStatusState(ctx context.Context, pr *issues_model.PullRequest, requiredContexts []string) (commitstatus.CommitStatusState, error) {
+	sha, err := getPullRequestHeadCommitID(ctx, pr)
 	if err != nil {
 		return "", err
 	}
@@ -139,14 +176,53 @@ func GetPullRequestCommitStatusState(ctx context.Context, pr *issues_model.PullR
 		return "", errors.Wrap(err, "GetLatestCommitStatus")
 	}
 
-	pb, err := git_model.GetFirstMatchProtectedBranchRule(ctx, pr.BaseRepoID, pr.BaseBranch)
+	return MergeRequiredContextsCommitStatus(commitStatuses, requiredContexts), nil
+}
+
+// GetPullRequestRequiredWorkflowsState returns the combined state of the required workflows runs on the head commit of the pull request
+func GetPullRequestRequiredWorkflowsState(ctx context.Context, pr *issues_model.PullRequest) (commitstatus.CommitStatusState, error) {
+	sha, err := getPullRequestHeadCommitID(ctx, pr)
 	if err != nil {
-		return "", errors.Wrap(err, "LoadProtectedBranch")
+		return "", err
 	}
-	var requiredContexts []string
-	if pb != nil {
-		requiredContexts = pb.StatusCheckContexts
+	if err := pr.LoadBaseRepo(ctx); err != nil {
+		return "", errors.Wrap(err, "LoadBaseRepo")
 	}
+	return GetRequiredWorkflowsState(ctx, pr.BaseRepo, sha)
+}
 
-	return MergeRequiredContextsCommitStatus(commitStatuses, requiredContexts), nil
+// GetRequiredWorkflowsState returns the combined state of the latest runs of the required workflows on the commit.
+// The required workflows which are not triggered for the commit don't block merging,
+// and the runs of the required workflows which have been removed are ignored.
+func GetRequiredWorkflowsState(ctx context.Context, repo *repo_model.Repository, commitID string) (commitstatus.CommitStatusState, error) {
+	rws, err := actions_model.GetRequiredWorkflowsForRepo(ctx, repo)
+	if err != nil {
+		return "", errors.Wrap(err, "GetRequiredWorkflowsForRepo")
+	}
+	if len(rws) == 0 {
+		return commitstatus.CommitStatusSuccess, nil
+	}
+	runs, err := actions_model.GetLatestRequiredWorkflowRuns(ctx, repo.ID, commitID)
+	if err != nil {
+		return "", errors.Wrap(err, "GetLatestRequiredWorkflowRuns")
+	}
+
+	states := make(commitstatus.CommitStatusStates, 0, len(runs))
+	for _, run := range runs {
+		if !slices.ContainsFunc(rws, func(rw *actions_model.ActionRequiredWorkflow) bool { return rw.ID == run.RequiredWorkflowID }) {
+			continue
+		}
+		switch {
+		case run.Status.IsSuccess(), run.Status.IsSkipped():
+			states = append(states, commitstatus.CommitStatusSuccess)
+		case run.Status.IsFailure(), run.Status.IsCancelled():
+			states = append(states, commitstatus.CommitStatusFailure)
+		default:
+			states = append(states, commitstatus.CommitStatusPending)
+		}
+	}
+	if len(states) == 0 {
+		return commitstatus.CommitStatusSuccess, nil
+	}
+	return states.Combine(), nil
 }
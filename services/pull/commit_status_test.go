@@ -20,10 +20,15 @@ package pull
 import (
 	"testing"
 
+	actions_model "github.com/kumose/kmup/models/actions"
+	"github.com/kumose/kmup/models/db"
 	git_model "github.com/kumose/kmup/models/git"
+	repo_model "github.com/kumose/kmup/models/repo"
+	"github.com/kumose/kmup/models/unittest"
 	"github.com/kumose/kmup/modules/commitstatus"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestMergeRequiredContextsCommitStatus(t *testing.T) {
@@ -103,3 +108,42 @@ func TestMergeRequiredContextsCommitStatus(t *testing.T) {
 		assert.Equal(t, c.expected, MergeRequiredContextsCommitStatus(c.commitStatuses, c.requiredContexts), "case %d", i)
 	}
 }
+
+func TestGetRequiredWorkflowsState(t *testing.T) {
+	require.NoError(t, unittest.PrepareTestDatabase())
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 4})
+	const sha = "c2d72f548424103f01ee1dc02889c1e2bff816b0"
+
+	assertState := func(t *testing.T, expected commitstatus.CommitStatusState) {
+		state, err := GetRequiredWorkflowsState(t.Context(), repo, sha)
+		require.NoError(t, err)
+		assert.Equal(t, expected, state)
+	}
+	insertRun := func(t *testing.T, index, requiredWorkflowID int64, status actions_model.Status) {
+		require.NoError(t, db.Insert(t.Context(), &actions_model.ActionRun{
+			RepoID:             repo.ID,
+			Index:              index,
+			RequiredWorkflowID: requiredWorkflowID,
+			CommitSHA:          sha,
+			Status:             status,
+		}))
+	}
+
+	// the runs of the removed required workflows are ignored
+	insertRun(t, 1001, 9999, actions_model.StatusFailure)
+	assertState(t, commitstatus.CommitStatusSuccess)
+
+	rw := &actions_model.ActionRequiredWorkflow{RepoID: 1, WorkflowPath: ".kmup/workflows/ci.yml"}
+	require.NoError(t, actions_model.CreateRequiredWorkflow(t.Context(), rw))
+	// the required workflow isn't triggered
+	assertState(t, commitstatus.CommitStatusSuccess)
+
+	insertRun(t, 1002, rw.ID, actions_model.StatusRunning)
+	assertState(t, commitstatus.CommitStatusPending)
+
+	insertRun(t, 1003, rw.ID, actions_model.StatusSuccess)
+	assertState(t, commitstatus.CommitStatusSuccess)
+
+	insertRun(t, 1004, rw.ID, actions_model.StatusFailure)
+	assertState(t, commitstatus.CommitStatusFailure)
+}
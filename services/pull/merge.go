@@ -283,8 +283,13 @@ func Merge(ctx context.Context, pr *issues_model.PullRequest, doer *user_model.U
 		return err
 	}
 
+	return afterMergePushed(ctx, pr.ID, doer, wasAutoMerged)
+}
+
+// afterMergePushed notifies about a pull request whose merge has been pushed to the base branch and resolves its cross references
+func afterMergePushed(ctx context.Context, prID int64, doer *user_model.User, wasAutoMerged bool) error {
 	// reload pull request because it has been updated by post receive hook
-	pr, err = issues_model.GetPullRequestByID(ctx, pr.ID)
+	pr, err := issues_model.GetPullRequestByID(ctx, prID)
 	if err != nil {
 		return err
 	}
@@ -353,61 +358,18 @@ func doMergeAndPush(ctx context.Context, pr *issues_model.PullRequest, doer *use
 	defer cancel()
 
 	// Merge commits.
-	switch mergeStyle {
-	case repo_model.MergeStyleMerge:
-		if err := doMergeStyleMerge(mergeCtx, message); err != nil {
-			return "", err
-		}
-	case repo_model.MergeStyleRebase, repo_model.MergeStyleRebaseMerge:
-		if err := doMergeStyleRebase(mergeCtx, mergeStyle, message); err != nil {
-			return "", err
-		}
-	case repo_model.MergeStyleSquash:
-		if err := doMergeStyleSquash(mergeCtx, message); err != nil {
-			return "", err
-		}
-	case repo_model.MergeStyleFastForwardOnly:
-		if err := doMergeStyleFastForwardOnly(mergeCtx); err != nil {
-			return "", err
-		}
-	default:
-		return "", ErrInvalidMergeStyle{ID: pr.BaseRepo.ID, Style: mergeStyle}
+	if err := doMergeStyle(mergeCtx, mergeStyle, message); err != nil {
+		return "", err
 	}
 
-	// OK we should cache our current head and origin/headbranch
-	mergeHeadSHA, err := git.GetFullCommitID(ctx, mergeCtx.tmpBasePath, "HEAD")
+	mergeCommitID, _, err := getMergeResultAndPushLFS(mergeCtx)
 	if err != nil {
-		return "", fmt.Errorf("Failed to get full commit id for HEAD: %w", err)
-	}
-	mergeBaseSHA, err := git.GetFullCommitID(ctx, mergeCtx.tmpBasePath, "original_"+baseBranch)
-	if err != nil {
-		return "", fmt.Errorf("Failed to get full commit id for origin/%s: %w", pr.BaseBranch, err)
-	}
-	mergeCommitID, err := git.GetFullCommitID(ctx, mergeCtx.tmpBasePath, baseBranch)
-	if err != nil {
-		return "", fmt.Errorf("Failed to get full commit id for the new merge: %w", err)
-	}
-
-	// Now it's questionable about where this should go - either after or before the push
-	// I think in the interests of data safety - failures to push to the lfs should prevent
-	// the merge as you can always remerge.
-	if setting.LFS.StartServer {
-		if err := LFSPush(ctx, mergeCtx.tmpBasePath, mergeHeadSHA, mergeBaseSHA, pr); err != nil {
-			return "", err
-		}
+		return "", err
 	}
 
-	var headUser *user_model.User
-	err = pr.HeadRepo.LoadOwner(ctx)
+	headUser, err := getHeadUser(ctx, pr, doer)
 	if err != nil {
-		if !user_model.IsErrUserNotExist(err) {
-			log.Error("Can't find user: %d for head repository in %-v: %v", pr.HeadRepo.OwnerID, pr, err)
-			return "", err
-		}
-		log.Warn("Can't find user: %d for head repository in %-v - defaulting to doer: %s - %v", pr.HeadRepo.OwnerID, pr, doer.Name, err)
-		headUser = doer
-	} else {
-		headUser = pr.HeadRepo.Owner
+		return "", err
 	}
 
 	mergeCtx.env = repo_module.FullPushingEnvironment(
@@ -448,6 +410,63 @@ func doMergeAndPush(ctx context.Context, pr *issues_model.PullRequest, doer *use
 	return mergeCommitID, nil
 }
 
+// doMergeStyle merges the tracking branch into the base branch of the temporary repository with the given style
+func doMergeStyle(mergeCtx *mergeContext, mergeStyle repo_model.MergeStyle, message string) error {
+	switch mergeStyle {
+	case repo_model.MergeStyleMerge:
+		return doMergeStyleMerge(mergeCtx, message)
+	case repo_model.MergeStyleRebase, repo_model.MergeStyleRebaseMerge:
+		return doMergeStyleRebase(mergeCtx, mergeStyle, message)
+	case repo_model.MergeStyleSquash:
+		return doMergeStyleSquash(mergeCtx, message)
+	case repo_model.MergeStyleFastForwardOnly:
+		return doMergeStyleFastForwardOnly(mergeCtx)
+	default:
+		return ErrInvalidMergeStyle{ID: mergeCtx.pr.BaseRepo.ID, Style: mergeStyle}
+	}
+}
+
+// getMergeResultAndPushLFS returns the new commit of the base branch and the commit it was merged onto,
+// the LFS objects of the merged commits are made available in the base repository
+func getMergeResultAndPushLFS(mergeCtx *mergeContext) (mergeCommitID, mergeBaseSHA string, err error) {
+	// OK we should cache our current head and origin/headbranch
+	mergeHeadSHA, err := git.GetFullCommitID(mergeCtx, mergeCtx.tmpBasePath, "HEAD")
+	if err != nil {
+		return "", "", fmt.Errorf("Failed to get full commit id for HEAD: %w", err)
+	}
+	mergeBaseSHA, err = git.GetFullCommitID(mergeCtx, mergeCtx.tmpBasePath, "original_"+baseBranch)
+	if err != nil {
+		return "", "", fmt.Errorf("Failed to get full commit id for origin/%s: %w", mergeCtx.pr.BaseBranch, err)
+	}
+	mergeCommitID, err = git.GetFullCommitID(mergeCtx, mergeCtx.tmpBasePath, baseBranch)
+	if err != nil {
+		return "", "", fmt.Errorf("Failed to get full commit id for the new merge: %w", err)
+	}
+
+	// Now it's questionable about where this should go - either after or before the push
+	// I think in the interests of data safety - failures to push to the lfs should prevent
+	// the merge as you can always remerge.
+	if setting.LFS.StartServer {
+		if err := LFSPush(mergeCtx, mergeCtx.tmpBasePath, mergeHeadSHA, mergeBaseSHA, mergeCtx.pr); err != nil {
+			return "", "", err
+		}
+	}
+	return mergeCommitID, mergeBaseSHA, nil
+}
+
+// getHeadUser returns the owner of the head repository which is used as the author of the merge push
+func getHeadUser(ctx context.Context, pr *issues_model.PullRequest, doer *user_model.User) (*user_model.User, error) {
+	if err := pr.HeadRepo.LoadOwner(ctx); err != nil {
+		if !user_model.IsErrUserNotExist(err) {
+			log.Error("Can't find user: %d for head repository in %-v: %v", pr.HeadRepo.OwnerID, pr, err)
+			return nil, err
+		}
+		log.Warn("Can't find user: %d for head repository in %-v - defaulting to doer: %s - %v", pr.HeadRepo.OwnerID, pr, doer.Name, err)
+		return doer, nil
+	}
+	return pr.HeadRepo.Owner, nil
+}
+
 func commitAndSignNoAuthor(ctx *mergeContext, message string) error {
 	cmdCommit := gitcmd.NewCommand("commit").AddOptionFormat("--message=%s", message)
 	if ctx.signKey == nil {
@@ -563,7 +582,7 @@ func IsUserAllowedToMerge(ctx context.Context, pr *issues_model.PullRequest, p a
 		return false, nil
 	}
 
-	pb, err := git_model.GetFirstMatchProtectedBranchRule(ctx, pr.BaseRepoID, pr.BaseBranch)
+	pb, err := git_model.GetFirstMatchProtectedBranchRuleForUser(ctx, pr.BaseRepoID, pr.BaseBranch, user)
 	if err != nil {
 		return false, err
 	}
@@ -576,25 +595,37 @@ func IsUserAllowedToMerge(ctx context.Context, pr *issues_model.PullRequest, p a
 }
 
 // CheckPullBranchProtections checks whether the PR is ready to be merged (reviews and status checks)
-func CheckPullBranchProtections(ctx context.Context, pr *issues_model.PullRequest, skipProtectedFilesCheck bool) (err error) {
-	if err = pr.LoadBaseRepo(ctx); err != nil {
-		return fmt.Errorf("LoadBaseRepo: %w", err)
-	}
+func CheckPullBranchProtections(ctx context.Context, pr *issues_model.PullRequest, skipProtectedFilesCheck bool) error {
+	return CheckPullBranchProtectionsForUser(ctx, pr, nil, skipProtectedFilesCheck)
+}
 
-	pb, err := git_model.GetFirstMatchProtectedBranchRule(ctx, pr.BaseRepoID, pr.BaseBranch)
+// CheckPullBranchProtectionsForUser checks whether the PR is ready to be merged by the user, the org rulesets which the user can bypass are ignored
+func CheckPullBranchProtectionsForUser(ctx context.Context, pr *issues_model.PullRequest, user *user_model.User, skipProtectedFilesCheck bool) error {
+	pb, err := git_model.GetFirstMatchProtectedBranchRuleForUser(ctx, pr.BaseRepoID, pr.BaseBranch, user)
 	if err != nil {
 		return fmt.Errorf("LoadProtectedBranch: %v", err)
 	}
+	return CheckPullProtectedBranchRule(ctx, pr, pb, skipProtectedFilesCheck)
+}
+
+// CheckPullProtectedBranchRule checks whether the PR is ready to be merged according to the protected branch rule
+func CheckPullProtectedBranchRule(ctx context.Context, pr *issues_model.PullRequest, pb *git_model.ProtectedBranch, skipProtectedFilesCheck bool) (err error) {
+	if err = pr.LoadBaseRepo(ctx); err != nil {
+		return fmt.Errorf("LoadBaseRepo: %w", err)
+	}
 	if pb == nil {
 		return nil
 	}
 
-	isPass, err := IsPullCommitStatusPass(ctx, pr)
-	if err != nil {
-		return err
-	}
-	if !isPass {
-		return util.ErrorWrap(ErrNotReadyToMerge, "Not all required status checks successful")
+	// with a merge queue the required status checks run on the merge group instead of the head of the pull request
+	if !pb.EnableMergeQueue {
+		isPass, err := IsPullCommitStatusPassForRule(ctx, pr, pb)
+		if err != nil {
+			return err
+		}
+		if !isPass {
+			return util.ErrorWrap(ErrNotReadyToMerge, "Not all required status checks successful")
+		}
 	}
 
 	if !issues_model.HasEnoughApprovals(ctx, pb, pr) {
@@ -611,6 +642,16 @@ func CheckPullBranchProtections(ctx context.Context, pr *issues_model.PullReques
 		return util.ErrorWrap(ErrNotReadyToMerge, "The head branch is behind the base branch")
 	}
 
+	if pb.RequireCodeOwnerApproval {
+		missing, err := GetMissingCodeOwnerApprovals(ctx, pb, pr)
+		if err != nil {
+			return fmt.Errorf("GetMissingCodeOwnerApprovals: %w", err)
+		}
+		if len(missing) > 0 {
+			return util.ErrorWrap(ErrNotReadyToMerge, "Code owners have not approved all changed files")
+		}
+	}
+
 	if skipProtectedFilesCheck {
 		return nil
 	}
@@ -0,0 +1,140 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package pull
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kumose/kmup/models/db"
+	git_model "github.com/kumose/kmup/models/git"
+	issues_model "github.com/kumose/kmup/models/issues"
+	repo_model "github.com/kumose/kmup/models/repo"
+	user_model "github.com/kumose/kmup/models/user"
+	"github.com/kumose/kmup/modules/commitstatus"
+	"github.com/kumose/kmup/modules/git"
+	"github.com/kumose/kmup/modules/git/gitcmd"
+	"github.com/kumose/kmup/modules/globallock"
+	"github.com/kumose/kmup/modules/log"
+	repo_module "github.com/kumose/kmup/modules/repository"
+)
+
+// IsMergeQueueRequired returns whether pull requests into the base branch of the pull request must be merged through a merge queue
+func IsMergeQueueRequired(ctx context.Context, pr *issues_model.PullRequest) (bool, error) {
+	pb, err := git_model.GetFirstMatchProtectedBranchRule(ctx, pr.BaseRepoID, pr.BaseBranch)
+	if err != nil {
+		return false, err
+	}
+	return pb != nil && pb.EnableMergeQueue, nil
+}
+
+// GetMergeGroupCommitStatusState returns the combined state of the required status checks and the required workflows
+// of the branch protection on the commit of a merge group
+func GetMergeGroupCommitStatusState(ctx context.Context, repo *repo_model.Repository, pb *git_model.ProtectedBranch, groupCommitID string) (commitstatus.CommitStatusState, error) {
+	if pb.EnableStatusCheck {
+		commitStatuses, err := git_model.GetLatestCommitStatus(ctx, repo.ID, groupCommitID, db.ListOptionsAll)
+		if err != nil {
+			return "", fmt.Errorf("GetLatestCommitStatus: %w", err)
+		}
+		if state := MergeRequiredContextsCommitStatus(commitStatuses, pb.StatusCheckContexts); !state.IsSuccess() {
+			return state, nil
+		}
+	}
+	if pb.RequireRequiredWorkflows {
+		return GetRequiredWorkflowsState(ctx, repo, groupCommitID)
+	}
+	return commitstatus.CommitStatusSuccess, nil
+}
+
+// BuildMergeGroup merges the pull request on top of the parent branch, which is either the base branch or the merge group
+// of the previous queue entry, and force pushes the result to the merge group branch.
+// The merge group branch is pushed without running the hooks, so it neither shows up as a branch nor triggers push events.
+// It returns the commit of the merge group and the commit it was built on.
+func BuildMergeGroup(ctx context.Context, pr *issues_model.PullRequest, doer *user_model.User, mergeStyle repo_model.MergeStyle, message, parentBranch, groupBranch string) (groupCommitID, parentCommitID string, err error) {
+	if err := pr.LoadBaseRepo(ctx); err != nil {
+		return "", "", fmt.Errorf("unable to load base repo: %w", err)
+	}
+
+	// the temporary repository uses the parent branch as its base, the pull request itself stays untouched
+	groupPR := *pr
+	groupPR.BaseBranch = parentBranch
+
+	mergeCtx, cancel, err := createTemporaryRepoForMerge(ctx, &groupPR, doer, "")
+	if err != nil {
+		return "", "", err
+	}
+	defer cancel()
+
+	if err := doMergeStyle(mergeCtx, mergeStyle, message); err != nil {
+		return "", "", err
+	}
+
+	groupCommitID, parentCommitID, err = getMergeResultAndPushLFS(mergeCtx)
+	if err != nil {
+		return "", "", err
+	}
+
+	mergeCtx.env = repo_module.InternalPushingEnvironment(doer, pr.BaseRepo)
+	pushCmd := gitcmd.NewCommand("push", "--force", "origin").AddDynamicArguments(baseBranch + ":" + git.BranchPrefix + groupBranch)
+	if err := mergeCtx.PrepareGitCmd(pushCmd).Run(ctx); err != nil {
+		return "", "", fmt.Errorf("git push: %s", mergeCtx.errbuf.String())
+	}
+	return groupCommitID, parentCommitID, nil
+}
+
+// MergeMergeGroup fast-forwards the base branch of the pull request to its tested merge group and marks the pull request as merged.
+// The push runs the hooks like a merge from the UI, so the branch protection of the base branch still applies,
+// except for the outdated branch check since the merge group is built on top of the base branch.
+func MergeMergeGroup(ctx context.Context, pr *issues_model.PullRequest, doer *user_model.User, groupCommitID string) error {
+	if err := pr.LoadBaseRepo(ctx); err != nil {
+		return fmt.Errorf("unable to load base repo: %w", err)
+	} else if err := pr.LoadHeadRepo(ctx); err != nil {
+		return fmt.Errorf("unable to load head repo: %w", err)
+	}
+
+	headUser, err := getHeadUser(ctx, pr, doer)
+	if err != nil {
+		return err
+	}
+
+	releaser, err := globallock.Lock(ctx, getPullWorkingLockKey(pr.ID))
+	if err != nil {
+		log.Error("lock.Lock(): %v", err)
+		return fmt.Errorf("lock.Lock: %w", err)
+	}
+
+	env := repo_module.FullPushingEnvironment(headUser, doer, pr.BaseRepo, pr.BaseRepo.Name, pr.ID)
+	env = append(env, repo_module.EnvPushTrigger+"="+string(repo_module.PushTriggerMergeQueueToBase))
+	err = git.Push(ctx, pr.BaseRepo.RepoPath(), git.PushOptions{
+		Remote: pr.BaseRepo.RepoPath(),
+		Branch: groupCommitID + ":" + git.BranchPrefix + pr.BaseBranch,
+		Env:    env,
+	})
+	releaser()
+	if err != nil {
+		return err
+	}
+
+	return afterMergePushed(ctx, pr.ID, doer, true)
+}
+
+// IsErrMergeGroupConflict returns whether building a merge group failed because the pull request can't be merged on top of its parent
+func IsErrMergeGroupConflict(err error) bool {
+	return IsErrMergeConflicts(err) || IsErrRebaseConflicts(err) || IsErrMergeUnrelatedHistories(err) ||
+		IsErrMergeDivergingFastForwardOnly(err) || git_model.IsErrBranchNotExist(err)
+}
@@ -0,0 +1,247 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package pushrule
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	git_model "github.com/kumose/kmup/models/git"
+	repo_model "github.com/kumose/kmup/models/repo"
+	"github.com/kumose/kmup/modules/git/gitcmd"
+	"github.com/kumose/kmup/modules/gitrepo"
+	"github.com/kumose/kmup/modules/secretscan"
+	"github.com/kumose/kmup/modules/setting"
+)
+
+// Violation represents a pushed commit or file which doesn't follow a push rule
+type Violation struct {
+	CommitID string
+	Path     string // empty if the violation is about the commit itself
+	Message  string
+}
+
+func (v *Violation) String() string {
+	if v.Path == "" {
+		return fmt.Sprintf("commit %s: %s", v.CommitID, v.Message)
+	}
+	return fmt.Sprintf("commit %s, file %s: %s", v.CommitID, v.Path, v.Message)
+}
+
+type pushedCommit struct {
+	ID          string
+	ParentIDs   []string
+	AuthorEmail string
+	Message     string
+}
+
+type pushedFile struct {
+	CommitID string
+	Path     string
+	BlobID   string
+}
+
+// CheckPush checks the commits which are reachable from newCommitID but not from any existing ref of the repository
+// against the push rules and returns all the violations.
+// env is the git environment of the pre-receive hook, so the quarantined objects can be read.
+func CheckPush(ctx context.Context, repo *repo_model.Repository, rules []*git_model.PushRule, newCommitID string, env []string) ([]*Violation, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	commits, err := getPushedCommits(ctx, repo, newCommitID, env)
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []*Violation
+	seen := make(map[Violation]bool)
+	addViolation := func(v Violation) {
+		if !seen[v] {
+			seen[v] = true
+			violations = append(violations, &v)
+		}
+	}
+
+	checkFiles, rejectSecrets := false, false
+	for _, rule := range rules {
+		checkFiles = checkFiles || rule.MaxFileSize > 0 || len(rule.ForbiddenPaths) > 0 || len(rule.ForbiddenExtensions) > 0 || rule.RejectSecrets
+		rejectSecrets = rejectSecrets || rule.RejectSecrets
+	}
+
+	for _, commit := range commits {
+		for _, rule := range rules {
+			if rule.RejectMergeCommits && len(commit.ParentIDs) > 1 {
+				addViolation(Violation{CommitID: commit.ID, Message: "merge commits are not allowed"})
+			}
+			if !rule.IsCommitMessageAllowed(commit.Message) {
+				addViolation(Violation{CommitID: commit.ID, Message: fmt.Sprintf("commit message does not match %q", rule.CommitMessagePattern)})
+			}
+			if !rule.IsAuthorEmailAllowed(commit.AuthorEmail) {
+				addViolation(Violation{CommitID: commit.ID, Message: fmt.Sprintf("author email %q is not in the allowed domains: %s", commit.AuthorEmail, strings.Join(rule.AuthorEmailDomains, ", "))})
+			}
+		}
+	}
+	if !checkFiles {
+		return violations, nil
+	}
+
+	var files []*pushedFile
+	for _, commit := range commits {
+		commitFiles, err := getPushedFiles(ctx, repo, commit, env)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, commitFiles...)
+	}
+
+	blobSizes, err := getBlobSizes(ctx, repo, files, env)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		size := blobSizes[file.BlobID]
+		for _, rule := range rules {
+			if rule.MaxFileSize > 0 && size > rule.MaxFileSize {
+				addViolation(Violation{CommitID: file.CommitID, Path: file.Path, Message: fmt.Sprintf("file size %d bytes exceeds the limit of %d bytes", size, rule.MaxFileSize)})
+			}
+			if rule.IsPathForbidden(file.Path) {
+				addViolation(Violation{CommitID: file.CommitID, Path: file.Path, Message: "file path is forbidden"})
+			}
+		}
+	}
+	if !rejectSecrets {
+		return violations, nil
+	}
+
+	scanned := make(map[string][]*secretscan.Finding)
+	for _, file := range files {
+		if blobSizes[file.BlobID] > setting.SecretScanning.MaxFileSize {
+			continue
+		}
+		findings, ok := scanned[file.BlobID]
+		if !ok {
+			content, _, err := gitrepo.RunCmdBytes(ctx, repo, gitcmd.NewCommand("cat-file", "blob").AddDynamicArguments(file.BlobID).WithEnv(env))
+			if err != nil {
+				return nil, fmt.Errorf("unable to read blob %s: %w", file.BlobID, err)
+			}
+			findings = secretscan.Scan(content)
+			scanned[file.BlobID] = findings
+		}
+		for _, finding := range findings {
+			addViolation(Violation{CommitID: file.CommitID, Path: file.Path, Message: fmt.Sprintf("line %d seems to contain a secret: %s", finding.Line, finding.Description)})
+		}
+	}
+	return violations, nil
+}
+
+// getPushedCommits returns the commits which are reachable from newCommitID but not from any existing ref
+func getPushedCommits(ctx context.Context, repo *repo_model.Repository, newCommitID string, env []string) ([]*pushedCommit, error) {
+	stdout, err := gitrepo.RunCmdString(ctx, repo, gitcmd.NewCommand("log", "-z", "--format=%H%x1f%P%x1f%ae%x1f%B").
+		AddDynamicArguments(newCommitID).
+		AddArguments("--not", "--all").
+		WithEnv(env))
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the pushed commits: %w", err)
+	}
+
+	var commits []*pushedCommit
+	for record := range strings.SplitSeq(stdout, "\x00") {
+		fields := strings.SplitN(record, "\x1f", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		commits = append(commits, &pushedCommit{
+			ID:          fields[0],
+			ParentIDs:   strings.Fields(fields[1]),
+			AuthorEmail: fields[2],
+			Message:     strings.TrimSpace(fields[3]),
+		})
+	}
+	return commits, nil
+}
+
+// getPushedFiles returns the files added or modified by the commit, merge commits are compared to their first parent
+func getPushedFiles(ctx context.Context, repo *repo_model.Repository, commit *pushedCommit, env []string) ([]*pushedFile, error) {
+	cmd := gitcmd.NewCommand("diff-tree", "-r", "-z", "--no-commit-id", "--root")
+	if len(commit.ParentIDs) > 1 {
+		cmd.AddDynamicArguments(commit.ParentIDs[0])
+	}
+	stdout, err := gitrepo.RunCmdString(ctx, repo, cmd.AddDynamicArguments(commit.ID).WithEnv(env))
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the files of commit %s: %w", commit.ID, err)
+	}
+
+	// the output is of the form ":<old_mode> <new_mode> <old_sha> <new_sha> <status>\0<path>\0"
+	var files []*pushedFile
+	parts := strings.Split(stdout, "\x00")
+	for i := 0; i+1 < len(parts); i += 2 {
+		fields := strings.Fields(strings.TrimPrefix(parts[i], ":"))
+		if len(fields) < 5 {
+			return nil, fmt.Errorf("unparsable output for diff-tree: %q", parts[i])
+		}
+		newMode, newBlobID, status := fields[1], fields[3], fields[4]
+		// skip the deleted files and the submodules
+		if status == "D" || newMode == "160000" {
+			continue
+		}
+		files = append(files, &pushedFile{CommitID: commit.ID, Path: parts[i+1], BlobID: newBlobID})
+	}
+	return files, nil
+}
+
+// getBlobSizes returns the sizes of the blobs of the files
+func getBlobSizes(ctx context.Context, repo *repo_model.Repository, files []*pushedFile, env []string) (map[string]int64, error) {
+	sizes := make(map[string]int64, len(files))
+	if len(files) == 0 {
+		return sizes, nil
+	}
+
+	var stdin bytes.Buffer
+	for _, file := range files {
+		if _, ok := sizes[file.BlobID]; !ok {
+			sizes[file.BlobID] = 0
+			stdin.WriteString(file.BlobID + "\n")
+		}
+	}
+
+	stdout, err := gitrepo.RunCmdString(ctx, repo, gitcmd.NewCommand("cat-file", "--batch-check").WithStdin(&stdin).WithEnv(env))
+	if err != nil {
+		return nil, fmt.Errorf("unable to get the sizes of the pushed files: %w", err)
+	}
+
+	// the output is of the form "<sha> <type> <size>\n"
+	scanner := bufio.NewScanner(strings.NewReader(stdout))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unparsable output for cat-file --batch-check: %q", scanner.Text())
+		}
+		sizes[fields[0]] = size
+	}
+	return sizes, scanner.Err()
+}
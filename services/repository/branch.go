@@ -102,7 +102,7 @@ func LoadBranches(ctx context.Context, repo *repo_model.Repository, gitRepo *git
 		return nil, nil, 0, err
 	}
 
-	rules, err := git_model.FindRepoProtectedBranchRules(ctx, repo.ID)
+	rules, err := git_model.FindEffectiveBranchRules(ctx, repo.ID)
 	if err != nil {
 		return nil, nil, 0, err
 	}
@@ -115,7 +115,7 @@ func LoadBranches(ctx context.Context, repo *repo_model.Repository, gitRepo *git
 
 	branches := make([]*Branch, 0, len(dbBranches))
 	for i := range dbBranches {
-		branch, err := loadOneBranch(ctx, repo, dbBranches[i], &rules, repoIDToRepo, repoIDToGitRepo)
+		branch, err := loadOneBranch(ctx, repo, dbBranches[i], rules, repoIDToRepo, repoIDToGitRepo)
 		if err != nil {
 			return nil, nil, 0, fmt.Errorf("loadOneBranch: %v", err)
 		}
@@ -124,7 +124,7 @@ func LoadBranches(ctx context.Context, repo *repo_model.Repository, gitRepo *git
 
 	// Always add the default branch
 	log.Debug("loadOneBranch: load default: '%s'", defaultDBBranch.Name)
-	defaultBranch, err := loadOneBranch(ctx, repo, defaultDBBranch, &rules, repoIDToRepo, repoIDToGitRepo)
+	defaultBranch, err := loadOneBranch(ctx, repo, defaultDBBranch, rules, repoIDToRepo, repoIDToGitRepo)
 	if err != nil {
 		return nil, nil, 0, fmt.Errorf("loadOneBranch: %v", err)
 	}
@@ -181,15 +181,16 @@ func DelRepoDivergenceFromCache(ctx context.Context, repoID int64) error {
 	return nil
 }
 
-func loadOneBranch(ctx context.Context, repo *repo_model.Repository, dbBranch *git_model.Branch, protectedBranches *git_model.ProtectedBranchRules,
+func loadOneBranch(ctx context.Context, repo *repo_model.Repository, dbBranch *git_model.Branch, protectedBranches *git_model.EffectiveBranchRules,
 	repoIDToRepo map[int64]*repo_model.Repository,
 	repoIDToGitRepo map[int64]*git.Repository,
 ) (*Branch, error) {
 	log.Trace("loadOneBranch: '%s'", dbBranch.Name)
 
 	branchName := dbBranch.Name
-	p := protectedBranches.GetFirstMatched(branchName)
-	isProtected := p != nil
+	// it must match DeleteBranch, which checks the rulesets whoever the doer is
+	p := protectedBranches.GetFirstMatchedForUser(ctx, branchName, nil)
+	isProtected := p != nil && p.IsDeletionProtected()
 
 	var divergence *gitrepo.DivergeObject
 
@@ -20,6 +20,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"path"
 
 	actions_model "github.com/kumose/kmup/models/actions"
 	activities_model "github.com/kumose/kmup/models/activities"
@@ -33,6 +34,7 @@ import (
 	project_model "github.com/kumose/kmup/models/project"
 	repo_model "github.com/kumose/kmup/models/repo"
 	secret_model "github.com/kumose/kmup/models/secret"
+	secretscan_model "github.com/kumose/kmup/models/secretscan"
 	system_model "github.com/kumose/kmup/models/system"
 	user_model "github.com/kumose/kmup/models/user"
 	"github.com/kumose/kmup/models/webhook"
@@ -45,6 +47,7 @@ import (
 	actions_service "github.com/kumose/kmup/services/actions"
 	asymkey_service "github.com/kumose/kmup/services/asymkey"
 	issue_service "github.com/kumose/kmup/services/issue"
+	terraform_service "github.com/kumose/kmup/services/packages/terraform"
 
 	"xorm.io/builder"
 )
@@ -96,6 +99,12 @@ func DeleteRepositoryDirectly(ctx context.Context, repoID int64, ignoreOrgTeams
 		return fmt.Errorf("list actions artifacts of repo %v: %w", repoID, err)
 	}
 
+	// Query the caches of this repo, they will be needed after they have been deleted to remove cache files in ObjectStorage
+	caches, err := db.Find[actions_model.ActionCache](ctx, actions_model.FindCachesOptions{RepoID: repoID})
+	if err != nil {
+		return fmt.Errorf("list actions caches of repo %v: %w", repoID, err)
+	}
+
 	// In case owner is a organization, we have to change repo specific teams
 	// if ignoreOrgTeams is not true
 	var org *user_model.User
@@ -173,6 +182,8 @@ func DeleteRepositoryDirectly(ctx context.Context, repoID int64, ignoreOrgTeams
 		&activities_model.Notification{RepoID: repoID},
 		&git_model.ProtectedBranch{RepoID: repoID},
 		&git_model.ProtectedTag{RepoID: repoID},
+		&git_model.PushRule{RepoID: repoID},
+		&git_model.RulesetEvaluation{RepoID: repoID},
 		&repo_model.PushMirror{RepoID: repoID},
 		&repo_model.Release{RepoID: repoID},
 		&repo_model.RepoIndexerStatus{RepoID: repoID},
@@ -183,7 +194,12 @@ func DeleteRepositoryDirectly(ctx context.Context, repoID int64, ignoreOrgTeams
 		&repo_model.Watch{RepoID: repoID},
 		&webhook.Webhook{RepoID: repoID},
 		&secret_model.Secret{RepoID: repoID},
+		&secretscan_model.Alert{RepoID: repoID},
+		&secretscan_model.RepoSetting{RepoID: repoID},
 		&actions_model.ActionTaskStep{RepoID: repoID},
+		&actions_model.ActionTaskAnnotation{RepoID: repoID},
+		&actions_model.ActionTestReport{RepoID: repoID},
+		&actions_model.ActionTestCase{RepoID: repoID},
 		&actions_model.ActionTask{RepoID: repoID},
 		&actions_model.ActionRunJob{RepoID: repoID},
 		&actions_model.ActionRun{RepoID: repoID},
@@ -192,6 +208,10 @@ func DeleteRepositoryDirectly(ctx context.Context, repoID int64, ignoreOrgTeams
 		&actions_model.ActionSchedule{RepoID: repoID},
 		&actions_model.ActionArtifact{RepoID: repoID},
 		&actions_model.ActionRunnerToken{RepoID: repoID},
+		&actions_model.ActionEnvironment{RepoID: repoID},
+		&actions_model.ActionDeployment{RepoID: repoID},
+		&actions_model.ActionCache{RepoID: repoID},
+		&actions_model.ActionRequiredWorkflow{RepoID: repoID},
 		&issues_model.IssuePin{RepoID: repoID},
 	); err != nil {
 		return fmt.Errorf("deleteBeans: %w", err)
@@ -301,6 +321,11 @@ func DeleteRepositoryDirectly(ctx context.Context, repoID int64, ignoreOrgTeams
 		return err
 	}
 
+	// Terraform states of this repository can't be accessed anymore
+	if err = terraform_service.DeleteRepositoryStates(ctx, repoID); err != nil {
+		return err
+	}
+
 	// unlink packages linked to this repository
 	if err = packages_model.UnlinkRepositoryFromAllPackages(ctx, repoID); err != nil {
 		return err
@@ -387,6 +412,22 @@ func DeleteRepositoryDirectly(ctx context.Context, repoID int64, ignoreOrgTeams
 		}
 	}
 
+	// delete actions caches and the uploaded chunks of incomplete caches in ObjectStorage
+	for _, c := range caches {
+		if c.Complete {
+			if err := storage.ActionsCache.Delete(c.StoragePath()); err != nil {
+				log.Error("remove cache file %q: %v", c.StoragePath(), err)
+			}
+			continue
+		}
+		if err := storage.ActionsCache.IterateObjects(c.TempStoragePath(), func(p string, obj storage.Object) error {
+			_ = obj.Close()
+			return storage.ActionsCache.Delete(c.TempStoragePath() + "/" + path.Base(p))
+		}); err != nil {
+			log.Error("remove cache chunks %q: %v", c.TempStoragePath(), err)
+		}
+	}
+
 	return nil
 }
 
@@ -703,7 +703,7 @@ func writeRepoObjectForRename(ctx context.Context, t *TemporaryUploadRepository,
 
 // VerifyBranchProtection verify the branch protection for modifying the given treePath on the given branch
 func VerifyBranchProtection(ctx context.Context, repo *repo_model.Repository, doer *user_model.User, branchName string, treePaths []string) error {
-	protectedBranch, err := git_model.GetFirstMatchProtectedBranchRule(ctx, repo.ID, branchName)
+	protectedBranch, err := git_model.GetFirstMatchProtectedBranchRuleForUser(ctx, repo.ID, branchName, doer)
 	if err != nil {
 		return err
 	}
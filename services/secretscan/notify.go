@@ -0,0 +1,55 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package secretscan
+
+import (
+	"context"
+
+	repo_model "github.com/kumose/kmup/models/repo"
+	secretscan_model "github.com/kumose/kmup/models/secretscan"
+	user_model "github.com/kumose/kmup/models/user"
+	"github.com/kumose/kmup/modules/log"
+	"github.com/kumose/kmup/modules/repository"
+	notify_service "github.com/kumose/kmup/services/notify"
+)
+
+type secretScanningNotifier struct {
+	notify_service.NullNotifier
+}
+
+var _ notify_service.Notifier = &secretScanningNotifier{}
+
+// NewNotifier create a new secretScanningNotifier notifier
+func NewNotifier() notify_service.Notifier {
+	return &secretScanningNotifier{}
+}
+
+func (n *secretScanningNotifier) PushCommits(ctx context.Context, pusher *user_model.User, repo *repo_model.Repository, opts *repository.PushUpdateOptions, commits *repository.PushCommits) {
+	if opts.IsDelRef() {
+		return
+	}
+	s, err := secretscan_model.GetRepoSetting(ctx, repo.ID)
+	if err != nil {
+		log.Error("GetRepoSetting[repo_id: %d]: %v", repo.ID, err)
+		return
+	}
+	if s.EffectivePushProtection() == secretscan_model.PushProtectionOff {
+		return
+	}
+	scanPush(repo, pusher.ID, opts.RefFullName, opts.OldCommitID, opts.NewCommitID)
+}
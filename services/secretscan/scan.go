@@ -0,0 +1,223 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package secretscan
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	repo_model "github.com/kumose/kmup/models/repo"
+	"github.com/kumose/kmup/modules/git"
+	"github.com/kumose/kmup/modules/git/gitcmd"
+	"github.com/kumose/kmup/modules/gitrepo"
+	"github.com/kumose/kmup/modules/secretscan"
+	"github.com/kumose/kmup/modules/setting"
+)
+
+// Leak is a secret found in a file of a commit
+type Leak struct {
+	*secretscan.Finding
+	CommitID string
+	Path     string
+}
+
+func (l *Leak) String() string {
+	return fmt.Sprintf("commit %s, file %s, line %d: %s (%s)", l.CommitID, l.Path, l.Line, l.Description, l.Redacted())
+}
+
+// blobRef is the first commit and path where a blob appears
+type blobRef struct {
+	CommitID string
+	Path     string
+	BlobID   string
+}
+
+// newLogCommand returns the command listing the blobs added or modified by commits, the revisions must be appended to it.
+// The merge commits are compared with each of their parents, so the blobs added by resolving the conflicts are listed too.
+func newLogCommand() *gitcmd.Command {
+	return gitcmd.NewCommand("log", "--reverse", "--raw", "-r", "-m", "--no-abbrev", "--no-renames", "--format=commit %H")
+}
+
+// scanLog scans the blobs listed by the log command, env is used to read the quarantined objects of a push
+func scanLog(ctx context.Context, repo *repo_model.Repository, logCmd *gitcmd.Command, env []string) ([]*Leak, error) {
+	blobs, err := listBlobs(ctx, repo, logCmd, env)
+	if err != nil {
+		return nil, err
+	}
+	if len(blobs) == 0 {
+		return nil, nil
+	}
+
+	findings, err := scanBlobs(ctx, repo, blobs, env)
+	if err != nil {
+		return nil, err
+	}
+
+	var leaks []*Leak
+	for _, blob := range blobs {
+		for _, finding := range findings[blob.BlobID] {
+			leaks = append(leaks, &Leak{Finding: finding, CommitID: blob.CommitID, Path: blob.Path})
+		}
+	}
+	return leaks, nil
+}
+
+// listBlobs returns the blobs added or modified by the commits of the log command, every blob is only returned once
+func listBlobs(ctx context.Context, repo *repo_model.Repository, logCmd *gitcmd.Command, env []string) ([]*blobRef, error) {
+	stdoutReader, stdoutWriter, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = stdoutReader.Close()
+		_ = stdoutWriter.Close()
+	}()
+
+	var blobs []*blobRef
+	seen := make(map[string]bool)
+	stderr := new(strings.Builder)
+	err = logCmd.WithDir(repo.RepoPath()).
+		WithEnv(env).
+		WithStdout(stdoutWriter).
+		WithStderr(stderr).
+		WithPipelineFunc(func(ctx context.Context, cancel context.CancelFunc) error {
+			_ = stdoutWriter.Close()
+			scanner := bufio.NewScanner(stdoutReader)
+			scanner.Buffer(nil, 1024*1024)
+
+			var commitID string
+			for scanner.Scan() {
+				line := scanner.Text()
+				// the merge commits are of the form "commit <sha> (from <parent_sha>)" since they are listed once per parent
+				if id, ok := strings.CutPrefix(line, "commit "); ok {
+					commitID, _, _ = strings.Cut(id, " ")
+					continue
+				}
+				// the file lines are of the form ":<old_mode> <new_mode> <old_sha> <new_sha> <status>\t<path>"
+				if !strings.HasPrefix(line, ":") {
+					continue
+				}
+				meta, path, ok := strings.Cut(line[1:], "\t")
+				fields := strings.Fields(meta)
+				if !ok || len(fields) < 5 {
+					continue
+				}
+				newMode, blobID, status := fields[1], fields[3], fields[4]
+				// skip the deleted files and the submodules
+				if status == "D" || newMode == "160000" || seen[blobID] {
+					continue
+				}
+				seen[blobID] = true
+				if strings.HasPrefix(path, `"`) {
+					if unquoted, err := strconv.Unquote(path); err == nil {
+						path = unquoted
+					}
+				}
+				blobs = append(blobs, &blobRef{CommitID: commitID, Path: path, BlobID: blobID})
+			}
+			return scanner.Err()
+		}).
+		Run(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the blobs: %w - %s", err, stderr)
+	}
+	return blobs, nil
+}
+
+// scanBlobs returns the secrets found in the blobs, the blobs larger than the maximum size are skipped
+func scanBlobs(ctx context.Context, repo *repo_model.Repository, blobs []*blobRef, env []string) (map[string][]*secretscan.Finding, error) {
+	var stdin bytes.Buffer
+	for _, blob := range blobs {
+		stdin.WriteString(blob.BlobID + "\n")
+	}
+	stdout, err := gitrepo.RunCmdString(ctx, repo, gitcmd.NewCommand("cat-file", "--batch-check").WithStdin(&stdin).WithEnv(env))
+	if err != nil {
+		return nil, fmt.Errorf("unable to get the sizes of the blobs: %w", err)
+	}
+
+	// the output is of the form "<sha> <type> <size>\n"
+	stdin.Reset()
+	for line := range strings.SplitSeq(stdout, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || fields[1] != "blob" {
+			continue
+		}
+		if size, err := strconv.ParseInt(fields[2], 10, 64); err == nil && size > 0 && size <= setting.SecretScanning.MaxFileSize {
+			stdin.WriteString(fields[0] + "\n")
+		}
+	}
+	findings := make(map[string][]*secretscan.Finding)
+	if stdin.Len() == 0 {
+		return findings, nil
+	}
+
+	stdoutReader, stdoutWriter, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = stdoutReader.Close()
+		_ = stdoutWriter.Close()
+	}()
+
+	rules := secretscan.Rules()
+	stderr := new(strings.Builder)
+	err = gitcmd.NewCommand("cat-file", "--batch").
+		WithDir(repo.RepoPath()).
+		WithEnv(env).
+		WithStdin(&stdin).
+		WithStdout(stdoutWriter).
+		WithStderr(stderr).
+		WithPipelineFunc(func(ctx context.Context, cancel context.CancelFunc) error {
+			_ = stdoutWriter.Close()
+			rd := bufio.NewReader(stdoutReader)
+			for {
+				sha, _, size, err := git.ReadBatchLine(rd)
+				if err == io.EOF {
+					return nil
+				} else if err != nil {
+					return err
+				}
+				// the content is followed by a LF
+				content := make([]byte, size+1)
+				if _, err := io.ReadFull(rd, content); err != nil {
+					return err
+				}
+				if found := secretscan.ScanWithRules(content[:size], rules); len(found) > 0 {
+					findings[string(sha)] = found
+				}
+			}
+		}).
+		Run(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the blobs: %w - %s", err, stderr)
+	}
+	return findings, nil
+}
+
+// CheckPush returns the secrets in the commits which are reachable from newCommitID but not from any existing ref of the repository.
+// env is the git environment of the pre-receive hook, so the quarantined objects can be read.
+func CheckPush(ctx context.Context, repo *repo_model.Repository, newCommitID string, env []string) ([]*Leak, error) {
+	return scanLog(ctx, repo, newLogCommand().AddDynamicArguments(newCommitID).AddArguments("--not", "--all"), env)
+}
@@ -0,0 +1,255 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package secretscan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	auth_model "github.com/kumose/kmup/models/auth"
+	repo_model "github.com/kumose/kmup/models/repo"
+	secretscan_model "github.com/kumose/kmup/models/secretscan"
+	user_model "github.com/kumose/kmup/models/user"
+	"github.com/kumose/kmup/modules/git"
+	"github.com/kumose/kmup/modules/globallock"
+	"github.com/kumose/kmup/modules/graceful"
+	"github.com/kumose/kmup/modules/log"
+	"github.com/kumose/kmup/modules/process"
+	"github.com/kumose/kmup/modules/queue"
+	"github.com/kumose/kmup/modules/secretscan"
+	"github.com/kumose/kmup/modules/setting"
+	"github.com/kumose/kmup/modules/util"
+	notify_service "github.com/kumose/kmup/services/notify"
+)
+
+// Actions of the secret_scanning_alert webhook event
+const (
+	AlertActionCreated  = "created"
+	AlertActionResolved = "resolved"
+	AlertActionReopened = "reopened"
+)
+
+// scanRequest is a scan of a range of commits, or of the whole history of the repository if NewCommitID is empty
+type scanRequest struct {
+	RepoID      int64
+	DoerID      int64
+	RefName     git.RefName
+	OldCommitID string
+	NewCommitID string
+}
+
+var scanQueue *queue.WorkerPoolQueue[*scanRequest]
+
+// Init runs the task queue that scans the pushes and the histories of the repositories
+func Init() error {
+	if !setting.SecretScanning.Enabled {
+		return nil
+	}
+	notify_service.RegisterNotifier(NewNotifier())
+
+	scanQueue = queue.CreateUniqueQueue(graceful.GetManager().ShutdownContext(), "secret_scanning", handler)
+	if scanQueue == nil {
+		return errors.New("unable to create secret_scanning queue")
+	}
+	go graceful.GetManager().RunWithCancel(scanQueue)
+	return nil
+}
+
+func handler(items ...*scanRequest) []*scanRequest {
+	for _, req := range items {
+		if err := processScanRequest(req); err != nil {
+			log.Error("Secret scanning of repo %d failed: %v", req.RepoID, err)
+		}
+	}
+	return nil
+}
+
+// ScanHistory adds a scan of the whole history of a repository to the queue
+func ScanHistory(repo *repo_model.Repository, doer *user_model.User) error {
+	if scanQueue == nil {
+		return util.NewInvalidArgumentErrorf("secret scanning is disabled")
+	}
+	err := scanQueue.Push(&scanRequest{RepoID: repo.ID, DoerID: doer.ID})
+	if errors.Is(err, queue.ErrAlreadyInQueue) {
+		return nil
+	}
+	return err
+}
+
+// scanPush adds a scan of the commits of a push to the queue
+func scanPush(repo *repo_model.Repository, pusherID int64, refName git.RefName, oldCommitID, newCommitID string) {
+	if scanQueue == nil {
+		return
+	}
+	req := &scanRequest{RepoID: repo.ID, DoerID: pusherID, RefName: refName, OldCommitID: oldCommitID, NewCommitID: newCommitID}
+	if err := scanQueue.Push(req); err != nil && !errors.Is(err, queue.ErrAlreadyInQueue) {
+		log.Error("Error adding the push to %s in repo %d to the secret scanning queue: %v", refName, repo.ID, err)
+	}
+}
+
+func getLockKey(repoID int64) string {
+	return fmt.Sprintf("secret_scanning_%d", repoID)
+}
+
+func processScanRequest(req *scanRequest) error {
+	ctx, _, finished := process.GetManager().AddContext(graceful.GetManager().HammerContext(),
+		fmt.Sprintf("Secret scanning of repo %d", req.RepoID))
+	defer finished()
+
+	releaser, err := globallock.Lock(ctx, getLockKey(req.RepoID))
+	if err != nil {
+		return fmt.Errorf("globallock.Lock: %w", err)
+	}
+	defer releaser()
+
+	repo, err := repo_model.GetRepositoryByID(ctx, req.RepoID)
+	if repo_model.IsErrRepoNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	doer, err := user_model.GetPossibleUserByID(ctx, req.DoerID)
+	if user_model.IsErrUserNotExist(err) {
+		doer = user_model.NewGhostUser()
+	} else if err != nil {
+		return err
+	}
+
+	logCmd := newLogCommand()
+	switch {
+	case req.NewCommitID == "":
+		logCmd.AddArguments("--all")
+	case git.IsEmptyCommitID(req.OldCommitID):
+		// a new ref only brings the commits which aren't reachable from the other refs
+		logCmd.AddDynamicArguments(req.NewCommitID).AddArguments("--not").AddOptionFormat("--exclude=%s", req.RefName.String()).AddArguments("--all")
+	default:
+		logCmd.AddDynamicArguments(req.OldCommitID + ".." + req.NewCommitID)
+	}
+
+	leaks, err := scanLog(ctx, repo, logCmd, nil)
+	if err != nil {
+		return err
+	}
+	for _, leak := range leaks {
+		if err := recordLeak(ctx, repo, doer, leak); err != nil {
+			return err
+		}
+	}
+
+	if req.NewCommitID == "" {
+		return secretscan_model.UpdateLastHistoryScan(ctx, repo.ID)
+	}
+	return nil
+}
+
+// recordLeak opens an alert for the leak unless the secret has already been reported.
+// Leaked kmup access tokens are revoked immediately.
+func recordLeak(ctx context.Context, repo *repo_model.Repository, doer *user_model.User, leak *Leak) error {
+	state := secretscan_model.AlertStateOpen
+	revoked, err := revokeLeakedAccessToken(ctx, repo, leak)
+	if err != nil {
+		return err
+	} else if revoked {
+		state = secretscan_model.AlertStateRevoked
+	}
+	return insertAlert(ctx, repo, doer, leak, state)
+}
+
+// insertAlert opens an alert in the given state for the leak unless the secret has already been reported
+func insertAlert(ctx context.Context, repo *repo_model.Repository, doer *user_model.User, leak *Leak, state secretscan_model.AlertState) error {
+	alert := &secretscan_model.Alert{
+		RepoID:         repo.ID,
+		SecretHash:     secretscan_model.HashSecret(leak.Secret),
+		RuleID:         leak.RuleID,
+		Description:    leak.Description,
+		RedactedSecret: leak.Redacted(),
+		CommitID:       leak.CommitID,
+		Path:           leak.Path,
+		Line:           leak.Line,
+		State:          state,
+	}
+
+	inserted, err := secretscan_model.InsertAlertIfNotExist(ctx, alert)
+	if err != nil {
+		return err
+	}
+	if inserted {
+		notify_service.SecretScanningAlert(ctx, doer, repo, alert, AlertActionCreated)
+	}
+	return nil
+}
+
+// revokeLeakedAccessToken revokes the leaked secret if it's a kmup access token, it returns whether a token has been revoked
+func revokeLeakedAccessToken(ctx context.Context, repo *repo_model.Repository, leak *Leak) (bool, error) {
+	if leak.RuleID != secretscan.KmupAccessTokenRuleID {
+		return false, nil
+	}
+	token, err := auth_model.RevokeAccessTokenBySHA(ctx, leak.Secret)
+	if err != nil {
+		if auth_model.IsErrAccessTokenNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	log.Info("Access token %d of user %d has been revoked because it was leaked in repo %s", token.ID, token.UID, repo.FullName())
+	return true, nil
+}
+
+// RevokeLeakedAccessTokens revokes the kmup access tokens among the leaks found by the push protection.
+// A token is revoked even if the push gets rejected, since it has left the machine of the pusher and may have been pushed elsewhere too.
+// If the push is accepted, the alerts of the revoked tokens are opened now since the queue can't revoke them again after the push.
+// It returns the number of the revoked tokens.
+func RevokeLeakedAccessTokens(ctx context.Context, repo *repo_model.Repository, doer *user_model.User, leaks []*Leak, accepted bool) (int, error) {
+	count := 0
+	for _, leak := range leaks {
+		revoked, err := revokeLeakedAccessToken(ctx, repo, leak)
+		if err != nil {
+			return count, err
+		} else if !revoked {
+			continue
+		}
+		count++
+
+		if accepted {
+			if err := insertAlert(ctx, repo, doer, leak, secretscan_model.AlertStateRevoked); err != nil {
+				return count, err
+			}
+		}
+	}
+	return count, nil
+}
+
+// UpdateAlertState changes the state of an alert
+func UpdateAlertState(ctx context.Context, doer *user_model.User, repo *repo_model.Repository, alert *secretscan_model.Alert, state secretscan_model.AlertState) error {
+	if alert.State == state {
+		return nil
+	}
+	wasResolved := alert.State.IsResolved()
+	if err := secretscan_model.UpdateAlertState(ctx, alert, state, doer.ID); err != nil {
+		return err
+	}
+
+	switch {
+	case !wasResolved && state.IsResolved():
+		notify_service.SecretScanningAlert(ctx, doer, repo, alert, AlertActionResolved)
+	case wasResolved && !state.IsResolved():
+		notify_service.SecretScanningAlert(ctx, doer, repo, alert, AlertActionReopened)
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	actions_model "github.com/kumose/kmup/models/actions"
+	"github.com/kumose/kmup/models/db"
+	git_model "github.com/kumose/kmup/models/git"
+	packages_model "github.com/kumose/kmup/models/packages"
+	repo_model "github.com/kumose/kmup/models/repo"
+	"github.com/kumose/kmup/modules/container"
+	"github.com/kumose/kmup/modules/log"
+	packages_module "github.com/kumose/kmup/modules/packages"
+	"github.com/kumose/kmup/modules/setting"
+	storage_module "github.com/kumose/kmup/modules/storage"
+
+	"xorm.io/builder"
+)
+
+// GCOptions configures a garbage collection run
+type GCOptions struct {
+	// OlderThan protects recently written objects which may belong to an upload in progress
+	OlderThan time.Duration
+	// DryRun only reports orphaned objects instead of deleting them
+	DryRun bool
+	// BatchSize is the number of objects or rows which are checked before pausing
+	BatchSize int
+	// BatchPause is the pause after every batch to throttle the load on the storage and the database
+	BatchPause time.Duration
+}
+
+// BucketReport is the result of the garbage collection of a storage bucket
+type BucketReport struct {
+	Name         string
+	Objects      int64
+	Size         int64
+	Orphaned     int64 // objects which are not referenced by a database row
+	OrphanedSize int64
+	Deleted      int64
+	Missing      int64 // database rows which reference a non-existing object
+}
+
+type bucket struct {
+	name    string
+	storer  storage_module.ObjectStorage
+	enabled bool
+	// skip excludes objects which are managed by other cleanup tasks
+	skip func(p string) bool
+	// isReferenced checks if a database row references the object
+	isReferenced func(ctx context.Context, p string) (bool, error)
+	// iterateReferences calls the function with the object path of every database row
+	iterateReferences func(ctx context.Context, f func(p string) error) error
+}
+
+func buckets() []*bucket {
+	return []*bucket{
+		{
+			name:    "packages",
+			storer:  storage_module.Packages,
+			enabled: setting.Packages.Enabled,
+			isReferenced: func(ctx context.Context, p string) (bool, error) {
+				key, err := packages_module.RelativePathToKey(p)
+				if err != nil {
+					return false, nil
+				}
+				return packages_model.ExistPackageBlobWithSHA(ctx, string(key))
+			},
+			iterateReferences: func(ctx context.Context, f func(p string) error) error {
+				return db.Iterate(ctx, nil, func(ctx context.Context, pb *packages_model.PackageBlob) error {
+					return f(packages_module.KeyToRelativePath(packages_module.BlobHash256Key(pb.HashSHA256)))
+				})
+			},
+		},
+		{
+			name:    "lfs",
+			storer:  storage_module.LFS,
+			enabled: setting.LFS.StartServer,
+			isReferenced: func(ctx context.Context, p string) (bool, error) {
+				// The oid of an LFS object is its path without the separators
+				return git_model.ExistsLFSObject(ctx, strings.ReplaceAll(strings.ReplaceAll(p, "\\", ""), "/", ""))
+			},
+			iterateReferences: func(ctx context.Context, f func(p string) error) error {
+				seen := make(container.Set[string])
+				return db.Iterate(ctx, nil, func(ctx context.Context, m *git_model.LFSMetaObject) error {
+					if !seen.Add(m.Oid) {
+						return nil
+					}
+					return f(m.RelativePath())
+				})
+			},
+		},
+		{
+			name:    "attachments",
+			storer:  storage_module.Attachments,
+			enabled: setting.Attachment.Enabled,
+			isReferenced: func(ctx context.Context, p string) (bool, error) {
+				return repo_model.ExistAttachmentsByUUID(ctx, path.Base(p))
+			},
+			iterateReferences: func(ctx context.Context, f func(p string) error) error {
+				return db.Iterate(ctx, nil, func(ctx context.Context, a *repo_model.Attachment) error {
+					if a.CustomDownloadURL != "" {
+						return nil
+					}
+					return f(a.RelativePath())
+				})
+			},
+		},
+		{
+			name:    "actions artifacts",
+			storer:  storage_module.ActionsArtifacts,
+			enabled: setting.Actions.Enabled,
+			skip: func(p string) bool {
+				// chunks of uploads in progress are removed by the actions cleanup
+				return strings.HasPrefix(p, "tmp")
+			},
+			isReferenced: func(ctx context.Context, p string) (bool, error) {
+				return actions_model.ExistsArtifactWithStoragePath(ctx, p)
+			},
+			iterateReferences: func(ctx context.Context, f func(p string) error) error {
+				cond := builder.Eq{"status": actions_model.ArtifactStatusUploadConfirmed}.And(builder.Neq{"storage_path": ""})
+				return db.Iterate(ctx, cond, func(ctx context.Context, a *actions_model.ActionArtifact) error {
+					return f(a.StoragePath)
+				})
+			},
+		},
+	}
+}
+
+type throttle struct {
+	ctx   context.Context
+	size  int
+	pause time.Duration
+	count int
+}
+
+// wait pauses after every batch and aborts if the context is done
+func (t *throttle) wait() error {
+	t.count++
+	if t.size > 0 && t.pause > 0 && t.count%t.size == 0 {
+		select {
+		case <-t.ctx.Done():
+		case <-time.After(t.pause):
+		}
+	}
+	return t.ctx.Err()
+}
+
+// GarbageCollect walks the storage buckets and cross-checks their objects with the database rows referencing them.
+// Objects without a row are deleted (or only reported in a dry run), rows without an object are reported.
+func GarbageCollect(ctx context.Context, opts *GCOptions) ([]*BucketReport, error) {
+	t := &throttle{ctx: ctx, size: opts.BatchSize, pause: opts.BatchPause}
+	deadline := time.Now().Add(-opts.OlderThan)
+
+	reports := make([]*BucketReport, 0, 4)
+	for _, b := range buckets() {
+		if !b.enabled {
+			continue
+		}
+
+		report := &BucketReport{Name: b.name}
+		reports = append(reports, report)
+
+		var orphans []string
+		err := b.storer.IterateObjects("", func(p string, obj storage_module.Object) error {
+			defer obj.Close()
+
+			if err := t.wait(); err != nil {
+				return err
+			}
+			if b.skip != nil && b.skip(p) {
+				return nil
+			}
+
+			stat, err := obj.Stat()
+			if err != nil {
+				return err
+			}
+			report.Objects++
+			report.Size += stat.Size()
+
+			referenced, err := b.isReferenced(ctx, p)
+			if err != nil {
+				return err
+			}
+			if referenced || stat.ModTime().After(deadline) {
+				return nil
+			}
+
+			report.Orphaned++
+			report.OrphanedSize += stat.Size()
+			orphans = append(orphans, p)
+			return nil
+		})
+		if err != nil {
+			return reports, err
+		}
+
+		for _, p := range orphans {
+			if opts.DryRun {
+				log.Info("Orphaned object in %s storage: %s", b.name, p)
+				continue
+			}
+			if err := b.storer.Delete(p); err != nil {
+				log.Error("Unable to delete orphaned object %s from %s storage: %v", p, b.name, err)
+				continue
+			}
+			report.Deleted++
+		}
+
+		err = b.iterateReferences(ctx, func(p string) error {
+			if err := t.wait(); err != nil {
+				return err
+			}
+			if _, err := b.storer.Stat(p); err != nil {
+				if !errors.Is(err, os.ErrNotExist) {
+					return err
+				}
+				log.Warn("Missing object in %s storage: %s", b.name, p)
+				report.Missing++
+			}
+			return nil
+		})
+		if err != nil {
+			return reports, err
+		}
+
+		log.Info("Storage %s: %d objects (%d bytes), %d orphaned (%d bytes), %d deleted, %d missing", b.name, report.Objects, report.Size, report.Orphaned, report.OrphanedSize, report.Deleted, report.Missing)
+	}
+	return reports, nil
+}
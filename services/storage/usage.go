@@ -0,0 +1,117 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package storage
+
+import (
+	"context"
+	"sort"
+
+	actions_model "github.com/kumose/kmup/models/actions"
+	git_model "github.com/kumose/kmup/models/git"
+	packages_model "github.com/kumose/kmup/models/packages"
+	repo_model "github.com/kumose/kmup/models/repo"
+	user_model "github.com/kumose/kmup/models/user"
+)
+
+// OwnerUsage is the storage used by an owner
+type OwnerUsage struct {
+	OwnerID     int64
+	Owner       *user_model.User // nil if the owner does not exist anymore
+	Git         int64
+	LFS         int64
+	Packages    int64
+	Attachments int64
+	Artifacts   int64
+}
+
+// Total returns the storage used in all buckets
+func (u *OwnerUsage) Total() int64 {
+	return u.Git + u.LFS + u.Packages + u.Attachments + u.Artifacts
+}
+
+// GetOwnerUsages computes the storage usage of every owner, the owners using the most storage come first.
+// Content addressed objects like LFS objects and package blobs are counted once per owner.
+func GetOwnerUsages(ctx context.Context) ([]*OwnerUsage, error) {
+	usages := make(map[int64]*OwnerUsage)
+	add := func(sizes map[int64]int64, field func(u *OwnerUsage) *int64) {
+		for ownerID, size := range sizes {
+			u, ok := usages[ownerID]
+			if !ok {
+				u = &OwnerUsage{OwnerID: ownerID}
+				usages[ownerID] = u
+			}
+			*field(u) += size
+		}
+	}
+
+	sizes, err := repo_model.GetGitSizesByOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	add(sizes, func(u *OwnerUsage) *int64 { return &u.Git })
+
+	sizes, err = git_model.GetLFSSizesByOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	add(sizes, func(u *OwnerUsage) *int64 { return &u.LFS })
+
+	sizes, err = packages_model.GetBlobSizesByOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	add(sizes, func(u *OwnerUsage) *int64 { return &u.Packages })
+
+	sizes, err = repo_model.GetAttachmentSizesByOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	add(sizes, func(u *OwnerUsage) *int64 { return &u.Attachments })
+
+	sizes, err = actions_model.GetArtifactSizesByOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	add(sizes, func(u *OwnerUsage) *int64 { return &u.Artifacts })
+
+	ownerIDs := make([]int64, 0, len(usages))
+	result := make([]*OwnerUsage, 0, len(usages))
+	for ownerID, u := range usages {
+		if u.Total() == 0 {
+			continue
+		}
+		ownerIDs = append(ownerIDs, ownerID)
+		result = append(result, u)
+	}
+
+	owners, err := user_model.GetUsersMapByIDs(ctx, ownerIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range result {
+		u.Owner = owners[u.OwnerID]
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Total() != result[j].Total() {
+			return result[i].Total() > result[j].Total()
+		}
+		return result[i].OwnerID < result[j].OwnerID
+	})
+	return result, nil
+}
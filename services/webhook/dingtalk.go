@@ -180,8 +180,9 @@ func (dc dingtalkConvertor) Release(p *api.ReleasePayload) (DingtalkPayload, err
 
 func (dc dingtalkConvertor) Package(p *api.PackagePayload) (DingtalkPayload, error) {
 	text, _ := getPackagePayloadInfo(p, noneLinkFormatter, true)
+	link, _ := getPackagePayloadLink(p)
 
-	return createDingtalkPayload(text, text, "view package", p.Package.HTMLURL), nil
+	return createDingtalkPayload(text, text, "view package", link), nil
 }
 
 func (dc dingtalkConvertor) Status(p *api.CommitStatusPayload) (DingtalkPayload, error) {
@@ -190,6 +191,18 @@ func (dc dingtalkConvertor) Status(p *api.CommitStatusPayload) (DingtalkPayload,
 	return createDingtalkPayload(text, text, "Status Changed", p.TargetURL), nil
 }
 
+func (dc dingtalkConvertor) MergeGroup(p *api.MergeGroupPayload) (DingtalkPayload, error) {
+	text, _ := getMergeGroupPayloadInfo(p, noneLinkFormatter, true)
+
+	return createDingtalkPayload(text, text, "Merge Group", p.MergeGroup.PullRequest.HTMLURL), nil
+}
+
+func (dc dingtalkConvertor) SecretScanningAlert(p *api.SecretScanningAlertPayload) (DingtalkPayload, error) {
+	text, _ := getSecretScanningAlertPayloadInfo(p, noneLinkFormatter, true)
+
+	return createDingtalkPayload(text, text, "Secret Scanning Alert", p.Alert.HTMLURL), nil
+}
+
 func (dingtalkConvertor) WorkflowRun(p *api.WorkflowRunPayload) (DingtalkPayload, error) {
 	text, _ := getWorkflowRunPayloadInfo(p, noneLinkFormatter, true)
 
@@ -282,8 +282,9 @@ func (d discordConvertor) Release(p *api.ReleasePayload) (DiscordPayload, error)
 
 func (d discordConvertor) Package(p *api.PackagePayload) (DiscordPayload, error) {
 	text, color := getPackagePayloadInfo(p, noneLinkFormatter, false)
+	link, _ := getPackagePayloadLink(p)
 
-	return d.createPayload(p.Sender, text, "", p.Package.HTMLURL, color), nil
+	return d.createPayload(p.Sender, text, "", link, color), nil
 }
 
 func (d discordConvertor) Status(p *api.CommitStatusPayload) (DiscordPayload, error) {
@@ -292,6 +293,18 @@ func (d discordConvertor) Status(p *api.CommitStatusPayload) (DiscordPayload, er
 	return d.createPayload(p.Sender, text, "", p.TargetURL, color), nil
 }
 
+func (d discordConvertor) MergeGroup(p *api.MergeGroupPayload) (DiscordPayload, error) {
+	text, color := getMergeGroupPayloadInfo(p, noneLinkFormatter, false)
+
+	return d.createPayload(p.Sender, text, "", p.MergeGroup.PullRequest.HTMLURL, color), nil
+}
+
+func (d discordConvertor) SecretScanningAlert(p *api.SecretScanningAlertPayload) (DiscordPayload, error) {
+	text, color := getSecretScanningAlertPayloadInfo(p, noneLinkFormatter, false)
+
+	return d.createPayload(p.Sender, text, "", p.Alert.HTMLURL, color), nil
+}
+
 func (d discordConvertor) WorkflowRun(p *api.WorkflowRunPayload) (DiscordPayload, error) {
 	text, color := getWorkflowRunPayloadInfo(p, noneLinkFormatter, false)
 
@@ -192,6 +192,18 @@ func (fc feishuConvertor) Status(p *api.CommitStatusPayload) (FeishuPayload, err
 	return newFeishuTextPayload(text), nil
 }
 
+func (fc feishuConvertor) MergeGroup(p *api.MergeGroupPayload) (FeishuPayload, error) {
+	text, _ := getMergeGroupPayloadInfo(p, noneLinkFormatter, true)
+
+	return newFeishuTextPayload(text), nil
+}
+
+func (fc feishuConvertor) SecretScanningAlert(p *api.SecretScanningAlertPayload) (FeishuPayload, error) {
+	text, _ := getSecretScanningAlertPayloadInfo(p, noneLinkFormatter, true)
+
+	return newFeishuTextPayload(text), nil
+}
+
 func (feishuConvertor) WorkflowRun(p *api.WorkflowRunPayload) (FeishuPayload, error) {
 	text, _ := getWorkflowRunPayloadInfo(p, noneLinkFormatter, true)
 
@@ -307,7 +307,25 @@ func getIssueCommentPayloadInfo(p *api.IssueCommentPayload, linkFormatter linkFo
 	return text, issueTitle, color
 }
 
+// getPackagePayloadLink returns the link and name of the package or, for a cleanup run, of the owner's package list
+func getPackagePayloadLink(p *api.PackagePayload) (link, name string) {
+	if p.Package == nil {
+		return p.Sender.HTMLURL + "/-/packages", p.Sender.UserName
+	}
+	return p.Package.HTMLURL, p.Package.Name
+}
+
 func getPackagePayloadInfo(p *api.PackagePayload, linkFormatter linkFormatter, withSender bool) (text string, color int) {
+	if p.Action == api.HookPackageCleanedUp {
+		link, name := getPackagePayloadLink(p)
+		text = fmt.Sprintf("Package cleanup: %d %s versions of %s removed, %s freed",
+			p.CleanupRun.VersionCount, p.CleanupRun.Type, linkFormatter(link, name), base.FileSize(p.CleanupRun.Size))
+		if p.CleanupRun.FailedCount > 0 {
+			text += fmt.Sprintf(", %d failed", p.CleanupRun.FailedCount)
+		}
+		return text, yellowColor
+	}
+
 	refLink := linkFormatter(p.Package.HTMLURL, p.Package.Name+":"+p.Package.Version)
 
 	switch p.Action {
@@ -317,6 +335,10 @@ func getPackagePayloadInfo(p *api.PackagePayload, linkFormatter linkFormatter, w
 	case api.HookPackageDeleted:
 		text = "Package deleted: " + refLink
 		color = redColor
+	case api.HookPackageVulnerable:
+		text = fmt.Sprintf("Package vulnerable: %s matches %d new advisories", refLink, len(p.Vulnerabilities))
+		color = orangeColor
+		withSender = false
 	}
 	if withSender {
 		text += " by " + linkFormatter(setting.AppURL+url.PathEscape(p.Sender.UserName), p.Sender.UserName)
@@ -341,6 +363,50 @@ func getStatusPayloadInfo(p *api.CommitStatusPayload, linkFormatter linkFormatte
 	return text, color
 }
 
+func getMergeGroupPayloadInfo(p *api.MergeGroupPayload, linkFormatter linkFormatter, withSender bool) (text string, color int) {
+	repoLink := linkFormatter(p.Repository.HTMLURL, p.Repository.FullName)
+	groupLink := linkFormatter(p.MergeGroup.PullRequest.HTMLURL, fmt.Sprintf("#%d %s", p.MergeGroup.PullRequest.Index, p.MergeGroup.PullRequest.Title))
+
+	switch p.Action {
+	case api.HookMergeGroupChecksRequested:
+		text = fmt.Sprintf("[%s] Merge group checks requested for %s [%s]", repoLink, groupLink, base.ShortSha(p.MergeGroup.HeadSHA))
+		color = yellowColor
+	case api.HookMergeGroupDestroyed:
+		text = fmt.Sprintf("[%s] Merge group %s for %s", repoLink, p.Reason, groupLink)
+		color = greyColor
+		if p.Reason == "merged" {
+			color = purpleColor
+		}
+	}
+	if withSender {
+		text += " by " + linkFormatter(setting.AppURL+url.PathEscape(p.Sender.UserName), p.Sender.UserName)
+	}
+
+	return text, color
+}
+
+func getSecretScanningAlertPayloadInfo(p *api.SecretScanningAlertPayload, linkFormatter linkFormatter, withSender bool) (text string, color int) {
+	repoLink := linkFormatter(p.Repository.HTMLURL, p.Repository.FullName)
+	alertLink := linkFormatter(p.Alert.HTMLURL, fmt.Sprintf("#%d %s", p.Alert.ID, p.Alert.Description))
+
+	switch p.Action {
+	case api.HookSecretScanningAlertCreated:
+		text = fmt.Sprintf("[%s] Secret scanning alert %s created in %s [%s]", repoLink, alertLink, p.Alert.Path, base.ShortSha(p.Alert.CommitID))
+		color = redColor
+	case api.HookSecretScanningAlertResolved:
+		text = fmt.Sprintf("[%s] Secret scanning alert %s resolved as %s", repoLink, alertLink, p.Alert.State)
+		color = greenColor
+	case api.HookSecretScanningAlertReopened:
+		text = fmt.Sprintf("[%s] Secret scanning alert %s reopened", repoLink, alertLink)
+		color = orangeColor
+	}
+	if withSender {
+		text += " by " + linkFormatter(setting.AppURL+url.PathEscape(p.Sender.UserName), p.Sender.UserName)
+	}
+
+	return text, color
+}
+
 func getWorkflowRunPayloadInfo(p *api.WorkflowRunPayload, linkFormatter linkFormatter, withSender bool) (text string, color int) {
 	description := p.WorkflowRun.Conclusion
 	if description == "" {
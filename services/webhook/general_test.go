@@ -624,6 +624,33 @@ func TestGetReleasePayloadInfo(t *testing.T) {
 	}
 }
 
+func TestGetPackagePayloadInfo(t *testing.T) {
+	p := packageTestPayload()
+
+	text, color := getPackagePayloadInfo(p, noneLinkFormatter, true)
+	assert.Equal(t, "Package created: KmupContainer:latest by user1", text)
+	assert.Equal(t, greenColor, color)
+
+	p.Action = api.HookPackageCleanedUp
+	p.Package = nil
+	p.Sender.HTMLURL = "http://localhost:3326/user1"
+	p.CleanupRun = &api.PackageCleanupRun{
+		Type:         "container",
+		VersionCount: 2,
+		FailedCount:  1,
+		Size:         2048,
+		Versions:     []string{"KmupContainer@v1", "KmupContainer@v2"},
+	}
+
+	text, color = getPackagePayloadInfo(p, noneLinkFormatter, true)
+	assert.Equal(t, "Package cleanup: 2 container versions of user1 removed, 2.0 KiB freed, 1 failed", text)
+	assert.Equal(t, yellowColor, color)
+
+	link, name := getPackagePayloadLink(p)
+	assert.Equal(t, "http://localhost:3326/user1/-/packages", link)
+	assert.Equal(t, "user1", name)
+}
+
 func TestGetIssueCommentPayloadInfo(t *testing.T) {
 	p := pullRequestCommentTestPayload()
 
@@ -246,7 +246,7 @@ func (m matrixConvertor) Repository(p *api.RepositoryPayload) (MatrixPayload, er
 
 func (m matrixConvertor) Package(p *api.PackagePayload) (MatrixPayload, error) {
 	senderLink := htmlLinkFormatter(setting.AppURL+p.Sender.UserName, p.Sender.UserName)
-	packageLink := htmlLinkFormatter(p.Package.HTMLURL, p.Package.Name)
+	packageLink := htmlLinkFormatter(getPackagePayloadLink(p))
 	var text string
 
 	switch p.Action {
@@ -254,6 +254,10 @@ func (m matrixConvertor) Package(p *api.PackagePayload) (MatrixPayload, error) {
 		text = fmt.Sprintf("[%s] Package published by %s", packageLink, senderLink)
 	case api.HookPackageDeleted:
 		text = fmt.Sprintf("[%s] Package deleted by %s", packageLink, senderLink)
+	case api.HookPackageVulnerable:
+		text = fmt.Sprintf("[%s] Package matches %d new advisories", packageLink, len(p.Vulnerabilities))
+	case api.HookPackageCleanedUp:
+		text, _ = getPackagePayloadInfo(p, htmlLinkFormatter, false)
 	}
 
 	return m.newPayload(text)
@@ -266,6 +270,18 @@ func (m matrixConvertor) Status(p *api.CommitStatusPayload) (MatrixPayload, erro
 	return m.newPayload(text)
 }
 
+func (m matrixConvertor) MergeGroup(p *api.MergeGroupPayload) (MatrixPayload, error) {
+	text, _ := getMergeGroupPayloadInfo(p, htmlLinkFormatter, true)
+
+	return m.newPayload(text)
+}
+
+func (m matrixConvertor) SecretScanningAlert(p *api.SecretScanningAlertPayload) (MatrixPayload, error) {
+	text, _ := getSecretScanningAlertPayloadInfo(p, htmlLinkFormatter, true)
+
+	return m.newPayload(text)
+}
+
 func (m matrixConvertor) WorkflowRun(p *api.WorkflowRunPayload) (MatrixPayload, error) {
 	text, _ := getWorkflowRunPayloadInfo(p, htmlLinkFormatter, true)
 
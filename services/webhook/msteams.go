@@ -306,15 +306,16 @@ func (m msteamsConvertor) Release(p *api.ReleasePayload) (MSTeamsPayload, error)
 
 func (m msteamsConvertor) Package(p *api.PackagePayload) (MSTeamsPayload, error) {
 	title, color := getPackagePayloadInfo(p, noneLinkFormatter, false)
+	link, name := getPackagePayloadLink(p)
 
 	return createMSTeamsPayload(
 		p.Repository,
 		p.Sender,
 		title,
 		"",
-		p.Package.HTMLURL,
+		link,
 		color,
-		&MSTeamsFact{"Package:", p.Package.Name},
+		&MSTeamsFact{"Package:", name},
 	), nil
 }
 
@@ -332,6 +333,34 @@ func (m msteamsConvertor) Status(p *api.CommitStatusPayload) (MSTeamsPayload, er
 	), nil
 }
 
+func (m msteamsConvertor) MergeGroup(p *api.MergeGroupPayload) (MSTeamsPayload, error) {
+	title, color := getMergeGroupPayloadInfo(p, noneLinkFormatter, false)
+
+	return createMSTeamsPayload(
+		p.Repository,
+		p.Sender,
+		title,
+		"",
+		p.MergeGroup.PullRequest.HTMLURL,
+		color,
+		&MSTeamsFact{"MergeGroup:", p.MergeGroup.HeadRef},
+	), nil
+}
+
+func (m msteamsConvertor) SecretScanningAlert(p *api.SecretScanningAlertPayload) (MSTeamsPayload, error) {
+	title, color := getSecretScanningAlertPayloadInfo(p, noneLinkFormatter, false)
+
+	return createMSTeamsPayload(
+		p.Repository,
+		p.Sender,
+		title,
+		"",
+		p.Alert.HTMLURL,
+		color,
+		&MSTeamsFact{"Alert:", p.Alert.RuleID},
+	), nil
+}
+
 func (msteamsConvertor) WorkflowRun(p *api.WorkflowRunPayload) (MSTeamsPayload, error) {
 	title, color := getWorkflowRunPayloadInfo(p, noneLinkFormatter, false)
 
@@ -27,7 +27,9 @@ import (
 	packages_model "github.com/kumose/kmup/models/packages"
 	"github.com/kumose/kmup/models/perm"
 	access_model "github.com/kumose/kmup/models/perm/access"
+	pull_model "github.com/kumose/kmup/models/pull"
 	repo_model "github.com/kumose/kmup/models/repo"
+	secretscan_model "github.com/kumose/kmup/models/secretscan"
 	user_model "github.com/kumose/kmup/models/user"
 	"github.com/kumose/kmup/modules/git"
 	"github.com/kumose/kmup/modules/gitrepo"
@@ -39,6 +41,7 @@ import (
 	webhook_module "github.com/kumose/kmup/modules/webhook"
 	"github.com/kumose/kmup/services/convert"
 	notify_service "github.com/kumose/kmup/services/notify"
+	vulnerability_service "github.com/kumose/kmup/services/packages/vulnerability"
 )
 
 func init() {
@@ -953,6 +956,52 @@ func (m *webhookNotifier) CreateCommitStatus(ctx context.Context, repo *repo_mod
 	}
 }
 
+func (m *webhookNotifier) MergeGroupChecksRequested(ctx context.Context, doer *user_model.User, pr *issues_model.PullRequest, entry *pull_model.MergeQueueEntry) {
+	m.sendMergeGroupPayload(ctx, doer, pr, entry, api.HookMergeGroupChecksRequested, "")
+}
+
+func (m *webhookNotifier) MergeGroupDestroyed(ctx context.Context, doer *user_model.User, pr *issues_model.PullRequest, entry *pull_model.MergeQueueEntry, reason string) {
+	m.sendMergeGroupPayload(ctx, doer, pr, entry, api.HookMergeGroupDestroyed, reason)
+}
+
+func (m *webhookNotifier) sendMergeGroupPayload(ctx context.Context, doer *user_model.User, pr *issues_model.PullRequest, entry *pull_model.MergeQueueEntry, action api.HookMergeGroupAction, reason string) {
+	if err := pr.LoadIssue(ctx); err != nil {
+		log.Error("LoadIssue: %v", err)
+		return
+	}
+	if err := pr.Issue.LoadRepo(ctx); err != nil {
+		log.Error("pr.Issue.LoadRepo: %v", err)
+		return
+	}
+
+	if err := PrepareWebhooks(ctx, EventSource{Repository: pr.Issue.Repo}, webhook_module.HookEventMergeGroup, &api.MergeGroupPayload{
+		Action: action,
+		Reason: reason,
+		MergeGroup: &api.MergeGroup{
+			HeadSHA:     entry.GroupCommitID,
+			HeadRef:     git.BranchPrefix + entry.GroupBranchName(pr.Index),
+			BaseSHA:     entry.BaseCommitID,
+			BaseRef:     git.BranchPrefix + entry.BaseBranch,
+			PullRequest: convert.ToAPIPullRequest(ctx, pr, doer),
+		},
+		Repository: convert.ToRepo(ctx, pr.Issue.Repo, access_model.Permission{AccessMode: perm.AccessModeOwner}),
+		Sender:     convert.ToUser(ctx, doer, nil),
+	}); err != nil {
+		log.Error("PrepareWebhooks [pull_id: %d]: %v", pr.ID, err)
+	}
+}
+
+func (m *webhookNotifier) SecretScanningAlert(ctx context.Context, doer *user_model.User, repo *repo_model.Repository, alert *secretscan_model.Alert, action string) {
+	if err := PrepareWebhooks(ctx, EventSource{Repository: repo}, webhook_module.HookEventSecretScanningAlert, &api.SecretScanningAlertPayload{
+		Action:     api.HookSecretScanningAlertAction(action),
+		Alert:      convert.ToSecretScanningAlert(ctx, repo, alert),
+		Repository: convert.ToRepo(ctx, repo, access_model.Permission{AccessMode: perm.AccessModeOwner}),
+		Sender:     convert.ToUser(ctx, doer, nil),
+	}); err != nil {
+		log.Error("PrepareWebhooks [repo_id: %d, alert_id: %d]: %v", repo.ID, alert.ID, err)
+	}
+}
+
 func (m *webhookNotifier) SyncCreateRef(ctx context.Context, pusher *user_model.User, repo *repo_model.Repository, refFullName git.RefName, refID string) {
 	m.CreateRef(ctx, pusher, repo, refFullName, refID)
 }
@@ -962,14 +1011,48 @@ func (m *webhookNotifier) SyncDeleteRef(ctx context.Context, pusher *user_model.
 }
 
 func (m *webhookNotifier) PackageCreate(ctx context.Context, doer *user_model.User, pd *packages_model.PackageDescriptor) {
-	notifyPackage(ctx, doer, pd, api.HookPackageCreated)
+	notifyPackage(ctx, doer, pd, &api.PackagePayload{Action: api.HookPackageCreated})
 }
 
 func (m *webhookNotifier) PackageDelete(ctx context.Context, doer *user_model.User, pd *packages_model.PackageDescriptor) {
-	notifyPackage(ctx, doer, pd, api.HookPackageDeleted)
+	notifyPackage(ctx, doer, pd, &api.PackagePayload{Action: api.HookPackageDeleted})
+}
+
+func (m *webhookNotifier) PackageVulnerable(ctx context.Context, pd *packages_model.PackageDescriptor, pvs []*packages_model.PackageVulnerability) {
+	vulnerabilities, err := vulnerability_service.LoadVulnerabilities(ctx, pd.Package.Type, pvs)
+	if err != nil {
+		log.Error("LoadVulnerabilities: %v", err)
+		return
+	}
+
+	apiVulnerabilities := make([]*api.PackageVulnerability, 0, len(vulnerabilities))
+	for _, v := range vulnerabilities {
+		apiVulnerabilities = append(apiVulnerabilities, convert.ToPackageVulnerability(v))
+	}
+
+	notifyPackage(ctx, pd.Owner, pd, &api.PackagePayload{
+		Action:          api.HookPackageVulnerable,
+		Vulnerabilities: apiVulnerabilities,
+	})
 }
 
-func notifyPackage(ctx context.Context, sender *user_model.User, pd *packages_model.PackageDescriptor, action api.HookPackageAction) {
+func (m *webhookNotifier) PackageCleanupRun(ctx context.Context, owner *user_model.User, run *packages_model.PackageCleanupRun) {
+	var org *api.Organization
+	if owner.IsOrganization() {
+		org = convert.ToOrganization(ctx, organization.OrgFromUser(owner))
+	}
+
+	if err := PrepareWebhooks(ctx, EventSource{Owner: owner}, webhook_module.HookEventPackage, &api.PackagePayload{
+		Action:       api.HookPackageCleanedUp,
+		Organization: org,
+		Sender:       convert.ToUser(ctx, owner, nil),
+		CleanupRun:   convert.ToPackageCleanupRun(run),
+	}); err != nil {
+		log.Error("PrepareWebhooks: %v", err)
+	}
+}
+
+func notifyPackage(ctx context.Context, sender *user_model.User, pd *packages_model.PackageDescriptor, payload *api.PackagePayload) {
 	source := EventSource{
 		Repository: pd.Repository,
 		Owner:      pd.Owner,
@@ -986,12 +1069,11 @@ func notifyPackage(ctx context.Context, sender *user_model.User, pd *packages_mo
 		org = convert.ToOrganization(ctx, organization.OrgFromUser(pd.Owner))
 	}
 
-	if err := PrepareWebhooks(ctx, source, webhook_module.HookEventPackage, &api.PackagePayload{
-		Action:       action,
-		Package:      apiPackage,
-		Organization: org,
-		Sender:       convert.ToUser(ctx, sender, nil),
-	}); err != nil {
+	payload.Package = apiPackage
+	payload.Organization = org
+	payload.Sender = convert.ToUser(ctx, sender, nil)
+
+	if err := PrepareWebhooks(ctx, source, webhook_module.HookEventPackage, payload); err != nil {
 		log.Error("PrepareWebhooks: %v", err)
 	}
 }
@@ -128,6 +128,14 @@ func (pc packagistConvertor) Status(_ *api.CommitStatusPayload) (PackagistPayloa
 	return PackagistPayload{}, nil
 }
 
+func (pc packagistConvertor) MergeGroup(_ *api.MergeGroupPayload) (PackagistPayload, error) {
+	return PackagistPayload{}, nil
+}
+
+func (pc packagistConvertor) SecretScanningAlert(_ *api.SecretScanningAlertPayload) (PackagistPayload, error) {
+	return PackagistPayload{}, nil
+}
+
 func (pc packagistConvertor) WorkflowRun(_ *api.WorkflowRunPayload) (PackagistPayload, error) {
 	return PackagistPayload{}, nil
 }
@@ -43,6 +43,8 @@ type payloadConvertor[T any] interface {
 	Wiki(*api.WikiPayload) (T, error)
 	Package(*api.PackagePayload) (T, error)
 	Status(*api.CommitStatusPayload) (T, error)
+	MergeGroup(*api.MergeGroupPayload) (T, error)
+	SecretScanningAlert(*api.SecretScanningAlertPayload) (T, error)
 	WorkflowRun(*api.WorkflowRunPayload) (T, error)
 	WorkflowJob(*api.WorkflowJobPayload) (T, error)
 }
@@ -96,6 +98,10 @@ func newPayload[T any](rc payloadConvertor[T], data []byte, event webhook_module
 		return convertUnmarshalledJSON(rc.Package, data)
 	case webhook_module.HookEventStatus:
 		return convertUnmarshalledJSON(rc.Status, data)
+	case webhook_module.HookEventMergeGroup:
+		return convertUnmarshalledJSON(rc.MergeGroup, data)
+	case webhook_module.HookEventSecretScanningAlert:
+		return convertUnmarshalledJSON(rc.SecretScanningAlert, data)
 	case webhook_module.HookEventWorkflowRun:
 		return convertUnmarshalledJSON(rc.WorkflowRun, data)
 	case webhook_module.HookEventWorkflowJob:
@@ -173,6 +173,18 @@ func (s slackConvertor) Status(p *api.CommitStatusPayload) (SlackPayload, error)
 	return s.createPayload(text, nil), nil
 }
 
+func (s slackConvertor) MergeGroup(p *api.MergeGroupPayload) (SlackPayload, error) {
+	text, _ := getMergeGroupPayloadInfo(p, SlackLinkFormatter, true)
+
+	return s.createPayload(text, nil), nil
+}
+
+func (s slackConvertor) SecretScanningAlert(p *api.SecretScanningAlertPayload) (SlackPayload, error) {
+	text, _ := getSecretScanningAlertPayloadInfo(p, SlackLinkFormatter, true)
+
+	return s.createPayload(text, nil), nil
+}
+
 func (s slackConvertor) WorkflowRun(p *api.WorkflowRunPayload) (SlackPayload, error) {
 	text, _ := getWorkflowRunPayloadInfo(p, SlackLinkFormatter, true)
 
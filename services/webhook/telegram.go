@@ -194,6 +194,18 @@ func (t telegramConvertor) Status(p *api.CommitStatusPayload) (TelegramPayload,
 	return createTelegramPayloadHTML(text), nil
 }
 
+func (t telegramConvertor) MergeGroup(p *api.MergeGroupPayload) (TelegramPayload, error) {
+	text, _ := getMergeGroupPayloadInfo(p, htmlLinkFormatter, true)
+
+	return createTelegramPayloadHTML(text), nil
+}
+
+func (t telegramConvertor) SecretScanningAlert(p *api.SecretScanningAlertPayload) (TelegramPayload, error) {
+	text, _ := getSecretScanningAlertPayloadInfo(p, htmlLinkFormatter, true)
+
+	return createTelegramPayloadHTML(text), nil
+}
+
 func (telegramConvertor) WorkflowRun(p *api.WorkflowRunPayload) (TelegramPayload, error) {
 	text, _ := getWorkflowRunPayloadInfo(p, htmlLinkFormatter, true)
 
@@ -0,0 +1,147 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package integration
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	auth_model "github.com/kumose/kmup/models/auth"
+	packages_model "github.com/kumose/kmup/models/packages"
+	"github.com/kumose/kmup/models/unittest"
+	user_model "github.com/kumose/kmup/models/user"
+	packages_module "github.com/kumose/kmup/modules/packages"
+	"github.com/kumose/kmup/modules/storage"
+	api "github.com/kumose/kmup/modules/structs"
+	storage_service "github.com/kumose/kmup/services/storage"
+	"github.com/kumose/kmup/tests"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIAdminStorage(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+
+	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+	content := []byte("storage accounting content")
+
+	req := NewRequestWithBody(t, "PUT", fmt.Sprintf("/api/packages/%s/generic/storage-package/1.0.0/file.bin", user.Name), bytes.NewReader(content)).
+		AddBasicAuth(user.Name)
+	MakeRequest(t, req, http.StatusCreated)
+
+	pvs, err := packages_model.GetVersionsByPackageType(t.Context(), user.ID, packages_model.TypeGeneric)
+	require.NoError(t, err)
+	require.Len(t, pvs, 1)
+	pfs, err := packages_model.GetFilesByVersionID(t.Context(), pvs[0].ID)
+	require.NoError(t, err)
+	require.Len(t, pfs, 1)
+	pb, err := packages_model.GetBlobByID(t.Context(), pfs[0].BlobID)
+	require.NoError(t, err)
+	blobPath := packages_module.KeyToRelativePath(packages_module.BlobHash256Key(pb.HashSHA256))
+
+	findReport := func(t *testing.T, reports []*storage_service.BucketReport, name string) *storage_service.BucketReport {
+		for _, report := range reports {
+			if report.Name == name {
+				return report
+			}
+		}
+		require.FailNow(t, "missing report", name)
+		return nil
+	}
+
+	t.Run("Usage", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", "/api/v1/admin/storage/usage")
+		MakeRequest(t, req, http.StatusUnauthorized)
+
+		token := getUserToken(t, user.Name, auth_model.AccessTokenScopeReadAdmin)
+		req = NewRequest(t, "GET", "/api/v1/admin/storage/usage").AddTokenAuth(token)
+		MakeRequest(t, req, http.StatusForbidden)
+
+		token = getUserToken(t, "user1", auth_model.AccessTokenScopeReadAdmin)
+		req = NewRequest(t, "GET", "/api/v1/admin/storage/usage").AddTokenAuth(token)
+		resp := MakeRequest(t, req, http.StatusOK)
+
+		var usages []*api.StorageUsage
+		DecodeJSON(t, resp, &usages)
+		assert.NotEmpty(t, usages)
+		assert.Equal(t, fmt.Sprint(len(usages)), resp.Header().Get("X-Total-Count"))
+
+		var usage *api.StorageUsage
+		for i, u := range usages {
+			if i > 0 {
+				assert.GreaterOrEqual(t, usages[i-1].TotalSize, u.TotalSize)
+			}
+			assert.Equal(t, u.GitSize+u.LFSSize+u.PackageSize+u.AttachmentSize+u.ArtifactSize, u.TotalSize)
+			if u.OwnerID == user.ID {
+				usage = u
+			}
+		}
+		require.NotNil(t, usage)
+		assert.Equal(t, user.Name, usage.OwnerName)
+		assert.EqualValues(t, len(content), usage.PackageSize)
+
+		req = NewRequest(t, "GET", "/api/v1/admin/storage/usage?page=1&limit=1").AddTokenAuth(token)
+		resp = MakeRequest(t, req, http.StatusOK)
+		DecodeJSON(t, resp, &usages)
+		assert.Len(t, usages, 1)
+	})
+
+	t.Run("GarbageCollect", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		orphanPath := packages_module.KeyToRelativePath("0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef")
+		_, err := storage.Packages.Save(orphanPath, strings.NewReader("orphan"), -1)
+		require.NoError(t, err)
+
+		reports, err := storage_service.GarbageCollect(t.Context(), &storage_service.GCOptions{DryRun: true})
+		require.NoError(t, err)
+		report := findReport(t, reports, "packages")
+		assert.EqualValues(t, 1, report.Orphaned)
+		assert.EqualValues(t, len("orphan"), report.OrphanedSize)
+		assert.EqualValues(t, 0, report.Deleted)
+		assert.EqualValues(t, 0, report.Missing)
+
+		_, err = storage.Packages.Stat(orphanPath)
+		assert.NoError(t, err)
+
+		reports, err = storage_service.GarbageCollect(t.Context(), &storage_service.GCOptions{BatchSize: 1})
+		require.NoError(t, err)
+		report = findReport(t, reports, "packages")
+		assert.EqualValues(t, 1, report.Orphaned)
+		assert.EqualValues(t, 1, report.Deleted)
+
+		_, err = storage.Packages.Stat(orphanPath)
+		assert.Error(t, err)
+		_, err = storage.Packages.Stat(blobPath)
+		assert.NoError(t, err)
+
+		require.NoError(t, storage.Packages.Delete(blobPath))
+
+		reports, err = storage_service.GarbageCollect(t.Context(), &storage_service.GCOptions{DryRun: true})
+		require.NoError(t, err)
+		report = findReport(t, reports, "packages")
+		assert.EqualValues(t, 0, report.Orphaned)
+		assert.EqualValues(t, 1, report.Missing)
+	})
+}
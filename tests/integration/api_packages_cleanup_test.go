@@ -0,0 +1,202 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package integration
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	auth_model "github.com/kumose/kmup/models/auth"
+	"github.com/kumose/kmup/models/db"
+	packages_model "github.com/kumose/kmup/models/packages"
+	"github.com/kumose/kmup/models/unittest"
+	user_model "github.com/kumose/kmup/models/user"
+	webhook_model "github.com/kumose/kmup/models/webhook"
+	"github.com/kumose/kmup/modules/json"
+	api "github.com/kumose/kmup/modules/structs"
+	webhook_module "github.com/kumose/kmup/modules/webhook"
+	packages_cleanup_service "github.com/kumose/kmup/services/packages/cleanup"
+	"github.com/kumose/kmup/tests"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackageCleanupRuleHistory(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+
+	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+	session := loginUser(t, user.Name)
+	token := getTokenForLoggedInUser(t, session, auth_model.AccessTokenScopeAll)
+
+	req := NewRequestWithJSON(t, "POST", "/api/v1/user/hooks", api.CreateHookOption{
+		Type: "kmup",
+		Config: api.CreateHookOptionConfig{
+			"content_type": "json",
+			"url":          "http://127.0.0.1:1/hook",
+		},
+		Events: []string{"package"},
+		Active: true,
+	}).AddTokenAuth(token)
+	resp := MakeRequest(t, req, http.StatusCreated)
+	var hook api.Hook
+	DecodeJSON(t, resp, &hook)
+
+	for _, version := range []string{"1.0.0", "1.1.0", "1.2.0"} {
+		req := NewRequestWithBody(t, "PUT", fmt.Sprintf("/api/packages/%s/generic/cleanup-package/%s/file.bin", user.Name, version), bytes.NewReader([]byte{1, 2, 3})).
+			AddBasicAuth(user.Name)
+		MakeRequest(t, req, http.StatusCreated)
+
+		pv, err := packages_model.GetVersionByNameAndVersion(t.Context(), user.ID, packages_model.TypeGeneric, "cleanup-package", version)
+		require.NoError(t, err)
+		_, err = db.GetEngine(t.Context()).Exec("UPDATE package_version SET created_unix = ? WHERE id = ?", time.Now().AddDate(0, 0, -100).Unix(), pv.ID)
+		require.NoError(t, err)
+	}
+
+	pcr, err := packages_model.InsertCleanupRule(t.Context(), &packages_model.PackageCleanupRule{
+		Enabled:       true,
+		OwnerID:       user.ID,
+		Type:          packages_model.TypeGeneric,
+		KeepPattern:   `1\.2\.0`,
+		RemoveDays:    30,
+		RemovePattern: `1\..+`,
+	})
+	require.NoError(t, err)
+
+	rootURL := fmt.Sprintf("/api/v1/packages/%s/-/cleanup-rules", user.Name)
+
+	t.Run("List", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", rootURL).AddTokenAuth(token)
+		resp := MakeRequest(t, req, http.StatusOK)
+
+		var rules []*api.PackageCleanupRule
+		DecodeJSON(t, resp, &rules)
+		require.Len(t, rules, 1)
+		assert.Equal(t, pcr.ID, rules[0].ID)
+		assert.Equal(t, "generic", rules[0].Type)
+		assert.Equal(t, 30, rules[0].RemoveDays)
+	})
+
+	t.Run("AccessDenied", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		other := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 4})
+		otherToken := getUserToken(t, other.Name, auth_model.AccessTokenScopeAll)
+
+		req := NewRequest(t, "GET", fmt.Sprintf("%s/%d/preview", rootURL, pcr.ID)).AddTokenAuth(otherToken)
+		MakeRequest(t, req, http.StatusForbidden)
+
+		req = NewRequest(t, "GET", fmt.Sprintf("/api/v1/packages/%s/-/cleanup-rules/%d/runs", other.Name, pcr.ID)).AddTokenAuth(otherToken)
+		MakeRequest(t, req, http.StatusNotFound)
+	})
+
+	t.Run("Preview", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", fmt.Sprintf("%s/%d/preview", rootURL, pcr.ID)).AddTokenAuth(token)
+		resp := MakeRequest(t, req, http.StatusOK)
+
+		var pkgs []*api.Package
+		DecodeJSON(t, resp, &pkgs)
+		require.Len(t, pkgs, 2)
+		versions := []string{pkgs[0].Version, pkgs[1].Version}
+		assert.ElementsMatch(t, []string{"1.0.0", "1.1.0"}, versions)
+
+		// the preview does not delete anything
+		_, err := packages_model.GetVersionByNameAndVersion(t.Context(), user.ID, packages_model.TypeGeneric, "cleanup-package", "1.0.0")
+		assert.NoError(t, err)
+
+		req = NewRequest(t, "GET", fmt.Sprintf("/user/settings/packages/rules/%d/preview", pcr.ID))
+		resp = session.MakeRequest(t, req, http.StatusOK)
+		assert.Contains(t, resp.Body.String(), "1.1.0")
+	})
+
+	t.Run("Execute", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", fmt.Sprintf("%s/%d/runs", rootURL, pcr.ID)).AddTokenAuth(token)
+		resp := MakeRequest(t, req, http.StatusOK)
+
+		var runs []*api.PackageCleanupRun
+		DecodeJSON(t, resp, &runs)
+		assert.Empty(t, runs)
+
+		require.NoError(t, packages_cleanup_service.ExecuteCleanupRules(t.Context()))
+		require.NoError(t, packages_cleanup_service.ExecuteCleanupRules(t.Context()))
+
+		req = NewRequest(t, "GET", fmt.Sprintf("%s/%d/runs", rootURL, pcr.ID)).AddTokenAuth(token)
+		resp = MakeRequest(t, req, http.StatusOK)
+		assert.Equal(t, "2", resp.Header().Get("X-Total-Count"))
+
+		DecodeJSON(t, resp, &runs)
+		require.Len(t, runs, 2)
+		assert.Equal(t, 0, runs[0].VersionCount)
+		assert.Empty(t, runs[0].Versions)
+		assert.Equal(t, 2, runs[1].VersionCount)
+		assert.Equal(t, int64(6), runs[1].Size)
+		assert.ElementsMatch(t, []string{"cleanup-package@1.0.0", "cleanup-package@1.1.0"}, runs[1].Versions)
+
+		_, err := packages_model.GetVersionByNameAndVersion(t.Context(), user.ID, packages_model.TypeGeneric, "cleanup-package", "1.0.0")
+		assert.ErrorIs(t, err, packages_model.ErrPackageNotExist)
+		_, err = packages_model.GetVersionByNameAndVersion(t.Context(), user.ID, packages_model.TypeGeneric, "cleanup-package", "1.2.0")
+		assert.NoError(t, err)
+
+		req = NewRequest(t, "GET", fmt.Sprintf("/user/settings/packages/rules/%d", pcr.ID))
+		resp = session.MakeRequest(t, req, http.StatusOK)
+		assert.Contains(t, resp.Body.String(), "cleanup-package@1.1.0")
+	})
+
+	t.Run("Webhook", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		tasks := make([]*webhook_model.HookTask, 0, 5)
+		require.NoError(t, db.GetEngine(t.Context()).Where("hook_id = ? AND event_type = ?", hook.ID, webhook_module.HookEventPackage).Find(&tasks))
+
+		var cleanedUp []*api.PackagePayload
+		for _, task := range tasks {
+			var payload api.PackagePayload
+			require.NoError(t, json.Unmarshal([]byte(task.PayloadContent), &payload))
+			if payload.Action == api.HookPackageCleanedUp {
+				cleanedUp = append(cleanedUp, &payload)
+			}
+		}
+		// runs which removed nothing are not sent
+		require.Len(t, cleanedUp, 1)
+		assert.Nil(t, cleanedUp[0].Package)
+		assert.Equal(t, user.Name, cleanedUp[0].Sender.UserName)
+		require.NotNil(t, cleanedUp[0].CleanupRun)
+		assert.Equal(t, pcr.ID, cleanedUp[0].CleanupRun.RuleID)
+		assert.Equal(t, 2, cleanedUp[0].CleanupRun.VersionCount)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		require.NoError(t, packages_model.DeleteCleanupRuleByID(t.Context(), pcr.ID))
+
+		runs, count, err := packages_model.GetCleanupRunsByRuleID(t.Context(), pcr.ID, db.ListOptions{})
+		require.NoError(t, err)
+		assert.Zero(t, count)
+		assert.Empty(t, runs)
+	})
+}
@@ -40,6 +40,7 @@ import (
 	package_service "github.com/kumose/kmup/services/packages"
 	"github.com/kumose/kmup/tests"
 
+	"github.com/opencontainers/go-digest"
 	oci "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/stretchr/testify/assert"
 )
@@ -794,6 +795,169 @@ func TestPackageContainer(t *testing.T) {
 		wg.Wait()
 	})
 
+	t.Run("Referrers", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		image := "referrers"
+		url := fmt.Sprintf("%sv2/%s/%s", setting.AppURL, user.Name, image)
+
+		emptyContent := []byte("{}")
+		for d, content := range map[string][]byte{
+			blobDigest:                              blobContent,
+			configDigest:                            []byte(configContent),
+			oci.DescriptorEmptyJSON.Digest.String(): emptyContent,
+		} {
+			req := NewRequestWithBody(t, "POST", fmt.Sprintf("%s/blobs/uploads?digest=%s", url, d), bytes.NewReader(content)).
+				AddTokenAuth(userToken)
+			MakeRequest(t, req, http.StatusCreated)
+		}
+
+		req := NewRequestWithBody(t, "PUT", url+"/manifests/latest", strings.NewReader(manifestContent)).
+			AddTokenAuth(userToken).
+			SetHeader("Content-Type", manifestContentType)
+		MakeRequest(t, req, http.StatusCreated)
+
+		subject := `{"mediaType":"` + manifestContentType + `","digest":"` + manifestDigest + `","size":` + strconv.Itoa(len(manifestContent)) + `}`
+		sbomContent := `{"schemaVersion":2,"mediaType":"` + oci.MediaTypeImageManifest + `","artifactType":"application/vnd.example.sbom","config":{"mediaType":"` + oci.MediaTypeEmptyJSON + `","digest":"` + oci.DescriptorEmptyJSON.Digest.String() + `","size":2},"layers":[{"mediaType":"application/vnd.example.sbom.layer","digest":"` + blobDigest + `","size":32}],"subject":` + subject + `,"annotations":{"org.example.kind":"sbom"}}`
+		sbomDigest := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(sbomContent)))
+		signatureContent := `{"schemaVersion":2,"mediaType":"` + oci.MediaTypeImageManifest + `","config":{"mediaType":"application/vnd.docker.container.image.v1+json","digest":"` + configDigest + `","size":1069},"layers":[{"mediaType":"application/vnd.example.signature","digest":"` + blobDigest + `","size":32}],"subject":` + subject + `}`
+		signatureDigest := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(signatureContent)))
+
+		getReferrers := func(t *testing.T, query string) *oci.Index {
+			req := NewRequest(t, "GET", fmt.Sprintf("%s/referrers/%s%s", url, manifestDigest, query)).
+				AddTokenAuth(userToken)
+			resp := MakeRequest(t, req, http.StatusOK)
+			assert.Equal(t, oci.MediaTypeImageIndex, resp.Header().Get("Content-Type"))
+
+			var index oci.Index
+			DecodeJSON(t, resp, &index)
+			assert.Equal(t, 2, index.SchemaVersion)
+			assert.Equal(t, oci.MediaTypeImageIndex, index.MediaType)
+			return &index
+		}
+
+		t.Run("Empty", func(t *testing.T) {
+			defer tests.PrintCurrentTest(t)()
+
+			assert.Empty(t, getReferrers(t, "").Manifests)
+
+			req := NewRequest(t, "GET", fmt.Sprintf("%sv2/%s/unknown-image/referrers/%s", setting.AppURL, user.Name, manifestDigest)).
+				AddTokenAuth(userToken)
+			MakeRequest(t, req, http.StatusOK)
+
+			req = NewRequest(t, "GET", url+"/referrers/invalid").
+				AddTokenAuth(userToken)
+			MakeRequest(t, req, http.StatusBadRequest)
+
+			req = NewRequest(t, "GET", url+"/manifests/"+container_module.ReferrersTag(digest.Digest(manifestDigest))).
+				AddTokenAuth(userToken)
+			MakeRequest(t, req, http.StatusNotFound)
+		})
+
+		t.Run("UploadReferrers", func(t *testing.T) {
+			defer tests.PrintCurrentTest(t)()
+
+			for d, content := range map[string]string{sbomDigest: sbomContent, signatureDigest: signatureContent} {
+				req := NewRequestWithBody(t, "PUT", url+"/manifests/"+d, strings.NewReader(content)).
+					AddTokenAuth(userToken).
+					SetHeader("Content-Type", oci.MediaTypeImageManifest)
+				resp := MakeRequest(t, req, http.StatusCreated)
+
+				assert.Equal(t, d, resp.Header().Get("Docker-Content-Digest"))
+				assert.Equal(t, manifestDigest, resp.Header().Get("OCI-Subject"))
+			}
+
+			pv, err := packages_model.GetVersionByNameAndVersion(t.Context(), user.ID, packages_model.TypeContainer, image, sbomDigest)
+			assert.NoError(t, err)
+
+			pd, err := packages_model.GetPackageDescriptor(t.Context(), pv)
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, []string{manifestDigest}, getAllByName(pd.VersionProperties, container_module.PropertyManifestSubject))
+
+			metadata := pd.Metadata.(*container_module.Metadata)
+			assert.Equal(t, container_module.TypeArtifact, metadata.Type)
+			assert.Equal(t, "application/vnd.example.sbom", metadata.ArtifactType)
+			assert.Equal(t, manifestDigest, metadata.Subject)
+			assert.Equal(t, map[string]string{"org.example.kind": "sbom"}, metadata.Annotations)
+		})
+
+		t.Run("GetReferrers", func(t *testing.T) {
+			defer tests.PrintCurrentTest(t)()
+
+			index := getReferrers(t, "")
+			assert.Len(t, index.Manifests, 2)
+			for _, desc := range index.Manifests {
+				assert.Equal(t, oci.MediaTypeImageManifest, desc.MediaType)
+				switch desc.Digest.String() {
+				case sbomDigest:
+					assert.Equal(t, "application/vnd.example.sbom", desc.ArtifactType)
+					assert.EqualValues(t, len(sbomContent), desc.Size)
+					assert.Equal(t, map[string]string{"org.example.kind": "sbom"}, desc.Annotations)
+				case signatureDigest:
+					assert.Equal(t, "application/vnd.docker.container.image.v1+json", desc.ArtifactType)
+					assert.EqualValues(t, len(signatureContent), desc.Size)
+				default:
+					assert.Fail(t, "unexpected referrer", desc.Digest)
+				}
+			}
+
+			req := NewRequest(t, "GET", fmt.Sprintf("%s/referrers/%s?artifactType=application/vnd.example.sbom", url, manifestDigest)).
+				AddTokenAuth(userToken)
+			resp := MakeRequest(t, req, http.StatusOK)
+			assert.Equal(t, "artifactType", resp.Header().Get("OCI-Filters-Applied"))
+
+			var filtered oci.Index
+			DecodeJSON(t, resp, &filtered)
+			assert.Len(t, filtered.Manifests, 1)
+			assert.Equal(t, sbomDigest, filtered.Manifests[0].Digest.String())
+
+			req = NewRequest(t, "GET", fmt.Sprintf("/%s/-/packages/container/%s/latest", user.Name, image))
+			resp = session.MakeRequest(t, req, http.StatusOK)
+			htmlDoc := NewHTMLParser(t, resp.Body)
+			assert.Equal(t, 1, htmlDoc.Find(fmt.Sprintf(`a[href$="/%s"]`, sbomDigest)).Length())
+
+			req = NewRequest(t, "GET", fmt.Sprintf("/%s/-/packages/container/%s/%s", user.Name, image, sbomDigest))
+			resp = session.MakeRequest(t, req, http.StatusOK)
+			htmlDoc = NewHTMLParser(t, resp.Body)
+			assert.Equal(t, 1, htmlDoc.Find(`a.tw-font-mono[href$="/latest"]`).Length())
+		})
+
+		t.Run("ReferrersTag", func(t *testing.T) {
+			defer tests.PrintCurrentTest(t)()
+
+			req := NewRequest(t, "HEAD", url+"/manifests/"+container_module.ReferrersTag(digest.Digest(manifestDigest))).
+				AddTokenAuth(userToken)
+			resp := MakeRequest(t, req, http.StatusOK)
+			assert.Equal(t, oci.MediaTypeImageIndex, resp.Header().Get("Content-Type"))
+			contentDigest := resp.Header().Get("Docker-Content-Digest")
+
+			req = NewRequest(t, "GET", url+"/manifests/"+container_module.ReferrersTag(digest.Digest(manifestDigest))).
+				AddTokenAuth(userToken)
+			resp = MakeRequest(t, req, http.StatusOK)
+			assert.Equal(t, contentDigest, fmt.Sprintf("sha256:%x", sha256.Sum256(resp.Body.Bytes())))
+
+			var index oci.Index
+			DecodeJSON(t, resp, &index)
+			assert.Len(t, index.Manifests, 2)
+		})
+
+		t.Run("DeleteSubject", func(t *testing.T) {
+			defer tests.PrintCurrentTest(t)()
+
+			req := NewRequest(t, "DELETE", url+"/manifests/latest").
+				AddTokenAuth(userToken)
+			MakeRequest(t, req, http.StatusAccepted)
+
+			for _, d := range []string{sbomDigest, signatureDigest} {
+				req = NewRequest(t, "HEAD", url+"/manifests/"+d).
+					AddTokenAuth(userToken)
+				MakeRequest(t, req, http.StatusNotFound)
+			}
+
+			assert.Empty(t, getReferrers(t, "").Manifests)
+		})
+	})
+
 	t.Run("OwnerNameChange", func(t *testing.T) {
 		defer tests.PrintCurrentTest(t)()
 
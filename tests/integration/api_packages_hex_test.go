@@ -0,0 +1,303 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package integration
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	auth_model "github.com/kumose/kmup/models/auth"
+	"github.com/kumose/kmup/models/packages"
+	"github.com/kumose/kmup/models/unittest"
+	user_model "github.com/kumose/kmup/models/user"
+	hex_module "github.com/kumose/kmup/modules/packages/hex"
+	"github.com/kumose/kmup/tests"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestPackageHex(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+
+	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+
+	// the hex client sends the plain api key as authorization header
+	token := getUserToken(t, user.Name, auth_model.AccessTokenScopeWritePackage)
+
+	packageName := "kmup_client"
+	packageVersion := "1.0.0-rc.1"
+	packageDescription := "Test Description"
+
+	createPackage := func(name, version string) []byte {
+		metadata := fmt.Sprintf(`{<<"app">>,<<"%[1]s">>}.
+{<<"build_tools">>,[<<"mix">>]}.
+{<<"description">>,<<"%[3]s">>}.
+{<<"licenses">>,[<<"MIT">>]}.
+{<<"name">>,<<"%[1]s">>}.
+{<<"requirements">>,[[{<<"app">>,<<"jason">>},{<<"name">>,<<"jason">>},{<<"optional">>,false},{<<"repository">>,<<"hexpm">>},{<<"requirement">>,<<"~> 1.4">>}],[{<<"app">>,<<"kmup_core">>},{<<"name">>,<<"kmup_core">>},{<<"optional">>,true},{<<"repository">>,<<"%[4]s">>},{<<"requirement">>,<<"~> 2.0">>}]]}.
+{<<"version">>,<<"%[2]s">>}.
+`, name, version, packageDescription, user.Name)
+
+		var contents bytes.Buffer
+		zw := gzip.NewWriter(&contents)
+		tw := tar.NewWriter(zw)
+		readme := "# " + name
+		tw.WriteHeader(&tar.Header{Name: "README.md", Mode: 0o600, Size: int64(len(readme))})
+		tw.Write([]byte(readme))
+		tw.Close()
+		zw.Close()
+
+		h := sha256.New()
+		h.Write([]byte("3"))
+		h.Write([]byte(metadata))
+		h.Write(contents.Bytes())
+
+		var buf bytes.Buffer
+		tw = tar.NewWriter(&buf)
+		for _, f := range []struct {
+			Name    string
+			Content []byte
+		}{
+			{"VERSION", []byte("3")},
+			{"CHECKSUM", []byte(strings.ToUpper(hex.EncodeToString(h.Sum(nil))))},
+			{"metadata.config", []byte(metadata)},
+			{"contents.tar.gz", contents.Bytes()},
+		} {
+			tw.WriteHeader(&tar.Header{Name: f.Name, Mode: 0o600, Size: int64(len(f.Content))})
+			tw.Write(f.Content)
+		}
+		tw.Close()
+		return buf.Bytes()
+	}
+
+	content := createPackage(packageName, packageVersion)
+
+	root := fmt.Sprintf("/api/packages/%s/hex", user.Name)
+
+	// readResource verifies the signature of a registry resource and returns the payload fields
+	readResource := func(t *testing.T, url string) map[protowire.Number][][]byte {
+		req := NewRequest(t, "GET", root+"/public_key").SetHeader("Authorization", token)
+		resp := MakeRequest(t, req, http.StatusOK)
+		block, _ := pem.Decode(resp.Body.Bytes())
+		require.NotNil(t, block)
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		require.NoError(t, err)
+
+		req = NewRequest(t, "GET", url).SetHeader("Authorization", token)
+		resp = MakeRequest(t, req, http.StatusOK)
+
+		zr, err := gzip.NewReader(resp.Body)
+		require.NoError(t, err)
+		signed, err := io.ReadAll(zr)
+		require.NoError(t, err)
+
+		envelope := decodeProtobuf(t, signed)
+		payload, signature := envelope[1][0], envelope[2][0]
+
+		hash := sha512.Sum512(payload)
+		require.NoError(t, rsa.VerifyPKCS1v15(pub.(*rsa.PublicKey), crypto.SHA512, hash[:], signature))
+
+		return decodeProtobuf(t, payload)
+	}
+
+	t.Run("Upload", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		uploadURL := root + "/api/publish"
+
+		req := NewRequestWithBody(t, "POST", uploadURL, bytes.NewReader(content))
+		MakeRequest(t, req, http.StatusUnauthorized)
+
+		req = NewRequestWithBody(t, "POST", uploadURL, bytes.NewReader([]byte("invalid"))).
+			SetHeader("Authorization", token)
+		MakeRequest(t, req, http.StatusBadRequest)
+
+		req = NewRequestWithBody(t, "POST", root+"/api/packages/other_package/releases", bytes.NewReader(content)).
+			SetHeader("Authorization", token)
+		MakeRequest(t, req, http.StatusBadRequest)
+
+		req = NewRequestWithBody(t, "POST", uploadURL, bytes.NewReader(content)).
+			SetHeader("Authorization", token)
+		resp := MakeRequest(t, req, http.StatusCreated)
+
+		var result struct {
+			Name     string `json:"name"`
+			Version  string `json:"version"`
+			Checksum string `json:"checksum"`
+			URL      string `json:"url"`
+		}
+		DecodeJSON(t, resp, &result)
+
+		outerChecksum := sha256.Sum256(content)
+		assert.Equal(t, packageName, result.Name)
+		assert.Equal(t, packageVersion, result.Version)
+		assert.Equal(t, hex.EncodeToString(outerChecksum[:]), result.Checksum)
+		assert.True(t, strings.HasSuffix(result.URL, fmt.Sprintf("/hex/tarballs/%s-%s.tar", packageName, packageVersion)))
+
+		pvs, err := packages.GetVersionsByPackageType(t.Context(), user.ID, packages.TypeHex)
+		require.NoError(t, err)
+		assert.Len(t, pvs, 1)
+
+		pd, err := packages.GetPackageDescriptor(t.Context(), pvs[0])
+		require.NoError(t, err)
+		assert.NotNil(t, pd.SemVer)
+		assert.IsType(t, &hex_module.Metadata{}, pd.Metadata)
+		metadata := pd.Metadata.(*hex_module.Metadata)
+		assert.Equal(t, packageDescription, metadata.Description)
+		assert.Equal(t, "# "+packageName, metadata.Readme)
+		assert.Len(t, metadata.Requirements, 2)
+
+		require.Len(t, pd.Files, 1)
+		assert.Equal(t, fmt.Sprintf("%s-%s.tar", packageName, packageVersion), pd.Files[0].File.Name)
+		assert.True(t, pd.Files[0].File.IsLead)
+		assert.Equal(t, int64(len(content)), pd.Files[0].Blob.Size)
+
+		req = NewRequestWithBody(t, "POST", uploadURL, bytes.NewReader(content)).
+			SetHeader("Authorization", token)
+		MakeRequest(t, req, http.StatusConflict)
+
+		req = NewRequestWithBody(t, "POST", uploadURL+"?replace=true", bytes.NewReader(content)).
+			SetHeader("Authorization", token)
+		MakeRequest(t, req, http.StatusCreated)
+
+		req = NewRequestWithBody(t, "POST", fmt.Sprintf("%s/api/packages/%s/releases", root, packageName), bytes.NewReader(createPackage(packageName, "0.9.0"))).
+			SetHeader("Authorization", token)
+		MakeRequest(t, req, http.StatusCreated)
+
+		pvs, err = packages.GetVersionsByPackageType(t.Context(), user.ID, packages.TypeHex)
+		require.NoError(t, err)
+		assert.Len(t, pvs, 2)
+	})
+
+	t.Run("Download", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", fmt.Sprintf("%s/tarballs/%s-%s.tar", root, packageName, packageVersion)).
+			SetHeader("Authorization", token)
+		resp := MakeRequest(t, req, http.StatusOK)
+		assert.Equal(t, content, resp.Body.Bytes())
+
+		req = NewRequest(t, "GET", fmt.Sprintf("%s/tarballs/%s-%s.tar", root, packageName, "2.0.0")).
+			SetHeader("Authorization", token)
+		MakeRequest(t, req, http.StatusNotFound)
+	})
+
+	t.Run("Names", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		names := readResource(t, root+"/names")
+		assert.Equal(t, user.Name, string(names[2][0]))
+		require.Len(t, names[1], 1)
+		assert.Equal(t, packageName, string(decodeProtobuf(t, names[1][0])[1][0]))
+	})
+
+	t.Run("Versions", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		versions := readResource(t, root+"/versions")
+		assert.Equal(t, user.Name, string(versions[2][0]))
+		require.Len(t, versions[1], 1)
+
+		pkg := decodeProtobuf(t, versions[1][0])
+		assert.Equal(t, packageName, string(pkg[1][0]))
+		assert.Equal(t, [][]byte{[]byte("0.9.0"), []byte(packageVersion)}, pkg[2])
+	})
+
+	t.Run("Package", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", root+"/packages/unknown").SetHeader("Authorization", token)
+		MakeRequest(t, req, http.StatusNotFound)
+
+		pkg := readResource(t, root+"/packages/"+packageName)
+		assert.Equal(t, packageName, string(pkg[2][0]))
+		assert.Equal(t, user.Name, string(pkg[3][0]))
+		require.Len(t, pkg[1], 2)
+
+		release := decodeProtobuf(t, pkg[1][1])
+		assert.Equal(t, packageVersion, string(release[1][0]))
+		outerChecksum := sha256.Sum256(content)
+		assert.Equal(t, outerChecksum[:], release[5][0])
+		require.Len(t, release[3], 2)
+
+		dep := decodeProtobuf(t, release[3][0])
+		assert.Equal(t, "jason", string(dep[1][0]))
+		assert.Equal(t, "~> 1.4", string(dep[2][0]))
+		assert.Equal(t, "hexpm", string(dep[5][0]))
+
+		// dependencies of the same repository have no repository
+		dep = decodeProtobuf(t, release[3][1])
+		assert.Equal(t, "kmup_core", string(dep[1][0]))
+		assert.NotEmpty(t, dep[3])
+		assert.Empty(t, dep[5])
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		url := fmt.Sprintf("%s/api/packages/%s/releases/%s", root, packageName, packageVersion)
+
+		req := NewRequest(t, "DELETE", url)
+		MakeRequest(t, req, http.StatusUnauthorized)
+
+		req = NewRequest(t, "DELETE", url).SetHeader("Authorization", token)
+		MakeRequest(t, req, http.StatusNoContent)
+
+		req = NewRequest(t, "DELETE", url).SetHeader("Authorization", token)
+		MakeRequest(t, req, http.StatusNotFound)
+
+		pvs, err := packages.GetVersionsByPackageType(t.Context(), user.ID, packages.TypeHex)
+		require.NoError(t, err)
+		assert.Len(t, pvs, 1)
+	})
+}
+
+// decodeProtobuf decodes the length-delimited and varint fields of a protobuf message
+func decodeProtobuf(t *testing.T, b []byte) map[protowire.Number][][]byte {
+	fields := make(map[protowire.Number][][]byte)
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		require.GreaterOrEqual(t, n, 0)
+		b = b[n:]
+
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		require.GreaterOrEqual(t, n, 0)
+		value := b[:n]
+		if typ == protowire.BytesType {
+			value, _ = protowire.ConsumeBytes(value)
+		}
+		fields[num] = append(fields[num], value)
+		b = b[n:]
+	}
+	return fields
+}
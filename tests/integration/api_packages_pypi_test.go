@@ -18,7 +18,10 @@
 package integration
 
 import (
+	"archive/zip"
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -31,6 +34,7 @@ import (
 	"github.com/kumose/kmup/models/unittest"
 	user_model "github.com/kumose/kmup/models/user"
 	"github.com/kumose/kmup/modules/packages/pypi"
+	"github.com/kumose/kmup/modules/setting"
 	"github.com/kumose/kmup/tests"
 
 	"github.com/stretchr/testify/assert"
@@ -249,4 +253,168 @@ func TestPackagePyPI(t *testing.T) {
 			}
 		}
 	})
+
+	t.Run("PackageMetadataJSON", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", fmt.Sprintf("%s/simple/%s/", root, packageName)).
+			SetHeader("Accept", "application/vnd.pypi.simple.v1+json, text/html;q=0.1").
+			AddBasicAuth(user.Name)
+		resp := MakeRequest(t, req, http.StatusOK)
+
+		assert.Equal(t, pypi.SimpleContentType, resp.Header().Get("Content-Type"))
+		assert.Equal(t, "Accept", resp.Header().Get("Vary"))
+
+		var project pypi.SimpleProject
+		DecodeJSON(t, resp, &project)
+
+		assert.Equal(t, pypi.SimpleAPIVersion, project.Meta.APIVersion)
+		assert.Equal(t, packageName, project.Name)
+		assert.Equal(t, []string{packageVersion}, project.Versions)
+		assert.Len(t, project.Files, 2)
+		for _, f := range project.Files {
+			assert.Equal(t, fmt.Sprintf("%s%s/files/%s/%s/%s", setting.AppURL, root[1:], packageName, packageVersion, f.Filename), f.URL)
+			assert.Equal(t, hashSHA256, f.Hashes["sha256"])
+			assert.Equal(t, "3.6", f.RequiresPython)
+			assert.EqualValues(t, 4, f.Size)
+			assert.NotEmpty(t, f.UploadTime)
+			assert.False(t, f.IsYanked())
+			// the uploaded files are no valid wheels
+			assert.Nil(t, f.CoreMetadata)
+		}
+
+		req = NewRequest(t, "GET", fmt.Sprintf("%s/simple/%s", root, packageName)).
+			SetHeader("Accept", "application/json").
+			AddBasicAuth(user.Name)
+		MakeRequest(t, req, http.StatusNotAcceptable)
+	})
+
+	wheelPackageName := "wheel-package"
+	wheelFilename := "wheel_package-1.0-py3-none-any.whl"
+	wheelMetadata := "Metadata-Version: 2.1\nName: wheel-package\nVersion: 1.0\nRequires-Dist: requests\n"
+
+	t.Run("CoreMetadata", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		w, _ := zw.Create("wheel_package-1.0.dist-info/METADATA")
+		_, _ = w.Write([]byte(wheelMetadata))
+		_ = zw.Close()
+
+		wheelHash := sha256.Sum256(buf.Bytes())
+
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, _ := writer.CreateFormFile("content", wheelFilename)
+		_, _ = part.Write(buf.Bytes())
+		writer.WriteField("name", wheelPackageName)
+		writer.WriteField("version", "1.0")
+		writer.WriteField("sha256_digest", hex.EncodeToString(wheelHash[:]))
+		_ = writer.Close()
+
+		uploadHelper(t, body, writer.FormDataContentType(), http.StatusCreated)
+
+		metadataHash := sha256.Sum256([]byte(wheelMetadata))
+
+		req := NewRequest(t, "GET", fmt.Sprintf("%s/simple/%s", root, wheelPackageName)).
+			AddBasicAuth(user.Name)
+		resp := MakeRequest(t, req, http.StatusOK)
+
+		a := NewHTMLParser(t, resp.Body).doc.Find("a")
+		assert.Equal(t, "sha256="+hex.EncodeToString(metadataHash[:]), a.AttrOr("data-core-metadata", ""))
+		assert.Equal(t, "sha256="+hex.EncodeToString(metadataHash[:]), a.AttrOr("data-dist-info-metadata", ""))
+
+		req = NewRequest(t, "GET", fmt.Sprintf("%s/files/%s/1.0/%s.metadata", root, wheelPackageName, wheelFilename)).
+			AddBasicAuth(user.Name)
+		resp = MakeRequest(t, req, http.StatusOK)
+		assert.Equal(t, wheelMetadata, resp.Body.String())
+
+		req = NewRequest(t, "GET", fmt.Sprintf("%s/files/%s/%s/test.whl.metadata", root, packageName, packageVersion)).
+			AddBasicAuth(user.Name)
+		MakeRequest(t, req, http.StatusNotFound)
+	})
+
+	t.Run("ProjectIndex", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		expected := []string{"homepage-package", "no-project-url-or-homepage-package", packageName, wheelPackageName}
+
+		req := NewRequest(t, "GET", root+"/simple/").
+			AddBasicAuth(user.Name)
+		resp := MakeRequest(t, req, http.StatusOK)
+
+		assert.Equal(t, "text/html; charset=utf-8", resp.Header().Get("Content-Type"))
+
+		nodes := NewHTMLParser(t, resp.Body).doc.Find("a")
+		assert.Equal(t, len(expected), nodes.Length())
+		for i, name := range expected {
+			assert.Equal(t, name, nodes.Eq(i).Text())
+			assert.Equal(t, fmt.Sprintf("%s%s/simple/%s/", setting.AppURL, root[1:], name), nodes.Eq(i).AttrOr("href", ""))
+		}
+
+		req = NewRequest(t, "GET", root+"/simple").
+			SetHeader("Accept", "application/vnd.pypi.simple.latest+json").
+			AddBasicAuth(user.Name)
+		resp = MakeRequest(t, req, http.StatusOK)
+
+		var index pypi.SimpleIndex
+		DecodeJSON(t, resp, &index)
+		assert.Equal(t, pypi.SimpleAPIVersion, index.Meta.APIVersion)
+		assert.Len(t, index.Projects, len(expected))
+		for i, name := range expected {
+			assert.Equal(t, name, index.Projects[i].Name)
+		}
+	})
+
+	t.Run("Yank", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		yankURL := fmt.Sprintf("%s/yank/%s/1.0", root, wheelPackageName)
+
+		getYanked := func(t *testing.T) (bool, string) {
+			req := NewRequest(t, "GET", fmt.Sprintf("%s/simple/%s", root, wheelPackageName))
+			resp := MakeRequest(t, req, http.StatusOK)
+			a := NewHTMLParser(t, resp.Body).doc.Find("a")
+			reason, ok := a.Attr("data-yanked")
+			return ok, reason
+		}
+
+		req := NewRequestWithValues(t, "POST", yankURL, map[string]string{"reason": "broken"})
+		MakeRequest(t, req, http.StatusUnauthorized)
+
+		req = NewRequestWithValues(t, "POST", yankURL, map[string]string{"reason": "broken"}).
+			AddBasicAuth(user.Name)
+		MakeRequest(t, req, http.StatusNoContent)
+
+		yanked, reason := getYanked(t)
+		assert.True(t, yanked)
+		assert.Equal(t, "broken", reason)
+
+		req = NewRequest(t, "GET", fmt.Sprintf("%s/simple/%s", root, wheelPackageName)).
+			SetHeader("Accept", pypi.SimpleContentType)
+		resp := MakeRequest(t, req, http.StatusOK)
+		var project pypi.SimpleProject
+		DecodeJSON(t, resp, &project)
+		assert.Equal(t, "broken", project.Files[0].Yanked)
+
+		req = NewRequest(t, "GET", fmt.Sprintf("/%s/-/packages/pypi/%s/1.0", user.Name, wheelPackageName))
+		resp = MakeRequest(t, req, http.StatusOK)
+		assert.Contains(t, NewHTMLParser(t, resp.Body).doc.Find(".ui.warning.message").Text(), "broken")
+
+		// yanked files can still be downloaded
+		req = NewRequest(t, "GET", fmt.Sprintf("%s/files/%s/1.0/%s", root, wheelPackageName, wheelFilename))
+		MakeRequest(t, req, http.StatusOK)
+
+		req = NewRequest(t, "DELETE", yankURL).
+			AddBasicAuth(user.Name)
+		MakeRequest(t, req, http.StatusNoContent)
+
+		yanked, _ = getYanked(t)
+		assert.False(t, yanked)
+
+		req = NewRequest(t, "POST", fmt.Sprintf("%s/yank/%s/2.0", root, wheelPackageName)).
+			AddBasicAuth(user.Name)
+		MakeRequest(t, req, http.StatusNotFound)
+	})
 }
@@ -0,0 +1,491 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package integration
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kumose/kmup/models/db"
+	packages_model "github.com/kumose/kmup/models/packages"
+	"github.com/kumose/kmup/models/unittest"
+	user_model "github.com/kumose/kmup/models/user"
+	"github.com/kumose/kmup/modules/json"
+	"github.com/kumose/kmup/modules/setting"
+	packages_cleanup_service "github.com/kumose/kmup/services/packages/cleanup"
+	"github.com/kumose/kmup/tests"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	oci "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackageRemote(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+
+	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+
+	var mu sync.Mutex
+	requests := map[string]int{}
+	upstreamRequests := func(path string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests[path]
+	}
+
+	mux := http.NewServeMux()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		mux.ServeHTTP(w, r)
+	}))
+	defer upstream.Close()
+
+	addRemote := func(t *testing.T, pr *packages_model.PackageRemote) *packages_model.PackageRemote {
+		pr.OwnerID = user.ID
+		pr.Enabled = true
+		pr, err := packages_model.InsertRemote(t.Context(), pr)
+		require.NoError(t, err)
+		return pr
+	}
+
+	t.Run("Npm", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		packageName := "remote-package"
+		tarballs := map[string][]byte{
+			"1.0.0": []byte("tarball 1.0.0"),
+			"1.1.0": []byte("tarball 1.1.0"),
+		}
+
+		mux.HandleFunc("/npm/"+packageName, func(w http.ResponseWriter, r *http.Request) {
+			versions := map[string]any{}
+			for version, content := range tarballs {
+				sum := sha512.Sum512(content)
+				versions[version] = map[string]any{
+					"name":    packageName,
+					"version": version,
+					"dist": map[string]any{
+						"integrity": "sha512-" + base64.StdEncoding.EncodeToString(sum[:]),
+						"tarball":   fmt.Sprintf("%s/npm/%s/-/%s-%s.tgz", upstream.URL, packageName, packageName, version),
+					},
+				}
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"name":      packageName,
+				"dist-tags": map[string]string{"latest": "1.1.0"},
+				"versions":  versions,
+			})
+		})
+		mux.HandleFunc("/npm/"+packageName+"/-/", func(w http.ResponseWriter, r *http.Request) {
+			version := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/npm/"+packageName+"/-/"+packageName+"-"), ".tgz")
+			_, _ = w.Write(tarballs[version])
+		})
+
+		root := fmt.Sprintf("/api/packages/%s/npm/%s", user.Name, packageName)
+
+		req := NewRequest(t, "GET", root)
+		MakeRequest(t, req, http.StatusNotFound)
+
+		pr := addRemote(t, &packages_model.PackageRemote{
+			Type:       packages_model.TypeNpm,
+			URL:        upstream.URL + "/npm",
+			TTLMinutes: 30,
+			KeepCount:  1,
+		})
+
+		req = NewRequest(t, "GET", root)
+		resp := MakeRequest(t, req, http.StatusOK)
+
+		var result map[string]any
+		DecodeJSON(t, resp, &result)
+		versions := result["versions"].(map[string]any)
+		assert.Len(t, versions, 2)
+		tarball := versions["1.0.0"].(map[string]any)["dist"].(map[string]any)["tarball"].(string)
+		assert.Equal(t, fmt.Sprintf("%sapi/packages/%s/npm/%s/-/1.0.0/%s-1.0.0.tgz", setting.AppURL, user.Name, packageName, packageName), tarball)
+
+		// the metadata is cached for the TTL of the remote
+		req = NewRequest(t, "GET", root)
+		MakeRequest(t, req, http.StatusOK)
+		assert.Equal(t, 1, upstreamRequests("/npm/"+packageName))
+
+		for _, version := range []string{"1.0.0", "1.1.0"} {
+			req = NewRequest(t, "GET", fmt.Sprintf("%s/-/%s/%s-%s.tgz", root, version, packageName, version))
+			resp = MakeRequest(t, req, http.StatusOK)
+			assert.Equal(t, tarballs[version], resp.Body.Bytes())
+
+			pv, err := packages_model.GetVersionByNameAndVersion(t.Context(), user.ID, packages_model.TypeNpm, packageName, version)
+			require.NoError(t, err)
+			assert.True(t, pv.IsRemote)
+		}
+
+		// cached files are served locally
+		req = NewRequest(t, "GET", fmt.Sprintf("%s/-/1.0.0/%s-1.0.0.tgz", root, packageName))
+		MakeRequest(t, req, http.StatusOK)
+		assert.Equal(t, 1, upstreamRequests(fmt.Sprintf("/npm/%s/-/%s-1.0.0.tgz", packageName, packageName)))
+
+		req = NewRequest(t, "GET", fmt.Sprintf("%s/-/2.0.0/%s-2.0.0.tgz", root, packageName))
+		MakeRequest(t, req, http.StatusNotFound)
+
+		t.Run("Cleanup", func(t *testing.T) {
+			defer tests.PrintCurrentTest(t)()
+
+			pv, err := packages_model.GetVersionByNameAndVersion(t.Context(), user.ID, packages_model.TypeNpm, packageName, "1.0.0")
+			require.NoError(t, err)
+			_, err = db.GetEngine(t.Context()).Exec("UPDATE package_version SET created_unix = ? WHERE id = ?", 1, pv.ID)
+			require.NoError(t, err)
+
+			require.NoError(t, packages_cleanup_service.CleanupTask(t.Context(), 0))
+
+			_, err = packages_model.GetVersionByNameAndVersion(t.Context(), user.ID, packages_model.TypeNpm, packageName, "1.0.0")
+			assert.ErrorIs(t, err, packages_model.ErrPackageNotExist)
+			_, err = packages_model.GetVersionByNameAndVersion(t.Context(), user.ID, packages_model.TypeNpm, packageName, "1.1.0")
+			assert.NoError(t, err)
+		})
+
+		t.Run("Disabled", func(t *testing.T) {
+			defer tests.PrintCurrentTest(t)()
+
+			pr.Enabled = false
+			require.NoError(t, packages_model.UpdateRemote(t.Context(), pr))
+
+			req := NewRequest(t, "GET", fmt.Sprintf("%s/-/1.0.0/%s-1.0.0.tgz", root, packageName))
+			MakeRequest(t, req, http.StatusNotFound)
+
+			// the cached version is still available
+			req = NewRequest(t, "GET", fmt.Sprintf("%s/-/1.1.0/%s-1.1.0.tgz", root, packageName))
+			MakeRequest(t, req, http.StatusOK)
+		})
+	})
+
+	t.Run("PyPI", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		packageName := "remote-package"
+		filename := "remote_package-1.0.0-py3-none-any.whl"
+		content := []byte("wheel")
+		sum := sha256.Sum256(content)
+
+		mux.HandleFunc("/pypi/simple/"+packageName+"/", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/vnd.pypi.simple.v1+json")
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"meta": map[string]string{"api-version": "1.0"},
+				"name": packageName,
+				"files": []map[string]any{
+					{
+						"filename":        filename,
+						"url":             "../../files/" + filename,
+						"hashes":          map[string]string{"sha256": hex.EncodeToString(sum[:])},
+						"requires-python": ">=3.8",
+					},
+					{
+						"filename": "remote_package-2.0.0.tar.gz",
+						"url":      "../../files/remote_package-2.0.0.tar.gz",
+						"hashes":   map[string]string{"sha256": "0000"},
+					},
+				},
+			})
+		})
+		mux.HandleFunc("/pypi/files/", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write(content)
+		})
+
+		addRemote(t, &packages_model.PackageRemote{
+			Type: packages_model.TypePyPI,
+			URL:  upstream.URL + "/pypi/simple",
+		})
+
+		root := fmt.Sprintf("/api/packages/%s/pypi", user.Name)
+
+		req := NewRequest(t, "GET", fmt.Sprintf("%s/simple/%s", root, packageName))
+		resp := MakeRequest(t, req, http.StatusOK)
+
+		htmlDoc := NewHTMLParser(t, resp.Body)
+		nodes := htmlDoc.doc.Find("a")
+		assert.Equal(t, 2, nodes.Length())
+		assert.Equal(t, fmt.Sprintf("%s%s/files/%s/1.0.0/%s#sha256=%s", setting.AppURL, root[1:], packageName, filename, hex.EncodeToString(sum[:])), nodes.First().AttrOr("href", ""))
+		assert.Equal(t, ">=3.8", nodes.First().AttrOr("data-requires-python", ""))
+
+		req = NewRequest(t, "GET", fmt.Sprintf("%s/files/%s/1.0.0/%s", root, packageName, filename))
+		resp = MakeRequest(t, req, http.StatusOK)
+		assert.Equal(t, content, resp.Body.Bytes())
+
+		pv, err := packages_model.GetVersionByNameAndVersion(t.Context(), user.ID, packages_model.TypePyPI, packageName, "1.0.0")
+		require.NoError(t, err)
+		assert.True(t, pv.IsRemote)
+
+		// the hash of the upstream file does not match
+		req = NewRequest(t, "GET", fmt.Sprintf("%s/files/%s/2.0.0/remote_package-2.0.0.tar.gz", root, packageName))
+		MakeRequest(t, req, http.StatusInternalServerError)
+	})
+
+	t.Run("Maven", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		pomContent := `<?xml version="1.0"?>
+<project>
+  <groupId>com.kmup</groupId>
+  <artifactId>remote-project</artifactId>
+  <version>1.0.0</version>
+</project>`
+		jarContent := "jar"
+		metadataContent := `<?xml version="1.0"?><metadata><groupId>com.kmup</groupId><artifactId>remote-project</artifactId></metadata>`
+
+		jarSum := sha1.Sum([]byte(jarContent))
+
+		mux.HandleFunc("/maven/com/kmup/remote-project/", func(w http.ResponseWriter, r *http.Request) {
+			files := map[string]string{
+				"maven-metadata.xml":                  metadataContent,
+				"1.0.0/remote-project-1.0.0.pom":      pomContent,
+				"1.0.0/remote-project-1.0.0.jar":      jarContent,
+				"1.0.0/broken-1.0.0.jar":              jarContent,
+				"1.0.0/broken-1.0.0.jar.sha1":         "0000",
+				"1.0.0/remote-project-1.0.0.jar.sha1": hex.EncodeToString(jarSum[:]),
+			}
+			content, ok := files[strings.TrimPrefix(r.URL.Path, "/maven/com/kmup/remote-project/")]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write([]byte(content))
+		})
+
+		addRemote(t, &packages_model.PackageRemote{
+			Type: packages_model.TypeMaven,
+			URL:  upstream.URL + "/maven",
+		})
+
+		root := fmt.Sprintf("/api/packages/%s/maven/com/kmup/remote-project", user.Name)
+
+		req := NewRequest(t, "GET", root+"/maven-metadata.xml")
+		resp := MakeRequest(t, req, http.StatusOK)
+		assert.Equal(t, metadataContent, resp.Body.String())
+
+		req = NewRequest(t, "GET", root+"/1.0.0/remote-project-1.0.0.pom")
+		resp = MakeRequest(t, req, http.StatusOK)
+		assert.Equal(t, pomContent, resp.Body.String())
+
+		req = NewRequest(t, "GET", root+"/1.0.0/remote-project-1.0.0.jar")
+		resp = MakeRequest(t, req, http.StatusOK)
+		assert.Equal(t, jarContent, resp.Body.String())
+
+		pv, err := packages_model.GetVersionByNameAndVersion(t.Context(), user.ID, packages_model.TypeMaven, "com.kmup:remote-project", "1.0.0")
+		require.NoError(t, err)
+		assert.True(t, pv.IsRemote)
+
+		pfs, err := packages_model.GetFilesByVersionID(t.Context(), pv.ID)
+		require.NoError(t, err)
+		assert.Len(t, pfs, 2)
+
+		req = NewRequest(t, "GET", root+"/1.0.0/broken-1.0.0.jar")
+		MakeRequest(t, req, http.StatusInternalServerError)
+
+		req = NewRequest(t, "GET", root+"/1.0.0/missing-1.0.0.jar")
+		MakeRequest(t, req, http.StatusNotFound)
+	})
+
+	t.Run("Container", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		image := "remote-image"
+		config := []byte(`{"architecture":"amd64","os":"linux"}`)
+		layer := []byte("layer")
+		configDigest := digest.FromBytes(config)
+		layerDigest := digest.FromBytes(layer)
+
+		manifest, err := json.Marshal(&oci.Manifest{
+			Versioned: specs.Versioned{SchemaVersion: 2},
+			MediaType: oci.MediaTypeImageManifest,
+			Config:    oci.Descriptor{MediaType: oci.MediaTypeImageConfig, Digest: configDigest, Size: int64(len(config))},
+			Layers:    []oci.Descriptor{{MediaType: oci.MediaTypeImageLayerGzip, Digest: layerDigest, Size: int64(len(layer))}},
+		})
+		require.NoError(t, err)
+		manifestDigest := digest.FromBytes(manifest)
+
+		blobs := map[digest.Digest][]byte{configDigest: config, layerDigest: layer}
+
+		mux.HandleFunc("/v2/"+image+"/", func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/v2/" + image + "/manifests/latest", "/v2/" + image + "/manifests/" + manifestDigest.String():
+				w.Header().Set("Content-Type", oci.MediaTypeImageManifest)
+				_, _ = w.Write(manifest)
+			default:
+				content, ok := blobs[digest.Digest(strings.TrimPrefix(r.URL.Path, "/v2/"+image+"/blobs/"))]
+				if !ok {
+					http.NotFound(w, r)
+					return
+				}
+				_, _ = w.Write(content)
+			}
+		})
+
+		addRemote(t, &packages_model.PackageRemote{
+			Type:       packages_model.TypeContainer,
+			URL:        upstream.URL,
+			TTLMinutes: 30,
+		})
+
+		req := NewRequest(t, "GET", setting.AppURL+"v2/token")
+		resp := MakeRequest(t, req, http.StatusOK)
+		tokenResponse := struct {
+			Token string `json:"token"`
+		}{}
+		DecodeJSON(t, resp, &tokenResponse)
+		token := "Bearer " + tokenResponse.Token
+
+		root := fmt.Sprintf("%sv2/%s/%s", setting.AppURL, user.Name, image)
+
+		req = NewRequest(t, "GET", root+"/manifests/latest").
+			AddTokenAuth(token)
+		resp = MakeRequest(t, req, http.StatusOK)
+		assert.Equal(t, manifestDigest.String(), resp.Header().Get("Docker-Content-Digest"))
+		assert.Equal(t, manifest, resp.Body.Bytes())
+
+		pv, err := packages_model.GetVersionByNameAndVersion(t.Context(), user.ID, packages_model.TypeContainer, image, "latest")
+		require.NoError(t, err)
+		assert.True(t, pv.IsRemote)
+
+		req = NewRequest(t, "GET", root+"/blobs/"+layerDigest.String()).
+			AddTokenAuth(token)
+		resp = MakeRequest(t, req, http.StatusOK)
+		assert.Equal(t, layer, resp.Body.Bytes())
+
+		// the tag is revalidated after the TTL only
+		req = NewRequest(t, "HEAD", root+"/manifests/latest").
+			AddTokenAuth(token)
+		MakeRequest(t, req, http.StatusOK)
+		assert.Equal(t, 1, upstreamRequests("/v2/"+image+"/manifests/latest"))
+
+		req = NewRequest(t, "GET", root+"/blobs/"+digest.FromString("missing").String()).
+			AddTokenAuth(token)
+		MakeRequest(t, req, http.StatusNotFound)
+	})
+
+	t.Run("LocalPackageShadowsRemote", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		mux.HandleFunc("/maven/com/kmup/local-project/", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("upstream"))
+		})
+
+		req := NewRequestWithBody(t, "PUT", fmt.Sprintf("/api/packages/%s/maven/com/kmup/local-project/1.0.0/local-project-1.0.0.jar", user.Name), strings.NewReader("local")).
+			AddBasicAuth(user.Name)
+		MakeRequest(t, req, http.StatusCreated)
+
+		req = NewRequest(t, "GET", fmt.Sprintf("/api/packages/%s/maven/com/kmup/local-project/1.0.0/other-1.0.0.jar", user.Name))
+		MakeRequest(t, req, http.StatusNotFound)
+		assert.Zero(t, upstreamRequests("/maven/com/kmup/local-project/1.0.0/other-1.0.0.jar"))
+	})
+}
+
+func TestPackageRemoteSettings(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+
+	org := unittest.AssertExistsAndLoadBean(t, &user_model.User{Name: "org3"})
+
+	session := loginUser(t, "user2")
+
+	root := fmt.Sprintf("/org/%s/settings/packages", org.Name)
+
+	req := NewRequestWithValues(t, "POST", root+"/remotes/add", map[string]string{
+		"_csrf":       GetUserCSRFToken(t, session),
+		"enabled":     "on",
+		"type":        "npm",
+		"url":         "https://registry.npmjs.org/",
+		"username":    "user",
+		"password":    "secret",
+		"ttl_minutes": "30",
+		"keep_count":  "5",
+		"remove_days": "0",
+		"action":      "save",
+	})
+	session.MakeRequest(t, req, http.StatusSeeOther)
+
+	prs, err := packages_model.GetRemotesByOwner(t.Context(), org.ID)
+	require.NoError(t, err)
+	require.Len(t, prs, 1)
+	pr := prs[0]
+	assert.True(t, pr.Enabled)
+	assert.Equal(t, packages_model.TypeNpm, pr.Type)
+	assert.Equal(t, "https://registry.npmjs.org", pr.URL)
+	assert.Equal(t, 5, pr.KeepCount)
+	password, err := pr.Password()
+	require.NoError(t, err)
+	assert.Equal(t, "secret", password)
+
+	req = NewRequest(t, "GET", root)
+	resp := session.MakeRequest(t, req, http.StatusOK)
+	assert.Contains(t, resp.Body.String(), "https://registry.npmjs.org")
+
+	remoteURL := fmt.Sprintf("%s/remotes/%d", root, pr.ID)
+
+	req = NewRequest(t, "GET", remoteURL)
+	session.MakeRequest(t, req, http.StatusOK)
+
+	// only one remote per package type
+	req = NewRequestWithValues(t, "POST", root+"/remotes/add", map[string]string{
+		"_csrf":  GetUserCSRFToken(t, session),
+		"type":   "npm",
+		"url":    "https://registry.example.com",
+		"action": "save",
+	})
+	session.MakeRequest(t, req, http.StatusOK)
+
+	// an empty password keeps the stored one
+	req = NewRequestWithValues(t, "POST", remoteURL, map[string]string{
+		"_csrf":       GetUserCSRFToken(t, session),
+		"type":        "npm",
+		"url":         "https://registry.example.com",
+		"username":    "user",
+		"ttl_minutes": "60",
+		"action":      "save",
+	})
+	session.MakeRequest(t, req, http.StatusSeeOther)
+
+	pr, err = packages_model.GetRemoteByID(t.Context(), pr.ID)
+	require.NoError(t, err)
+	assert.False(t, pr.Enabled)
+	assert.Equal(t, "https://registry.example.com", pr.URL)
+	assert.Equal(t, 60, pr.TTLMinutes)
+	password, err = pr.Password()
+	require.NoError(t, err)
+	assert.Equal(t, "secret", password)
+
+	req = NewRequestWithValues(t, "POST", remoteURL, map[string]string{
+		"_csrf":  GetUserCSRFToken(t, session),
+		"type":   "npm",
+		"url":    "https://registry.example.com",
+		"action": "remove",
+	})
+	session.MakeRequest(t, req, http.StatusSeeOther)
+
+	_, err = packages_model.GetRemoteByID(t.Context(), pr.ID)
+	assert.ErrorIs(t, err, packages_model.ErrPackageRemoteNotExist)
+}
@@ -0,0 +1,308 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package integration
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	auth_model "github.com/kumose/kmup/models/auth"
+	packages_model "github.com/kumose/kmup/models/packages"
+	"github.com/kumose/kmup/models/unittest"
+	user_model "github.com/kumose/kmup/models/user"
+	"github.com/kumose/kmup/modules/json"
+	sigstore_module "github.com/kumose/kmup/modules/packages/sigstore"
+	"github.com/kumose/kmup/modules/setting"
+	api "github.com/kumose/kmup/modules/structs"
+	signing_service "github.com/kumose/kmup/services/packages/signing"
+	"github.com/kumose/kmup/tests"
+
+	oci "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackageSigning(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+
+	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+	session := loginUser(t, user.Name)
+	tokenReadPackage := getTokenForLoggedInUser(t, session, auth_model.AccessTokenScopeReadPackage)
+	tokenWritePackage := getTokenForLoggedInUser(t, session, auth_model.AccessTokenScopeWritePackage)
+
+	packageName := "signed-package"
+	filename := "file.bin"
+	content := []byte("signed content")
+
+	uploadVersion := func(t *testing.T, version string) {
+		req := NewRequestWithBody(t, "PUT", fmt.Sprintf("/api/packages/%s/generic/%s/%s/%s", user.Name, packageName, version, filename), bytes.NewReader(append(content, version...))).
+			AddBasicAuth(user.Name)
+		MakeRequest(t, req, http.StatusCreated)
+	}
+	uploadVersion(t, "1.0.0")
+	uploadVersion(t, "2.0.0")
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	publicKey := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	createBundle := func(t *testing.T, data []byte) []byte {
+		digest := sha256.Sum256(data)
+		sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+		require.NoError(t, err)
+
+		bundle, err := json.Marshal(&sigstore_module.Bundle{
+			MediaType: "application/vnd.dev.sigstore.bundle.v0.3+json",
+			VerificationMaterial: &sigstore_module.VerificationMaterial{
+				PublicKey: &sigstore_module.PublicKeyIdentifier{Hint: "release"},
+			},
+			MessageSignature: &sigstore_module.MessageSignature{
+				MessageDigest: &sigstore_module.HashOutput{Algorithm: "SHA2_256", Digest: digest[:]},
+				Signature:     sig,
+			},
+		})
+		require.NoError(t, err)
+		return bundle
+	}
+
+	apiURL := fmt.Sprintf("/api/v1/packages/%s/generic/%s/1.0.0/attestations", user.Name, packageName)
+	downloadURL := func(version string) string {
+		return fmt.Sprintf("/api/packages/%s/generic/%s/%s/%s", user.Name, packageName, version, filename)
+	}
+
+	var attestationID int64
+
+	t.Run("Upload", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		bundle := createBundle(t, append(content, "1.0.0"...))
+
+		req := NewRequestWithBody(t, "POST", apiURL, bytes.NewReader(bundle)).
+			AddTokenAuth(tokenReadPackage)
+		MakeRequest(t, req, http.StatusForbidden)
+
+		req = NewRequestWithBody(t, "POST", apiURL, bytes.NewReader([]byte("{}"))).
+			AddTokenAuth(tokenWritePackage)
+		MakeRequest(t, req, http.StatusBadRequest)
+
+		// the bundle must sign a file of the version
+		req = NewRequestWithBody(t, "POST", apiURL, bytes.NewReader(createBundle(t, []byte("other content")))).
+			AddTokenAuth(tokenWritePackage)
+		MakeRequest(t, req, http.StatusBadRequest)
+
+		req = NewRequestWithBody(t, "POST", apiURL, bytes.NewReader(bundle)).
+			AddTokenAuth(tokenWritePackage)
+		resp := MakeRequest(t, req, http.StatusCreated)
+
+		var a *api.PackageAttestation
+		DecodeJSON(t, resp, &a)
+		assert.Equal(t, "signature", a.Kind)
+		assert.Equal(t, []string{filename}, a.Files)
+		assert.False(t, a.Verified)
+		assert.NotEmpty(t, a.Error)
+		attestationID = a.ID
+
+		req = NewRequestWithBody(t, "POST", apiURL, bytes.NewReader(bundle)).
+			AddTokenAuth(tokenWritePackage)
+		MakeRequest(t, req, http.StatusConflict)
+
+		req = NewRequest(t, "GET", fmt.Sprintf("%s/%d", apiURL, attestationID)).
+			AddTokenAuth(tokenReadPackage)
+		resp = MakeRequest(t, req, http.StatusOK)
+		assert.Equal(t, bundle, resp.Body.Bytes())
+	})
+
+	t.Run("Verify", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		_, err := packages_model.InsertSigner(t.Context(), &packages_model.PackageSigner{
+			OwnerID:   user.ID,
+			Name:      "release",
+			PublicKey: publicKey,
+		})
+		require.NoError(t, err)
+
+		req := NewRequest(t, "GET", apiURL).
+			AddTokenAuth(tokenReadPackage)
+		resp := MakeRequest(t, req, http.StatusOK)
+
+		var v *api.PackageVerification
+		DecodeJSON(t, resp, &v)
+		assert.True(t, v.Verified)
+		assert.False(t, v.SignatureRequired)
+		assert.Len(t, v.Attestations, 1)
+		assert.True(t, v.Attestations[0].Verified)
+		assert.Equal(t, "release", v.Attestations[0].Signer)
+
+		req = NewRequest(t, "GET", fmt.Sprintf("/%s/-/packages/generic/%s/1.0.0", user.Name, packageName))
+		resp = MakeRequest(t, req, http.StatusOK)
+		assert.Contains(t, resp.Body.String(), "Signed by release")
+	})
+
+	t.Run("Policy", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		MakeRequest(t, NewRequest(t, "GET", downloadURL("2.0.0")), http.StatusOK)
+
+		require.NoError(t, signing_service.SetSignatureRequired(t.Context(), user.ID, true))
+		defer func() {
+			require.NoError(t, signing_service.SetSignatureRequired(t.Context(), user.ID, false))
+		}()
+
+		MakeRequest(t, NewRequest(t, "GET", downloadURL("1.0.0")), http.StatusOK)
+		MakeRequest(t, NewRequest(t, "GET", downloadURL("2.0.0")), http.StatusForbidden)
+
+		req := NewRequest(t, "DELETE", fmt.Sprintf("%s/%d", apiURL, attestationID)).
+			AddTokenAuth(tokenWritePackage)
+		MakeRequest(t, req, http.StatusNoContent)
+
+		MakeRequest(t, NewRequest(t, "GET", downloadURL("1.0.0")), http.StatusForbidden)
+
+		req = NewRequest(t, "DELETE", fmt.Sprintf("%s/%d", apiURL, attestationID)).
+			AddTokenAuth(tokenWritePackage)
+		MakeRequest(t, req, http.StatusNotFound)
+	})
+
+	t.Run("Container", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		image := "signed-image"
+		url := fmt.Sprintf("%sv2/%s/%s", setting.AppURL, user.Name, image)
+
+		uploadBlob := func(t *testing.T, content string) string {
+			blobDigest := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(content)))
+			req := NewRequestWithBody(t, "POST", fmt.Sprintf("%s/blobs/uploads?digest=%s", url, blobDigest), strings.NewReader(content)).
+				AddBasicAuth(user.Name)
+			MakeRequest(t, req, http.StatusCreated)
+			return blobDigest
+		}
+		uploadManifest := func(t *testing.T, reference, mediaType, content string) string {
+			req := NewRequestWithBody(t, "PUT", fmt.Sprintf("%s/manifests/%s", url, reference), strings.NewReader(content)).
+				AddBasicAuth(user.Name).
+				SetHeader("Content-Type", mediaType)
+			resp := MakeRequest(t, req, http.StatusCreated)
+			return resp.Header().Get("Docker-Content-Digest")
+		}
+
+		configContent := `{"architecture":"amd64","os":"linux"}`
+		configDigest := uploadBlob(t, configContent)
+		layerDigest := uploadBlob(t, "layer content")
+
+		manifestContent := fmt.Sprintf(`{"schemaVersion":2,"mediaType":%q,"config":{"mediaType":%q,"digest":%q,"size":%d},"layers":[{"mediaType":%q,"digest":%q,"size":13}]}`,
+			oci.MediaTypeImageManifest, oci.MediaTypeImageConfig, configDigest, len(configContent), oci.MediaTypeImageLayerGzip, layerDigest)
+		manifestDigest := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(manifestContent)))
+		assert.Equal(t, manifestDigest, uploadManifest(t, manifestDigest, oci.MediaTypeImageManifest, manifestContent))
+
+		indexContent := fmt.Sprintf(`{"schemaVersion":2,"mediaType":%q,"manifests":[{"mediaType":%q,"digest":%q,"size":%d,"platform":{"os":"linux","architecture":"amd64"}}]}`,
+			oci.MediaTypeImageIndex, oci.MediaTypeImageManifest, manifestDigest, len(manifestContent))
+		uploadManifest(t, "latest", oci.MediaTypeImageIndex, indexContent)
+
+		getManifest := func(t *testing.T, method, reference string, expectedStatus int) {
+			req := NewRequest(t, method, fmt.Sprintf("%s/manifests/%s", url, reference)).
+				AddBasicAuth(user.Name)
+			MakeRequest(t, req, expectedStatus)
+		}
+
+		getManifest(t, "GET", manifestDigest, http.StatusOK)
+
+		require.NoError(t, signing_service.SetSignatureRequired(t.Context(), user.ID, true))
+		defer func() {
+			require.NoError(t, signing_service.SetSignatureRequired(t.Context(), user.ID, false))
+		}()
+
+		getManifest(t, "GET", "latest", http.StatusForbidden)
+		getManifest(t, "GET", manifestDigest, http.StatusForbidden)
+		getManifest(t, "HEAD", manifestDigest, http.StatusOK)
+
+		// the blobs are exempted
+		req := NewRequest(t, "GET", fmt.Sprintf("%s/blobs/%s", url, layerDigest)).
+			AddBasicAuth(user.Name)
+		MakeRequest(t, req, http.StatusOK)
+
+		// the signature of the index covers the manifests it references
+		req = NewRequestWithBody(t, "POST", fmt.Sprintf("/api/v1/packages/%s/container/%s/latest/attestations", user.Name, image), bytes.NewReader(createBundle(t, []byte(indexContent)))).
+			AddTokenAuth(tokenWritePackage)
+		MakeRequest(t, req, http.StatusCreated)
+
+		getManifest(t, "GET", "latest", http.StatusOK)
+		getManifest(t, "GET", manifestDigest, http.StatusOK)
+	})
+
+	t.Run("Settings", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", "/user/settings/packages/signers/add")
+		session.MakeRequest(t, req, http.StatusOK)
+
+		// either a public key or an identity is required
+		req = NewRequestWithValues(t, "POST", "/user/settings/packages/signers/add", map[string]string{
+			"_csrf":  GetUserCSRFToken(t, session),
+			"name":   "invalid",
+			"action": "save",
+		})
+		session.MakeRequest(t, req, http.StatusOK)
+
+		req = NewRequestWithValues(t, "POST", "/user/settings/packages/signers/add", map[string]string{
+			"_csrf":    GetUserCSRFToken(t, session),
+			"name":     "ci",
+			"identity": "https://example.com/org/repo/*",
+			"issuer":   "https://token.actions.example.com",
+			"action":   "save",
+		})
+		session.MakeRequest(t, req, http.StatusSeeOther)
+
+		pss, err := packages_model.GetSignersByOwner(t.Context(), user.ID)
+		require.NoError(t, err)
+		assert.Len(t, pss, 2)
+		assert.Equal(t, "ci", pss[0].Name)
+		assert.True(t, pss[0].IsKeyless())
+
+		req = NewRequestWithValues(t, "POST", "/user/settings/packages/signing", map[string]string{
+			"_csrf":              GetUserCSRFToken(t, session),
+			"signature_required": "on",
+		})
+		session.MakeRequest(t, req, http.StatusSeeOther)
+		defer func() {
+			require.NoError(t, signing_service.SetSignatureRequired(t.Context(), user.ID, false))
+		}()
+
+		required, err := signing_service.IsSignatureRequired(t.Context(), user.ID)
+		require.NoError(t, err)
+		assert.True(t, required)
+
+		req = NewRequestWithValues(t, "POST", fmt.Sprintf("/user/settings/packages/signers/%d", pss[0].ID), map[string]string{
+			"_csrf":  GetUserCSRFToken(t, session),
+			"name":   "ci",
+			"action": "remove",
+		})
+		session.MakeRequest(t, req, http.StatusSeeOther)
+
+		unittest.AssertNotExistsBean(t, &packages_model.PackageSigner{ID: pss[0].ID})
+	})
+}
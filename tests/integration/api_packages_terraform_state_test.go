@@ -0,0 +1,282 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	auth_model "github.com/kumose/kmup/models/auth"
+	packages_model "github.com/kumose/kmup/models/packages"
+	repo_model "github.com/kumose/kmup/models/repo"
+	"github.com/kumose/kmup/models/unittest"
+	user_model "github.com/kumose/kmup/models/user"
+	terraform_module "github.com/kumose/kmup/modules/packages/terraform"
+	"github.com/kumose/kmup/tests"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackageTerraformState(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+	repo := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{ID: 1})
+
+	packageToken := "Bearer " + getUserToken(t, user.Name, auth_model.AccessTokenScopeWritePackage)
+	readPackageToken := "Bearer " + getUserToken(t, user.Name, auth_model.AccessTokenScopeReadPackage)
+	repoToken := "Bearer " + getUserToken(t, user.Name, auth_model.AccessTokenScopeWriteRepository)
+
+	stateContent := func(serial int) string {
+		return fmt.Sprintf(`{"version":4,"terraform_version":"1.9.0","serial":%d,"lineage":"6a1b2c3d","outputs":{},"resources":[]}`, serial)
+	}
+	lockInfo := func(id string) string {
+		return fmt.Sprintf(`{"ID":"%s","Operation":"OperationTypeApply","Info":"","Who":"user@host","Version":"1.9.0","Created":"2026-01-02T03:04:05Z","Path":""}`, id)
+	}
+
+	t.Run("Owner", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		stateURL := fmt.Sprintf("/api/packages/%s/terraform/state/production", user.Name)
+
+		t.Run("Permissions", func(t *testing.T) {
+			defer tests.PrintCurrentTest(t)()
+
+			MakeRequest(t, NewRequest(t, "GET", stateURL), http.StatusUnauthorized)
+			MakeRequest(t, NewRequest(t, "GET", stateURL).AddTokenAuth(readPackageToken), http.StatusUnauthorized)
+			MakeRequest(t, NewRequest(t, "GET", stateURL).AddTokenAuth(repoToken), http.StatusUnauthorized)
+			MakeRequest(t, NewRequest(t, "GET", stateURL).AddTokenAuth(packageToken), http.StatusNotFound)
+			MakeRequest(t, NewRequest(t, "GET", fmt.Sprintf("/api/packages/%s/terraform/state/.invalid", user.Name)).AddTokenAuth(packageToken), http.StatusBadRequest)
+		})
+
+		t.Run("Locking", func(t *testing.T) {
+			defer tests.PrintCurrentTest(t)()
+
+			req := NewRequestWithBody(t, "LOCK", stateURL, strings.NewReader(lockInfo("lock-a"))).AddTokenAuth(packageToken)
+			MakeRequest(t, req, http.StatusOK)
+
+			req = NewRequestWithBody(t, "LOCK", stateURL, strings.NewReader(lockInfo("lock-b"))).AddTokenAuth(packageToken)
+			resp := MakeRequest(t, req, http.StatusLocked)
+
+			var lock terraform_module.LockInfo
+			DecodeJSON(t, resp, &lock)
+			assert.Equal(t, "lock-a", lock.ID)
+			assert.Equal(t, "user@host", lock.Who)
+
+			req = NewRequestWithBody(t, "POST", stateURL, strings.NewReader(stateContent(1))).AddTokenAuth(packageToken)
+			MakeRequest(t, req, http.StatusLocked)
+
+			req = NewRequestWithBody(t, "POST", stateURL+"?ID=lock-b", strings.NewReader(stateContent(1))).AddTokenAuth(packageToken)
+			MakeRequest(t, req, http.StatusLocked)
+
+			req = NewRequestWithBody(t, "POST", stateURL+"?ID=lock-a", strings.NewReader(`{"serial":1}`)).AddTokenAuth(packageToken)
+			MakeRequest(t, req, http.StatusBadRequest)
+
+			req = NewRequestWithBody(t, "POST", stateURL+"?ID=lock-a", strings.NewReader(stateContent(1))).AddTokenAuth(packageToken)
+			MakeRequest(t, req, http.StatusOK)
+
+			req = NewRequestWithBody(t, "UNLOCK", stateURL, strings.NewReader(lockInfo("lock-b"))).AddTokenAuth(packageToken)
+			MakeRequest(t, req, http.StatusLocked)
+
+			req = NewRequestWithBody(t, "UNLOCK", stateURL, strings.NewReader(lockInfo("lock-a"))).AddTokenAuth(packageToken)
+			MakeRequest(t, req, http.StatusOK)
+
+			// an empty body forces the unlock
+			req = NewRequestWithBody(t, "LOCK", stateURL, strings.NewReader(lockInfo("lock-c"))).AddTokenAuth(packageToken)
+			MakeRequest(t, req, http.StatusOK)
+			req = NewRequestWithBody(t, "UNLOCK", stateURL, strings.NewReader("")).AddTokenAuth(packageToken)
+			MakeRequest(t, req, http.StatusOK)
+
+			req = NewRequestWithBody(t, "LOCK", stateURL, strings.NewReader(lockInfo("lock-b"))).AddTokenAuth(packageToken)
+			MakeRequest(t, req, http.StatusOK)
+			req = NewRequestWithBody(t, "UNLOCK", stateURL, strings.NewReader(lockInfo("lock-b"))).AddTokenAuth(packageToken)
+			MakeRequest(t, req, http.StatusOK)
+		})
+
+		t.Run("State", func(t *testing.T) {
+			defer tests.PrintCurrentTest(t)()
+
+			req := NewRequest(t, "GET", stateURL).AddTokenAuth(packageToken)
+			resp := MakeRequest(t, req, http.StatusOK)
+			assert.Equal(t, stateContent(1), resp.Body.String())
+
+			req = NewRequestWithBody(t, "POST", stateURL, strings.NewReader(stateContent(2))).AddTokenAuth(packageToken)
+			MakeRequest(t, req, http.StatusOK)
+
+			req = NewRequest(t, "GET", stateURL).AddTokenAuth(packageToken)
+			resp = MakeRequest(t, req, http.StatusOK)
+			assert.Equal(t, stateContent(2), resp.Body.String())
+
+			// states are not visible as packages
+			pvs, err := packages_model.GetVersionsByPackageType(t.Context(), user.ID, packages_model.TypeTerraformState)
+			require.NoError(t, err)
+			assert.Empty(t, pvs)
+			MakeRequest(t, NewRequest(t, "GET", fmt.Sprintf("/%s/-/packages/terraform_state/production/1", user.Name)).AddTokenAuth(packageToken), http.StatusNotFound)
+		})
+
+		type stateVersion struct {
+			Version string `json:"version"`
+			Serial  int64  `json:"serial"`
+			Lineage string `json:"lineage"`
+			Creator string `json:"creator"`
+		}
+
+		t.Run("History", func(t *testing.T) {
+			defer tests.PrintCurrentTest(t)()
+
+			req := NewRequest(t, "GET", stateURL+"/versions").AddTokenAuth(packageToken)
+			resp := MakeRequest(t, req, http.StatusOK)
+
+			var result struct {
+				Lock     *terraform_module.LockInfo `json:"lock"`
+				Versions []*stateVersion            `json:"versions"`
+			}
+			DecodeJSON(t, resp, &result)
+
+			assert.Nil(t, result.Lock)
+			require.Len(t, result.Versions, 2)
+			assert.Equal(t, "2", result.Versions[0].Version)
+			assert.EqualValues(t, 2, result.Versions[0].Serial)
+			assert.Equal(t, "1", result.Versions[1].Version)
+			assert.EqualValues(t, 1, result.Versions[1].Serial)
+			assert.Equal(t, "6a1b2c3d", result.Versions[1].Lineage)
+			assert.Equal(t, user.Name, result.Versions[1].Creator)
+
+			req = NewRequest(t, "GET", stateURL+"/versions/1").AddTokenAuth(packageToken)
+			resp = MakeRequest(t, req, http.StatusOK)
+			assert.Equal(t, stateContent(1), resp.Body.String())
+
+			req = NewRequest(t, "GET", stateURL+"/versions/9").AddTokenAuth(packageToken)
+			MakeRequest(t, req, http.StatusNotFound)
+		})
+
+		t.Run("Rollback", func(t *testing.T) {
+			defer tests.PrintCurrentTest(t)()
+
+			req := NewRequestWithBody(t, "LOCK", stateURL, strings.NewReader(lockInfo("lock-a"))).AddTokenAuth(packageToken)
+			MakeRequest(t, req, http.StatusOK)
+
+			req = NewRequest(t, "POST", stateURL+"/versions/1/rollback").AddTokenAuth(packageToken)
+			MakeRequest(t, req, http.StatusLocked)
+
+			req = NewRequest(t, "POST", stateURL+"/versions/1/rollback?ID=lock-a").AddTokenAuth(packageToken)
+			resp := MakeRequest(t, req, http.StatusCreated)
+
+			var version stateVersion
+			DecodeJSON(t, resp, &version)
+			assert.Equal(t, "3", version.Version)
+			assert.EqualValues(t, 1, version.Serial)
+
+			req = NewRequest(t, "GET", stateURL).AddTokenAuth(packageToken)
+			resp = MakeRequest(t, req, http.StatusOK)
+			assert.Equal(t, stateContent(1), resp.Body.String())
+
+			req = NewRequest(t, "GET", stateURL+"/versions").AddTokenAuth(packageToken)
+			resp = MakeRequest(t, req, http.StatusOK)
+
+			var result struct {
+				Lock     *terraform_module.LockInfo `json:"lock"`
+				Versions []*stateVersion            `json:"versions"`
+			}
+			DecodeJSON(t, resp, &result)
+			require.NotNil(t, result.Lock)
+			assert.Equal(t, "lock-a", result.Lock.ID)
+			assert.Len(t, result.Versions, 3)
+		})
+
+		t.Run("Delete", func(t *testing.T) {
+			defer tests.PrintCurrentTest(t)()
+
+			req := NewRequest(t, "DELETE", stateURL).AddTokenAuth(packageToken)
+			MakeRequest(t, req, http.StatusLocked)
+
+			req = NewRequest(t, "DELETE", stateURL+"?ID=lock-a").AddTokenAuth(packageToken)
+			MakeRequest(t, req, http.StatusOK)
+
+			req = NewRequest(t, "GET", stateURL).AddTokenAuth(packageToken)
+			MakeRequest(t, req, http.StatusNotFound)
+
+			req = NewRequest(t, "GET", stateURL+"/versions").AddTokenAuth(packageToken)
+			MakeRequest(t, req, http.StatusNotFound)
+		})
+	})
+
+	t.Run("Repository", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		stateURL := fmt.Sprintf("/api/packages/%s/terraform/repos/%s/state/production", user.Name, repo.Name)
+
+		t.Run("Permissions", func(t *testing.T) {
+			defer tests.PrintCurrentTest(t)()
+
+			unknownURL := fmt.Sprintf("/api/packages/%s/terraform/repos/%s/state/production", user.Name, "unknown")
+
+			// the authentication is checked before the repository is resolved
+			MakeRequest(t, NewRequest(t, "GET", stateURL), http.StatusUnauthorized)
+			MakeRequest(t, NewRequest(t, "GET", unknownURL), http.StatusUnauthorized)
+			MakeRequest(t, NewRequest(t, "GET", stateURL).AddTokenAuth(packageToken), http.StatusUnauthorized)
+
+			// user4 can read the public repository but has no write permission, which is reported like a missing repository
+			otherToken := "Bearer " + getUserToken(t, "user4", auth_model.AccessTokenScopeWriteRepository)
+			MakeRequest(t, NewRequest(t, "GET", stateURL).AddTokenAuth(otherToken), http.StatusNotFound)
+			MakeRequest(t, NewRequest(t, "GET", unknownURL).AddTokenAuth(otherToken), http.StatusNotFound)
+
+			MakeRequest(t, NewRequest(t, "GET", unknownURL).AddTokenAuth(repoToken), http.StatusNotFound)
+			MakeRequest(t, NewRequest(t, "GET", stateURL).AddTokenAuth(repoToken), http.StatusNotFound)
+		})
+
+		t.Run("State", func(t *testing.T) {
+			defer tests.PrintCurrentTest(t)()
+
+			req := NewRequestWithBody(t, "POST", stateURL+"/lock", strings.NewReader(lockInfo("lock-a"))).AddTokenAuth(repoToken)
+			MakeRequest(t, req, http.StatusOK)
+
+			req = NewRequestWithBody(t, "POST", stateURL+"?ID=lock-a", strings.NewReader(stateContent(7))).AddTokenAuth(repoToken)
+			MakeRequest(t, req, http.StatusOK)
+
+			req = NewRequestWithBody(t, "DELETE", stateURL+"/lock", strings.NewReader(lockInfo("lock-a"))).AddTokenAuth(repoToken)
+			MakeRequest(t, req, http.StatusOK)
+
+			req = NewRequest(t, "GET", stateURL).AddTokenAuth(repoToken)
+			resp := MakeRequest(t, req, http.StatusOK)
+			assert.Equal(t, stateContent(7), resp.Body.String())
+
+			// the owner state with the same name is a different state
+			req = NewRequest(t, "GET", fmt.Sprintf("/api/packages/%s/terraform/state/production", user.Name)).AddTokenAuth(packageToken)
+			MakeRequest(t, req, http.StatusNotFound)
+		})
+
+		t.Run("DeleteRepository", func(t *testing.T) {
+			defer tests.PrintCurrentTest(t)()
+
+			ps, err := packages_model.GetPackagesByRepositoryAndType(t.Context(), repo.ID, packages_model.TypeTerraformState)
+			require.NoError(t, err)
+			assert.Len(t, ps, 1)
+
+			req := NewRequest(t, "DELETE", fmt.Sprintf("/api/v1/repos/%s/%s", user.Name, repo.Name)).
+				AddTokenAuth(repoToken)
+			MakeRequest(t, req, http.StatusNoContent)
+
+			ps, err = packages_model.GetPackagesByRepositoryAndType(t.Context(), repo.ID, packages_model.TypeTerraformState)
+			require.NoError(t, err)
+			assert.Empty(t, ps)
+		})
+	})
+}
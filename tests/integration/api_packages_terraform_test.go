@@ -0,0 +1,385 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package integration
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	asymkey_model "github.com/kumose/kmup/models/asymkey"
+	auth_model "github.com/kumose/kmup/models/auth"
+	"github.com/kumose/kmup/models/packages"
+	"github.com/kumose/kmup/models/unittest"
+	user_model "github.com/kumose/kmup/models/user"
+	terraform_module "github.com/kumose/kmup/modules/packages/terraform"
+	"github.com/kumose/kmup/modules/setting"
+	"github.com/kumose/kmup/tests"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackageTerraform(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+
+	token := "Bearer " + getUserToken(t, user.Name, auth_model.AccessTokenScopeWritePackage)
+
+	t.Run("ServiceDiscovery", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", "/.well-known/terraform.json")
+		resp := MakeRequest(t, req, http.StatusOK)
+
+		var result map[string]string
+		DecodeJSON(t, resp, &result)
+
+		assert.Equal(t, "/api/packages/-/terraform/modules/v1/", result["modules.v1"])
+		assert.Equal(t, "/api/packages/-/terraform/providers/v1/", result["providers.v1"])
+	})
+
+	t.Run("Module", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		moduleName := "vpc"
+		moduleSystem := "aws"
+		moduleVersion := "1.2.0"
+		readme := "# VPC module"
+
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		archive := tar.NewWriter(zw)
+		for name, content := range map[string]string{
+			"README.md":              readme,
+			"main.tf":                `resource "aws_vpc" "this" {}`,
+			"modules/subnet/main.tf": `resource "aws_subnet" "this" {}`,
+			"examples/simple/main.tf": `module "vpc" {
+  source = "../../"
+}`,
+		} {
+			archive.WriteHeader(&tar.Header{
+				Name: name,
+				Mode: 0o600,
+				Size: int64(len(content)),
+			})
+			archive.Write([]byte(content))
+		}
+		archive.Close()
+		zw.Close()
+		content := buf.Bytes()
+
+		moduleURL := fmt.Sprintf("/api/packages/-/terraform/modules/v1/%s/%s/%s", user.Name, moduleName, moduleSystem)
+
+		t.Run("Upload", func(t *testing.T) {
+			defer tests.PrintCurrentTest(t)()
+
+			uploadURL := moduleURL + "/" + moduleVersion
+
+			req := NewRequestWithBody(t, "PUT", uploadURL, bytes.NewReader(content))
+			MakeRequest(t, req, http.StatusUnauthorized)
+
+			req = NewRequestWithBody(t, "PUT", fmt.Sprintf("/api/packages/-/terraform/modules/v1/%s/%s/%s/%s", user.Name, moduleName, "AWS", moduleVersion), bytes.NewReader(content)).
+				AddTokenAuth(token)
+			MakeRequest(t, req, http.StatusBadRequest)
+
+			req = NewRequestWithBody(t, "PUT", moduleURL+"/invalid", bytes.NewReader(content)).
+				AddTokenAuth(token)
+			MakeRequest(t, req, http.StatusBadRequest)
+
+			req = NewRequestWithBody(t, "PUT", uploadURL, bytes.NewReader(content)).
+				AddTokenAuth(token)
+			MakeRequest(t, req, http.StatusCreated)
+
+			pvs, err := packages.GetVersionsByPackageType(t.Context(), user.ID, packages.TypeTerraformModule)
+			require.NoError(t, err)
+			require.Len(t, pvs, 1)
+
+			pd, err := packages.GetPackageDescriptor(t.Context(), pvs[0])
+			require.NoError(t, err)
+			assert.NotNil(t, pd.SemVer)
+			assert.Equal(t, "vpc-aws", pd.Package.Name)
+			assert.Equal(t, moduleVersion, pd.Version.Version)
+			require.IsType(t, &terraform_module.ModuleMetadata{}, pd.Metadata)
+			metadata := pd.Metadata.(*terraform_module.ModuleMetadata)
+			assert.Equal(t, moduleName, metadata.Name)
+			assert.Equal(t, moduleSystem, metadata.System)
+			assert.Equal(t, readme, metadata.Readme)
+			assert.Equal(t, []string{"subnet"}, metadata.Submodules)
+			assert.Equal(t, []string{"simple"}, metadata.Examples)
+
+			require.Len(t, pd.Files, 1)
+			assert.Equal(t, "vpc-aws-1.2.0.tar.gz", pd.Files[0].File.Name)
+			assert.Equal(t, int64(len(content)), pd.Files[0].Blob.Size)
+
+			req = NewRequestWithBody(t, "PUT", uploadURL, bytes.NewReader(content)).
+				AddTokenAuth(token)
+			MakeRequest(t, req, http.StatusConflict)
+		})
+
+		t.Run("Versions", func(t *testing.T) {
+			defer tests.PrintCurrentTest(t)()
+
+			req := NewRequest(t, "GET", moduleURL+"/versions")
+			resp := MakeRequest(t, req, http.StatusOK)
+
+			var result struct {
+				Modules []struct {
+					Versions []struct {
+						Version string `json:"version"`
+					} `json:"versions"`
+				} `json:"modules"`
+			}
+			DecodeJSON(t, resp, &result)
+
+			require.Len(t, result.Modules, 1)
+			require.Len(t, result.Modules[0].Versions, 1)
+			assert.Equal(t, moduleVersion, result.Modules[0].Versions[0].Version)
+
+			req = NewRequest(t, "GET", fmt.Sprintf("/api/packages/-/terraform/modules/v1/%s/%s/%s/versions", user.Name, "other", moduleSystem))
+			MakeRequest(t, req, http.StatusNotFound)
+		})
+
+		t.Run("Download", func(t *testing.T) {
+			defer tests.PrintCurrentTest(t)()
+
+			req := NewRequest(t, "GET", moduleURL+"/"+moduleVersion+"/download")
+			resp := MakeRequest(t, req, http.StatusNoContent)
+
+			archiveURL := resp.Header().Get("X-Terraform-Get")
+			assert.Equal(t, setting.AppURL+strings.TrimPrefix(moduleURL, "/")+"/"+moduleVersion+"/archive.tar.gz", archiveURL)
+
+			req = NewRequest(t, "GET", archiveURL)
+			resp = MakeRequest(t, req, http.StatusOK)
+			assert.Equal(t, content, resp.Body.Bytes())
+
+			req = NewRequest(t, "GET", moduleURL+"/9.9.9/download")
+			MakeRequest(t, req, http.StatusNotFound)
+		})
+
+		t.Run("Delete", func(t *testing.T) {
+			defer tests.PrintCurrentTest(t)()
+
+			req := NewRequest(t, "DELETE", moduleURL+"/"+moduleVersion)
+			MakeRequest(t, req, http.StatusUnauthorized)
+
+			req = NewRequest(t, "DELETE", moduleURL+"/"+moduleVersion).
+				AddTokenAuth(token)
+			MakeRequest(t, req, http.StatusNoContent)
+
+			pvs, err := packages.GetVersionsByPackageType(t.Context(), user.ID, packages.TypeTerraformModule)
+			require.NoError(t, err)
+			assert.Empty(t, pvs)
+		})
+	})
+
+	t.Run("Provider", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		providerType := "dummy"
+		providerVersion := "2.1.0"
+		prefix := fmt.Sprintf("terraform-provider-%s_%s", providerType, providerVersion)
+
+		archives := map[string][]byte{
+			prefix + "_linux_amd64.zip":  []byte("linux amd64 archive"),
+			prefix + "_darwin_arm64.zip": []byte("darwin arm64 archive"),
+		}
+		var sums strings.Builder
+		for name, content := range archives {
+			hash := sha256.Sum256(content)
+			fmt.Fprintf(&sums, "%s  %s\n", hex.EncodeToString(hash[:]), name)
+		}
+		sumsContent := []byte(sums.String())
+
+		createKey := func(t *testing.T, owner *user_model.User) (*openpgp.Entity, string) {
+			e, err := openpgp.NewEntity(owner.Name, "", owner.Email, nil)
+			require.NoError(t, err)
+
+			var pub strings.Builder
+			w, err := armor.Encode(&pub, openpgp.PublicKeyType, nil)
+			require.NoError(t, err)
+			require.NoError(t, e.Serialize(w))
+			require.NoError(t, w.Close())
+
+			return e, pub.String()
+		}
+		sign := func(t *testing.T, e *openpgp.Entity) []byte {
+			var sig bytes.Buffer
+			require.NoError(t, openpgp.DetachSign(&sig, e, bytes.NewReader(sumsContent), nil))
+			return sig.Bytes()
+		}
+
+		providerURL := fmt.Sprintf("/api/packages/-/terraform/providers/v1/%s/%s", user.Name, providerType)
+		filesURL := fmt.Sprintf("%s/%s/files/", providerURL, providerVersion)
+
+		upload := func(t *testing.T, filename string, content []byte, expectedStatus int) {
+			req := NewRequestWithBody(t, "PUT", filesURL+filename, bytes.NewReader(content)).
+				AddTokenAuth(token)
+			MakeRequest(t, req, expectedStatus)
+		}
+
+		entity, publicKey := createKey(t, user)
+
+		t.Run("Upload", func(t *testing.T) {
+			defer tests.PrintCurrentTest(t)()
+
+			req := NewRequestWithBody(t, "PUT", filesURL+prefix+"_linux_amd64.zip", bytes.NewReader(archives[prefix+"_linux_amd64.zip"]))
+			MakeRequest(t, req, http.StatusUnauthorized)
+
+			upload(t, prefix+"_linux_amd64.tar.gz", []byte{}, http.StatusBadRequest)
+			upload(t, prefix+"_SHA256SUMS.sig", sign(t, entity), http.StatusBadRequest)
+
+			for name, content := range archives {
+				upload(t, name, content, http.StatusCreated)
+			}
+			upload(t, prefix+"_manifest.json", []byte(`{"version":1,"metadata":{"protocol_versions":["6.0"]}}`), http.StatusCreated)
+			upload(t, prefix+"_SHA256SUMS", sumsContent, http.StatusCreated)
+
+			// the key is not registered yet
+			upload(t, prefix+"_SHA256SUMS.sig", sign(t, entity), http.StatusBadRequest)
+
+			req = NewRequest(t, "GET", providerURL+"/versions")
+			MakeRequest(t, req, http.StatusNotFound)
+
+			_, err := asymkey_model.AddGPGKey(t.Context(), user.ID, publicKey, "", "")
+			require.NoError(t, err)
+
+			// a key of another user is not accepted
+			other := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 4})
+			otherEntity, otherPublicKey := createKey(t, other)
+			_, err = asymkey_model.AddGPGKey(t.Context(), other.ID, otherPublicKey, "", "")
+			require.NoError(t, err)
+			upload(t, prefix+"_SHA256SUMS.sig", sign(t, otherEntity), http.StatusBadRequest)
+
+			upload(t, prefix+"_SHA256SUMS.sig", sign(t, entity), http.StatusCreated)
+			upload(t, prefix+"_SHA256SUMS.sig", sign(t, entity), http.StatusConflict)
+
+			pvs, err := packages.GetVersionsByPackageType(t.Context(), user.ID, packages.TypeTerraformProvider)
+			require.NoError(t, err)
+			require.Len(t, pvs, 1)
+
+			pd, err := packages.GetPackageDescriptor(t.Context(), pvs[0])
+			require.NoError(t, err)
+			assert.Nil(t, pd.Metadata)
+			assert.Equal(t, providerType, pd.Package.Name)
+			assert.Equal(t, providerVersion, pd.Version.Version)
+			assert.Len(t, pd.Files, 5)
+			assert.Equal(t, "6.0", pd.VersionProperties.GetByName(terraform_module.PropertyProtocols))
+			assert.Equal(t, entity.PrimaryKey.KeyIdString(), pd.VersionProperties.GetByName(terraform_module.PropertySigningKeyID))
+		})
+
+		t.Run("Versions", func(t *testing.T) {
+			defer tests.PrintCurrentTest(t)()
+
+			req := NewRequest(t, "GET", providerURL+"/versions")
+			resp := MakeRequest(t, req, http.StatusOK)
+
+			type platform struct {
+				OS   string `json:"os"`
+				Arch string `json:"arch"`
+			}
+			var result struct {
+				Versions []struct {
+					Version   string     `json:"version"`
+					Protocols []string   `json:"protocols"`
+					Platforms []platform `json:"platforms"`
+				} `json:"versions"`
+			}
+			DecodeJSON(t, resp, &result)
+
+			require.Len(t, result.Versions, 1)
+			assert.Equal(t, providerVersion, result.Versions[0].Version)
+			assert.Equal(t, []string{"6.0"}, result.Versions[0].Protocols)
+			assert.ElementsMatch(t, []platform{{"linux", "amd64"}, {"darwin", "arm64"}}, result.Versions[0].Platforms)
+		})
+
+		t.Run("Download", func(t *testing.T) {
+			defer tests.PrintCurrentTest(t)()
+
+			req := NewRequest(t, "GET", fmt.Sprintf("%s/%s/download/windows/amd64", providerURL, providerVersion))
+			MakeRequest(t, req, http.StatusNotFound)
+
+			req = NewRequest(t, "GET", fmt.Sprintf("%s/%s/download/linux/amd64", providerURL, providerVersion))
+			resp := MakeRequest(t, req, http.StatusOK)
+
+			var result struct {
+				Protocols           []string `json:"protocols"`
+				OS                  string   `json:"os"`
+				Arch                string   `json:"arch"`
+				Filename            string   `json:"filename"`
+				DownloadURL         string   `json:"download_url"`
+				SHASumsURL          string   `json:"shasums_url"`
+				SHASumsSignatureURL string   `json:"shasums_signature_url"`
+				SHASum              string   `json:"shasum"`
+				SigningKeys         struct {
+					GPGPublicKeys []struct {
+						KeyID      string `json:"key_id"`
+						ASCIIArmor string `json:"ascii_armor"`
+					} `json:"gpg_public_keys"`
+				} `json:"signing_keys"`
+			}
+			DecodeJSON(t, resp, &result)
+
+			filename := prefix + "_linux_amd64.zip"
+			hash := sha256.Sum256(archives[filename])
+
+			assert.Equal(t, []string{"6.0"}, result.Protocols)
+			assert.Equal(t, "linux", result.OS)
+			assert.Equal(t, "amd64", result.Arch)
+			assert.Equal(t, filename, result.Filename)
+			assert.Equal(t, hex.EncodeToString(hash[:]), result.SHASum)
+			require.Len(t, result.SigningKeys.GPGPublicKeys, 1)
+			assert.Equal(t, entity.PrimaryKey.KeyIdString(), result.SigningKeys.GPGPublicKeys[0].KeyID)
+			assert.Equal(t, publicKey, result.SigningKeys.GPGPublicKeys[0].ASCIIArmor)
+
+			for url, expected := range map[string][]byte{
+				result.DownloadURL:         archives[filename],
+				result.SHASumsURL:          sumsContent,
+				result.SHASumsSignatureURL: nil,
+			} {
+				req = NewRequest(t, "GET", url)
+				resp = MakeRequest(t, req, http.StatusOK)
+				if expected != nil {
+					assert.Equal(t, expected, resp.Body.Bytes())
+				} else {
+					_, err := openpgp.CheckDetachedSignature(openpgp.EntityList{entity}, bytes.NewReader(sumsContent), resp.Body, nil)
+					assert.NoError(t, err)
+				}
+			}
+		})
+
+		t.Run("Delete", func(t *testing.T) {
+			defer tests.PrintCurrentTest(t)()
+
+			req := NewRequest(t, "DELETE", providerURL+"/"+providerVersion).
+				AddTokenAuth(token)
+			MakeRequest(t, req, http.StatusNoContent)
+
+			req = NewRequest(t, "GET", providerURL+"/versions")
+			MakeRequest(t, req, http.StatusNotFound)
+		})
+	})
+}
@@ -0,0 +1,234 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	auth_model "github.com/kumose/kmup/models/auth"
+	"github.com/kumose/kmup/models/db"
+	"github.com/kumose/kmup/models/unittest"
+	user_model "github.com/kumose/kmup/models/user"
+	webhook_model "github.com/kumose/kmup/models/webhook"
+	"github.com/kumose/kmup/modules/json"
+	"github.com/kumose/kmup/modules/queue"
+	api "github.com/kumose/kmup/modules/structs"
+	webhook_module "github.com/kumose/kmup/modules/webhook"
+	vulnerability_service "github.com/kumose/kmup/services/packages/vulnerability"
+	"github.com/kumose/kmup/tests"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackageVulnerability(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+
+	user := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+	session := loginUser(t, user.Name)
+	token := getTokenForLoggedInUser(t, session, auth_model.AccessTokenScopeAll)
+
+	req := NewRequestWithJSON(t, "POST", "/api/v1/user/hooks", api.CreateHookOption{
+		Type: "kmup",
+		Config: api.CreateHookOptionConfig{
+			"content_type": "json",
+			"url":          "http://127.0.0.1:1/hook",
+		},
+		Events: []string{"package"},
+		Active: true,
+	}).AddTokenAuth(token)
+	resp := MakeRequest(t, req, http.StatusCreated)
+	var hook api.Hook
+	DecodeJSON(t, resp, &hook)
+
+	packageName := "vulnerable-package"
+	packageVersion := "1.0.0"
+
+	data := "H4sIAAAAAAAA/ytITM5OTE/VL4DQelnF+XkMVAYGBgZmJiYK2MRBwNDcSIHB2NTMwNDQzMwAqA7IMDUxA9LUdgg2UFpcklgEdAql5kD8ogCnhwio5lJQUMpLzE1VslJQcihOzi9I1S9JLS7RhSYIJR2QgrLUouLM/DyQGkM9Az1D3YIiqExKanFyUWZBCVQ2BKhVwQVJDKwosbQkI78IJO/tZ+LsbRykxFXLNdA+HwWjYBSMgpENACgAbtAACAAA"
+	upload := `{
+		"_id": "` + packageName + `",
+		"name": "` + packageName + `",
+		"dist-tags": {
+			"latest": "` + packageVersion + `"
+		},
+		"versions": {
+			"` + packageVersion + `": {
+				"name": "` + packageName + `",
+				"version": "` + packageVersion + `",
+				"dist": {
+					"integrity": "sha512-yA4FJsVhetynGfOC1jFf79BuS+jrHbm0fhh+aHzCQkOaOBXKf9oBnC4a6DnLLnEsHQDRLYd00cwj8sCXpC+wIg==",
+					"shasum": "aaa7eaf852a948b0aa05afeda35b1badca155d90"
+				},
+				"dependencies": {
+					"left-pad": "^1.1.0",
+					"safe-lib": "~2.0.0"
+				}
+			}
+		},
+		"_attachments": {
+			"` + packageName + `-` + packageVersion + `.tgz": {
+				"data": "` + data + `"
+			}
+		}
+	}`
+	req = NewRequestWithBody(t, "PUT", fmt.Sprintf("/api/packages/%s/npm/%s", user.Name, packageName), strings.NewReader(upload)).
+		AddTokenAuth(token)
+	MakeRequest(t, req, http.StatusCreated)
+
+	advisoryDir := t.TempDir()
+	writeAdvisory := func(t *testing.T, id, modified, body string) {
+		content := `{"id":"` + id + `","modified":"` + modified + `",` + body + `}`
+		require.NoError(t, os.WriteFile(filepath.Join(advisoryDir, id+".json"), []byte(content), 0o644))
+	}
+	writeAdvisory(t, "GHSA-test-0001", "2024-01-01T00:00:00Z", `
+		"aliases": ["CVE-2024-0001"],
+		"summary": "Remote code execution in vulnerable-package",
+		"database_specific": {"severity": "HIGH"},
+		"references": [{"type": "ADVISORY", "url": "https://example.com/GHSA-test-0001"}],
+		"affected": [{"package": {"ecosystem": "npm", "name": "vulnerable-package"}, "ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}, {"fixed": "1.2.0"}]}]}]`)
+	writeAdvisory(t, "GHSA-test-0002", "2024-01-01T00:00:00Z", `
+		"summary": "Prototype pollution in left-pad",
+		"database_specific": {"severity": "MODERATE"},
+		"affected": [{"package": {"ecosystem": "npm", "name": "left-pad"}, "ranges": [{"type": "SEMVER", "events": [{"introduced": "1.0.0"}, {"fixed": "1.3.0"}]}]}]`)
+	writeAdvisory(t, "GHSA-test-0003", "2024-01-01T00:00:00Z", `
+		"summary": "Fixed before the declared requirement",
+		"affected": [{"package": {"ecosystem": "npm", "name": "safe-lib"}, "ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}, {"fixed": "1.5.0"}]}]}]`)
+	writeAdvisory(t, "PYSEC-test-0004", "2024-01-01T00:00:00Z", `
+		"summary": "Other ecosystem",
+		"affected": [{"package": {"ecosystem": "PyPI", "name": "vulnerable-package"}, "versions": ["1.0.0"]}]`)
+
+	vulnerabilitiesURL := fmt.Sprintf("/api/v1/packages/%s/npm/%s/%s/vulnerabilities", user.Name, packageName, packageVersion)
+
+	getVulnerabilities := func(t *testing.T) []*api.PackageVulnerability {
+		req := NewRequest(t, "GET", vulnerabilitiesURL).AddTokenAuth(token)
+		resp := MakeRequest(t, req, http.StatusOK)
+
+		var vulnerabilities []*api.PackageVulnerability
+		DecodeJSON(t, resp, &vulnerabilities)
+		return vulnerabilities
+	}
+
+	importAdvisories := func(t *testing.T) int {
+		imported, err := vulnerability_service.ImportAdvisories(t.Context(), advisoryDir)
+		require.NoError(t, err)
+		require.NoError(t, queue.GetManager().FlushAll(t.Context(), 10*time.Second))
+		return imported
+	}
+
+	t.Run("Import", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		assert.Empty(t, getVulnerabilities(t))
+
+		assert.Equal(t, 4, importAdvisories(t))
+
+		vulnerabilities := getVulnerabilities(t)
+		require.Len(t, vulnerabilities, 2)
+
+		byID := make(map[string]*api.PackageVulnerability)
+		for _, v := range vulnerabilities {
+			byID[v.AdvisoryID] = v
+		}
+
+		v := byID["GHSA-test-0001"]
+		require.NotNil(t, v)
+		assert.Equal(t, packageName, v.Package)
+		assert.Equal(t, packageVersion, v.Version)
+		assert.Empty(t, v.Requirement)
+		assert.Equal(t, "high", v.Severity)
+		assert.Equal(t, []string{"CVE-2024-0001"}, v.Aliases)
+		assert.Equal(t, []string{"1.2.0"}, v.FixedVersions)
+		assert.Equal(t, "https://example.com/GHSA-test-0001", v.URL)
+
+		v = byID["GHSA-test-0002"]
+		require.NotNil(t, v)
+		assert.Equal(t, "left-pad", v.Package)
+		assert.Equal(t, "1.1.0", v.Version)
+		assert.Equal(t, "^1.1.0", v.Requirement)
+		assert.Equal(t, "moderate", v.Severity)
+	})
+
+	t.Run("Webhook", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		tasks := make([]*webhook_model.HookTask, 0, 5)
+		require.NoError(t, db.GetEngine(t.Context()).Where("hook_id = ? AND event_type = ?", hook.ID, webhook_module.HookEventPackage).Find(&tasks))
+
+		var vulnerable []*api.PackagePayload
+		for _, task := range tasks {
+			var payload api.PackagePayload
+			require.NoError(t, json.Unmarshal([]byte(task.PayloadContent), &payload))
+			if payload.Action == api.HookPackageVulnerable {
+				vulnerable = append(vulnerable, &payload)
+			}
+		}
+		require.Len(t, vulnerable, 1)
+		assert.Equal(t, packageName, vulnerable[0].Package.Name)
+		assert.Len(t, vulnerable[0].Vulnerabilities, 2)
+	})
+
+	t.Run("View", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		req := NewRequest(t, "GET", fmt.Sprintf("/%s/-/packages/npm/%s/%s", user.Name, packageName, packageVersion))
+		resp := session.MakeRequest(t, req, http.StatusOK)
+
+		body := resp.Body.String()
+		assert.Contains(t, body, "GHSA-test-0001")
+		assert.Contains(t, body, "GHSA-test-0002")
+		assert.NotContains(t, body, "GHSA-test-0003")
+	})
+
+	t.Run("Unchanged", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		assert.Equal(t, 0, importAdvisories(t))
+		assert.Len(t, getVulnerabilities(t), 2)
+	})
+
+	t.Run("Withdrawn", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		writeAdvisory(t, "GHSA-test-0002", "2024-02-01T00:00:00Z", `
+			"withdrawn": "2024-02-01T00:00:00Z",
+			"affected": [{"package": {"ecosystem": "npm", "name": "left-pad"}, "ranges": [{"type": "SEMVER", "events": [{"introduced": "1.0.0"}, {"fixed": "1.3.0"}]}]}]`)
+
+		assert.Equal(t, 0, importAdvisories(t))
+
+		vulnerabilities := getVulnerabilities(t)
+		require.Len(t, vulnerabilities, 1)
+		assert.Equal(t, "GHSA-test-0001", vulnerabilities[0].AdvisoryID)
+	})
+
+	t.Run("Updated", func(t *testing.T) {
+		defer tests.PrintCurrentTest(t)()
+
+		writeAdvisory(t, "GHSA-test-0001", "2024-03-01T00:00:00Z", `
+			"summary": "Remote code execution in vulnerable-package",
+			"affected": [{"package": {"ecosystem": "npm", "name": "vulnerable-package"}, "ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}, {"fixed": "0.9.0"}]}]}]`)
+
+		assert.Equal(t, 1, importAdvisories(t))
+		assert.Empty(t, getVulnerabilities(t))
+	})
+}
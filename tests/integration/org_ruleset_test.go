@@ -0,0 +1,253 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	auth_model "github.com/kumose/kmup/models/auth"
+	issues_model "github.com/kumose/kmup/models/issues"
+	repo_model "github.com/kumose/kmup/models/repo"
+	"github.com/kumose/kmup/models/unittest"
+	user_model "github.com/kumose/kmup/models/user"
+	"github.com/kumose/kmup/modules/git/gitcmd"
+	api "github.com/kumose/kmup/modules/structs"
+	"github.com/kumose/kmup/modules/util"
+	"github.com/kumose/kmup/services/forms"
+	"github.com/kumose/kmup/tests"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrgRulesets(t *testing.T) {
+	onKmupRun(t, func(t *testing.T, u *url.URL) {
+		token := getUserToken(t, "user2", auth_model.AccessTokenScopeWriteOrganization)
+		rulesetsURL := "/api/v1/orgs/org3/rulesets"
+
+		req := NewRequestWithJSON(t, "POST", rulesetsURL, &api.CreateRulesetOption{
+			Name:           "release",
+			RepoTopics:     []string{"unknown"},
+			RefPatterns:    []string{"refs/heads/release/*", "refs/tags/v*"},
+			BlockDeletion:  true,
+			BlockForcePush: true,
+		}).AddTokenAuth(token)
+		resp := MakeRequest(t, req, http.StatusCreated)
+		var ruleset api.Ruleset
+		DecodeJSON(t, resp, &ruleset)
+		assert.Equal(t, "active", ruleset.Enforcement)
+		assert.Equal(t, []string{"refs/heads/release/*", "refs/tags/v*"}, ruleset.RefPatterns)
+		rulesetURL := fmt.Sprintf("%s/%d", rulesetsURL, ruleset.ID)
+
+		req = NewRequestWithJSON(t, "POST", rulesetsURL, &api.CreateRulesetOption{Name: "release", RefPatterns: []string{"refs/heads/*"}}).AddTokenAuth(token)
+		MakeRequest(t, req, http.StatusConflict)
+		req = NewRequestWithJSON(t, "POST", rulesetsURL, &api.CreateRulesetOption{Name: "invalid", RefPatterns: []string{"main"}}).AddTokenAuth(token)
+		MakeRequest(t, req, http.StatusUnprocessableEntity)
+
+		editRuleset := func(t *testing.T, opts api.EditRulesetOption) api.Ruleset {
+			resp := MakeRequest(t, NewRequestWithJSON(t, "PATCH", rulesetURL, &opts).AddTokenAuth(token), http.StatusOK)
+			var ruleset api.Ruleset
+			DecodeJSON(t, resp, &ruleset)
+			return ruleset
+		}
+
+		u.Path = "org3/repo3.git"
+		u.User = url.UserPassword("user2", userPassword)
+		dstPath := t.TempDir()
+		t.Run("Clone", doGitClone(dstPath, u))
+		doGitCreateBranch(dstPath, "release/1")(t)
+		doGitPushTestRepository(dstPath, "origin", "release/1")(t)
+		_, _, err := gitcmd.NewCommand("tag", "v1").WithDir(dstPath).RunStdString(t.Context())
+		require.NoError(t, err)
+		doGitPushTestRepository(dstPath, "origin", "v1")(t)
+
+		pushFail := func(t *testing.T, args ...string) string {
+			_, stderr, err := gitcmd.NewCommand("push").AddArguments(gitcmd.ToTrustedCmdArgs(args)...).WithDir(dstPath).RunStdString(t.Context())
+			require.Error(t, err)
+			return stderr
+		}
+
+		t.Run("NotMatchingRepo", func(t *testing.T) {
+			doGitPushTestRepository(dstPath, "origin", "--delete", "v1")(t)
+			doGitPushTestRepository(dstPath, "origin", "v1")(t)
+			editRuleset(t, api.EditRulesetOption{RepoTopics: []string{}})
+		})
+
+		t.Run("Active", func(t *testing.T) {
+			assert.Contains(t, pushFail(t, "origin", "--delete", "release/1"), "branch release/1 is protected from deletion")
+			assert.Contains(t, pushFail(t, "origin", "--delete", "v1"), `push to v1 rejected by ruleset "release": deletion is blocked`)
+
+			doGitCheckoutWriteFileCommit(localGitAddCommitOptions{
+				LocalRepoPath:   dstPath,
+				CheckoutBranch:  "release/1",
+				TreeFilePath:    "release.txt",
+				TreeFileContent: "release",
+			})(t)
+			doGitPushTestRepository(dstPath, "origin", "release/1")(t)
+
+			_, _, err := gitcmd.NewCommand("tag", "-f", "v1").WithDir(dstPath).RunStdString(t.Context())
+			require.NoError(t, err)
+			assert.Contains(t, pushFail(t, "-f", "origin", "v1"), "moving the tag is blocked")
+
+			_, _, err = gitcmd.NewCommand("reset", "--hard", "origin/master").WithDir(dstPath).RunStdString(t.Context())
+			require.NoError(t, err)
+			assert.Contains(t, pushFail(t, "-f", "origin", "release/1"), "branch release/1 is protected from force push")
+		})
+
+		t.Run("Bypass", func(t *testing.T) {
+			ruleset := editRuleset(t, api.EditRulesetOption{BypassUsers: []string{"user2"}})
+			assert.Equal(t, []string{"user2"}, ruleset.BypassUsers)
+			doGitPushTestRepository(dstPath, "-f", "origin", "v1")(t)
+		})
+
+		t.Run("Evaluate", func(t *testing.T) {
+			ruleset := editRuleset(t, api.EditRulesetOption{Enforcement: util.ToPointer("evaluate"), BypassUsers: []string{}})
+			assert.Empty(t, ruleset.BypassUsers)
+			doGitPushTestRepository(dstPath, "origin", "--delete", "release/1")(t)
+
+			resp := MakeRequest(t, NewRequest(t, "GET", rulesetURL+"/evaluations").AddTokenAuth(token), http.StatusOK)
+			var evaluations []*api.RulesetEvaluation
+			DecodeJSON(t, resp, &evaluations)
+			require.Len(t, evaluations, 1)
+			assert.Equal(t, "refs/heads/release/1", evaluations[0].RefName)
+			assert.Equal(t, "deletion is blocked", evaluations[0].Reason)
+			assert.Equal(t, "user2", evaluations[0].Pusher.UserName)
+			assert.Equal(t, "org3/repo3", evaluations[0].Repository.FullName)
+		})
+
+		MakeRequest(t, NewRequest(t, "DELETE", rulesetURL).AddTokenAuth(token), http.StatusNoContent)
+		MakeRequest(t, NewRequest(t, "GET", rulesetURL).AddTokenAuth(token), http.StatusNotFound)
+		doGitPushTestRepository(dstPath, "origin", "--delete", "v1")(t)
+	})
+}
+
+func TestOrgRulesetsPullRequest(t *testing.T) {
+	onKmupRun(t, func(t *testing.T, u *url.URL) {
+		user2 := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+		repo3 := unittest.AssertExistsAndLoadBean(t, &repo_model.Repository{OwnerName: "org3", Name: "repo3"})
+		testCreateFileInBranch(t, user2, repo3, createFileInBranchOptions{OldBranch: "master", NewBranch: "ruleset-pr"}, map[string]string{"ruleset.txt": "ruleset"})
+
+		session := loginUser(t, "user2")
+		orgToken := getTokenForLoggedInUser(t, session, auth_model.AccessTokenScopeWriteOrganization)
+		token := getTokenForLoggedInUser(t, session, auth_model.AccessTokenScopeWriteRepository)
+
+		req := NewRequestWithJSON(t, "POST", "/api/v1/orgs/org3/rulesets", &api.CreateRulesetOption{
+			Name:              "approvals",
+			RefPatterns:       []string{"~DEFAULT_BRANCH"},
+			RequiredApprovals: 1,
+		}).AddTokenAuth(orgToken)
+		var ruleset api.Ruleset
+		DecodeJSON(t, MakeRequest(t, req, http.StatusCreated), &ruleset)
+		rulesetURL := fmt.Sprintf("/api/v1/orgs/org3/rulesets/%d", ruleset.ID)
+
+		req = NewRequestWithJSON(t, "POST", "/api/v1/repos/org3/repo3/pulls", &api.CreatePullRequestOption{
+			Head:  "ruleset-pr",
+			Base:  "master",
+			Title: "ruleset pull request",
+		}).AddTokenAuth(token)
+		var apiPull api.PullRequest
+		DecodeJSON(t, MakeRequest(t, req, http.StatusCreated), &apiPull)
+		assert.Eventually(t, func() bool {
+			pr := unittest.AssertExistsAndLoadBean(t, &issues_model.PullRequest{ID: apiPull.ID})
+			return pr.Status == issues_model.PullRequestStatusMergeable
+		}, 10*time.Second, 100*time.Millisecond)
+
+		getBranch := func(t *testing.T) *api.Branch {
+			var branch api.Branch
+			DecodeJSON(t, MakeRequest(t, NewRequest(t, "GET", "/api/v1/repos/org3/repo3/branches/master").AddTokenAuth(token), http.StatusOK), &branch)
+
+			var branches []*api.Branch
+			DecodeJSON(t, MakeRequest(t, NewRequest(t, "GET", "/api/v1/repos/org3/repo3/branches").AddTokenAuth(token), http.StatusOK), &branches)
+			idx := slices.IndexFunc(branches, func(b *api.Branch) bool { return b.Name == "master" })
+			require.NotEqual(t, -1, idx)
+			assert.Equal(t, branch.Protected, branches[idx].Protected)
+			assert.Equal(t, branch.RequiredApprovals, branches[idx].RequiredApprovals)
+			return &branch
+		}
+		pullURL := fmt.Sprintf("/org3/repo3/pulls/%d", apiPull.Index)
+		mergeURL := fmt.Sprintf("/api/v1/repos/org3/repo3/pulls/%d/merge", apiPull.Index)
+
+		t.Run("Active", func(t *testing.T) {
+			branch := getBranch(t)
+			assert.True(t, branch.Protected)
+			assert.EqualValues(t, 1, branch.RequiredApprovals)
+
+			resp := session.MakeRequest(t, NewRequest(t, "GET", pullURL), http.StatusOK)
+			assert.Contains(t, resp.Body.String(), "0 of 1 official approvals granted")
+
+			req := NewRequestWithJSON(t, "POST", mergeURL, &forms.MergePullRequestForm{Do: string(repo_model.MergeStyleMerge)}).AddTokenAuth(token)
+			MakeRequest(t, req, http.StatusMethodNotAllowed)
+		})
+
+		t.Run("Bypass", func(t *testing.T) {
+			req := NewRequestWithJSON(t, "PATCH", rulesetURL, &api.EditRulesetOption{BypassUsers: []string{"user2"}}).AddTokenAuth(orgToken)
+			MakeRequest(t, req, http.StatusOK)
+
+			branch := getBranch(t)
+			assert.False(t, branch.Protected)
+
+			resp := session.MakeRequest(t, NewRequest(t, "GET", pullURL), http.StatusOK)
+			assert.NotContains(t, resp.Body.String(), "official approvals granted")
+
+			req = NewRequestWithJSON(t, "POST", mergeURL, &forms.MergePullRequestForm{Do: string(repo_model.MergeStyleMerge)}).AddTokenAuth(token)
+			MakeRequest(t, req, http.StatusOK)
+		})
+	})
+}
+
+func TestOrgRulesetsSettings(t *testing.T) {
+	defer tests.PrepareTestEnv(t)()
+
+	session := loginUser(t, "user2")
+	req := NewRequestWithValues(t, "POST", "/org/org3/settings/rulesets/new", map[string]string{
+		"_csrf":            GetUserCSRFToken(t, session),
+		"name":             "main",
+		"enforcement":      "evaluate",
+		"ref_patterns":     "~DEFAULT_BRANCH\nrefs/heads/release/*",
+		"block_force_push": "on",
+		"bypass_users":     "2",
+	})
+	session.MakeRequest(t, req, http.StatusSeeOther)
+
+	resp := session.MakeRequest(t, NewRequest(t, "GET", "/org/org3/settings/rulesets"), http.StatusOK)
+	htmlDoc := NewHTMLParser(t, resp.Body)
+	link, exists := htmlDoc.Find(".flex-item-title a").Attr("href")
+	require.True(t, exists)
+
+	resp = session.MakeRequest(t, NewRequest(t, "GET", link), http.StatusOK)
+	htmlDoc = NewHTMLParser(t, resp.Body)
+	assert.Equal(t, "main", htmlDoc.GetInputValueByName("name"))
+	assert.Equal(t, "2", htmlDoc.GetInputValueByName("bypass_users"))
+	assert.Equal(t, "~DEFAULT_BRANCH\nrefs/heads/release/*", htmlDoc.Find("textarea[name=ref_patterns]").Text())
+
+	req = NewRequestWithValues(t, "POST", link, map[string]string{
+		"_csrf":        GetUserCSRFToken(t, session),
+		"name":         "main",
+		"ref_patterns": "main",
+	})
+	resp = session.MakeRequest(t, req, http.StatusOK)
+	assert.Contains(t, resp.Body.String(), "must start with refs/heads/ or refs/tags/")
+
+	session.MakeRequest(t, NewRequestWithValues(t, "POST", link+"/delete", map[string]string{"_csrf": GetUserCSRFToken(t, session)}), http.StatusOK)
+	loginUser(t, "user4").MakeRequest(t, NewRequest(t, "GET", "/org/org3/settings/rulesets"), http.StatusNotFound)
+}
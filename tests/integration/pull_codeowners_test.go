@@ -0,0 +1,202 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	auth_model "github.com/kumose/kmup/models/auth"
+	issues_model "github.com/kumose/kmup/models/issues"
+	"github.com/kumose/kmup/models/unittest"
+	user_model "github.com/kumose/kmup/models/user"
+	"github.com/kumose/kmup/modules/git"
+	"github.com/kumose/kmup/modules/gitrepo"
+	api "github.com/kumose/kmup/modules/structs"
+	"github.com/kumose/kmup/modules/util"
+	pull_service "github.com/kumose/kmup/services/pull"
+	repo_service "github.com/kumose/kmup/services/repository"
+	files_service "github.com/kumose/kmup/services/repository/files"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPullCodeOwnerApproval(t *testing.T) {
+	onKmupRun(t, func(t *testing.T, u *url.URL) {
+		user2 := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+		user4 := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 4})
+		user5 := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 5})
+		user8 := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 8})
+
+		repo, err := repo_service.CreateRepositoryDirectly(t.Context(), user2, user2, repo_service.CreateRepoOptions{
+			Name:             "test_codeowner_approval",
+			Readme:           "Default",
+			AutoInit:         true,
+			ObjectFormatName: git.Sha1ObjectFormat.Name(),
+			DefaultBranch:    "master",
+		}, true)
+		require.NoError(t, err)
+
+		_, err = files_service.ChangeRepoFiles(t.Context(), repo, user2, &files_service.ChangeRepoFilesOptions{
+			OldBranch: repo.DefaultBranch,
+			Files: []*files_service.ChangeRepoFile{
+				{
+					Operation:     "create",
+					TreePath:      "CODEOWNERS",
+					ContentReader: strings.NewReader("README.md @user5\nuser8-file.md @user8\ndocs/.* @org3/team1\nposter.md @user2\n"),
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		session := loginUser(t, user2.Name)
+		token := getTokenForLoggedInUser(t, session, auth_model.AccessTokenScopeWriteRepository)
+
+		req := NewRequestWithJSON(t, "POST", fmt.Sprintf("/api/v1/repos/%s/%s/branch_protections", user2.Name, repo.Name), &api.CreateBranchProtectionOption{
+			RuleName:                 repo.DefaultBranch,
+			RequireCodeOwnerApproval: true,
+		}).AddTokenAuth(token)
+		resp := MakeRequest(t, req, http.StatusCreated)
+		var protection api.BranchProtection
+		DecodeJSON(t, resp, &protection)
+		assert.True(t, protection.RequireCodeOwnerApproval)
+
+		_, err = files_service.ChangeRepoFiles(t.Context(), repo, user2, &files_service.ChangeRepoFilesOptions{
+			NewBranch: "codeowner-approval",
+			Files: []*files_service.ChangeRepoFile{
+				{
+					Operation:     "update",
+					TreePath:      "README.md",
+					ContentReader: strings.NewReader("# Changed\n"),
+				},
+				{
+					Operation:     "create",
+					TreePath:      "user8-file.md",
+					ContentReader: strings.NewReader("# User 8\n"),
+				},
+				{
+					Operation:     "create",
+					TreePath:      "docs/guide.md",
+					ContentReader: strings.NewReader("# Guide\n"),
+				},
+				{
+					Operation:     "create",
+					TreePath:      "poster.md",
+					ContentReader: strings.NewReader("# Poster\n"),
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		testPullCreate(t, session, user2.Name, repo.Name, false, repo.DefaultBranch, "codeowner-approval", "Code owner approval")
+		pr := unittest.AssertExistsAndLoadBean(t, &issues_model.PullRequest{BaseRepoID: repo.ID, HeadBranch: "codeowner-approval"})
+		require.NoError(t, pr.LoadIssue(t.Context()))
+		require.NoError(t, pr.Issue.LoadRepo(t.Context()))
+
+		getMissing := func(t *testing.T) map[string][]string {
+			req := NewRequest(t, "GET", fmt.Sprintf("/api/v1/repos/%s/%s/pulls/%d/code_owners", user2.Name, repo.Name, pr.Index)).AddTokenAuth(token)
+			resp := MakeRequest(t, req, http.StatusOK)
+
+			var missing []*api.MissingCodeOwnerApproval
+			DecodeJSON(t, resp, &missing)
+
+			result := make(map[string][]string, len(missing))
+			for _, m := range missing {
+				owners := make([]string, 0, len(m.Users)+len(m.Teams))
+				for _, u := range m.Users {
+					owners = append(owners, u.UserName)
+				}
+				for _, team := range m.Teams {
+					owners = append(owners, team.Organization.UserName+"/"+team.Name)
+				}
+				result[m.Path] = owners
+			}
+			return result
+		}
+
+		approve := func(t *testing.T, doer *user_model.User) {
+			gitRepo, err := gitrepo.OpenRepository(t.Context(), repo)
+			require.NoError(t, err)
+			defer gitRepo.Close()
+
+			commitID, err := gitRepo.GetRefCommitID(pr.GetGitHeadRefName())
+			require.NoError(t, err)
+
+			_, _, err = pull_service.SubmitReview(t.Context(), doer, gitRepo, pr.Issue, issues_model.ReviewTypeApprove, "", commitID, nil)
+			require.NoError(t, err)
+		}
+
+		// the poster doesn't need to approve the own pull request
+		assert.Equal(t, map[string][]string{
+			"README.md":     {"user5"},
+			"user8-file.md": {"user8"},
+			"docs/guide.md": {"org3/team1"},
+		}, getMissing(t))
+
+		err = pull_service.CheckPullBranchProtections(t.Context(), pr, false)
+		assert.ErrorIs(t, err, pull_service.ErrNotReadyToMerge)
+
+		req = NewRequest(t, "GET", fmt.Sprintf("/%s/%s/pulls/%d", user2.Name, repo.Name, pr.Index))
+		resp = session.MakeRequest(t, req, http.StatusOK)
+		assert.Contains(t, resp.Body.String(), "docs/guide.md")
+
+		approve(t, user5)
+		approve(t, user4)
+
+		assert.Equal(t, map[string][]string{
+			"user8-file.md": {"user8"},
+		}, getMissing(t))
+
+		approve(t, user8)
+
+		assert.Empty(t, getMissing(t))
+		assert.NoError(t, pull_service.CheckPullBranchProtections(t.Context(), pr, false))
+
+		// a new commit makes the approvals stale, which still count unless the rule ignores them
+		_, err = files_service.ChangeRepoFiles(t.Context(), repo, user2, &files_service.ChangeRepoFilesOptions{
+			OldBranch: "codeowner-approval",
+			Files: []*files_service.ChangeRepoFile{
+				{
+					Operation:     "update",
+					TreePath:      "README.md",
+					ContentReader: strings.NewReader("# Changed again\n"),
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		assert.Eventually(t, func() bool {
+			return unittest.GetCount(t, &issues_model.Review{IssueID: pr.IssueID, Type: issues_model.ReviewTypeApprove, Stale: true}) == 3
+		}, 10*time.Second, 200*time.Millisecond)
+		assert.Empty(t, getMissing(t))
+
+		req = NewRequestWithJSON(t, "PATCH", fmt.Sprintf("/api/v1/repos/%s/%s/branch_protections/%s", user2.Name, repo.Name, repo.DefaultBranch), &api.EditBranchProtectionOption{
+			IgnoreStaleApprovals: util.ToPointer(true),
+		}).AddTokenAuth(token)
+		MakeRequest(t, req, http.StatusOK)
+
+		assert.Len(t, getMissing(t), 3)
+		err = pull_service.CheckPullBranchProtections(t.Context(), pr, false)
+		assert.ErrorIs(t, err, pull_service.ErrNotReadyToMerge)
+	})
+}
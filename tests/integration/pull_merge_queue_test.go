@@ -0,0 +1,226 @@
+// Copyright (C) Kumo inc. and its affiliates.
+// Author: Jeff.li lijippy@163.com
+// All rights reserved.
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+//
+
+package integration
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	auth_model "github.com/kumose/kmup/models/auth"
+	issues_model "github.com/kumose/kmup/models/issues"
+	pull_model "github.com/kumose/kmup/models/pull"
+	repo_model "github.com/kumose/kmup/models/repo"
+	"github.com/kumose/kmup/models/unittest"
+	user_model "github.com/kumose/kmup/models/user"
+	webhook_model "github.com/kumose/kmup/models/webhook"
+	"github.com/kumose/kmup/modules/commitstatus"
+	"github.com/kumose/kmup/modules/git"
+	"github.com/kumose/kmup/modules/gitrepo"
+	api "github.com/kumose/kmup/modules/structs"
+	webhook_module "github.com/kumose/kmup/modules/webhook"
+	"github.com/kumose/kmup/services/forms"
+	repo_service "github.com/kumose/kmup/services/repository"
+	files_service "github.com/kumose/kmup/services/repository/files"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPullMergeQueue(t *testing.T) {
+	onKmupRun(t, func(t *testing.T, u *url.URL) {
+		user2 := unittest.AssertExistsAndLoadBean(t, &user_model.User{ID: 2})
+
+		repo, err := repo_service.CreateRepositoryDirectly(t.Context(), user2, user2, repo_service.CreateRepoOptions{
+			Name:             "test_merge_queue",
+			Readme:           "Default",
+			AutoInit:         true,
+			ObjectFormatName: git.Sha1ObjectFormat.Name(),
+			DefaultBranch:    "master",
+		}, true)
+		require.NoError(t, err)
+
+		session := loginUser(t, user2.Name)
+		token := getTokenForLoggedInUser(t, session, auth_model.AccessTokenScopeWriteRepository)
+		repoAPIURL := fmt.Sprintf("/api/v1/repos/%s/%s", user2.Name, repo.Name)
+
+		req := NewRequestWithJSON(t, "POST", repoAPIURL+"/branch_protections", &api.CreateBranchProtectionOption{
+			RuleName:            repo.DefaultBranch,
+			EnableStatusCheck:   true,
+			StatusCheckContexts: []string{"ci/merge-group"},
+			EnableMergeQueue:    true,
+			// the merge groups are built on top of the base branch, so the queued pull requests can't be outdated
+			BlockOnOutdatedBranch: true,
+		}).AddTokenAuth(token)
+		resp := MakeRequest(t, req, http.StatusCreated)
+		var protection api.BranchProtection
+		DecodeJSON(t, resp, &protection)
+		assert.True(t, protection.EnableMergeQueue)
+
+		req = NewRequestWithJSON(t, "POST", repoAPIURL+"/hooks", &api.CreateHookOption{
+			Type: "kmup",
+			Config: api.CreateHookOptionConfig{
+				"content_type": "json",
+				"url":          "http://127.0.0.1:1/merge-group-hook",
+			},
+			Events: []string{string(webhook_module.HookEventMergeGroup)},
+			Active: true,
+		}).AddTokenAuth(token)
+		resp = MakeRequest(t, req, http.StatusCreated)
+		var hook api.Hook
+		DecodeJSON(t, resp, &hook)
+
+		// three pull requests which change different files, so they can be merged together
+		pulls := make([]*issues_model.PullRequest, 0, 3)
+		for i := 1; i <= 3; i++ {
+			branch := fmt.Sprintf("queued-%d", i)
+			_, err = files_service.ChangeRepoFiles(t.Context(), repo, user2, &files_service.ChangeRepoFilesOptions{
+				OldBranch: repo.DefaultBranch,
+				NewBranch: branch,
+				Files: []*files_service.ChangeRepoFile{
+					{
+						Operation:     "create",
+						TreePath:      branch + ".md",
+						ContentReader: strings.NewReader("# " + branch + "\n"),
+					},
+				},
+			})
+			require.NoError(t, err)
+
+			testPullCreate(t, session, user2.Name, repo.Name, false, repo.DefaultBranch, branch, "Merge queue "+branch)
+			pr := unittest.AssertExistsAndLoadBean(t, &issues_model.PullRequest{BaseRepoID: repo.ID, HeadBranch: branch})
+			assert.Eventually(t, func() bool {
+				pr = unittest.AssertExistsAndLoadBean(t, &issues_model.PullRequest{ID: pr.ID})
+				return pr.CanAutoMerge()
+			}, 10*time.Second, 100*time.Millisecond)
+			pulls = append(pulls, pr)
+		}
+
+		// merging a pull request adds it to the merge queue
+		for _, pr := range pulls {
+			req = NewRequestWithJSON(t, "POST", fmt.Sprintf("%s/pulls/%d/merge", repoAPIURL, pr.Index), &forms.MergePullRequestForm{
+				Do: string(repo_model.MergeStyleMerge),
+			}).AddTokenAuth(token)
+			MakeRequest(t, req, http.StatusAccepted)
+		}
+		req = NewRequestWithJSON(t, "POST", fmt.Sprintf("%s/pulls/%d/merge", repoAPIURL, pulls[0].Index), &forms.MergePullRequestForm{
+			Do: string(repo_model.MergeStyleMerge),
+		}).AddTokenAuth(token)
+		MakeRequest(t, req, http.StatusConflict)
+
+		getQueue := func(t *testing.T) []*api.MergeQueueEntry {
+			req := NewRequest(t, "GET", repoAPIURL+"/pulls/merge_queue?branch="+repo.DefaultBranch).AddTokenAuth(token)
+			resp := MakeRequest(t, req, http.StatusOK)
+			var entries []*api.MergeQueueEntry
+			DecodeJSON(t, resp, &entries)
+			return entries
+		}
+
+		var queue []*api.MergeQueueEntry
+		assert.Eventually(t, func() bool {
+			queue = getQueue(t)
+			return len(queue) == 3 && queue[0].GroupSHA != "" && queue[1].GroupSHA != "" && queue[2].GroupSHA != ""
+		}, 10*time.Second, 100*time.Millisecond)
+		require.Len(t, queue, 3)
+		for i, entry := range queue {
+			assert.Equal(t, pulls[i].Index, entry.PullRequest.Index)
+		}
+		assert.Equal(t, commitstatus.CommitStatusPending.String(), queue[0].State)
+		// the merge groups are stacked on each other
+		assert.Equal(t, queue[0].GroupSHA, queue[1].BaseSHA)
+		assert.Equal(t, queue[1].GroupSHA, queue[2].BaseSHA)
+		assert.Equal(t, git.BranchPrefix+fmt.Sprintf("kmup-merge-queue/master/pr-%d", pulls[0].Index), queue[0].GroupRef)
+
+		gitRepo, err := gitrepo.OpenRepository(t.Context(), repo)
+		require.NoError(t, err)
+		defer gitRepo.Close()
+		for _, entry := range queue {
+			commitID, err := gitRepo.GetRefCommitID(entry.GroupRef)
+			require.NoError(t, err)
+			assert.Equal(t, entry.GroupSHA, commitID)
+		}
+		assert.Positive(t, unittest.GetCount(t, &webhook_model.HookTask{HookID: hook.ID, EventType: webhook_module.HookEventMergeGroup}))
+
+		createStatus := func(t *testing.T, sha string, state commitstatus.CommitStatusState) {
+			req := NewRequestWithJSON(t, "POST", fmt.Sprintf("%s/statuses/%s", repoAPIURL, sha), &api.CreateStatusOption{
+				State:   state,
+				Context: "ci/merge-group",
+			}).AddTokenAuth(token)
+			MakeRequest(t, req, http.StatusCreated)
+		}
+
+		// a failed check ejects the pull request from the queue at once, even if it isn't at the head of the queue
+		createStatus(t, queue[1].GroupSHA, commitstatus.CommitStatusFailure)
+		var rebuilt []*api.MergeQueueEntry
+		assert.Eventually(t, func() bool {
+			rebuilt = getQueue(t)
+			return len(rebuilt) == 2 && rebuilt[1].BaseSHA == queue[0].GroupSHA
+		}, 10*time.Second, 100*time.Millisecond)
+		require.Len(t, rebuilt, 2)
+		assert.Equal(t, pulls[0].Index, rebuilt[0].PullRequest.Index)
+		assert.Equal(t, pulls[2].Index, rebuilt[1].PullRequest.Index)
+		assert.Equal(t, queue[0].GroupSHA, rebuilt[0].GroupSHA)
+		ejected := unittest.AssertExistsAndLoadBean(t, &issues_model.PullRequest{ID: pulls[1].ID})
+		assert.False(t, ejected.HasMerged)
+		unittest.AssertExistsAndLoadBean(t, &issues_model.Comment{IssueID: ejected.IssueID, Type: issues_model.CommentTypePRRemovedFromMergeQueue, Content: pull_model.MergeQueueRemovedChecksFailed})
+		assert.False(t, gitrepo.IsReferenceExist(t.Context(), repo, queue[1].GroupRef))
+
+		// the pull request isn't merged before the checks of its merge group succeed
+		pr := unittest.AssertExistsAndLoadBean(t, &issues_model.PullRequest{ID: pulls[0].ID})
+		assert.False(t, pr.HasMerged)
+
+		createStatus(t, rebuilt[0].GroupSHA, commitstatus.CommitStatusSuccess)
+		assert.Eventually(t, func() bool {
+			pr = unittest.AssertExistsAndLoadBean(t, &issues_model.PullRequest{ID: pulls[0].ID})
+			return pr.HasMerged
+		}, 10*time.Second, 100*time.Millisecond)
+		assert.Equal(t, rebuilt[0].GroupSHA, pr.MergedCommitID)
+		masterCommitID, err := gitRepo.GetBranchCommitID(repo.DefaultBranch)
+		require.NoError(t, err)
+		assert.Equal(t, rebuilt[0].GroupSHA, masterCommitID)
+		unittest.AssertExistsAndLoadBean(t, &issues_model.Comment{IssueID: pr.IssueID, Type: issues_model.CommentTypePRRemovedFromMergeQueue, Content: pull_model.MergeQueueRemovedMerged})
+		assert.False(t, gitrepo.IsReferenceExist(t.Context(), repo, rebuilt[0].GroupRef))
+
+		// the last merge group is still valid, so it isn't rebuilt
+		assert.Eventually(t, func() bool {
+			remaining := getQueue(t)
+			return len(remaining) == 1 && remaining[0].GroupSHA == rebuilt[1].GroupSHA
+		}, 10*time.Second, 100*time.Millisecond)
+
+		// the head branch of the last pull request is behind the base branch now, but its merge group contains the base branch
+		assert.Eventually(t, func() bool {
+			pr = unittest.AssertExistsAndLoadBean(t, &issues_model.PullRequest{ID: pulls[2].ID})
+			return pr.CommitsBehind > 0
+		}, 10*time.Second, 100*time.Millisecond)
+		createStatus(t, rebuilt[1].GroupSHA, commitstatus.CommitStatusSuccess)
+		assert.Eventually(t, func() bool {
+			pr = unittest.AssertExistsAndLoadBean(t, &issues_model.PullRequest{ID: pulls[2].ID})
+			return pr.HasMerged
+		}, 10*time.Second, 100*time.Millisecond)
+		assert.Equal(t, rebuilt[1].GroupSHA, pr.MergedCommitID)
+		assert.Empty(t, getQueue(t))
+
+		pr = ejected
+		req = NewRequest(t, "GET", fmt.Sprintf("/%s/%s/pulls/%d", user2.Name, repo.Name, pr.Index))
+		resp = session.MakeRequest(t, req, http.StatusOK)
+		assert.Contains(t, resp.Body.String(), "the checks of its merge group failed")
+	})
+}
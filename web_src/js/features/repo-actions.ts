@@ -13,12 +13,18 @@ export function initRepositoryActionView() {
   const view = createApp(RepoActionView, {
     runIndex: el.getAttribute('data-run-index'),
     jobIndex: el.getAttribute('data-job-index'),
+    runAttempt: el.getAttribute('data-run-attempt'),
     actionsURL: el.getAttribute('data-actions-url'),
     locale: {
       approve: el.getAttribute('data-locale-approve'),
+      rejectDeployment: el.getAttribute('data-locale-reject-deployment'),
       cancel: el.getAttribute('data-locale-cancel'),
       rerun: el.getAttribute('data-locale-rerun'),
       rerun_all: el.getAttribute('data-locale-rerun-all'),
+      rerunFailed: el.getAttribute('data-locale-rerun-failed'),
+      rerunDebugLogging: el.getAttribute('data-locale-rerun-debug-logging'),
+      attempt: el.getAttribute('data-locale-runs-attempt'),
+      attemptPrevious: el.getAttribute('data-locale-runs-attempt-previous'),
       scheduled: el.getAttribute('data-locale-runs-scheduled'),
       commit: el.getAttribute('data-locale-runs-commit'),
       pushedBy: el.getAttribute('data-locale-runs-pushed-by'),
@@ -39,9 +45,16 @@ export function initRepositoryActionView() {
         cancelled: el.getAttribute('data-locale-status-cancelled'),
         skipped: el.getAttribute('data-locale-status-skipped'),
         blocked: el.getAttribute('data-locale-status-blocked'),
+        pending_approval: el.getAttribute('data-locale-status-pending-approval'),
       },
       logsAlwaysAutoScroll: el.getAttribute('data-locale-logs-always-auto-scroll'),
       logsAlwaysExpandRunning: el.getAttribute('data-locale-logs-always-expand-running'),
+      annotations: el.getAttribute('data-locale-annotations'),
+      testsTitle: el.getAttribute('data-locale-tests-title'),
+      testsPassed: el.getAttribute('data-locale-tests-passed'),
+      testsFailed: el.getAttribute('data-locale-tests-failed'),
+      testsSkipped: el.getAttribute('data-locale-tests-skipped'),
+      testsFlaky: el.getAttribute('data-locale-tests-flaky'),
     },
   });
   view.mount(el);